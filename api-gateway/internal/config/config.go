@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/sirupsen/logrus"
@@ -11,7 +13,10 @@ type Config struct {
 	Port        int    `envconfig:"PORT" default:"8080"`
 	Environment string `envconfig:"ENVIRONMENT" default:"development"`
 	LogLevel    string `envconfig:"LOG_LEVEL" default:"info"`
-	JWTSecret   string `envconfig:"JWT_SECRET" default:"your-secret-key-change-in-production"`
+	LogFormat   string `envconfig:"LOG_FORMAT" default:""` // json или text
+	// ShutdownTimeout время на завершение запросов при остановке
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"25s"`
+	JWTSecret       string        `envconfig:"JWT_SECRET" default:"your-secret-key-change-in-production"`
 
 	UserServiceURL         string `envconfig:"USER_SERVICE_URL" default:"http://localhost:8081"`
 	TemplateServiceURL     string `envconfig:"TEMPLATE_SERVICE_URL" default:"http://localhost:8082"`
@@ -19,6 +24,10 @@ type Config struct {
 	DataServiceURL         string `envconfig:"DATA_SERVICE_URL" default:"http://localhost:8084"`
 	NotificationServiceURL string `envconfig:"NOTIFICATION_SERVICE_URL" default:"http://localhost:8085"`
 	StorageServiceURL      string `envconfig:"STORAGE_SERVICE_URL" default:"http://localhost:8087"`
+
+	// APITokenCacheTTL время кеширования проверки API токена в User Service;
+	// отзыв токена вступает в силу не позже чем через этот интервал
+	APITokenCacheTTL time.Duration `envconfig:"API_TOKEN_CACHE_TTL" default:"30s"`
 }
 
 func Load() (*Config, error) {
@@ -31,16 +40,29 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// SetupLogger применяет LOG_FORMAT и LOG_LEVEL. Без LOG_FORMAT в production
+// используется JSON, иначе текст; некорректный уровень заменяется на info.
 func (c *Config) SetupLogger() {
+	switch strings.ToLower(c.LogFormat) {
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	default:
+		if c.Environment == "production" {
+			logrus.SetFormatter(&logrus.JSONFormatter{})
+		} else {
+			logrus.SetFormatter(&logrus.TextFormatter{})
+		}
+		if c.LogFormat != "" {
+			logrus.Warnf("Неизвестный LOG_FORMAT %q, формат выбран по окружению", c.LogFormat)
+		}
+	}
+
 	level, err := logrus.ParseLevel(c.LogLevel)
 	if err != nil {
 		level = logrus.InfoLevel
+		logrus.Warnf("Некорректный LOG_LEVEL %q, используется info", c.LogLevel)
 	}
 	logrus.SetLevel(level)
-
-	if c.Environment == "production" {
-		logrus.SetFormatter(&logrus.JSONFormatter{})
-	} else {
-		logrus.SetFormatter(&logrus.TextFormatter{})
-	}
 }
@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"strings"
 
 	"api-gateway/internal/config"
+	"api-gateway/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -23,11 +25,7 @@ func NewGatewayHandler(cfg *config.Config) *GatewayHandler {
 }
 
 func (h *GatewayHandler) Health(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"service": "api-gateway",
-		"version": "1.0.0",
-	})
+	c.JSON(http.StatusOK, version.Health("api-gateway"))
 }
 
 // ProxyToUserService проксирование запросов к User Service
@@ -81,6 +79,11 @@ func (h *GatewayHandler) proxyRequest(c *gin.Context, targetURL string) {
 		path += "/"
 	}
 
+	// Журнал аудита каждого сервиса доступен по /api/v1/admin/audit
+	if strings.HasPrefix(path, "/api/v1/admin/audit/") {
+		path = "/api/v1/admin/audit"
+	}
+
 	fullURL := targetURL + path
 
 	if c.Request.URL.RawQuery != "" {
@@ -105,7 +108,13 @@ func (h *GatewayHandler) proxyRequest(c *gin.Context, targetURL string) {
 		body = bytes.NewReader(bodyBytes)
 	}
 
-	req, err := http.NewRequest(c.Request.Method, fullURL, body)
+	// Поток событий закрывается вместе с соединением клиента, поэтому запрос к сервису
+	// привязан к его контексту; остальные запросы выполняются как прежде
+	ctx := context.Background()
+	if isEventStream(c.GetHeader("Accept")) {
+		ctx = c.Request.Context()
+	}
+	req, err := http.NewRequestWithContext(ctx, c.Request.Method, fullURL, body)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка создания запроса"})
 		return
@@ -129,6 +138,17 @@ func (h *GatewayHandler) proxyRequest(c *gin.Context, targetURL string) {
 	}
 	defer resp.Body.Close()
 
+	if isEventStream(resp.Header.Get("Content-Type")) {
+		for key, values := range resp.Header {
+			for _, value := range values {
+				c.Header(key, value)
+			}
+		}
+		c.Status(resp.StatusCode)
+		streamResponse(c, resp.Body)
+		return
+	}
+
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": "Ошибка чтения ответа"})
@@ -145,3 +165,32 @@ func (h *GatewayHandler) proxyRequest(c *gin.Context, targetURL string) {
 
 	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
 }
+
+// isEventStream сообщает, что заголовок Accept или Content-Type относится к Server-Sent Events
+func isEventStream(header string) bool {
+	return strings.Contains(header, "text/event-stream")
+}
+
+// streamResponse передает тело ответа клиенту по мере поступления, сбрасывая буфер после
+// каждой порции, чтобы события доходили без ожидания конца потока
+func streamResponse(c *gin.Context, body io.Reader) {
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+		if err != nil {
+			if err != io.EOF {
+				logrus.WithError(err).Warn("Поток событий сервиса прерван")
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"api-gateway/internal/config"
+	"api-gateway/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readEvent читает из потока одно событие Server-Sent Events и возвращает его имя и данные
+func readEvent(t *testing.T, reader *bufio.Reader) (name, data string) {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("чтение потока: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			return name, data
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+}
+
+func TestProxyStreamsSagaEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Сервис отправляет первое событие и ждет, пока клиент его получит:
+	// буферизующий прокси отдал бы ответ только после завершения потока
+	received := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/sagas/saga-1/stream" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		flusher := w.(http.Flusher)
+
+		fmt.Fprint(w, "event: progress\ndata: {\"completed_steps\":1}\n\n")
+		flusher.Flush()
+		select {
+		case <-received:
+		case <-r.Context().Done():
+			return
+		}
+
+		// Поток живет дольше таймаута запросов шлюза
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, "event: progress\ndata: {\"completed_steps\":2}\n\n")
+		flusher.Flush()
+		fmt.Fprint(w, "event: complete\ndata: {\"status\":\"completed\"}\n\n")
+		flusher.Flush()
+	}))
+	defer upstream.Close()
+
+	handler := NewGatewayHandler(&config.Config{ReportServiceURL: upstream.URL})
+	router := gin.New()
+	router.Use(middleware.Timeout(50 * time.Millisecond))
+	router.GET("/api/v1/sagas/*path", handler.ProxyToReportService)
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, gateway.URL+"/api/v1/sagas/saga-1/stream", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("подключение к потоку: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type %q, ожидался text/event-stream", got)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if name, data := readEvent(t, reader); name != "progress" || data != `{"completed_steps":1}` {
+			t.Errorf("первое событие %s %s, ожидался progress", name, data)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("первое событие не получено до завершения потока")
+	}
+	close(received)
+
+	if name, data := readEvent(t, reader); name != "progress" || data != `{"completed_steps":2}` {
+		t.Errorf("второе событие %s %s, ожидался progress", name, data)
+	}
+	if name, data := readEvent(t, reader); name != "complete" || data != `{"status":"completed"}` {
+		t.Errorf("последнее событие %s %s, ожидался complete", name, data)
+	}
+}
+
+func TestProxyBuffersRegularResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"completed"}`))
+	}))
+	defer upstream.Close()
+
+	handler := NewGatewayHandler(&config.Config{ReportServiceURL: upstream.URL})
+	router := gin.New()
+	router.GET("/api/v1/sagas/*path", handler.ProxyToReportService)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/sagas/saga-1/progress", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"status":"completed"}` {
+		t.Errorf("ответ %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"api-gateway/internal/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testAPIToken = APITokenPrefix + "valid"
+
+// fakeUserService отвечает на проверку API токенов как User Service
+func fakeUserService(t *testing.T, calls *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/auth/tokens/introspect" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(calls, 1)
+
+		var req struct {
+			Token string `json:"token"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Token != testAPIToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(APITokenIdentity{UserID: 7, Name: "Скрипт", Role: "user", Scopes: []string{"storage:read"}})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// scopedRouter маршрутизатор с группой, требующей прав storage
+func scopedRouter(verifier *APITokenVerifier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/storage", Auth(jwt.NewManager("test-secret"), verifier), RequireScope("storage"))
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id")})
+	}
+	group.GET("/files", handler)
+	group.POST("/files", handler)
+	return router
+}
+
+func request(router http.Handler, method, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/storage/files", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthAcceptsAPITokenWithinScopes(t *testing.T) {
+	var calls int32
+	userService := fakeUserService(t, &calls)
+	router := scopedRouter(NewAPITokenVerifier(userService.URL, time.Minute))
+
+	rec := request(router, http.MethodGet, testAPIToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("чтение с storage:read: статус %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		UserID uint `json:"user_id"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.UserID != 7 {
+		t.Errorf("user_id %d, ожидался владелец токена 7", body.UserID)
+	}
+
+	if rec := request(router, http.MethodPost, testAPIToken); rec.Code != http.StatusForbidden {
+		t.Errorf("запись без storage:write: статус %d, ожидался 403", rec.Code)
+	}
+
+	// Повторные запросы берут результат проверки из кеша
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("запросов к User Service: %d, ожидался 1", n)
+	}
+}
+
+func TestAuthRejectsUnknownAPIToken(t *testing.T) {
+	var calls int32
+	userService := fakeUserService(t, &calls)
+	router := scopedRouter(NewAPITokenVerifier(userService.URL, time.Minute))
+
+	if rec := request(router, http.MethodGet, APITokenPrefix+"revoked"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("неизвестный токен: статус %d, ожидался 401", rec.Code)
+	}
+	// Отказ не кешируется
+	request(router, http.MethodGet, APITokenPrefix+"revoked")
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("запросов к User Service: %d, ожидалось 2", n)
+	}
+}
+
+func TestAuthReportsUnavailableUserService(t *testing.T) {
+	var calls int32
+	userService := fakeUserService(t, &calls)
+	userService.Close()
+	router := scopedRouter(NewAPITokenVerifier(userService.URL, time.Minute))
+
+	if rec := request(router, http.MethodGet, testAPIToken); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("User Service недоступен: статус %d, ожидался 503", rec.Code)
+	}
+}
+
+func TestRequireScopeIgnoresJWT(t *testing.T) {
+	router := scopedRouter(nil)
+	token, err := jwt.NewManager("test-secret").GenerateToken(1, "Пользователь", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("выпуск JWT: %v", err)
+	}
+
+	if rec := request(router, http.MethodPost, token); rec.Code != http.StatusOK {
+		t.Errorf("запрос по JWT: статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -2,7 +2,10 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
 	"api-gateway/internal/jwt"
@@ -48,7 +51,7 @@ func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Upload-Token")
 		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -60,6 +63,38 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// RequireJSON отвечает 415 на POST/PUT/PATCH запросы с телом, у которых Content-Type
+// не application/json. Шлюз проксирует сервисы через маршруты с *path, поэтому
+// исключения — загрузки файлов — задаются префиксами пути запроса
+func RequireJSON(exemptPrefixes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength == 0 {
+			c.Next()
+			return
+		}
+		for _, prefix := range exemptPrefixes {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type должен быть application/json"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // RequestID middleware для добавления уникального ID запроса
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -74,7 +109,8 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
-func Auth(jwtManager *jwt.Manager) gin.HandlerFunc {
+// Auth middleware для аутентификации по JWT или, если apiTokens задан, по API токену пользователя
+func Auth(jwtManager *jwt.Manager, apiTokens *APITokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -94,6 +130,24 @@ func Auth(jwtManager *jwt.Manager) gin.HandlerFunc {
 			return
 		}
 
+		if apiTokens != nil && strings.HasPrefix(tokenString, APITokenPrefix) {
+			identity, err := apiTokens.Verify(c.Request.Context(), tokenString)
+			if err != nil {
+				if errors.Is(err, ErrInvalidAPIToken) {
+					c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				} else {
+					logrus.WithError(err).WithField("request_id", c.GetString("request_id")).Error("Ошибка проверки API токена")
+					c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Token verification unavailable"})
+				}
+				c.Abort()
+				return
+			}
+
+			setAPITokenIdentity(c, identity)
+			c.Next()
+			return
+		}
+
 		claims, err := jwtManager.ValidateToken(tokenString)
 		if err != nil {
 			logrus.WithError(err).WithField("request_id", c.GetString("request_id")).Warn("Невалидный JWT токен")
@@ -119,8 +173,28 @@ func Auth(jwtManager *jwt.Manager) gin.HandlerFunc {
 	}
 }
 
+// SkipFor пропускает запросы method к path мимо handler. Так публичный маршрут сервиса,
+// который попадает под catch-all маршрут защищенной группы и не может быть
+// зарегистрирован в gin отдельно, обходит проверку авторизации
+func SkipFor(handler gin.HandlerFunc, method, path string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == method && c.Request.URL.Path == path {
+			c.Next()
+			return
+		}
+		handler(c)
+	}
+}
+
+// Timeout ограничивает время обработки запроса. Поток Server-Sent Events
+// (Accept: text/event-stream) живет до конечного события и не ограничивается
 func Timeout(timeout time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+			c.Next()
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequireJSON("/api/v1/storage/files/upload"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/api/v1/reports/*path", ok)
+	router.POST("/api/v1/storage/*path", ok)
+	router.GET("/api/v1/reports/*path", ok)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"JSON", http.MethodPost, "/api/v1/reports/", "application/json; charset=utf-8", `{}`, http.StatusOK},
+		{"форма вместо JSON", http.MethodPost, "/api/v1/reports/", "application/x-www-form-urlencoded", "name=x", http.StatusUnsupportedMediaType},
+		{"без Content-Type", http.MethodPost, "/api/v1/reports/", "", `{}`, http.StatusUnsupportedMediaType},
+		{"пустое тело", http.MethodPost, "/api/v1/reports/1/retry", "", "", http.StatusOK},
+		{"GET не проверяется", http.MethodGet, "/api/v1/reports/", "text/plain", "x", http.StatusOK},
+		{"загрузка файла", http.MethodPost, "/api/v1/storage/files/upload", "multipart/form-data; boundary=x", "--x--", http.StatusOK},
+		{"часть загрузки", http.MethodPost, "/api/v1/storage/files/upload/abc/chunk/1", "application/octet-stream", "data", http.StatusOK},
+		{"другие маршруты storage", http.MethodPost, "/api/v1/storage/files/1/tags", "text/plain", "x", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("статус %d, ожидался %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
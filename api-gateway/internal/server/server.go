@@ -39,6 +39,8 @@ func NewServer(cfg *config.Config) *Server {
 	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS())
 	router.Use(middleware.RequestID())
+	// Загрузки файлов в storage-service принимают multipart/form-data и поток байт
+	router.Use(middleware.RequireJSON("/api/v1/storage/files/upload"))
 	router.Use(middleware.SecurityHeaders())
 	router.Use(middleware.Metrics())
 	router.Use(middleware.Timeout(30 * time.Second))
@@ -46,7 +48,9 @@ func NewServer(cfg *config.Config) *Server {
 	// Настройка метрик
 	serviceMetrics.SetupMetricsEndpoint(router, "api-gateway")
 
-	setupRoutes(router, gatewayHandler, jwtManager)
+	apiTokens := middleware.NewAPITokenVerifier(cfg.UserServiceURL, cfg.APITokenCacheTTL)
+
+	setupRoutes(router, gatewayHandler, jwtManager, apiTokens)
 
 	return &Server{
 		config:  cfg,
@@ -74,7 +78,7 @@ func (s *Server) Start() error {
 	<-quit
 	logrus.Info("Остановка API Gateway...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
@@ -85,7 +89,10 @@ func (s *Server) Start() error {
 	return nil
 }
 
-func setupRoutes(router *gin.Engine, gatewayHandler *handlers.GatewayHandler, jwtManager *jwt.Manager) {
+// setupRoutes настраивает маршруты. Права API токенов на маршруты сервисов проверяют
+// сами сервисы; шлюз проверяет их для Notification и Storage Service, которые
+// принимают запросы без аутентификации.
+func setupRoutes(router *gin.Engine, gatewayHandler *handlers.GatewayHandler, jwtManager *jwt.Manager, apiTokens *middleware.APITokenVerifier) {
 	router.GET("/health", gatewayHandler.Health)
 
 	// Публичные маршруты для аутентификации (БЕЗ авторизации)
@@ -100,8 +107,10 @@ func setupRoutes(router *gin.Engine, gatewayHandler *handlers.GatewayHandler, jw
 			public.GET("/health", gatewayHandler.Health)
 		}
 
+		// Скачивание отчета по подписанной ссылке публичное: токен проверяет Report Service.
+		// Маршрут попадает под /reports/*path, поэтому пропускается мимо авторизации группы
 		protected := api.Group("/")
-		protected.Use(middleware.Auth(jwtManager))
+		protected.Use(middleware.SkipFor(middleware.Auth(jwtManager, apiTokens), http.MethodGet, "/api/v1/reports/shared"))
 		{
 			// Проксирование запросов к микросервисам
 			protected.Any("/templates", gatewayHandler.ProxyToTemplateService)
@@ -113,14 +122,27 @@ func setupRoutes(router *gin.Engine, gatewayHandler *handlers.GatewayHandler, jw
 			protected.Any("/data-sources", gatewayHandler.ProxyToDataService)
 			protected.Any("/data-sources/*path", gatewayHandler.ProxyToDataService)
 			protected.Any("/data/*path", gatewayHandler.ProxyToDataService)
-			protected.Any("/notifications/*path", gatewayHandler.ProxyToNotificationService)
-			protected.Any("/storage/*path", gatewayHandler.ProxyToStorageService)
+			protected.Any("/notifications/*path", middleware.RequireScope("notifications"), gatewayHandler.ProxyToNotificationService)
+			protected.Any("/storage/*path", middleware.RequireScope("storage"), gatewayHandler.ProxyToStorageService)
+
+			// Проверка API токенов (/auth/tokens/introspect) доступна только внутри кластера
+			protected.Any("/auth/tokens", gatewayHandler.ProxyToUserService)
+			protected.DELETE("/auth/tokens/:id", gatewayHandler.ProxyToUserService)
+
+			// Журналы аудита сервисов (доступ проверяется на стороне сервиса)
+			protected.GET("/admin/audit/users", gatewayHandler.ProxyToUserService)
+			protected.GET("/admin/audit/templates", gatewayHandler.ProxyToTemplateService)
+			protected.GET("/admin/audit/reports", gatewayHandler.ProxyToReportService)
+			protected.POST("/admin/sagas/cleanup", gatewayHandler.ProxyToReportService)
+			protected.GET("/admin/settings/:key", gatewayHandler.ProxyToReportService)
+			protected.PUT("/admin/settings/:key", gatewayHandler.ProxyToReportService)
 		}
 
 		// Защищенные маршруты для users (с авторизацией)
 		protectedUsers := api.Group("/users")
-		protectedUsers.Use(middleware.Auth(jwtManager))
+		protectedUsers.Use(middleware.Auth(jwtManager, apiTokens))
 		{
+			protectedUsers.GET("/me", gatewayHandler.ProxyToUserService)
 			protectedUsers.GET("/profile", gatewayHandler.ProxyToUserService)
 			protectedUsers.PUT("/profile", gatewayHandler.ProxyToUserService)
 			protectedUsers.DELETE("/profile", gatewayHandler.ProxyToUserService)
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"api-gateway/internal/config"
+	"api-gateway/internal/handlers"
+	"api-gateway/internal/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSharedReportRouteIsPublic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Report Service отвечает путем и запросом, которые получил от шлюза
+	var proxied []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.Method+" "+r.URL.RequestURI())
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	jwtManager := jwt.NewManager("test-secret")
+	router := gin.New()
+	setupRoutes(router, handlers.NewGatewayHandler(&config.Config{ReportServiceURL: upstream.URL}), jwtManager, nil)
+
+	serve := func(method, path, authorization string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve(http.MethodGet, "/api/v1/reports/shared?token=abc.def", ""); code != http.StatusOK {
+		t.Fatalf("скачивание по ссылке без авторизации: статус %d, ожидался 200", code)
+	}
+	if len(proxied) != 1 || proxied[0] != "GET /api/v1/reports/shared?token=abc.def" {
+		t.Fatalf("в Report Service переданы запросы %v", proxied)
+	}
+
+	// Остальные маршруты отчетов по-прежнему требуют авторизацию
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/reports/1"},
+		{http.MethodGet, "/api/v1/reports"},
+		{http.MethodPost, "/api/v1/reports/shared"},
+		{http.MethodGet, "/api/v1/reports/shared/1"},
+	} {
+		if code := serve(route.method, route.path, ""); code != http.StatusUnauthorized {
+			t.Errorf("%s %s без авторизации: статус %d, ожидался 401", route.method, route.path, code)
+		}
+	}
+	if len(proxied) != 1 {
+		t.Fatalf("неавторизованные запросы переданы в Report Service: %v", proxied)
+	}
+
+	token, err := jwtManager.GenerateToken(1, "Пользователь", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("создание токена: %v", err)
+	}
+	if code := serve(http.MethodGet, "/api/v1/reports/1", "Bearer "+token); code != http.StatusOK {
+		t.Errorf("запрос с токеном: статус %d, ожидался 200", code)
+	}
+}
@@ -0,0 +1,34 @@
+package csvutil
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// WriteRecords записывает заголовки и строки в формате CSV (RFC 4180).
+// Поля с запятыми, кавычками и переводами строк экранируются автоматически.
+func WriteRecords(w io.Writer, headers []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+
+	if len(headers) > 0 {
+		if err := writer.Write(headers); err != nil {
+			return fmt.Errorf("ошибка записи заголовков CSV: %w", err)
+		}
+	}
+
+	for i, row := range rows {
+		if len(headers) > 0 && len(row) != len(headers) {
+			return fmt.Errorf("строка %d содержит %d полей вместо %d", i+1, len(row), len(headers))
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("ошибка записи строки CSV %d: %w", i+1, err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("ошибка записи CSV: %w", err)
+	}
+	return nil
+}
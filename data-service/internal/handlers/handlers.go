@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -30,11 +32,11 @@ func (h *DataSourceHandler) CreateDataSource(c *gin.Context) {
 	var req models.DataSourceCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.metrics.RecordBusinessOperation("data-service", "create_data_source", time.Since(start), false)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
-	dataSource, err := h.dataSourceService.CreateDataSource(&req)
+	dataSource, err := h.dataSourceService.CreateDataSource(c.GetUint("user_id"), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка создания источника данных")
 		h.metrics.RecordBusinessOperation("data-service", "create_data_source", time.Since(start), false)
@@ -48,8 +50,10 @@ func (h *DataSourceHandler) CreateDataSource(c *gin.Context) {
 
 func (h *DataSourceHandler) GetDataSources(c *gin.Context) {
 	start := time.Now()
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 	active := c.Query("active")
 
 	dataSources, total, err := h.dataSourceService.GetDataSources(page, limit, active)
@@ -87,6 +91,42 @@ func (h *DataSourceHandler) GetDataSource(c *gin.Context) {
 	c.JSON(http.StatusOK, dataSource)
 }
 
+// GetDataSourceCollections возвращает сборы данных, использующие источник
+func (h *DataSourceHandler) GetDataSourceCollections(c *gin.Context) {
+	start := time.Now()
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		return
+	}
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	dataCollections, total, err := h.dataSourceService.GetDataSourceCollections(uint(id), page, limit)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения сборов данных источника")
+		h.metrics.RecordBusinessOperation("data-service", "get_data_source_collections", time.Since(start), false)
+		if errors.Is(err, services.ErrDataSourceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.metrics.RecordBusinessOperation("data-service", "get_data_source_collections", time.Since(start), true)
+	c.JSON(http.StatusOK, models.DataCollectionsResponse{
+		DataCollections: dataCollections,
+		Total:           total,
+		Page:            page,
+		Limit:           limit,
+	})
+}
+
 func (h *DataSourceHandler) UpdateDataSource(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -97,11 +137,11 @@ func (h *DataSourceHandler) UpdateDataSource(c *gin.Context) {
 
 	var req models.DataSourceUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
-	dataSource, err := h.dataSourceService.UpdateDataSource(uint(id), &req)
+	dataSource, err := h.dataSourceService.UpdateDataSource(uint(id), c.GetUint("user_id"), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка обновления источника данных")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -130,34 +170,70 @@ func (h *DataSourceHandler) DeleteDataSource(c *gin.Context) {
 
 type DataCollectionHandler struct {
 	dataCollectionService *services.DataCollectionService
+	metrics               *metrics.Metrics
 }
 
-func NewDataCollectionHandler(dataCollectionService *services.DataCollectionService) *DataCollectionHandler {
+func NewDataCollectionHandler(dataCollectionService *services.DataCollectionService, metrics *metrics.Metrics) *DataCollectionHandler {
 	return &DataCollectionHandler{
 		dataCollectionService: dataCollectionService,
+		metrics:               metrics,
 	}
 }
 
 func (h *DataCollectionHandler) CreateDataCollection(c *gin.Context) {
+	start := time.Now()
 	var req models.DataCollectionCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.metrics.RecordBusinessOperation("data-service", "create_data_collection", time.Since(start), false)
+		respondBindingError(c, err)
+		return
+	}
+
+	if c.Query("validate") == "true" {
+		h.validateDataCollection(c, &req)
 		return
 	}
 
 	dataCollection, err := h.dataCollectionService.CreateDataCollection(&req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка создания сбора данных")
+		h.metrics.RecordBusinessOperation("data-service", "create_data_collection", time.Since(start), false)
+		if errors.Is(err, services.ErrInvalidTransform) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.metrics.RecordBusinessOperation("data-service", "create_data_collection", time.Since(start), true)
 	c.JSON(http.StatusCreated, dataCollection)
 }
 
+// validateDataCollection проверяет запрос сбора на источнике и возвращает результат без создания сбора
+func (h *DataCollectionHandler) validateDataCollection(c *gin.Context, req *models.DataCollectionCreateRequest) {
+	result, err := h.dataCollectionService.ValidateDataCollection(c.Request.Context(), req)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка проверки сбора данных")
+		switch {
+		case errors.Is(err, services.ErrDataSourceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidTransform), errors.Is(err, services.ErrValidationUnsupported):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 func (h *DataCollectionHandler) GetDataCollections(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 	active := c.Query("active")
 
 	dataCollections, total, err := h.dataCollectionService.GetDataCollections(page, limit, active)
@@ -203,13 +279,17 @@ func (h *DataCollectionHandler) UpdateDataCollection(c *gin.Context) {
 
 	var req models.DataCollectionUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
 	dataCollection, err := h.dataCollectionService.UpdateDataCollection(uint(id), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка обновления сбора данных")
+		if errors.Is(err, services.ErrInvalidTransform) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -236,34 +316,42 @@ func (h *DataCollectionHandler) DeleteDataCollection(c *gin.Context) {
 
 type CollectDataHandler struct {
 	collectDataService *services.CollectDataService
+	metrics            *metrics.Metrics
 }
 
-func NewCollectDataHandler(collectDataService *services.CollectDataService) *CollectDataHandler {
+func NewCollectDataHandler(collectDataService *services.CollectDataService, metrics *metrics.Metrics) *CollectDataHandler {
 	return &CollectDataHandler{
 		collectDataService: collectDataService,
+		metrics:            metrics,
 	}
 }
 
 func (h *CollectDataHandler) CollectData(c *gin.Context) {
+	start := time.Now()
 	var req models.DataCollectRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.metrics.RecordBusinessOperation("data-service", "collect_data", time.Since(start), false)
+		respondBindingError(c, err)
 		return
 	}
 
 	result, err := h.collectDataService.CollectData(&req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка сбора данных")
+		h.metrics.RecordBusinessOperation("data-service", "collect_data", time.Since(start), false)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.metrics.RecordBusinessOperation("data-service", "collect_data", time.Since(start), true)
 	c.JSON(http.StatusOK, result)
 }
 
 func (h *CollectDataHandler) GetDataRecords(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 	collectionIDStr := c.Query("collection_id")
 
 	var collectionID uint
@@ -291,6 +379,65 @@ func (h *CollectDataHandler) GetDataRecords(c *gin.Context) {
 	})
 }
 
+// ExportDataRecords выгружает записи данных в CSV
+func (h *CollectDataHandler) ExportDataRecords(c *gin.Context) {
+	start := time.Now()
+	var collectionID uint
+	if collectionIDStr := c.Query("collection_id"); collectionIDStr != "" {
+		id, err := strconv.ParseUint(collectionIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный collection_id"})
+			return
+		}
+		collectionID = uint(id)
+	}
+
+	var buf bytes.Buffer
+	if err := h.collectDataService.ExportDataRecordsCSV(&buf, collectionID); err != nil {
+		logrus.WithError(err).Error("Ошибка экспорта записей данных в CSV")
+		h.metrics.RecordBusinessOperation("data-service", "export_data_records", time.Since(start), false)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.metrics.RecordBusinessOperation("data-service", "export_data_records", time.Since(start), true)
+	c.Header("Content-Disposition", "attachment; filename=data_records.csv")
+	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
+
+// ExportDataRecordsNDJSON потоково выгружает записи данных в формате NDJSON
+func (h *CollectDataHandler) ExportDataRecordsNDJSON(c *gin.Context) {
+	start := time.Now()
+	var collectionID uint
+	if collectionIDStr := c.Query("collection_id"); collectionIDStr != "" {
+		id, err := strconv.ParseUint(collectionIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный collection_id"})
+			return
+		}
+		collectionID = uint(id)
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", "attachment; filename=data_records.ndjson")
+	written, err := h.collectDataService.ExportDataRecordsNDJSON(c.Writer, collectionID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Ошибка экспорта записей данных в NDJSON после %d записей", written)
+		h.metrics.RecordBusinessOperation("data-service", "export_data_records_ndjson", time.Since(start), false)
+		// После начала передачи статус изменить нельзя, клиент увидит оборванный поток
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if !c.Writer.Written() {
+		c.Status(http.StatusOK)
+		c.Writer.WriteHeaderNow()
+	}
+	h.metrics.RecordBusinessOperation("data-service", "export_data_records_ndjson", time.Since(start), true)
+}
+
 func (h *CollectDataHandler) GetDataRecord(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -308,3 +455,48 @@ func (h *CollectDataHandler) GetDataRecord(c *gin.Context) {
 
 	c.JSON(http.StatusOK, dataRecord)
 }
+
+// DeleteDataRecord удаляет запись данных
+func (h *CollectDataHandler) DeleteDataRecord(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		return
+	}
+
+	deleted, err := h.collectDataService.DeleteDataRecord(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrDataRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.WithError(err).Error("Ошибка удаления записи данных")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DeleteDataRecordsResponse{Deleted: deleted})
+}
+
+// DeleteDataRecords удаляет все записи сбора данных, collection_id обязателен
+func (h *CollectDataHandler) DeleteDataRecords(c *gin.Context) {
+	start := time.Now()
+	collectionID, err := strconv.ParseUint(c.Query("collection_id"), 10, 32)
+	if err != nil || collectionID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный или отсутствующий collection_id"})
+		return
+	}
+
+	deleted, err := h.collectDataService.DeleteDataRecords(uint(collectionID))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка удаления записей данных")
+		h.metrics.RecordBusinessOperation("data-service", "delete_data_records", time.Since(start), false)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.metrics.RecordBusinessOperation("data-service", "delete_data_records", time.Since(start), true)
+	logrus.Infof("Удалено записей сбора данных %d: %d", collectionID, deleted)
+	c.JSON(http.StatusOK, models.DeleteDataRecordsResponse{Deleted: deleted})
+}
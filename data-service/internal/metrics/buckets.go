@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// bucketsKey ключ контекста gin с границами гистограммы длительности маршрута
+const bucketsKey = "metrics_duration_buckets"
+
+// ExportBuckets границы гистограммы для выгрузок, которые длятся секунды и минуты
+var ExportBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Buckets переопределяет границы гистограммы http_request_duration_seconds для маршрута.
+// Указывается при регистрации маршрута перед обработчиком:
+//
+//	router.GET("/export", metrics.Buckets(metrics.ExportBuckets), handler)
+func Buckets(buckets []float64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(bucketsKey, buckets)
+		c.Next()
+	}
+}
+
+// durationObserver возвращает гистограмму длительности запроса: общую или гистограмму
+// маршрута, если для него заданы свои границы
+func (m *Metrics) durationObserver(c *gin.Context, serviceName string) prometheus.Observer {
+	if value, ok := c.Get(bucketsKey); ok {
+		if buckets, _ := value.([]float64); len(buckets) > 0 {
+			return m.routeDurations.histogram(c.FullPath(), buckets).WithLabelValues(serviceName, c.Request.Method, c.FullPath())
+		}
+	}
+	return m.HTTPRequestDuration.WithLabelValues(serviceName, c.Request.Method, c.FullPath())
+}
+
+// routeDurationCollector хранит гистограммы http_request_duration_seconds маршрутов
+// с собственными границами. Describe ничего не сообщает (unchecked collector): иначе
+// реестр не позволил бы зарегистрировать вторую гистограмму с тем же именем и метками.
+type routeDurationCollector struct {
+	mu         sync.RWMutex
+	histograms map[string]*prometheus.HistogramVec
+}
+
+// newRouteDurationCollector создает и регистрирует коллектор гистограмм маршрутов
+func newRouteDurationCollector() *routeDurationCollector {
+	collector := &routeDurationCollector{histograms: make(map[string]*prometheus.HistogramVec)}
+	prometheus.MustRegister(collector)
+	return collector
+}
+
+// histogram возвращает гистограмму маршрута, создавая ее при первом запросе.
+// Границы фиксируются при создании: первый запрос к маршруту определяет их.
+func (r *routeDurationCollector) histogram(endpoint string, buckets []float64) *prometheus.HistogramVec {
+	r.mu.RLock()
+	vec, ok := r.histograms[endpoint]
+	r.mu.RUnlock()
+	if ok {
+		return vec
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if vec, ok := r.histograms[endpoint]; ok {
+		return vec
+	}
+	vec = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request duration in seconds",
+			Buckets: buckets,
+		},
+		[]string{"service", "method", "endpoint"},
+	)
+	r.histograms[endpoint] = vec
+	return vec
+}
+
+// Describe не сообщает описаний, чтобы коллектор был unchecked
+func (r *routeDurationCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect передает значения всех гистограмм маршрутов
+func (r *routeDurationCollector) Collect(ch chan<- prometheus.Metric) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, vec := range r.histograms {
+		vec.Collect(ch)
+	}
+}
@@ -16,6 +16,8 @@ type Metrics struct {
 	HTTPRequestsTotal    *prometheus.CounterVec
 	HTTPRequestDuration  *prometheus.HistogramVec
 	HTTPRequestsInFlight *prometheus.GaugeVec
+	// Гистограммы длительности маршрутов с собственными границами (см. Buckets)
+	routeDurations *routeDurationCollector
 
 	// Бизнес метрики
 	BusinessOperationsTotal   *prometheus.CounterVec
@@ -53,6 +55,8 @@ func NewMetrics(serviceName string) *Metrics {
 			[]string{"service", "method", "endpoint"},
 		),
 
+		routeDurations: newRouteDurationCollector(),
+
 		HTTPRequestsInFlight: promauto.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "http_requests_in_flight",
@@ -156,11 +160,7 @@ func (m *Metrics) HTTPMiddleware(serviceName string) gin.HandlerFunc {
 			statusStr,
 		).Inc()
 
-		m.HTTPRequestDuration.WithLabelValues(
-			serviceName,
-			c.Request.Method,
-			c.FullPath(),
-		).Observe(duration)
+		m.durationObserver(c, serviceName).Observe(duration)
 	})
 }
 
@@ -13,6 +13,8 @@ type DataSource struct {
 	Type        string         `json:"type" gorm:"not null"`    // database, api, file, etc.
 	Config      string         `json:"config" gorm:"type:text"` // JSON конфигурация
 	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	CreatedBy   uint           `json:"created_by"` // ID пользователя, создавшего источник
+	UpdatedBy   uint           `json:"updated_by"` // ID пользователя, последним изменившего источник
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
@@ -29,6 +31,7 @@ type DataCollection struct {
 	DataSourceID uint           `json:"data_source_id" gorm:"not null"`
 	Query        string         `json:"query" gorm:"type:text"`
 	Parameters   string         `json:"parameters" gorm:"type:text"` // JSON параметры
+	Transform    string         `json:"transform" gorm:"type:text"`  // JSON описание преобразования записей
 	IsActive     bool           `json:"is_active" gorm:"default:true"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
@@ -42,7 +45,8 @@ func (DataCollection) TableName() string {
 type DataRecord struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	CollectionID uint           `json:"collection_id" gorm:"not null"`
-	Data         string         `json:"data" gorm:"type:text"`     // JSON данные
+	Data         string         `json:"data" gorm:"type:text"`     // JSON данные после преобразования
+	RawData      string         `json:"raw_data" gorm:"type:text"` // JSON данные до преобразования
 	Metadata     string         `json:"metadata" gorm:"type:text"` // JSON метаданные
 	ProcessedAt  *time.Time     `json:"processed_at"`
 	CreatedAt    time.Time      `json:"created_at"`
@@ -76,6 +80,7 @@ type DataCollectionCreateRequest struct {
 	DataSourceID uint   `json:"data_source_id" binding:"required"`
 	Query        string `json:"query"`
 	Parameters   string `json:"parameters"`
+	Transform    string `json:"transform"`
 	IsActive     bool   `json:"is_active"`
 }
 
@@ -85,6 +90,7 @@ type DataCollectionUpdateRequest struct {
 	DataSourceID uint   `json:"data_source_id"`
 	Query        string `json:"query"`
 	Parameters   string `json:"parameters"`
+	Transform    string `json:"transform"`
 	IsActive     bool   `json:"is_active"`
 }
 
@@ -100,6 +106,8 @@ type DataSourceResponse struct {
 	Type        string    `json:"type"`
 	Config      string    `json:"config"`
 	IsActive    bool      `json:"is_active"`
+	CreatedBy   uint      `json:"created_by"`
+	UpdatedBy   uint      `json:"updated_by"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -112,6 +120,8 @@ func (ds *DataSource) ToResponse() DataSourceResponse {
 		Type:        ds.Type,
 		Config:      ds.Config,
 		IsActive:    ds.IsActive,
+		CreatedBy:   ds.CreatedBy,
+		UpdatedBy:   ds.UpdatedBy,
 		CreatedAt:   ds.CreatedAt,
 		UpdatedAt:   ds.UpdatedAt,
 	}
@@ -124,6 +134,7 @@ type DataCollectionResponse struct {
 	DataSourceID uint      `json:"data_source_id"`
 	Query        string    `json:"query"`
 	Parameters   string    `json:"parameters"`
+	Transform    string    `json:"transform"`
 	IsActive     bool      `json:"is_active"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
@@ -137,16 +148,30 @@ func (dc *DataCollection) ToResponse() DataCollectionResponse {
 		DataSourceID: dc.DataSourceID,
 		Query:        dc.Query,
 		Parameters:   dc.Parameters,
+		Transform:    dc.Transform,
 		IsActive:     dc.IsActive,
 		CreatedAt:    dc.CreatedAt,
 		UpdatedAt:    dc.UpdatedAt,
 	}
 }
 
+// DataCollectionValidationResponse результат проверки запроса сбора данных без его создания
+type DataCollectionValidationResponse struct {
+	Valid          bool     `json:"valid"`
+	DataSourceID   uint     `json:"data_source_id"`
+	DataSourceType string   `json:"data_source_type"`
+	EstimatedCost  *float64 `json:"estimated_cost,omitempty"`  // Total Cost из плана запроса
+	EstimatedRows  *int64   `json:"estimated_rows,omitempty"`  // Plan Rows из плана запроса
+	EstimatedBytes *int64   `json:"estimated_bytes,omitempty"` // Content-Length ответа API
+	StatusCode     int      `json:"status_code,omitempty"`     // статус HEAD запроса к API
+	Error          string   `json:"error,omitempty"`
+}
+
 type DataRecordResponse struct {
 	ID           uint       `json:"id"`
 	CollectionID uint       `json:"collection_id"`
 	Data         string     `json:"data"`
+	RawData      string     `json:"raw_data"`
 	Metadata     string     `json:"metadata"`
 	ProcessedAt  *time.Time `json:"processed_at"`
 	CreatedAt    time.Time  `json:"created_at"`
@@ -158,6 +183,7 @@ func (dr *DataRecord) ToResponse() DataRecordResponse {
 		ID:           dr.ID,
 		CollectionID: dr.CollectionID,
 		Data:         dr.Data,
+		RawData:      dr.RawData,
 		Metadata:     dr.Metadata,
 		ProcessedAt:  dr.ProcessedAt,
 		CreatedAt:    dr.CreatedAt,
@@ -190,3 +216,7 @@ type CollectDataResponse struct {
 	RecordsCollected int    `json:"records_collected"`
 	Message          string `json:"message"`
 }
+
+type DeleteDataRecordsResponse struct {
+	Deleted int64 `json:"deleted"`
+}
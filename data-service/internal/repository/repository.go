@@ -1,26 +1,36 @@
 package repository
 
 import (
+	"errors"
+
 	"data-service/internal/models"
+	"data-service/internal/secrets"
 
 	"gorm.io/gorm"
 )
 
 type DataSourceRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cipher *secrets.Cipher
 }
 
-func NewDataSourceRepository(db *gorm.DB) *DataSourceRepository {
-	return &DataSourceRepository{db: db}
+// NewDataSourceRepository создает репозиторий; Config шифруется при записи и расшифровывается при чтении
+func NewDataSourceRepository(db *gorm.DB, cipher *secrets.Cipher) *DataSourceRepository {
+	return &DataSourceRepository{db: db, cipher: cipher}
 }
 
 func (r *DataSourceRepository) Create(dataSource *models.DataSource) error {
-	return r.db.Create(dataSource).Error
+	return r.saveEncrypted(dataSource, func() error {
+		return r.db.Create(dataSource).Error
+	})
 }
 
 func (r *DataSourceRepository) GetByID(id uint) (*models.DataSource, error) {
 	var dataSource models.DataSource
-	err := r.db.First(&dataSource, id).Error
+	if err := r.db.First(&dataSource, id).Error; err != nil {
+		return &dataSource, err
+	}
+	err := r.decrypt(&dataSource)
 	return &dataSource, err
 }
 
@@ -38,18 +48,81 @@ func (r *DataSourceRepository) GetAll(page, limit int, isActive *bool) ([]models
 	}
 
 	offset := (page - 1) * limit
-	err := query.Offset(offset).Limit(limit).Find(&dataSources).Error
-	return dataSources, total, err
+	if err := query.Offset(offset).Limit(limit).Find(&dataSources).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for i := range dataSources {
+		if err := r.decrypt(&dataSources[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return dataSources, total, nil
 }
 
 func (r *DataSourceRepository) Update(dataSource *models.DataSource) error {
-	return r.db.Save(dataSource).Error
+	return r.saveEncrypted(dataSource, func() error {
+		return r.db.Save(dataSource).Error
+	})
 }
 
 func (r *DataSourceRepository) Delete(id uint) error {
 	return r.db.Delete(&models.DataSource{}, id).Error
 }
 
+// EncryptExisting шифрует Config у записей, сохраненных до включения шифрования
+func (r *DataSourceRepository) EncryptExisting() (int, error) {
+	if r.cipher == nil {
+		return 0, errors.New("ключ шифрования не задан")
+	}
+
+	var items []models.DataSource
+	if err := r.db.Unscoped().Find(&items).Error; err != nil {
+		return 0, err
+	}
+
+	encrypted := 0
+	for _, item := range items {
+		if item.Config == "" || secrets.IsEncrypted(item.Config) {
+			continue
+		}
+		value, err := r.cipher.Encrypt(item.Config)
+		if err != nil {
+			return encrypted, err
+		}
+		if err := r.db.Unscoped().Model(&models.DataSource{}).Where("id = ?", item.ID).UpdateColumn("config", value).Error; err != nil {
+			return encrypted, err
+		}
+		encrypted++
+	}
+
+	return encrypted, nil
+}
+
+// saveEncrypted сохраняет запись с зашифрованным Config, оставляя в структуре открытое значение
+func (r *DataSourceRepository) saveEncrypted(dataSource *models.DataSource, save func() error) error {
+	plaintext := dataSource.Config
+	encrypted, err := r.cipher.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	dataSource.Config = encrypted
+	err = save()
+	dataSource.Config = plaintext
+	return err
+}
+
+// decrypt расшифровывает Config прочитанной записи
+func (r *DataSourceRepository) decrypt(dataSource *models.DataSource) error {
+	plaintext, err := r.cipher.Decrypt(dataSource.Config)
+	if err != nil {
+		return err
+	}
+	dataSource.Config = plaintext
+	return nil
+}
+
 type DataCollectionRepository struct {
 	db *gorm.DB
 }
@@ -86,6 +159,21 @@ func (r *DataCollectionRepository) GetAll(page, limit int, isActive *bool) ([]mo
 	return dataCollections, total, err
 }
 
+// GetByDataSourceID возвращает сборы данных, использующие указанный источник
+func (r *DataCollectionRepository) GetByDataSourceID(dataSourceID uint, page, limit int) ([]models.DataCollection, int64, error) {
+	var dataCollections []models.DataCollection
+	var total int64
+
+	query := r.db.Model(&models.DataCollection{}).Where("data_source_id = ?", dataSourceID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Order("id").Offset(offset).Limit(limit).Find(&dataCollections).Error
+	return dataCollections, total, err
+}
+
 func (r *DataCollectionRepository) Update(dataCollection *models.DataCollection) error {
 	return r.db.Save(dataCollection).Error
 }
@@ -130,10 +218,45 @@ func (r *DataRecordRepository) GetAll(page, limit int, collectionID uint) ([]mod
 	return dataRecords, total, err
 }
 
+// GetForExport получает записи для выгрузки, не более limit штук
+func (r *DataRecordRepository) GetForExport(collectionID uint, limit int) ([]models.DataRecord, error) {
+	var dataRecords []models.DataRecord
+
+	query := r.db.Model(&models.DataRecord{})
+	if collectionID != 0 {
+		query = query.Where("collection_id = ?", collectionID)
+	}
+
+	err := query.Limit(limit).Order("created_at DESC").Find(&dataRecords).Error
+	return dataRecords, err
+}
+
+// GetPageAfter получает следующую страницу записей с ID больше afterID в порядке возрастания ID.
+// Постраничный обход по ID не зависит от вставок во время выгрузки.
+func (r *DataRecordRepository) GetPageAfter(collectionID, afterID uint, limit int) ([]models.DataRecord, error) {
+	var dataRecords []models.DataRecord
+
+	query := r.db.Model(&models.DataRecord{}).Where("id > ?", afterID)
+	if collectionID != 0 {
+		query = query.Where("collection_id = ?", collectionID)
+	}
+
+	err := query.Order("id ASC").Limit(limit).Find(&dataRecords).Error
+	return dataRecords, err
+}
+
 func (r *DataRecordRepository) Update(dataRecord *models.DataRecord) error {
 	return r.db.Save(dataRecord).Error
 }
 
-func (r *DataRecordRepository) Delete(id uint) error {
-	return r.db.Delete(&models.DataRecord{}, id).Error
+// Delete удаляет запись данных и возвращает число удаленных строк
+func (r *DataRecordRepository) Delete(id uint) (int64, error) {
+	result := r.db.Delete(&models.DataRecord{}, id)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteByCollectionID удаляет все записи сбора данных и возвращает их число
+func (r *DataRecordRepository) DeleteByCollectionID(collectionID uint) (int64, error) {
+	result := r.db.Where("collection_id = ?", collectionID).Delete(&models.DataRecord{})
+	return result.RowsAffected, result.Error
 }
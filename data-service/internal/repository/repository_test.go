@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"data-service/internal/models"
+	"data-service/internal/secrets"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB создает отдельную SQLite базу с мигрированными моделями data-service
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "data.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.AutoMigrate(&models.DataSource{}, &models.DataCollection{}, &models.DataRecord{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+	return db
+}
+
+// rawConfig читает колонку config в обход репозитория, то есть так, как она хранится в БД
+func rawConfig(t *testing.T, db *gorm.DB, id uint) string {
+	t.Helper()
+	var config string
+	if err := db.Unscoped().Model(&models.DataSource{}).Where("id = ?", id).Pluck("config", &config).Error; err != nil {
+		t.Fatalf("чтение config: %v", err)
+	}
+	return config
+}
+
+func TestDataSourceConfigIsStoredEncrypted(t *testing.T) {
+	db := newTestDB(t)
+	cipher, err := secrets.NewCipher("test-key")
+	if err != nil {
+		t.Fatalf("создание шифра: %v", err)
+	}
+	repo := NewDataSourceRepository(db, cipher)
+
+	const config = `{"dsn":"postgres://reports:s3cret@db:5432/reports"}`
+	source := &models.DataSource{Name: "Продажи", Type: "database", Config: config}
+	if err := repo.Create(source); err != nil {
+		t.Fatalf("создание источника: %v", err)
+	}
+	if source.Config != config {
+		t.Errorf("после сохранения Config в структуре %q, ожидалось открытое значение", source.Config)
+	}
+
+	stored := rawConfig(t, db, source.ID)
+	if !strings.HasPrefix(stored, "enc:v1:") || strings.Contains(stored, "s3cret") {
+		t.Fatalf("в БД хранится %q, ожидался шифротекст с префиксом enc:v1:", stored)
+	}
+
+	loaded, err := repo.GetByID(source.ID)
+	if err != nil {
+		t.Fatalf("получение источника: %v", err)
+	}
+	if loaded.Config != config {
+		t.Errorf("расшифрован Config %q, ожидался %q", loaded.Config, config)
+	}
+
+	// Обновление снова шифрует значение
+	loaded.Config = `{"dsn":"postgres://reports:n3w@db:5432/reports"}`
+	if err := repo.Update(loaded); err != nil {
+		t.Fatalf("обновление источника: %v", err)
+	}
+	if stored := rawConfig(t, db, source.ID); !strings.HasPrefix(stored, "enc:v1:") || strings.Contains(stored, "n3w") {
+		t.Errorf("после обновления в БД хранится %q", stored)
+	}
+
+	// Другой ключ не расшифровывает значение
+	otherCipher, _ := secrets.NewCipher("other-key")
+	if _, err := NewDataSourceRepository(db, otherCipher).GetByID(source.ID); err == nil {
+		t.Error("ожидалась ошибка расшифровки другим ключом")
+	}
+}
+
+func TestDataSourceEncryptExistingSkipsEncryptedRows(t *testing.T) {
+	db := newTestDB(t)
+
+	// Записи, сохраненные до включения шифрования
+	plain := &models.DataSource{Name: "API", Type: "api", Config: `{"token":"plain-token"}`}
+	empty := &models.DataSource{Name: "Файл", Type: "file"}
+	if err := NewDataSourceRepository(db, nil).Create(plain); err != nil {
+		t.Fatalf("создание источника: %v", err)
+	}
+	if err := NewDataSourceRepository(db, nil).Create(empty); err != nil {
+		t.Fatalf("создание источника: %v", err)
+	}
+
+	cipher, _ := secrets.NewCipher("test-key")
+	repo := NewDataSourceRepository(db, cipher)
+	encrypted := &models.DataSource{Name: "БД", Type: "database", Config: `{"dsn":"already"}`}
+	if err := repo.Create(encrypted); err != nil {
+		t.Fatalf("создание источника: %v", err)
+	}
+	encryptedBefore := rawConfig(t, db, encrypted.ID)
+
+	count, err := repo.EncryptExisting()
+	if err != nil {
+		t.Fatalf("шифрование записей: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("зашифровано записей: %d, ожидалась 1", count)
+	}
+
+	if stored := rawConfig(t, db, plain.ID); !strings.HasPrefix(stored, "enc:v1:") {
+		t.Errorf("открытый Config не зашифрован: %q", stored)
+	}
+	if stored := rawConfig(t, db, encrypted.ID); stored != encryptedBefore {
+		t.Error("уже зашифрованный Config зашифрован повторно")
+	}
+	if stored := rawConfig(t, db, empty.ID); stored != "" {
+		t.Errorf("пустой Config изменен: %q", stored)
+	}
+
+	loaded, err := repo.GetByID(plain.ID)
+	if err != nil || loaded.Config != `{"token":"plain-token"}` {
+		t.Errorf("после шифрования прочитан Config %q (%v)", loaded.Config, err)
+	}
+
+	if count, err := repo.EncryptExisting(); err != nil || count != 0 {
+		t.Errorf("повторный запуск зашифровал %d записей (%v), ожидалось 0", count, err)
+	}
+}
@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encryptedPrefix помечает значения, зашифрованные Cipher
+const encryptedPrefix = "enc:v1:"
+
+// Cipher шифрует секреты в конфигурации перед сохранением в БД (AES-256-GCM)
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher создает шифр из ключа конфигурации.
+// Пустой ключ отключает шифрование: возвращается nil без ошибки.
+func NewCipher(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания блочного шифра: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания GCM: %w", err)
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// IsEncrypted проверяет, зашифровано ли значение
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Encrypt шифрует значение. Пустые и уже зашифрованные значения не изменяются.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if c == nil || plaintext == "" || IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("ошибка генерации nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает значение. Незашифрованные значения возвращаются как есть,
+// что позволяет читать записи, сохраненные до включения шифрования.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if c == nil {
+		return "", errors.New("значение зашифровано, но ключ шифрования не задан")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("ошибка декодирования значения: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("некорректное зашифрованное значение")
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("ошибка расшифровки значения: %w", err)
+	}
+
+	return string(plaintext), nil
+}
@@ -7,7 +7,6 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"data-service/internal/config"
 	"data-service/internal/database"
@@ -16,7 +15,9 @@ import (
 	"data-service/internal/metrics"
 	"data-service/internal/middleware"
 	"data-service/internal/repository"
+	"data-service/internal/secrets"
 	"data-service/internal/services"
+	"data-service/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -35,6 +36,8 @@ func NewServer(cfg *config.Config) *Server {
 
 func (s *Server) Start() error {
 	s.cfg.SetupLogger()
+	handlers.SetDefaultPageLimit(s.cfg.DefaultPageLimit)
+	handlers.SetMaxPageLimit(s.cfg.MaxPageLimit)
 
 	if s.cfg.AutoMigrate {
 		if err := s.migrate(); err != nil {
@@ -42,7 +45,7 @@ func (s *Server) Start() error {
 		}
 	}
 
-	db, err := database.Connect(s.cfg.DatabaseURL)
+	db, err := database.ConnectWithRetry(s.cfg.DatabaseURL, s.cfg.DBConnectAttempts, s.cfg.DBConnectInterval)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
@@ -50,7 +53,15 @@ func (s *Server) Start() error {
 	jwtManager := jwt.NewManager(s.cfg.JWTSecret)
 	metricsManager := metrics.NewMetrics("data-service")
 
-	router := s.setupRouter(db, jwtManager, metricsManager)
+	cipher, err := secrets.NewCipher(s.cfg.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("ошибка инициализации шифрования: %w", err)
+	}
+	if cipher == nil {
+		logrus.Warn("ENCRYPTION_KEY не задан, секреты в конфигурации хранятся в открытом виде")
+	}
+
+	router := s.setupRouter(db, jwtManager, cipher, metricsManager)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.cfg.Port),
@@ -69,7 +80,7 @@ func (s *Server) Start() error {
 	<-quit
 	logrus.Info("Остановка Data Service...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
@@ -80,7 +91,7 @@ func (s *Server) Start() error {
 	return nil
 }
 
-func (s *Server) setupRouter(db *gorm.DB, jwtManager *jwt.Manager, metricsManager *metrics.Metrics) *gin.Engine {
+func (s *Server) setupRouter(db *gorm.DB, jwtManager *jwt.Manager, cipher *secrets.Cipher, metricsManager *metrics.Metrics) *gin.Engine {
 	router := gin.Default()
 
 	// Инициализация метрик
@@ -90,47 +101,45 @@ func (s *Server) setupRouter(db *gorm.DB, jwtManager *jwt.Manager, metricsManage
 	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS())
 	router.Use(middleware.RequestID())
+	router.Use(middleware.RequireJSON())
 
-	dataSourceRepo := repository.NewDataSourceRepository(db)
+	dataSourceRepo := repository.NewDataSourceRepository(db, cipher)
 	dataCollectionRepo := repository.NewDataCollectionRepository(db)
 	dataRecordRepo := repository.NewDataRecordRepository(db)
 
-	dataSourceService := services.NewDataSourceService(dataSourceRepo)
-	dataCollectionService := services.NewDataCollectionService(dataCollectionRepo)
-	collectDataService := services.NewCollectDataService(dataRecordRepo)
+	dataSourceService := services.NewDataSourceService(dataSourceRepo, dataCollectionRepo)
+	dataCollectionService := services.NewDataCollectionService(dataCollectionRepo, dataSourceRepo, services.NewQueryValidator(s.cfg.QueryValidationTimeout))
+	collectDataService := services.NewCollectDataService(dataRecordRepo, dataCollectionRepo, s.cfg.NDJSONExportMaxRows)
 
 	dataSourceHandler := handlers.NewDataSourceHandler(dataSourceService, metricsManager)
-	dataCollectionHandler := handlers.NewDataCollectionHandler(dataCollectionService)
-	collectDataHandler := handlers.NewCollectDataHandler(collectDataService)
+	dataCollectionHandler := handlers.NewDataCollectionHandler(dataCollectionService, metricsManager)
+	collectDataHandler := handlers.NewCollectDataHandler(collectDataService, metricsManager)
 
-	s.setupRoutes(router, dataSourceHandler, dataCollectionHandler, collectDataHandler, jwtManager)
+	s.setupRoutes(router, dataSourceHandler, dataCollectionHandler, collectDataHandler, jwtManager, middleware.NewAPITokenVerifier(s.cfg.UserServiceURL, s.cfg.APITokenCacheTTL))
 
 	return router
 }
 
-func (s *Server) setupRoutes(router *gin.Engine, dataSourceHandler *handlers.DataSourceHandler, dataCollectionHandler *handlers.DataCollectionHandler, collectDataHandler *handlers.CollectDataHandler, jwtManager *jwt.Manager) {
+func (s *Server) setupRoutes(router *gin.Engine, dataSourceHandler *handlers.DataSourceHandler, dataCollectionHandler *handlers.DataCollectionHandler, collectDataHandler *handlers.CollectDataHandler, jwtManager *jwt.Manager, apiTokens *middleware.APITokenVerifier) {
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  "healthy",
-			"service": "data-service",
-			"version": "1.0.0",
-		})
+		c.JSON(http.StatusOK, version.Health("data-service"))
 	})
 
 	api := router.Group("/api/v1")
 	{
 		dataSources := api.Group("/data-sources")
-		dataSources.Use(middleware.Auth(jwtManager))
+		dataSources.Use(middleware.Auth(jwtManager, apiTokens), middleware.RequireScope("data"))
 		{
 			dataSources.POST("/", dataSourceHandler.CreateDataSource)
 			dataSources.GET("/", dataSourceHandler.GetDataSources)
 			dataSources.GET("/:id", dataSourceHandler.GetDataSource)
+			dataSources.GET("/:id/collections", dataSourceHandler.GetDataSourceCollections)
 			dataSources.PUT("/:id", dataSourceHandler.UpdateDataSource)
 			dataSources.DELETE("/:id", dataSourceHandler.DeleteDataSource)
 		}
 
 		dataCollections := api.Group("/data-collections")
-		dataCollections.Use(middleware.Auth(jwtManager))
+		dataCollections.Use(middleware.Auth(jwtManager, apiTokens), middleware.RequireScope("data"))
 		{
 			dataCollections.POST("/", dataCollectionHandler.CreateDataCollection)
 			dataCollections.GET("/", dataCollectionHandler.GetDataCollections)
@@ -140,17 +149,21 @@ func (s *Server) setupRoutes(router *gin.Engine, dataSourceHandler *handlers.Dat
 		}
 
 		collect := api.Group("/collect")
-		collect.Use(middleware.Auth(jwtManager))
+		collect.Use(middleware.Auth(jwtManager, apiTokens), middleware.RequireScope("data"))
 		{
 			collect.POST("/", collectDataHandler.CollectData)
 			collect.GET("/records", collectDataHandler.GetDataRecords)
+			collect.GET("/records/export", metrics.Buckets(metrics.ExportBuckets), collectDataHandler.ExportDataRecords)
+			collect.GET("/records/export/ndjson", metrics.Buckets(metrics.ExportBuckets), collectDataHandler.ExportDataRecordsNDJSON)
 			collect.GET("/records/:id", collectDataHandler.GetDataRecord)
+			collect.DELETE("/records", collectDataHandler.DeleteDataRecords)
+			collect.DELETE("/records/:id", collectDataHandler.DeleteDataRecord)
 		}
 	}
 }
 
 func (s *Server) migrate() error {
-	_, err := database.Connect(s.cfg.DatabaseURL)
+	_, err := database.ConnectWithRetry(s.cfg.DatabaseURL, s.cfg.DBConnectAttempts, s.cfg.DBConnectInterval)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
@@ -0,0 +1,441 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"data-service/internal/config"
+	"data-service/internal/jwt"
+	"data-service/internal/metrics"
+	"data-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testMetrics метрики регистрируются в глобальном реестре, поэтому создаются один раз
+var testMetrics = sync.OnceValue(func() *metrics.Metrics {
+	return metrics.NewMetrics("data-service-test")
+})
+
+// testRouter маршрутизатор Data Service поверх отдельной SQLite базы и JWT пользователя
+func testRouter(t *testing.T, cfg *config.Config) (*gin.Engine, *gorm.DB, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dsn := filepath.Join(t.TempDir(), "data.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&models.DataSource{}, &models.DataCollection{}, &models.DataRecord{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+
+	jwtManager := jwt.NewManager("test-secret")
+	router := NewServer(cfg).setupRouter(db, jwtManager, nil, testMetrics())
+
+	token, err := jwtManager.GenerateToken(1, "Пользователь", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("выпуск JWT: %v", err)
+	}
+	return router, db, token
+}
+
+// do выполняет запрос с токеном в заголовке Authorization
+func do(router http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// seed сохраняет модели в обход API
+func seed(t *testing.T, db *gorm.DB, values ...interface{}) {
+	t.Helper()
+	for _, value := range values {
+		if err := db.Create(value).Error; err != nil {
+			t.Fatalf("создание %T: %v", value, err)
+		}
+	}
+}
+
+func TestGetDataSourceCollections(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{})
+	sales := &models.DataSource{Name: "Продажи", Type: "database"}
+	staff := &models.DataSource{Name: "Кадры", Type: "api"}
+	empty := &models.DataSource{Name: "Пустой", Type: "file"}
+	seed(t, db, sales, staff, empty)
+
+	daily := &models.DataCollection{Name: "За день", DataSourceID: sales.ID}
+	weekly := &models.DataCollection{Name: "За неделю", DataSourceID: sales.ID}
+	removed := &models.DataCollection{Name: "Удаленный", DataSourceID: sales.ID}
+	seed(t, db, daily, &models.DataCollection{Name: "Сотрудники", DataSourceID: staff.ID}, weekly, removed)
+	db.Delete(removed)
+
+	get := func(t *testing.T, path string) models.DataCollectionsResponse {
+		t.Helper()
+		rec := do(router, http.MethodGet, path, token, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+		var result models.DataCollectionsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return result
+	}
+
+	t.Run("сборы источника", func(t *testing.T) {
+		result := get(t, fmt.Sprintf("/api/v1/data-sources/%d/collections", sales.ID))
+		// Сборы других источников и удаленные не возвращаются
+		if result.Total != 2 || len(result.DataCollections) != 2 || result.DataCollections[0].ID != daily.ID || result.DataCollections[1].ID != weekly.ID {
+			t.Errorf("сборы %+v (total %d)", result.DataCollections, result.Total)
+		}
+
+		page := get(t, fmt.Sprintf("/api/v1/data-sources/%d/collections?page=2&limit=1", sales.ID))
+		if page.Total != 2 || len(page.DataCollections) != 1 || page.DataCollections[0].ID != weekly.ID {
+			t.Errorf("вторая страница %+v (total %d)", page.DataCollections, page.Total)
+		}
+	})
+
+	t.Run("источник без сборов", func(t *testing.T) {
+		rec := do(router, http.MethodGet, fmt.Sprintf("/api/v1/data-sources/%d/collections", empty.ID), token, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		// Пустой список, а не null
+		if string(raw["data_collections"]) != "[]" || string(raw["total"]) != "0" {
+			t.Errorf("ответ %s", rec.Body.String())
+		}
+	})
+
+	if rec := do(router, http.MethodGet, "/api/v1/data-sources/999/collections", token, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("несуществующий источник: статус %d, ожидался 404", rec.Code)
+	}
+}
+
+func TestDeleteDataRecords(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{})
+	source := &models.DataSource{Name: "Продажи", Type: "database"}
+	seed(t, db, source)
+	sales := &models.DataCollection{Name: "За день", DataSourceID: source.ID}
+	other := &models.DataCollection{Name: "За неделю", DataSourceID: source.ID}
+	seed(t, db, sales, other)
+
+	first := &models.DataRecord{CollectionID: sales.ID, Data: `{"n": 1}`}
+	seed(t, db, first,
+		&models.DataRecord{CollectionID: sales.ID, Data: `{"n": 2}`},
+		&models.DataRecord{CollectionID: sales.ID, Data: `{"n": 3}`},
+		&models.DataRecord{CollectionID: other.ID, Data: `{"n": 4}`},
+	)
+
+	remaining := func(collectionID uint) int64 {
+		var count int64
+		db.Model(&models.DataRecord{}).Where("collection_id = ?", collectionID).Count(&count)
+		return count
+	}
+	deleted := func(t *testing.T, rec *httptest.ResponseRecorder) int64 {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+		var result models.DeleteDataRecordsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return result.Deleted
+	}
+
+	t.Run("одна запись", func(t *testing.T) {
+		path := fmt.Sprintf("/api/v1/collect/records/%d", first.ID)
+		if n := deleted(t, do(router, http.MethodDelete, path, token, nil)); n != 1 {
+			t.Errorf("удалено %d, ожидалась 1", n)
+		}
+		if n := remaining(sales.ID); n != 2 {
+			t.Errorf("осталось записей %d, ожидалось 2", n)
+		}
+		// Повторное удаление не находит запись
+		if rec := do(router, http.MethodDelete, path, token, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("повторное удаление: статус %d, ожидался 404", rec.Code)
+		}
+	})
+
+	t.Run("все записи сбора", func(t *testing.T) {
+		if n := deleted(t, do(router, http.MethodDelete, fmt.Sprintf("/api/v1/collect/records?collection_id=%d", sales.ID), token, nil)); n != 2 {
+			t.Errorf("удалено %d, ожидалось 2", n)
+		}
+		if remaining(sales.ID) != 0 || remaining(other.ID) != 1 {
+			t.Errorf("осталось записей: %d в сборе и %d в другом сборе", remaining(sales.ID), remaining(other.ID))
+		}
+		// Пустой сбор удаляется без ошибки
+		if n := deleted(t, do(router, http.MethodDelete, fmt.Sprintf("/api/v1/collect/records?collection_id=%d", sales.ID), token, nil)); n != 0 {
+			t.Errorf("повторно удалено %d", n)
+		}
+	})
+
+	for _, query := range []string{"", "?collection_id=0", "?collection_id=abc"} {
+		if rec := do(router, http.MethodDelete, "/api/v1/collect/records"+query, token, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("collection_id %q: статус %d, ожидался 400", query, rec.Code)
+		}
+	}
+}
+
+// flushRecorder запоминает, сколько строк было записано к моменту каждого Flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushedLines []int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushedLines = append(r.flushedLines, bytes.Count(r.Body.Bytes(), []byte("\n")))
+	r.ResponseRecorder.Flush()
+}
+
+func TestExportDataRecordsNDJSONStreamsValidLines(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{NDJSONExportMaxRows: 1100})
+	source := &models.DataSource{Name: "Продажи", Type: "database"}
+	seed(t, db, source)
+	sales := &models.DataCollection{Name: "За день", DataSourceID: source.ID}
+	other := &models.DataCollection{Name: "За неделю", DataSourceID: source.ID}
+	seed(t, db, sales, other)
+
+	records := make([]models.DataRecord, 0, 1201)
+	for i := 0; i < 1200; i++ {
+		records = append(records, models.DataRecord{CollectionID: sales.ID, Data: fmt.Sprintf(`{"n": %d, "text": "строка\nс переводом"}`, i)})
+	}
+	records = append(records, models.DataRecord{CollectionID: other.ID, Data: `{"n": -1}`})
+	if err := db.CreateInBatches(records, 200).Error; err != nil {
+		t.Fatalf("создание записей: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/collect/records/export/ndjson?collection_id=%d", sales.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("статус %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	// Каждая строка — отдельный JSON объект; переводы строк внутри данных экранированы
+	body := rec.Body.String()
+	if !strings.HasSuffix(body, "\n") {
+		t.Error("выгрузка не завершается переводом строки")
+	}
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	if len(lines) != 1100 {
+		t.Fatalf("строк %d, ожидалось 1100 (NDJSON_EXPORT_MAX_ROWS)", len(lines))
+	}
+	var previous uint
+	for i, line := range lines {
+		var record models.DataRecordResponse
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("строка %d не JSON: %v", i+1, err)
+		}
+		if record.CollectionID != sales.ID || record.ID <= previous {
+			t.Fatalf("строка %d: запись %d сбора %d после записи %d", i+1, record.ID, record.CollectionID, previous)
+		}
+		previous = record.ID
+	}
+
+	// Данные уходят клиенту страницами по мере чтения из БД, а не одним ответом в конце
+	if len(rec.flushedLines) < 2 || rec.flushedLines[0] != 500 || rec.flushedLines[1] != 1000 {
+		t.Errorf("буфер сброшен после строк %v, ожидалось после 500 и 1000", rec.flushedLines)
+	}
+}
+
+func TestCreateDataCollectionValidateOnly(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("метод %s, ожидался HEAD", r.Method)
+		}
+		switch r.URL.Path {
+		case "/v1/sales":
+			w.Header().Set("Content-Length", "2048")
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	router, db, token := testRouter(t, &config.Config{})
+	source := &models.DataSource{Name: "CRM", Type: "api", Config: fmt.Sprintf(`{"url": %q}`, api.URL+"/")}
+	file := &models.DataSource{Name: "Выгрузка", Type: "file"}
+	seed(t, db, source, file)
+
+	validate := func(t *testing.T, body models.DataCollectionCreateRequest) models.DataCollectionValidationResponse {
+		t.Helper()
+		rec := do(router, http.MethodPost, "/api/v1/data-collections/?validate=true", token, body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+		var result models.DataCollectionValidationResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return result
+	}
+
+	t.Run("корректный запрос", func(t *testing.T) {
+		result := validate(t, models.DataCollectionCreateRequest{Name: "Продажи", DataSourceID: source.ID, Parameters: `{"endpoint": "/v1/sales"}`})
+		if !result.Valid || result.Error != "" || result.DataSourceID != source.ID || result.DataSourceType != "api" {
+			t.Errorf("результат %+v", result)
+		}
+		if result.StatusCode != http.StatusOK || result.EstimatedBytes == nil || *result.EstimatedBytes != 2048 {
+			t.Errorf("статус API %d, оценка размера %v", result.StatusCode, result.EstimatedBytes)
+		}
+	})
+
+	t.Run("некорректный запрос", func(t *testing.T) {
+		// Ошибка запроса — это результат проверки, а не ошибка API сервиса
+		result := validate(t, models.DataCollectionCreateRequest{Name: "Продажи", DataSourceID: source.ID, Query: "/v1/missing"})
+		if result.Valid || result.StatusCode != http.StatusNotFound || !strings.Contains(result.Error, "статус 404") {
+			t.Errorf("результат %+v", result)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		body   models.DataCollectionCreateRequest
+		status int
+	}{
+		{"неизвестный источник", models.DataCollectionCreateRequest{Name: "Продажи", DataSourceID: 999}, http.StatusNotFound},
+		{"источник без проверки", models.DataCollectionCreateRequest{Name: "Продажи", DataSourceID: file.ID}, http.StatusBadRequest},
+		{"некорректное преобразование", models.DataCollectionCreateRequest{Name: "Продажи", DataSourceID: source.ID, Transform: `{"fields": []}`}, http.StatusBadRequest},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(router, http.MethodPost, "/api/v1/data-collections/?validate=true", token, tt.body); rec.Code != tt.status {
+				t.Errorf("статус %d, ожидался %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+
+	// Проверка не создает сборов
+	var count int64
+	db.Model(&models.DataCollection{}).Count(&count)
+	if count != 0 {
+		t.Errorf("создано сборов: %d", count)
+	}
+}
+
+func TestMetricsEndpointCountsRequests(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{})
+	source := &models.DataSource{Name: "Продажи", Type: "database"}
+	seed(t, db, source)
+	m := testMetrics()
+
+	// Метрики общие для всех тестов пакета, поэтому сравниваются приращения
+	requests := m.HTTPRequestsTotal.WithLabelValues("data-service", http.MethodGet, "/api/v1/data-sources/", "OK")
+	collections := m.BusinessOperationsTotal.WithLabelValues("data-service", "create_data_collection", "success")
+	failedCollects := m.BusinessOperationsTotal.WithLabelValues("data-service", "collect_data", "error")
+	requestsBefore := testutil.ToFloat64(requests)
+	collectionsBefore, failedBefore := testutil.ToFloat64(collections), testutil.ToFloat64(failedCollects)
+
+	for i := 0; i < 2; i++ {
+		if rec := do(router, http.MethodGet, "/api/v1/data-sources/", token, nil); rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	collection := models.DataCollectionCreateRequest{Name: "За день", DataSourceID: source.ID}
+	if rec := do(router, http.MethodPost, "/api/v1/data-collections/", token, collection); rec.Code != http.StatusCreated {
+		t.Fatalf("создание сбора: статус %d: %s", rec.Code, rec.Body.String())
+	}
+	do(router, http.MethodPost, "/api/v1/collect/", token, map[string]string{"collection_id": "не число"})
+
+	if got := testutil.ToFloat64(requests) - requestsBefore; got != 2 {
+		t.Errorf("http_requests_total /api/v1/data-sources/ вырос на %v, ожидалось 2", got)
+	}
+	if got := testutil.ToFloat64(collections) - collectionsBefore; got != 1 {
+		t.Errorf("созданных сборов %v, ожидался 1", got)
+	}
+	if got := testutil.ToFloat64(failedCollects) - failedBefore; got != 1 {
+		t.Errorf("неудачных сборов данных %v, ожидался 1", got)
+	}
+
+	rec := do(router, http.MethodGet, "/metrics", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics: статус %d", rec.Code)
+	}
+	for _, series := range []string{
+		`http_requests_total{endpoint="/api/v1/data-sources/",method="GET",service="data-service",status_code="OK"}`,
+		`business_operations_total{operation="create_data_collection",service="data-service",status="success"}`,
+		`http_request_duration_seconds_count{endpoint="/api/v1/data-sources/",method="GET",service="data-service"}`,
+	} {
+		if !strings.Contains(rec.Body.String(), series) {
+			t.Errorf("в /metrics нет %s", series)
+		}
+	}
+}
+
+func TestDataSourceAuthorsComeFromToken(t *testing.T) {
+	router, _, token := testRouter(t, &config.Config{})
+	editorToken, err := jwt.NewManager("test-secret").GenerateToken(2, "Редактор", "editor@example.com", "user")
+	if err != nil {
+		t.Fatalf("выпуск JWT: %v", err)
+	}
+
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder, status int) models.DataSourceResponse {
+		t.Helper()
+		if rec.Code != status {
+			t.Fatalf("статус %d, ожидался %d: %s", rec.Code, status, rec.Body.String())
+		}
+		var source models.DataSourceResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &source); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return source
+	}
+
+	// Значения из тела запроса не подменяют пользователя из токена
+	created := decode(t, do(router, http.MethodPost, "/api/v1/data-sources/", token, map[string]interface{}{
+		"name": "Продажи", "type": "database", "created_by": 99, "updated_by": 99,
+	}), http.StatusCreated)
+	if created.CreatedBy != 1 || created.UpdatedBy != 1 {
+		t.Errorf("после создания created_by %d, updated_by %d, ожидалось 1 и 1", created.CreatedBy, created.UpdatedBy)
+	}
+
+	path := fmt.Sprintf("/api/v1/data-sources/%d", created.ID)
+	updated := decode(t, do(router, http.MethodPut, path, editorToken, map[string]interface{}{"name": "Продажи 2024", "updated_by": 99}), http.StatusOK)
+	if updated.CreatedBy != 1 || updated.UpdatedBy != 2 {
+		t.Errorf("после изменения created_by %d, updated_by %d, ожидалось 1 и 2", updated.CreatedBy, updated.UpdatedBy)
+	}
+
+	// Значения сохранены, а не только возвращены в ответе
+	stored := decode(t, do(router, http.MethodGet, path, token, nil), http.StatusOK)
+	if stored.CreatedBy != 1 || stored.UpdatedBy != 2 {
+		t.Errorf("сохранено created_by %d, updated_by %d", stored.CreatedBy, stored.UpdatedBy)
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"data-service/internal/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ErrValidationUnsupported проверка запроса не поддерживается для типа источника
+var ErrValidationUnsupported = errors.New("проверка запроса не поддерживается для этого типа источника")
+
+// databaseSourceConfig параметры подключения из Config источника типа database
+type databaseSourceConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Database string `json:"database"`
+	User     string `json:"user"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	SSLMode  string `json:"sslmode"`
+}
+
+// dsn собирает строку подключения к PostgreSQL; без порта используется 5432
+func (c *databaseSourceConfig) dsn() string {
+	port := c.Port
+	if port == 0 {
+		port = 5432
+	}
+	user := c.User
+	if user == "" {
+		user = c.Username
+	}
+
+	u := url.URL{
+		Scheme: "postgres",
+		Host:   net.JoinHostPort(c.Host, strconv.Itoa(port)),
+		Path:   "/" + c.Database,
+	}
+	if user != "" {
+		u.User = url.UserPassword(user, c.Password)
+	}
+	if c.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {c.SSLMode}}.Encode()
+	}
+	return u.String()
+}
+
+// apiSourceConfig параметры из Config источника типа api
+type apiSourceConfig struct {
+	URL string `json:"url"`
+}
+
+// QueryValidator проверяет запрос сбора данных на источнике, не выполняя его:
+// для БД — через EXPLAIN, для API — через HEAD запрос
+type QueryValidator struct {
+	timeout    time.Duration
+	httpClient *http.Client
+}
+
+// NewQueryValidator создает проверку запросов с ограничением времени на одну проверку
+func NewQueryValidator(timeout time.Duration) *QueryValidator {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &QueryValidator{
+		timeout:    timeout,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Validate проверяет запрос на источнике. Ошибка запроса отражается в Valid и Error
+// результата; error возвращается только для неподдерживаемого типа источника
+func (v *QueryValidator) Validate(ctx context.Context, dataSource *models.DataSource, query, parameters string) (*models.DataCollectionValidationResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	result := &models.DataCollectionValidationResponse{
+		DataSourceID:   dataSource.ID,
+		DataSourceType: dataSource.Type,
+	}
+
+	var err error
+	switch dataSource.Type {
+	case "database":
+		err = v.explain(ctx, dataSource.Config, query, result)
+	case "api":
+		err = v.head(ctx, dataSource.Config, query, parameters, result)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrValidationUnsupported, dataSource.Type)
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Valid = true
+	return result, nil
+}
+
+// explain выполняет EXPLAIN запроса в транзакции только для чтения и сохраняет
+// оценку стоимости и числа строк из плана
+func (v *QueryValidator) explain(ctx context.Context, rawConfig, query string, result *models.DataCollectionValidationResponse) error {
+	if strings.TrimSpace(query) == "" {
+		return errors.New("запрос не указан")
+	}
+
+	var cfg databaseSourceConfig
+	if err := json.Unmarshal([]byte(rawConfig), &cfg); err != nil {
+		return fmt.Errorf("некорректная конфигурация источника: %w", err)
+	}
+	if cfg.Host == "" || cfg.Database == "" {
+		return errors.New("в конфигурации источника не указаны host и database")
+	}
+
+	db, err := gorm.Open(postgres.Open(cfg.dsn()), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к источнику: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	tx := db.WithContext(ctx).Begin(&sql.TxOptions{ReadOnly: true})
+	if tx.Error != nil {
+		return fmt.Errorf("ошибка подключения к источнику: %w", tx.Error)
+	}
+	defer tx.Rollback()
+
+	// Параметры запроса подставляются как NULL: для построения плана достаточно их типов
+	args := make([]interface{}, strings.Count(query, "?"))
+	var plan string
+	if err := tx.Raw("EXPLAIN (FORMAT JSON) "+query, args...).Row().Scan(&plan); err != nil {
+		return fmt.Errorf("запрос не прошел проверку: %w", err)
+	}
+
+	var explained []struct {
+		Plan struct {
+			TotalCost float64 `json:"Total Cost"`
+			PlanRows  int64   `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &explained); err != nil || len(explained) == 0 {
+		return fmt.Errorf("не удалось разобрать план запроса: %v", err)
+	}
+
+	result.EstimatedCost = &explained[0].Plan.TotalCost
+	result.EstimatedRows = &explained[0].Plan.PlanRows
+	return nil
+}
+
+// head отправляет HEAD запрос на адрес API источника. Путь берется из endpoint
+// в параметрах сбора или из запроса, если он начинается с "/"
+func (v *QueryValidator) head(ctx context.Context, rawConfig, query, parameters string, result *models.DataCollectionValidationResponse) error {
+	var cfg apiSourceConfig
+	if err := json.Unmarshal([]byte(rawConfig), &cfg); err != nil {
+		return fmt.Errorf("некорректная конфигурация источника: %w", err)
+	}
+	if cfg.URL == "" {
+		return errors.New("в конфигурации источника не указан url")
+	}
+
+	endpoint := ""
+	if parameters != "" {
+		var params struct {
+			Endpoint string `json:"endpoint"`
+		}
+		if err := json.Unmarshal([]byte(parameters), &params); err != nil {
+			return fmt.Errorf("некорректные параметры сбора: %w", err)
+		}
+		endpoint = params.Endpoint
+	}
+	if endpoint == "" && strings.HasPrefix(query, "/") {
+		endpoint = query
+	}
+	target := strings.TrimRight(cfg.URL, "/") + endpoint
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return fmt.Errorf("некорректный адрес API: %w", err)
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("API недоступен: %w", err)
+	}
+	resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if resp.ContentLength >= 0 {
+		contentLength := resp.ContentLength
+		result.EstimatedBytes = &contentLength
+	}
+	// 405 означает, что адрес существует, но не поддерживает HEAD
+	if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode != http.StatusMethodNotAllowed {
+		return fmt.Errorf("API вернул статус %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -1,32 +1,60 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"time"
 
+	"data-service/internal/csvutil"
 	"data-service/internal/models"
 	"data-service/internal/repository"
+	"data-service/internal/transform"
 
 	"gorm.io/gorm"
 )
 
+// ErrDataSourceNotFound источник данных не найден
+var ErrDataSourceNotFound = errors.New("источник данных не найден")
+
+// ErrDataRecordNotFound запись данных не найдена
+var ErrDataRecordNotFound = errors.New("запись данных не найдена")
+
+// ErrInvalidTransform некорректное описание преобразования сбора данных
+var ErrInvalidTransform = errors.New("некорректное преобразование")
+
+// validateTransform проверяет описание преобразования перед сохранением
+func validateTransform(raw string) error {
+	if _, err := transform.Parse(raw); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTransform, err)
+	}
+	return nil
+}
+
 type DataSourceService struct {
-	dataSourceRepo *repository.DataSourceRepository
+	dataSourceRepo     *repository.DataSourceRepository
+	dataCollectionRepo *repository.DataCollectionRepository
 }
 
-func NewDataSourceService(dataSourceRepo *repository.DataSourceRepository) *DataSourceService {
+func NewDataSourceService(dataSourceRepo *repository.DataSourceRepository, dataCollectionRepo *repository.DataCollectionRepository) *DataSourceService {
 	return &DataSourceService{
-		dataSourceRepo: dataSourceRepo,
+		dataSourceRepo:     dataSourceRepo,
+		dataCollectionRepo: dataCollectionRepo,
 	}
 }
 
-func (s *DataSourceService) CreateDataSource(req *models.DataSourceCreateRequest) (*models.DataSourceResponse, error) {
+func (s *DataSourceService) CreateDataSource(actorID uint, req *models.DataSourceCreateRequest) (*models.DataSourceResponse, error) {
 	dataSource := &models.DataSource{
 		Name:        req.Name,
 		Description: req.Description,
 		Type:        req.Type,
 		Config:      req.Config,
 		IsActive:    req.IsActive,
+		CreatedBy:   actorID,
+		UpdatedBy:   actorID,
 	}
 
 	if err := s.dataSourceRepo.Create(dataSource); err != nil {
@@ -61,7 +89,7 @@ func (s *DataSourceService) GetDataSource(id uint) (*models.DataSourceResponse,
 	dataSource, err := s.dataSourceRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("источник данных не найден")
+			return nil, ErrDataSourceNotFound
 		}
 		return nil, fmt.Errorf("ошибка получения источника данных: %w", err)
 	}
@@ -70,7 +98,29 @@ func (s *DataSourceService) GetDataSource(id uint) (*models.DataSourceResponse,
 	return &response, nil
 }
 
-func (s *DataSourceService) UpdateDataSource(id uint, req *models.DataSourceUpdateRequest) (*models.DataSourceResponse, error) {
+// GetDataSourceCollections возвращает сборы данных, использующие источник
+func (s *DataSourceService) GetDataSourceCollections(id uint, page, limit int) ([]models.DataCollectionResponse, int64, error) {
+	if _, err := s.dataSourceRepo.GetByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, ErrDataSourceNotFound
+		}
+		return nil, 0, fmt.Errorf("ошибка получения источника данных: %w", err)
+	}
+
+	dataCollections, total, err := s.dataCollectionRepo.GetByDataSourceID(id, page, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка получения сборов данных: %w", err)
+	}
+
+	responses := make([]models.DataCollectionResponse, len(dataCollections))
+	for i, dc := range dataCollections {
+		responses[i] = dc.ToResponse()
+	}
+
+	return responses, total, nil
+}
+
+func (s *DataSourceService) UpdateDataSource(id, actorID uint, req *models.DataSourceUpdateRequest) (*models.DataSourceResponse, error) {
 	dataSource, err := s.dataSourceRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -92,6 +142,7 @@ func (s *DataSourceService) UpdateDataSource(id uint, req *models.DataSourceUpda
 		dataSource.Config = req.Config
 	}
 	dataSource.IsActive = req.IsActive
+	dataSource.UpdatedBy = actorID
 
 	if err := s.dataSourceRepo.Update(dataSource); err != nil {
 		return nil, fmt.Errorf("ошибка обновления источника данных: %w", err)
@@ -110,21 +161,47 @@ func (s *DataSourceService) DeleteDataSource(id uint) error {
 
 type DataCollectionService struct {
 	dataCollectionRepo *repository.DataCollectionRepository
+	dataSourceRepo     *repository.DataSourceRepository
+	validator          *QueryValidator
 }
 
-func NewDataCollectionService(dataCollectionRepo *repository.DataCollectionRepository) *DataCollectionService {
+func NewDataCollectionService(dataCollectionRepo *repository.DataCollectionRepository, dataSourceRepo *repository.DataSourceRepository, validator *QueryValidator) *DataCollectionService {
 	return &DataCollectionService{
 		dataCollectionRepo: dataCollectionRepo,
+		dataSourceRepo:     dataSourceRepo,
+		validator:          validator,
+	}
+}
+
+// ValidateDataCollection проверяет запрос сбора на его источнике без создания сбора
+func (s *DataCollectionService) ValidateDataCollection(ctx context.Context, req *models.DataCollectionCreateRequest) (*models.DataCollectionValidationResponse, error) {
+	if err := validateTransform(req.Transform); err != nil {
+		return nil, err
+	}
+
+	dataSource, err := s.dataSourceRepo.GetByID(req.DataSourceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDataSourceNotFound
+		}
+		return nil, fmt.Errorf("ошибка получения источника данных: %w", err)
 	}
+
+	return s.validator.Validate(ctx, dataSource, req.Query, req.Parameters)
 }
 
 func (s *DataCollectionService) CreateDataCollection(req *models.DataCollectionCreateRequest) (*models.DataCollectionResponse, error) {
+	if err := validateTransform(req.Transform); err != nil {
+		return nil, err
+	}
+
 	dataCollection := &models.DataCollection{
 		Name:         req.Name,
 		Description:  req.Description,
 		DataSourceID: req.DataSourceID,
 		Query:        req.Query,
 		Parameters:   req.Parameters,
+		Transform:    req.Transform,
 		IsActive:     req.IsActive,
 	}
 
@@ -193,6 +270,12 @@ func (s *DataCollectionService) UpdateDataCollection(id uint, req *models.DataCo
 	if req.Parameters != "" {
 		dataCollection.Parameters = req.Parameters
 	}
+	if req.Transform != "" {
+		if err := validateTransform(req.Transform); err != nil {
+			return nil, err
+		}
+		dataCollection.Transform = req.Transform
+	}
 	dataCollection.IsActive = req.IsActive
 
 	if err := s.dataCollectionRepo.Update(dataCollection); err != nil {
@@ -211,19 +294,32 @@ func (s *DataCollectionService) DeleteDataCollection(id uint) error {
 }
 
 type CollectDataService struct {
-	dataRecordRepo *repository.DataRecordRepository
+	dataRecordRepo     *repository.DataRecordRepository
+	dataCollectionRepo *repository.DataCollectionRepository
+	// ndjsonMaxRows максимальное число записей в одной NDJSON выгрузке
+	ndjsonMaxRows int
 }
 
-func NewCollectDataService(dataRecordRepo *repository.DataRecordRepository) *CollectDataService {
+func NewCollectDataService(dataRecordRepo *repository.DataRecordRepository, dataCollectionRepo *repository.DataCollectionRepository, ndjsonMaxRows int) *CollectDataService {
 	return &CollectDataService{
-		dataRecordRepo: dataRecordRepo,
+		dataRecordRepo:     dataRecordRepo,
+		dataCollectionRepo: dataCollectionRepo,
+		ndjsonMaxRows:      ndjsonMaxRows,
 	}
 }
 
 func (s *CollectDataService) CollectData(req *models.DataCollectRequest) (*models.CollectDataResponse, error) {
+	rawData := `{"collected": true, "timestamp": "2024-01-01T00:00:00Z"}`
+
+	data, err := s.applyTransform(req.CollectionID, rawData)
+	if err != nil {
+		return nil, err
+	}
+
 	dataRecord := &models.DataRecord{
 		CollectionID: req.CollectionID,
-		Data:         `{"collected": true, "timestamp": "2024-01-01T00:00:00Z"}`,
+		Data:         data,
+		RawData:      rawData,
 		Metadata:     `{"source": "simulation", "parameters": "test"}`,
 	}
 
@@ -237,6 +333,42 @@ func (s *CollectDataService) CollectData(req *models.DataCollectRequest) (*model
 	}, nil
 }
 
+// applyTransform применяет преобразование сбора данных к сырой записи.
+// Без преобразования запись возвращается как есть.
+func (s *CollectDataService) applyTransform(collectionID uint, rawData string) (string, error) {
+	dataCollection, err := s.dataCollectionRepo.GetByID(collectionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return rawData, nil
+		}
+		return "", fmt.Errorf("ошибка получения сбора данных: %w", err)
+	}
+
+	spec, err := transform.Parse(dataCollection.Transform)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidTransform, err)
+	}
+	if spec == nil {
+		return rawData, nil
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(rawData), &record); err != nil {
+		return "", fmt.Errorf("ошибка разбора записи данных: %w", err)
+	}
+
+	transformed, err := spec.Apply(record)
+	if err != nil {
+		return "", fmt.Errorf("ошибка преобразования записи данных: %w", err)
+	}
+
+	data, err := json.Marshal(transformed)
+	if err != nil {
+		return "", fmt.Errorf("ошибка сериализации записи данных: %w", err)
+	}
+	return string(data), nil
+}
+
 func (s *CollectDataService) GetDataRecords(page, limit int, collectionID uint) ([]models.DataRecordResponse, int64, error) {
 	dataRecords, total, err := s.dataRecordRepo.GetAll(page, limit, collectionID)
 	if err != nil {
@@ -251,11 +383,84 @@ func (s *CollectDataService) GetDataRecords(page, limit int, collectionID uint)
 	return responses, total, nil
 }
 
+// maxExportRecords максимальное число записей в одной CSV выгрузке
+const maxExportRecords = 10000
+
+// ExportDataRecordsCSV выгружает записи данных в CSV
+func (s *CollectDataService) ExportDataRecordsCSV(w io.Writer, collectionID uint) error {
+	dataRecords, err := s.dataRecordRepo.GetForExport(collectionID, maxExportRecords)
+	if err != nil {
+		return fmt.Errorf("ошибка получения записей данных: %w", err)
+	}
+
+	headers := []string{"ID", "Collection ID", "Data", "Metadata", "Processed At", "Created At"}
+	rows := make([][]string, len(dataRecords))
+	for i, dr := range dataRecords {
+		processedAt := ""
+		if dr.ProcessedAt != nil {
+			processedAt = dr.ProcessedAt.Format(time.RFC3339)
+		}
+		rows[i] = []string{
+			strconv.FormatUint(uint64(dr.ID), 10),
+			strconv.FormatUint(uint64(dr.CollectionID), 10),
+			dr.Data,
+			dr.Metadata,
+			processedAt,
+			dr.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	return csvutil.WriteRecords(w, headers, rows)
+}
+
+// ndjsonBatchSize число записей, читаемых из БД за один запрос при NDJSON выгрузке
+const ndjsonBatchSize = 500
+
+// ExportDataRecordsNDJSON пишет записи данных в w по одной JSON записи на строку,
+// читая БД страницами, и останавливается на ndjsonMaxRows записях.
+// Если w поддерживает Flush, буфер сбрасывается после каждой страницы.
+// Возвращает число записанных записей.
+func (s *CollectDataService) ExportDataRecordsNDJSON(w io.Writer, collectionID uint) (int, error) {
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(interface{ Flush() })
+
+	written := 0
+	var afterID uint
+	for written < s.ndjsonMaxRows {
+		batch := ndjsonBatchSize
+		if remaining := s.ndjsonMaxRows - written; remaining < batch {
+			batch = remaining
+		}
+
+		dataRecords, err := s.dataRecordRepo.GetPageAfter(collectionID, afterID, batch)
+		if err != nil {
+			return written, fmt.Errorf("ошибка получения записей данных: %w", err)
+		}
+
+		for _, dr := range dataRecords {
+			if err := encoder.Encode(dr.ToResponse()); err != nil {
+				return written, fmt.Errorf("ошибка записи NDJSON: %w", err)
+			}
+			written++
+			afterID = dr.ID
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if len(dataRecords) < batch {
+			break
+		}
+	}
+
+	return written, nil
+}
+
 func (s *CollectDataService) GetDataRecord(id uint) (*models.DataRecordResponse, error) {
 	dataRecord, err := s.dataRecordRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("запись данных не найдена")
+			return nil, ErrDataRecordNotFound
 		}
 		return nil, fmt.Errorf("ошибка получения записи данных: %w", err)
 	}
@@ -263,3 +468,24 @@ func (s *CollectDataService) GetDataRecord(id uint) (*models.DataRecordResponse,
 	response := dataRecord.ToResponse()
 	return &response, nil
 }
+
+// DeleteDataRecord удаляет запись данных по ID
+func (s *CollectDataService) DeleteDataRecord(id uint) (int64, error) {
+	deleted, err := s.dataRecordRepo.Delete(id)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления записи данных: %w", err)
+	}
+	if deleted == 0 {
+		return 0, ErrDataRecordNotFound
+	}
+	return deleted, nil
+}
+
+// DeleteDataRecords удаляет все записи сбора данных
+func (s *CollectDataService) DeleteDataRecords(collectionID uint) (int64, error) {
+	deleted, err := s.dataRecordRepo.DeleteByCollectionID(collectionID)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления записей данных: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,168 @@
+package transform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые приведения типов
+const (
+	CastString = "string"
+	CastInt    = "int"
+	CastFloat  = "float"
+	CastBool   = "bool"
+	CastTime   = "time" // RFC3339
+)
+
+// FieldRule переносит значение поля From (допускается путь через точку) в поле To
+// с необязательным приведением типа
+type FieldRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Cast string `json:"cast,omitempty"`
+}
+
+// Spec описание преобразования записи сбора данных
+type Spec struct {
+	Fields []FieldRule `json:"fields"`
+	// DropUnmapped оставляет в результате только поля из Fields
+	DropUnmapped bool `json:"drop_unmapped,omitempty"`
+}
+
+// Parse разбирает и проверяет JSON описание преобразования.
+// Пустая строка означает отсутствие преобразования и возвращает nil.
+func Parse(raw string) (*Spec, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var spec Spec
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("некорректный JSON преобразования: %w", err)
+	}
+	if len(spec.Fields) == 0 {
+		return nil, errors.New("преобразование должно содержать хотя бы одно поле")
+	}
+
+	targets := make(map[string]bool, len(spec.Fields))
+	for i := range spec.Fields {
+		rule := &spec.Fields[i]
+		if rule.From == "" {
+			return nil, fmt.Errorf("поле %d: не указан from", i)
+		}
+		if rule.To == "" {
+			rule.To = rule.From
+		}
+		if targets[rule.To] {
+			return nil, fmt.Errorf("поле %d: повторяющееся имя результата %q", i, rule.To)
+		}
+		targets[rule.To] = true
+
+		switch rule.Cast {
+		case "", CastString, CastInt, CastFloat, CastBool, CastTime:
+		default:
+			return nil, fmt.Errorf("поле %d: неизвестное приведение %q", i, rule.Cast)
+		}
+	}
+
+	return &spec, nil
+}
+
+// Apply применяет преобразование к записи и возвращает новую запись.
+// Отсутствующие в записи поля пропускаются, ошибка приведения возвращается.
+func (s *Spec) Apply(record map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(record))
+	if !s.DropUnmapped {
+		for k, v := range record {
+			result[k] = v
+		}
+	}
+
+	for _, rule := range s.Fields {
+		value, ok := lookup(record, rule.From)
+		if !ok {
+			continue
+		}
+
+		casted, err := cast(value, rule.Cast)
+		if err != nil {
+			return nil, fmt.Errorf("поле %q: %w", rule.From, err)
+		}
+
+		if !s.DropUnmapped && !strings.Contains(rule.From, ".") {
+			delete(result, rule.From)
+		}
+		result[rule.To] = casted
+	}
+
+	return result, nil
+}
+
+// lookup получает значение по пути вида a.b.c
+func lookup(record map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = record
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// cast приводит значение к указанному типу
+func cast(value interface{}, to string) (interface{}, error) {
+	if to == "" || value == nil {
+		return value, nil
+	}
+
+	text := fmt.Sprint(value)
+	switch to {
+	case CastString:
+		return text, nil
+	case CastInt:
+		if f, ok := value.(float64); ok {
+			return int64(f), nil
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("значение %q не является целым числом", text)
+		}
+		return n, nil
+	case CastFloat:
+		if f, ok := value.(float64); ok {
+			return f, nil
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("значение %q не является числом", text)
+		}
+		return f, nil
+	case CastBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("значение %q не является логическим", text)
+		}
+		return b, nil
+	case CastTime:
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("значение %q не является временем RFC3339", text)
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	default:
+		return nil, fmt.Errorf("неизвестное приведение %q", to)
+	}
+}
@@ -0,0 +1,108 @@
+package transform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRejectsInvalidSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{"некорректный JSON", `{"fields": [`, "некорректный JSON"},
+		{"неизвестное поле", `{"fields": [{"from": "a"}], "rename": true}`, "некорректный JSON"},
+		{"без полей", `{"fields": []}`, "хотя бы одно поле"},
+		{"без from", `{"fields": [{"to": "a"}]}`, "не указан from"},
+		{"повтор результата", `{"fields": [{"from": "a", "to": "c"}, {"from": "b", "to": "c"}]}`, "повторяющееся имя"},
+		{"неизвестное приведение", `{"fields": [{"from": "a", "cast": "decimal"}]}`, "неизвестное приведение"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.raw); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ошибка %v, ожидалась содержащая %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if spec, err := Parse("  "); spec != nil || err != nil {
+		t.Errorf("пустое преобразование: %v, %v", spec, err)
+	}
+}
+
+func TestApplyRenamesAndCasts(t *testing.T) {
+	spec, err := Parse(`{"fields": [
+		{"from": "amount", "to": "sum", "cast": "float"},
+		{"from": "qty", "cast": "int"},
+		{"from": "paid", "to": "is_paid", "cast": "bool"},
+		{"from": "at", "to": "created_at", "cast": "time"},
+		{"from": "user.id", "to": "user_id", "cast": "string"},
+		{"from": "missing", "to": "absent"}
+	]}`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	record := map[string]interface{}{
+		"amount": "12.5",
+		"qty":    " 3 ",
+		"paid":   "true",
+		"at":     "2024-05-01T15:00:00+03:00",
+		"user":   map[string]interface{}{"id": float64(7)},
+		"note":   "без изменений",
+	}
+	got, err := spec.Apply(record)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	// Переименованные поля заменяют исходные, вложенный источник и прочие поля сохраняются
+	want := map[string]interface{}{
+		"sum":        12.5,
+		"qty":        int64(3),
+		"is_paid":    true,
+		"created_at": "2024-05-01T12:00:00Z",
+		"user_id":    "7",
+		"user":       map[string]interface{}{"id": float64(7)},
+		"note":       "без изменений",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("результат %v, ожидалось %v", got, want)
+	}
+	if _, ok := record["sum"]; ok {
+		t.Error("исходная запись изменена")
+	}
+
+	spec.DropUnmapped = true
+	got, err = spec.Apply(record)
+	if err != nil {
+		t.Fatalf("Apply с drop_unmapped: %v", err)
+	}
+	if len(got) != 5 || got["note"] != nil || got["user"] != nil {
+		t.Errorf("drop_unmapped оставил %v", got)
+	}
+}
+
+func TestApplyReportsCastErrors(t *testing.T) {
+	tests := []struct {
+		cast  string
+		value interface{}
+	}{
+		{CastInt, "три"},
+		{CastFloat, "12,5"},
+		{CastBool, "да"},
+		{CastTime, "01.05.2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cast, func(t *testing.T) {
+			spec := &Spec{Fields: []FieldRule{{From: "value", To: "value", Cast: tt.cast}}}
+			if _, err := spec.Apply(map[string]interface{}{"value": tt.value}); err == nil || !strings.Contains(err.Error(), `поле "value"`) {
+				t.Errorf("ошибка %v, ожидалась ошибка приведения поля value", err)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 
 	"data-service/internal/config"
 	"data-service/internal/database"
+	"data-service/internal/repository"
+	"data-service/internal/secrets"
 	"data-service/internal/server"
 
 	"github.com/sirupsen/logrus"
@@ -25,6 +27,7 @@ func main() {
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(migrateCmd)
 	rootCmd.AddCommand(cleanupCmd)
+	rootCmd.AddCommand(encryptSecretsCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		logrus.Fatal(err)
@@ -59,6 +62,16 @@ var cleanupCmd = &cobra.Command{
 	},
 }
 
+var encryptSecretsCmd = &cobra.Command{
+	Use:   "encrypt-secrets",
+	Short: "Шифрование конфигурации существующих источников данных",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := encryptSecrets(); err != nil {
+			logrus.Fatal("Ошибка шифрования секретов:", err)
+		}
+	},
+}
+
 func serve() {
 	var err error
 	cfg, err = config.Load()
@@ -89,3 +102,31 @@ func cleanup() error {
 
 	return database.CleanupWithConfig(cfg)
 }
+
+func encryptSecrets() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки конфигурации: %w", err)
+	}
+
+	cipher, err := secrets.NewCipher(cfg.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("ошибка инициализации шифрования: %w", err)
+	}
+	if cipher == nil {
+		return fmt.Errorf("ENCRYPTION_KEY не задан")
+	}
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
+	}
+
+	count, err := repository.NewDataSourceRepository(db, cipher).EncryptExisting()
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Зашифровано записей: %d", count)
+	return nil
+}
@@ -0,0 +1,123 @@
+package apperrors
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Машиночитаемые коды ошибок
+const (
+	CodeValidation   = "validation_error"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeInternal     = "internal_error"
+	CodeDelivery     = "delivery_failed"
+	CodeUnavailable  = "service_unavailable"
+	CodeUnsupported  = "unsupported_media_type"
+)
+
+// AppError ошибка приложения с кодом и безопасным сообщением
+type AppError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+	Status  int         `json:"-"`
+	Err     error       `json:"-"`
+}
+
+// Error возвращает текст ошибки
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap возвращает исходную ошибку
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails добавляет детали к ошибке
+func (e *AppError) WithDetails(details interface{}) *AppError {
+	e.Details = details
+	return e
+}
+
+// New создает новую ошибку приложения
+func New(status int, code, message string) *AppError {
+	return &AppError{
+		Code:    code,
+		Message: message,
+		Status:  status,
+	}
+}
+
+// Validation создает ошибку валидации
+func Validation(message string) *AppError {
+	return New(http.StatusBadRequest, CodeValidation, message)
+}
+
+// Unauthorized создает ошибку авторизации
+func Unauthorized(message string) *AppError {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden создает ошибку доступа
+func Forbidden(message string) *AppError {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound создает ошибку отсутствия ресурса
+func NotFound(message string) *AppError {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// Conflict создает ошибку конфликта состояния
+func Conflict(message string) *AppError {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+// Unavailable создает ошибку недоступности зависимого сервиса
+func Unavailable(message string) *AppError {
+	return New(http.StatusServiceUnavailable, CodeUnavailable, message)
+}
+
+// UnsupportedMediaType создает ошибку неподдерживаемого типа тела запроса
+func UnsupportedMediaType(message string) *AppError {
+	return New(http.StatusUnsupportedMediaType, CodeUnsupported, message)
+}
+
+// Internal создает внутреннюю ошибку, скрывая исходное сообщение от клиента
+func Internal(err error) *AppError {
+	appErr := New(http.StatusInternalServerError, CodeInternal, "Внутренняя ошибка сервера")
+	appErr.Err = err
+	return appErr
+}
+
+// DeliveryFailed создает ошибку отправки уведомления всем получателям;
+// статусы получателей передаются в Details
+func DeliveryFailed(err error) *AppError {
+	appErr := New(http.StatusBadGateway, CodeDelivery, "Не удалось отправить уведомление ни одному получателю")
+	appErr.Err = err
+	return appErr
+}
+
+// From приводит произвольную ошибку к AppError
+func From(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+// Respond записывает ошибку в ответ в едином формате
+func Respond(c *gin.Context, err error) {
+	appErr := From(err)
+	c.JSON(appErr.Status, gin.H{"error": appErr})
+}
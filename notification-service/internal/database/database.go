@@ -19,6 +19,8 @@ func Connect(databaseURL string) (*gorm.DB, error) {
 
 	db, err = gorm.Open(postgres.Open(databaseURL), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// Нарушения уникальности приходят как gorm.ErrDuplicatedKey
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("ошибка подключения к базе данных: %w", err)
@@ -41,19 +43,53 @@ func Migrate() error {
 		return fmt.Errorf("база данных не подключена")
 	}
 
+	if err := migrate(db); err != nil {
+		return err
+	}
+
+	log.Println("Миграции выполнены успешно")
+	return nil
+}
+
+// migrate приводит схему db к текущим моделям
+func migrate(db *gorm.DB) error {
+	renamed, err := dedupTemplateNames(db)
+	if err != nil {
+		return fmt.Errorf("ошибка устранения дубликатов имен шаблонов: %w", err)
+	}
+	if renamed > 0 {
+		log.Printf("Переименовано шаблонов с повторяющимися именами: %d", renamed)
+	}
+
 	// Миграция моделей
 	if err := db.AutoMigrate(
 		&models.NotificationTemplate{},
 		&models.Notification{},
 		&models.NotificationChannel{},
+		&models.ProcessedEvent{},
 	); err != nil {
 		return fmt.Errorf("ошибка миграции моделей: %w", err)
 	}
-
-	log.Println("Миграции выполнены успешно")
 	return nil
 }
 
+// dedupTemplateNames переименовывает шаблоны с повторяющимися именами перед созданием
+// уникального индекса по имени. Раньше при отсутствии шаблона по умолчанию каждый вызов
+// создавал новый "Report Ready", поэтому в старых базах имена повторяются. Самый ранний
+// шаблон сохраняет имя, к остальным добавляется их ID; записи не удаляются, так как на
+// них ссылаются уведомления.
+func dedupTemplateNames(db *gorm.DB) (int64, error) {
+	if !db.Migrator().HasTable(&models.NotificationTemplate{}) {
+		return 0, nil
+	}
+
+	first := db.Model(&models.NotificationTemplate{}).Select("MIN(id)").Group("name")
+	result := db.Model(&models.NotificationTemplate{}).
+		Where("id NOT IN (?)", first).
+		UpdateColumn("name", gorm.Expr("name || ' #' || id"))
+	return result.RowsAffected, result.Error
+}
+
 func SeedData() error {
 	if db == nil {
 		return fmt.Errorf("база данных не подключена")
@@ -93,6 +129,24 @@ func SeedData() error {
 			Variables: `["message", "user_id"]`,
 			IsActive:  true,
 		},
+		{
+			Name:      "Report Ready",
+			Key:       "report_ready",
+			Subject:   "Отчет готов",
+			Body:      "Отчет {{report_id}} сформирован и доступен для скачивания.",
+			Type:      "email",
+			Variables: `["report_id"]`,
+			IsActive:  true,
+		},
+		{
+			Name:      "Report Failed",
+			Key:       "report_failed",
+			Subject:   "Ошибка генерации отчета",
+			Body:      "Не удалось сформировать отчет {{report_id}}: {{error}}",
+			Type:      "email",
+			Variables: `["report_id", "error"]`,
+			IsActive:  true,
+		},
 	}
 
 	for _, t := range templates {
@@ -112,7 +166,7 @@ func SeedData() error {
 		{
 			Name:     "SMS Gateway",
 			Type:     "sms",
-			Config:   `{"provider": "twilio", "account_sid": "AC123", "auth_token": "token"}`,
+			Config:   `{"provider": "twilio", "account_sid": "AC123", "auth_token": "token", "from": "+15005550006"}`,
 			IsActive: true,
 		},
 		{
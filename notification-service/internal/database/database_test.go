@@ -0,0 +1,100 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notification-service/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// legacyTemplate схема шаблона уведомления до уникального индекса по имени
+type legacyTemplate struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	Subject   string `gorm:"not null"`
+	Body      string `gorm:"type:text"`
+	Type      string `gorm:"not null"`
+	Variables string `gorm:"type:text"`
+	IsActive  bool   `gorm:"default:true"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyTemplate) TableName() string {
+	return "notification_templates"
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "notifications.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestMigrateRenamesDuplicateTemplateNames(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&legacyTemplate{}); err != nil {
+		t.Fatalf("миграция старой схемы: %v", err)
+	}
+
+	// Старый SendNotification создавал "Report Ready" при каждом промахе поиска
+	for _, name := range []string{"Report Ready", "Welcome Email", "Report Ready", "Report Ready"} {
+		if err := db.Create(&legacyTemplate{Name: name, Subject: name, Type: "email", IsActive: true}).Error; err != nil {
+			t.Fatalf("создание шаблона %q: %v", name, err)
+		}
+	}
+
+	if err := migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	var templates []models.NotificationTemplate
+	if err := db.Order("id").Find(&templates).Error; err != nil {
+		t.Fatalf("чтение шаблонов: %v", err)
+	}
+	want := []string{"Report Ready", "Welcome Email", "Report Ready #3", "Report Ready #4"}
+	if len(templates) != len(want) {
+		t.Fatalf("шаблонов после миграции: %d, ожидалось %d", len(templates), len(want))
+	}
+	for i, template := range templates {
+		if template.Name != want[i] {
+			t.Errorf("шаблон %d: имя %q, ожидалось %q", template.ID, template.Name, want[i])
+		}
+	}
+
+	// Уникальный индекс создан и не пропускает новые дубликаты
+	err := db.Create(&models.NotificationTemplate{Name: "Report Ready", Subject: "s", Type: "email"}).Error
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("создание дубликата: %v, ожидалась gorm.ErrDuplicatedKey", err)
+	}
+}
+
+func TestMigrateOnEmptyDatabase(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if !db.Migrator().HasIndex(&models.NotificationTemplate{}, "idx_notification_templates_name") {
+		t.Error("уникальный индекс по имени шаблона не создан")
+	}
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ExchangeName exchange, через который сервисы обмениваются событиями
+const ExchangeName = "events"
+
+// Типы событий жизненного цикла уведомления
+const (
+	NotificationDelivered = "notification.delivered"
+	NotificationFailed    = "notification.failed"
+)
+
+// Event событие в формате, общем для всех сервисов
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Source    string                 `json:"source"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+// NewEvent создает событие notification-service
+func NewEvent(eventType string, data map[string]interface{}) *Event {
+	return &Event{
+		ID:        generateEventID(),
+		Type:      eventType,
+		Source:    "notification-service",
+		Timestamp: time.Now(),
+		Data:      data,
+		Metadata:  make(map[string]interface{}),
+	}
+}
+
+// Channel методы канала AMQP, через которые публикуются события
+type Channel interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+// Publish публикует событие в exchange events с ключом, равным типу события
+func Publish(ch Channel, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события: %w", err)
+	}
+
+	err = ch.Publish(ExchangeName, event.Type, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         body,
+		MessageId:    event.ID,
+		Timestamp:    event.Timestamp,
+		DeliveryMode: amqp.Persistent,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка публикации события %s: %w", event.Type, err)
+	}
+	return nil
+}
+
+// generateEventID генерирует уникальный ID события
+func generateEventID() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(buf)
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"crypto/subtle"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"notification-service/internal/apperrors"
 	"notification-service/internal/metrics"
 	"notification-service/internal/models"
 	"notification-service/internal/services"
@@ -31,7 +35,7 @@ func (h *NotificationTemplateHandler) CreateTemplate(c *gin.Context) {
 	var req models.NotificationTemplateCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.metrics.RecordBusinessOperation("notification-service", "create_template", time.Since(start), false)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
 		return
 	}
 
@@ -39,7 +43,7 @@ func (h *NotificationTemplateHandler) CreateTemplate(c *gin.Context) {
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка создания шаблона уведомления")
 		h.metrics.RecordBusinessOperation("notification-service", "create_template", time.Since(start), false)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -49,14 +53,16 @@ func (h *NotificationTemplateHandler) CreateTemplate(c *gin.Context) {
 
 // GetTemplates получение списка шаблонов уведомлений
 func (h *NotificationTemplateHandler) GetTemplates(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 	active := c.Query("active")
 
 	templates, total, err := h.templateService.GetTemplates(page, limit, active)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка получения шаблонов уведомлений")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -73,14 +79,14 @@ func (h *NotificationTemplateHandler) GetTemplate(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID"))
 		return
 	}
 
 	template, err := h.templateService.GetTemplate(uint(id))
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка получения шаблона уведомления")
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -92,38 +98,56 @@ func (h *NotificationTemplateHandler) UpdateTemplate(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID"))
 		return
 	}
 
 	var req models.NotificationTemplateUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
 		return
 	}
 
 	template, err := h.templateService.UpdateTemplate(uint(id), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка обновления шаблона уведомления")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, template)
 }
 
+// BulkSetTemplatesActive массовое включение или отключение шаблонов уведомлений
+func (h *NotificationTemplateHandler) BulkSetTemplatesActive(c *gin.Context) {
+	var req models.NotificationTemplateBulkActiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
+		return
+	}
+
+	result, err := h.templateService.SetTemplatesActive(req.IDs, *req.IsActive)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка массового изменения активности шаблонов уведомлений")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // DeleteTemplate удаление шаблона уведомления
 func (h *NotificationTemplateHandler) DeleteTemplate(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID"))
 		return
 	}
 
 	if err := h.templateService.DeleteTemplate(uint(id)); err != nil {
 		logrus.WithError(err).Error("Ошибка удаления шаблона уведомления")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -132,11 +156,13 @@ func (h *NotificationTemplateHandler) DeleteTemplate(c *gin.Context) {
 
 type NotificationHandler struct {
 	notificationService *services.NotificationService
+	webhookSecret       string
 }
 
-func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+func NewNotificationHandler(notificationService *services.NotificationService, webhookSecret string) *NotificationHandler {
 	return &NotificationHandler{
 		notificationService: notificationService,
+		webhookSecret:       webhookSecret,
 	}
 }
 
@@ -144,14 +170,25 @@ func NewNotificationHandler(notificationService *services.NotificationService) *
 func (h *NotificationHandler) SendNotification(c *gin.Context) {
 	var req models.NotificationCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
+		return
+	}
+
+	if req.DryRun || c.Query("dry_run") == "true" {
+		preview, err := h.notificationService.PreviewNotification(&req)
+		if err != nil {
+			logrus.WithError(err).Error("Ошибка пробной отправки уведомления")
+			apperrors.Respond(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, preview)
 		return
 	}
 
 	result, err := h.notificationService.SendNotification(&req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка отправки уведомления")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -160,15 +197,22 @@ func (h *NotificationHandler) SendNotification(c *gin.Context) {
 
 // GetNotifications получение списка уведомлений
 func (h *NotificationHandler) GetNotifications(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	status := c.Query("status")
-	recipient := c.Query("recipient")
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	filter := models.NotificationFilter{
+		Status:        c.Query("status"),
+		Recipient:     c.Query("recipient"),
+		Type:          c.Query("type"),
+		Query:         strings.TrimSpace(c.Query("q")),
+		CorrelationID: c.Query("correlation_id"),
+	}
 
-	notifications, total, err := h.notificationService.GetNotifications(page, limit, status, recipient)
+	notifications, total, err := h.notificationService.GetNotifications(page, limit, filter)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка получения уведомлений")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -180,19 +224,43 @@ func (h *NotificationHandler) GetNotifications(c *gin.Context) {
 	})
 }
 
+// GetNotificationStats статистика уведомлений по статусам и типам, период задается from/to в RFC3339
+func (h *NotificationHandler) GetNotificationStats(c *gin.Context) {
+	var filter models.NotificationStatsFilter
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				apperrors.Respond(c, apperrors.Validation("Некорректный параметр "+param+", ожидается RFC3339"))
+				return
+			}
+			*target = &t
+		}
+	}
+
+	stats, err := h.notificationService.GetNotificationStats(filter)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения статистики уведомлений")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
 // GetNotification получение уведомления по ID
 func (h *NotificationHandler) GetNotification(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID"))
 		return
 	}
 
 	notification, err := h.notificationService.GetNotification(uint(id))
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка получения уведомления")
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -204,7 +272,7 @@ func (h *NotificationHandler) UpdateNotificationStatus(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID"))
 		return
 	}
 
@@ -213,20 +281,73 @@ func (h *NotificationHandler) UpdateNotificationStatus(c *gin.Context) {
 		ErrorMessage string `json:"error_message"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
 		return
 	}
 
 	notification, err := h.notificationService.UpdateNotificationStatus(uint(id), req.Status, req.ErrorMessage)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка обновления статуса уведомления")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// ResendNotification повторная отправка неудачного уведомления
+func (h *NotificationHandler) ResendNotification(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID"))
+		return
+	}
+
+	notification, err := h.notificationService.ResendNotification(uint(id))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка повторной отправки уведомления")
+		apperrors.Respond(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, notification)
 }
 
+// maxDeliveryCallbackBatch максимальный размер пакета от провайдера
+const maxDeliveryCallbackBatch = 500
+
+// DeliveryCallbackBatch применяет пакет результатов доставки от провайдера
+func (h *NotificationHandler) DeliveryCallbackBatch(c *gin.Context) {
+	if h.webhookSecret == "" {
+		apperrors.Respond(c, apperrors.Forbidden("Приём callback от провайдеров не настроен"))
+		return
+	}
+	secret := c.GetHeader("X-Webhook-Secret")
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(h.webhookSecret)) != 1 {
+		apperrors.Respond(c, apperrors.Unauthorized("Некорректный секрет вебхука"))
+		return
+	}
+
+	var items []models.DeliveryCallbackItem
+	if err := c.ShouldBindJSON(&items); err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
+		return
+	}
+	if len(items) == 0 || len(items) > maxDeliveryCallbackBatch {
+		apperrors.Respond(c, apperrors.Validation(fmt.Sprintf("Пакет должен содержать от 1 до %d элементов", maxDeliveryCallbackBatch)))
+		return
+	}
+
+	result, err := h.notificationService.ApplyDeliveryCallbacks(items)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка применения результатов доставки")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 type NotificationChannelHandler struct {
 	channelService *services.NotificationChannelService
 }
@@ -241,14 +362,14 @@ func NewNotificationChannelHandler(channelService *services.NotificationChannelS
 func (h *NotificationChannelHandler) CreateChannel(c *gin.Context) {
 	var req models.NotificationChannelCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
 		return
 	}
 
-	channel, err := h.channelService.CreateChannel(&req)
+	channel, err := h.channelService.CreateChannel(c.GetUint("user_id"), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка создания канала уведомлений")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -257,14 +378,16 @@ func (h *NotificationChannelHandler) CreateChannel(c *gin.Context) {
 
 // GetChannels получение списка каналов уведомлений
 func (h *NotificationChannelHandler) GetChannels(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 	active := c.Query("active")
 
 	channels, total, err := h.channelService.GetChannels(page, limit, active)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка получения каналов уведомлений")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -281,14 +404,14 @@ func (h *NotificationChannelHandler) GetChannel(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID"))
 		return
 	}
 
 	channel, err := h.channelService.GetChannel(uint(id))
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка получения канала уведомлений")
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -300,20 +423,20 @@ func (h *NotificationChannelHandler) UpdateChannel(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID"))
 		return
 	}
 
 	var req models.NotificationChannelUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
 		return
 	}
 
-	channel, err := h.channelService.UpdateChannel(uint(id), &req)
+	channel, err := h.channelService.UpdateChannel(uint(id), c.GetUint("user_id"), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка обновления канала уведомлений")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -324,13 +447,13 @@ func (h *NotificationChannelHandler) DeleteChannel(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID"))
 		return
 	}
 
 	if err := h.channelService.DeleteChannel(uint(id)); err != nil {
 		logrus.WithError(err).Error("Ошибка удаления канала уведомлений")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -1,10 +1,16 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
+	"notification-service/internal/apperrors"
+	"notification-service/internal/jwt"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -45,6 +51,37 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// RequireJSON отвечает 415 на POST/PUT/PATCH запросы с телом, у которых Content-Type
+// не application/json. Маршруты из exempt (в виде c.FullPath()) не проверяются
+func RequireJSON(exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			c.Next()
+			return
+		}
+		if skip[c.FullPath()] || c.Request.ContentLength == 0 {
+			c.Next()
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			apperrors.Respond(c, apperrors.UnsupportedMediaType("Content-Type должен быть application/json"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
@@ -60,3 +97,49 @@ func RequestID() gin.HandlerFunc {
 func generateRequestID() string {
 	return fmt.Sprintf("req_%d", time.Now().UnixNano())
 }
+
+// AuthMiddleware проверяет JWT или, если apiTokens задан, API токен пользователя и
+// сохраняет данные пользователя в контексте
+func AuthMiddleware(jwtManager *jwt.Manager, apiTokens *APITokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			apperrors.Respond(c, apperrors.Unauthorized("Требуется токен авторизации"))
+			c.Abort()
+			return
+		}
+
+		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+		if apiTokens != nil && strings.HasPrefix(tokenString, APITokenPrefix) {
+			identity, err := apiTokens.Verify(c.Request.Context(), tokenString)
+			if err != nil {
+				if errors.Is(err, ErrInvalidAPIToken) {
+					apperrors.Respond(c, apperrors.Unauthorized("Недействительный токен"))
+				} else {
+					logrus.WithError(err).Error("Ошибка проверки API токена")
+					apperrors.Respond(c, apperrors.Unavailable("Проверка токена недоступна"))
+				}
+				c.Abort()
+				return
+			}
+
+			setAPITokenIdentity(c, identity)
+			c.Next()
+			return
+		}
+
+		claims, err := jwtManager.ValidateToken(tokenString)
+		if err != nil {
+			apperrors.Respond(c, apperrors.Unauthorized("Недействительный токен"))
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("name", claims.Name)
+		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
+
+		c.Next()
+	}
+}
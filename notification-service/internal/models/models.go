@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -9,7 +10,8 @@ import (
 // NotificationTemplate модель шаблона уведомления
 type NotificationTemplate struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"not null"`
+	Name      string         `json:"name" gorm:"not null;uniqueIndex:idx_notification_templates_name,where:deleted_at IS NULL"`
+	Key       string         `json:"key" gorm:"index"` // ключ для выбора шаблона по событию, например report_failed
 	Subject   string         `json:"subject" gorm:"not null"`
 	Body      string         `json:"body" gorm:"type:text"`
 	Type      string         `json:"type" gorm:"not null"`       // email, sms, push, webhook
@@ -26,20 +28,27 @@ func (NotificationTemplate) TableName() string {
 
 // Notification модель уведомления
 type Notification struct {
-	ID           uint           `json:"id" gorm:"primaryKey"`
-	TemplateID   uint           `json:"template_id" gorm:"not null"`
-	Recipient    string         `json:"recipient" gorm:"not null"` // email, phone, user_id
-	Subject      string         `json:"subject"`
-	Body         string         `json:"body" gorm:"type:text"`
-	Type         string         `json:"type" gorm:"not null"`            // email, sms, push, webhook
-	Status       string         `json:"status" gorm:"default:'pending'"` // pending, sent, failed, delivered
-	Data         string         `json:"data" gorm:"type:text"`           // JSON данные для подстановки
-	SentAt       *time.Time     `json:"sent_at"`
-	DeliveredAt  *time.Time     `json:"delivered_at"`
-	ErrorMessage string         `json:"error_message"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	TemplateID    uint       `json:"template_id" gorm:"not null"`
+	ChannelID     uint       `json:"channel_id"`                                                                // 0, если канал не указан
+	Recipient     string     `json:"recipient" gorm:"not null;uniqueIndex:idx_notifications_message_recipient"` // email, phone, user_id, токен устройства
+	ProviderID    string     `json:"provider_id" gorm:"index"`                                                  // ID сообщения у провайдера доставки
+	CorrelationID string     `json:"correlation_id" gorm:"index"`                                               // связь с отчетом и Saga report-service
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body" gorm:"type:text"`
+	Type          string     `json:"type" gorm:"not null"`            // email, sms, push, webhook
+	Status        string     `json:"status" gorm:"default:'pending'"` // pending, sent, failed, delivered
+	Data          string     `json:"data" gorm:"type:text"`           // JSON данные для подстановки
+	SentAt        *time.Time `json:"sent_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	ErrorMessage  string     `json:"error_message"`
+	RetryCount    int        `json:"retry_count" gorm:"not null;default:0"`
+	MessageID     string     `json:"message_id" gorm:"size:255;uniqueIndex:idx_notifications_message_recipient,where:message_id <> '' AND deleted_at IS NULL"`
+	// AttachmentFileID файл Storage Service, прикладываемый к письму; 0 — без вложения
+	AttachmentFileID uint           `json:"attachment_file_id,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 func (Notification) TableName() string {
@@ -53,6 +62,8 @@ type NotificationChannel struct {
 	Type      string         `json:"type" gorm:"not null"`    // email, sms, push, webhook
 	Config    string         `json:"config" gorm:"type:text"` // JSON конфигурация
 	IsActive  bool           `json:"is_active" gorm:"default:true"`
+	CreatedBy uint           `json:"created_by"` // ID пользователя, создавшего канал
+	UpdatedBy uint           `json:"updated_by"` // ID пользователя, последним изменившего канал
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -62,8 +73,20 @@ func (NotificationChannel) TableName() string {
 	return "notification_channels"
 }
 
+// ProcessedEvent событие брокера, по которому уже создано уведомление
+type ProcessedEvent struct {
+	EventKey  string    `json:"event_key" gorm:"primaryKey"`
+	EventType string    `json:"event_type" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (ProcessedEvent) TableName() string {
+	return "processed_events"
+}
+
 type NotificationTemplateCreateRequest struct {
 	Name      string `json:"name" binding:"required"`
+	Key       string `json:"key"`
 	Subject   string `json:"subject" binding:"required"`
 	Body      string `json:"body"`
 	Type      string `json:"type" binding:"required"`
@@ -73,6 +96,7 @@ type NotificationTemplateCreateRequest struct {
 
 type NotificationTemplateUpdateRequest struct {
 	Name      string `json:"name"`
+	Key       string `json:"key"`
 	Subject   string `json:"subject"`
 	Body      string `json:"body"`
 	Type      string `json:"type"`
@@ -81,10 +105,18 @@ type NotificationTemplateUpdateRequest struct {
 }
 
 type NotificationCreateRequest struct {
-	TemplateID uint                   `json:"template_id" binding:"required"`
-	Recipient  string                 `json:"recipient" binding:"required"`
-	Data       map[string]interface{} `json:"data"`
-	Type       string                 `json:"type"`
+	TemplateID    uint                   `json:"template_id"`
+	TemplateKey   string                 `json:"template_key"` // альтернатива template_id: выбор шаблона по ключу
+	Recipient     string                 `json:"recipient"`
+	Recipients    []string               `json:"recipients"`
+	ChannelID     uint                   `json:"channel_id"` // канал отправки с ограничением скорости
+	DryRun        bool                   `json:"dry_run"`    // только отрендерить, ничего не сохраняя
+	Data          map[string]interface{} `json:"data"`
+	Type          string                 `json:"type"`
+	MessageID     string                 `json:"message_id" binding:"max=255"`
+	CorrelationID string                 `json:"correlation_id"` // сквозной ID отчета report-service
+	// AttachmentFileID ID файла в Storage Service, который прикладывается к письму email канала
+	AttachmentFileID uint `json:"attachment_file_id"`
 }
 
 type NotificationChannelCreateRequest struct {
@@ -104,6 +136,7 @@ type NotificationChannelUpdateRequest struct {
 type NotificationTemplateResponse struct {
 	ID        uint      `json:"id"`
 	Name      string    `json:"name"`
+	Key       string    `json:"key"`
 	Subject   string    `json:"subject"`
 	Body      string    `json:"body"`
 	Type      string    `json:"type"`
@@ -117,6 +150,7 @@ func (nt *NotificationTemplate) ToResponse() NotificationTemplateResponse {
 	return NotificationTemplateResponse{
 		ID:        nt.ID,
 		Name:      nt.Name,
+		Key:       nt.Key,
 		Subject:   nt.Subject,
 		Body:      nt.Body,
 		Type:      nt.Type,
@@ -128,36 +162,49 @@ func (nt *NotificationTemplate) ToResponse() NotificationTemplateResponse {
 }
 
 type NotificationResponse struct {
-	ID           uint       `json:"id"`
-	TemplateID   uint       `json:"template_id"`
-	Recipient    string     `json:"recipient"`
-	Subject      string     `json:"subject"`
-	Body         string     `json:"body"`
-	Type         string     `json:"type"`
-	Status       string     `json:"status"`
-	Data         string     `json:"data"`
-	SentAt       *time.Time `json:"sent_at"`
-	DeliveredAt  *time.Time `json:"delivered_at"`
-	ErrorMessage string     `json:"error_message"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID            uint       `json:"id"`
+	TemplateID    uint       `json:"template_id"`
+	ChannelID     uint       `json:"channel_id,omitempty"`
+	Recipient     string     `json:"recipient"`
+	ProviderID    string     `json:"provider_id,omitempty"`
+	CorrelationID string     `json:"correlation_id,omitempty"`
+	MessageID     string     `json:"message_id,omitempty"`
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body"`
+	Type          string     `json:"type"`
+	Status        string     `json:"status"`
+	Data          string     `json:"data"`
+	SentAt        *time.Time `json:"sent_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	ErrorMessage  string     `json:"error_message"`
+	RetryCount    int        `json:"retry_count"`
+	// AttachmentFileID файл Storage Service, приложенный к письму
+	AttachmentFileID uint      `json:"attachment_file_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
 
 func (n *Notification) ToResponse() NotificationResponse {
 	return NotificationResponse{
-		ID:           n.ID,
-		TemplateID:   n.TemplateID,
-		Recipient:    n.Recipient,
-		Subject:      n.Subject,
-		Body:         n.Body,
-		Type:         n.Type,
-		Status:       n.Status,
-		Data:         n.Data,
-		SentAt:       n.SentAt,
-		DeliveredAt:  n.DeliveredAt,
-		ErrorMessage: n.ErrorMessage,
-		CreatedAt:    n.CreatedAt,
-		UpdatedAt:    n.UpdatedAt,
+		ID:               n.ID,
+		TemplateID:       n.TemplateID,
+		ChannelID:        n.ChannelID,
+		Recipient:        n.Recipient,
+		ProviderID:       n.ProviderID,
+		CorrelationID:    n.CorrelationID,
+		MessageID:        n.MessageID,
+		Subject:          n.Subject,
+		Body:             n.Body,
+		Type:             n.Type,
+		Status:           n.Status,
+		Data:             n.Data,
+		SentAt:           n.SentAt,
+		DeliveredAt:      n.DeliveredAt,
+		ErrorMessage:     n.ErrorMessage,
+		RetryCount:       n.RetryCount,
+		AttachmentFileID: n.AttachmentFileID,
+		CreatedAt:        n.CreatedAt,
+		UpdatedAt:        n.UpdatedAt,
 	}
 }
 
@@ -167,6 +214,8 @@ type NotificationChannelResponse struct {
 	Type      string    `json:"type"`
 	Config    string    `json:"config"`
 	IsActive  bool      `json:"is_active"`
+	CreatedBy uint      `json:"created_by"`
+	UpdatedBy uint      `json:"updated_by"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -178,11 +227,25 @@ func (nc *NotificationChannel) ToResponse() NotificationChannelResponse {
 		Type:      nc.Type,
 		Config:    nc.Config,
 		IsActive:  nc.IsActive,
+		CreatedBy: nc.CreatedBy,
+		UpdatedBy: nc.UpdatedBy,
 		CreatedAt: nc.CreatedAt,
 		UpdatedAt: nc.UpdatedAt,
 	}
 }
 
+// NotificationTemplateBulkActiveRequest массовое включение или отключение шаблонов уведомлений
+type NotificationTemplateBulkActiveRequest struct {
+	IDs      []uint `json:"ids" binding:"required,min=1,max=100"`
+	IsActive *bool  `json:"is_active" binding:"required"`
+}
+
+// NotificationTemplateBulkActiveResponse результат массового изменения активности шаблонов
+type NotificationTemplateBulkActiveResponse struct {
+	Updated    []NotificationTemplateResponse `json:"updated"`
+	MissingIDs []uint                         `json:"missing_ids"`
+}
+
 type NotificationTemplatesResponse struct {
 	Templates []NotificationTemplateResponse `json:"templates"`
 	Total     int64                          `json:"total"`
@@ -190,6 +253,30 @@ type NotificationTemplatesResponse struct {
 	Limit     int                            `json:"limit"`
 }
 
+// NotificationFilter параметры фильтрации списка уведомлений
+type NotificationFilter struct {
+	Status        string
+	Recipient     string
+	Type          string // email, sms, push, webhook
+	Query         string // поиск по теме и тексту
+	CorrelationID string
+}
+
+// NotificationStatsFilter период, за который считается статистика уведомлений
+type NotificationStatsFilter struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// NotificationStatsResponse число уведомлений всего и в разбивке по статусам и типам
+type NotificationStatsResponse struct {
+	Total    int64            `json:"total"`
+	ByStatus map[string]int64 `json:"by_status"`
+	ByType   map[string]int64 `json:"by_type"`
+	From     *time.Time       `json:"from,omitempty"`
+	To       *time.Time       `json:"to,omitempty"`
+}
+
 type NotificationsResponse struct {
 	Notifications []NotificationResponse `json:"notifications"`
 	Total         int64                  `json:"total"`
@@ -204,8 +291,71 @@ type NotificationChannelsResponse struct {
 	Limit    int                           `json:"limit"`
 }
 
-type SendNotificationResponse struct {
-	NotificationID uint   `json:"notification_id"`
+// RecipientList возвращает уникальный список получателей запроса
+func (r *NotificationCreateRequest) RecipientList() []string {
+	seen := make(map[string]bool)
+	var recipients []string
+	for _, recipient := range append([]string{r.Recipient}, r.Recipients...) {
+		recipient = strings.TrimSpace(recipient)
+		if recipient == "" || seen[recipient] {
+			continue
+		}
+		seen[recipient] = true
+		recipients = append(recipients, recipient)
+	}
+	return recipients
+}
+
+// RecipientStatus результат отправки уведомления одному получателю
+type RecipientStatus struct {
+	Recipient      string `json:"recipient"`
+	NotificationID uint   `json:"notification_id,omitempty"`
 	Status         string `json:"status"`
-	Message        string `json:"message"`
+	Error          string `json:"error,omitempty"`
+	// Duplicate уведомление уже было создано ранее с тем же message_id
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+// NotificationPreviewResponse результат пробной отправки без сохранения
+type NotificationPreviewResponse struct {
+	DryRun      bool     `json:"dry_run"`
+	TemplateID  uint     `json:"template_id"`
+	Subject     string   `json:"subject"`
+	Body        string   `json:"body"`
+	Type        string   `json:"type"`
+	ChannelID   uint     `json:"channel_id,omitempty"`
+	ChannelName string   `json:"channel_name,omitempty"`
+	Recipients  []string `json:"recipients"`
+}
+
+// DeliveryCallbackItem результат доставки одного уведомления от провайдера
+type DeliveryCallbackItem struct {
+	ID         uint       `json:"id"`
+	ProviderID string     `json:"provider_id"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error"`
+	Timestamp  *time.Time `json:"timestamp"`
+}
+
+// DeliveryCallbackResult итог применения одного элемента пакета
+type DeliveryCallbackResult struct {
+	Index      int    `json:"index"`
+	ID         uint   `json:"id,omitempty"`
+	ProviderID string `json:"provider_id,omitempty"`
+	Outcome    string `json:"outcome"` // updated, not_found, invalid
+	Error      string `json:"error,omitempty"`
+}
+
+// DeliveryCallbackBatchResponse ответ на пакетный callback провайдера
+type DeliveryCallbackBatchResponse struct {
+	Results []DeliveryCallbackResult `json:"results"`
+	Updated int                      `json:"updated"`
+	Failed  int                      `json:"failed"`
+}
+
+type SendNotificationResponse struct {
+	NotificationID uint              `json:"notification_id"`
+	Status         string            `json:"status"`
+	Message        string            `json:"message"`
+	Recipients     []RecipientStatus `json:"recipients"`
 }
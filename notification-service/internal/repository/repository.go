@@ -1,9 +1,15 @@
 package repository
 
 import (
+	"errors"
+	"strings"
+
+	"notification-service/internal/database"
 	"notification-service/internal/models"
+	"notification-service/internal/secrets"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type NotificationTemplateRepository struct {
@@ -26,6 +32,13 @@ func (r *NotificationTemplateRepository) GetByID(id uint) (*models.NotificationT
 	return &template, err
 }
 
+// GetByKey получает активный шаблон уведомления по ключу
+func (r *NotificationTemplateRepository) GetByKey(key string) (*models.NotificationTemplate, error) {
+	var template models.NotificationTemplate
+	err := r.db.Where("key = ? AND is_active = ?", key, true).Order("id").First(&template).Error
+	return &template, err
+}
+
 // GetAll получает все шаблоны уведомлений с пагинацией
 func (r *NotificationTemplateRepository) GetAll(page, limit int, isActive *bool) ([]models.NotificationTemplate, int64, error) {
 	var templates []models.NotificationTemplate
@@ -45,11 +58,41 @@ func (r *NotificationTemplateRepository) GetAll(page, limit int, isActive *bool)
 	return templates, total, err
 }
 
+// FirstOrCreateByName находит шаблон с именем template.Name или создает его.
+// Поиск и создание выполняются в одной транзакции, а уникальный индекс по имени
+// не дает параллельным вызовам создать дубликаты
+func (r *NotificationTemplateRepository) FirstOrCreateByName(template *models.NotificationTemplate) error {
+	err := database.WithTransaction(r.db, func(tx *gorm.DB) error {
+		return tx.Where("name = ?", template.Name).FirstOrCreate(template).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Шаблон с этим именем успел создать параллельный запрос, транзакция откачена
+		template.ID = 0
+		return r.db.Where("name = ?", template.Name).First(template).Error
+	}
+	return err
+}
+
 // Update обновляет шаблон уведомления
 func (r *NotificationTemplateRepository) Update(template *models.NotificationTemplate) error {
 	return r.db.Save(template).Error
 }
 
+// SetActive изменяет активность шаблонов уведомлений из списка в одной транзакции
+// и возвращает найденные шаблоны после изменения
+func (r *NotificationTemplateRepository) SetActive(ids []uint, active bool) ([]models.NotificationTemplate, error) {
+	var templates []models.NotificationTemplate
+	err := database.WithTransaction(r.db, func(tx *gorm.DB) error {
+		if err := tx.Model(&models.NotificationTemplate{}).
+			Where("id IN ?", ids).
+			Update("is_active", active).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Find(&templates).Error
+	})
+	return templates, err
+}
+
 // Delete удаляет шаблон уведомления
 func (r *NotificationTemplateRepository) Delete(id uint) error {
 	return r.db.Delete(&models.NotificationTemplate{}, id).Error
@@ -76,17 +119,49 @@ func (r *NotificationRepository) GetByID(id uint) (*models.Notification, error)
 	return &notification, err
 }
 
+// GetByProviderID получает уведомление по ID сообщения у провайдера
+func (r *NotificationRepository) GetByProviderID(providerID string) (*models.Notification, error) {
+	var notification models.Notification
+	err := r.db.Where("provider_id = ?", providerID).First(&notification).Error
+	return &notification, err
+}
+
+// GetByMessageID получает уведомление по ID сообщения клиента и получателю
+func (r *NotificationRepository) GetByMessageID(messageID, recipient string) (*models.Notification, error) {
+	var notification models.Notification
+	err := r.db.Where("message_id = ? AND recipient = ?", messageID, recipient).First(&notification).Error
+	return &notification, err
+}
+
+// Transaction выполняет fn с репозиторием, работающим в одной транзакции
+func (r *NotificationRepository) Transaction(fn func(txRepo *NotificationRepository) error) error {
+	return database.WithTransaction(r.db, func(tx *gorm.DB) error {
+		return fn(&NotificationRepository{db: tx})
+	})
+}
+
 // GetAll получает все уведомления с пагинацией
-func (r *NotificationRepository) GetAll(page, limit int, status, recipient string) ([]models.Notification, int64, error) {
+func (r *NotificationRepository) GetAll(page, limit int, filter models.NotificationFilter) ([]models.Notification, int64, error) {
 	var notifications []models.Notification
 	var total int64
 
 	query := r.db.Model(&models.Notification{})
-	if status != "" {
-		query = query.Where("status = ?", status)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Recipient != "" {
+		query = query.Where("recipient = ?", filter.Recipient)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
 	}
-	if recipient != "" {
-		query = query.Where("recipient = ?", recipient)
+	if filter.CorrelationID != "" {
+		query = query.Where("correlation_id = ?", filter.CorrelationID)
+	}
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		like := likeOperator(r.db)
+		query = query.Where("subject "+like+" ? ESCAPE '\\' OR body "+like+" ? ESCAPE '\\'", pattern, pattern)
 	}
 
 	if err := query.Count(&total).Error; err != nil {
@@ -98,6 +173,33 @@ func (r *NotificationRepository) GetAll(page, limit int, status, recipient strin
 	return notifications, total, err
 }
 
+// CountGroupedBy считает уведомления за период в разбивке по значению колонки column
+func (r *NotificationRepository) CountGroupedBy(column string, filter models.NotificationStatsFilter) (map[string]int64, error) {
+	var rows []struct {
+		Name  string
+		Count int64
+	}
+
+	query := r.db.Model(&models.Notification{})
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	err := query.Select(column + " AS name, COUNT(*) AS count").Group(column).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Name] = row.Count
+	}
+	return counts, nil
+}
+
 // Update обновляет уведомление
 func (r *NotificationRepository) Update(notification *models.Notification) error {
 	return r.db.Save(notification).Error
@@ -109,22 +211,29 @@ func (r *NotificationRepository) Delete(id uint) error {
 }
 
 type NotificationChannelRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cipher *secrets.Cipher
 }
 
-func NewNotificationChannelRepository(db *gorm.DB) *NotificationChannelRepository {
-	return &NotificationChannelRepository{db: db}
+// NewNotificationChannelRepository создает репозиторий; Config шифруется при записи и расшифровывается при чтении
+func NewNotificationChannelRepository(db *gorm.DB, cipher *secrets.Cipher) *NotificationChannelRepository {
+	return &NotificationChannelRepository{db: db, cipher: cipher}
 }
 
 // Create создает новый канал уведомлений
 func (r *NotificationChannelRepository) Create(channel *models.NotificationChannel) error {
-	return r.db.Create(channel).Error
+	return r.saveEncrypted(channel, func() error {
+		return r.db.Create(channel).Error
+	})
 }
 
 // GetByID получает канал уведомлений по ID
 func (r *NotificationChannelRepository) GetByID(id uint) (*models.NotificationChannel, error) {
 	var channel models.NotificationChannel
-	err := r.db.First(&channel, id).Error
+	if err := r.db.First(&channel, id).Error; err != nil {
+		return &channel, err
+	}
+	err := r.decrypt(&channel)
 	return &channel, err
 }
 
@@ -143,16 +252,121 @@ func (r *NotificationChannelRepository) GetAll(page, limit int, isActive *bool)
 	}
 
 	offset := (page - 1) * limit
-	err := query.Offset(offset).Limit(limit).Find(&channels).Error
-	return channels, total, err
+	if err := query.Offset(offset).Limit(limit).Find(&channels).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for i := range channels {
+		if err := r.decrypt(&channels[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return channels, total, nil
 }
 
 // Update обновляет канал уведомлений
 func (r *NotificationChannelRepository) Update(channel *models.NotificationChannel) error {
-	return r.db.Save(channel).Error
+	return r.saveEncrypted(channel, func() error {
+		return r.db.Save(channel).Error
+	})
 }
 
 // Delete удаляет канал уведомлений
 func (r *NotificationChannelRepository) Delete(id uint) error {
 	return r.db.Delete(&models.NotificationChannel{}, id).Error
 }
+
+// EncryptExisting шифрует Config у записей, сохраненных до включения шифрования
+func (r *NotificationChannelRepository) EncryptExisting() (int, error) {
+	if r.cipher == nil {
+		return 0, errors.New("ключ шифрования не задан")
+	}
+
+	var items []models.NotificationChannel
+	if err := r.db.Unscoped().Find(&items).Error; err != nil {
+		return 0, err
+	}
+
+	encrypted := 0
+	for _, item := range items {
+		if item.Config == "" || secrets.IsEncrypted(item.Config) {
+			continue
+		}
+		value, err := r.cipher.Encrypt(item.Config)
+		if err != nil {
+			return encrypted, err
+		}
+		if err := r.db.Unscoped().Model(&models.NotificationChannel{}).Where("id = ?", item.ID).UpdateColumn("config", value).Error; err != nil {
+			return encrypted, err
+		}
+		encrypted++
+	}
+
+	return encrypted, nil
+}
+
+// saveEncrypted сохраняет запись с зашифрованным Config, оставляя в структуре открытое значение
+func (r *NotificationChannelRepository) saveEncrypted(channel *models.NotificationChannel, save func() error) error {
+	plaintext := channel.Config
+	encrypted, err := r.cipher.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	channel.Config = encrypted
+	err = save()
+	channel.Config = plaintext
+	return err
+}
+
+// decrypt расшифровывает Config прочитанной записи
+func (r *NotificationChannelRepository) decrypt(channel *models.NotificationChannel) error {
+	plaintext, err := r.cipher.Decrypt(channel.Config)
+	if err != nil {
+		return err
+	}
+	channel.Config = plaintext
+	return nil
+}
+
+type ProcessedEventRepository struct {
+	db *gorm.DB
+}
+
+// NewProcessedEventRepository создает репозиторий обработанных событий
+func NewProcessedEventRepository(db *gorm.DB) *ProcessedEventRepository {
+	return &ProcessedEventRepository{db: db}
+}
+
+// IsProcessed сообщает, отмечено ли событие обработанным
+func (r *ProcessedEventRepository) IsProcessed(eventKey string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.ProcessedEvent{}).Where("event_key = ?", eventKey).Count(&count).Error
+	return count > 0, err
+}
+
+// Claim отмечает событие обработанным. Возвращает false, если оно уже было отмечено.
+func (r *ProcessedEventRepository) Claim(eventKey, eventType string) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ProcessedEvent{
+		EventKey:  eventKey,
+		EventType: eventType,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// likeOperator оператор поиска без учета регистра: ILIKE есть только в PostgreSQL,
+// LIKE в SQLite и так не учитывает регистр латиницы
+func likeOperator(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"notification-service/internal/models"
+	"notification-service/internal/secrets"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB создает отдельную SQLite базу с мигрированными шаблонами, каналами и уведомлениями
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "notifications.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.AutoMigrate(&models.NotificationTemplate{}, &models.NotificationChannel{}, &models.Notification{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+	return db
+}
+
+// rawConfig читает колонку config в обход репозитория, то есть так, как она хранится в БД
+func rawConfig(t *testing.T, db *gorm.DB, id uint) string {
+	t.Helper()
+	var config string
+	if err := db.Unscoped().Model(&models.NotificationChannel{}).Where("id = ?", id).Pluck("config", &config).Error; err != nil {
+		t.Fatalf("чтение config: %v", err)
+	}
+	return config
+}
+
+func TestNotificationChannelConfigIsStoredEncrypted(t *testing.T) {
+	db := newTestDB(t)
+	cipher, err := secrets.NewCipher("test-key")
+	if err != nil {
+		t.Fatalf("создание шифра: %v", err)
+	}
+	repo := NewNotificationChannelRepository(db, cipher)
+
+	const config = `{"smtp_host":"smtp.example.com","password":"s3cret"}`
+	channel := &models.NotificationChannel{Name: "Почта", Type: "email", Config: config}
+	if err := repo.Create(channel); err != nil {
+		t.Fatalf("создание канала: %v", err)
+	}
+	if channel.Config != config {
+		t.Errorf("после сохранения Config в структуре %q, ожидалось открытое значение", channel.Config)
+	}
+
+	stored := rawConfig(t, db, channel.ID)
+	if !strings.HasPrefix(stored, "enc:v1:") || strings.Contains(stored, "s3cret") {
+		t.Fatalf("в БД хранится %q, ожидался шифротекст с префиксом enc:v1:", stored)
+	}
+
+	loaded, err := repo.GetByID(channel.ID)
+	if err != nil {
+		t.Fatalf("получение канала: %v", err)
+	}
+	if loaded.Config != config {
+		t.Errorf("расшифрован Config %q, ожидался %q", loaded.Config, config)
+	}
+
+	channels, _, err := repo.GetAll(1, 10, nil)
+	if err != nil || len(channels) != 1 || channels[0].Config != config {
+		t.Errorf("в списке каналов Config не расшифрован: %+v (%v)", channels, err)
+	}
+
+	// Обновление снова шифрует значение
+	loaded.Config = `{"smtp_host":"smtp.example.com","password":"n3w"}`
+	if err := repo.Update(loaded); err != nil {
+		t.Fatalf("обновление канала: %v", err)
+	}
+	if stored := rawConfig(t, db, channel.ID); !strings.HasPrefix(stored, "enc:v1:") || strings.Contains(stored, "n3w") {
+		t.Errorf("после обновления в БД хранится %q", stored)
+	}
+
+	// Другой ключ не расшифровывает значение
+	otherCipher, _ := secrets.NewCipher("other-key")
+	if _, err := NewNotificationChannelRepository(db, otherCipher).GetByID(channel.ID); err == nil {
+		t.Error("ожидалась ошибка расшифровки другим ключом")
+	}
+}
+
+func TestNotificationChannelEncryptExistingSkipsEncryptedRows(t *testing.T) {
+	db := newTestDB(t)
+
+	// Записи, сохраненные до включения шифрования
+	plain := &models.NotificationChannel{Name: "Вебхук", Type: "webhook", Config: `{"secret":"plain-secret"}`}
+	empty := &models.NotificationChannel{Name: "Push", Type: "push"}
+	if err := NewNotificationChannelRepository(db, nil).Create(plain); err != nil {
+		t.Fatalf("создание канала: %v", err)
+	}
+	if err := NewNotificationChannelRepository(db, nil).Create(empty); err != nil {
+		t.Fatalf("создание канала: %v", err)
+	}
+
+	cipher, _ := secrets.NewCipher("test-key")
+	repo := NewNotificationChannelRepository(db, cipher)
+	encrypted := &models.NotificationChannel{Name: "SMS", Type: "sms", Config: `{"api_key":"already"}`}
+	if err := repo.Create(encrypted); err != nil {
+		t.Fatalf("создание канала: %v", err)
+	}
+	encryptedBefore := rawConfig(t, db, encrypted.ID)
+
+	count, err := repo.EncryptExisting()
+	if err != nil {
+		t.Fatalf("шифрование записей: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("зашифровано записей: %d, ожидалась 1", count)
+	}
+
+	if stored := rawConfig(t, db, plain.ID); !strings.HasPrefix(stored, "enc:v1:") {
+		t.Errorf("открытый Config не зашифрован: %q", stored)
+	}
+	if stored := rawConfig(t, db, encrypted.ID); stored != encryptedBefore {
+		t.Error("уже зашифрованный Config зашифрован повторно")
+	}
+	if stored := rawConfig(t, db, empty.ID); stored != "" {
+		t.Errorf("пустой Config изменен: %q", stored)
+	}
+
+	loaded, err := repo.GetByID(plain.ID)
+	if err != nil || loaded.Config != `{"secret":"plain-secret"}` {
+		t.Errorf("после шифрования прочитан Config %q (%v)", loaded.Config, err)
+	}
+
+	if count, err := repo.EncryptExisting(); err != nil || count != 0 {
+		t.Errorf("повторный запуск зашифровал %d записей (%v), ожидалось 0", count, err)
+	}
+
+	if _, err := NewNotificationChannelRepository(db, nil).EncryptExisting(); err == nil {
+		t.Error("без ключа шифрования ожидалась ошибка")
+	}
+}
+
+func TestNotificationSearchMatchesSubjectAndBody(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewNotificationRepository(db)
+
+	for _, n := range []models.Notification{
+		{Recipient: "a@example.com", Subject: "Invoice ready", Body: "Your invoice #42 is ready", Status: "sent"},
+		{Recipient: "b@example.com", Subject: "Отчет готов", Body: "Отчет 42 готов", Status: "failed"},
+		{Recipient: "c@example.com", Subject: "Скидка 100%", Body: "Только сегодня", Status: "sent"},
+		{Recipient: "d@example.com", Subject: "Report", Body: "Done for 100 percent", Status: "sent"},
+	} {
+		n.TemplateID, n.Type = 1, "email"
+		if err := db.Create(&n).Error; err != nil {
+			t.Fatalf("создание уведомления: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter models.NotificationFilter
+		want   []string
+	}{
+		{"тема без учета регистра", models.NotificationFilter{Query: "INVOICE"}, []string{"a@example.com"}},
+		{"текст уведомления", models.NotificationFilter{Query: "42"}, []string{"a@example.com", "b@example.com"}},
+		{"вместе с фильтром статуса", models.NotificationFilter{Query: "42", Status: "failed"}, []string{"b@example.com"}},
+		// Спецсимволы LIKE ищутся буквально
+		{"знак процента", models.NotificationFilter{Query: "100%"}, []string{"c@example.com"}},
+		{"подчеркивание", models.NotificationFilter{Query: "_"}, nil},
+		{"нет совпадений", models.NotificationFilter{Query: "счет"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, total, err := repo.GetAll(1, 10, tt.filter)
+			if err != nil {
+				t.Fatalf("GetAll: %v", err)
+			}
+			var recipients []string
+			for _, n := range found {
+				recipients = append(recipients, n.Recipient)
+			}
+			sort.Strings(recipients)
+			if total != int64(len(tt.want)) || !reflect.DeepEqual(recipients, tt.want) {
+				t.Errorf("найдено %v (total %d), ожидалось %v", recipients, total, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotificationTransactionRollsBackOnError(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewNotificationRepository(db)
+
+	errSend := errors.New("отправка не удалась")
+	err := repo.Transaction(func(txRepo *NotificationRepository) error {
+		notification := &models.Notification{TemplateID: 1, Recipient: "user@example.com", Type: "email", Status: "pending"}
+		if err := txRepo.Create(notification); err != nil {
+			return err
+		}
+		notification.Status = "sent"
+		if err := txRepo.Update(notification); err != nil {
+			return err
+		}
+		return errSend
+	})
+	if !errors.Is(err, errSend) {
+		t.Fatalf("Transaction вернул %v, ожидалась ошибка fn", err)
+	}
+
+	var count int64
+	db.Model(&models.Notification{}).Count(&count)
+	if count != 0 {
+		t.Errorf("после отката осталось уведомлений: %d", count)
+	}
+}
+
+func TestFirstOrCreateByNameReturnsExisting(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewNotificationTemplateRepository(db)
+
+	first := &models.NotificationTemplate{Name: "Report Ready", Subject: "Отчет готов", Type: "email"}
+	if err := repo.FirstOrCreateByName(first); err != nil {
+		t.Fatalf("создание шаблона: %v", err)
+	}
+	second := &models.NotificationTemplate{Name: "Report Ready", Subject: "Другая тема", Type: "email"}
+	if err := repo.FirstOrCreateByName(second); err != nil {
+		t.Fatalf("повторный вызов: %v", err)
+	}
+
+	if second.ID != first.ID || second.Subject != "Отчет готов" {
+		t.Errorf("повторный вызов вернул шаблон %d %q, ожидался %d", second.ID, second.Subject, first.ID)
+	}
+	var count int64
+	db.Model(&models.NotificationTemplate{}).Count(&count)
+	if count != 1 {
+		t.Errorf("создано шаблонов: %d, ожидался 1", count)
+	}
+}
@@ -3,22 +3,26 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
-	"time"
 
 	"notification-service/internal/config"
 	"notification-service/internal/database"
+	"notification-service/internal/events"
 	"notification-service/internal/handlers"
 	"notification-service/internal/jwt"
 	"notification-service/internal/metrics"
 	"notification-service/internal/middleware"
 	"notification-service/internal/models"
 	"notification-service/internal/repository"
+	"notification-service/internal/secrets"
 	"notification-service/internal/services"
+	"notification-service/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -30,6 +34,7 @@ import (
 type Server struct {
 	cfg                 *config.Config
 	notificationService *services.NotificationService
+	emailSender         *services.EmailSender
 }
 
 // NewServer создает новый экземпляр сервера
@@ -43,6 +48,8 @@ func NewServer(cfg *config.Config) *Server {
 func (s *Server) Start() error {
 	// Настройка логгера
 	s.cfg.SetupLogger()
+	handlers.SetDefaultPageLimit(s.cfg.DefaultPageLimit)
+	handlers.SetMaxPageLimit(s.cfg.MaxPageLimit)
 
 	// Автомиграция если включена
 	if s.cfg.AutoMigrate {
@@ -52,7 +59,7 @@ func (s *Server) Start() error {
 	}
 
 	// Подключение к базе данных
-	db, err := database.Connect(s.cfg.DatabaseURL)
+	db, err := database.ConnectWithRetry(s.cfg.DatabaseURL, s.cfg.DBConnectAttempts, s.cfg.DBConnectInterval)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
@@ -61,8 +68,16 @@ func (s *Server) Start() error {
 	jwtManager := jwt.NewManager(s.cfg.JWTSecret)
 	metricsManager := metrics.NewMetrics("notification-service")
 
+	cipher, err := secrets.NewCipher(s.cfg.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("ошибка инициализации шифрования: %w", err)
+	}
+	if cipher == nil {
+		logrus.Warn("ENCRYPTION_KEY не задан, секреты в конфигурации хранятся в открытом виде")
+	}
+
 	// Создание роутера
-	router := s.setupRouter(db, jwtManager, metricsManager)
+	router := s.setupRouter(db, jwtManager, cipher, metricsManager)
 
 	// Запуск потребителя RabbitMQ (если URL указан)
 	if s.cfg.RabbitMQURL != "" {
@@ -90,18 +105,25 @@ func (s *Server) Start() error {
 	logrus.Info("Остановка Notification Service...")
 
 	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
 		return fmt.Errorf("принудительная остановка сервера: %w", err)
 	}
+	s.emailSender.Close()
 
 	logrus.Info("Notification Service остановлен")
 	return nil
 }
 
-// startRabbitConsumer запускает consumer для событий report.completed
+// reportEventTemplates сопоставляет события отчетов с ключами шаблонов уведомлений
+var reportEventTemplates = map[string]string{
+	"report.completed": "report_ready",
+	"report.failed":    "report_failed",
+}
+
+// startRabbitConsumer запускает consumer для событий report.completed и report.failed
 func (s *Server) startRabbitConsumer() {
 	amqpURL := s.cfg.RabbitMQURL
 	conn, err := amqp.Dial(amqpURL)
@@ -128,65 +150,250 @@ func (s *Server) startRabbitConsumer() {
 		return
 	}
 
-	if err := ch.QueueBind(q.Name, "report.completed", "events", false, nil); err != nil {
-		logrus.WithError(err).Warn("Не удалось привязать очередь к ключу report.completed")
-		return
+	for routingKey := range reportEventTemplates {
+		if err := ch.QueueBind(q.Name, routingKey, "events", false, nil); err != nil {
+			logrus.WithError(err).Warnf("Не удалось привязать очередь к ключу %s", routingKey)
+			return
+		}
 	}
 
-	msgs, err := ch.Consume(q.Name, "", true, false, false, false, nil)
+	// Ручное подтверждение: событие подтверждается только после обработки
+	msgs, err := ch.Consume(q.Name, "", false, false, false, false, nil)
 	if err != nil {
 		logrus.WithError(err).Warn("Не удалось создать consumer RabbitMQ")
 		return
 	}
 
-	logrus.Info("RabbitMQ consumer notification-service запущен (report.completed)")
+	logrus.Info("RabbitMQ consumer notification-service запущен (report.completed, report.failed)")
 
-	// Простой обработчик: создаем запись уведомления на основе события
 	go func() {
 		for m := range msgs {
 			logrus.WithField("routing_key", m.RoutingKey).Info("Получено событие из RabbitMQ")
-			var evt struct {
-				Type string                 `json:"type"`
-				Data map[string]interface{} `json:"data"`
-			}
-			if err := json.Unmarshal(m.Body, &evt); err != nil {
-				logrus.WithError(err).Warn("Не удалось распарсить событие")
-				continue
-			}
-			if evt.Type != "report.completed" {
-				continue
-			}
-			userID := ""
-			if v, ok := evt.Data["user_id"].(string); ok {
-				userID = v
-			}
-			reportID := ""
-			if v, ok := evt.Data["report_id"].(string); ok {
-				reportID = v
-			}
-			req := &models.NotificationCreateRequest{
-				TemplateID: 1,
-				Recipient:  userID,
-				Type:       "report_ready",
-				Data: map[string]interface{}{
-					"report_id": reportID,
-				},
-			}
-			if s.notificationService == nil {
-				logrus.Warn("notificationService не инициализирован")
-				continue
-			}
-			if _, err := s.notificationService.SendNotification(req); err != nil {
-				logrus.WithError(err).Warn("Не удалось создать уведомление из события")
-			} else {
-				logrus.Info("Уведомление создано из события report.completed")
-			}
+			err := s.handleReportEvent(ch, m)
+			s.settleDelivery(ch, q.Name, m, err)
 		}
 	}()
 }
 
+// handleReportEvent создает уведомление по событию report.completed или
+// report.failed, выбирая шаблон по ключу из поля type события.
+// Возвращает ошибку, только если обработку имеет смысл повторить.
+func (s *Server) handleReportEvent(ch events.Channel, m amqp.Delivery) error {
+	var evt struct {
+		ID   string                 `json:"id"`
+		Type string                 `json:"type"`
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(m.Body, &evt); err != nil {
+		logrus.WithError(err).Warn("Не удалось распарсить событие, событие отброшено")
+		return nil
+	}
+	templateKey, ok := reportEventTemplates[evt.Type]
+	if !ok {
+		return nil
+	}
+	if v, ok := evt.Data["type"].(string); ok && v != "" {
+		templateKey = v
+	}
+	// Получатель по умолчанию — владелец отчета; рассылка отчета указывает получателя явно
+	recipient := ""
+	if v, ok := evt.Data["user_id"].(string); ok {
+		recipient = v
+	}
+	if v, ok := evt.Data["recipient"].(string); ok && v != "" {
+		recipient = v
+	}
+	reportID := ""
+	if v, ok := evt.Data["report_id"].(string); ok {
+		reportID = v
+	}
+	data := map[string]interface{}{
+		"report_id": reportID,
+	}
+	if v, ok := evt.Data["error"].(string); ok {
+		data["error"] = v
+	}
+	if v, ok := evt.Data["file_path"].(string); ok && v != "" {
+		data["file_path"] = v
+	}
+	if v, ok := evt.Data["file_name"].(string); ok && v != "" {
+		data["file_name"] = v
+	}
+	req := &models.NotificationCreateRequest{
+		TemplateKey: templateKey,
+		Recipient:   recipient,
+		Type:        templateKey,
+		Data:        data,
+	}
+	// Рассылка отчета передает файл из storage-service и email канал, через который он
+	// отправляется вложением
+	if v, ok := evt.Data["file_id"].(string); ok && v != "" {
+		fileID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			logrus.WithField("event_id", evt.ID).Warnf("Некорректный file_id %q в событии, событие отброшено", v)
+			return nil
+		}
+		req.AttachmentFileID = uint(fileID)
+	}
+	if v, ok := evt.Data["channel_id"].(string); ok && v != "" {
+		channelID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			logrus.WithField("event_id", evt.ID).Warnf("Некорректный channel_id %q в событии, событие отброшено", v)
+			return nil
+		}
+		req.ChannelID = uint(channelID)
+	}
+	if v, ok := evt.Data["correlation_id"].(string); ok {
+		req.CorrelationID = v
+	}
+	if s.notificationService == nil {
+		return fmt.Errorf("notificationService не инициализирован")
+	}
+
+	resp, err := s.notificationService.SendNotificationOnce(reportEventKey(evt.ID, evt.Type, reportID, recipient), evt.Type, req)
+	if errors.Is(err, services.ErrEventAlreadyProcessed) {
+		logrus.WithField("event_id", evt.ID).Infof("Повторная доставка события %s пропущена", evt.Type)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось создать уведомление из события: %w", err)
+	}
+
+	logrus.Infof("Уведомление создано из события %s", evt.Type)
+	s.publishDeliveryEvents(ch, evt.Data, resp, nil)
+	return nil
+}
+
+// retryCountHeader заголовок с числом повторных постановок события в очередь
+const retryCountHeader = "x-retry-count"
+
+// settleDelivery подтверждает событие после успешной обработки. При ошибке событие
+// ставится в очередь повторно с увеличенным счетчиком, а после CONSUMER_MAX_RETRIES
+// повторов отклоняется без возврата в очередь, и об ошибке доставки сообщается report-service.
+func (s *Server) settleDelivery(ch events.Channel, queue string, m amqp.Delivery, procErr error) {
+	if procErr == nil {
+		if err := m.Ack(false); err != nil {
+			logrus.WithError(err).Warn("Не удалось подтвердить событие")
+		}
+		return
+	}
+
+	retries := deliveryRetryCount(m)
+	logger := logrus.WithError(procErr).WithField("retries", retries)
+
+	if retries >= s.cfg.ConsumerMaxRetries {
+		logger.Error("Событие не обработано после всех повторов и отклонено")
+		var evt struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(m.Body, &evt); err == nil {
+			s.publishDeliveryEvents(ch, evt.Data, nil, procErr)
+		}
+		if err := m.Nack(false, false); err != nil {
+			logrus.WithError(err).Warn("Не удалось отклонить событие")
+		}
+		return
+	}
+
+	// Повторная публикация в ту же очередь через exchange по умолчанию сохраняет
+	// счетчик повторов, которого нет у обычного requeue
+	headers := amqp.Table{}
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	headers[retryCountHeader] = int32(retries + 1)
+
+	err := ch.Publish("", queue, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  m.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    m.MessageId,
+		Timestamp:    m.Timestamp,
+		Body:         m.Body,
+	})
+	if err != nil {
+		// Не удалось переопубликовать: возвращаем исходное сообщение брокеру
+		logger.WithField("publish_error", err).Warn("Событие возвращено в очередь")
+		if nackErr := m.Nack(false, true); nackErr != nil {
+			logrus.WithError(nackErr).Warn("Не удалось вернуть событие в очередь")
+		}
+		return
+	}
+
+	logger.Warn("Ошибка обработки события, событие поставлено в очередь повторно")
+	if err := m.Ack(false); err != nil {
+		logrus.WithError(err).Warn("Не удалось подтвердить исходное событие")
+	}
+}
+
+// deliveryRetryCount возвращает число уже выполненных повторов события
+func deliveryRetryCount(m amqp.Delivery) int {
+	switch v := m.Headers[retryCountHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+// reportEventKey возвращает ключ идемпотентности события: его ID, а для событий
+// без ID — сочетание типа, отчета и получателя
+func reportEventKey(eventID, eventType, reportID, recipient string) string {
+	if eventID != "" {
+		return eventID
+	}
+	return fmt.Sprintf("%s:%s:%s", eventType, reportID, recipient)
+}
+
+// publishDeliveryEvents сообщает report-service о результате доставки уведомления
+func (s *Server) publishDeliveryEvents(ch events.Channel, source map[string]interface{}, resp *models.SendNotificationResponse, sendErr error) {
+	base := map[string]interface{}{
+		"report_id": source["report_id"],
+		"user_id":   source["user_id"],
+	}
+	if sagaID, ok := source["saga_id"]; ok {
+		base["saga_id"] = sagaID
+	}
+
+	var results []models.RecipientStatus
+	if resp != nil {
+		results = resp.Recipients
+	}
+	if len(results) == 0 {
+		status := models.RecipientStatus{Status: "sent"}
+		if sendErr != nil {
+			status = models.RecipientStatus{Status: "failed", Error: sendErr.Error()}
+		}
+		results = []models.RecipientStatus{status}
+	}
+
+	for _, result := range results {
+		data := make(map[string]interface{}, len(base)+4)
+		for k, v := range base {
+			data[k] = v
+		}
+		data["recipient"] = result.Recipient
+		data["notification_id"] = result.NotificationID
+		data["status"] = result.Status
+
+		eventType := events.NotificationDelivered
+		if result.Status != "sent" {
+			eventType = events.NotificationFailed
+			data["error"] = result.Error
+		}
+
+		if err := events.Publish(ch, events.NewEvent(eventType, data)); err != nil {
+			logrus.WithError(err).Warn("Не удалось опубликовать событие доставки уведомления")
+		}
+	}
+}
+
 // setupRouter настраивает маршруты и middleware
-func (s *Server) setupRouter(db *gorm.DB, jwtManager *jwt.Manager, metricsManager *metrics.Metrics) *gin.Engine {
+func (s *Server) setupRouter(db *gorm.DB, jwtManager *jwt.Manager, cipher *secrets.Cipher, metricsManager *metrics.Metrics) *gin.Engine {
 	router := gin.Default()
 
 	// Инициализация метрик
@@ -197,38 +404,45 @@ func (s *Server) setupRouter(db *gorm.DB, jwtManager *jwt.Manager, metricsManage
 	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS())
 	router.Use(middleware.RequestID())
+	router.Use(middleware.RequireJSON())
 
 	// Инициализация репозиториев
 	templateRepo := repository.NewNotificationTemplateRepository(db)
 	notificationRepo := repository.NewNotificationRepository(db)
-	channelRepo := repository.NewNotificationChannelRepository(db)
+	channelRepo := repository.NewNotificationChannelRepository(db, cipher)
+	processedEventRepo := repository.NewProcessedEventRepository(db)
 
 	// Инициализация сервисов
 	templateService := services.NewNotificationTemplateService(templateRepo)
-	notificationService := services.NewNotificationService(notificationRepo, templateRepo)
+	s.emailSender = services.NewEmailSender(services.SMTPPoolConfig{
+		Size:        s.cfg.SMTPPoolSize,
+		IdleTimeout: s.cfg.SMTPPoolIdleTimeout,
+		DialTimeout: s.cfg.SMTPDialTimeout,
+	}, services.NewStorageAttachments(s.cfg.StorageServiceURL))
+	notificationService := services.NewNotificationService(notificationRepo, templateRepo, channelRepo, processedEventRepo, services.NewChannelRateLimiter(), map[string]services.Sender{
+		"email": s.emailSender,
+		"push":  services.NewPushSender(s.cfg.FCMEndpoint),
+		"sms":   services.NewSMSSender(s.cfg.TwilioEndpoint),
+	})
 	channelService := services.NewNotificationChannelService(channelRepo)
 	s.notificationService = notificationService
 
 	// Инициализация обработчиков
 	templateHandler := handlers.NewNotificationTemplateHandler(templateService, metricsManager)
-	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService, s.cfg.WebhookSecret)
 	channelHandler := handlers.NewNotificationChannelHandler(channelService)
 
 	// Настройка маршрутов
-	s.setupRoutes(router, templateHandler, notificationHandler, channelHandler, jwtManager)
+	s.setupRoutes(router, templateHandler, notificationHandler, channelHandler, jwtManager, middleware.NewAPITokenVerifier(s.cfg.UserServiceURL, s.cfg.APITokenCacheTTL))
 
 	return router
 }
 
 // setupRoutes настраивает маршруты API
-func (s *Server) setupRoutes(router *gin.Engine, templateHandler *handlers.NotificationTemplateHandler, notificationHandler *handlers.NotificationHandler, channelHandler *handlers.NotificationChannelHandler, jwtManager *jwt.Manager) {
+func (s *Server) setupRoutes(router *gin.Engine, templateHandler *handlers.NotificationTemplateHandler, notificationHandler *handlers.NotificationHandler, channelHandler *handlers.NotificationChannelHandler, jwtManager *jwt.Manager, apiTokens *middleware.APITokenVerifier) {
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  "healthy",
-			"service": "notification-service",
-			"version": "1.0.0",
-		})
+		c.JSON(http.StatusOK, version.Health("notification-service"))
 	})
 
 	api := router.Group("/api/v1")
@@ -242,6 +456,7 @@ func (s *Server) setupRoutes(router *gin.Engine, templateHandler *handlers.Notif
 			templates.GET("/:id", templateHandler.GetTemplate)
 			templates.PUT("/:id", templateHandler.UpdateTemplate)
 			templates.DELETE("/:id", templateHandler.DeleteTemplate)
+			templates.POST("/bulk-active", templateHandler.BulkSetTemplatesActive)
 		}
 
 		// Уведомления
@@ -249,14 +464,17 @@ func (s *Server) setupRoutes(router *gin.Engine, templateHandler *handlers.Notif
 		// notifications.Use(middleware.AuthMiddleware(jwtManager))
 		{
 			notifications.POST("/send", notificationHandler.SendNotification)
+			notifications.POST("/delivery-callback/batch", notificationHandler.DeliveryCallbackBatch)
 			notifications.GET("/", notificationHandler.GetNotifications)
+			notifications.GET("/stats", notificationHandler.GetNotificationStats)
 			notifications.GET("/:id", notificationHandler.GetNotification)
 			notifications.PUT("/:id/status", notificationHandler.UpdateNotificationStatus)
+			notifications.POST("/:id/resend", notificationHandler.ResendNotification)
 		}
 
 		// Каналы уведомлений
 		channels := api.Group("/channels")
-		// channels.Use(middleware.AuthMiddleware(jwtManager))
+		channels.Use(middleware.AuthMiddleware(jwtManager, apiTokens), middleware.RequireScope("notifications"))
 		{
 			channels.POST("/", channelHandler.CreateChannel)
 			channels.GET("/", channelHandler.GetChannels)
@@ -269,7 +487,7 @@ func (s *Server) setupRoutes(router *gin.Engine, templateHandler *handlers.Notif
 
 // migrate выполняет миграции базы данных
 func (s *Server) migrate() error {
-	_, err := database.Connect(s.cfg.DatabaseURL)
+	_, err := database.ConnectWithRetry(s.cfg.DatabaseURL, s.cfg.DBConnectAttempts, s.cfg.DBConnectInterval)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
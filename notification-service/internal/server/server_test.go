@@ -0,0 +1,561 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"notification-service/internal/config"
+	"notification-service/internal/events"
+	"notification-service/internal/jwt"
+	"notification-service/internal/metrics"
+	"notification-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/streadway/amqp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testMetrics метрики регистрируются в глобальном реестре, поэтому создаются один раз
+var testMetrics = sync.OnceValue(func() *metrics.Metrics {
+	return metrics.NewMetrics("notification-service-test")
+})
+
+// testRouter маршрутизатор Notification Service поверх отдельной SQLite базы
+// с шаблоном report_ready и email каналом SMTP
+func testRouter(t *testing.T, cfg *config.Config) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	_, router, db := newTestServer(t, cfg)
+	return router, db
+}
+
+// newTestServer сервер с маршрутизатором из testRouter; обработчики событий сервера
+// работают с той же базой
+func newTestServer(t *testing.T, cfg *config.Config) (*Server, *gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dsn := filepath.Join(t.TempDir(), "notifications.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&models.NotificationTemplate{}, &models.Notification{}, &models.NotificationChannel{}, &models.ProcessedEvent{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+
+	template := &models.NotificationTemplate{Name: "Report Ready", Key: "report_ready", Subject: "Отчет {{report_id}}", Body: "Отчет {{report_id}} готов", Type: "email", IsActive: true}
+	if err := db.Create(template).Error; err != nil {
+		t.Fatalf("создание шаблона: %v", err)
+	}
+	if err := db.Create(&models.NotificationChannel{Name: "SMTP", Type: "email", IsActive: true}).Error; err != nil {
+		t.Fatalf("создание канала: %v", err)
+	}
+
+	s := NewServer(cfg)
+	router := s.setupRouter(db, jwt.NewManager("test-secret"), nil, testMetrics())
+	return s, router, db
+}
+
+// seedNotification сохраняет уведомление в обход API
+func seedNotification(t *testing.T, db *gorm.DB, notification *models.Notification) *models.Notification {
+	t.Helper()
+	if notification.TemplateID == 0 {
+		notification.TemplateID = 1
+	}
+	if notification.Type == "" {
+		notification.Type = "email"
+	}
+	if err := db.Create(notification).Error; err != nil {
+		t.Fatalf("создание уведомления для %s: %v", notification.Recipient, err)
+	}
+	return notification
+}
+
+// do выполняет JSON запрос к маршрутизатору
+func do(router http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// fakeChannel запоминает опубликованные сообщения и может завершать публикацию ошибкой
+type fakeChannel struct {
+	publishErr error
+	published  []fakePublishing
+}
+
+// fakePublishing сообщение, опубликованное через fakeChannel
+type fakePublishing struct {
+	exchange, key string
+	msg           amqp.Publishing
+}
+
+func (c *fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if c.publishErr != nil {
+		return c.publishErr
+	}
+	c.published = append(c.published, fakePublishing{exchange: exchange, key: key, msg: msg})
+	return nil
+}
+
+// fakeAcknowledger запоминает, как было подтверждено сообщение
+type fakeAcknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+// reportCompletedDelivery доставка события report.completed с числом уже выполненных повторов
+func reportCompletedDelivery(t *testing.T, ack amqp.Acknowledger, retries int32) amqp.Delivery {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{
+		"id":   "event-1",
+		"type": "report.completed",
+		"data": map[string]interface{}{"report_id": "7", "user_id": "1"},
+	})
+	if err != nil {
+		t.Fatalf("сериализация события: %v", err)
+	}
+	delivery := amqp.Delivery{Acknowledger: ack, RoutingKey: "report.completed", MessageId: "event-1", Body: body}
+	if retries > 0 {
+		delivery.Headers = amqp.Table{retryCountHeader: retries}
+	}
+	return delivery
+}
+
+func TestFailedReportEventIsNotAcknowledgedWithoutRequeue(t *testing.T) {
+	// Без сервиса уведомлений обработка события завершается ошибкой
+	s := NewServer(&config.Config{ConsumerMaxRetries: 3})
+	const queue = "notification-report-completed"
+
+	t.Run("повтор через копию в очереди", func(t *testing.T) {
+		ch, ack := &fakeChannel{}, &fakeAcknowledger{}
+		m := reportCompletedDelivery(t, ack, 1)
+
+		err := s.handleReportEvent(ch, m)
+		if err == nil {
+			t.Fatal("ожидалась ошибка обработки")
+		}
+		s.settleDelivery(ch, queue, m, err)
+
+		// Исходное сообщение подтверждается только после публикации копии со счетчиком повторов
+		if len(ch.published) != 1 {
+			t.Fatalf("опубликовано сообщений: %d, ожидалась копия события", len(ch.published))
+		}
+		copied := ch.published[0]
+		if copied.exchange != "" || copied.key != queue || string(copied.msg.Body) != string(m.Body) {
+			t.Errorf("копия опубликована в %q/%q с телом %s", copied.exchange, copied.key, copied.msg.Body)
+		}
+		if retries := deliveryRetryCount(amqp.Delivery{Headers: copied.msg.Headers}); retries != 2 {
+			t.Errorf("счетчик повторов копии %d, ожидалось 2", retries)
+		}
+		if !ack.acked || ack.nacked {
+			t.Errorf("исходное сообщение: ack=%v nack=%v", ack.acked, ack.nacked)
+		}
+	})
+
+	t.Run("копию не удалось опубликовать", func(t *testing.T) {
+		ch, ack := &fakeChannel{publishErr: errors.New("канал закрыт")}, &fakeAcknowledger{}
+		m := reportCompletedDelivery(t, ack, 0)
+
+		s.settleDelivery(ch, queue, m, s.handleReportEvent(ch, m))
+
+		if ack.acked || !ack.nacked || !ack.requeued {
+			t.Errorf("сообщение должно вернуться брокеру: ack=%v nack=%v requeue=%v", ack.acked, ack.nacked, ack.requeued)
+		}
+	})
+
+	t.Run("повторы исчерпаны", func(t *testing.T) {
+		ch, ack := &fakeChannel{}, &fakeAcknowledger{}
+		m := reportCompletedDelivery(t, ack, 3)
+
+		s.settleDelivery(ch, queue, m, s.handleReportEvent(ch, m))
+
+		if ack.acked || !ack.nacked || ack.requeued {
+			t.Errorf("сообщение должно быть отклонено: ack=%v nack=%v requeue=%v", ack.acked, ack.nacked, ack.requeued)
+		}
+		// report-service узнает об ошибке доставки
+		if len(ch.published) != 1 || ch.published[0].key != events.NotificationFailed {
+			t.Fatalf("опубликованы события %+v, ожидалось %s", ch.published, events.NotificationFailed)
+		}
+	})
+}
+
+func TestProcessedReportEventIsAcknowledged(t *testing.T) {
+	s := NewServer(&config.Config{ConsumerMaxRetries: 3})
+	ch, ack := &fakeChannel{}, &fakeAcknowledger{}
+
+	s.settleDelivery(ch, "notification-report-completed", reportCompletedDelivery(t, ack, 0), nil)
+
+	if !ack.acked || ack.nacked || len(ch.published) != 0 {
+		t.Errorf("ack=%v nack=%v, опубликовано %d", ack.acked, ack.nacked, len(ch.published))
+	}
+}
+
+func TestSendNotificationDryRunRendersWithoutSaving(t *testing.T) {
+	router, db := testRouter(t, &config.Config{})
+	request := map[string]interface{}{
+		"template_key": "report_ready",
+		"channel_id":   1,
+		"recipients":   []string{"user@example.com", "boss@example.com"},
+		"data":         map[string]interface{}{"report_id": 42},
+	}
+
+	check := func(t *testing.T, rec *httptest.ResponseRecorder) {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+		var preview models.NotificationPreviewResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		if !preview.DryRun || preview.Subject != "Отчет 42" || preview.Body != "Отчет 42 готов" {
+			t.Errorf("отрендерено %+v", preview)
+		}
+		if preview.ChannelName != "SMTP" || preview.Type != "email" || len(preview.Recipients) != 2 {
+			t.Errorf("канал и получатели %+v", preview)
+		}
+
+		// Пробная отправка не создает уведомлений и не доходит до SMTP
+		var count int64
+		if err := db.Model(&models.Notification{}).Count(&count).Error; err != nil {
+			t.Fatalf("подсчет уведомлений: %v", err)
+		}
+		if count != 0 {
+			t.Errorf("создано уведомлений: %d", count)
+		}
+	}
+
+	t.Run("флаг в теле", func(t *testing.T) {
+		body := map[string]interface{}{"dry_run": true}
+		for key, value := range request {
+			body[key] = value
+		}
+		check(t, do(router, http.MethodPost, "/api/v1/notifications/send", body))
+	})
+
+	t.Run("параметр запроса", func(t *testing.T) {
+		check(t, do(router, http.MethodPost, "/api/v1/notifications/send?dry_run=true", request))
+	})
+}
+
+func TestDeliveryCallbackBatchWithUnknownItems(t *testing.T) {
+	router, db := testRouter(t, &config.Config{WebhookSecret: "webhook-secret"})
+	byID := seedNotification(t, db, &models.Notification{Recipient: "user@example.com", Status: "sent"})
+	byProvider := seedNotification(t, db, &models.Notification{Recipient: "boss@example.com", Status: "sent", ProviderID: "smtp-2"})
+
+	callback := func(secret string, items []map[string]interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(items)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/notifications/delivery-callback/batch", &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Webhook-Secret", secret)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	items := []map[string]interface{}{
+		{"id": byID.ID, "status": "delivered"},
+		{"id": 999, "status": "delivered"},
+		{"provider_id": "smtp-2", "status": "failed", "error": "mailbox full"},
+		{"provider_id": "smtp-404", "status": "delivered"},
+		{"id": byID.ID, "status": "opened"},
+		{"status": "delivered"},
+	}
+
+	if rec := callback("wrong", items); rec.Code != http.StatusUnauthorized {
+		t.Errorf("неверный секрет: статус %d, ожидался 401", rec.Code)
+	}
+
+	rec := callback("webhook-secret", items)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+	}
+	var result models.DeliveryCallbackBatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+
+	// Неизвестные и некорректные элементы не прерывают пакет
+	if result.Updated != 2 || result.Failed != 4 || len(result.Results) != len(items) {
+		t.Fatalf("updated %d, failed %d, результатов %d", result.Updated, result.Failed, len(result.Results))
+	}
+	outcomes := []string{"updated", "not_found", "updated", "not_found", "invalid", "invalid"}
+	for i, want := range outcomes {
+		if got := result.Results[i]; got.Index != i || got.Outcome != want {
+			t.Errorf("элемент %d: %+v, ожидался исход %s", i, got, want)
+		}
+	}
+	if result.Results[2].ID != byProvider.ID {
+		t.Errorf("элемент по provider_id сопоставлен с уведомлением %d, ожидалось %d", result.Results[2].ID, byProvider.ID)
+	}
+
+	var delivered, failed models.Notification
+	db.First(&delivered, byID.ID)
+	db.First(&failed, byProvider.ID)
+	if delivered.Status != "delivered" || delivered.DeliveredAt == nil {
+		t.Errorf("уведомление %d: статус %q, delivered_at %v", byID.ID, delivered.Status, delivered.DeliveredAt)
+	}
+	if failed.Status != "failed" || failed.ErrorMessage != "mailbox full" {
+		t.Errorf("уведомление %d: статус %q, ошибка %q", byProvider.ID, failed.Status, failed.ErrorMessage)
+	}
+}
+
+func TestNotificationStatsOverPeriod(t *testing.T) {
+	router, db := testRouter(t, &config.Config{})
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 12, 0, 0, 0, time.UTC) }
+
+	for _, n := range []*models.Notification{
+		{Recipient: "a@example.com", Type: "email", Status: "sent", CreatedAt: day(time.January, 10)},
+		{Recipient: "b@example.com", Type: "email", Status: "failed", CreatedAt: day(time.January, 10)},
+		{Recipient: "+79991234567", Type: "sms", Status: "delivered", CreatedAt: day(time.January, 20)},
+		{Recipient: "device-1", Type: "push", Status: "failed", CreatedAt: day(time.January, 25)},
+		{Recipient: "c@example.com", Type: "email", Status: "sent", CreatedAt: day(time.February, 5)},
+	} {
+		seedNotification(t, db, n)
+	}
+	// Удаленные уведомления в статистику не попадают
+	deleted := seedNotification(t, db, &models.Notification{Recipient: "d@example.com", Status: "sent", CreatedAt: day(time.January, 20)})
+	db.Delete(deleted)
+
+	stats := func(t *testing.T, query string) models.NotificationStatsResponse {
+		t.Helper()
+		rec := do(router, http.MethodGet, "/api/v1/notifications/stats"+query, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+		var result models.NotificationStatsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return result
+	}
+
+	t.Run("за все время", func(t *testing.T) {
+		result := stats(t, "")
+		wantStatus := map[string]int64{"sent": 2, "failed": 2, "delivered": 1}
+		wantType := map[string]int64{"email": 3, "sms": 1, "push": 1}
+		if result.Total != 5 || !reflect.DeepEqual(result.ByStatus, wantStatus) || !reflect.DeepEqual(result.ByType, wantType) {
+			t.Errorf("статистика %+v", result)
+		}
+	})
+
+	t.Run("за период", func(t *testing.T) {
+		result := stats(t, "?from=2024-01-15T00:00:00Z&to=2024-01-31T23:59:59Z")
+		wantStatus := map[string]int64{"failed": 1, "delivered": 1}
+		wantType := map[string]int64{"sms": 1, "push": 1}
+		if result.Total != 2 || !reflect.DeepEqual(result.ByStatus, wantStatus) || !reflect.DeepEqual(result.ByType, wantType) {
+			t.Errorf("статистика %+v", result)
+		}
+		if result.From == nil || !result.From.Equal(time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("from %v", result.From)
+		}
+	})
+
+	for _, query := range []string{"?from=15.01.2024", "?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z"} {
+		if rec := do(router, http.MethodGet, "/api/v1/notifications/stats"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: статус %d, ожидался 400", query, rec.Code)
+		}
+	}
+}
+
+func TestReportEventSelectsTemplateByOutcome(t *testing.T) {
+	s, _, db := newTestServer(t, &config.Config{ConsumerMaxRetries: 3})
+	failedTemplate := &models.NotificationTemplate{Name: "Report Failed", Key: "report_failed", Subject: "Ошибка отчета {{report_id}}", Body: "Отчет {{report_id}} не сформирован: {{error}}", Type: "email", IsActive: true}
+	if err := db.Create(failedTemplate).Error; err != nil {
+		t.Fatalf("создание шаблона: %v", err)
+	}
+
+	deliver := func(t *testing.T, id, eventType string, data map[string]interface{}) models.Notification {
+		t.Helper()
+		body, err := json.Marshal(map[string]interface{}{"id": id, "type": eventType, "data": data})
+		if err != nil {
+			t.Fatalf("сериализация события: %v", err)
+		}
+		ch, ack := &fakeChannel{}, &fakeAcknowledger{}
+		m := amqp.Delivery{Acknowledger: ack, RoutingKey: eventType, MessageId: id, Body: body}
+		if err := s.handleReportEvent(ch, m); err != nil {
+			t.Fatalf("обработка события: %v", err)
+		}
+
+		var notification models.Notification
+		if err := db.Where("message_id = ?", id).First(&notification).Error; err != nil {
+			t.Fatalf("уведомление по событию %s не создано: %v", id, err)
+		}
+		return notification
+	}
+
+	t.Run("отчет готов", func(t *testing.T) {
+		notification := deliver(t, "event-ready", "report.completed", map[string]interface{}{"report_id": "7", "user_id": "1", "type": "report_ready"})
+		if notification.Subject != "Отчет 7" || notification.Body != "Отчет 7 готов" {
+			t.Errorf("уведомление %q / %q", notification.Subject, notification.Body)
+		}
+	})
+
+	t.Run("ошибка генерации", func(t *testing.T) {
+		notification := deliver(t, "event-failed", "report.failed", map[string]interface{}{"report_id": "8", "user_id": "1", "type": "report_failed", "error": "storage-service недоступен"})
+		if notification.TemplateID != failedTemplate.ID || notification.Body != "Отчет 8 не сформирован: storage-service недоступен" {
+			t.Errorf("уведомление по шаблону %d: %q", notification.TemplateID, notification.Body)
+		}
+	})
+
+	t.Run("ключ по типу события", func(t *testing.T) {
+		// Без type в данных шаблон выбирается по типу события
+		notification := deliver(t, "event-failed-untyped", "report.failed", map[string]interface{}{"report_id": "9", "user_id": "1", "error": "таймаут"})
+		if notification.TemplateID != failedTemplate.ID {
+			t.Errorf("уведомление по шаблону %d, ожидался %d", notification.TemplateID, failedTemplate.ID)
+		}
+	})
+}
+
+func TestBulkSetNotificationTemplatesActive(t *testing.T) {
+	router, db := testRouter(t, &config.Config{})
+	failed := &models.NotificationTemplate{Name: "Report Failed", Key: "report_failed", Body: "Отчет {{report_id}} не сформирован", Type: "email", IsActive: true}
+	if err := db.Create(failed).Error; err != nil {
+		t.Fatalf("создание шаблона: %v", err)
+	}
+	var readyTemplate models.NotificationTemplate
+	if err := db.First(&readyTemplate, "name = ?", "Report Ready").Error; err != nil {
+		t.Fatalf("шаблон report_ready: %v", err)
+	}
+	ready := readyTemplate.ID
+
+	bulk := func(t *testing.T, active bool, ids ...uint) models.NotificationTemplateBulkActiveResponse {
+		t.Helper()
+		rec := do(router, http.MethodPost, "/api/v1/templates/bulk-active", map[string]interface{}{"ids": ids, "is_active": active})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+		var result models.NotificationTemplateBulkActiveResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return result
+	}
+	activeByKey := func(t *testing.T) map[string]bool {
+		t.Helper()
+		var templates []models.NotificationTemplate
+		if err := db.Find(&templates).Error; err != nil {
+			t.Fatalf("чтение шаблонов: %v", err)
+		}
+		active := make(map[string]bool, len(templates))
+		for _, template := range templates {
+			active[template.Key] = template.IsActive
+		}
+		return active
+	}
+
+	result := bulk(t, false, ready, failed.ID, 404)
+	if len(result.Updated) != 2 || result.Updated[0].ID != ready || result.Updated[1].ID != failed.ID || result.Updated[1].IsActive {
+		t.Errorf("отключены %+v", result.Updated)
+	}
+	if !reflect.DeepEqual(result.MissingIDs, []uint{404}) {
+		t.Errorf("missing_ids %v, ожидалось [404]", result.MissingIDs)
+	}
+	if got := activeByKey(t); !reflect.DeepEqual(got, map[string]bool{"report_ready": false, "report_failed": false}) {
+		t.Errorf("после отключения %v", got)
+	}
+
+	result = bulk(t, true, failed.ID)
+	if len(result.Updated) != 1 || !result.Updated[0].IsActive || len(result.MissingIDs) != 0 {
+		t.Errorf("включение: %+v", result)
+	}
+	if got := activeByKey(t); !reflect.DeepEqual(got, map[string]bool{"report_ready": false, "report_failed": true}) {
+		t.Errorf("после включения %v", got)
+	}
+
+	if rec := do(router, http.MethodPost, "/api/v1/templates/bulk-active", map[string]interface{}{"ids": []uint{ready}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("без is_active: статус %d, ожидался 400", rec.Code)
+	}
+}
+
+func TestHandlersRespondWithErrorShape(t *testing.T) {
+	router, db := testRouter(t, &config.Config{})
+	sent := seedNotification(t, db, &models.Notification{Recipient: "user@example.com", Status: "sent"})
+
+	// details — ожидаемое поле details в JSON, пустая строка означает его отсутствие
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    interface{}
+		status  int
+		code    string
+		details string
+	}{
+		{"некорректный ID", http.MethodGet, "/api/v1/notifications/abc", nil, http.StatusBadRequest, "validation_error", ""},
+		{"некорректный период", http.MethodGet, "/api/v1/notifications/stats?from=вчера", nil, http.StatusBadRequest, "validation_error", ""},
+		{"некорректные данные запроса", http.MethodPost, "/api/v1/notifications/send", map[string]interface{}{"recipient": 42}, http.StatusBadRequest, "validation_error", `"json: cannot unmarshal number into Go struct field NotificationCreateRequest.recipient of type string"`},
+		{"callback без настроенного секрета", http.MethodPost, "/api/v1/notifications/delivery-callback/batch", []interface{}{}, http.StatusForbidden, "forbidden", ""},
+		{"несуществующее уведомление", http.MethodGet, "/api/v1/notifications/999", nil, http.StatusNotFound, "not_found", ""},
+		{"повтор отправленного уведомления", http.MethodPost, fmt.Sprintf("/api/v1/notifications/%d/resend", sent.ID), nil, http.StatusConflict, "conflict", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(router, tt.method, tt.path, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("статус %d, ожидался %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			var body struct {
+				Error struct {
+					Code    string          `json:"code"`
+					Message string          `json:"message"`
+					Details json.RawMessage `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+			if body.Error.Code != tt.code {
+				t.Errorf("код ошибки %q, ожидался %q: %s", body.Error.Code, tt.code, rec.Body.String())
+			}
+			if body.Error.Message == "" {
+				t.Errorf("пустое сообщение ошибки: %s", rec.Body.String())
+			}
+			if string(body.Error.Details) != tt.details {
+				t.Errorf("details %s, ожидалось %q", body.Error.Details, tt.details)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxAttachmentSize ограничивает вложение, которое целиком загружается в память для письма
+const maxAttachmentSize = 20 << 20
+
+// Attachment файл, прикладываемый к уведомлению
+type Attachment struct {
+	Name    string
+	Content []byte
+}
+
+// AttachmentSource загружает вложение уведомления по ID файла
+type AttachmentSource interface {
+	Attachment(ctx context.Context, fileID uint) (*Attachment, error)
+}
+
+// StorageAttachments загружает вложения из Storage Service
+type StorageAttachments struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewStorageAttachments создает источник вложений по адресу Storage Service
+func NewStorageAttachments(baseURL string) *StorageAttachments {
+	return &StorageAttachments{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Attachment скачивает файл; имя берется из Content-Disposition ответа
+func (s *StorageAttachments) Attachment(ctx context.Context, fileID uint) (*Attachment, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/files/%d/download", s.baseURL, fileID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса к storage-service: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage-service недоступен: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("файл %d не найден в storage-service", fileID)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("storage-service вернул статус %d", resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachmentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла %d: %w", fileID, err)
+	}
+	if len(content) > maxAttachmentSize {
+		return nil, fmt.Errorf("файл %d больше допустимого размера вложения %d байт", fileID, maxAttachmentSize)
+	}
+
+	name := fmt.Sprintf("file_%d", fileID)
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		name = params["filename"]
+	}
+	return &Attachment{Name: name, Content: content}, nil
+}
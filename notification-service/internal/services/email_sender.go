@@ -0,0 +1,348 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"notification-service/internal/models"
+)
+
+// emailChannelConfig параметры SMTP сервера из Config email канала
+type emailChannelConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+// address возвращает адрес SMTP сервера; без порта используется 587
+func (c *emailChannelConfig) address() string {
+	port := c.Port
+	if port == 0 {
+		port = 587
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
+}
+
+// sender возвращает адрес отправителя: from или, если он не задан, username
+func (c *emailChannelConfig) sender() string {
+	if c.From != "" {
+		return c.From
+	}
+	return c.Username
+}
+
+// SMTPPoolConfig параметры пула SMTP соединений
+type SMTPPoolConfig struct {
+	// Size максимальное число простаивающих соединений одного канала
+	Size int
+	// IdleTimeout время, после которого простаивающее соединение закрывается
+	IdleTimeout time.Duration
+	// DialTimeout время на установку соединения с SMTP сервером
+	DialTimeout time.Duration
+}
+
+// EmailSender отправляет письма через SMTP сервер из Config канала,
+// переиспользуя соединения из пула канала
+type EmailSender struct {
+	cfg         SMTPPoolConfig
+	attachments AttachmentSource
+
+	mu    sync.Mutex
+	pools map[uint]*smtpPool
+}
+
+// NewEmailSender создает отправителя email с пулом соединений на каждый канал;
+// attachments загружает файлы, прикладываемые к письмам (nil — вложения не поддерживаются)
+func NewEmailSender(cfg SMTPPoolConfig, attachments AttachmentSource) *EmailSender {
+	if cfg.Size <= 0 {
+		cfg.Size = 1
+	}
+	return &EmailSender{
+		cfg:         cfg,
+		attachments: attachments,
+		pools:       make(map[uint]*smtpPool),
+	}
+}
+
+// Send отправляет письмо на адрес из Recipient и возвращает его Message-ID
+func (s *EmailSender) Send(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+	var cfg emailChannelConfig
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return "", fmt.Errorf("некорректная конфигурация email канала: %w", err)
+	}
+	if cfg.Host == "" || cfg.sender() == "" {
+		return "", errors.New("в конфигурации email канала не указаны host и from (или username)")
+	}
+
+	messageID, err := newMessageID(cfg.Host)
+	if err != nil {
+		return "", err
+	}
+
+	var attachment *Attachment
+	if notification.AttachmentFileID != 0 {
+		if s.attachments == nil {
+			return "", errors.New("вложения писем не поддерживаются: не задан источник файлов")
+		}
+		attachment, err = s.attachments.Attachment(ctx, notification.AttachmentFileID)
+		if err != nil {
+			return "", fmt.Errorf("ошибка загрузки вложения: %w", err)
+		}
+	}
+	message := buildEmailMessage(cfg.sender(), notification.Recipient, notification.Subject, notification.Body, messageID, attachment)
+
+	pool := s.pool(channel.ID, channel.Config, &cfg)
+	client, reused, err := pool.get()
+	if err != nil {
+		return "", err
+	}
+
+	err = sendEmail(client, cfg.sender(), notification.Recipient, message)
+	if err != nil && reused {
+		// Соединение из пула могло быть закрыто сервером: повторяем один раз через новое
+		client.Close()
+		client, err = pool.dial()
+		if err != nil {
+			return "", err
+		}
+		err = sendEmail(client, cfg.sender(), notification.Recipient, message)
+	}
+	if err != nil {
+		client.Close()
+		return "", fmt.Errorf("ошибка отправки письма: %w", err)
+	}
+
+	pool.put(client)
+	return messageID, nil
+}
+
+// Close закрывает все соединения пулов
+func (s *EmailSender) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, pool := range s.pools {
+		pool.close()
+		delete(s.pools, id)
+	}
+}
+
+// pool возвращает пул канала, пересоздавая его при изменении конфигурации
+func (s *EmailSender) pool(channelID uint, rawConfig string, cfg *emailChannelConfig) *smtpPool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pools[channelID]
+	if !ok || p.rawConfig != rawConfig {
+		if ok {
+			p.close()
+		}
+		p = &smtpPool{
+			rawConfig:   rawConfig,
+			channel:     *cfg,
+			size:        s.cfg.Size,
+			idleTimeout: s.cfg.IdleTimeout,
+			dialTimeout: s.cfg.DialTimeout,
+		}
+		s.pools[channelID] = p
+	}
+	return p
+}
+
+// idleSMTPConn простаивающее соединение пула
+type idleSMTPConn struct {
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+// smtpPool пул соединений с SMTP сервером одного канала
+type smtpPool struct {
+	rawConfig   string
+	channel     emailChannelConfig
+	size        int
+	idleTimeout time.Duration
+	dialTimeout time.Duration
+
+	mu   sync.Mutex
+	idle []idleSMTPConn
+}
+
+// get возвращает соединение из пула или открывает новое; reused сообщает,
+// что соединение взято из пула
+func (p *smtpPool) get() (client *smtp.Client, reused bool, err error) {
+	p.mu.Lock()
+	now := time.Now()
+	for len(p.idle) > 0 {
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.idleTimeout > 0 && now.Sub(conn.lastUsed) > p.idleTimeout {
+			conn.client.Close()
+			continue
+		}
+		p.mu.Unlock()
+		return conn.client, true, nil
+	}
+	p.mu.Unlock()
+
+	client, err = p.dial()
+	return client, false, err
+}
+
+// put возвращает соединение в пул; сверх размера пула соединение закрывается
+func (p *smtpPool) put(client *smtp.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.idle) >= p.size {
+		client.Quit()
+		return
+	}
+	p.idle = append(p.idle, idleSMTPConn{client: client, lastUsed: time.Now()})
+}
+
+// close закрывает простаивающие соединения
+func (p *smtpPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, conn := range p.idle {
+		conn.client.Quit()
+	}
+	p.idle = nil
+}
+
+// dial открывает соединение, включает STARTTLS, если сервер его поддерживает,
+// и выполняет аутентификацию при заданном username
+func (p *smtpPool) dial() (*smtp.Client, error) {
+	dialTimeout := p.dialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 10 * time.Second
+	}
+
+	conn, err := net.DialTimeout("tcp", p.channel.address(), dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к SMTP серверу: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, p.channel.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ошибка подключения к SMTP серверу: %w", err)
+	}
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: p.channel.Host}); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("ошибка STARTTLS: %w", err)
+		}
+	}
+
+	if p.channel.Username != "" {
+		auth := smtp.PlainAuth("", p.channel.Username, p.channel.Password, p.channel.Host)
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("ошибка аутентификации SMTP: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// sendEmail передает одно письмо в рамках открытого соединения
+func sendEmail(client *smtp.Client, from, to string, message []byte) error {
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// buildEmailMessage формирует письмо с заголовками; тема кодируется по RFC 2047.
+// С вложением письмо собирается как multipart/mixed: текст и файл в base64
+func buildEmailMessage(from, to, subject, body, messageID string, attachment *Attachment) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + to + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	buf.WriteString("Message-ID: " + messageID + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	if attachment == nil {
+		buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+		buf.WriteString("\r\n")
+		buf.WriteString(body)
+		return buf.Bytes()
+	}
+
+	parts := multipart.NewWriter(&buf)
+	buf.WriteString("Content-Type: multipart/mixed; boundary=" + parts.Boundary() + "\r\n")
+	buf.WriteString("\r\n")
+
+	text, _ := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	text.Write([]byte(body))
+
+	contentType := mime.TypeByExtension(filepath.Ext(attachment.Name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	// FormatMediaType кодирует имя не в ASCII по RFC 2231
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	file, _ := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Disposition":       {disposition},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+	for len(encoded) > 76 {
+		file.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	file.Write([]byte(encoded + "\r\n"))
+
+	parts.Close()
+	return buf.Bytes()
+}
+
+// newMessageID генерирует уникальный Message-ID письма
+func newMessageID(host string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("ошибка генерации Message-ID: %w", err)
+	}
+	return "<" + hex.EncodeToString(b) + "@" + host + ">", nil
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"notification-service/internal/models"
+)
+
+// fakeSMTPServer минимальный SMTP сервер, считающий соединения и принятые письма
+type fakeSMTPServer struct {
+	listener net.Listener
+	// closeAfter закрывает соединение после указанного числа писем (0 — не закрывать)
+	closeAfter int
+
+	mu          sync.Mutex
+	connections int
+	messages    []string
+}
+
+func newFakeSMTPServer(t *testing.T, closeAfter int) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("запуск SMTP сервера: %v", err)
+	}
+	server := &fakeSMTPServer{listener: listener, closeAfter: closeAfter}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.mu.Lock()
+			server.connections++
+			server.mu.Unlock()
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+	reply("220 fake.smtp ESMTP")
+
+	delivered := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 fake.smtp")
+		case strings.HasPrefix(command, "MAIL"), strings.HasPrefix(command, "RCPT"), command == "RSET", command == "NOOP":
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var body strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				body.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, body.String())
+			s.mu.Unlock()
+			reply("250 OK queued")
+
+			delivered++
+			if s.closeAfter > 0 && delivered >= s.closeAfter {
+				return
+			}
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// stats возвращает число соединений и принятых писем
+func (s *fakeSMTPServer) stats() (connections, messages int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections, len(s.messages)
+}
+
+// channel email канал, указывающий на сервер
+func (s *fakeSMTPServer) channel() *models.NotificationChannel {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return &models.NotificationChannel{
+		ID:     1,
+		Type:   "email",
+		Config: fmt.Sprintf(`{"host": "127.0.0.1", "port": %d, "from": "noreply@example.com"}`, addr.Port),
+	}
+}
+
+func sendTestEmails(t *testing.T, sender *EmailSender, channel *models.NotificationChannel, count int, pause time.Duration) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			time.Sleep(pause)
+		}
+		notification := &models.Notification{Recipient: "user@example.com", Subject: "Отчет готов", Body: fmt.Sprintf("письмо %d", i)}
+		if _, err := sender.Send(context.Background(), channel, notification); err != nil {
+			t.Fatalf("отправка письма %d: %v", i, err)
+		}
+	}
+}
+
+func TestEmailSenderReusesConnection(t *testing.T) {
+	server := newFakeSMTPServer(t, 0)
+	sender := NewEmailSender(SMTPPoolConfig{Size: 2, IdleTimeout: time.Minute}, nil)
+	defer sender.Close()
+
+	sendTestEmails(t, sender, server.channel(), 3, 0)
+
+	connections, messages := server.stats()
+	if connections != 1 || messages != 3 {
+		t.Errorf("соединений: %d, писем: %d; ожидалось 1 соединение и 3 письма", connections, messages)
+	}
+}
+
+func TestEmailSenderReconnectsAfterServerClose(t *testing.T) {
+	server := newFakeSMTPServer(t, 1)
+	sender := NewEmailSender(SMTPPoolConfig{Size: 2, IdleTimeout: time.Minute}, nil)
+	defer sender.Close()
+
+	// Сервер закрывает соединение после каждого письма: соединение из пула
+	// удаляется, письмо повторяется через новое
+	sendTestEmails(t, sender, server.channel(), 2, 10*time.Millisecond)
+
+	connections, messages := server.stats()
+	if connections != 2 || messages != 2 {
+		t.Errorf("соединений: %d, писем: %d; ожидалось 2 соединения и 2 письма", connections, messages)
+	}
+}
+
+func TestEmailSenderClosesIdleConnections(t *testing.T) {
+	server := newFakeSMTPServer(t, 0)
+	sender := NewEmailSender(SMTPPoolConfig{Size: 2, IdleTimeout: 5 * time.Millisecond}, nil)
+	defer sender.Close()
+
+	sendTestEmails(t, sender, server.channel(), 2, 20*time.Millisecond)
+
+	connections, messages := server.stats()
+	if connections != 2 || messages != 2 {
+		t.Errorf("соединений: %d, писем: %d; ожидалось 2 соединения и 2 письма", connections, messages)
+	}
+}
+
+// staticAttachments источник вложений с заранее заданными файлами
+type staticAttachments map[uint]*Attachment
+
+func (a staticAttachments) Attachment(ctx context.Context, fileID uint) (*Attachment, error) {
+	attachment, ok := a[fileID]
+	if !ok {
+		return nil, fmt.Errorf("файл %d не найден", fileID)
+	}
+	return attachment, nil
+}
+
+func TestEmailSenderAttachesFile(t *testing.T) {
+	server := newFakeSMTPServer(t, 0)
+	content := []byte(strings.Repeat("id,name\n1,Продажи\n", 10))
+	sender := NewEmailSender(SMTPPoolConfig{Size: 1}, staticAttachments{
+		42: {Name: "report_7.csv", Content: content},
+	})
+	defer sender.Close()
+
+	notification := &models.Notification{Recipient: "user@example.com", Subject: "Отчет готов", Body: "Отчет во вложении", AttachmentFileID: 42}
+	if _, err := sender.Send(context.Background(), server.channel(), notification); err != nil {
+		t.Fatalf("отправка письма: %v", err)
+	}
+
+	server.mu.Lock()
+	message := server.messages[0]
+	server.mu.Unlock()
+
+	for _, want := range []string{"Content-Type: multipart/mixed; boundary=", "Отчет во вложении", `attachment; filename=report_7.csv`, "Content-Transfer-Encoding: base64"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("в письме нет %q:\n%s", want, message)
+		}
+	}
+	encoded := base64.StdEncoding.EncodeToString(content)
+	if !strings.Contains(strings.ReplaceAll(message, "\r\n", ""), encoded) {
+		t.Errorf("в письме нет содержимого файла в base64")
+	}
+}
+
+func TestEmailSenderFailsWhenAttachmentMissing(t *testing.T) {
+	server := newFakeSMTPServer(t, 0)
+	sender := NewEmailSender(SMTPPoolConfig{Size: 1}, staticAttachments{})
+	defer sender.Close()
+
+	notification := &models.Notification{Recipient: "user@example.com", Subject: "Отчет готов", AttachmentFileID: 42}
+	if _, err := sender.Send(context.Background(), server.channel(), notification); err == nil {
+		t.Fatal("письмо отправлено без вложения")
+	}
+	if _, messages := server.stats(); messages != 0 {
+		t.Errorf("принято писем: %d, ожидалось 0", messages)
+	}
+}
+
+func TestStorageAttachmentsDownloadsFile(t *testing.T) {
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/files/7/download" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="report.csv"; filename*=UTF-8''%D0%BE%D1%82%D1%87%D0%B5%D1%82.csv`)
+		w.Write([]byte("a,b\n"))
+	}))
+	defer storage.Close()
+
+	attachments := NewStorageAttachments(storage.URL)
+	attachment, err := attachments.Attachment(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("загрузка вложения: %v", err)
+	}
+	if attachment.Name != "отчет.csv" || string(attachment.Content) != "a,b\n" {
+		t.Errorf("вложение %q с содержимым %q", attachment.Name, attachment.Content)
+	}
+
+	if _, err := attachments.Attachment(context.Background(), 8); err == nil {
+		t.Error("отсутствующий файл загружен без ошибки")
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"notification-service/internal/models"
+)
+
+// DefaultFCMEndpoint адрес HTTP API Firebase Cloud Messaging
+const DefaultFCMEndpoint = "https://fcm.googleapis.com/fcm/send"
+
+// pushChannelConfig параметры FCM из Config канала
+type pushChannelConfig struct {
+	ServerKey string `json:"server_key"`
+	ProjectID string `json:"project_id"`
+}
+
+// fcmMessage запрос к FCM; получатель уведомления — токен устройства
+type fcmMessage struct {
+	To           string                 `json:"to"`
+	Notification fcmNotification        `json:"notification"`
+	Data         map[string]interface{} `json:"data,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body"`
+}
+
+// fcmResponse ответ FCM; ошибка по конкретному токену приходит в results
+type fcmResponse struct {
+	Success int `json:"success"`
+	Failure int `json:"failure"`
+	Results []struct {
+		MessageID string `json:"message_id"`
+		Error     string `json:"error"`
+	} `json:"results"`
+}
+
+// PushSender отправляет push уведомления через FCM
+type PushSender struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewPushSender создает отправителя push уведомлений. Пустой endpoint заменяется на адрес FCM.
+func NewPushSender(endpoint string) *PushSender {
+	if endpoint == "" {
+		endpoint = DefaultFCMEndpoint
+	}
+	return &PushSender{
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send отправляет уведомление на токен устройства из Recipient
+func (s *PushSender) Send(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+	var cfg pushChannelConfig
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return "", fmt.Errorf("некорректная конфигурация push канала: %w", err)
+	}
+	if cfg.ServerKey == "" {
+		return "", errors.New("в конфигурации push канала не указан server_key")
+	}
+
+	message := fcmMessage{
+		To: notification.Recipient,
+		Notification: fcmNotification{
+			Title: notification.Subject,
+			Body:  notification.Body,
+		},
+	}
+	if notification.Data != "" {
+		// Данные шаблона передаются приложению как есть; некорректный JSON не мешает отправке
+		_ = json.Unmarshal([]byte(notification.Data), &message.Data)
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return "", fmt.Errorf("ошибка сериализации push уведомления: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания запроса к FCM: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "key="+cfg.ServerKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("FCM недоступен: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("FCM (проект %s) вернул статус %d", cfg.ProjectID, resp.StatusCode)
+	}
+
+	var result fcmResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("ошибка разбора ответа FCM: %w", err)
+	}
+	if len(result.Results) == 0 {
+		return "", errors.New("FCM не вернул результат отправки")
+	}
+	if result.Results[0].Error != "" {
+		return "", fmt.Errorf("FCM отклонил уведомление: %s", result.Results[0].Error)
+	}
+
+	return result.Results[0].MessageID, nil
+}
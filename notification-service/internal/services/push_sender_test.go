@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"notification-service/internal/models"
+)
+
+// stubFCM поддельный FCM, который запоминает последний запрос и отвечает status и body
+func stubFCM(t *testing.T, status int, body string) (*httptest.Server, *fcmMessage, *string) {
+	t.Helper()
+
+	var received fcmMessage
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("разбор запроса к FCM: %v", err)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &received, &authorization
+}
+
+// pushNotification push уведомление на токен устройства
+func pushNotification() *models.Notification {
+	return &models.Notification{
+		Recipient: "device-token-1",
+		Subject:   "Отчет готов",
+		Body:      "Отчет 42 готов",
+		Type:      "push",
+		Data:      `{"report_id": 42}`,
+	}
+}
+
+func TestPushSenderSendsToFCM(t *testing.T) {
+	server, received, authorization := stubFCM(t, http.StatusOK, `{"success": 1, "results": [{"message_id": "fcm-1"}]}`)
+	channel := &models.NotificationChannel{Type: "push", Config: `{"server_key": "server-key", "project_id": "reports"}`}
+
+	providerID, err := NewPushSender(server.URL).Send(context.Background(), channel, pushNotification())
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if providerID != "fcm-1" {
+		t.Errorf("provider_id %q, ожидался fcm-1", providerID)
+	}
+	if *authorization != "key=server-key" {
+		t.Errorf("Authorization %q", *authorization)
+	}
+	if received.To != "device-token-1" || received.Notification.Title != "Отчет готов" || received.Notification.Body != "Отчет 42 готов" {
+		t.Errorf("запрос к FCM %+v", received)
+	}
+	if received.Data["report_id"] != float64(42) {
+		t.Errorf("данные уведомления %v", received.Data)
+	}
+}
+
+func TestPushSenderReportsFCMErrors(t *testing.T) {
+	channel := &models.NotificationChannel{Type: "push", Config: `{"server_key": "server-key", "project_id": "reports"}`}
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"токен не зарегистрирован", http.StatusOK, `{"failure": 1, "results": [{"error": "NotRegistered"}]}`, "NotRegistered"},
+		{"ошибка авторизации", http.StatusUnauthorized, ``, "статус 401"},
+		{"пустой результат", http.StatusOK, `{"success": 0, "results": []}`, "не вернул результат"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _, _ := stubFCM(t, tt.status, tt.body)
+			_, err := NewPushSender(server.URL).Send(context.Background(), channel, pushNotification())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ошибка %v, ожидалась содержащая %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPushSenderRequiresServerKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("запрос к FCM без server_key")
+	}))
+	defer server.Close()
+
+	channel := &models.NotificationChannel{Type: "push", Config: `{"project_id": "reports"}`}
+	if _, err := NewPushSender(server.URL).Send(context.Background(), channel, pushNotification()); err == nil {
+		t.Error("ожидалась ошибка конфигурации канала")
+	}
+}
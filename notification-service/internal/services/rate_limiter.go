@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+// ChannelRateConfig параметры ограничения скорости отправки из Config канала
+type ChannelRateConfig struct {
+	RatePerSecond float64 `json:"rate_limit_per_second"`
+	Burst         int     `json:"rate_limit_burst"`
+}
+
+// parseChannelRateConfig читает параметры ограничения из JSON конфигурации канала.
+// Нулевая скорость означает отсутствие ограничения.
+func parseChannelRateConfig(config string) ChannelRateConfig {
+	var cfg ChannelRateConfig
+	if config == "" {
+		return cfg
+	}
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		return ChannelRateConfig{}
+	}
+	if cfg.RatePerSecond < 0 {
+		cfg.RatePerSecond = 0
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = int(math.Max(1, math.Ceil(cfg.RatePerSecond)))
+	}
+	return cfg
+}
+
+// tokenBucket ведро токенов одного канала
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	lastFill time.Time
+}
+
+// reserve забирает токен и возвращает время ожидания до его появления
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.lastFill).Seconds()*b.rate)
+	b.lastFill = now
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// ChannelRateLimiter реестр ограничителей скорости отправки по ID канала
+type ChannelRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[uint]*tokenBucket
+}
+
+// NewChannelRateLimiter создает реестр ограничителей
+func NewChannelRateLimiter() *ChannelRateLimiter {
+	return &ChannelRateLimiter{
+		buckets: make(map[uint]*tokenBucket),
+	}
+}
+
+// Wait блокирует отправку, пока в ведре канала не появится токен
+func (l *ChannelRateLimiter) Wait(ctx context.Context, channelID uint, cfg ChannelRateConfig) error {
+	if cfg.RatePerSecond <= 0 {
+		return nil
+	}
+
+	delay := l.bucket(channelID, cfg).reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// bucket возвращает ведро канала, пересоздавая его при изменении настроек
+func (l *ChannelRateLimiter) bucket(channelID uint, cfg ChannelRateConfig) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[channelID]
+	if !ok || b.rate != cfg.RatePerSecond || b.burst != float64(cfg.Burst) {
+		b = &tokenBucket{
+			rate:     cfg.RatePerSecond,
+			burst:    float64(cfg.Burst),
+			tokens:   float64(cfg.Burst),
+			lastFill: time.Now(),
+		}
+		l.buckets[channelID] = b
+	}
+	return b
+}
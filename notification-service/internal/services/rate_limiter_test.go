@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseChannelRateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   ChannelRateConfig
+	}{
+		{"без настроек", "", ChannelRateConfig{}},
+		{"некорректный JSON", "{", ChannelRateConfig{}},
+		{"скорость и всплеск", `{"rate_limit_per_second": 10, "rate_limit_burst": 3}`, ChannelRateConfig{RatePerSecond: 10, Burst: 3}},
+		{"всплеск по умолчанию — скорость с округлением вверх", `{"rate_limit_per_second": 2.5}`, ChannelRateConfig{RatePerSecond: 2.5, Burst: 3}},
+		{"отрицательная скорость отключает ограничение", `{"rate_limit_per_second": -1}`, ChannelRateConfig{Burst: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseChannelRateConfig(tt.config); got != tt.want {
+				t.Errorf("parseChannelRateConfig(%q) = %+v, ожидалось %+v", tt.config, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenBucketThrottlesBurstToRate(t *testing.T) {
+	start := time.Now()
+	bucket := &tokenBucket{rate: 10, burst: 2, tokens: 2, lastFill: start}
+
+	// Всплеск проходит сразу, следующие отправки ждут по 100мс на токен
+	want := []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	for i, expected := range want {
+		if delay := bucket.reserve(start); delay.Round(time.Millisecond) != expected {
+			t.Errorf("отправка %d: ожидание %v, ожидалось %v", i+1, delay, expected)
+		}
+	}
+
+	// Через секунду долг погашен и ведро снова заполнено
+	if delay := bucket.reserve(start.Add(time.Second)); delay != 0 {
+		t.Errorf("после паузы: ожидание %v, ожидалось 0", delay)
+	}
+}
+
+func TestChannelRateLimiterThrottlesPerChannel(t *testing.T) {
+	limiter := NewChannelRateLimiter()
+	cfg := ChannelRateConfig{RatePerSecond: 20, Burst: 2}
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := limiter.Wait(ctx, 1, cfg); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	// 2 отправки во всплеске и еще 4 со скоростью 20 в секунду
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("6 отправок заняли %v, ожидалось не меньше 200мс", elapsed)
+	}
+
+	// Ведро другого канала не затронуто
+	start = time.Now()
+	for i := 0; i < 2; i++ {
+		if err := limiter.Wait(ctx, 2, cfg); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("всплеск другого канала занял %v", elapsed)
+	}
+}
+
+func TestChannelRateLimiterWaitHonorsContext(t *testing.T) {
+	limiter := NewChannelRateLimiter()
+	cfg := ChannelRateConfig{RatePerSecond: 1, Burst: 1}
+	if err := limiter.Wait(context.Background(), 1, cfg); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, 1, cfg); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ожидалась отмена по контексту, получено %v", err)
+	}
+}
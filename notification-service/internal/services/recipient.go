@@ -0,0 +1,61 @@
+package services
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+
+	"notification-service/internal/apperrors"
+)
+
+// phonePattern номер телефона в формате E.164
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// pushTokenPattern токен устройства FCM/APNs: длинная строка без пробелов.
+// Верхняя граница длины проверяется отдельно: regexp не допускает повторов больше 1000.
+var pushTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_:.\-]{32,}$`)
+
+// maxPushTokenLength максимальная длина токена устройства
+const maxPushTokenLength = 4096
+
+// isPhoneNumber проверяет, что строка — номер телефона в формате E.164
+func isPhoneNumber(value string) bool {
+	return phonePattern.MatchString(value)
+}
+
+// isEmail проверяет, что строка — адрес электронной почты без отображаемого имени
+func isEmail(value string) bool {
+	address, err := mail.ParseAddress(value)
+	return err == nil && address.Address == value
+}
+
+// isPushToken проверяет, что строка похожа на токен устройства
+func isPushToken(value string) bool {
+	return len(value) <= maxPushTokenLength && pushTokenPattern.MatchString(value)
+}
+
+// recipientFormats проверка и описание формата получателя по типу уведомления
+var recipientFormats = map[string]struct {
+	valid       func(string) bool
+	description string
+}{
+	"email": {isEmail, "адрес электронной почты"},
+	"sms":   {isPhoneNumber, "номер телефона в формате E.164, например +79991234567"},
+	"push":  {isPushToken, "токен устройства"},
+}
+
+// validateRecipients проверяет формат получателей для типа уведомления.
+// Для типов без известного формата проверка не выполняется.
+func validateRecipients(notificationType string, recipients []string) error {
+	format, ok := recipientFormats[notificationType]
+	if !ok {
+		return nil
+	}
+
+	for _, recipient := range recipients {
+		if !format.valid(recipient) {
+			return apperrors.Validation(fmt.Sprintf("получатель %q не подходит для уведомления типа %s: ожидается %s", recipient, notificationType, format.description))
+		}
+	}
+	return nil
+}
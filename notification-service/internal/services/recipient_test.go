@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"notification-service/internal/apperrors"
+)
+
+func TestValidateRecipients(t *testing.T) {
+	validToken := strings.Repeat("a1B2_c3:d4.e5-", 4)
+
+	tests := []struct {
+		name             string
+		notificationType string
+		recipient        string
+		wantErr          bool
+	}{
+		{"email valid", "email", "user@example.com", false},
+		{"email with display name", "email", "User <user@example.com>", true},
+		{"email without domain", "email", "user", true},
+		{"email as phone", "email", "+79991234567", true},
+
+		{"sms valid", "sms", "+79991234567", false},
+		{"sms without plus", "sms", "79991234567", true},
+		{"sms too short", "sms", "+7999", true},
+		{"sms with letters", "sms", "+7999abc4567", true},
+
+		{"push valid", "push", validToken, false},
+		{"push max length", "push", strings.Repeat("a", maxPushTokenLength), false},
+		{"push too long", "push", strings.Repeat("a", maxPushTokenLength+1), true},
+		{"push too short", "push", "short-token", true},
+		{"push with spaces", "push", strings.Repeat("a", 20) + " " + strings.Repeat("b", 20), true},
+
+		{"unknown type is not checked", "webhook", "anything", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRecipients(tt.notificationType, []string{tt.recipient})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("validateRecipients(%q, %q) = %v, want nil", tt.notificationType, tt.recipient, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validateRecipients(%q, %q) = nil, want error", tt.notificationType, tt.recipient)
+			}
+			var appErr *apperrors.AppError
+			if !errors.As(err, &appErr) || appErr.Status != http.StatusBadRequest {
+				t.Fatalf("validateRecipients(%q, %q) = %v, want 400 validation error", tt.notificationType, tt.recipient, err)
+			}
+		})
+	}
+}
+
+func TestValidateRecipientsRejectsFirstInvalid(t *testing.T) {
+	err := validateRecipients("email", []string{"ok@example.com", "broken"})
+	if err == nil || !strings.Contains(err.Error(), `"broken"`) {
+		t.Fatalf("validateRecipients() = %v, want error naming the invalid recipient", err)
+	}
+}
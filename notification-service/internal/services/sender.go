@@ -0,0 +1,13 @@
+package services
+
+import (
+	"context"
+
+	"notification-service/internal/models"
+)
+
+// Sender доставляет уведомление через внешнего провайдера канала
+type Sender interface {
+	// Send отправляет уведомление и возвращает ID сообщения у провайдера
+	Send(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error)
+}
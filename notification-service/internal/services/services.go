@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"notification-service/internal/apperrors"
 	"notification-service/internal/models"
 	"notification-service/internal/repository"
 
@@ -59,6 +61,7 @@ func NewNotificationTemplateService(templateRepo *repository.NotificationTemplat
 func (s *NotificationTemplateService) CreateTemplate(req *models.NotificationTemplateCreateRequest) (*models.NotificationTemplateResponse, error) {
 	template := &models.NotificationTemplate{
 		Name:      req.Name,
+		Key:       req.Key,
 		Subject:   req.Subject,
 		Body:      req.Body,
 		Type:      req.Type,
@@ -67,6 +70,9 @@ func (s *NotificationTemplateService) CreateTemplate(req *models.NotificationTem
 	}
 
 	if err := s.templateRepo.Create(template); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, apperrors.Conflict("шаблон уведомления с таким именем уже существует")
+		}
 		return nil, fmt.Errorf("ошибка создания шаблона уведомления: %w", err)
 	}
 
@@ -100,7 +106,7 @@ func (s *NotificationTemplateService) GetTemplate(id uint) (*models.Notification
 	template, err := s.templateRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("шаблон уведомления не найден")
+			return nil, apperrors.NotFound("шаблон уведомления не найден")
 		}
 		return nil, fmt.Errorf("ошибка получения шаблона уведомления: %w", err)
 	}
@@ -114,7 +120,7 @@ func (s *NotificationTemplateService) UpdateTemplate(id uint, req *models.Notifi
 	template, err := s.templateRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("шаблон уведомления не найден")
+			return nil, apperrors.NotFound("шаблон уведомления не найден")
 		}
 		return nil, fmt.Errorf("ошибка получения шаблона уведомления: %w", err)
 	}
@@ -123,6 +129,9 @@ func (s *NotificationTemplateService) UpdateTemplate(id uint, req *models.Notifi
 	if req.Name != "" {
 		template.Name = req.Name
 	}
+	if req.Key != "" {
+		template.Key = req.Key
+	}
 	if req.Subject != "" {
 		template.Subject = req.Subject
 	}
@@ -138,6 +147,9 @@ func (s *NotificationTemplateService) UpdateTemplate(id uint, req *models.Notifi
 	template.IsActive = req.IsActive
 
 	if err := s.templateRepo.Update(template); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, apperrors.Conflict("шаблон уведомления с таким именем уже существует")
+		}
 		return nil, fmt.Errorf("ошибка обновления шаблона уведомления: %w", err)
 	}
 
@@ -145,6 +157,39 @@ func (s *NotificationTemplateService) UpdateTemplate(id uint, req *models.Notifi
 	return &response, nil
 }
 
+// SetTemplatesActive включает или отключает шаблоны уведомлений из списка одной транзакцией.
+// Ненайденные ID возвращаются в missing_ids.
+func (s *NotificationTemplateService) SetTemplatesActive(ids []uint, active bool) (*models.NotificationTemplateBulkActiveResponse, error) {
+	templates, err := s.templateRepo.SetActive(ids, active)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка изменения активности шаблонов уведомлений: %w", err)
+	}
+
+	byID := make(map[uint]models.NotificationTemplate, len(templates))
+	for _, template := range templates {
+		byID[template.ID] = template
+	}
+
+	response := &models.NotificationTemplateBulkActiveResponse{
+		Updated:    make([]models.NotificationTemplateResponse, 0, len(templates)),
+		MissingIDs: []uint{},
+	}
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if template, ok := byID[id]; ok {
+			response.Updated = append(response.Updated, template.ToResponse())
+		} else {
+			response.MissingIDs = append(response.MissingIDs, id)
+		}
+	}
+
+	return response, nil
+}
+
 // DeleteTemplate удаляет шаблон уведомления
 func (s *NotificationTemplateService) DeleteTemplate(id uint) error {
 	if err := s.templateRepo.Delete(id); err != nil {
@@ -153,41 +198,75 @@ func (s *NotificationTemplateService) DeleteTemplate(id uint) error {
 	return nil
 }
 
+// ErrEventAlreadyProcessed событие брокера уже обработано, уведомление не создается повторно
+var ErrEventAlreadyProcessed = errors.New("событие уже обработано")
+
 type NotificationService struct {
-	notificationRepo *repository.NotificationRepository
-	templateRepo     *repository.NotificationTemplateRepository
+	notificationRepo   *repository.NotificationRepository
+	templateRepo       *repository.NotificationTemplateRepository
+	channelRepo        *repository.NotificationChannelRepository
+	processedEventRepo *repository.ProcessedEventRepository
+	rateLimiter        *ChannelRateLimiter
+	senders            map[string]Sender
 }
 
-func NewNotificationService(notificationRepo *repository.NotificationRepository, templateRepo *repository.NotificationTemplateRepository) *NotificationService {
+// NewNotificationService создает сервис уведомлений; senders сопоставляет тип канала с отправителем
+func NewNotificationService(notificationRepo *repository.NotificationRepository, templateRepo *repository.NotificationTemplateRepository, channelRepo *repository.NotificationChannelRepository, processedEventRepo *repository.ProcessedEventRepository, rateLimiter *ChannelRateLimiter, senders map[string]Sender) *NotificationService {
 	return &NotificationService{
-		notificationRepo: notificationRepo,
-		templateRepo:     templateRepo,
+		notificationRepo:   notificationRepo,
+		templateRepo:       templateRepo,
+		channelRepo:        channelRepo,
+		processedEventRepo: processedEventRepo,
+		rateLimiter:        rateLimiter,
+		senders:            senders,
 	}
 }
 
-// SendNotification отправляет уведомление
+// SendNotificationOnce отправляет уведомление по событию брокера не более одного раза.
+// Повторная доставка обработанного события возвращает ErrEventAlreadyProcessed. Событие
+// отмечается обработанным только после отправки, а ключ события служит message_id
+// уведомления: если сервис упал между отправкой и отметкой, при повторной доставке
+// уже отправленное уведомление не дублируется, а неудачное отправляется заново.
+func (s *NotificationService) SendNotificationOnce(eventKey, eventType string, req *models.NotificationCreateRequest) (*models.SendNotificationResponse, error) {
+	processed, err := s.processedEventRepo.IsProcessed(eventKey)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки обработанного события: %w", err)
+	}
+	if processed {
+		return nil, ErrEventAlreadyProcessed
+	}
+
+	if req.MessageID == "" {
+		req.MessageID = eventKey
+	}
+	response, err := s.SendNotification(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.processedEventRepo.Claim(eventKey, eventType); err != nil {
+		return nil, fmt.Errorf("ошибка отметки обработанного события: %w", err)
+	}
+
+	return response, nil
+}
+
+// SendNotification отправляет уведомление каждому получателю. Если не удалось отправить
+// ни одному, вместе с ошибкой DeliveryFailed возвращаются статусы получателей
 func (s *NotificationService) SendNotification(req *models.NotificationCreateRequest) (*models.SendNotificationResponse, error) {
-	template, err := s.templateRepo.GetByID(req.TemplateID)
+	recipients := req.RecipientList()
+	if len(recipients) == 0 {
+		return nil, apperrors.Validation("необходимо указать получателя уведомления")
+	}
+
+	channel, err := s.resolveChannel(req.ChannelID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// Автосоздание дефолтного шаблона, если указанный не найден
-			defaultTemplate := &models.NotificationTemplate{
-				Name:      "Report Ready",
-				Subject:   "Report Ready",
-				Body:      "Report {{report_id}} is ready",
-				Type:      "email",
-				Variables: "{}",
-				IsActive:  true,
-			}
-			if createErr := s.templateRepo.Create(defaultTemplate); createErr != nil {
-				return nil, fmt.Errorf("не удалось создать дефолтный шаблон уведомления: %w", createErr)
-			}
-			template = defaultTemplate
-			// Переключаемся на только что созданный шаблон
-			req.TemplateID = template.ID
-		} else {
-			return nil, fmt.Errorf("ошибка получения шаблона уведомления: %w", err)
-		}
+		return nil, err
+	}
+
+	template, err := s.resolveTemplate(req, true)
+	if err != nil {
+		return nil, err
 	}
 
 	dataJSON := ""
@@ -203,42 +282,376 @@ func (s *NotificationService) SendNotification(req *models.NotificationCreateReq
 	subject := replaceVariables(template.Subject, req.Data)
 	body := replaceVariables(template.Body, req.Data)
 
-	notification := &models.Notification{
-		TemplateID: req.TemplateID,
-		Recipient:  req.Recipient,
-		Subject:    subject,
-		Body:       body,
+	notificationType := req.Type
+	if notificationType == "" && channel != nil {
+		notificationType = channel.Type
+	}
+	if notificationType == "" {
+		notificationType = template.Type
+	}
+	if err := validateRecipients(notificationType, recipients); err != nil {
+		return nil, err
+	}
+
+	var rateConfig ChannelRateConfig
+	if channel != nil {
+		rateConfig = parseChannelRateConfig(channel.Config)
+	}
+
+	response := &models.SendNotificationResponse{}
+	var lastErr error
+	sent := 0
+	for _, recipient := range recipients {
+		existing, err := s.findSentMessage(req.MessageID, recipient)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.Status != "failed" {
+			sent++
+			response.Recipients = append(response.Recipients, duplicateStatus(existing))
+			if response.NotificationID == 0 {
+				response.NotificationID = existing.ID
+			}
+			continue
+		}
+
+		if channel != nil {
+			if err := s.rateLimiter.Wait(context.Background(), channel.ID, rateConfig); err != nil {
+				return nil, fmt.Errorf("ошибка ожидания лимита канала: %w", err)
+			}
+		}
+
+		notification := existing
+		if notification != nil {
+			// Повтор с тем же message_id после неудачной отправки отправляет уведомление заново
+			notification.Subject = subject
+			notification.Body = body
+			notification.Data = dataJSON
+			notification.RetryCount++
+			err = s.deliver(notification, channel)
+		} else {
+			notification = &models.Notification{
+				TemplateID:       template.ID,
+				ChannelID:        req.ChannelID,
+				Recipient:        recipient,
+				Subject:          subject,
+				Body:             body,
+				Type:             notificationType,
+				Status:           "pending",
+				Data:             dataJSON,
+				CorrelationID:    req.CorrelationID,
+				MessageID:        req.MessageID,
+				AttachmentFileID: req.AttachmentFileID,
+			}
+			err = s.deliver(notification, channel)
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				// Параллельный запрос с тем же message_id успел создать уведомление раньше
+				concurrent, findErr := s.findSentMessage(req.MessageID, recipient)
+				if findErr != nil {
+					return nil, findErr
+				}
+				if concurrent != nil {
+					status := duplicateStatus(concurrent)
+					if concurrent.Status == "failed" {
+						lastErr = fmt.Errorf("ошибка отправки уведомления %d: %s", concurrent.ID, concurrent.ErrorMessage)
+						status.Error = "не удалось отправить уведомление"
+					} else {
+						sent++
+						if response.NotificationID == 0 {
+							response.NotificationID = concurrent.ID
+						}
+					}
+					response.Recipients = append(response.Recipients, status)
+					continue
+				}
+			}
+		}
+
+		result := models.RecipientStatus{Recipient: recipient, NotificationID: notification.ID}
+		if err != nil {
+			lastErr = err
+			result.Status = "failed"
+			result.Error = "не удалось отправить уведомление"
+		} else {
+			sent++
+			result.Status = notification.Status
+			if response.NotificationID == 0 {
+				response.NotificationID = notification.ID
+			}
+		}
+		response.Recipients = append(response.Recipients, result)
+	}
+
+	if sent == 0 {
+		response.Status = "failed"
+		response.Message = "Не удалось отправить уведомление ни одному получателю"
+		return response, apperrors.DeliveryFailed(lastErr).WithDetails(response)
+	}
+
+	response.Status = "sent"
+	response.Message = "Уведомление отправлено успешно"
+	if sent < len(recipients) {
+		response.Status = "partial"
+		response.Message = fmt.Sprintf("Уведомление отправлено %d из %d получателей", sent, len(recipients))
+	}
+
+	return response, nil
+}
+
+// findSentMessage ищет уведомление, уже созданное с тем же message_id для получателя.
+// Без message_id дедупликация не выполняется.
+func (s *NotificationService) findSentMessage(messageID, recipient string) (*models.Notification, error) {
+	if messageID == "" {
+		return nil, nil
+	}
+	notification, err := s.notificationRepo.GetByMessageID(messageID, recipient)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка проверки message_id: %w", err)
+	}
+	return notification, nil
+}
+
+// duplicateStatus результат отправки для получателя, которому уведомление уже создано
+func duplicateStatus(notification *models.Notification) models.RecipientStatus {
+	return models.RecipientStatus{
+		Recipient:      notification.Recipient,
+		NotificationID: notification.ID,
+		Status:         notification.Status,
+		Duplicate:      true,
+	}
+}
+
+// PreviewNotification рендерит уведомление без создания записей и отправки
+func (s *NotificationService) PreviewNotification(req *models.NotificationCreateRequest) (*models.NotificationPreviewResponse, error) {
+	recipients := req.RecipientList()
+	if len(recipients) == 0 {
+		return nil, apperrors.Validation("необходимо указать получателя уведомления")
+	}
+
+	channel, err := s.resolveChannel(req.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := s.resolveTemplate(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &models.NotificationPreviewResponse{
+		DryRun:     true,
+		TemplateID: template.ID,
+		Subject:    replaceVariables(template.Subject, req.Data),
+		Body:       replaceVariables(template.Body, req.Data),
 		Type:       req.Type,
-		Status:     "pending",
-		Data:       dataJSON,
+		Recipients: recipients,
+	}
+	if channel != nil {
+		preview.ChannelID = channel.ID
+		preview.ChannelName = channel.Name
+		if preview.Type == "" {
+			preview.Type = channel.Type
+		}
 	}
+	if preview.Type == "" {
+		preview.Type = template.Type
+	}
+
+	return preview, nil
+}
 
-	if notification.Type == "" {
-		notification.Type = template.Type
+// defaultTemplates содержит встроенные шаблоны для событий отчетов, которые
+// создаются при первом обращении, если шаблон с таким ключом не заведен
+var defaultTemplates = map[string]models.NotificationTemplate{
+	"report_ready": {
+		Name:      "Report Ready",
+		Key:       "report_ready",
+		Subject:   "Report Ready",
+		Body:      "Report {{report_id}} is ready",
+		Type:      "email",
+		Variables: "{}",
+		IsActive:  true,
+	},
+	"report_failed": {
+		Name:      "Report Failed",
+		Key:       "report_failed",
+		Subject:   "Report Failed",
+		Body:      "Report {{report_id}} generation failed: {{error}}",
+		Type:      "email",
+		Variables: "{}",
+		IsActive:  true,
+	},
+}
+
+// resolveTemplate находит шаблон уведомления по ключу или ID из запроса.
+// При useDefault отсутствующий шаблон заменяется встроенным (report_ready,
+// если ключ не указан), иначе возвращается ошибка NotFound
+func (s *NotificationService) resolveTemplate(req *models.NotificationCreateRequest, useDefault bool) (*models.NotificationTemplate, error) {
+	if req.TemplateID == 0 && req.TemplateKey == "" {
+		return nil, apperrors.Validation("необходимо указать template_id или template_key")
 	}
 
-	if err := s.notificationRepo.Create(notification); err != nil {
-		return nil, fmt.Errorf("ошибка создания уведомления: %w", err)
+	var template *models.NotificationTemplate
+	var err error
+	if req.TemplateKey != "" {
+		template, err = s.templateRepo.GetByKey(req.TemplateKey)
+	} else {
+		template, err = s.templateRepo.GetByID(req.TemplateID)
+	}
+	if err == nil {
+		return template, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("ошибка получения шаблона уведомления: %w", err)
+	}
+
+	defaultKey := "report_ready"
+	if req.TemplateKey != "" {
+		defaultKey = req.TemplateKey
+	}
+	defaultTemplate, ok := defaultTemplates[defaultKey]
+	if !useDefault || !ok {
+		return nil, apperrors.NotFound("шаблон уведомления не найден")
+	}
+
+	// Если указанный шаблон не найден, используем дефолтный, создавая его при необходимости
+	if err := s.templateRepo.FirstOrCreateByName(&defaultTemplate); err != nil {
+		return nil, fmt.Errorf("не удалось создать дефолтный шаблон уведомления: %w", err)
+	}
+	return &defaultTemplate, nil
+}
+
+// resolveChannel получает активный канал отправки, если он указан в запросе
+func (s *NotificationService) resolveChannel(channelID uint) (*models.NotificationChannel, error) {
+	if channelID == 0 {
+		return nil, nil
+	}
+
+	channel, err := s.channelRepo.GetByID(channelID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("канал уведомлений не найден")
+		}
+		return nil, fmt.Errorf("ошибка получения канала уведомлений: %w", err)
+	}
+	if !channel.IsActive {
+		return nil, apperrors.Validation("канал уведомлений неактивен")
+	}
+
+	return channel, nil
+}
+
+// deliver отправляет уведомление одному получателю. Новое уведомление создается в статусе
+// pending, отправляется и получает итоговый статус в одной транзакции: сбой до ее фиксации
+// откатывает запись целиком, поэтому в базе не остается уведомлений, застрявших в pending.
+// Ошибка отправителя транзакцию не откатывает — уведомление сохраняется со статусом failed.
+func (s *NotificationService) deliver(notification *models.Notification, channel *models.NotificationChannel) error {
+	created := notification.ID == 0
+	var sendErr error
+	err := s.notificationRepo.Transaction(func(txRepo *repository.NotificationRepository) error {
+		if created {
+			notification.Status = "pending"
+			if err := txRepo.Create(notification); err != nil {
+				return fmt.Errorf("ошибка создания уведомления: %w", err)
+			}
+		}
+
+		sendErr = s.send(notification, channel)
+
+		if err := txRepo.Update(notification); err != nil {
+			return fmt.Errorf("ошибка обновления статуса уведомления: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if created {
+			// Транзакция откачена: записи с этим ID нет
+			notification.ID = 0
+		}
+		return err
+	}
+	if sendErr != nil {
+		return fmt.Errorf("ошибка отправки уведомления: %w", sendErr)
+	}
+
+	return nil
+}
+
+// send передает уведомление отправителю канала и выставляет итоговый статус.
+// Статус sent ставится только после подтверждения отправителя; если для типа
+// канала нет отправителя, уведомление сразу отмечается отправленным.
+func (s *NotificationService) send(notification *models.Notification, channel *models.NotificationChannel) error {
+	if channel != nil {
+		if sender, ok := s.senders[channel.Type]; ok {
+			providerID, err := sender.Send(context.Background(), channel, notification)
+			if err != nil {
+				notification.Status = "failed"
+				notification.ErrorMessage = err.Error()
+				return err
+			}
+			notification.ProviderID = providerID
+		}
 	}
 
 	now := time.Now()
 	notification.Status = "sent"
 	notification.SentAt = &now
+	notification.ErrorMessage = ""
+	return nil
+}
 
-	if err := s.notificationRepo.Update(notification); err != nil {
-		return nil, fmt.Errorf("ошибка обновления статуса уведомления: %w", err)
+// ResendNotification повторно отправляет неудачное уведомление,
+// заново рендеря тему и текст из шаблона и сохраненных данных
+func (s *NotificationService) ResendNotification(id uint) (*models.NotificationResponse, error) {
+	notification, err := s.notificationRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("уведомление не найдено")
+		}
+		return nil, fmt.Errorf("ошибка получения уведомления: %w", err)
 	}
 
-	return &models.SendNotificationResponse{
-		NotificationID: notification.ID,
-		Status:         notification.Status,
-		Message:        "Уведомление отправлено успешно",
-	}, nil
+	if notification.Status != "failed" {
+		return nil, apperrors.Conflict("повторно отправить можно только неудачное уведомление")
+	}
+
+	var data map[string]interface{}
+	if notification.Data != "" {
+		if err := json.Unmarshal([]byte(notification.Data), &data); err != nil {
+			return nil, fmt.Errorf("ошибка разбора данных уведомления: %w", err)
+		}
+	}
+
+	// Если шаблон удален, отправляем сохраненное содержимое
+	template, err := s.templateRepo.GetByID(notification.TemplateID)
+	switch {
+	case err == nil:
+		notification.Subject = replaceVariables(template.Subject, data)
+		notification.Body = replaceVariables(template.Body, data)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("ошибка получения шаблона уведомления: %w", err)
+	}
+
+	channel, err := s.resolveChannel(notification.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	notification.RetryCount++
+	if err := s.deliver(notification, channel); err != nil {
+		return nil, err
+	}
+
+	response := notification.ToResponse()
+	return &response, nil
 }
 
 // GetNotifications получает список уведомлений
-func (s *NotificationService) GetNotifications(page, limit int, status, recipient string) ([]models.NotificationResponse, int64, error) {
-	notifications, total, err := s.notificationRepo.GetAll(page, limit, status, recipient)
+func (s *NotificationService) GetNotifications(page, limit int, filter models.NotificationFilter) ([]models.NotificationResponse, int64, error) {
+	notifications, total, err := s.notificationRepo.GetAll(page, limit, filter)
 	if err != nil {
 		return nil, 0, fmt.Errorf("ошибка получения уведомлений: %w", err)
 	}
@@ -251,12 +664,41 @@ func (s *NotificationService) GetNotifications(page, limit int, status, recipien
 	return responses, total, nil
 }
 
+// GetNotificationStats считает уведомления по статусам и типам за период
+func (s *NotificationService) GetNotificationStats(filter models.NotificationStatsFilter) (*models.NotificationStatsResponse, error) {
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, apperrors.Validation("from не может быть позже to")
+	}
+
+	byStatus, err := s.notificationRepo.CountGroupedBy("status", filter)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подсчета уведомлений по статусам: %w", err)
+	}
+	byType, err := s.notificationRepo.CountGroupedBy("type", filter)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подсчета уведомлений по типам: %w", err)
+	}
+
+	var total int64
+	for _, count := range byStatus {
+		total += count
+	}
+
+	return &models.NotificationStatsResponse{
+		Total:    total,
+		ByStatus: byStatus,
+		ByType:   byType,
+		From:     filter.From,
+		To:       filter.To,
+	}, nil
+}
+
 // GetNotification получает уведомление по ID
 func (s *NotificationService) GetNotification(id uint) (*models.NotificationResponse, error) {
 	notification, err := s.notificationRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("уведомление не найдено")
+			return nil, apperrors.NotFound("уведомление не найдено")
 		}
 		return nil, fmt.Errorf("ошибка получения уведомления: %w", err)
 	}
@@ -270,30 +712,103 @@ func (s *NotificationService) UpdateNotificationStatus(id uint, status, errorMes
 	notification, err := s.notificationRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("уведомление не найдено")
+			return nil, apperrors.NotFound("уведомление не найдено")
 		}
 		return nil, fmt.Errorf("ошибка получения уведомления: %w", err)
 	}
 
+	applyStatus(notification, status, errorMessage, time.Now())
+
+	if err := s.notificationRepo.Update(notification); err != nil {
+		return nil, fmt.Errorf("ошибка обновления статуса уведомления: %w", err)
+	}
+
+	response := notification.ToResponse()
+	return &response, nil
+}
+
+// deliveryCallbackStatuses статусы, которые может сообщить провайдер
+var deliveryCallbackStatuses = map[string]bool{
+	"sent":      true,
+	"delivered": true,
+	"failed":    true,
+}
+
+// ApplyDeliveryCallbacks применяет пакет результатов доставки в одной транзакции.
+// Неизвестные и некорректные элементы не прерывают пакет и попадают в результат.
+func (s *NotificationService) ApplyDeliveryCallbacks(items []models.DeliveryCallbackItem) (*models.DeliveryCallbackBatchResponse, error) {
+	response := &models.DeliveryCallbackBatchResponse{
+		Results: make([]models.DeliveryCallbackResult, 0, len(items)),
+	}
+
+	err := s.notificationRepo.Transaction(func(txRepo *repository.NotificationRepository) error {
+		for i, item := range items {
+			result := models.DeliveryCallbackResult{Index: i, ID: item.ID, ProviderID: item.ProviderID}
+
+			switch {
+			case item.ID == 0 && item.ProviderID == "":
+				result.Outcome = "invalid"
+				result.Error = "не указан id или provider_id"
+			case !deliveryCallbackStatuses[item.Status]:
+				result.Outcome = "invalid"
+				result.Error = "некорректный статус"
+			default:
+				var notification *models.Notification
+				var err error
+				if item.ID != 0 {
+					notification, err = txRepo.GetByID(item.ID)
+				} else {
+					notification, err = txRepo.GetByProviderID(item.ProviderID)
+				}
+				if err != nil {
+					if !errors.Is(err, gorm.ErrRecordNotFound) {
+						return fmt.Errorf("ошибка получения уведомления: %w", err)
+					}
+					result.Outcome = "not_found"
+					result.Error = "уведомление не найдено"
+					break
+				}
+
+				at := time.Now()
+				if item.Timestamp != nil {
+					at = *item.Timestamp
+				}
+				applyStatus(notification, item.Status, item.Error, at)
+				if err := txRepo.Update(notification); err != nil {
+					return fmt.Errorf("ошибка обновления статуса уведомления: %w", err)
+				}
+				result.ID = notification.ID
+				result.Outcome = "updated"
+			}
+
+			if result.Outcome == "updated" {
+				response.Updated++
+			} else {
+				response.Failed++
+			}
+			response.Results = append(response.Results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// applyStatus выставляет статус уведомления и время отправки или доставки
+func applyStatus(notification *models.Notification, status, errorMessage string, at time.Time) {
 	notification.Status = status
 	notification.ErrorMessage = errorMessage
 
 	if status == "sent" && notification.SentAt == nil {
-		now := time.Now()
-		notification.SentAt = &now
+		notification.SentAt = &at
 	}
 
 	if status == "delivered" && notification.DeliveredAt == nil {
-		now := time.Now()
-		notification.DeliveredAt = &now
-	}
-
-	if err := s.notificationRepo.Update(notification); err != nil {
-		return nil, fmt.Errorf("ошибка обновления статуса уведомления: %w", err)
+		notification.DeliveredAt = &at
 	}
-
-	response := notification.ToResponse()
-	return &response, nil
 }
 
 type NotificationChannelService struct {
@@ -307,12 +822,14 @@ func NewNotificationChannelService(channelRepo *repository.NotificationChannelRe
 }
 
 // CreateChannel создает новый канал уведомлений
-func (s *NotificationChannelService) CreateChannel(req *models.NotificationChannelCreateRequest) (*models.NotificationChannelResponse, error) {
+func (s *NotificationChannelService) CreateChannel(actorID uint, req *models.NotificationChannelCreateRequest) (*models.NotificationChannelResponse, error) {
 	channel := &models.NotificationChannel{
-		Name:     req.Name,
-		Type:     req.Type,
-		Config:   req.Config,
-		IsActive: req.IsActive,
+		Name:      req.Name,
+		Type:      req.Type,
+		Config:    req.Config,
+		IsActive:  req.IsActive,
+		CreatedBy: actorID,
+		UpdatedBy: actorID,
 	}
 
 	if err := s.channelRepo.Create(channel); err != nil {
@@ -349,7 +866,7 @@ func (s *NotificationChannelService) GetChannel(id uint) (*models.NotificationCh
 	channel, err := s.channelRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("канал уведомлений не найден")
+			return nil, apperrors.NotFound("канал уведомлений не найден")
 		}
 		return nil, fmt.Errorf("ошибка получения канала уведомлений: %w", err)
 	}
@@ -359,11 +876,11 @@ func (s *NotificationChannelService) GetChannel(id uint) (*models.NotificationCh
 }
 
 // UpdateChannel обновляет канал уведомлений
-func (s *NotificationChannelService) UpdateChannel(id uint, req *models.NotificationChannelUpdateRequest) (*models.NotificationChannelResponse, error) {
+func (s *NotificationChannelService) UpdateChannel(id, actorID uint, req *models.NotificationChannelUpdateRequest) (*models.NotificationChannelResponse, error) {
 	channel, err := s.channelRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("канал уведомлений не найден")
+			return nil, apperrors.NotFound("канал уведомлений не найден")
 		}
 		return nil, fmt.Errorf("ошибка получения канала уведомлений: %w", err)
 	}
@@ -378,6 +895,7 @@ func (s *NotificationChannelService) UpdateChannel(id uint, req *models.Notifica
 		channel.Config = req.Config
 	}
 	channel.IsActive = req.IsActive
+	channel.UpdatedBy = actorID
 
 	if err := s.channelRepo.Update(channel); err != nil {
 		return nil, fmt.Errorf("ошибка обновления канала уведомлений: %w", err)
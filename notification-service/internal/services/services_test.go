@@ -0,0 +1,527 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"notification-service/internal/apperrors"
+	"notification-service/internal/models"
+	"notification-service/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// senderFunc адаптирует функцию к интерфейсу Sender
+type senderFunc func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error)
+
+func (f senderFunc) Send(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+	return f(ctx, channel, notification)
+}
+
+// testEnv сервис уведомлений поверх отдельной SQLite базы
+type testEnv struct {
+	db      *gorm.DB
+	service *NotificationService
+	channel *models.NotificationChannel
+}
+
+// newTestEnv создает базу с мигрированными моделями, шаблоном и email каналом,
+// отправка через который выполняется sender
+func newTestEnv(t *testing.T, sender Sender) *testEnv {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "notifications.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.AutoMigrate(
+		&models.NotificationTemplate{},
+		&models.Notification{},
+		&models.NotificationChannel{},
+		&models.ProcessedEvent{},
+	); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+
+	template := &models.NotificationTemplate{Name: "Report Ready", Key: "report_ready", Subject: "Отчет {{report_id}}", Body: "Отчет {{report_id}} готов", Type: "email", IsActive: true}
+	if err := db.Create(template).Error; err != nil {
+		t.Fatalf("создание шаблона: %v", err)
+	}
+	channel := &models.NotificationChannel{Name: "SMTP", Type: "email", IsActive: true}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatalf("создание канала: %v", err)
+	}
+
+	service := NewNotificationService(
+		repository.NewNotificationRepository(db),
+		repository.NewNotificationTemplateRepository(db),
+		repository.NewNotificationChannelRepository(db, nil),
+		repository.NewProcessedEventRepository(db),
+		NewChannelRateLimiter(),
+		map[string]Sender{"email": sender},
+	)
+
+	return &testEnv{db: db, service: service, channel: channel}
+}
+
+// request запрос отправки по шаблону report_ready через канал окружения
+func (e *testEnv) request(recipients ...string) *models.NotificationCreateRequest {
+	return &models.NotificationCreateRequest{
+		TemplateKey: "report_ready",
+		ChannelID:   e.channel.ID,
+		Recipients:  recipients,
+		Data:        map[string]interface{}{"report_id": 42},
+	}
+}
+
+// notifications возвращает все сохраненные уведомления по порядку создания
+func (e *testEnv) notifications(t *testing.T) []models.Notification {
+	t.Helper()
+	var items []models.Notification
+	if err := e.db.Order("id").Find(&items).Error; err != nil {
+		t.Fatalf("чтение уведомлений: %v", err)
+	}
+	return items
+}
+
+func TestSendNotificationFinalizesInOneTransaction(t *testing.T) {
+	var env *testEnv
+	var visibleDuringSend bool
+	var statusDuringSend string
+	env = newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		// Вне транзакции уведомление не видно, пока не сохранен итоговый статус
+		var count int64
+		env.db.Model(&models.Notification{}).Where("id = ?", notification.ID).Count(&count)
+		visibleDuringSend = count > 0
+		statusDuringSend = notification.Status
+		return "provider-1", nil
+	}))
+
+	response, err := env.service.SendNotification(env.request("user@example.com"))
+	if err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+	if visibleDuringSend {
+		t.Error("уведомление зафиксировано до сохранения итогового статуса")
+	}
+	if statusDuringSend != "pending" {
+		t.Errorf("статус во время отправки = %q, ожидался pending", statusDuringSend)
+	}
+
+	items := env.notifications(t)
+	if len(items) != 1 {
+		t.Fatalf("сохранено уведомлений: %d, ожидалось 1", len(items))
+	}
+	if items[0].Status != "sent" || items[0].SentAt == nil || items[0].ProviderID != "provider-1" {
+		t.Errorf("уведомление после отправки: status=%q sent_at=%v provider_id=%q", items[0].Status, items[0].SentAt, items[0].ProviderID)
+	}
+	if response.NotificationID != items[0].ID {
+		t.Errorf("notification_id = %d, ожидался %d", response.NotificationID, items[0].ID)
+	}
+}
+
+func TestSendNotificationCrashBeforeSendLeavesNoPending(t *testing.T) {
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		// Процесс падает после создания записи, до обращения к провайдеру
+		panic("сбой между созданием и отправкой")
+	}))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("ожидалась паника отправителя")
+			}
+		}()
+		env.service.SendNotification(env.request("user@example.com"))
+	}()
+
+	if items := env.notifications(t); len(items) != 0 {
+		t.Errorf("после сбоя осталось уведомлений: %d (status=%q), ожидалось 0", len(items), items[0].Status)
+	}
+}
+
+func TestSendNotificationSenderFailureMarksFailed(t *testing.T) {
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		return "", errors.New("smtp недоступен")
+	}))
+
+	if _, err := env.service.SendNotification(env.request("user@example.com")); err == nil {
+		t.Fatal("ожидалась ошибка отправки")
+	}
+
+	items := env.notifications(t)
+	if len(items) != 1 {
+		t.Fatalf("сохранено уведомлений: %d, ожидалось 1", len(items))
+	}
+	if items[0].Status != "failed" || items[0].SentAt != nil || items[0].ErrorMessage != "smtp недоступен" {
+		t.Errorf("уведомление после ошибки: status=%q sent_at=%v error=%q", items[0].Status, items[0].SentAt, items[0].ErrorMessage)
+	}
+}
+
+func TestSendNotificationFailureAfterSendLeavesNoPending(t *testing.T) {
+	failUpdate := false
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		failUpdate = true
+		return "provider-1", nil
+	}))
+	// Сбой БД между отправкой и сохранением итогового статуса
+	env.db.Callback().Update().Before("gorm:update").Register("test:fail_update", func(tx *gorm.DB) {
+		if failUpdate {
+			tx.AddError(errors.New("db down"))
+		}
+	})
+
+	response, err := env.service.SendNotification(env.request("user@example.com"))
+	if err == nil {
+		t.Fatal("ожидалась ошибка сохранения статуса")
+	}
+	if response == nil || response.Recipients[0].NotificationID != 0 {
+		t.Errorf("ответ после отката: %+v, ожидался получатель без notification_id", response)
+	}
+
+	if items := env.notifications(t); len(items) != 0 {
+		t.Errorf("после сбоя осталось уведомлений: %d (status=%q), ожидалось 0", len(items), items[0].Status)
+	}
+}
+
+func TestResolveTemplateCreatesDefaultOnce(t *testing.T) {
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		return "", nil
+	}))
+
+	const workers = 8
+	var wg sync.WaitGroup
+	ids := make([]uint, workers)
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := &models.NotificationCreateRequest{TemplateKey: "report_failed"}
+			template, err := env.service.resolveTemplate(req, true)
+			errs[i] = err
+			if err == nil {
+				ids[i] = template.ID
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("resolveTemplate[%d]: %v", i, err)
+		}
+		if ids[i] == 0 || ids[i] != ids[0] {
+			t.Errorf("resolveTemplate[%d] вернул шаблон %d, ожидался %d", i, ids[i], ids[0])
+		}
+	}
+
+	var count int64
+	env.db.Model(&models.NotificationTemplate{}).Where("key = ?", "report_failed").Count(&count)
+	if count != 1 {
+		t.Errorf("создано шаблонов report_failed: %d, ожидался 1", count)
+	}
+}
+
+func TestSendNotificationSameMessageIDCreatesOne(t *testing.T) {
+	calls := 0
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		calls++
+		return "provider-1", nil
+	}))
+
+	req := env.request("user@example.com")
+	req.MessageID = "msg-1"
+	first, err := env.service.SendNotification(req)
+	if err != nil {
+		t.Fatalf("первая отправка: %v", err)
+	}
+	second, err := env.service.SendNotification(req)
+	if err != nil {
+		t.Fatalf("повторная отправка: %v", err)
+	}
+
+	if items := env.notifications(t); len(items) != 1 {
+		t.Fatalf("сохранено уведомлений: %d, ожидалось 1", len(items))
+	}
+	if calls != 1 {
+		t.Errorf("отправок провайдеру: %d, ожидалась 1", calls)
+	}
+	if second.NotificationID != first.NotificationID || !second.Recipients[0].Duplicate {
+		t.Errorf("повтор вернул %+v, ожидался дубликат уведомления %d", second.Recipients[0], first.NotificationID)
+	}
+}
+
+func TestSendNotificationSameMessageIDResendsFailed(t *testing.T) {
+	fail := true
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		if fail {
+			return "", errors.New("smtp недоступен")
+		}
+		return "provider-2", nil
+	}))
+
+	req := env.request("user@example.com")
+	req.MessageID = "msg-1"
+	if _, err := env.service.SendNotification(req); err == nil {
+		t.Fatal("ожидалась ошибка первой отправки")
+	}
+
+	fail = false
+	response, err := env.service.SendNotification(req)
+	if err != nil {
+		t.Fatalf("повторная отправка: %v", err)
+	}
+	if response.Status != "sent" || response.Recipients[0].Duplicate {
+		t.Errorf("повтор после ошибки: status=%q recipient=%+v", response.Status, response.Recipients[0])
+	}
+
+	items := env.notifications(t)
+	if len(items) != 1 {
+		t.Fatalf("сохранено уведомлений: %d, ожидалось 1", len(items))
+	}
+	if items[0].Status != "sent" || items[0].RetryCount != 1 || items[0].ProviderID != "provider-2" {
+		t.Errorf("уведомление после повтора: status=%q retry_count=%d provider_id=%q", items[0].Status, items[0].RetryCount, items[0].ProviderID)
+	}
+}
+
+func TestSendNotificationConcurrentSameMessageID(t *testing.T) {
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		return "provider-1", nil
+	}))
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := env.request("user@example.com")
+			req.MessageID = "msg-1"
+			_, errs[i] = env.service.SendNotification(req)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("SendNotification[%d]: %v", i, err)
+		}
+	}
+	if items := env.notifications(t); len(items) != 1 {
+		t.Errorf("сохранено уведомлений: %d, ожидалось 1", len(items))
+	}
+}
+
+func TestSendNotificationOnceRedeliveredEvent(t *testing.T) {
+	calls := 0
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		calls++
+		return "provider-1", nil
+	}))
+
+	if _, err := env.service.SendNotificationOnce("evt-1", "report.completed", env.request("user@example.com")); err != nil {
+		t.Fatalf("первая доставка: %v", err)
+	}
+	_, err := env.service.SendNotificationOnce("evt-1", "report.completed", env.request("user@example.com"))
+	if !errors.Is(err, ErrEventAlreadyProcessed) {
+		t.Fatalf("повторная доставка вернула %v, ожидалась ErrEventAlreadyProcessed", err)
+	}
+
+	if items := env.notifications(t); len(items) != 1 {
+		t.Errorf("сохранено уведомлений: %d, ожидалось 1", len(items))
+	}
+	if calls != 1 {
+		t.Errorf("отправок провайдеру: %d, ожидалась 1", calls)
+	}
+}
+
+func TestSendNotificationOnceCrashBeforeClaim(t *testing.T) {
+	calls := 0
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		calls++
+		return "provider-1", nil
+	}))
+
+	// Уведомление отправлено, но событие не успели отметить обработанным
+	if _, err := env.service.SendNotificationOnce("evt-1", "report.completed", env.request("user@example.com")); err != nil {
+		t.Fatalf("первая доставка: %v", err)
+	}
+	if err := env.db.Where("event_key = ?", "evt-1").Delete(&models.ProcessedEvent{}).Error; err != nil {
+		t.Fatalf("удаление отметки: %v", err)
+	}
+
+	response, err := env.service.SendNotificationOnce("evt-1", "report.completed", env.request("user@example.com"))
+	if err != nil {
+		t.Fatalf("повторная доставка: %v", err)
+	}
+	if !response.Recipients[0].Duplicate {
+		t.Errorf("повторная доставка вернула %+v, ожидался дубликат", response.Recipients[0])
+	}
+	if items := env.notifications(t); len(items) != 1 {
+		t.Errorf("сохранено уведомлений: %d, ожидалось 1", len(items))
+	}
+	if calls != 1 {
+		t.Errorf("отправок провайдеру: %d, ожидалась 1", calls)
+	}
+
+	processed, err := repository.NewProcessedEventRepository(env.db).IsProcessed("evt-1")
+	if err != nil || !processed {
+		t.Errorf("событие не отмечено обработанным: processed=%v err=%v", processed, err)
+	}
+}
+
+func TestSendNotificationOnceFailedSendIsRetried(t *testing.T) {
+	fail := true
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		if fail {
+			return "", errors.New("smtp недоступен")
+		}
+		return "provider-1", nil
+	}))
+
+	if _, err := env.service.SendNotificationOnce("evt-1", "report.completed", env.request("user@example.com")); err == nil {
+		t.Fatal("ожидалась ошибка отправки")
+	}
+
+	fail = false
+	if _, err := env.service.SendNotificationOnce("evt-1", "report.completed", env.request("user@example.com")); err != nil {
+		t.Fatalf("повторная доставка: %v", err)
+	}
+
+	items := env.notifications(t)
+	if len(items) != 1 || items[0].Status != "sent" {
+		t.Errorf("после повторной доставки: %d уведомлений, ожидалось одно отправленное", len(items))
+	}
+}
+
+func TestSendNotificationRecipientsHaveIndependentOutcomes(t *testing.T) {
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		if notification.Recipient == "bad@example.com" {
+			return "", errors.New("почтовый ящик не существует")
+		}
+		return "provider-" + notification.Recipient, nil
+	}))
+
+	response, err := env.service.SendNotification(env.request("a@example.com", "bad@example.com", "b@example.com"))
+	if err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+	if response.Status != "partial" || len(response.Recipients) != 3 {
+		t.Fatalf("ответ: status=%q recipients=%d, ожидался partial с 3 получателями", response.Status, len(response.Recipients))
+	}
+
+	want := map[string]string{"a@example.com": "sent", "bad@example.com": "failed", "b@example.com": "sent"}
+	items := env.notifications(t)
+	if len(items) != 3 {
+		t.Fatalf("сохранено уведомлений: %d, ожидалось 3", len(items))
+	}
+	for _, item := range items {
+		if item.Status != want[item.Recipient] {
+			t.Errorf("%s: статус %q, ожидался %q", item.Recipient, item.Status, want[item.Recipient])
+		}
+	}
+	for _, status := range response.Recipients {
+		if status.Status != want[status.Recipient] || status.NotificationID == 0 {
+			t.Errorf("статус получателя %+v, ожидался %q", status, want[status.Recipient])
+		}
+	}
+}
+
+func TestSendNotificationAllRecipientsFailed(t *testing.T) {
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		return "", errors.New("smtp недоступен")
+	}))
+
+	response, err := env.service.SendNotification(env.request("a@example.com", "b@example.com"))
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) || appErr.Status != http.StatusBadGateway {
+		t.Fatalf("ошибка = %v, ожидалась DeliveryFailed", err)
+	}
+	if response == nil || response.Status != "failed" || len(response.Recipients) != 2 {
+		t.Fatalf("ответ = %+v, ожидались статусы обоих получателей", response)
+	}
+	for _, status := range response.Recipients {
+		if status.Status != "failed" || status.NotificationID == 0 {
+			t.Errorf("статус получателя %+v, ожидался failed", status)
+		}
+	}
+	if appErr.Details != response {
+		t.Errorf("details ошибки не содержат статусы получателей")
+	}
+}
+
+func TestResendNotificationRedeliversFailed(t *testing.T) {
+	var sent []models.Notification
+	var sendErr error
+	env := newTestEnv(t, senderFunc(func(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+		sent = append(sent, *notification)
+		return "provider-2", sendErr
+	}))
+
+	failed := &models.Notification{
+		TemplateID:   1,
+		ChannelID:    env.channel.ID,
+		Recipient:    "user@example.com",
+		Subject:      "Старая тема",
+		Type:         "email",
+		Status:       "failed",
+		Data:         `{"report_id": 7}`,
+		ErrorMessage: "smtp недоступен",
+	}
+	if err := env.db.Create(failed).Error; err != nil {
+		t.Fatalf("создание уведомления: %v", err)
+	}
+
+	// Повтор снова неудачен: статус остается failed, счетчик растет
+	sendErr = errors.New("smtp перегружен")
+	if _, err := env.service.ResendNotification(failed.ID); err == nil {
+		t.Fatal("ожидалась ошибка отправки")
+	}
+	items := env.notifications(t)
+	if items[0].Status != "failed" || items[0].RetryCount != 1 || items[0].ErrorMessage != "smtp перегружен" {
+		t.Errorf("после неудачного повтора: status=%q retry_count=%d error=%q", items[0].Status, items[0].RetryCount, items[0].ErrorMessage)
+	}
+
+	sendErr = nil
+	response, err := env.service.ResendNotification(failed.ID)
+	if err != nil {
+		t.Fatalf("ResendNotification: %v", err)
+	}
+	if response.Status != "sent" || response.RetryCount != 2 {
+		t.Errorf("ответ: status=%q retry_count=%d", response.Status, response.RetryCount)
+	}
+
+	// Содержимое заново рендерится из шаблона и сохраненных данных
+	if len(sent) != 2 || sent[1].Subject != "Отчет 7" || sent[1].Body != "Отчет 7 готов" || sent[1].Recipient != "user@example.com" {
+		t.Fatalf("отправлено %+v", sent)
+	}
+	items = env.notifications(t)
+	if items[0].Status != "sent" || items[0].SentAt == nil || items[0].ProviderID != "provider-2" || items[0].RetryCount != 2 {
+		t.Errorf("после повтора: status=%q sent_at=%v provider_id=%q retry_count=%d", items[0].Status, items[0].SentAt, items[0].ProviderID, items[0].RetryCount)
+	}
+
+	// Отправленное уведомление повторно не отправляется
+	_, err = env.service.ResendNotification(failed.ID)
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) || appErr.Status != http.StatusConflict || len(sent) != 2 {
+		t.Errorf("повтор отправленного уведомления: %v, отправок %d", err, len(sent))
+	}
+}
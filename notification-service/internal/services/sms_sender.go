@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"notification-service/internal/models"
+)
+
+// DefaultTwilioEndpoint базовый адрес REST API Twilio
+const DefaultTwilioEndpoint = "https://api.twilio.com/2010-04-01"
+
+// ErrInvalidPhone возвращается, если получатель SMS не похож на номер телефона
+var ErrInvalidPhone = errors.New("получатель SMS должен быть номером телефона в формате E.164, например +79991234567")
+
+// smsChannelConfig параметры SMS провайдера из Config канала
+type smsChannelConfig struct {
+	Provider   string `json:"provider"`
+	AccountSID string `json:"account_sid"`
+	AuthToken  string `json:"auth_token"`
+	From       string `json:"from"`
+}
+
+// twilioMessage ответ Twilio на создание сообщения
+type twilioMessage struct {
+	SID     string `json:"sid"`
+	Message string `json:"message"`
+}
+
+// SMSSender отправляет SMS через провайдера, указанного в Config канала (поддерживается twilio)
+type SMSSender struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewSMSSender создает отправителя SMS. Пустой endpoint заменяется на адрес API Twilio.
+func NewSMSSender(endpoint string) *SMSSender {
+	if endpoint == "" {
+		endpoint = DefaultTwilioEndpoint
+	}
+	return &SMSSender{
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send отправляет текст уведомления на номер телефона из Recipient
+func (s *SMSSender) Send(ctx context.Context, channel *models.NotificationChannel, notification *models.Notification) (string, error) {
+	if !isPhoneNumber(notification.Recipient) {
+		return "", ErrInvalidPhone
+	}
+
+	var cfg smsChannelConfig
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return "", fmt.Errorf("некорректная конфигурация SMS канала: %w", err)
+	}
+
+	switch cfg.Provider {
+	case "twilio":
+		return s.sendTwilio(ctx, &cfg, notification)
+	default:
+		return "", fmt.Errorf("неподдерживаемый SMS провайдер %q", cfg.Provider)
+	}
+}
+
+// sendTwilio создает сообщение через Messages API Twilio
+func (s *SMSSender) sendTwilio(ctx context.Context, cfg *smsChannelConfig, notification *models.Notification) (string, error) {
+	if cfg.AccountSID == "" || cfg.AuthToken == "" {
+		return "", errors.New("в конфигурации SMS канала не указаны account_sid и auth_token")
+	}
+
+	form := url.Values{}
+	form.Set("To", notification.Recipient)
+	form.Set("Body", notification.Body)
+	if cfg.From != "" {
+		form.Set("From", cfg.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", s.endpoint, url.PathEscape(cfg.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания запроса к Twilio: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(cfg.AccountSID, cfg.AuthToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Twilio недоступен: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result twilioMessage
+	decodeErr := json.NewDecoder(resp.Body).Decode(&result)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		if result.Message != "" {
+			return "", fmt.Errorf("Twilio вернул статус %d: %s", resp.StatusCode, result.Message)
+		}
+		return "", fmt.Errorf("Twilio вернул статус %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("ошибка разбора ответа Twilio: %w", decodeErr)
+	}
+	if result.SID == "" {
+		return "", errors.New("Twilio не вернул ID сообщения")
+	}
+
+	return result.SID, nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"notification-service/internal/models"
+)
+
+// twilioRequest запрос, полученный поддельным Twilio
+type twilioRequest struct {
+	path               string
+	account, authToken string
+	form               url.Values
+}
+
+// stubTwilio поддельный Twilio, который запоминает последний запрос и отвечает status и body
+func stubTwilio(t *testing.T, status int, body string) (*httptest.Server, *twilioRequest) {
+	t.Helper()
+
+	received := &twilioRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("разбор запроса к Twilio: %v", err)
+		}
+		received.path = r.URL.Path
+		received.account, received.authToken, _ = r.BasicAuth()
+		received.form = r.PostForm
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+// twilioChannel SMS канал с учетными данными Twilio
+func twilioChannel() *models.NotificationChannel {
+	return &models.NotificationChannel{Type: "sms", Config: `{"provider": "twilio", "account_sid": "AC123", "auth_token": "secret", "from": "+15550001111"}`}
+}
+
+func TestSMSSenderSendsViaTwilio(t *testing.T) {
+	server, received := stubTwilio(t, http.StatusCreated, `{"sid": "SM1"}`)
+	notification := &models.Notification{Recipient: "+79991234567", Body: "Отчет 42 готов", Type: "sms"}
+
+	// Завершающий слэш в адресе не ломает путь к API
+	providerID, err := NewSMSSender(server.URL+"/").Send(context.Background(), twilioChannel(), notification)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if providerID != "SM1" {
+		t.Errorf("provider_id %q, ожидался SM1", providerID)
+	}
+	if received.path != "/Accounts/AC123/Messages.json" || received.account != "AC123" || received.authToken != "secret" {
+		t.Errorf("запрос к %s от %s:%s", received.path, received.account, received.authToken)
+	}
+	if received.form.Get("To") != "+79991234567" || received.form.Get("From") != "+15550001111" || received.form.Get("Body") != "Отчет 42 готов" {
+		t.Errorf("форма запроса %v", received.form)
+	}
+}
+
+func TestSMSSenderReportsProviderErrors(t *testing.T) {
+	notification := &models.Notification{Recipient: "+79991234567", Body: "Отчет 42 готов", Type: "sms"}
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"ошибка с сообщением", http.StatusBadRequest, `{"code": 21211, "message": "Invalid 'To' Phone Number"}`, "Invalid 'To' Phone Number"},
+		{"ошибка без тела", http.StatusServiceUnavailable, ``, "статус 503"},
+		{"нет ID сообщения", http.StatusCreated, `{}`, "не вернул ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := stubTwilio(t, tt.status, tt.body)
+			_, err := NewSMSSender(server.URL).Send(context.Background(), twilioChannel(), notification)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ошибка %v, ожидалась содержащая %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSMSSenderValidatesBeforeRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("запрос к провайдеру не должен выполняться")
+	}))
+	defer server.Close()
+	sender := NewSMSSender(server.URL)
+
+	_, err := sender.Send(context.Background(), twilioChannel(), &models.Notification{Recipient: "user@example.com", Body: "текст"})
+	if !errors.Is(err, ErrInvalidPhone) {
+		t.Errorf("email получателя: %v, ожидалась ErrInvalidPhone", err)
+	}
+
+	channel := &models.NotificationChannel{Type: "sms", Config: `{"provider": "nexmo"}`}
+	if _, err := sender.Send(context.Background(), channel, &models.Notification{Recipient: "+79991234567", Body: "текст"}); err == nil {
+		t.Error("ожидалась ошибка неподдерживаемого провайдера")
+	}
+}
@@ -0,0 +1,135 @@
+package apperrors
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Машиночитаемые коды ошибок
+const (
+	CodeValidation   = "validation_error"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeGone         = "gone"
+	CodeRateLimited  = "too_many_requests"
+	CodeInternal     = "internal_error"
+)
+
+// AppError ошибка приложения с кодом и безопасным сообщением
+type AppError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+	// RetryAfter рекомендуемая пауза перед повтором в секундах, передается и в заголовке Retry-After
+	RetryAfter int   `json:"retry_after_seconds,omitempty"`
+	Status     int   `json:"-"`
+	Err        error `json:"-"`
+}
+
+// Error возвращает текст ошибки
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap возвращает исходную ошибку
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails добавляет детали к ошибке
+func (e *AppError) WithDetails(details interface{}) *AppError {
+	e.Details = details
+	return e
+}
+
+// WithRetryAfter добавляет к ошибке рекомендуемую паузу перед повтором,
+// округленную вверх до целых секунд
+func (e *AppError) WithRetryAfter(d time.Duration) *AppError {
+	e.RetryAfter = int(math.Max(1, math.Ceil(d.Seconds())))
+	return e
+}
+
+// New создает новую ошибку приложения
+func New(status int, code, message string) *AppError {
+	return &AppError{
+		Code:    code,
+		Message: message,
+		Status:  status,
+	}
+}
+
+// Validation создает ошибку валидации
+func Validation(message string) *AppError {
+	return New(http.StatusBadRequest, CodeValidation, message)
+}
+
+// Unauthorized создает ошибку авторизации
+func Unauthorized(message string) *AppError {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden создает ошибку доступа
+func Forbidden(message string) *AppError {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound создает ошибку отсутствия ресурса
+func NotFound(message string) *AppError {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// Conflict создает ошибку конфликта состояния
+func Conflict(message string) *AppError {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+// Gone создает ошибку ресурса, который больше недоступен
+func Gone(message string) *AppError {
+	return New(http.StatusGone, CodeGone, message)
+}
+
+// TooManyRequests создает ошибку превышения допустимой нагрузки
+func TooManyRequests(message string) *AppError {
+	return New(http.StatusTooManyRequests, CodeRateLimited, message)
+}
+
+// Internal создает внутреннюю ошибку, скрывая исходное сообщение от клиента
+func Internal(err error) *AppError {
+	appErr := New(http.StatusInternalServerError, CodeInternal, "Внутренняя ошибка сервера")
+	appErr.Err = err
+	return appErr
+}
+
+// From приводит произвольную ошибку к AppError
+func From(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+// HasStatus сообщает, что err содержит AppError с указанным HTTP статусом
+func HasStatus(err error, status int) bool {
+	var appErr *AppError
+	return errors.As(err, &appErr) && appErr.Status == status
+}
+
+// Respond записывает ошибку в ответ в едином формате
+func Respond(c *gin.Context, err error) {
+	appErr := From(err)
+	if appErr.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(appErr.RetryAfter))
+	}
+	c.JSON(appErr.Status, gin.H{"error": appErr})
+}
@@ -0,0 +1,229 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Действия, фиксируемые в журнале аудита
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// ignoredFields поля, изменение которых не считается изменением сущности
+var ignoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// AuditLog запись журнала аудита: кто, что и как изменил
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey"`
+	ActorID    uint      `gorm:"not null;index"`
+	Action     string    `gorm:"not null;index"`
+	EntityType string    `gorm:"not null;index:idx_audit_logs_entity"`
+	EntityID   uint      `gorm:"not null;index:idx_audit_logs_entity"`
+	Before     string    `gorm:"type:text"`
+	After      string    `gorm:"type:text"`
+	Changes    string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+// TableName возвращает имя таблицы
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// FieldChange значение поля до и после изменения
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditLogResponse запись журнала аудита в ответе API
+type AuditLogResponse struct {
+	ID         uint            `json:"id"`
+	ActorID    uint            `json:"actor_id"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   uint            `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Changes    json.RawMessage `json:"changes,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// ToResponse преобразует запись в ответ API
+func (l *AuditLog) ToResponse() AuditLogResponse {
+	return AuditLogResponse{
+		ID:         l.ID,
+		ActorID:    l.ActorID,
+		Action:     l.Action,
+		EntityType: l.EntityType,
+		EntityID:   l.EntityID,
+		Before:     rawJSON(l.Before),
+		After:      rawJSON(l.After),
+		Changes:    rawJSON(l.Changes),
+		CreatedAt:  l.CreatedAt,
+	}
+}
+
+// AuditLogsResponse страница журнала аудита
+type AuditLogsResponse struct {
+	AuditLogs []AuditLogResponse `json:"audit_logs"`
+	Total     int64              `json:"total"`
+	Page      int                `json:"page"`
+	Limit     int                `json:"limit"`
+}
+
+// Filter условия выборки журнала; нулевые значения не ограничивают выборку
+type Filter struct {
+	ActorID    uint
+	EntityType string
+	EntityID   uint
+	Action     string
+	From       *time.Time
+	To         *time.Time
+}
+
+// Logger пишет и читает журнал аудита
+type Logger struct {
+	db *gorm.DB
+}
+
+// NewLogger создает журнал аудита
+func NewLogger(db *gorm.DB) *Logger {
+	return &Logger{db: db}
+}
+
+// Record записывает изменение сущности. before и after — снимки сущности
+// (nil для создания и удаления соответственно). Ошибка записи не прерывает
+// основную операцию и только логируется.
+func (l *Logger) Record(actorID uint, action, entityType string, entityID uint, before, after interface{}) {
+	entry := &AuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+
+	beforeFields, err := snapshot(before, &entry.Before)
+	if err != nil {
+		logrus.WithError(err).Warnf("Аудит: не удалось сериализовать %s %d", entityType, entityID)
+		return
+	}
+	afterFields, err := snapshot(after, &entry.After)
+	if err != nil {
+		logrus.WithError(err).Warnf("Аудит: не удалось сериализовать %s %d", entityType, entityID)
+		return
+	}
+
+	// Для создания и удаления список изменений повторял бы весь снимок
+	if beforeFields != nil && afterFields != nil {
+		if changes := diff(beforeFields, afterFields); len(changes) > 0 {
+			data, err := json.Marshal(changes)
+			if err == nil {
+				entry.Changes = string(data)
+			}
+		}
+	}
+
+	if err := l.db.Create(entry).Error; err != nil {
+		logrus.WithError(err).Warnf("Аудит: не удалось записать %s %s %d", action, entityType, entityID)
+	}
+}
+
+// List возвращает записи журнала по фильтру, начиная с последних
+func (l *Logger) List(filter Filter, page, limit int) ([]AuditLogResponse, int64, error) {
+	query := l.db.Model(&AuditLog{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []AuditLog
+	offset := (page - 1) * limit
+	if err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]AuditLogResponse, 0, len(entries))
+	for i := range entries {
+		responses = append(responses, entries[i].ToResponse())
+	}
+	return responses, total, nil
+}
+
+// snapshot сериализует снимок сущности в JSON и возвращает его поля
+func snapshot(value interface{}, out *string) (map[string]interface{}, error) {
+	if value == nil || reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	*out = string(data)
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// Снимок не является объектом: сохраняем как есть, без списка изменений
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// diff возвращает поля, значения которых отличаются в снимках
+func diff(before, after map[string]interface{}) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	for key, value := range after {
+		if ignoredFields[key] {
+			continue
+		}
+		if old, ok := before[key]; !ok || !reflect.DeepEqual(old, value) {
+			changes[key] = FieldChange{Before: before[key], After: value}
+		}
+	}
+	for key, old := range before {
+		if ignoredFields[key] {
+			continue
+		}
+		if _, ok := after[key]; !ok {
+			changes[key] = FieldChange{Before: old}
+		}
+	}
+	return changes
+}
+
+// rawJSON возвращает сохраненный JSON для вложения в ответ
+func rawJSON(value string) json.RawMessage {
+	if value == "" {
+		return nil
+	}
+	return json.RawMessage(value)
+}
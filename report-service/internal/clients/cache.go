@@ -0,0 +1,80 @@
+package clients
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlCache кеш значений в памяти процесса со сроком жизни записей.
+// Одновременные промахи по одному ключу выполняют загрузку один раз.
+type ttlCache[K comparable, V any] struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	entries  map[K]cacheEntry[V]
+	inflight map[K]*cacheLoad[V]
+}
+
+// cacheEntry значение кеша и момент его устаревания
+type cacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// cacheLoad загрузка значения, которую ждут остальные запросы того же ключа
+type cacheLoad[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// newTTLCache создает кеш; при ttl <= 0 кеш не используется
+func newTTLCache[K comparable, V any](ttl time.Duration) *ttlCache[K, V] {
+	if ttl <= 0 {
+		return nil
+	}
+	return &ttlCache[K, V]{
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[K]cacheEntry[V]),
+		inflight: make(map[K]*cacheLoad[V]),
+	}
+}
+
+// Get возвращает значение из кеша или загружает его через load.
+// Ошибки загрузки не кешируются; nil кеш всегда вызывает load.
+func (c *ttlCache[K, V]) Get(key K, load func() (V, error)) (V, error) {
+	if c == nil {
+		return load()
+	}
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		if c.now().Before(entry.expiresAt) {
+			c.mu.Unlock()
+			return entry.value, nil
+		}
+		delete(c.entries, key)
+	}
+	if pending, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-pending.done
+		return pending.value, pending.err
+	}
+	pending := &cacheLoad[V]{done: make(chan struct{})}
+	c.inflight[key] = pending
+	c.mu.Unlock()
+
+	pending.value, pending.err = load()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if pending.err == nil {
+		c.entries[key] = cacheEntry[V]{value: pending.value, expiresAt: c.now().Add(c.ttl)}
+	}
+	c.mu.Unlock()
+	close(pending.done)
+
+	return pending.value, pending.err
+}
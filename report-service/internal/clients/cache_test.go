@@ -0,0 +1,135 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// templateServer template-service, считающий запросы по пути; status задает код ответа
+type templateServer struct {
+	mu     sync.Mutex
+	calls  map[string]int
+	status int
+}
+
+func (s *templateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.calls[r.URL.Path]++
+	status := s.status
+	s.mu.Unlock()
+
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/api/v1/variables/" {
+		w.Write([]byte(`{"variables": [{"name": "month", "required": true}]}`))
+		return
+	}
+	w.Write([]byte(`{"id": 3, "name": "Продажи", "type": "csv"}`))
+}
+
+func (s *templateServer) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[path]
+}
+
+func (s *templateServer) setStatus(status int) {
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+}
+
+// newCachedTemplateClient клиент template-service с подменяемыми часами кеша
+func newCachedTemplateClient(t *testing.T, ttl time.Duration) (*TemplateClient, *templateServer, *time.Time) {
+	t.Helper()
+	backend := &templateServer{calls: make(map[string]int), status: http.StatusOK}
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	client := NewTemplateClient(server.URL, RetryPolicy{Attempts: 1}, ttl)
+	now := time.Now()
+	if client.templates != nil {
+		client.templates.now = func() time.Time { return now }
+		client.variables.now = func() time.Time { return now }
+	}
+	return client, backend, &now
+}
+
+func TestTemplateClientCachesWithinTTL(t *testing.T) {
+	client, backend, now := newCachedTemplateClient(t, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		template, err := client.GetTemplate(ctx, 3, "Bearer token")
+		if err != nil {
+			t.Fatalf("запрос %d: %v", i+1, err)
+		}
+		if template.ID != 3 || template.Name != "Продажи" {
+			t.Errorf("шаблон %+v", template)
+		}
+		if _, err := client.GetTemplateVariables(ctx, 3, "Bearer token"); err != nil {
+			t.Fatalf("переменные, запрос %d: %v", i+1, err)
+		}
+		*now = now.Add(20 * time.Second)
+	}
+	if got := backend.count("/api/v1/templates/3"); got != 1 {
+		t.Errorf("запросов шаблона в пределах TTL: %d, ожидался 1", got)
+	}
+	if got := backend.count("/api/v1/variables/"); got != 1 {
+		t.Errorf("запросов переменных в пределах TTL: %d, ожидался 1", got)
+	}
+
+	// Другой шаблон кешируется отдельно
+	if _, err := client.GetTemplate(ctx, 4, ""); err != nil {
+		t.Fatalf("шаблон 4: %v", err)
+	}
+	if got := backend.count("/api/v1/templates/4"); got != 1 {
+		t.Errorf("запросов шаблона 4: %d, ожидался 1", got)
+	}
+
+	// После истечения TTL шаблон загружается заново
+	*now = now.Add(time.Minute)
+	if _, err := client.GetTemplate(ctx, 3, ""); err != nil {
+		t.Fatalf("после TTL: %v", err)
+	}
+	if got := backend.count("/api/v1/templates/3"); got != 2 {
+		t.Errorf("запросов шаблона после TTL: %d, ожидалось 2", got)
+	}
+}
+
+func TestTemplateClientDoesNotCacheErrors(t *testing.T) {
+	client, backend, _ := newCachedTemplateClient(t, time.Minute)
+	ctx := context.Background()
+
+	backend.setStatus(http.StatusNotFound)
+	if _, err := client.GetTemplate(ctx, 3, ""); err == nil {
+		t.Fatal("ошибка template-service не возвращена")
+	}
+
+	backend.setStatus(http.StatusOK)
+	if _, err := client.GetTemplate(ctx, 3, ""); err != nil {
+		t.Fatalf("после восстановления: %v", err)
+	}
+	if got := backend.count("/api/v1/templates/3"); got != 2 {
+		t.Errorf("запросов шаблона: %d, ожидалось 2 — ошибка не должна кешироваться", got)
+	}
+}
+
+func TestTemplateClientWithoutTTLSkipsCache(t *testing.T) {
+	client, backend, _ := newCachedTemplateClient(t, 0)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetTemplate(context.Background(), 3, ""); err != nil {
+			t.Fatalf("запрос %d: %v", i+1, err)
+		}
+	}
+	if got := backend.count("/api/v1/templates/3"); got != 2 {
+		t.Errorf("запросов шаблона без кеша: %d, ожидалось 2", got)
+	}
+}
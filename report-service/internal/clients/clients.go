@@ -0,0 +1,311 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound возвращается, когда downstream сервис ответил 404
+var ErrNotFound = errors.New("ресурс не найден")
+
+// defaultTimeout таймаут запросов к соседним сервисам
+const defaultTimeout = 5 * time.Second
+
+// TemplateInfo сведения о шаблоне из template-service
+type TemplateInfo struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// FileInfo метаданные файла из storage-service
+type FileInfo struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	Size          int64  `json:"size"`
+	MimeType      string `json:"mime_type"`
+	Path          string `json:"path"`
+	Hash          string `json:"hash"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// NotificationInfo сведения об уведомлении из notification-service
+type NotificationInfo struct {
+	ID            uint       `json:"id"`
+	Recipient     string     `json:"recipient"`
+	Type          string     `json:"type"`
+	Status        string     `json:"status"`
+	ErrorMessage  string     `json:"error_message,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CorrelationID string     `json:"correlation_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// TemplateClient клиент template-service
+type TemplateClient struct {
+	baseURL    string
+	httpClient *RetryingClient
+
+	templates *ttlCache[uint, *TemplateInfo]
+	variables *ttlCache[uint, []TemplateVariable]
+}
+
+// NewTemplateClient создает клиент template-service.
+// cacheTTL задает срок кеширования шаблонов и переменных по ID шаблона, 0 отключает кеш.
+func NewTemplateClient(baseURL string, retry RetryPolicy, cacheTTL time.Duration) *TemplateClient {
+	return &TemplateClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: NewRetryingClient(&http.Client{Timeout: defaultTimeout}, retry),
+		templates:  newTTLCache[uint, *TemplateInfo](cacheTTL),
+		variables:  newTTLCache[uint, []TemplateVariable](cacheTTL),
+	}
+}
+
+// GetTemplate получает шаблон по ID; authHeader пробрасывается из исходного запроса
+func (c *TemplateClient) GetTemplate(ctx context.Context, id uint, authHeader string) (*TemplateInfo, error) {
+	return c.templates.Get(id, func() (*TemplateInfo, error) {
+		var template TemplateInfo
+		endpoint := fmt.Sprintf("%s/api/v1/templates/%d", c.baseURL, id)
+		if err := getJSON(ctx, c.httpClient, endpoint, authHeader, &template); err != nil {
+			return nil, fmt.Errorf("template-service: %w", err)
+		}
+		return &template, nil
+	})
+}
+
+// TemplateVariable переменная шаблона из template-service
+type TemplateVariable struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	Default  string `json:"default"`
+}
+
+// GetTemplateVariables получает переменные шаблона
+func (c *TemplateClient) GetTemplateVariables(ctx context.Context, templateID uint, authHeader string) ([]TemplateVariable, error) {
+	return c.variables.Get(templateID, func() ([]TemplateVariable, error) {
+		var page struct {
+			Variables []TemplateVariable `json:"variables"`
+		}
+		endpoint := fmt.Sprintf("%s/api/v1/variables/?template_id=%d&limit=100", c.baseURL, templateID)
+		if err := getJSON(ctx, c.httpClient, endpoint, authHeader, &page); err != nil {
+			return nil, fmt.Errorf("template-service: %w", err)
+		}
+		return page.Variables, nil
+	})
+}
+
+// StorageClient клиент storage-service
+type StorageClient struct {
+	baseURL        string
+	httpClient     *RetryingClient
+	downloadClient *RetryingClient
+}
+
+// NewStorageClient создает клиент storage-service
+func NewStorageClient(baseURL string, retry RetryPolicy) *StorageClient {
+	return &StorageClient{
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		httpClient:     NewRetryingClient(&http.Client{Timeout: defaultTimeout}, retry),
+		downloadClient: NewRetryingClient(&http.Client{}, retry),
+	}
+}
+
+// GetFileByHash получает метаданные файла по MD5 хешу
+func (c *StorageClient) GetFileByHash(ctx context.Context, hash, authHeader string) (*FileInfo, error) {
+	var file FileInfo
+	endpoint := fmt.Sprintf("%s/api/v1/files/hash/%s", c.baseURL, url.PathEscape(hash))
+	if err := getJSON(ctx, c.httpClient, endpoint, authHeader, &file); err != nil {
+		return nil, fmt.Errorf("storage-service: %w", err)
+	}
+	return &file, nil
+}
+
+// FindFilesByCorrelationID получает файлы, загруженные со сквозным ID отчета
+func (c *StorageClient) FindFilesByCorrelationID(ctx context.Context, correlationID, authHeader string) ([]FileInfo, error) {
+	var page struct {
+		Files []FileInfo `json:"files"`
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/files/?correlation_id=%s", c.baseURL, url.QueryEscape(correlationID))
+	if err := getJSON(ctx, c.httpClient, endpoint, authHeader, &page); err != nil {
+		return nil, fmt.Errorf("storage-service: %w", err)
+	}
+	return page.Files, nil
+}
+
+// UploadFile загружает файл в storage-service со сквозным ID отчета. Хранилище не создает
+// дубликат файла с тем же MD5, поэтому запрос повторяется с ключом идемпотентности по хешу
+func (c *StorageClient) UploadFile(ctx context.Context, name string, content []byte, correlationID, authHeader string) (*FileInfo, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return nil, fmt.Errorf("storage-service: ошибка формирования запроса: %w", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, fmt.Errorf("storage-service: ошибка формирования запроса: %w", err)
+	}
+	fields := map[string]string{"name": name, "correlation_id": correlationID}
+	for field, value := range fields {
+		if err := form.WriteField(field, value); err != nil {
+			return nil, fmt.Errorf("storage-service: ошибка формирования запроса: %w", err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("storage-service: ошибка формирования запроса: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/files/upload", bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("storage-service: ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set(IdempotencyKeyHeader, fmt.Sprintf("%x", md5.Sum(content)))
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage-service: сервис недоступен: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("storage-service: неожиданный статус %d", resp.StatusCode)
+	}
+
+	var uploaded struct {
+		File FileInfo `json:"file"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		return nil, fmt.Errorf("storage-service: ошибка разбора ответа: %w", err)
+	}
+	return &uploaded.File, nil
+}
+
+// DownloadFile копирует содержимое файла в w без буферизации в памяти
+func (c *StorageClient) DownloadFile(ctx context.Context, fileID uint, authHeader string, w io.Writer) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.DownloadURL(fileID), nil)
+	if err != nil {
+		return 0, fmt.Errorf("storage-service: ошибка создания запроса: %w", err)
+	}
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	// Таймаут клиента ограничил бы размер скачиваемого файла, поэтому полагаемся на контекст
+	resp, err := c.downloadClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("storage-service: сервис недоступен: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, fmt.Errorf("storage-service: %w", ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("storage-service: неожиданный статус %d", resp.StatusCode)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("storage-service: ошибка чтения файла: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteFile удаляет файл из хранилища; отсутствие файла считается успешным удалением
+func (c *StorageClient) DeleteFile(ctx context.Context, fileID uint, authHeader string) error {
+	endpoint := fmt.Sprintf("%s/api/v1/files/%d", c.baseURL, fileID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("storage-service: ошибка создания запроса: %w", err)
+	}
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("storage-service: сервис недоступен: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("storage-service: неожиданный статус %d", resp.StatusCode)
+	}
+}
+
+// DownloadURL возвращает адрес скачивания файла
+func (c *StorageClient) DownloadURL(fileID uint) string {
+	return fmt.Sprintf("%s/api/v1/files/%d/download", c.baseURL, fileID)
+}
+
+// NotificationClient клиент notification-service
+type NotificationClient struct {
+	baseURL    string
+	httpClient *RetryingClient
+}
+
+// NewNotificationClient создает клиент notification-service
+func NewNotificationClient(baseURL string, retry RetryPolicy) *NotificationClient {
+	return &NotificationClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: NewRetryingClient(&http.Client{Timeout: defaultTimeout}, retry),
+	}
+}
+
+// FindByCorrelationID получает уведомления, отправленные по событиям отчета со сквозным ID
+func (c *NotificationClient) FindByCorrelationID(ctx context.Context, correlationID, authHeader string) ([]NotificationInfo, error) {
+	var page struct {
+		Notifications []NotificationInfo `json:"notifications"`
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/notifications/?correlation_id=%s", c.baseURL, url.QueryEscape(correlationID))
+	if err := getJSON(ctx, c.httpClient, endpoint, authHeader, &page); err != nil {
+		return nil, fmt.Errorf("notification-service: %w", err)
+	}
+	return page.Notifications, nil
+}
+
+// getJSON выполняет GET запрос и декодирует JSON ответ
+func getJSON(ctx context.Context, client *RetryingClient, endpoint, authHeader string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("сервис недоступен: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("неожиданный статус %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("ошибка разбора ответа: %w", err)
+	}
+	return nil
+}
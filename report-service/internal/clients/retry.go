@@ -0,0 +1,118 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// IdempotencyKeyHeader заголовок, при наличии которого POST и PATCH считаются безопасными для повтора
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy параметры повторов запросов к соседним сервисам
+type RetryPolicy struct {
+	Attempts   int           // общее число попыток, 1 — без повторов
+	Backoff    time.Duration // задержка перед первым повтором, далее удваивается
+	MaxBackoff time.Duration // верхняя граница задержки, 0 — без ограничения
+}
+
+// RetryingClient HTTP клиент, повторяющий запросы при ошибках соединения и ответах 5xx.
+// Неидемпотентные запросы (POST, PATCH) повторяются только с заголовком Idempotency-Key.
+type RetryingClient struct {
+	client *http.Client
+	policy RetryPolicy
+}
+
+// NewRetryingClient создает клиент с повторами поверх client
+func NewRetryingClient(client *http.Client, policy RetryPolicy) *RetryingClient {
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+	return &RetryingClient{client: client, policy: policy}
+}
+
+// Do выполняет запрос с повторами; возвращает последний ответ или ошибку
+func (c *RetryingClient) Do(req *http.Request) (*http.Response, error) {
+	attempts := c.policy.Attempts
+	if !canRetry(req) {
+		attempts = 1
+	}
+
+	delay := c.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if attempt >= attempts || !shouldRetry(req.Context(), resp, err) {
+			return resp, err
+		}
+
+		if err != nil {
+			logrus.WithError(err).Warnf("%s %s: попытка %d из %d не удалась, повтор через %s", req.Method, req.URL.Path, attempt, attempts, delay)
+		} else {
+			logrus.Warnf("%s %s: статус %d (попытка %d из %d), повтор через %s", req.Method, req.URL.Path, resp.StatusCode, attempt, attempts, delay)
+			// Дочитываем тело, чтобы соединение вернулось в пул
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		delay *= 2
+		if c.policy.MaxBackoff > 0 && delay > c.policy.MaxBackoff {
+			delay = c.policy.MaxBackoff
+		}
+	}
+}
+
+// canRetry проверяет, что запрос можно безопасно отправить повторно
+func canRetry(req *http.Request) bool {
+	// Тело без GetBody нельзя отправить второй раз
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	default:
+		return req.Header.Get(IdempotencyKeyHeader) != ""
+	}
+}
+
+// shouldRetry повторяет ошибки соединения и ответы 5xx, но не отмену контекста
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// sleepContext ждет d или отмены контекста
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
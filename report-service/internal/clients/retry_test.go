@@ -0,0 +1,113 @@
+package clients
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer отвечает 503 на первые failures запросов, затем 200, и проверяет тело каждого запроса
+func flakyServer(t *testing.T, failures int32, wantBody string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != wantBody {
+			t.Errorf("попытка %d: тело %q, ожидалось %q", calls.Load()+1, body, wantBody)
+		}
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// newTestRetryingClient клиент с тремя попытками и минимальной задержкой
+func newTestRetryingClient() *RetryingClient {
+	return NewRetryingClient(&http.Client{Timeout: 5 * time.Second}, RetryPolicy{Attempts: 3, Backoff: time.Millisecond})
+}
+
+func TestRetryingClientRetriesServiceUnavailable(t *testing.T) {
+	server, calls := flakyServer(t, 2, "")
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := newTestRetryingClient().Do(req)
+	if err != nil {
+		t.Fatalf("запрос: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("статус %d после %d попыток, ожидался 200 после 3", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestRetryingClientReturnsLastResponseWhenAttemptsExhausted(t *testing.T) {
+	server, calls := flakyServer(t, 10, "")
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL, nil)
+	resp, err := newTestRetryingClient().Do(req)
+	if err != nil {
+		t.Fatalf("запрос: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 3 {
+		t.Errorf("статус %d после %d попыток, ожидался 503 после 3", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestRetryingClientPostRequiresIdempotencyKey(t *testing.T) {
+	const body = `{"report_id":1}`
+
+	server, calls := flakyServer(t, 1, body)
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+	resp, err := newTestRetryingClient().Do(req)
+	if err != nil {
+		t.Fatalf("запрос: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("POST без ключа: статус %d после %d попыток, ожидался 503 без повтора", resp.StatusCode, calls.Load())
+	}
+
+	// С ключом идемпотентности POST повторяется с тем же телом
+	server, calls = flakyServer(t, 1, body)
+	req, _ = http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, "report-1")
+	resp, err = newTestRetryingClient().Do(req)
+	if err != nil {
+		t.Fatalf("запрос: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("POST с ключом: статус %d после %d попыток, ожидался 200 после 2", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestRetryingClientDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := newTestRetryingClient().Do(req)
+	if err != nil {
+		t.Fatalf("запрос: %v", err)
+	}
+	resp.Body.Close()
+	if calls.Load() != 1 {
+		t.Errorf("ответ 404 запрошен %d раз, ожидался 1", calls.Load())
+	}
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/sirupsen/logrus"
@@ -11,10 +13,80 @@ type Config struct {
 	Port        int    `envconfig:"PORT" default:"8083"`
 	Environment string `envconfig:"ENVIRONMENT" default:"development"`
 	LogLevel    string `envconfig:"LOG_LEVEL" default:"info"`
+	LogFormat   string `envconfig:"LOG_FORMAT" default:""` // json или text
+	// ShutdownTimeout время на завершение запросов и выполняющихся Saga при остановке
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"25s"`
 
 	DatabaseURL string `envconfig:"DATABASE_URL" required:"true"`
-	JWTSecret   string `envconfig:"JWT_SECRET" required:"true"`
-	RabbitMQURL string `envconfig:"RABBITMQ_URL" default:""`
+	// DBConnectAttempts и DBConnectInterval задают ожидание БД при старте: число попыток и начальный интервал
+	DBConnectAttempts int           `envconfig:"DB_CONNECT_ATTEMPTS" default:"10"`
+	DBConnectInterval time.Duration `envconfig:"DB_CONNECT_INTERVAL" default:"1s"`
+	JWTSecret         string        `envconfig:"JWT_SECRET" required:"true"`
+	RabbitMQURL       string        `envconfig:"RABBITMQ_URL" default:""`
+
+	// UserServiceURL адрес User Service для проверки API токенов; APITokenCacheTTL время
+	// кеширования проверки, отзыв токена вступает в силу не позже чем через этот интервал
+	UserServiceURL   string        `envconfig:"USER_SERVICE_URL" default:"http://localhost:8081"`
+	APITokenCacheTTL time.Duration `envconfig:"API_TOKEN_CACHE_TTL" default:"30s"`
+
+	SagaWorkers   int `envconfig:"SAGA_WORKERS" default:"10"`
+	SagaQueueSize int `envconfig:"SAGA_QUEUE_SIZE" default:"100"`
+	// SagaMaxSteps максимальное число шагов в одной Saga; ноль снимает ограничение
+	SagaMaxSteps int `envconfig:"SAGA_MAX_STEPS" default:"50"`
+	// SagaIDGenerator формат ID новых Saga: uuid (UUIDv4) или timestamp (прежний формат со временем)
+	SagaIDGenerator string `envconfig:"SAGA_ID_GENERATOR" default:"uuid"`
+
+	// Поиск Saga, зависших в статусе executing; нулевой порог отключает проверку
+	SagaStaleThreshold     time.Duration `envconfig:"SAGA_STALE_THRESHOLD" default:"10m"`
+	SagaStaleCheckInterval time.Duration `envconfig:"SAGA_STALE_CHECK_INTERVAL" default:"1m"`
+	SagaStaleMaxRetries    int           `envconfig:"SAGA_STALE_MAX_RETRIES" default:"3"`
+
+	// Срок хранения завершенных и компенсированных Saga для POST /admin/sagas/cleanup
+	SagaRetention        time.Duration `envconfig:"SAGA_RETENTION" default:"720h"`
+	SagaCleanupBatchSize int           `envconfig:"SAGA_CLEANUP_BATCH_SIZE" default:"500"`
+
+	// SagaStreamPollInterval интервал опроса состояния Saga для GET /sagas/:id/stream
+	SagaStreamPollInterval time.Duration `envconfig:"SAGA_STREAM_POLL_INTERVAL" default:"500ms"`
+
+	// Интервал обновления метрик outbox_pending и outbox_failed; ноль отключает обновление
+	OutboxMetricsInterval time.Duration `envconfig:"OUTBOX_METRICS_INTERVAL" default:"30s"`
+	// OutboxBatchSize размер пачки публикации Outbox по умолчанию; меняется через /admin/settings/outbox.batch_size
+	OutboxBatchSize int `envconfig:"OUTBOX_BATCH_SIZE" default:"10"`
+
+	// SettingsRefreshInterval период перечитывания настроек из базы
+	SettingsRefreshInterval time.Duration `envconfig:"SETTINGS_REFRESH_INTERVAL" default:"30s"`
+
+	DefaultPageLimit int `envconfig:"DEFAULT_PAGE_LIMIT" default:"10"`
+	MaxPageLimit     int `envconfig:"MAX_PAGE_LIMIT" default:"100"`
+
+	// Адреса соседних сервисов
+	TemplateServiceURL     string `envconfig:"TEMPLATE_SERVICE_URL" default:"http://localhost:8082"`
+	StorageServiceURL      string `envconfig:"STORAGE_SERVICE_URL" default:"http://localhost:8087"`
+	NotificationServiceURL string `envconfig:"NOTIFICATION_SERVICE_URL" default:"http://localhost:8085"`
+
+	// TemplateCacheTTL срок кеширования шаблонов при выполнении Saga; 0 отключает кеш
+	TemplateCacheTTL time.Duration `envconfig:"TEMPLATE_CACHE_TTL" default:"1m"`
+
+	// Повторы запросов к соседним сервисам при ошибках соединения и 5xx
+	HTTPRetryAttempts   int           `envconfig:"HTTP_RETRY_ATTEMPTS" default:"3"`
+	HTTPRetryBackoff    time.Duration `envconfig:"HTTP_RETRY_BACKOFF" default:"200ms"`
+	HTTPRetryMaxBackoff time.Duration `envconfig:"HTTP_RETRY_MAX_BACKOFF" default:"2s"`
+
+	// Публичные ссылки на отчеты; пустой ShareSecret заменяется на JWTSecret
+	ShareSecret   string        `envconfig:"SHARE_SECRET" default:""`
+	ShareLinkTTL  time.Duration `envconfig:"SHARE_LINK_TTL" default:"24h"`
+	PublicBaseURL string        `envconfig:"PUBLIC_BASE_URL" default:"http://localhost:8083"`
+
+	// GenerationLockTTL срок блокировки генерации отчета на случай, если Saga не сняла ее (падение реплики)
+	GenerationLockTTL time.Duration `envconfig:"GENERATION_LOCK_TTL" default:"30m"`
+
+	// ReportTTL срок хранения файла отчета после генерации, если он не указан в запросе; 0 — бессрочно
+	ReportTTL time.Duration `envconfig:"REPORT_TTL" default:"0"`
+	// ReportExpirationInterval интервал удаления просроченных отчетов; ноль отключает удаление
+	ReportExpirationInterval time.Duration `envconfig:"REPORT_EXPIRATION_INTERVAL" default:"10m"`
+
+	// HideForeignReports возвращает 404 вместо 403 для чужих отчетов
+	HideForeignReports bool `envconfig:"HIDE_FOREIGN_REPORTS" default:"true"`
 
 	AutoMigrate bool `envconfig:"AUTO_MIGRATE" default:"true"`
 	SeedData    bool `envconfig:"SEED_DATA" default:"true"`
@@ -30,18 +102,31 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// SetupLogger применяет LOG_FORMAT и LOG_LEVEL. Без LOG_FORMAT в production
+// используется JSON, иначе текст; некорректный уровень заменяется на info.
 func (c *Config) SetupLogger() {
+	switch strings.ToLower(c.LogFormat) {
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	default:
+		if c.Environment == "production" {
+			logrus.SetFormatter(&logrus.JSONFormatter{})
+		} else {
+			logrus.SetFormatter(&logrus.TextFormatter{})
+		}
+		if c.LogFormat != "" {
+			logrus.Warnf("Неизвестный LOG_FORMAT %q, формат выбран по окружению", c.LogFormat)
+		}
+	}
+
 	level, err := logrus.ParseLevel(c.LogLevel)
 	if err != nil {
 		level = logrus.InfoLevel
+		logrus.Warnf("Некорректный LOG_LEVEL %q, используется info", c.LogLevel)
 	}
 	logrus.SetLevel(level)
-
-	if c.Environment == "production" {
-		logrus.SetFormatter(&logrus.JSONFormatter{})
-	} else {
-		logrus.SetFormatter(&logrus.TextFormatter{})
-	}
 }
 
 func (c *Config) IsDevelopment() bool {
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// captureLogs перенаправляет вывод logrus в буфер и восстанавливает настройки после теста
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	logger := logrus.StandardLogger()
+	out, formatter, level := logger.Out, logger.Formatter, logger.GetLevel()
+	t.Cleanup(func() {
+		logrus.SetOutput(out)
+		logrus.SetFormatter(formatter)
+		logrus.SetLevel(level)
+	})
+
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	return &buf
+}
+
+func TestSetupLoggerSelectsFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		environment string
+		wantJSON    bool
+		wantWarning bool
+	}{
+		{"json", "json", "development", true, false},
+		{"text в production", "TEXT", "production", false, false},
+		{"по умолчанию в development", "", "development", false, false},
+		{"по умолчанию в production", "", "production", true, false},
+		{"неизвестный формат", "yaml", "production", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLogs(t)
+			(&Config{LogFormat: tt.format, LogLevel: "info", Environment: tt.environment}).SetupLogger()
+
+			_, isJSON := logrus.StandardLogger().Formatter.(*logrus.JSONFormatter)
+			if isJSON != tt.wantJSON {
+				t.Errorf("JSON формат %v, ожидалось %v", isJSON, tt.wantJSON)
+			}
+			if warned := strings.Contains(buf.String(), "Неизвестный LOG_FORMAT"); warned != tt.wantWarning {
+				t.Errorf("предупреждение %v, ожидалось %v: %s", warned, tt.wantWarning, buf.String())
+			}
+		})
+	}
+}
+
+func TestSetupLoggerWritesJSON(t *testing.T) {
+	buf := captureLogs(t)
+	(&Config{LogFormat: "json", LogLevel: "debug"}).SetupLogger()
+
+	logrus.WithField("report_id", 42).Debug("Отчет создан")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("запись не JSON: %v: %s", err, buf.String())
+	}
+	if entry["level"] != "debug" || entry["msg"] != "Отчет создан" || entry["report_id"] != float64(42) || entry["time"] == nil {
+		t.Errorf("запись %v", entry)
+	}
+}
+
+func TestSetupLoggerFallsBackToInfoLevel(t *testing.T) {
+	for _, level := range []string{"verbose", ""} {
+		t.Run(level, func(t *testing.T) {
+			buf := captureLogs(t)
+			logrus.SetLevel(logrus.TraceLevel)
+			(&Config{LogFormat: "json", LogLevel: level}).SetupLogger()
+
+			if got := logrus.GetLevel(); got != logrus.InfoLevel {
+				t.Errorf("уровень %v, ожидался info", got)
+			}
+			// Предупреждение пишется уже в выбранном формате
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("предупреждение не JSON: %v: %s", err, buf.String())
+			}
+			if entry["level"] != "warning" || !strings.Contains(entry["msg"].(string), "Некорректный LOG_LEVEL") {
+				t.Errorf("предупреждение %v", entry)
+			}
+		})
+	}
+
+	buf := captureLogs(t)
+	(&Config{LogLevel: "WARN"}).SetupLogger()
+	if logrus.GetLevel() != logrus.WarnLevel || buf.Len() != 0 {
+		t.Errorf("уровень %v, вывод %q", logrus.GetLevel(), buf.String())
+	}
+}
@@ -0,0 +1,58 @@
+package csvutil
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteRecordsQuotesSpecialCharacters(t *testing.T) {
+	headers := []string{"id", "name", "comment"}
+	rows := [][]string{
+		{"1", "Иванов, Иван", "обычный"},
+		{"2", `Отчет "Продажи"`, "многострочный\nкомментарий"},
+		{"3", "", " пробелы "}, // ведущий пробел тоже заключается в кавычки
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, headers, rows); err != nil {
+		t.Fatalf("запись CSV: %v", err)
+	}
+
+	want := "id,name,comment\n" +
+		"1,\"Иванов, Иван\",обычный\n" +
+		"2,\"Отчет \"\"Продажи\"\"\",\"многострочный\nкомментарий\"\n" +
+		"3,,\" пробелы \"\n"
+	if buf.String() != want {
+		t.Errorf("получено:\n%s\nожидалось:\n%s", buf.String(), want)
+	}
+
+	// Результат читается стандартным разборщиком без потерь
+	parsed, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("разбор CSV: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, append([][]string{headers}, rows...)) {
+		t.Errorf("после разбора получено %q", parsed)
+	}
+}
+
+func TestWriteRecordsRejectsMismatchedRow(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteRecords(&buf, []string{"id", "name"}, [][]string{{"1", "a"}, {"2"}})
+	if err == nil || !strings.Contains(err.Error(), "строка 2") {
+		t.Fatalf("ожидалась ошибка для строки 2, получено %v", err)
+	}
+}
+
+func TestWriteRecordsWithoutHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, nil, [][]string{{"a"}, {"b", "c"}}); err != nil {
+		t.Fatalf("запись CSV: %v", err)
+	}
+	if buf.String() != "a\nb,c\n" {
+		t.Errorf("получено %q", buf.String())
+	}
+}
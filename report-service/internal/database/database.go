@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"log"
 
+	"report-service/internal/audit"
 	"report-service/internal/config"
 	"report-service/internal/models"
+	"report-service/internal/settings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -50,6 +52,10 @@ func Migrate() error {
 
 	err := db.AutoMigrate(
 		&models.Report{},
+		&models.ReportShare{},
+		&models.ReportGenerationLock{},
+		&audit.AuditLog{},
+		&settings.Setting{},
 	)
 	if err != nil {
 		return fmt.Errorf("ошибка миграции: %w", err)
@@ -79,7 +85,7 @@ func SeedData() error {
 			UserID:      1,
 			Status:      string(models.StatusCompleted),
 			Parameters:  `{"start_date": "2024-01-01", "end_date": "2024-01-31"}`,
-			FilePath:    "/reports/sales_january_2024.pdf",
+			FilePath:    "/reports/sales_january_2024.csv",
 			FileSize:    1024000,
 			MD5Hash:     "abc123def456",
 		},
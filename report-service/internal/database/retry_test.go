@@ -0,0 +1,64 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// flakyConnect возвращает подключение, которое удается только после failures неудачных попыток
+func flakyConnect(db *gorm.DB, failures int, calls *int) func() (*gorm.DB, error) {
+	return func() (*gorm.DB, error) {
+		*calls++
+		if *calls <= failures {
+			return nil, errors.New("connection refused")
+		}
+		return db, nil
+	}
+}
+
+func TestRetryConnectSucceedsAfterFailures(t *testing.T) {
+	db := newTestDB(t)
+
+	calls := 0
+	start := time.Now()
+	conn, err := retryConnect(flakyConnect(db, 2, &calls), 5, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("подключение: %v", err)
+	}
+	if conn != db || calls != 3 {
+		t.Errorf("попыток %d, ожидалось 3; подключение %p, ожидалось %p", calls, conn, db)
+	}
+	// Задержка удваивается: 10мс после первой неудачи и 20мс после второй
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("подключение заняло %v, ожидалось не меньше 30мс", elapsed)
+	}
+}
+
+func TestRetryConnectGivesUpAfterAttempts(t *testing.T) {
+	calls := 0
+	conn, err := retryConnect(flakyConnect(nil, 10, &calls), 3, time.Millisecond)
+	if conn != nil || err == nil {
+		t.Fatalf("подключение %v, ошибка %v, ожидалась ошибка", conn, err)
+	}
+	if calls != 3 {
+		t.Errorf("попыток %d, ожидалось 3", calls)
+	}
+	if err.Error() != "база данных недоступна после 3 попыток: connection refused" {
+		t.Errorf("ошибка %q", err)
+	}
+}
+
+func TestRetryConnectMakesAtLeastOneAttempt(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, attempts := range []int{0, -1} {
+		calls := 0
+		conn, err := retryConnect(flakyConnect(db, 0, &calls), attempts, time.Hour)
+		if err != nil || conn != db || calls != 1 {
+			t.Errorf("attempts=%d: попыток %d, ошибка %v", attempts, calls, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// txRecord запись для проверки фиксации и отката транзакций
+type txRecord struct {
+	ID   uint
+	Name string
+}
+
+// newTestDB создает отдельную SQLite базу с таблицей txRecord
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "tx.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&txRecord{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+	return db
+}
+
+// countRecords возвращает число сохраненных записей
+func countRecords(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(&txRecord{}).Count(&count).Error; err != nil {
+		t.Fatalf("подсчет записей: %v", err)
+	}
+	return count
+}
+
+func TestWithTransactionCommits(t *testing.T) {
+	db := newTestDB(t)
+
+	err := WithTransaction(db, func(tx *gorm.DB) error {
+		if err := tx.Create(&txRecord{Name: "отчет"}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&txRecord{Name: "событие"}).Error
+	})
+	if err != nil {
+		t.Fatalf("транзакция: %v", err)
+	}
+	if count := countRecords(t, db); count != 2 {
+		t.Errorf("сохранено записей: %d, ожидалось 2", count)
+	}
+}
+
+func TestWithTransactionRollsBackOnError(t *testing.T) {
+	db := newTestDB(t)
+	errOutbox := errors.New("ошибка записи события")
+
+	err := WithTransaction(db, func(tx *gorm.DB) error {
+		if err := tx.Create(&txRecord{Name: "отчет"}).Error; err != nil {
+			return err
+		}
+		return errOutbox
+	})
+	if !errors.Is(err, errOutbox) {
+		t.Fatalf("ожидалась исходная ошибка, получено %v", err)
+	}
+	if count := countRecords(t, db); count != 0 {
+		t.Errorf("после отката осталось записей: %d", count)
+	}
+}
+
+func TestWithTransactionRollsBackOnPanic(t *testing.T) {
+	db := newTestDB(t)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("паника не передана вызывающему")
+			}
+		}()
+		WithTransaction(db, func(tx *gorm.DB) error {
+			tx.Create(&txRecord{Name: "отчет"})
+			panic("сбой обработчика")
+		})
+	}()
+
+	if count := countRecords(t, db); count != 0 {
+		t.Errorf("после паники осталось записей: %d", count)
+	}
+}
@@ -2,6 +2,7 @@ package events
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"time"
 )
@@ -21,11 +22,14 @@ func NewSagaCoordinator(publisher EventPublisher) *SagaCoordinator {
 func (sc *SagaCoordinator) StartSaga(ctx context.Context, saga *Saga) error {
 	log.Printf("Запуск Saga %s: %s", saga.ID, saga.Name)
 
-	event := NewEvent(SagaStarted, "report-service", map[string]interface{}{
+	event, err := NewEvent(SagaStarted, "report-service", map[string]interface{}{
 		"saga_id":   saga.ID,
 		"saga_name": saga.Name,
 		"steps":     len(saga.Steps),
 	})
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
 
 	return sc.publisher.Publish(ctx, event)
 }
@@ -35,10 +39,13 @@ func (sc *SagaCoordinator) ExecuteStep(ctx context.Context, sagaID string, stepI
 
 	time.Sleep(100 * time.Millisecond)
 
-	event := NewEvent(ReportGenerated, "report-service", map[string]interface{}{
+	event, err := NewEvent(ReportGenerated, "report-service", map[string]interface{}{
 		"saga_id": sagaID,
 		"step_id": stepID,
 	})
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
 
 	return sc.publisher.Publish(ctx, event)
 }
@@ -48,10 +55,13 @@ func (sc *SagaCoordinator) CompensateStep(ctx context.Context, sagaID string, st
 
 	time.Sleep(50 * time.Millisecond)
 
-	event := NewEvent(SagaCompensated, "report-service", map[string]interface{}{
+	event, err := NewEvent(SagaCompensated, "report-service", map[string]interface{}{
 		"saga_id": sagaID,
 		"step_id": stepID,
 	})
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
 
 	return sc.publisher.Publish(ctx, event)
 }
@@ -74,10 +84,13 @@ func (sc *SagaCoordinator) UpdateSagaStatus(ctx context.Context, sagaID string,
 		eventType = SagaFailed
 	}
 
-	event := NewEvent(eventType, "report-service", map[string]interface{}{
+	event, err := NewEvent(eventType, "report-service", map[string]interface{}{
 		"saga_id": sagaID,
 		"status":  string(status),
 	})
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
 
 	return sc.publisher.Publish(ctx, event)
 }
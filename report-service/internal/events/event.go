@@ -2,6 +2,7 @@ package events
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -37,6 +38,10 @@ const (
 	// Storage Events (для Saga)
 	FileStored        EventType = "file.stored"
 	FileStorageFailed EventType = "file.storage_failed"
+
+	// Notification Events (от notification-service)
+	NotificationDelivered EventType = "notification.delivered"
+	NotificationFailed    EventType = "notification.failed"
 )
 
 // Event представляет базовое событие
@@ -49,8 +54,20 @@ type Event struct {
 	Metadata  map[string]interface{} `json:"metadata"`
 }
 
-// NewEvent создает новое событие
-func NewEvent(eventType EventType, source string, data map[string]interface{}) *Event {
+// CorrelationIDKey ключ сквозного ID отчета в данных Saga, шагов и метаданных событий
+const CorrelationIDKey = "correlation_id"
+
+// NewEvent создает новое событие зарегистрированного типа.
+// Неизвестный тип или отсутствие обязательных полей возвращают ошибку.
+func NewEvent(eventType EventType, source string, data map[string]interface{}) (*Event, error) {
+	def, ok := LookupEventType(eventType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+	}
+	if err := def.Validate(data); err != nil {
+		return nil, err
+	}
+
 	return &Event{
 		ID:        generateEventID(),
 		Type:      eventType,
@@ -58,7 +75,15 @@ func NewEvent(eventType EventType, source string, data map[string]interface{}) *
 		Timestamp: time.Now(),
 		Data:      data,
 		Metadata:  make(map[string]interface{}),
+	}, nil
+}
+
+// WithCorrelationID добавляет в метаданные события сквозной ID отчета, если он известен
+func (e *Event) WithCorrelationID(correlationID string) *Event {
+	if correlationID != "" {
+		e.Metadata[CorrelationIDKey] = correlationID
 	}
+	return e
 }
 
 // ToJSON конвертирует событие в JSON
@@ -0,0 +1,35 @@
+package events
+
+import (
+	"context"
+	"log"
+)
+
+// GenerationLocker блокировка генерации отчета: пока ее держит выполняющаяся Saga,
+// другая генерация того же отчета не запускается
+type GenerationLocker interface {
+	AcquireGenerationLock(reportID uint) error
+	ReleaseGenerationLock(reportID uint)
+}
+
+// LockSagaReport захватывает блокировку генерации отчета, с которым работает Saga.
+// Если отчет еще не создан, блокировку захватывает шаг генерации при его создании.
+func LockSagaReport(locker GenerationLocker, saga *Saga) error {
+	if reportID := saga.ReportID(); reportID != 0 {
+		return locker.AcquireGenerationLock(reportID)
+	}
+	return nil
+}
+
+// ReleaseSagaReport снимает блокировку генерации отчета после выполнения Saga.
+// Отчет берется из сохраненного состояния, так как он мог быть создан во время выполнения.
+func ReleaseSagaReport(ctx context.Context, locker GenerationLocker, stateStore *SagaStateStore, sagaID string) {
+	saga, err := stateStore.GetSagaState(context.WithoutCancel(ctx), sagaID)
+	if err != nil {
+		log.Printf("Блокировка генерации отчета Saga %s не снята: %v", sagaID, err)
+		return
+	}
+	if reportID := saga.ReportID(); reportID != 0 {
+		locker.ReleaseGenerationLock(reportID)
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Виды генераторов ID Saga для SAGA_ID_GENERATOR
+const (
+	SagaIDGeneratorUUID      = "uuid"
+	SagaIDGeneratorTimestamp = "timestamp"
+)
+
+// IDGenerator формирует уникальные идентификаторы
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc позволяет использовать функцию как IDGenerator, например в тестах
+type IDGeneratorFunc func() string
+
+// NewID вызывает функцию генерации
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// UUIDGenerator генерирует ID на основе UUIDv4 с необязательным префиксом
+type UUIDGenerator struct {
+	Prefix string
+}
+
+// NewID возвращает новый ID
+func (g UUIDGenerator) NewID() string {
+	return g.Prefix + uuid.New().String()
+}
+
+// TimestampGenerator генерирует ID прежнего формата: префикс, время до секунды
+// и случайный суффикс. Оставлен для совместимости с инструментами, разбирающими ID.
+type TimestampGenerator struct {
+	Prefix string
+}
+
+// NewID возвращает новый ID
+func (g TimestampGenerator) NewID() string {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return g.Prefix + uuid.New().String()
+	}
+	return g.Prefix + time.Now().Format("20060102150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// NewSagaIDGenerator создает генератор ID Saga по имени из конфигурации
+func NewSagaIDGenerator(kind string) (IDGenerator, error) {
+	switch kind {
+	case "", SagaIDGeneratorUUID:
+		return UUIDGenerator{Prefix: "saga-"}, nil
+	case SagaIDGeneratorTimestamp:
+		return TimestampGenerator{Prefix: "saga-"}, nil
+	default:
+		return nil, fmt.Errorf("неизвестный генератор ID Saga: %s", kind)
+	}
+}
+
+var (
+	sagaIDMu        sync.RWMutex
+	sagaIDGenerator IDGenerator = UUIDGenerator{Prefix: "saga-"}
+)
+
+// SetSagaIDGenerator задает генератор ID новых Saga; nil восстанавливает UUIDv4
+func SetSagaIDGenerator(generator IDGenerator) {
+	if generator == nil {
+		generator = UUIDGenerator{Prefix: "saga-"}
+	}
+	sagaIDMu.Lock()
+	sagaIDGenerator = generator
+	sagaIDMu.Unlock()
+}
+
+// generateSagaID генерирует уникальный ID для Saga текущим генератором
+func generateSagaID() string {
+	sagaIDMu.RLock()
+	generator := sagaIDGenerator
+	sagaIDMu.RUnlock()
+	return generator.NewID()
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// setTestSagaIDGenerator подменяет генератор ID Saga на время теста
+func setTestSagaIDGenerator(t *testing.T, generator IDGenerator) {
+	t.Helper()
+	SetSagaIDGenerator(generator)
+	t.Cleanup(func() { SetSagaIDGenerator(nil) })
+}
+
+func TestSagaIDGeneratorsAreUniqueUnderConcurrency(t *testing.T) {
+	timestampID := regexp.MustCompile(`^saga-\d{14}-[0-9a-f]{16}$`)
+	tests := []struct {
+		kind  string
+		valid func(id string) bool
+	}{
+		{SagaIDGeneratorUUID, func(id string) bool {
+			parsed, err := uuid.Parse(strings.TrimPrefix(id, "saga-"))
+			return strings.HasPrefix(id, "saga-") && err == nil && parsed.Version() == 4
+		}},
+		{SagaIDGeneratorTimestamp, timestampID.MatchString},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			generator, err := NewSagaIDGenerator(tt.kind)
+			if err != nil {
+				t.Fatalf("NewSagaIDGenerator: %v", err)
+			}
+			setTestSagaIDGenerator(t, generator)
+
+			const workers, perWorker = 16, 500
+			var mu sync.Mutex
+			seen := make(map[string]bool, workers*perWorker)
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ids := make([]string, perWorker)
+					for i := range ids {
+						ids[i] = generateSagaID()
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					for _, id := range ids {
+						if seen[id] {
+							t.Errorf("повторный ID %s", id)
+						}
+						if !tt.valid(id) {
+							t.Errorf("ID %q не соответствует формату %s", id, tt.kind)
+						}
+						seen[id] = true
+					}
+				}()
+			}
+			wg.Wait()
+
+			if len(seen) != workers*perWorker {
+				t.Errorf("уникальных ID %d, ожидалось %d", len(seen), workers*perWorker)
+			}
+		})
+	}
+}
+
+func TestSetSagaIDGeneratorInjectsIDs(t *testing.T) {
+	var counter atomic.Int32
+	setTestSagaIDGenerator(t, IDGeneratorFunc(func() string {
+		return fmt.Sprintf("saga-test-%d", counter.Add(1))
+	}))
+
+	if saga := NewIdempotentReportCreationSaga("1", "7", "3", nil); saga.ID != "saga-test-1" {
+		t.Errorf("ID Saga %q, ожидался saga-test-1", saga.ID)
+	}
+	if saga := NewReportCreationSaga("7", "3", nil); saga.ID != "saga-test-2" {
+		t.Errorf("ID Saga %q, ожидался saga-test-2", saga.ID)
+	}
+
+	// nil возвращает генератор по умолчанию
+	SetSagaIDGenerator(nil)
+	id := generateSagaID()
+	if _, err := uuid.Parse(strings.TrimPrefix(id, "saga-")); err != nil || !strings.HasPrefix(id, "saga-") {
+		t.Errorf("после сброса ID %q, ожидался UUID с префиксом saga-", id)
+	}
+	if counter.Load() != 2 {
+		t.Errorf("подмененный генератор вызван %d раз, ожидалось 2", counter.Load())
+	}
+}
+
+func TestNewSagaIDGeneratorKinds(t *testing.T) {
+	for _, kind := range []string{"", SagaIDGeneratorUUID} {
+		if generator, err := NewSagaIDGenerator(kind); err != nil || generator != (UUIDGenerator{Prefix: "saga-"}) {
+			t.Errorf("вид %q: генератор %#v, ошибка %v", kind, generator, err)
+		}
+	}
+	if generator, err := NewSagaIDGenerator(SagaIDGeneratorTimestamp); err != nil || generator != (TimestampGenerator{Prefix: "saga-"}) {
+		t.Errorf("timestamp: генератор %#v, ошибка %v", generator, err)
+	}
+	if generator, err := NewSagaIDGenerator("snowflake"); err == nil || generator != nil {
+		t.Errorf("неизвестный вид: генератор %#v, ошибка %v", generator, err)
+	}
+}
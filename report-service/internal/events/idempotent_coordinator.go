@@ -2,6 +2,7 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -9,12 +10,26 @@ import (
 	"report-service/internal/metrics"
 )
 
+// ErrStepNotFailed возвращается при попытке повторить шаг, который не завершился ошибкой
+var ErrStepNotFailed = errors.New("шаг Saga не в статусе failed")
+
+// ErrTooManySteps возвращается при запуске Saga, число шагов которой превышает допустимое
+var ErrTooManySteps = errors.New("слишком много шагов в Saga")
+
+// ErrUnknownCompensationPolicy возвращается при запуске Saga с неизвестной политикой компенсации шага
+var ErrUnknownCompensationPolicy = errors.New("неизвестная политика компенсации шага")
+
+// ErrCompensationAborted возвращается CompensateStep, когда не удалась компенсация шага
+// с политикой must_succeed: компенсацию остальных шагов нужно прервать
+var ErrCompensationAborted = errors.New("компенсация Saga прервана")
+
 // IdempotentSagaCoordinator управляет Saga с идемпотентностью
 type IdempotentSagaCoordinator struct {
 	publisher   EventPublisher
 	stateStore  *SagaStateStore
 	maxRetries  int
 	retryDelay  time.Duration
+	maxSteps    int
 	stepHandler SagaStepHandlerInterface
 	metrics     *metrics.Metrics
 }
@@ -25,20 +40,41 @@ type SagaStepHandlerInterface interface {
 	CompensateStep(ctx context.Context, step *SagaStep) error
 }
 
-// NewIdempotentSagaCoordinator создает новый идемпотентный Saga Coordinator
-func NewIdempotentSagaCoordinator(publisher EventPublisher, stateStore *SagaStateStore, stepHandler SagaStepHandlerInterface, metrics *metrics.Metrics) *IdempotentSagaCoordinator {
+// NewIdempotentSagaCoordinator создает новый идемпотентный Saga Coordinator.
+// maxSteps ограничивает число шагов запускаемой Saga, ноль снимает ограничение.
+func NewIdempotentSagaCoordinator(publisher EventPublisher, stateStore *SagaStateStore, stepHandler SagaStepHandlerInterface, metrics *metrics.Metrics, maxSteps int) *IdempotentSagaCoordinator {
 	return &IdempotentSagaCoordinator{
 		publisher:   publisher,
 		stateStore:  stateStore,
 		maxRetries:  1,
 		retryDelay:  1 * time.Second,
+		maxSteps:    maxSteps,
 		stepHandler: stepHandler,
 		metrics:     metrics,
 	}
 }
 
+// ValidateSteps проверяет число шагов и политики компенсации; обработчики вызывают
+// ее до ответа клиенту, чтобы не принимать Saga, которую StartSaga отклонит
+func (sc *IdempotentSagaCoordinator) ValidateSteps(steps []*SagaStep) error {
+	if sc.maxSteps > 0 && len(steps) > sc.maxSteps {
+		return fmt.Errorf("%w: %d при максимуме %d", ErrTooManySteps, len(steps), sc.maxSteps)
+	}
+	for _, step := range steps {
+		if !step.CompensationPolicy.IsValid() {
+			return fmt.Errorf("%w: %q у шага %s", ErrUnknownCompensationPolicy, step.CompensationPolicy, step.ID)
+		}
+	}
+	return nil
+}
+
 // StartSaga запускает новую Saga с проверкой идемпотентности
 func (sc *IdempotentSagaCoordinator) StartSaga(ctx context.Context, saga *Saga) error {
+	// Отклоняем недопустимую Saga до сохранения состояния
+	if err := sc.ValidateSteps(saga.Steps); err != nil {
+		return err
+	}
+
 	// Проверяем, не существует ли уже Saga с таким ID
 	existingSaga, err := sc.stateStore.GetSagaState(ctx, saga.ID)
 	if err == nil {
@@ -69,11 +105,15 @@ func (sc *IdempotentSagaCoordinator) StartSaga(ctx context.Context, saga *Saga)
 	sc.metrics.RecordBusinessOperation("report-service", "saga_started", time.Since(time.Now()), true)
 
 	// Публикуем событие начала Saga
-	event := NewEvent(SagaStarted, "report-service", map[string]interface{}{
+	event, err := NewEvent(SagaStarted, "report-service", map[string]interface{}{
 		"saga_id":   saga.ID,
 		"saga_name": saga.Name,
 		"steps":     len(saga.Steps),
 	})
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
+	event.WithCorrelationID(saga.CorrelationID())
 
 	// Логируем событие для идемпотентности
 	if err := sc.stateStore.LogEvent(ctx, saga.ID, event.ID, event.Type); err != nil {
@@ -139,6 +179,7 @@ func (sc *IdempotentSagaCoordinator) ExecuteStep(ctx context.Context, sagaID str
 	for k, v := range actualStep.Data {
 		stepCopy.Data[k] = v
 	}
+	actualSaga.ResolveInputs(actualStep, stepCopy.Data)
 
 	// Проверяем идемпотентность шага
 	if stepCopy.Status == SagaStepCompleted {
@@ -174,6 +215,7 @@ func (sc *IdempotentSagaCoordinator) ExecuteStep(ctx context.Context, sagaID str
 			time.Sleep(sc.retryDelay)
 		}
 
+		step.Attempts++
 		err := sc.executeStepInternal(ctx, sagaID, stepID, stepCopy)
 		if err == nil {
 			// Шаг выполнен успешно
@@ -253,12 +295,15 @@ func (sc *IdempotentSagaCoordinator) executeStepInternal(ctx context.Context, sa
 	}
 
 	// Публикуем событие выполнения шага
-	event := NewEvent(ReportGenerated, "report-service", map[string]interface{}{
+	event, err := NewEvent(ReportGenerated, "report-service", map[string]interface{}{
 		"saga_id": sagaID,
 		"step_id": stepID,
 		"service": step.Service,
 		"action":  step.Action,
 	})
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
 
 	// Логируем событие для идемпотентности
 	if err := sc.stateStore.LogEvent(ctx, sagaID, event.ID, event.Type); err != nil {
@@ -301,10 +346,17 @@ func (sc *IdempotentSagaCoordinator) CompensateStep(ctx context.Context, sagaID
 
 	if step.Compensate == "none" {
 		log.Printf("Шаг %s не требует компенсации", stepID)
+		now := time.Now()
 		step.Status = SagaStepCompensated
+		step.CompensatedAt = &now
 		return sc.stateStore.SaveSagaState(ctx, saga)
 	}
 
+	if step.CompensationPolicy == CompensationSkip {
+		log.Printf("Компенсация шага %s пропущена по политике skip", stepID)
+		return nil
+	}
+
 	log.Printf("Компенсация шага %s в Saga %s", stepID, sagaID)
 
 	// Выполняем компенсацию с повторными попытками
@@ -317,7 +369,10 @@ func (sc *IdempotentSagaCoordinator) CompensateStep(ctx context.Context, sagaID
 		err := sc.compensateStepInternal(ctx, sagaID, stepID, step)
 		if err == nil {
 			// Компенсация выполнена успешно
+			now := time.Now()
 			step.Status = SagaStepCompensated
+			step.CompensatedAt = &now
+			step.CompensationError = ""
 
 			// Сохраняем обновленное состояние
 			if err := sc.stateStore.SaveSagaState(ctx, saga); err != nil {
@@ -332,6 +387,24 @@ func (sc *IdempotentSagaCoordinator) CompensateStep(ctx context.Context, sagaID
 
 		if attempt == sc.maxRetries {
 			log.Printf("Не удалось компенсировать шаг %s после %d попыток", stepID, sc.maxRetries+1)
+
+			// Сохраняем ошибку на шаге, чтобы частичная компенсация была видна в статусе Saga
+			step.CompensationError = err.Error()
+			if step.CompensationPolicy == CompensationMustSucceed {
+				saga.Status = SagaStatusCompensationFailed
+			}
+			if saveErr := sc.stateStore.SaveSagaState(ctx, saga); saveErr != nil {
+				log.Printf("Ошибка сохранения состояния после неудачной компенсации: %v", saveErr)
+			}
+
+			switch step.CompensationPolicy {
+			case CompensationBestEffort:
+				log.Printf("Ошибка компенсации шага %s не прерывает Saga по политике best_effort", stepID)
+				return nil
+			case CompensationMustSucceed:
+				return fmt.Errorf("%w: шаг %s: %v", ErrCompensationAborted, stepID, err)
+			}
+
 			// Продолжаем компенсацию других шагов
 			return err
 		}
@@ -358,12 +431,15 @@ func (sc *IdempotentSagaCoordinator) compensateStepInternal(ctx context.Context,
 	}
 
 	// Публикуем событие компенсации
-	event := NewEvent(SagaCompensated, "report-service", map[string]interface{}{
+	event, err := NewEvent(SagaCompensated, "report-service", map[string]interface{}{
 		"saga_id": sagaID,
 		"step_id": stepID,
 		"service": step.Service,
 		"action":  step.Compensate,
 	})
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
 
 	// Логируем событие для идемпотентности
 	if err := sc.stateStore.LogEvent(ctx, sagaID, event.ID, event.Type); err != nil {
@@ -378,6 +454,51 @@ func (sc *IdempotentSagaCoordinator) GetSaga(ctx context.Context, sagaID string)
 	return sc.stateStore.GetSagaState(ctx, sagaID)
 }
 
+// FindReportSaga получает последнюю Saga генерации отчета
+func (sc *IdempotentSagaCoordinator) FindReportSaga(ctx context.Context, reportID uint) (*Saga, error) {
+	sagaID, err := sc.stateStore.FindSagaIDByReportID(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	return sc.stateStore.GetSagaState(ctx, sagaID)
+}
+
+// SagaFailure сведения о причине неудачи Saga
+type SagaFailure struct {
+	StepName   string
+	Error      string
+	RetryCount int
+}
+
+// GetReportSagaFailure возвращает упавший шаг, ошибку и число повторов Saga генерации отчета
+func (sc *IdempotentSagaCoordinator) GetReportSagaFailure(ctx context.Context, reportID uint) (*SagaFailure, error) {
+	sagaID, err := sc.stateStore.FindSagaIDByReportID(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := sc.stateStore.GetSagaStateRecord(ctx, sagaID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения Saga: %w", err)
+	}
+	saga, err := sc.stateStore.GetSagaState(ctx, sagaID)
+	if err != nil {
+		return nil, err
+	}
+
+	failure := &SagaFailure{Error: saga.Error, RetryCount: record.RetryCount}
+	for _, step := range saga.Steps {
+		if step.Status == SagaStepFailed {
+			failure.StepName = step.Name
+			if step.Error != "" {
+				failure.Error = step.Error
+			}
+			break
+		}
+	}
+	return failure, nil
+}
+
 // UpdateSagaStatus обновляет статус Saga
 func (sc *IdempotentSagaCoordinator) UpdateSagaStatus(ctx context.Context, sagaID string, status SagaStatus) error {
 	log.Printf("Обновление статуса Saga %s на %s", sagaID, status)
@@ -389,14 +510,17 @@ func (sc *IdempotentSagaCoordinator) UpdateSagaStatus(ctx context.Context, sagaI
 
 	// Публикуем событие обновления статуса
 	eventType := SagaCompleted
-	if status == SagaStatusFailed {
+	if status == SagaStatusFailed || status == SagaStatusCompensationFailed {
 		eventType = SagaFailed
 	}
 
-	event := NewEvent(eventType, "report-service", map[string]interface{}{
+	event, err := NewEvent(eventType, "report-service", map[string]interface{}{
 		"saga_id": sagaID,
 		"status":  string(status),
 	})
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
 
 	// Логируем событие для идемпотентности
 	if err := sc.stateStore.LogEvent(ctx, sagaID, event.ID, event.Type); err != nil {
@@ -406,6 +530,48 @@ func (sc *IdempotentSagaCoordinator) UpdateSagaStatus(ctx context.Context, sagaI
 	return sc.publisher.Publish(ctx, event)
 }
 
+// NotifyReportFailed публикует событие report.failed для отчета, созданного Saga,
+// чтобы notification-service отправил пользователю уведомление по шаблону report_failed.
+// Если отчет еще не был создан, событие не публикуется
+func (sc *IdempotentSagaCoordinator) NotifyReportFailed(ctx context.Context, sagaID string, cause error) error {
+	saga, err := sc.stateStore.GetSagaState(ctx, sagaID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения Saga: %w", err)
+	}
+
+	reportID := ""
+	if generate := saga.FindStep("generate-report"); generate != nil {
+		if v, ok := generate.Data["report_id"].(string); ok {
+			reportID = v
+		}
+	}
+	if reportID == "" || reportID == "0" {
+		log.Printf("Saga %s завершилась ошибкой до создания отчета, уведомление не отправляется", sagaID)
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"report_id": reportID,
+		"saga_id":   sagaID,
+		"type":      "report_failed",
+		"error":     cause.Error(),
+	}
+	if userID, ok := saga.Data["user_id"].(string); ok {
+		data["user_id"] = userID
+	}
+	if correlationID := saga.CorrelationID(); correlationID != "" {
+		data[CorrelationIDKey] = correlationID
+	}
+
+	event, err := NewEvent(ReportFailed, "report-service", data)
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
+	event.WithCorrelationID(saga.CorrelationID())
+
+	return sc.publisher.Publish(ctx, event)
+}
+
 // HandleSagaEvent обрабатывает события Saga с проверкой идемпотентности
 func (sc *IdempotentSagaCoordinator) HandleSagaEvent(ctx context.Context, event *Event) error {
 	// Проверяем, не было ли событие уже обработано
@@ -481,3 +647,108 @@ func (sc *IdempotentSagaCoordinator) ForceCompleteSaga(ctx context.Context, saga
 	log.Printf("Saga %s принудительно завершена", sagaID)
 	return nil
 }
+
+// ResetFailedStep сбрасывает неудачный шаг в pending для повторного выполнения
+func (sc *IdempotentSagaCoordinator) ResetFailedStep(ctx context.Context, sagaID, stepID string) error {
+	saga, err := sc.stateStore.GetSagaState(ctx, sagaID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения Saga %s: %w", sagaID, err)
+	}
+
+	step := saga.FindStep(stepID)
+	if step == nil {
+		return fmt.Errorf("шаг %s не найден в Saga %s", stepID, sagaID)
+	}
+	if step.Status != SagaStepFailed {
+		return ErrStepNotFailed
+	}
+
+	step.Status = SagaStepPending
+	step.Error = ""
+	step.ExecutedAt = nil
+	step.CompletedAt = nil
+
+	saga.Status = SagaStatusExecuting
+	saga.Error = ""
+	if err := sc.stateStore.SaveSagaState(ctx, saga); err != nil {
+		return fmt.Errorf("ошибка сохранения состояния Saga: %w", err)
+	}
+
+	log.Printf("Шаг %s Saga %s сброшен для повторного выполнения", stepID, sagaID)
+	return nil
+}
+
+// ResumeSaga продолжает выполнение Saga начиная с указанного шага
+func (sc *IdempotentSagaCoordinator) ResumeSaga(ctx context.Context, sagaID, fromStepID string) error {
+	saga, err := sc.stateStore.GetSagaState(ctx, sagaID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения Saga %s: %w", sagaID, err)
+	}
+
+	started := false
+	for _, step := range saga.Steps {
+		if step.ID == fromStepID {
+			started = true
+		}
+		if !started || step.Done() {
+			continue
+		}
+
+		if err := sc.ExecuteStep(ctx, sagaID, step.ID); err != nil {
+			if step.Optional {
+				log.Printf("Необязательный шаг %s Saga %s не выполнен: %v", step.ID, sagaID, err)
+				continue
+			}
+			if updateErr := sc.UpdateSagaStatus(ctx, sagaID, SagaStatusFailed); updateErr != nil {
+				log.Printf("Ошибка обновления статуса Saga: %v", updateErr)
+			}
+			return fmt.Errorf("ошибка выполнения шага %s: %w", step.ID, err)
+		}
+	}
+
+	if !started {
+		return fmt.Errorf("шаг %s не найден в Saga %s", fromStepID, sagaID)
+	}
+
+	return sc.UpdateSagaStatus(ctx, sagaID, SagaStatusCompleted)
+}
+
+// CompensateSaga переводит Saga в failed и компенсирует выполненные шаги в обратном порядке
+func (sc *IdempotentSagaCoordinator) CompensateSaga(ctx context.Context, sagaID, reason string) error {
+	saga, err := sc.stateStore.GetSagaState(ctx, sagaID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения Saga %s: %w", sagaID, err)
+	}
+
+	saga.Status = SagaStatusFailed
+	saga.Error = reason
+	if err := sc.stateStore.SaveSagaState(ctx, saga); err != nil {
+		return fmt.Errorf("ошибка сохранения состояния Saga: %w", err)
+	}
+	if err := sc.UpdateSagaStatus(ctx, sagaID, SagaStatusFailed); err != nil {
+		log.Printf("Ошибка публикации статуса Saga %s: %v", sagaID, err)
+	}
+
+	var compensateErr error
+	for i := len(saga.Steps) - 1; i >= 0; i-- {
+		step := saga.Steps[i]
+		if step.Status != SagaStepCompleted {
+			continue
+		}
+		if err := sc.CompensateStep(ctx, sagaID, step.ID); err != nil {
+			compensateErr = fmt.Errorf("ошибка компенсации шага %s: %w", step.ID, err)
+			if errors.Is(err, ErrCompensationAborted) {
+				break
+			}
+		}
+	}
+	if compensateErr != nil {
+		if err := sc.stateStore.UpdateSagaStatus(ctx, sagaID, SagaStatusCompensationFailed); err != nil {
+			log.Printf("Ошибка обновления статуса Saga %s: %v", sagaID, err)
+		}
+		return compensateErr
+	}
+
+	log.Printf("Saga %s компенсирована: %s", sagaID, reason)
+	return sc.stateStore.UpdateSagaStatus(ctx, sagaID, SagaStatusCompensated)
+}
@@ -0,0 +1,186 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"report-service/internal/metrics"
+)
+
+// testMetrics метрики регистрируются в глобальном реестре, поэтому создаются один раз
+var testMetrics = sync.OnceValue(func() *metrics.Metrics {
+	return metrics.NewMetrics("report-service-test")
+})
+
+// fakeStepHandler выполняет шаги успешно, а компенсацию шагов из failCompensation завершает ошибкой
+type fakeStepHandler struct {
+	failCompensation map[string]bool
+	executed         []string
+	compensated      []string
+}
+
+func (h *fakeStepHandler) ExecuteStep(ctx context.Context, step *SagaStep) error {
+	h.executed = append(h.executed, step.ID)
+	return nil
+}
+
+func (h *fakeStepHandler) CompensateStep(ctx context.Context, step *SagaStep) error {
+	h.compensated = append(h.compensated, step.ID)
+	if h.failCompensation[step.ID] {
+		return errors.New("сервис недоступен")
+	}
+	return nil
+}
+
+// newTestCoordinator создает координатор без задержки между повторами
+func newTestCoordinator(t *testing.T, handler SagaStepHandlerInterface) (*IdempotentSagaCoordinator, *SagaStateStore) {
+	t.Helper()
+
+	store, _ := newTestStateStore(t)
+	coordinator := NewIdempotentSagaCoordinator(NewLocalEventPublisher(), store, handler, testMetrics(), 0)
+	coordinator.retryDelay = time.Millisecond
+	return coordinator, store
+}
+
+// saveCompletedSaga сохраняет Saga, все шаги которой выполнены
+func saveCompletedSaga(t *testing.T, store *SagaStateStore, steps ...*SagaStep) *Saga {
+	t.Helper()
+
+	for _, step := range steps {
+		step.Status = SagaStepCompleted
+	}
+	saga := &Saga{ID: "saga-test", Name: "test", Status: SagaStatusExecuting, Steps: steps, Data: map[string]interface{}{}}
+	if err := store.SaveSagaState(context.Background(), saga); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+	return saga
+}
+
+func TestCompensateSagaMustSucceedFailureAbortsCompensation(t *testing.T) {
+	handler := &fakeStepHandler{failCompensation: map[string]bool{"generate-report": true}}
+	coordinator, store := newTestCoordinator(t, handler)
+	ctx := context.Background()
+
+	saga := saveCompletedSaga(t, store,
+		&SagaStep{ID: "create-record", Service: "report-service", Action: "create", Compensate: "delete"},
+		&SagaStep{ID: "generate-report", Service: "report-service", Action: "generate_report", Compensate: "delete_report", CompensationPolicy: CompensationMustSucceed},
+		&SagaStep{ID: "store-file", Service: "storage-service", Action: "store_file", Compensate: "delete_file"},
+	)
+
+	err := coordinator.CompensateSaga(ctx, saga.ID, "ошибка уведомления")
+	if !errors.Is(err, ErrCompensationAborted) {
+		t.Fatalf("ожидалась ErrCompensationAborted, получено %v", err)
+	}
+
+	stored, err := store.GetSagaState(ctx, saga.ID)
+	if err != nil {
+		t.Fatalf("получение Saga: %v", err)
+	}
+	if stored.Status != SagaStatusCompensationFailed {
+		t.Errorf("статус Saga %s, ожидался %s", stored.Status, SagaStatusCompensationFailed)
+	}
+
+	steps := map[string]*SagaStep{}
+	for _, step := range stored.Steps {
+		steps[step.ID] = step
+	}
+	if steps["store-file"].Status != SagaStepCompensated {
+		t.Errorf("шаг store-file в статусе %s, ожидался %s", steps["store-file"].Status, SagaStepCompensated)
+	}
+	if steps["generate-report"].CompensationError == "" {
+		t.Error("ошибка компенсации generate-report не сохранена на шаге")
+	}
+	// Компенсация прервана: шаг перед must_succeed не откатывается
+	if steps["create-record"].Status != SagaStepCompleted {
+		t.Errorf("шаг create-record в статусе %s, компенсация должна была прерваться", steps["create-record"].Status)
+	}
+	for _, id := range handler.compensated {
+		if id == "create-record" {
+			t.Fatal("компенсация create-record вызвана после ошибки must_succeed")
+		}
+	}
+}
+
+func TestCompensateSagaRecordsFailedCompensation(t *testing.T) {
+	handler := &fakeStepHandler{failCompensation: map[string]bool{"store-file": true}}
+	coordinator, store := newTestCoordinator(t, handler)
+	ctx := context.Background()
+
+	saga := saveCompletedSaga(t, store,
+		&SagaStep{ID: "generate-report", Service: "report-service", Action: "generate_report", Compensate: "delete_report"},
+		&SagaStep{ID: "store-file", Service: "storage-service", Action: "store_file", Compensate: "delete_file"},
+	)
+
+	if err := coordinator.CompensateSaga(ctx, saga.ID, "ошибка уведомления"); err == nil {
+		t.Fatal("ожидалась ошибка компенсации")
+	}
+
+	stored, err := store.GetSagaState(ctx, saga.ID)
+	if err != nil {
+		t.Fatalf("получение Saga: %v", err)
+	}
+	if stored.Status != SagaStatusCompensationFailed {
+		t.Errorf("статус Saga %s, ожидался %s", stored.Status, SagaStatusCompensationFailed)
+	}
+
+	// Ошибка одного шага сохраняется на нем и не останавливает откат остальных
+	for _, step := range stored.Steps {
+		switch step.ID {
+		case "store-file":
+			if step.Status != SagaStepCompleted || step.CompensatedAt != nil {
+				t.Errorf("store-file: статус %s, compensated_at %v", step.Status, step.CompensatedAt)
+			}
+			if !strings.Contains(step.CompensationError, "сервис недоступен") {
+				t.Errorf("store-file: compensation_error %q", step.CompensationError)
+			}
+		case "generate-report":
+			if step.Status != SagaStepCompensated || step.CompensatedAt == nil || step.CompensationError != "" {
+				t.Errorf("generate-report: статус %s, compensated_at %v, compensation_error %q", step.Status, step.CompensatedAt, step.CompensationError)
+			}
+		}
+	}
+
+	// Попытки компенсации: исходная и повторная для store-file, затем generate-report
+	if len(handler.compensated) != 3 {
+		t.Errorf("вызовы компенсации %v", handler.compensated)
+	}
+}
+
+func TestStartSagaRejectsTooManySteps(t *testing.T) {
+	store, _ := newTestStateStore(t)
+	coordinator := NewIdempotentSagaCoordinator(NewLocalEventPublisher(), store, &fakeStepHandler{}, testMetrics(), 3)
+	ctx := context.Background()
+
+	steps := func(n int) []*SagaStep {
+		var steps []*SagaStep
+		for i := 0; i < n; i++ {
+			steps = append(steps, &SagaStep{ID: fmt.Sprintf("step-%d", i), Service: "report-service", Action: "noop", Compensate: "none", Status: SagaStepPending})
+		}
+		return steps
+	}
+
+	oversized := &Saga{ID: "saga-oversized", Name: "test", Steps: steps(4), Data: map[string]interface{}{}}
+	if err := coordinator.StartSaga(ctx, oversized); !errors.Is(err, ErrTooManySteps) {
+		t.Fatalf("ожидалась ErrTooManySteps, получено %v", err)
+	}
+	// Отклоненная Saga не сохраняется
+	if _, err := store.GetSagaState(ctx, oversized.ID); err == nil {
+		t.Error("состояние отклоненной Saga сохранено")
+	}
+
+	atLimit := &Saga{ID: "saga-at-limit", Name: "test", Steps: steps(3), Data: map[string]interface{}{}}
+	if err := coordinator.StartSaga(ctx, atLimit); err != nil {
+		t.Fatalf("Saga из максимального числа шагов отклонена: %v", err)
+	}
+
+	// Без ограничения принимается Saga любого размера
+	unlimited := NewIdempotentSagaCoordinator(NewLocalEventPublisher(), store, &fakeStepHandler{}, testMetrics(), 0)
+	if err := unlimited.StartSaga(ctx, &Saga{ID: "saga-unlimited", Name: "test", Steps: steps(20), Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("Saga без ограничения шагов отклонена: %v", err)
+	}
+}
@@ -2,21 +2,43 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Имена Saga, под которыми они сохраняются в хранилище состояний
+const (
+	ReportCreationSagaName    = "Idempotent Report Creation Saga"
+	BatchRenderNotifySagaName = "Batch Render and Notify Saga"
+)
+
 // IdempotentReportCreationSaga представляет идемпотентную Saga для создания отчета
 type IdempotentReportCreationSaga struct {
-	ID    string
-	Steps []*SagaStep
+	ID string
+	// Name имя Saga в сохраненном состоянии; пустое значение означает создание отчета
+	Name          string
+	UserID        string
+	CorrelationID string
+	Steps         []*SagaStep
+	// data данные сохраненной Saga, которые переносятся в состояние при повторе
+	data map[string]interface{}
 }
 
 // NewIdempotentReportCreationSaga создает новую идемпотентную Saga для создания отчета
 func NewIdempotentReportCreationSaga(reportID, userID, templateID string, parameters map[string]interface{}) *IdempotentReportCreationSaga {
+	// Формат определяет расширение сохраняемого файла
+	format, _ := parameters["format"].(string)
+	// Сквозной ID отчета передается шагам, которые создают отчет, файл и уведомление
+	correlationID, _ := parameters[CorrelationIDKey].(string)
+
 	return &IdempotentReportCreationSaga{
-		ID: generateSagaID(),
+		ID:            generateSagaID(),
+		UserID:        userID,
+		CorrelationID: correlationID,
 		Steps: []*SagaStep{
 			{
 				ID:         "validate-user",
@@ -52,17 +74,39 @@ func NewIdempotentReportCreationSaga(reportID, userID, templateID string, parame
 				},
 				Status: SagaStepPending,
 			},
+			{
+				// Переменные шаблона проверяются после сбора данных: их значения могут
+				// прийти как из parameters, так и из полей собранных записей
+				ID:         "validate-parameters",
+				Name:       "Validate Parameters",
+				Service:    "report-service",
+				Action:     "validate_parameters",
+				Compensate: "none", // Нет компенсации для валидации
+				Data: map[string]interface{}{
+					"template_id": templateID,
+					"user_id":     userID,
+					"parameters":  parameters,
+				},
+				Inputs: map[string]StepInput{
+					"collected_fields": FromStep("collect-data", "collected_fields"),
+				},
+				Status: SagaStepPending,
+			},
 			{
 				ID:         "generate-report",
 				Name:       "Generate Report",
 				Service:    "report-service",
 				Action:     "generate_report",
 				Compensate: "delete_report",
+				// Отчет без файла и данных не должен остаться после отката
+				CompensationPolicy: CompensationMustSucceed,
 				Data: map[string]interface{}{
-					"report_id":   reportID,
-					"template_id": templateID,
-					"user_id":     userID,
-					"parameters":  parameters,
+					"report_id":      reportID,
+					"template_id":    templateID,
+					"user_id":        userID,
+					"parameters":     parameters,
+					"format":         format,
+					CorrelationIDKey: correlationID,
 				},
 				Status: SagaStepPending,
 			},
@@ -72,11 +116,33 @@ func NewIdempotentReportCreationSaga(reportID, userID, templateID string, parame
 				Service:    "storage-service",
 				Action:     "store_file",
 				Compensate: "delete_file",
+				Data: map[string]interface{}{
+					"report_id":      reportID,
+					"file_type":      "report",
+					"user_id":        userID,
+					"format":         format,
+					CorrelationIDKey: correlationID,
+				},
+				Inputs: generatedReportInput(),
+				Status: SagaStepPending,
+			},
+			{
+				// Метаданные пишутся до перевода отчета в completed: их ошибка откатывает
+				// отчет, который еще не был показан пользователю готовым
+				ID:         "record-metadata",
+				Name:       "Record Report Metadata",
+				Service:    "report-service",
+				Action:     RecordMetadataAction,
+				Compensate: "none", // Метаданные не компенсируются
 				Data: map[string]interface{}{
 					"report_id": reportID,
-					"file_type": "report",
 					"user_id":   userID,
 				},
+				Inputs: map[string]StepInput{
+					"report_id":       FromStep("generate-report", "report_id"),
+					"record_count":    FromStep("collect-data", "record_count"),
+					"saga_started_at": {Key: SagaStartedAtKey},
+				},
 				Status: SagaStepPending,
 			},
 			{
@@ -86,10 +152,12 @@ func NewIdempotentReportCreationSaga(reportID, userID, templateID string, parame
 				Action:     "send_notification",
 				Compensate: "none", // Уведомления не компенсируются
 				Data: map[string]interface{}{
-					"report_id": reportID,
-					"user_id":   userID,
-					"type":      "report_ready",
+					"report_id":      reportID,
+					"user_id":        userID,
+					"type":           "report_ready",
+					CorrelationIDKey: correlationID,
 				},
+				Inputs: generatedReportInput(),
 				Status: SagaStepPending,
 			},
 			{
@@ -99,29 +167,98 @@ func NewIdempotentReportCreationSaga(reportID, userID, templateID string, parame
 				Action:     "update_status",
 				Compensate: "none", // Статус не компенсируется
 				Data: map[string]interface{}{
-					"user_id": userID,
-					"status":  "completed",
+					"report_id": reportID,
+					"user_id":   userID,
+					"status":    "completed",
 				},
+				Inputs: generatedReportInput(),
 				Status: SagaStepPending,
 			},
 		},
 	}
 }
 
+// generatedReportInput передает шагу ID отчета, созданного или обновленного шагом generate-report
+func generatedReportInput() map[string]StepInput {
+	return map[string]StepInput{"report_id": FromStep("generate-report", "report_id")}
+}
+
+// NewBatchRenderNotifySaga создает Saga, которая генерирует и сохраняет отчет, а после
+// перевода отчета в completed отправляет файл каждому получателю отдельным шагом.
+// Шаги отправки необязательные и не компенсируются: ошибка отправки одному получателю
+// не отзывает уже доставленные уведомления и не откатывает готовый отчет.
+// channelID задает email канал notification-service, через который файл уходит вложением.
+func NewBatchRenderNotifySaga(reportID, userID, templateID string, parameters map[string]interface{}, recipients []string, channelID uint) *IdempotentReportCreationSaga {
+	saga := NewIdempotentReportCreationSaga(reportID, userID, templateID, parameters)
+	saga.Name = BatchRenderNotifySagaName
+
+	steps := make([]*SagaStep, 0, len(saga.Steps)-1+len(recipients))
+	for _, step := range saga.Steps {
+		// Общее уведомление владельцу заменяется шагами по получателям
+		if step.ID != "send-notification" {
+			steps = append(steps, step)
+		}
+	}
+	for i, recipient := range recipients {
+		data := map[string]interface{}{
+			"report_id":      reportID,
+			"user_id":        userID,
+			"recipient":      recipient,
+			"type":           "report_ready",
+			CorrelationIDKey: saga.CorrelationID,
+		}
+		if channelID != 0 {
+			data["channel_id"] = strconv.FormatUint(uint64(channelID), 10)
+		}
+		steps = append(steps, &SagaStep{
+			ID:         fmt.Sprintf("notify-recipient-%d", i+1),
+			Name:       fmt.Sprintf("Notify Recipient %d", i+1),
+			Service:    "notification-service",
+			Action:     SendReportToRecipientAction,
+			Compensate: "none", // Уведомления не компенсируются
+			Optional:   true,
+			Data:       data,
+			Inputs: map[string]StepInput{
+				"report_id": FromStep("generate-report", "report_id"),
+				"file_id":   FromStep("store-file", "file_id"),
+				"file_name": FromStep("store-file", "file_name"),
+			},
+			Status: SagaStepPending,
+		})
+	}
+	saga.Steps = steps
+	return saga
+}
+
 // Execute выполняет идемпотентную Saga
 func (s *IdempotentReportCreationSaga) Execute(ctx context.Context, coordinator *IdempotentSagaCoordinator) error {
 	log.Printf("Начинаем выполнение идемпотентной Saga создания отчета %s", s.ID)
 
+	name := s.Name
+	if name == "" {
+		name = ReportCreationSagaName
+	}
+
 	// Создаем объект Saga для передачи в coordinator
 	saga := &Saga{
 		ID:        s.ID,
-		Name:      "Idempotent Report Creation Saga",
+		Name:      name,
 		Status:    SagaStatusPending,
 		Steps:     s.Steps,
-		Data:      make(map[string]interface{}),
+		Data:      make(map[string]interface{}, len(s.data)+3),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	for key, value := range s.data {
+		saga.Data[key] = value
+	}
+	if s.UserID != "" {
+		saga.Data["user_id"] = s.UserID
+	}
+	if s.CorrelationID != "" {
+		saga.Data[CorrelationIDKey] = s.CorrelationID
+	}
+	saga.Data[SagaStartedAtKey] = saga.CreatedAt.Format(time.RFC3339Nano)
 
 	// Запускаем Saga через идемпотентный coordinator
 	if err := coordinator.StartSaga(ctx, saga); err != nil {
@@ -154,6 +291,10 @@ func (s *IdempotentReportCreationSaga) Execute(ctx context.Context, coordinator
 
 		// Выполняем шаг через идемпотентный coordinator
 		err = coordinator.ExecuteStep(ctx, s.ID, step.ID)
+		if err != nil && actualStep.Optional {
+			log.Printf("Необязательный шаг %s не выполнен, Saga продолжается: %v", step.Name, err)
+			continue
+		}
 		if err != nil {
 			log.Printf("Ошибка выполнения шага %s: %v", step.Name, err)
 
@@ -162,6 +303,11 @@ func (s *IdempotentReportCreationSaga) Execute(ctx context.Context, coordinator
 				log.Printf("Ошибка обновления статуса Saga: %v", updateErr)
 			}
 
+			// Уведомляем пользователя о неудачной генерации отчета
+			if notifyErr := coordinator.NotifyReportFailed(ctx, s.ID, err); notifyErr != nil {
+				log.Printf("Ошибка публикации события report.failed: %v", notifyErr)
+			}
+
 			// Компенсируем выполненные шаги
 			return s.compensate(ctx, coordinator, i)
 		}
@@ -183,6 +329,7 @@ func (s *IdempotentReportCreationSaga) compensate(ctx context.Context, coordinat
 	log.Printf("Начинаем компенсацию идемпотентной Saga %s с шага %d", s.ID, failedStepIndex)
 
 	// Компенсируем шаги в обратном порядке
+	var failedCompensations []string
 	for i := failedStepIndex - 1; i >= 0; i-- {
 		step := s.Steps[i]
 		if step.Compensate == "none" {
@@ -196,10 +343,21 @@ func (s *IdempotentReportCreationSaga) compensate(ctx context.Context, coordinat
 		err := coordinator.CompensateStep(ctx, s.ID, step.ID)
 		if err != nil {
 			log.Printf("Ошибка компенсации шага %s: %v", step.Name, err)
+			failedCompensations = append(failedCompensations, step.ID)
+			if errors.Is(err, ErrCompensationAborted) {
+				break
+			}
 			// Продолжаем компенсацию других шагов
 		}
 	}
 
+	if len(failedCompensations) > 0 {
+		if err := coordinator.UpdateSagaStatus(ctx, s.ID, SagaStatusCompensationFailed); err != nil {
+			log.Printf("Ошибка обновления статуса Saga на CompensationFailed: %v", err)
+		}
+		return fmt.Errorf("идемпотентная Saga %s выполнена с ошибками, не компенсированы шаги: %s", s.ID, strings.Join(failedCompensations, ", "))
+	}
+
 	// Обновляем статус Saga на Compensated
 	if err := coordinator.UpdateSagaStatus(ctx, s.ID, SagaStatusCompensated); err != nil {
 		log.Printf("Ошибка обновления статуса Saga на Compensated: %v", err)
@@ -219,17 +377,35 @@ func (s *IdempotentReportCreationSaga) RetryFailedSaga(ctx context.Context, coor
 	}
 
 	// Проверяем, что Saga действительно неудачная
-	if saga.Status != SagaStatusFailed {
+	if !saga.Status.IsRetryable() {
 		return fmt.Errorf("Saga %s не в статусе Failed, текущий статус: %s", s.ID, saga.Status)
 	}
 
+	// Saga, восстановленная только по ID, берет имя, шаги и данные из сохраненного состояния
+	if s.Name == "" {
+		s.Name = saga.Name
+	}
+	if len(s.Steps) == 0 {
+		s.Steps = saga.Steps
+	}
+	if s.UserID == "" {
+		s.UserID, _ = saga.Data["user_id"].(string)
+	}
+	if s.CorrelationID == "" {
+		s.CorrelationID = saga.CorrelationID()
+	}
+	s.data = saga.Data
+
 	// Сбрасываем статусы шагов для повторного выполнения
 	for _, step := range s.Steps {
-		if step.Status == SagaStepFailed {
+		if step.Status == SagaStepFailed || step.Status == SagaStepCompensated {
 			step.Status = SagaStepPending
 			step.Error = ""
+			step.Attempts = 0
 			step.ExecutedAt = nil
 			step.CompletedAt = nil
+			step.CompensationError = ""
+			step.CompensatedAt = nil
 		}
 	}
 
@@ -247,8 +423,11 @@ func (s *IdempotentReportCreationSaga) GetSagaProgress(ctx context.Context, coor
 	completedSteps := 0
 	failedSteps := 0
 	compensatedSteps := 0
+	steps := make([]StepProgress, 0, len(saga.Steps))
 
 	for _, step := range saga.Steps {
+		steps = append(steps, newStepProgress(step))
+
 		switch step.Status {
 		case SagaStepCompleted:
 			completedSteps++
@@ -270,9 +449,28 @@ func (s *IdempotentReportCreationSaga) GetSagaProgress(ctx context.Context, coor
 		CreatedAt:        saga.CreatedAt,
 		UpdatedAt:        saga.UpdatedAt,
 		CompletedAt:      saga.CompletedAt,
+		Steps:            steps,
 	}, nil
 }
 
+// newStepProgress формирует прогресс шага; длительность считается,
+// только если у шага известны и начало, и завершение
+func newStepProgress(step *SagaStep) StepProgress {
+	progress := StepProgress{
+		ID:          step.ID,
+		Name:        step.Name,
+		Status:      step.Status,
+		ExecutedAt:  step.ExecutedAt,
+		CompletedAt: step.CompletedAt,
+		Error:       step.Error,
+	}
+	if step.ExecutedAt != nil && step.CompletedAt != nil {
+		durationMs := step.CompletedAt.Sub(*step.ExecutedAt).Milliseconds()
+		progress.DurationMs = &durationMs
+	}
+	return progress
+}
+
 // SagaProgress представляет прогресс выполнения Saga
 type SagaProgress struct {
 	SagaID           string     `json:"saga_id"`
@@ -285,4 +483,17 @@ type SagaProgress struct {
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
 	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	// Steps шаги в порядке выполнения с временем начала, завершения и длительностью
+	Steps []StepProgress `json:"steps"`
+}
+
+// StepProgress прогресс одного шага Saga для отображения хронологии
+type StepProgress struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Status      SagaStepStatus `json:"status"`
+	ExecutedAt  *time.Time     `json:"executed_at,omitempty"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	DurationMs  *int64         `json:"duration_ms,omitempty"`
+	Error       string         `json:"error,omitempty"`
 }
@@ -2,6 +2,7 @@ package events
 
 import (
 	"context"
+	"strconv"
 	"time"
 )
 
@@ -33,8 +34,61 @@ type SagaStep struct {
 	Data        map[string]interface{} `json:"data"`
 	Status      SagaStepStatus         `json:"status"`
 	Error       string                 `json:"error,omitempty"`
+	Attempts    int                    `json:"attempts"`
 	ExecutedAt  *time.Time             `json:"executed_at,omitempty"`
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+	// CompensationError последняя ошибка компенсации после исчерпания повторов
+	CompensationError string     `json:"compensation_error,omitempty"`
+	CompensatedAt     *time.Time `json:"compensated_at,omitempty"`
+	// CompensationPolicy поведение Saga при неудачной компенсации шага; пустое значение — прежнее поведение
+	CompensationPolicy CompensationPolicy `json:"compensation_policy,omitempty"`
+	// Inputs значения из данных других шагов или самой Saga, которые координатор
+	// подставляет в Data перед выполнением шага; ключ — имя значения в Data шага
+	Inputs map[string]StepInput `json:"inputs,omitempty"`
+	// Optional ошибка шага сохраняется в его статусе, но не останавливает Saga
+	// и не запускает компенсацию выполненных шагов
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Done сообщает, что шаг не нужно выполнять снова: он завершен или это
+// необязательный шаг, завершившийся ошибкой
+func (s *SagaStep) Done() bool {
+	return s.Status == SagaStepCompleted || (s.Optional && s.Status == SagaStepFailed)
+}
+
+// StepInput ссылается на значение из данных шага-источника или самой Saga
+type StepInput struct {
+	// Step ID шага-источника; пустое значение — данные Saga
+	Step string `json:"step,omitempty"`
+	Key  string `json:"key"`
+}
+
+// FromStep объявляет вход из данных шага stepID по тому же ключу
+func FromStep(stepID, key string) StepInput {
+	return StepInput{Step: stepID, Key: key}
+}
+
+// CompensationPolicy определяет, как Saga реагирует на неудачную компенсацию шага
+type CompensationPolicy string
+
+const (
+	// CompensationBestEffort ошибка компенсации после повторов сохраняется на шаге, но не
+	// мешает Saga получить статус compensated
+	CompensationBestEffort CompensationPolicy = "best_effort"
+	// CompensationMustSucceed ошибка компенсации после повторов прерывает компенсацию
+	// остальных шагов и переводит Saga в compensation_failed
+	CompensationMustSucceed CompensationPolicy = "must_succeed"
+	// CompensationSkip компенсация шага не выполняется
+	CompensationSkip CompensationPolicy = "skip"
+)
+
+// IsValid сообщает, известна ли политика; пустая политика допустима
+func (p CompensationPolicy) IsValid() bool {
+	switch p {
+	case "", CompensationBestEffort, CompensationMustSucceed, CompensationSkip:
+		return true
+	}
+	return false
 }
 
 // SagaStepStatus представляет статус шага Saga
@@ -48,6 +102,15 @@ const (
 	SagaStepCompensated SagaStepStatus = "compensated"
 )
 
+// RecordMetadataAction действие шага, сохраняющего метаданные генерации отчета
+const RecordMetadataAction = "record_metadata"
+
+// SendReportToRecipientAction действие шага, отправляющего готовый отчет одному получателю
+const SendReportToRecipientAction = "send_report_to_recipient"
+
+// SagaStartedAtKey ключ времени старта Saga (RFC 3339) в данных Saga
+const SagaStartedAtKey = "started_at"
+
 // Saga представляет Saga транзакцию
 type Saga struct {
 	ID          string                 `json:"id"`
@@ -61,6 +124,74 @@ type Saga struct {
 	Error       string                 `json:"error,omitempty"`
 }
 
+// CorrelationID возвращает сквозной ID отчета, для которого запущена Saga
+func (s *Saga) CorrelationID() string {
+	correlationID, _ := s.Data[CorrelationIDKey].(string)
+	return correlationID
+}
+
+// ResolveInputs подставляет в data объявленные входы шага. Значение, которого еще нет
+// в источнике, не затирает данные шага
+func (s *Saga) ResolveInputs(step *SagaStep, data map[string]interface{}) {
+	for name, input := range step.Inputs {
+		source := s.Data
+		if input.Step != "" {
+			from := s.FindStep(input.Step)
+			if from == nil {
+				continue
+			}
+			source = from.Data
+		}
+		if value, ok := source[input.Key]; ok && value != nil && value != "" {
+			data[name] = value
+		}
+	}
+}
+
+// FindStep возвращает шаг Saga по ID или nil
+func (s *Saga) FindStep(stepID string) *SagaStep {
+	for _, step := range s.Steps {
+		if step.ID == stepID {
+			return step
+		}
+	}
+	return nil
+}
+
+// OwnerID возвращает ID пользователя, запустившего Saga
+func (s *Saga) OwnerID() string {
+	if userID, ok := s.Data["user_id"].(string); ok && userID != "" {
+		return userID
+	}
+	// Для Saga, сохраненных до появления user_id в данных, берем его из шагов
+	for _, step := range s.Steps {
+		if userID, ok := step.Data["user_id"].(string); ok && userID != "" {
+			return userID
+		}
+	}
+	return ""
+}
+
+// ReportID возвращает ID отчета, связанного с Saga, или 0, пока отчет не создан
+func (s *Saga) ReportID() uint {
+	candidates := []interface{}{s.Data["report_id"]}
+	for _, step := range s.Steps {
+		candidates = append(candidates, step.Data["report_id"])
+	}
+
+	for _, candidate := range candidates {
+		raw, ok := candidate.(string)
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err == nil && id != 0 {
+			return uint(id)
+		}
+	}
+	return 0
+}
+
 // SagaStatus представляет статус Saga
 type SagaStatus string
 
@@ -70,8 +201,25 @@ const (
 	SagaStatusCompleted   SagaStatus = "completed"
 	SagaStatusFailed      SagaStatus = "failed"
 	SagaStatusCompensated SagaStatus = "compensated"
+	// SagaStatusCompensationFailed хотя бы один шаг не удалось компенсировать
+	SagaStatusCompensationFailed SagaStatus = "compensation_failed"
 )
 
+// IsRetryable сообщает, можно ли повторно выполнить Saga: она завершилась ошибкой,
+// а выполненные шаги, если были, успешно компенсированы
+func (s SagaStatus) IsRetryable() bool {
+	return s == SagaStatusFailed || s == SagaStatusCompensated
+}
+
+// IsTerminal сообщает, что Saga больше не выполняется и ее состояние не изменится без повтора
+func (s SagaStatus) IsTerminal() bool {
+	switch s {
+	case SagaStatusCompleted, SagaStatusFailed, SagaStatusCompensated, SagaStatusCompensationFailed:
+		return true
+	}
+	return false
+}
+
 // SagaManager управляет Saga транзакциями
 type SagaManager interface {
 	StartSaga(ctx context.Context, saga *Saga) error
@@ -34,6 +34,11 @@ func NewOutboxManager(db *gorm.DB) *OutboxManager {
 	return &OutboxManager{db: db}
 }
 
+// WithTx возвращает OutboxManager, записывающий события в транзакции tx
+func (om *OutboxManager) WithTx(tx *gorm.DB) *OutboxManager {
+	return &OutboxManager{db: tx}
+}
+
 // SaveEvent сохраняет событие в Outbox таблице
 func (om *OutboxManager) SaveEvent(ctx context.Context, event *Event) error {
 	eventData, err := json.Marshal(event.Data)
@@ -65,6 +70,32 @@ func (om *OutboxManager) GetPendingEvents(ctx context.Context, limit int) ([]*Ou
 	return events, nil
 }
 
+// CountBacklog возвращает число ожидающих и неудачных событий
+func (om *OutboxManager) CountBacklog(ctx context.Context) (int64, int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := om.db.WithContext(ctx).Model(&OutboxEvent{}).
+		Select("status, COUNT(*) AS count").
+		Where("status IN ?", []string{"pending", "failed"}).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return 0, 0, fmt.Errorf("ошибка подсчета событий Outbox: %w", err)
+	}
+
+	var pending, failed int64
+	for _, row := range rows {
+		switch row.Status {
+		case "pending":
+			pending = row.Count
+		case "failed":
+			failed = row.Count
+		}
+	}
+	return pending, failed, nil
+}
+
 // MarkAsProcessing помечает событие как обрабатываемое
 func (om *OutboxManager) MarkAsProcessing(ctx context.Context, eventID string) error {
 	if err := om.db.WithContext(ctx).Model(&OutboxEvent{}).Where("id = ?", eventID).Update("status", "processing").Error; err != nil {
@@ -118,8 +149,9 @@ func NewOutboxPublisher(om *OutboxManager, ep EventPublisher) *OutboxPublisher {
 	}
 }
 
-// StartPublishing запускает процесс публикации событий из Outbox
-func (op *OutboxPublisher) StartPublishing(ctx context.Context, interval time.Duration, batchSize int) {
+// StartPublishing запускает процесс публикации событий из Outbox. Размер пачки
+// запрашивается у batchSize перед каждым проходом, чтобы его можно было менять без перезапуска
+func (op *OutboxPublisher) StartPublishing(ctx context.Context, interval time.Duration, batchSize func() int) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -129,7 +161,7 @@ func (op *OutboxPublisher) StartPublishing(ctx context.Context, interval time.Du
 			log.Println("Остановка Outbox Publisher")
 			return
 		case <-ticker.C:
-			op.publishPendingEvents(ctx, batchSize)
+			op.publishPendingEvents(ctx, batchSize())
 		}
 	}
 }
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"report-service/internal/metrics"
+)
+
+// OutboxBacklogMonitor периодически публикует размер очереди Outbox в метрики
+type OutboxBacklogMonitor struct {
+	outboxManager *OutboxManager
+	metrics       *metrics.Metrics
+}
+
+// NewOutboxBacklogMonitor создает монитор очереди Outbox
+func NewOutboxBacklogMonitor(outboxManager *OutboxManager, metrics *metrics.Metrics) *OutboxBacklogMonitor {
+	return &OutboxBacklogMonitor{
+		outboxManager: outboxManager,
+		metrics:       metrics,
+	}
+}
+
+// Start обновляет метрики сразу и затем с заданным интервалом до отмены контекста
+func (m *OutboxBacklogMonitor) Start(ctx context.Context, interval time.Duration) {
+	m.report(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Остановка монитора очереди Outbox")
+			return
+		case <-ticker.C:
+			m.report(ctx)
+		}
+	}
+}
+
+func (m *OutboxBacklogMonitor) report(ctx context.Context) {
+	pending, failed, err := m.outboxManager.CountBacklog(ctx)
+	if err != nil {
+		log.Printf("Ошибка получения размера очереди Outbox: %v", err)
+		return
+	}
+	m.metrics.SetOutboxBacklog("report-service", pending, failed)
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+)
+
+// seedOutbox сохраняет count событий Outbox в статусе status
+func seedOutbox(t *testing.T, db *gorm.DB, status string, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		event := &OutboxEvent{
+			ID:          fmt.Sprintf("%s-%d-%d", status, i, time.Now().UnixNano()),
+			EventType:   ReportCreated,
+			AggregateID: "1",
+			Data:        `{"report_id": 1}`,
+			Status:      status,
+		}
+		if err := db.Create(event).Error; err != nil {
+			t.Fatalf("создание события Outbox: %v", err)
+		}
+	}
+}
+
+func TestOutboxBacklogMonitorReportsSeededRows(t *testing.T) {
+	_, db := newTestStateStore(t)
+	outbox := NewOutboxManager(db)
+	if err := outbox.MigrateOutboxTable(context.Background()); err != nil {
+		t.Fatalf("миграция Outbox: %v", err)
+	}
+	seedOutbox(t, db, "pending", 3)
+	seedOutbox(t, db, "failed", 2)
+	seedOutbox(t, db, "processing", 1)
+	seedOutbox(t, db, "published", 4)
+
+	m := testMetrics()
+	pending := m.OutboxPending.WithLabelValues("report-service")
+	failed := m.OutboxFailed.WithLabelValues("report-service")
+	m.SetOutboxBacklog("report-service", -1, -1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		NewOutboxBacklogMonitor(outbox, m).Start(ctx, 10*time.Millisecond)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	waitFor := func(wantPending, wantFailed float64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if testutil.ToFloat64(pending) == wantPending && testutil.ToFloat64(failed) == wantFailed {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("outbox_pending %v, outbox_failed %v, ожидалось %v и %v",
+			testutil.ToFloat64(pending), testutil.ToFloat64(failed), wantPending, wantFailed)
+	}
+
+	// Обрабатываемые и опубликованные события в очередь не входят
+	waitFor(3, 2)
+
+	// Следующая проверка видит изменения в таблице
+	seedOutbox(t, db, "pending", 2)
+	db.Model(&OutboxEvent{}).Where("status = ?", "failed").Update("status", "published")
+	waitFor(5, 0)
+}
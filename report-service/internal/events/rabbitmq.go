@@ -4,17 +4,28 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
+
+	"report-service/internal/metrics"
 
 	"github.com/streadway/amqp"
 )
 
+// publisherChannel методы канала AMQP, которые использует издатель
+type publisherChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Close() error
+}
+
 // RabbitMQPublisher реализует EventPublisher для RabbitMQ
 type RabbitMQPublisher struct {
 	conn    *amqp.Connection
-	channel *amqp.Channel
+	channel publisherChannel
+	metrics *metrics.Metrics
 }
 
-func NewRabbitMQPublisher(amqpURL string) (*RabbitMQPublisher, error) {
+func NewRabbitMQPublisher(amqpURL string, metrics *metrics.Metrics) (*RabbitMQPublisher, error) {
 	conn, err := amqp.Dial(amqpURL)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка подключения к RabbitMQ: %w", err)
@@ -29,6 +40,7 @@ func NewRabbitMQPublisher(amqpURL string) (*RabbitMQPublisher, error) {
 	return &RabbitMQPublisher{
 		conn:    conn,
 		channel: channel,
+		metrics: metrics,
 	}, nil
 }
 
@@ -48,10 +60,17 @@ func (p *RabbitMQPublisher) PublishAsync(ctx context.Context, event *Event) erro
 }
 
 // publishEvent публикует событие
-func (p *RabbitMQPublisher) publishEvent(event *Event, async bool) error {
+func (p *RabbitMQPublisher) publishEvent(event *Event, async bool) (err error) {
+	start := time.Now()
+	defer func() {
+		if p.metrics != nil {
+			p.metrics.RecordEventPublished("report-service", string(event.Type), time.Since(start), err)
+		}
+	}()
+
 	// Создаем exchange если не существует
 	exchangeName := "events"
-	err := p.channel.ExchangeDeclare(
+	err = p.channel.ExchangeDeclare(
 		exchangeName, // name
 		"topic",      // type
 		true,         // durable
@@ -111,10 +130,11 @@ func (p *RabbitMQPublisher) Close() error {
 type RabbitMQSubscriber struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
+	metrics *metrics.Metrics
 }
 
 // NewRabbitMQSubscriber создает новый RabbitMQ subscriber
-func NewRabbitMQSubscriber(amqpURL string) (*RabbitMQSubscriber, error) {
+func NewRabbitMQSubscriber(amqpURL string, metrics *metrics.Metrics) (*RabbitMQSubscriber, error) {
 	conn, err := amqp.Dial(amqpURL)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка подключения к RabbitMQ: %w", err)
@@ -129,6 +149,7 @@ func NewRabbitMQSubscriber(amqpURL string) (*RabbitMQSubscriber, error) {
 	return &RabbitMQSubscriber{
 		conn:    conn,
 		channel: channel,
+		metrics: metrics,
 	}, nil
 }
 
@@ -206,7 +227,11 @@ func (s *RabbitMQSubscriber) Subscribe(ctx context.Context, eventType EventType,
 			select {
 			case <-ctx.Done():
 				return
-			case msg := <-msgs:
+			case msg, ok := <-msgs:
+				if !ok {
+					log.Printf("Канал сообщений %s закрыт", eventType)
+					return
+				}
 				s.handleMessage(ctx, msg, handler)
 			}
 		}
@@ -218,11 +243,14 @@ func (s *RabbitMQSubscriber) Subscribe(ctx context.Context, eventType EventType,
 
 // handleMessage обрабатывает входящее сообщение
 func (s *RabbitMQSubscriber) handleMessage(ctx context.Context, msg amqp.Delivery, handler EventHandler) {
+	start := time.Now()
+
 	// Парсим событие
 	event, err := FromJSON(msg.Body)
 	if err != nil {
 		log.Printf("Ошибка парсинга события: %v", err)
 		msg.Nack(false, false)
+		s.recordConsumed(msg.RoutingKey, "reject", start)
 		return
 	}
 
@@ -231,14 +259,23 @@ func (s *RabbitMQSubscriber) handleMessage(ctx context.Context, msg amqp.Deliver
 	if err != nil {
 		log.Printf("Ошибка обработки события %s: %v", event.Type, err)
 		msg.Nack(false, true) // Повторяем попытку
+		s.recordConsumed(string(event.Type), "nack", start)
 		return
 	}
 
 	// Подтверждаем обработку
 	msg.Ack(false)
+	s.recordConsumed(string(event.Type), "ack", start)
 	log.Printf("Событие %s обработано Report Service", event.Type)
 }
 
+// recordConsumed записывает метрики обработки сообщения
+func (s *RabbitMQSubscriber) recordConsumed(eventType, result string, start time.Time) {
+	if s.metrics != nil {
+		s.metrics.RecordEventConsumed("report-service", eventType, result, time.Since(start))
+	}
+}
+
 // Unsubscribe отписывается от событий
 func (s *RabbitMQSubscriber) Unsubscribe(ctx context.Context, eventType EventType) error {
 	log.Printf("Отписка Report Service от событий %s", eventType)
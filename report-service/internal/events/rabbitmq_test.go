@@ -0,0 +1,136 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/streadway/amqp"
+)
+
+// fakeChannel запоминает опубликованные сообщения и может завершать публикацию ошибкой
+type fakeChannel struct {
+	publishErr error
+	published  []amqp.Publishing
+}
+
+func (c *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	return nil
+}
+
+func (c *fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if c.publishErr != nil {
+		return c.publishErr
+	}
+	c.published = append(c.published, msg)
+	return nil
+}
+
+func (c *fakeChannel) Close() error {
+	return nil
+}
+
+// fakeAcknowledger запоминает, как было подтверждено сообщение
+type fakeAcknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+// fakeEventHandler завершает обработку ошибкой err
+type fakeEventHandler struct {
+	err error
+}
+
+func (h *fakeEventHandler) Handle(ctx context.Context, event *Event) error {
+	return h.err
+}
+
+func (h *fakeEventHandler) EventType() EventType {
+	return ReportCompleted
+}
+
+func TestRabbitMQPublisherRecordsMetrics(t *testing.T) {
+	m := testMetrics()
+	published := m.EventsPublishedTotal.WithLabelValues("report-service", string(ReportCompleted), "success")
+	failed := m.EventsPublishedTotal.WithLabelValues("report-service", string(ReportCompleted), "error")
+	publishedBefore, failedBefore := testutil.ToFloat64(published), testutil.ToFloat64(failed)
+
+	channel := &fakeChannel{}
+	publisher := &RabbitMQPublisher{channel: channel, metrics: m}
+	event := &Event{ID: "event-1", Type: ReportCompleted, Timestamp: time.Now()}
+
+	if err := publisher.Publish(context.Background(), event); err != nil {
+		t.Fatalf("публикация: %v", err)
+	}
+	if len(channel.published) != 1 || channel.published[0].MessageId != "event-1" {
+		t.Fatalf("опубликованы сообщения %+v", channel.published)
+	}
+
+	channel.publishErr = errors.New("канал закрыт")
+	if err := publisher.Publish(context.Background(), event); err == nil {
+		t.Fatal("ожидалась ошибка публикации")
+	}
+
+	if got := testutil.ToFloat64(published) - publishedBefore; got != 1 {
+		t.Errorf("успешных публикаций учтено %v, ожидалась 1", got)
+	}
+	if got := testutil.ToFloat64(failed) - failedBefore; got != 1 {
+		t.Errorf("ошибок публикации учтено %v, ожидалась 1", got)
+	}
+}
+
+func TestRabbitMQSubscriberRecordsConsumeResult(t *testing.T) {
+	m := testMetrics()
+	subscriber := &RabbitMQSubscriber{metrics: m}
+	body, _ := (&Event{ID: "event-1", Type: ReportCompleted}).ToJSON()
+
+	tests := []struct {
+		name      string
+		body      []byte
+		handleErr error
+		eventType string
+		result    string
+	}{
+		{"обработано", body, nil, string(ReportCompleted), "ack"},
+		{"ошибка обработки", body, errors.New("сервис недоступен"), string(ReportCompleted), "nack"},
+		{"некорректное сообщение", []byte("{"), nil, "report.broken", "reject"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := m.EventsConsumedTotal.WithLabelValues("report-service", tt.eventType, tt.result)
+			before := testutil.ToFloat64(counter)
+
+			ack := &fakeAcknowledger{}
+			delivery := amqp.Delivery{Acknowledger: ack, RoutingKey: tt.eventType, Body: tt.body}
+			subscriber.handleMessage(context.Background(), delivery, &fakeEventHandler{err: tt.handleErr})
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("результат %s учтен %v раз, ожидался 1", tt.result, got)
+			}
+			if ack.acked != (tt.result == "ack") || ack.nacked == (tt.result == "ack") {
+				t.Errorf("подтверждение: ack=%v nack=%v", ack.acked, ack.nacked)
+			}
+			// Повторная доставка только для ошибки обработки, битое сообщение отбрасывается
+			if ack.requeued != (tt.result == "nack") {
+				t.Errorf("повторная доставка %v", ack.requeued)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownEventType тип события не зарегистрирован
+var ErrUnknownEventType = errors.New("неизвестный тип события")
+
+// EventDefinition описание зарегистрированного типа события
+type EventDefinition struct {
+	Type EventType
+	// RequiredFields ключи, которые обязательно должны быть в Data события
+	RequiredFields []string
+}
+
+// Validate проверяет данные события по описанию типа
+func (d EventDefinition) Validate(data map[string]interface{}) error {
+	for _, field := range d.RequiredFields {
+		if _, ok := data[field]; !ok {
+			return fmt.Errorf("событие %s: отсутствует обязательное поле %s", d.Type, field)
+		}
+	}
+	return nil
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[EventType]EventDefinition)
+)
+
+func init() {
+	for _, def := range []EventDefinition{
+		{Type: ReportCreated, RequiredFields: []string{"report_id"}},
+		{Type: ReportUpdated, RequiredFields: []string{"report_id"}},
+		{Type: ReportDeleted, RequiredFields: []string{"report_id"}},
+		{Type: ReportGenerated, RequiredFields: []string{"saga_id"}},
+		{Type: ReportCompleted, RequiredFields: []string{"report_id"}},
+		{Type: ReportFailed, RequiredFields: []string{"report_id"}},
+
+		{Type: SagaStarted, RequiredFields: []string{"saga_id"}},
+		{Type: SagaCompleted, RequiredFields: []string{"saga_id"}},
+		{Type: SagaFailed, RequiredFields: []string{"saga_id"}},
+		{Type: SagaCompensated, RequiredFields: []string{"saga_id"}},
+
+		{Type: UserValidated},
+		{Type: UserValidationFailed},
+		{Type: TemplateValidated},
+		{Type: TemplateValidationFailed},
+		{Type: DataCollected},
+		{Type: DataCollectionFailed},
+		{Type: FileStored},
+		{Type: FileStorageFailed},
+
+		{Type: NotificationDelivered, RequiredFields: []string{"report_id"}},
+		{Type: NotificationFailed, RequiredFields: []string{"report_id"}},
+	} {
+		if err := RegisterEventType(def); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterEventType регистрирует новый тип события
+func RegisterEventType(def EventDefinition) error {
+	if def.Type == "" {
+		return errors.New("тип события не указан")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[def.Type]; exists {
+		return fmt.Errorf("тип события %s уже зарегистрирован", def.Type)
+	}
+	registry[def.Type] = def
+	return nil
+}
+
+// LookupEventType возвращает описание зарегистрированного типа события
+func LookupEventType(eventType EventType) (EventDefinition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	def, ok := registry[eventType]
+	return def, ok
+}
+
+// KnownEventTypes возвращает все зарегистрированные типы событий в порядке сортировки
+func KnownEventTypes() []EventType {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]EventType, 0, len(registry))
+	for eventType := range registry {
+		types = append(types, eventType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// IsKnown сообщает, зарегистрирован ли тип события
+func (t EventType) IsKnown() bool {
+	_, ok := LookupEventType(t)
+	return ok
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// registerTestEventType регистрирует тип события на время теста
+func registerTestEventType(t *testing.T, def EventDefinition) {
+	t.Helper()
+	if err := RegisterEventType(def); err != nil {
+		t.Fatalf("регистрация %s: %v", def.Type, err)
+	}
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, def.Type)
+		registryMu.Unlock()
+	})
+}
+
+func TestNewEventRejectsUnknownType(t *testing.T) {
+	for _, eventType := range []EventType{"report.archived", "", "REPORT.CREATED"} {
+		event, err := NewEvent(eventType, "report-service", map[string]interface{}{"report_id": 1})
+		if !errors.Is(err, ErrUnknownEventType) || event != nil {
+			t.Errorf("тип %q: событие %v, ошибка %v, ожидалась ErrUnknownEventType", eventType, event, err)
+		}
+		if eventType.IsKnown() {
+			t.Errorf("тип %q считается известным", eventType)
+		}
+	}
+}
+
+func TestNewEventBuildsRegisteredType(t *testing.T) {
+	data := map[string]interface{}{"report_id": uint(42), "status": "completed"}
+	event, err := NewEvent(ReportCompleted, "report-service", data)
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+	if event.Type != ReportCompleted || event.Source != "report-service" || event.Data["report_id"] != uint(42) {
+		t.Errorf("событие %+v", event)
+	}
+	if event.ID == "" || event.Timestamp.IsZero() || event.Metadata == nil {
+		t.Errorf("ID %q, время %v, метаданные %v", event.ID, event.Timestamp, event.Metadata)
+	}
+
+	// Обязательные поля проверяются по описанию типа
+	if _, err := NewEvent(ReportCompleted, "report-service", map[string]interface{}{"status": "completed"}); err == nil || !strings.Contains(err.Error(), "report_id") {
+		t.Errorf("без report_id: ошибка %v", err)
+	}
+	// Типы без обязательных полей создаются и без данных
+	if _, err := NewEvent(FileStored, "storage-service", nil); err != nil {
+		t.Errorf("file.stored без данных: %v", err)
+	}
+}
+
+func TestRegisterEventType(t *testing.T) {
+	const archived EventType = "report.archived"
+	registerTestEventType(t, EventDefinition{Type: archived, RequiredFields: []string{"report_id", "archived_at"}})
+
+	if !archived.IsKnown() {
+		t.Fatal("зарегистрированный тип не найден")
+	}
+	found := false
+	for _, eventType := range KnownEventTypes() {
+		found = found || eventType == archived
+	}
+	if !found {
+		t.Errorf("KnownEventTypes() не содержит %s", archived)
+	}
+
+	if _, err := NewEvent(archived, "report-service", map[string]interface{}{"report_id": 1}); err == nil || !strings.Contains(err.Error(), "archived_at") {
+		t.Errorf("без archived_at: ошибка %v", err)
+	}
+	if _, err := NewEvent(archived, "report-service", map[string]interface{}{"report_id": 1, "archived_at": "2024-05-01"}); err != nil {
+		t.Errorf("NewEvent: %v", err)
+	}
+
+	// Повторная регистрация и пустой тип отклоняются
+	if err := RegisterEventType(EventDefinition{Type: ReportCreated}); err == nil {
+		t.Error("повторная регистрация report.created принята")
+	}
+	if err := RegisterEventType(EventDefinition{}); err == nil {
+		t.Error("регистрация без типа принята")
+	}
+}
+
+func TestKnownEventTypesAreSorted(t *testing.T) {
+	types := KnownEventTypes()
+	for i := 1; i < len(types); i++ {
+		if types[i-1] >= types[i] {
+			t.Fatalf("типы не отсортированы: %s перед %s", types[i-1], types[i])
+		}
+	}
+	for _, eventType := range []EventType{ReportCreated, SagaCompensated, NotificationFailed} {
+		if !eventType.IsKnown() {
+			t.Errorf("встроенный тип %s не зарегистрирован", eventType)
+		}
+	}
+}
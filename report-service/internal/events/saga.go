@@ -2,6 +2,7 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -144,16 +145,18 @@ func (s *ReportCreationSaga) compensate(ctx context.Context, coordinator SagaMan
 		err := coordinator.CompensateStep(ctx, s.ID, step.ID)
 		if err != nil {
 			log.Printf("Ошибка компенсации шага %s: %v", step.Name, err)
+			step.CompensationError = err.Error()
+			if errors.Is(err, ErrCompensationAborted) {
+				break
+			}
 			// Продолжаем компенсацию других шагов
+			continue
 		}
 
+		now := time.Now()
 		step.Status = SagaStepCompensated
+		step.CompensatedAt = &now
 	}
 
 	return fmt.Errorf("Saga %s выполнена с ошибками и компенсирована", s.ID)
 }
-
-// generateSagaID генерирует уникальный ID для Saga
-func generateSagaID() string {
-	return "saga-" + time.Now().Format("20060102150405") + "-" + randomString(6)
-}
@@ -0,0 +1,164 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// staleRetriesKey ключ в данных Saga со счетчиком повторов после зависания
+const staleRetriesKey = "stale_retries"
+
+// StaleSagaMonitor находит Saga, зависшие в статусе executing, и повторяет их
+// или, после исчерпания попыток, переводит в failed с компенсацией шагов
+type StaleSagaMonitor struct {
+	coordinator *IdempotentSagaCoordinator
+	stateStore  *SagaStateStore
+	pool        *SagaWorkerPool
+	locker      GenerationLocker
+	threshold   time.Duration
+	maxRetries  int
+	batchSize   int
+}
+
+// NewStaleSagaMonitor создает монитор зависших Saga; повтор выполняется под блокировкой
+// генерации отчета из locker
+func NewStaleSagaMonitor(coordinator *IdempotentSagaCoordinator, stateStore *SagaStateStore, pool *SagaWorkerPool, locker GenerationLocker, threshold time.Duration, maxRetries int) *StaleSagaMonitor {
+	return &StaleSagaMonitor{
+		coordinator: coordinator,
+		stateStore:  stateStore,
+		pool:        pool,
+		locker:      locker,
+		threshold:   threshold,
+		maxRetries:  maxRetries,
+		batchSize:   50,
+	}
+}
+
+// Start периодически проверяет зависшие Saga до отмены контекста
+func (m *StaleSagaMonitor) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Остановка монитора зависших Saga")
+			return
+		case <-ticker.C:
+			m.checkStaleSagas(ctx)
+		}
+	}
+}
+
+func (m *StaleSagaMonitor) checkStaleSagas(ctx context.Context) {
+	states, err := m.stateStore.FindStaleSagas(ctx, time.Now().Add(-m.threshold), m.batchSize)
+	if err != nil {
+		log.Printf("Ошибка поиска зависших Saga: %v", err)
+		return
+	}
+
+	for _, state := range states {
+		claimed, err := m.stateStore.ClaimStaleSaga(ctx, state.ID, state.UpdatedAt)
+		if err != nil {
+			log.Printf("%v", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := m.recover(ctx, state.ID); err != nil {
+			log.Printf("Ошибка обработки зависшей Saga %s: %v", state.ID, err)
+		}
+	}
+}
+
+// recover повторяет зависшую Saga с первого незавершенного шага или запускает компенсацию
+func (m *StaleSagaMonitor) recover(ctx context.Context, sagaID string) error {
+	saga, err := m.stateStore.GetSagaState(ctx, sagaID)
+	if err != nil {
+		return err
+	}
+
+	retries := staleRetries(saga)
+	if retries >= m.maxRetries {
+		cause := fmt.Errorf("Saga не завершилась за %s после %d повторов", m.threshold, retries)
+		log.Printf("Saga %s зависла, запускаем компенсацию", sagaID)
+		return m.pool.Submit(func(ctx context.Context) {
+			m.fail(ctx, sagaID, cause)
+		})
+	}
+
+	var fromStep *SagaStep
+	for _, step := range saga.Steps {
+		if !step.Done() {
+			fromStep = step
+			break
+		}
+	}
+	if fromStep == nil {
+		// Все шаги выполнены, не был сохранен только итоговый статус
+		return m.coordinator.UpdateSagaStatus(ctx, sagaID, SagaStatusCompleted)
+	}
+
+	// Шаг, прерванный во время выполнения, остался в executing: ExecuteStep такой шаг
+	// отклоняет, поэтому перед повтором он возвращается в pending
+	if fromStep.Status == SagaStepExecuting {
+		fromStep.Status = SagaStepPending
+		fromStep.ExecutedAt = nil
+	}
+
+	// Пока блокировку держит другая генерация отчета, Saga не повторяется: монитор
+	// вернется к ней через threshold
+	if err := LockSagaReport(m.locker, saga); err != nil {
+		return err
+	}
+
+	if saga.Data == nil {
+		saga.Data = make(map[string]interface{})
+	}
+	saga.Data[staleRetriesKey] = retries + 1
+	if err := m.stateStore.SaveSagaState(ctx, saga); err != nil {
+		ReleaseSagaReport(ctx, m.locker, m.stateStore, sagaID)
+		return err
+	}
+
+	fromStepID := fromStep.ID
+	log.Printf("Saga %s зависла на шаге %s, повтор %d из %d", sagaID, fromStepID, retries+1, m.maxRetries)
+	err = m.pool.Submit(func(ctx context.Context) {
+		defer ReleaseSagaReport(ctx, m.locker, m.stateStore, sagaID)
+		if err := m.coordinator.ResumeSaga(ctx, sagaID, fromStepID); err != nil {
+			log.Printf("Ошибка повторного выполнения Saga %s: %v", sagaID, err)
+			m.fail(ctx, sagaID, err)
+		}
+	})
+	if err != nil {
+		ReleaseSagaReport(ctx, m.locker, m.stateStore, sagaID)
+	}
+	return err
+}
+
+// fail завершает Saga ошибкой так же, как при ошибке шага во время выполнения:
+// уведомляет о неудачной генерации отчета и компенсирует выполненные шаги
+func (m *StaleSagaMonitor) fail(ctx context.Context, sagaID string, cause error) {
+	if err := m.coordinator.NotifyReportFailed(ctx, sagaID, cause); err != nil {
+		log.Printf("Ошибка публикации события report.failed: %v", err)
+	}
+	if err := m.coordinator.CompensateSaga(ctx, sagaID, cause.Error()); err != nil {
+		log.Printf("Ошибка компенсации Saga %s: %v", sagaID, err)
+	}
+}
+
+// staleRetries возвращает число уже выполненных повторов зависшей Saga
+func staleRetries(saga *Saga) int {
+	// После JSON десериализации число приходит как float64
+	switch v := saga.Data[staleRetriesKey].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeLocker блокировка генерации отчетов в памяти
+type fakeLocker struct {
+	locked map[uint]bool
+}
+
+func (l *fakeLocker) AcquireGenerationLock(reportID uint) error {
+	if l.locked[reportID] {
+		return errors.New("генерация отчета уже выполняется")
+	}
+	l.locked[reportID] = true
+	return nil
+}
+
+func (l *fakeLocker) ReleaseGenerationLock(reportID uint) {
+	delete(l.locked, reportID)
+}
+
+// saveExecutingSaga сохраняет Saga отчета, прерванную на втором шаге, и сдвигает
+// время ее обновления на age назад
+func saveExecutingSaga(t *testing.T, store *SagaStateStore, db *gorm.DB, id string, age time.Duration, data map[string]interface{}) {
+	t.Helper()
+
+	steps := []*SagaStep{
+		{ID: "generate-report", Service: "report-service", Action: "generate_report", Compensate: "delete_report", Status: SagaStepCompleted, Data: map[string]interface{}{"report_id": "5"}},
+		{ID: "store-file", Service: "storage-service", Action: "store_file", Compensate: "delete_file", Status: SagaStepExecuting},
+		{ID: "update-status", Service: "report-service", Action: "update_report_status", Compensate: "none", Status: SagaStepPending},
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	saga := &Saga{ID: id, Name: "report_creation", Status: SagaStatusExecuting, Steps: steps, Data: data}
+	if err := store.SaveSagaState(context.Background(), saga); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+	if err := db.Model(&SagaState{}).Where("id = ?", id).UpdateColumn("updated_at", time.Now().Add(-age)).Error; err != nil {
+		t.Fatalf("сдвиг времени обновления Saga: %v", err)
+	}
+}
+
+// runStaleCheck выполняет одну проверку монитора и ждет завершения запущенных ею задач
+func runStaleCheck(t *testing.T, handler *fakeStepHandler, locker *fakeLocker, prepare func(store *SagaStateStore, db *gorm.DB)) *SagaStateStore {
+	t.Helper()
+
+	store, db := newTestStateStore(t)
+	coordinator := NewIdempotentSagaCoordinator(NewLocalEventPublisher(), store, handler, testMetrics(), 0)
+	coordinator.retryDelay = time.Millisecond
+	prepare(store, db)
+
+	pool := NewSagaWorkerPool(1, 10)
+	pool.Start()
+	NewStaleSagaMonitor(coordinator, store, pool, locker, time.Minute, 1).checkStaleSagas(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := pool.Stop(ctx); err != nil {
+		t.Fatalf("остановка пула: %v", err)
+	}
+	return store
+}
+
+func TestStaleSagaMonitorResumesStaleSaga(t *testing.T) {
+	handler := &fakeStepHandler{}
+	locker := &fakeLocker{locked: map[uint]bool{}}
+	store := runStaleCheck(t, handler, locker, func(store *SagaStateStore, db *gorm.DB) {
+		saveExecutingSaga(t, store, db, "saga-stale", 10*time.Minute, nil)
+		saveExecutingSaga(t, store, db, "saga-fresh", 0, nil)
+	})
+	ctx := context.Background()
+
+	stale, err := store.GetSagaState(ctx, "saga-stale")
+	if err != nil {
+		t.Fatalf("получение Saga: %v", err)
+	}
+	if stale.Status != SagaStatusCompleted {
+		t.Errorf("зависшая Saga в статусе %s, ожидался completed", stale.Status)
+	}
+	if staleRetries(stale) != 1 {
+		t.Errorf("счетчик повторов %d, ожидался 1", staleRetries(stale))
+	}
+	// Повтор продолжается с прерванного шага, выполненные шаги не повторяются
+	if strings.Join(handler.executed, ",") != "store-file,update-status" {
+		t.Errorf("выполнены шаги %v, ожидались store-file и update-status", handler.executed)
+	}
+	if len(locker.locked) != 0 {
+		t.Errorf("блокировка генерации не снята: %v", locker.locked)
+	}
+
+	fresh, err := store.GetSagaState(ctx, "saga-fresh")
+	if err != nil {
+		t.Fatalf("получение Saga: %v", err)
+	}
+	if fresh.Status != SagaStatusExecuting || staleRetries(fresh) != 0 {
+		t.Errorf("Saga младше порога затронута: статус %s, повторов %d", fresh.Status, staleRetries(fresh))
+	}
+}
+
+func TestStaleSagaMonitorCompensatesAfterRetries(t *testing.T) {
+	handler := &fakeStepHandler{}
+	locker := &fakeLocker{locked: map[uint]bool{}}
+	store := runStaleCheck(t, handler, locker, func(store *SagaStateStore, db *gorm.DB) {
+		saveExecutingSaga(t, store, db, "saga-stale", 10*time.Minute, map[string]interface{}{staleRetriesKey: 1})
+	})
+
+	saga, err := store.GetSagaState(context.Background(), "saga-stale")
+	if err != nil {
+		t.Fatalf("получение Saga: %v", err)
+	}
+	if saga.Status != SagaStatusCompensated {
+		t.Errorf("Saga в статусе %s, ожидался compensated", saga.Status)
+	}
+	if len(handler.executed) != 0 {
+		t.Errorf("после исчерпания повторов выполнены шаги %v", handler.executed)
+	}
+	if len(handler.compensated) != 1 || handler.compensated[0] != "generate-report" {
+		t.Errorf("компенсированы шаги %v, ожидался generate-report", handler.compensated)
+	}
+}
+
+func TestStaleSagaMonitorSkipsLockedReport(t *testing.T) {
+	handler := &fakeStepHandler{}
+	// Генерацию отчета уже выполняет другая Saga
+	locker := &fakeLocker{locked: map[uint]bool{5: true}}
+	store := runStaleCheck(t, handler, locker, func(store *SagaStateStore, db *gorm.DB) {
+		saveExecutingSaga(t, store, db, "saga-stale", 10*time.Minute, nil)
+	})
+
+	saga, err := store.GetSagaState(context.Background(), "saga-stale")
+	if err != nil {
+		t.Fatalf("получение Saga: %v", err)
+	}
+	if saga.Status != SagaStatusExecuting || staleRetries(saga) != 0 || len(handler.executed) != 0 {
+		t.Errorf("Saga повторена при занятой блокировке: статус %s, повторов %d, шаги %v", saga.Status, staleRetries(saga), handler.executed)
+	}
+}
@@ -22,6 +22,8 @@ type SagaState struct {
 	Error       string     `gorm:"type:text" json:"error,omitempty"`
 	RetryCount  int        `gorm:"default:0" json:"retry_count"`
 	LastStepID  string     `json:"last_step_id,omitempty"`
+	// ReportID отчет, с которым работает Saga; 0, пока отчет не создан
+	ReportID uint `gorm:"index" json:"report_id,omitempty"`
 }
 
 // EventLog представляет лог событий для идемпотентности
@@ -62,14 +64,14 @@ func (s *SagaStateStore) SaveSagaState(ctx context.Context, saga *Saga) error {
 	}
 
 	sagaState := &SagaState{
-		ID:         saga.ID,
-		Name:       saga.Name,
-		Status:     saga.Status,
-		Steps:      string(stepsJSON),
-		Data:       string(dataJSON),
-		UpdatedAt:  time.Now(),
-		Error:      saga.Error,
-		RetryCount: 0,
+		ID:        saga.ID,
+		Name:      saga.Name,
+		Status:    saga.Status,
+		Steps:     string(stepsJSON),
+		Data:      string(dataJSON),
+		UpdatedAt: time.Now(),
+		Error:     saga.Error,
+		ReportID:  saga.ReportID(),
 	}
 
 	// Определяем последний выполненный шаг
@@ -82,8 +84,8 @@ func (s *SagaStateStore) SaveSagaState(ctx context.Context, saga *Saga) error {
 		}
 	}
 
-	// Используем Upsert для идемпотентности
-	return s.db.WithContext(ctx).Save(sagaState).Error
+	// Используем Upsert для идемпотентности; счетчик повторов ведет только IncrementRetryCount
+	return s.db.WithContext(ctx).Omit("RetryCount").Save(sagaState).Error
 }
 
 // GetSagaState получает состояние Saga
@@ -141,9 +143,93 @@ func (s *SagaStateStore) IncrementRetryCount(ctx context.Context, sagaID string)
 	return s.db.WithContext(ctx).Model(&SagaState{}).Where("id = ?", sagaID).UpdateColumn("retry_count", gorm.Expr("retry_count + ?", 1)).Error
 }
 
+// FindStaleSagas возвращает Saga в статусе executing, которые не обновлялись с момента olderThan
+func (s *SagaStateStore) FindStaleSagas(ctx context.Context, olderThan time.Time, limit int) ([]SagaState, error) {
+	var states []SagaState
+	err := s.db.WithContext(ctx).
+		Where("status = ? AND updated_at < ?", SagaStatusExecuting, olderThan).
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&states).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска зависших Saga: %w", err)
+	}
+	return states, nil
+}
+
+// FindSagaIDByReportID возвращает ID последней Saga отчета
+func (s *SagaStateStore) FindSagaIDByReportID(ctx context.Context, reportID uint) (string, error) {
+	var sagaState SagaState
+	err := s.db.WithContext(ctx).
+		Select("id").
+		Where("report_id = ?", reportID).
+		Order("created_at DESC").
+		First(&sagaState).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", fmt.Errorf("Saga для отчета %d не найдена", reportID)
+		}
+		return "", fmt.Errorf("ошибка поиска Saga отчета: %w", err)
+	}
+	return sagaState.ID, nil
+}
+
+// ClaimStaleSaga помечает зависшую Saga как взятую в обработку, обновляя updated_at.
+// Возвращает false, если Saga уже изменилась или ее забрал другой экземпляр сервиса.
+func (s *SagaStateStore) ClaimStaleSaga(ctx context.Context, sagaID string, updatedAt time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&SagaState{}).
+		Where("id = ? AND status = ? AND updated_at = ?", sagaID, SagaStatusExecuting, updatedAt).
+		UpdateColumn("updated_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("ошибка захвата Saga %s: %w", sagaID, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteFinishedSagas удаляет завершенные и компенсированные Saga, не обновлявшиеся с olderThan,
+// вместе с журналом событий. Удаление идет пачками по batchSize, каждая пачка в своей транзакции.
+func (s *SagaStateStore) DeleteFinishedSagas(ctx context.Context, olderThan time.Time, batchSize int) (sagas, eventLogs int64, err error) {
+	finished := []SagaStatus{SagaStatusCompleted, SagaStatusCompensated}
+	for {
+		var ids []string
+		if err := s.db.WithContext(ctx).Model(&SagaState{}).
+			Where("status IN ? AND updated_at < ?", finished, olderThan).
+			Order("updated_at ASC").
+			Limit(batchSize).
+			Pluck("id", &ids).Error; err != nil {
+			return sagas, eventLogs, fmt.Errorf("ошибка поиска завершенных Saga: %w", err)
+		}
+		if len(ids) == 0 {
+			return sagas, eventLogs, nil
+		}
+
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("saga_id IN ?", ids).Delete(&EventLog{})
+			if result.Error != nil {
+				return result.Error
+			}
+			eventLogs += result.RowsAffected
+
+			result = tx.Where("id IN ?", ids).Delete(&SagaState{})
+			if result.Error != nil {
+				return result.Error
+			}
+			sagas += result.RowsAffected
+			return nil
+		})
+		if err != nil {
+			return sagas, eventLogs, fmt.Errorf("ошибка удаления завершенных Saga: %w", err)
+		}
+		if len(ids) < batchSize {
+			return sagas, eventLogs, nil
+		}
+	}
+}
+
 // LogEvent логирует событие для идемпотентности
 func (s *SagaStateStore) LogEvent(ctx context.Context, sagaID, eventID string, eventType EventType) error {
 	eventLog := &EventLog{
+		ID:        eventID,
 		SagaID:    sagaID,
 		EventID:   eventID,
 		EventType: eventType,
@@ -172,7 +258,58 @@ func (s *SagaStateStore) GetSagaByEventID(ctx context.Context, eventID string) (
 	return s.GetSagaState(ctx, eventLog.SagaID)
 }
 
-// MigrateSagaTables создает таблицы для Saga
+// GetSagaStateRecord получает сохраненную запись Saga без десериализации
+func (s *SagaStateStore) GetSagaStateRecord(ctx context.Context, sagaID string) (*SagaState, error) {
+	var sagaState SagaState
+	if err := s.db.WithContext(ctx).Where("id = ?", sagaID).First(&sagaState).Error; err != nil {
+		return nil, err
+	}
+	return &sagaState, nil
+}
+
+// GetEventLogs возвращает журнал событий Saga в порядке записи
+func (s *SagaStateStore) GetEventLogs(ctx context.Context, sagaID string) ([]EventLog, error) {
+	var logs []EventLog
+	err := s.db.WithContext(ctx).Where("saga_id = ?", sagaID).Order("created_at ASC").Find(&logs).Error
+	return logs, err
+}
+
+// MigrateSagaTables создает таблицы для Saga и заполняет report_id у Saga,
+// сохраненных до появления колонки
 func (s *SagaStateStore) MigrateSagaTables(ctx context.Context) error {
-	return s.db.WithContext(ctx).AutoMigrate(&SagaState{}, &EventLog{})
+	if err := s.db.WithContext(ctx).AutoMigrate(&SagaState{}, &EventLog{}); err != nil {
+		return err
+	}
+	return s.backfillReportIDs(ctx)
+}
+
+// backfillReportIDs проставляет report_id по данным шагов Saga, у которых он не заполнен
+func (s *SagaStateStore) backfillReportIDs(ctx context.Context) error {
+	var states []SagaState
+	err := s.db.WithContext(ctx).
+		Select("id", "steps", "data").
+		Where("report_id = 0 OR report_id IS NULL").
+		FindInBatches(&states, 500, func(tx *gorm.DB, batch int) error {
+			for _, state := range states {
+				saga := &Saga{}
+				if err := json.Unmarshal([]byte(state.Steps), &saga.Steps); err != nil {
+					continue
+				}
+				_ = json.Unmarshal([]byte(state.Data), &saga.Data)
+
+				reportID := saga.ReportID()
+				if reportID == 0 {
+					continue
+				}
+				if err := s.db.WithContext(ctx).Model(&SagaState{}).Where("id = ?", state.ID).
+					UpdateColumn("report_id", reportID).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("ошибка заполнения report_id Saga: %w", err)
+	}
+	return nil
 }
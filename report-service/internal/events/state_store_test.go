@@ -0,0 +1,88 @@
+package events
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestStateStore создает хранилище Saga поверх отдельной SQLite базы
+func newTestStateStore(t *testing.T) (*SagaStateStore, *gorm.DB) {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "sagas.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	store := NewSagaStateStore(db)
+	if err := store.MigrateSagaTables(context.Background()); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+	return store, db
+}
+
+func TestFindSagaIDByReportIDUsesReportColumn(t *testing.T) {
+	store, db := newTestStateStore(t)
+	ctx := context.Background()
+
+	saga := NewIdempotentReportCreationSaga("12", "1", "1", nil)
+	if err := store.SaveSagaState(ctx, &Saga{ID: saga.ID, Name: saga.Name, Status: SagaStatusFailed, Steps: saga.Steps, Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+	// ID отчета, содержащий искомый как подстроку, не должен находиться
+	other := NewIdempotentReportCreationSaga("120", "1", "1", nil)
+	if err := store.SaveSagaState(ctx, &Saga{ID: other.ID, Name: other.Name, Status: SagaStatusFailed, Steps: other.Steps, Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+
+	var record SagaState
+	if err := db.First(&record, "id = ?", saga.ID).Error; err != nil || record.ReportID != 12 {
+		t.Fatalf("report_id Saga = %d (%v), ожидался 12", record.ReportID, err)
+	}
+
+	found, err := store.FindSagaIDByReportID(ctx, 12)
+	if err != nil {
+		t.Fatalf("поиск Saga: %v", err)
+	}
+	if found != saga.ID {
+		t.Errorf("найдена Saga %s, ожидалась %s", found, saga.ID)
+	}
+
+	if _, err := store.FindSagaIDByReportID(ctx, 7); err == nil {
+		t.Error("ожидалась ошибка для отчета без Saga")
+	}
+}
+
+func TestMigrateSagaTablesBackfillsReportID(t *testing.T) {
+	store, db := newTestStateStore(t)
+	ctx := context.Background()
+
+	// Saga, сохраненная до появления колонки report_id
+	saga := NewIdempotentReportCreationSaga("34", "1", "1", nil)
+	if err := store.SaveSagaState(ctx, &Saga{ID: saga.ID, Name: saga.Name, Status: SagaStatusCompleted, Steps: saga.Steps, Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+	if err := db.Model(&SagaState{}).Where("id = ?", saga.ID).UpdateColumn("report_id", 0).Error; err != nil {
+		t.Fatalf("сброс report_id: %v", err)
+	}
+
+	if err := store.MigrateSagaTables(ctx); err != nil {
+		t.Fatalf("повторная миграция: %v", err)
+	}
+
+	found, err := store.FindSagaIDByReportID(ctx, 34)
+	if err != nil || found != saga.ID {
+		t.Errorf("после миграции найдена Saga %q (%v), ожидалась %s", found, err, saga.ID)
+	}
+}
@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+// ErrSagaQueueFull возвращается, когда очередь выполнения Saga переполнена
+var ErrSagaQueueFull = errors.New("очередь выполнения Saga переполнена")
+
+// SagaTask задача выполнения Saga
+type SagaTask func(ctx context.Context)
+
+// SagaWorkerPool ограниченный пул воркеров для выполнения Saga
+type SagaWorkerPool struct {
+	tasks   chan SagaTask
+	workers int
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// NewSagaWorkerPool создает пул с заданным числом воркеров и размером очереди
+func NewSagaWorkerPool(workers, queueSize int) *SagaWorkerPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SagaWorkerPool{
+		tasks:   make(chan SagaTask, queueSize),
+		workers: workers,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start запускает воркеры пула
+func (p *SagaWorkerPool) Start() {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	log.Printf("Пул выполнения Saga запущен: %d воркеров, очередь %d", p.workers, cap(p.tasks))
+}
+
+// Submit ставит задачу в очередь без блокировки
+func (p *SagaWorkerPool) Submit(task SagaTask) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return ErrSagaQueueFull
+	}
+
+	select {
+	case p.tasks <- task:
+		return nil
+	default:
+		return ErrSagaQueueFull
+	}
+}
+
+// Stop прекращает прием задач и дожидается завершения выполняющихся и
+// поставленных в очередь. Если ctx истек раньше, выполняющиеся Saga
+// прерываются, а Stop возвращает ошибку контекста.
+func (p *SagaWorkerPool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		// Прерываем выполняющиеся Saga, если не успели завершиться
+		p.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// worker выполняет задачи из очереди
+func (p *SagaWorkerPool) worker() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		p.run(task)
+	}
+}
+
+// run выполняет задачу, не позволяя панике остановить воркер
+func (p *SagaWorkerPool) run(task SagaTask) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Паника при выполнении Saga: %v", r)
+		}
+	}()
+	task(p.ctx)
+}
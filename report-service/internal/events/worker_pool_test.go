@@ -0,0 +1,170 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSagaWorkerPoolCapsConcurrency(t *testing.T) {
+	pool := NewSagaWorkerPool(2, 10)
+	pool.Start()
+
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		err := pool.Submit(func(ctx context.Context) {
+			defer wg.Done()
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+		if err != nil {
+			t.Fatalf("задача %d отклонена: %v", i, err)
+		}
+	}
+	wg.Wait()
+
+	if maxRunning != 2 {
+		t.Errorf("одновременно выполнялось задач: %d, ожидалось 2", maxRunning)
+	}
+	if err := pool.Stop(context.Background()); err != nil {
+		t.Fatalf("остановка пула: %v", err)
+	}
+}
+
+func TestSagaWorkerPoolRejectsOverflow(t *testing.T) {
+	pool := NewSagaWorkerPool(1, 2)
+	pool.Start()
+
+	// Воркер занят первой задачей, две следующие ждут в очереди
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var completed int32
+	block := func(ctx context.Context) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		atomic.AddInt32(&completed, 1)
+	}
+
+	if err := pool.Submit(block); err != nil {
+		t.Fatalf("первая задача: %v", err)
+	}
+	<-started
+	for i := 0; i < 2; i++ {
+		if err := pool.Submit(block); err != nil {
+			t.Fatalf("задача в очередь: %v", err)
+		}
+	}
+	if err := pool.Submit(block); !errors.Is(err, ErrSagaQueueFull) {
+		t.Fatalf("при заполненной очереди ожидалась ErrSagaQueueFull, получено %v", err)
+	}
+
+	close(release)
+	if err := pool.Stop(context.Background()); err != nil {
+		t.Fatalf("остановка пула: %v", err)
+	}
+	if completed != 3 {
+		t.Errorf("выполнено задач: %d, ожидалось 3 — поставленные в очередь задачи должны завершиться", completed)
+	}
+	if err := pool.Submit(block); !errors.Is(err, ErrSagaQueueFull) {
+		t.Errorf("после остановки ожидалась ErrSagaQueueFull, получено %v", err)
+	}
+}
+
+func TestSagaWorkerPoolSurvivesPanic(t *testing.T) {
+	pool := NewSagaWorkerPool(1, 2)
+	pool.Start()
+
+	done := make(chan struct{})
+	pool.Submit(func(ctx context.Context) { panic("ошибка Saga") })
+	pool.Submit(func(ctx context.Context) { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("после паники воркер не выполнил следующую задачу")
+	}
+	pool.Stop(context.Background())
+}
+
+func TestSagaWorkerPoolStopDrainsWithinTimeout(t *testing.T) {
+	pool := NewSagaWorkerPool(2, 4)
+	pool.Start()
+
+	// Задачи выполняются и стоят в очереди в момент остановки
+	var completed, cancelled int32
+	for i := 0; i < 4; i++ {
+		err := pool.Submit(func(ctx context.Context) {
+			select {
+			case <-time.After(50 * time.Millisecond):
+				atomic.AddInt32(&completed, 1)
+			case <-ctx.Done():
+				atomic.AddInt32(&cancelled, 1)
+			}
+		})
+		if err != nil {
+			t.Fatalf("задача %d отклонена: %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := pool.Stop(ctx); err != nil {
+		t.Fatalf("остановка пула: %v", err)
+	}
+	if completed != 4 || cancelled != 0 {
+		t.Errorf("завершено %d, прервано %d, ожидалось 4 и 0", completed, cancelled)
+	}
+}
+
+func TestSagaWorkerPoolStopCancelsAfterTimeout(t *testing.T) {
+	pool := NewSagaWorkerPool(1, 1)
+	pool.Start()
+
+	started := make(chan struct{})
+	finished := make(chan error, 1)
+	pool.Submit(func(ctx context.Context) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			finished <- ctx.Err()
+		case <-time.After(5 * time.Second):
+			finished <- nil
+		}
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := pool.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ожидалась ошибка таймаута, получено %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("остановка заняла %v при таймауте 30мс", elapsed)
+	}
+
+	// Stop возвращается только после того, как прерванная Saga завершилась
+	select {
+	case err := <-finished:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Saga завершилась с %v, ожидалась отмена контекста", err)
+		}
+	default:
+		t.Error("Stop вернулся раньше завершения Saga")
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"report-service/internal/apperrors"
+	"report-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// DetailHandler обработчик сводной информации об отчете
+type DetailHandler struct {
+	detailService *services.DetailService
+}
+
+// NewDetailHandler создает новый обработчик сводной информации
+func NewDetailHandler(detailService *services.DetailService) *DetailHandler {
+	return &DetailHandler{
+		detailService: detailService,
+	}
+}
+
+// GetReportDetail возвращает отчет вместе с шаблоном и метаданными файла
+func (h *DetailHandler) GetReportDetail(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
+		return
+	}
+
+	detail, err := h.detailService.GetReportDetail(c.Request.Context(), uint(id), userID.(uint), c.GetHeader("Authorization"))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения сводной информации об отчете")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// GetReportTrace возвращает Saga, файлы и уведомления, связанные с отчетом через correlation_id
+func (h *DetailHandler) GetReportTrace(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
+		return
+	}
+
+	trace, err := h.detailService.GetReportTrace(c.Request.Context(), uint(id), userID.(uint), c.GetHeader("Authorization"))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения трассировки отчета")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, trace)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"report-service/internal/clients"
+	"report-service/internal/models"
+	"report-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubDownstream поддельный соседний сервис: отвечает status и body на запрос к path
+// и запоминает заголовок Authorization последнего запроса
+func stubDownstream(t *testing.T, path string, status int, body string) (*httptest.Server, *string) {
+	t.Helper()
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if r.URL.Path != path {
+			t.Errorf("запрос к %s, ожидался %s", r.URL.Path, path)
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &authorization
+}
+
+func TestGetReportDetailCombinesDownstreams(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusCompleted)
+	env.db.Model(report).Update("md5_hash", "abc123")
+
+	const templateOK = `{"id": 1, "name": "Продажи", "type": "sales"}`
+	const fileOK = `{"id": 7, "name": "report.csv", "size": 2048, "mime_type": "text/csv", "hash": "abc123"}`
+
+	tests := []struct {
+		name           string
+		templateStatus int
+		templateBody   string
+		storageStatus  int
+		storageBody    string
+		wantWarnings   []string
+	}{
+		{"все сервисы отвечают", http.StatusOK, templateOK, http.StatusOK, fileOK, nil},
+		{"storage-service недоступен", http.StatusOK, templateOK, http.StatusInternalServerError, ``, []string{"storage-service недоступен"}},
+		{"шаблон удален", http.StatusNotFound, ``, http.StatusOK, fileOK, []string{"шаблон отчета не найден"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, templateAuth := stubDownstream(t, "/api/v1/templates/1", tt.templateStatus, tt.templateBody)
+			storage, storageAuth := stubDownstream(t, "/api/v1/files/hash/abc123", tt.storageStatus, tt.storageBody)
+			retry := clients.RetryPolicy{Attempts: 1}
+			detailService := services.NewDetailService(env.reportService,
+				clients.NewTemplateClient(templates.URL, retry, 0),
+				clients.NewStorageClient(storage.URL, retry),
+				nil, env.coordinator)
+			router := env.router(1, func(r gin.IRoutes) {
+				r.GET("/reports/:id/detail", NewDetailHandler(detailService).GetReportDetail)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/reports/%d/detail", report.ID), nil)
+			req.Header.Set("Authorization", "Bearer user-token")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			// Сбой соседнего сервиса не прерывает запрос
+			if rec.Code != http.StatusOK {
+				t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+			}
+			var detail models.ReportDetailResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+			if detail.Report.ID != report.ID {
+				t.Errorf("отчет %d, ожидался %d", detail.Report.ID, report.ID)
+			}
+			if *templateAuth != "Bearer user-token" || *storageAuth != "Bearer user-token" {
+				t.Errorf("Authorization не передан: %q, %q", *templateAuth, *storageAuth)
+			}
+
+			if ok := tt.templateStatus == http.StatusOK; detail.TemplateAvailable != ok || (detail.Template != nil) != ok {
+				t.Errorf("шаблон %+v, template_available %v", detail.Template, detail.TemplateAvailable)
+			}
+			if ok := tt.storageStatus == http.StatusOK; detail.FileAvailable != ok || (detail.File != nil) != ok {
+				t.Errorf("файл %+v, file_available %v", detail.File, detail.FileAvailable)
+			}
+			if detail.File != nil && (detail.File.ID != 7 || detail.File.Size != 2048 || detail.File.DownloadURL != storage.URL+"/api/v1/files/7/download") {
+				t.Errorf("файл %+v", detail.File)
+			}
+
+			if detail.Partial != (len(tt.wantWarnings) > 0) || fmt.Sprint(detail.Warnings) != fmt.Sprint(tt.wantWarnings) {
+				t.Errorf("partial %v, предупреждения %v, ожидались %v", detail.Partial, detail.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
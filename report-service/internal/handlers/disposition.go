@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportContentTypes сопоставляет расширение выгрузки с MIME-типом
+var exportContentTypes = map[string]string{
+	".csv":  "text/csv; charset=utf-8",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".json": "application/json; charset=utf-8",
+	".zip":  "application/zip",
+}
+
+// maxFilenameLength ограничивает длину базового имени файла в символах
+const maxFilenameLength = 100
+
+// sanitizeFilename убирает из имени управляющие символы, кавычки и разделители путей.
+// Если после очистки имя пустое, возвращается fallback.
+func sanitizeFilename(name, fallback string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case unicode.IsControl(r):
+			continue
+		case strings.ContainsRune(`"\/:*?<>|;`, r):
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	result := strings.Trim(b.String(), " .")
+	if runes := []rune(result); len(runes) > maxFilenameLength {
+		result = string(runes[:maxFilenameLength])
+	}
+	if result == "" {
+		return fallback
+	}
+	return result
+}
+
+// asciiFilename заменяет не-ASCII символы для параметра filename старых клиентов
+func asciiFilename(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < 0x20 || r > 0x7e {
+			b.WriteRune('_')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// encodeRFC5987 кодирует значение ext-value: все байты вне attr-char экранируются через %XX
+func encodeRFC5987(value string) string {
+	const attrChars = "!#$&+-.^_`|~"
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		if ch < 0x80 && (unicode.IsLetter(rune(ch)) || unicode.IsDigit(rune(ch)) || strings.IndexByte(attrChars, ch) >= 0) {
+			b.WriteByte(ch)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+	return b.String()
+}
+
+// setAttachmentHeaders выставляет Content-Type по расширению и Content-Disposition
+// с именем файла в двух формах: ASCII и RFC 5987 (filename*=UTF-8”).
+func setAttachmentHeaders(c *gin.Context, filename string) {
+	contentType, ok := exportContentTypes[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(
+		"attachment; filename=\"%s\"; filename*=UTF-8''%s",
+		asciiFilename(filename), encodeRFC5987(filename),
+	))
+}
+
+// reportFilename формирует имя файла выгрузки отчета по его названию с откатом на ID
+func reportFilename(name string, id uint, ext string) string {
+	return sanitizeFilename(name, fmt.Sprintf("report_%d", id)) + ext
+}
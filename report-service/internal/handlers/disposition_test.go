@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"report-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attachmentHeader возвращает Content-Disposition, выставленный для имени файла
+func attachmentHeader(filename string) string {
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	setAttachmentHeaders(c, filename)
+	return rec.Header().Get("Content-Disposition")
+}
+
+func TestAttachmentFilenameSanitizing(t *testing.T) {
+	tests := []struct {
+		name      string
+		report    string
+		wantFile  string
+		wantASCII string
+	}{
+		{"пробелы сохраняются", "  Sales report 2024  ", "Sales report 2024.csv", "Sales report 2024.csv"},
+		{"кавычки заменяются", `Q1 "final" report`, "Q1 _final_ report.csv", "Q1 _final_ report.csv"},
+		{"unicode", "Отчет за май", "Отчет за май.csv", "_____ __ ___.csv"},
+		{"emoji", "📊 Продажи", "📊 Продажи.csv", "_ _______.csv"},
+		{"разделители путей", "../../etc/passwd", "_.._etc_passwd.csv", "_.._etc_passwd.csv"},
+		{"переводы строк", "отчет\r\nSet-Cookie: a=1", "отчетSet-Cookie_ a=1.csv", "_____Set-Cookie_ a=1.csv"},
+		{"пустое имя", " \t. ", "report_7.csv", "report_7.csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := reportFilename(tt.report, 7, ".csv")
+			if filename != tt.wantFile {
+				t.Fatalf("имя файла %q, ожидалось %q", filename, tt.wantFile)
+			}
+
+			header := attachmentHeader(filename)
+			if strings.ContainsAny(header, "\r\n") {
+				t.Fatalf("заголовок содержит перевод строки: %q", header)
+			}
+			if want := fmt.Sprintf(`attachment; filename="%s"; `, tt.wantASCII); !strings.HasPrefix(header, want) {
+				t.Errorf("заголовок %q, ожидалось начало %q", header, want)
+			}
+
+			// filename* декодируется обратно в исходное имя
+			disposition, params, err := mime.ParseMediaType(header)
+			if err != nil {
+				t.Fatalf("разбор %q: %v", header, err)
+			}
+			if disposition != "attachment" || params["filename"] != tt.wantFile {
+				t.Errorf("разобрано %q %q, ожидалось имя %q", disposition, params["filename"], tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestAttachmentFilenameTruncatesLongNames(t *testing.T) {
+	filename := reportFilename(strings.Repeat("я", maxFilenameLength+20), 1, ".csv")
+	if want := strings.Repeat("я", maxFilenameLength) + ".csv"; filename != want {
+		t.Errorf("имя из %d символов, ожидалось %d", len([]rune(filename)), len([]rune(want)))
+	}
+}
+
+func TestExportReportCSVUsesReportName(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusCompleted)
+	if err := env.db.Model(report).Update("name", `Итоги "Q2" 2024`).Error; err != nil {
+		t.Fatalf("переименование отчета: %v", err)
+	}
+	router := env.router(1, func(r gin.IRoutes) { r.GET("/reports/:id/export/csv", env.reports.ExportReportCSV) })
+
+	rec := doJSON(router, http.MethodGet, fmt.Sprintf("/reports/%d/export/csv", report.ID), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+	_, params, err := mime.ParseMediaType(rec.Header().Get("Content-Disposition"))
+	if err != nil {
+		t.Fatalf("разбор Content-Disposition: %v", err)
+	}
+	if want := "Итоги _Q2_ 2024.csv"; params["filename"] != want {
+		t.Errorf("имя файла %q, ожидалось %q", params["filename"], want)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type %q", got)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"report-service/internal/apperrors"
+	"report-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportHandler обработчик выгрузки всех отчетов пользователя
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandler создает новый обработчик выгрузки отчетов
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// parseTimezone разбирает параметр tz (имя зоны IANA, например Europe/Moscow) для дат
+// в выгрузках. Без параметра используется UTC; при неизвестной зоне отвечает 400.
+func parseTimezone(c *gin.Context) (*time.Location, bool) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return time.UTC, true
+	}
+	// Local зависит от настроек сервера, поэтому не принимается
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		apperrors.Respond(c, apperrors.Validation("Некорректный параметр tz, ожидается имя часового пояса IANA"))
+		return nil, false
+	}
+	return loc, true
+}
+
+// ExportAllReports потоково отдает ZIP архив со всеми отчетами пользователя
+func (h *ExportHandler) ExportAllReports(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
+		return
+	}
+
+	loc, ok := parseTimezone(c)
+	if !ok {
+		return
+	}
+
+	reports, err := h.exportService.ListUserReports(userID.(uint))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения отчетов для выгрузки")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("reports_%d_%s.zip", userID.(uint), time.Now().Format("20060102_150405"))
+	setAttachmentHeaders(c, filename)
+	c.Status(http.StatusOK)
+
+	// После начала передачи статус изменить нельзя: при ошибке клиент получит обрезанный архив
+	if err := h.exportService.WriteArchive(c.Request.Context(), userID.(uint), reports, c.GetHeader("Authorization"), loc, c.Writer); err != nil {
+		logrus.WithError(err).Errorf("Выгрузка отчетов пользователя %d прервана", userID.(uint))
+		c.Abort()
+	}
+}
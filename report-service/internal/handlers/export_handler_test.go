@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"report-service/internal/clients"
+	"report-service/internal/models"
+	"report-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportStorage storage-service с одним файлом sales.csv (ID 7, хеш ready-hash)
+func exportStorage(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/files/hash/ready-hash", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 7, "name": "sales.csv", "size": 19, "mime_type": "text/csv", "hash": "ready-hash"}`))
+	})
+	mux.HandleFunc("/api/v1/files/7/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("month,total\nmay,42\n"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// readArchive распаковывает ZIP архив в карту имя файла → содержимое с сохранением порядка имен
+func readArchive(t *testing.T, body []byte) ([]string, map[string][]byte) {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("ответ не ZIP архив: %v", err)
+	}
+	names := make([]string, 0, len(archive.File))
+	contents := make(map[string][]byte, len(archive.File))
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("открытие %s: %v", file.Name, err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("чтение %s: %v", file.Name, err)
+		}
+		names = append(names, file.Name)
+		contents[file.Name] = content
+	}
+	return names, contents
+}
+
+func TestExportAllReportsWritesFilesAndManifest(t *testing.T) {
+	env := newTestEnv(t)
+	ready := env.createReport(t, 1, models.StatusCompleted)
+	env.db.Model(ready).Update("md5_hash", "ready-hash")
+	pending := env.createReport(t, 1, models.StatusPending)
+	noFile := env.createReport(t, 1, models.StatusCompleted)
+	lost := env.createReport(t, 1, models.StatusCompleted)
+	env.db.Model(lost).Update("md5_hash", "lost-hash")
+	env.createReport(t, 2, models.StatusCompleted)
+
+	storage := exportStorage(t)
+	exportService := services.NewExportService(env.reportService, clients.NewStorageClient(storage.URL, clients.RetryPolicy{Attempts: 1}))
+	router := env.router(1, func(r gin.IRoutes) {
+		r.GET("/reports/export/all", NewExportHandler(exportService).ExportAllReports)
+	})
+
+	rec := doJSON(router, http.MethodGet, "/reports/export/all", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "application/zip" || !strings.Contains(rec.Header().Get("Content-Disposition"), "reports_1_") {
+		t.Errorf("Content-Type %q, Content-Disposition %q", rec.Header().Get("Content-Type"), rec.Header().Get("Content-Disposition"))
+	}
+
+	// В архиве только файл готового отчета и манифест
+	names, contents := readArchive(t, rec.Body.Bytes())
+	readyFile := fmt.Sprintf("reports/%d_sales.csv", ready.ID)
+	if strings.Join(names, ",") != readyFile+",manifest.json" {
+		t.Fatalf("файлы архива %v", names)
+	}
+	if string(contents[readyFile]) != "month,total\nmay,42\n" {
+		t.Errorf("содержимое %s: %q", readyFile, contents[readyFile])
+	}
+
+	var manifest models.ReportExportManifest
+	if err := json.Unmarshal(contents["manifest.json"], &manifest); err != nil {
+		t.Fatalf("разбор манифеста: %v", err)
+	}
+	if manifest.UserID != 1 || manifest.GeneratedAt.IsZero() {
+		t.Errorf("манифест user_id %d, generated_at %v", manifest.UserID, manifest.GeneratedAt)
+	}
+
+	// Отчеты другого пользователя в манифест не попадают
+	want := []struct {
+		id       uint
+		included bool
+		note     string
+	}{
+		{ready.ID, true, ""},
+		{pending.ID, false, "отчет не готов (статус pending)"},
+		{noFile.ID, false, "у отчета нет файла"},
+		{lost.ID, false, "файл отчета не найден в хранилище"},
+	}
+	if len(manifest.Reports) != len(want) {
+		t.Fatalf("записей манифеста %d, ожидалось %d: %+v", len(manifest.Reports), len(want), manifest.Reports)
+	}
+	for i, expected := range want {
+		entry := manifest.Reports[i]
+		if entry.ID != expected.id || entry.Included != expected.included || entry.Note != expected.note {
+			t.Errorf("запись %d: %+v, ожидались id %d, included %v, note %q", i, entry, expected.id, expected.included, expected.note)
+		}
+	}
+	if entry := manifest.Reports[0]; entry.File != readyFile || entry.FileSize != 19 || entry.MD5Hash != "ready-hash" {
+		t.Errorf("файл готового отчета в манифесте: %+v", entry)
+	}
+}
+
+func TestExportsFormatDatesInRequestedTimezone(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusPending)
+	createdAt := time.Date(2024, 5, 1, 21, 30, 0, 0, time.UTC)
+	env.db.Model(report).UpdateColumns(map[string]interface{}{"created_at": createdAt, "status": string(models.StatusCompleted)})
+
+	exportService := services.NewExportService(env.reportService, clients.NewStorageClient(exportStorage(t).URL, clients.RetryPolicy{Attempts: 1}))
+	router := env.router(1, func(r gin.IRoutes) {
+		r.GET("/reports/:id/export/csv", env.reports.ExportReportCSV)
+		r.GET("/reports/export/all", NewExportHandler(exportService).ExportAllReports)
+	})
+	csvPath := fmt.Sprintf("/reports/%d/export/csv?columns=created_at", report.ID)
+
+	tests := []struct {
+		name   string
+		query  string
+		want   string
+		offset string
+	}{
+		{"UTC по умолчанию", "", "2024-05-01T21:30:00Z", "Z"},
+		{"явный UTC", "&tz=UTC", "2024-05-01T21:30:00Z", "Z"},
+		{"Москва", "&tz=Europe/Moscow", "2024-05-02T00:30:00+03:00", "+03:00"},
+		{"Нью-Йорк, летнее время", "&tz=America/New_York", "2024-05-01T17:30:00-04:00", "-04:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doJSON(router, http.MethodGet, csvPath+tt.query, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("CSV: статус %d: %s", rec.Code, rec.Body.String())
+			}
+			if want := "Created At\n" + tt.want + "\n"; rec.Body.String() != want {
+				t.Errorf("CSV %q, ожидалось %q", rec.Body.String(), want)
+			}
+
+			rec = doJSON(router, http.MethodGet, "/reports/export/all?"+strings.TrimPrefix(tt.query, "&"), nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("архив: статус %d: %s", rec.Code, rec.Body.String())
+			}
+			_, contents := readArchive(t, rec.Body.Bytes())
+			var manifest struct {
+				GeneratedAt string `json:"generated_at"`
+				Reports     []struct {
+					CreatedAt string `json:"created_at"`
+				} `json:"reports"`
+			}
+			if err := json.Unmarshal(contents["manifest.json"], &manifest); err != nil {
+				t.Fatalf("разбор манифеста: %v", err)
+			}
+			if len(manifest.Reports) != 1 || manifest.Reports[0].CreatedAt != tt.want {
+				t.Errorf("created_at в манифесте %+v, ожидалось %s", manifest.Reports, tt.want)
+			}
+			generatedAt, err := time.Parse(time.RFC3339Nano, manifest.GeneratedAt)
+			if err != nil {
+				t.Fatalf("generated_at %q: %v", manifest.GeneratedAt, err)
+			}
+			if !strings.HasSuffix(manifest.GeneratedAt, tt.offset) || time.Since(generatedAt) > time.Minute {
+				t.Errorf("generated_at %s, ожидалось текущее время со смещением %s", manifest.GeneratedAt, tt.offset)
+			}
+		})
+	}
+
+	for _, tz := range []string{"Mars/Olympus", "Local", "+03:00"} {
+		t.Run("некорректный пояс "+tz, func(t *testing.T) {
+			for _, path := range []string{csvPath + "&tz=" + url.QueryEscape(tz), "/reports/export/all?tz=" + url.QueryEscape(tz)} {
+				rec := doJSON(router, http.MethodGet, path, nil)
+				if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"validation_error"`) {
+					t.Errorf("%s: статус %d: %s", path, rec.Code, rec.Body.String())
+				}
+			}
+		})
+	}
+}
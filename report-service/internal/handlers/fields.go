@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseFields разбирает параметр fields со списком полей через запятую.
+// Возвращает nil, если проекция не запрошена.
+func parseFields(c *gin.Context) map[string]bool {
+	raw := c.Query("fields")
+	if raw == "" {
+		return nil
+	}
+
+	fields := make(map[string]bool)
+	for _, field := range strings.Split(raw, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields[field] = true
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// projectFields оставляет в JSON-представлении каждого элемента только
+// запрошенные поля. Неизвестные поля игнорируются.
+func projectFields(items interface{}, fields map[string]bool) ([]map[string]json.RawMessage, error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	projected := make([]map[string]json.RawMessage, 0, len(decoded))
+	for _, item := range decoded {
+		shaped := make(map[string]json.RawMessage, len(fields))
+		for name, value := range item {
+			if fields[name] {
+				shaped[name] = value
+			}
+		}
+		projected = append(projected, shaped)
+	}
+	return projected, nil
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"report-service/internal/audit"
+	"report-service/internal/events"
+	"report-service/internal/metrics"
+	"report-service/internal/models"
+	"report-service/internal/repository"
+	"report-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testMetrics метрики регистрируются в глобальном реестре, поэтому создаются один раз
+var testMetrics = sync.OnceValue(func() *metrics.Metrics {
+	return metrics.NewMetrics("report-service-test")
+})
+
+// stubStepHandler выполняет шаги Saga без обращения к другим сервисам и запоминает их
+type stubStepHandler struct {
+	mu       sync.Mutex
+	executed []string
+	// fail возвращает ошибку выполнения шага, если не nil
+	fail func(step *events.SagaStep) error
+	// failCompensation возвращает ошибку компенсации шага, если не nil
+	failCompensation func(step *events.SagaStep) error
+}
+
+func (h *stubStepHandler) ExecuteStep(ctx context.Context, step *events.SagaStep) error {
+	h.mu.Lock()
+	h.executed = append(h.executed, step.ID)
+	fail := h.fail
+	h.mu.Unlock()
+
+	if fail != nil {
+		return fail(step)
+	}
+	return nil
+}
+
+func (h *stubStepHandler) CompensateStep(ctx context.Context, step *events.SagaStep) error {
+	h.mu.Lock()
+	fail := h.failCompensation
+	h.mu.Unlock()
+
+	if fail != nil {
+		return fail(step)
+	}
+	return nil
+}
+
+// testEnv обработчики report-service поверх отдельной SQLite базы
+type testEnv struct {
+	db            *gorm.DB
+	reportService *services.ReportService
+	stateStore    *events.SagaStateStore
+	coordinator   *events.IdempotentSagaCoordinator
+	pool          *events.SagaWorkerPool
+	steps         *stubStepHandler
+	reports       *ReportHandler
+	sagas         *SagaHandler
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dsn := filepath.Join(t.TempDir(), "reports.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.AutoMigrate(&models.Report{}, &models.ReportShare{}, &models.ReportGenerationLock{}, &audit.AuditLog{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+	stateStore := events.NewSagaStateStore(db)
+	if err := stateStore.MigrateSagaTables(context.Background()); err != nil {
+		t.Fatalf("миграция Saga: %v", err)
+	}
+	outbox := events.NewOutboxManager(db)
+	if err := outbox.MigrateOutboxTable(context.Background()); err != nil {
+		t.Fatalf("миграция Outbox: %v", err)
+	}
+
+	reportService := services.NewReportService(repository.NewReportRepository(db), repository.NewReportGenerationLockRepository(db), outbox, false, time.Hour, 0)
+	steps := &stubStepHandler{}
+	coordinator := events.NewIdempotentSagaCoordinator(events.NewLocalEventPublisher(), stateStore, steps, testMetrics(), 0)
+
+	pool := events.NewSagaWorkerPool(2, 10)
+	pool.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		pool.Stop(ctx)
+	})
+
+	return &testEnv{
+		db:            db,
+		reportService: reportService,
+		stateStore:    stateStore,
+		coordinator:   coordinator,
+		pool:          pool,
+		steps:         steps,
+		reports:       NewReportHandler(reportService, coordinator, pool, testMetrics(), audit.NewLogger(db)),
+		sagas:         NewSagaHandler(coordinator, stateStore, pool, reportService, nil, time.Hour, 100, 0),
+	}
+}
+
+// router возвращает маршрутизатор, в котором запросы выполняются от имени userID
+func (e *testEnv) router(userID uint, register func(r gin.IRoutes)) *gin.Engine {
+	router := gin.New()
+	group := router.Group("/", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("role", "user")
+		c.Next()
+	})
+	register(group)
+	return router
+}
+
+// createReport сохраняет отчет пользователя в указанном статусе
+func (e *testEnv) createReport(t *testing.T, userID uint, status models.ReportStatus) *models.Report {
+	t.Helper()
+	report := &models.Report{Name: "Отчет", TemplateID: 1, UserID: userID, Status: string(status), Format: string(models.FormatCSV)}
+	if err := e.db.Create(report).Error; err != nil {
+		t.Fatalf("создание отчета: %v", err)
+	}
+	return report
+}
+
+// saveReportSaga сохраняет Saga генерации отчета в указанном статусе
+func (e *testEnv) saveReportSaga(t *testing.T, reportID uint, status events.SagaStatus) *events.Saga {
+	t.Helper()
+	saga := events.NewIdempotentReportCreationSaga(strconv.FormatUint(uint64(reportID), 10), "1", "1", map[string]interface{}{})
+	state := &events.Saga{
+		ID:     saga.ID,
+		Name:   saga.Name,
+		Status: status,
+		Steps:  saga.Steps,
+		Data:   map[string]interface{}{"user_id": "1"},
+	}
+	if status != events.SagaStatusExecuting && status != events.SagaStatusPending {
+		state.Steps[0].Status = events.SagaStepFailed
+	}
+	if err := e.stateStore.SaveSagaState(context.Background(), state); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+	return state
+}
+
+// waitForLockRelease ждет, пока Saga отчета завершится и снимет блокировку генерации
+func (e *testEnv) waitForLockRelease(t *testing.T, reportID uint) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var count int64
+		e.db.Model(&models.ReportGenerationLock{}).Where("report_id = ?", reportID).Count(&count)
+		if count == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("блокировка генерации отчета %d не снята", reportID)
+}
+
+// doJSON выполняет запрос и возвращает ответ
+func doJSON(router http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
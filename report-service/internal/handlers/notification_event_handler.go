@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"report-service/internal/apperrors"
+	"report-service/internal/events"
+	"report-service/internal/models"
+	"report-service/internal/services"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NotificationEventHandler обрабатывает события доставки уведомлений от notification-service
+type NotificationEventHandler struct {
+	eventType     events.EventType
+	reportService *services.ReportService
+	stateStore    *events.SagaStateStore
+}
+
+// NewNotificationEventHandler создает обработчик событий доставки уведомлений
+func NewNotificationEventHandler(eventType events.EventType, reportService *services.ReportService, stateStore *events.SagaStateStore) *NotificationEventHandler {
+	return &NotificationEventHandler{
+		eventType:     eventType,
+		reportService: reportService,
+		stateStore:    stateStore,
+	}
+}
+
+// EventType возвращает тип обрабатываемого события
+func (h *NotificationEventHandler) EventType() events.EventType {
+	return h.eventType
+}
+
+// Handle фиксирует статус доставки уведомления в отчете
+func (h *NotificationEventHandler) Handle(ctx context.Context, event *events.Event) error {
+	// Повторно доставленные события пропускаем
+	processed, err := h.stateStore.IsEventProcessed(ctx, event.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка проверки обработки события: %w", err)
+	}
+	if processed {
+		logrus.Infof("Событие %s уже обработано, пропускаем", event.ID)
+		return nil
+	}
+
+	status := models.NotificationStatusDelivered
+	if event.Type == events.NotificationFailed {
+		status = models.NotificationStatusFailed
+	}
+
+	reportID, err := reportIDFromEvent(event)
+	if err != nil {
+		// Повтор не поможет, поэтому событие не возвращаем в очередь
+		logrus.WithError(err).Warnf("Событие %s пропущено", event.ID)
+		return nil
+	}
+
+	if err := h.reportService.RecordNotificationDelivery(reportID, status, notificationEventTimestamp(event)); err != nil {
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) {
+			return err
+		}
+		logrus.WithError(err).Warnf("Статус уведомления для отчета %d не сохранен", reportID)
+	}
+
+	sagaID, _ := event.Data["saga_id"].(string)
+	if err := h.stateStore.LogEvent(ctx, sagaID, event.ID, event.Type); err != nil {
+		logrus.WithError(err).Warnf("Не удалось записать событие %s в журнал", event.ID)
+	}
+
+	logrus.Infof("Статус уведомления отчета %d: %s", reportID, status)
+	return nil
+}
+
+// reportIDFromEvent извлекает ID отчета из данных события
+func reportIDFromEvent(event *events.Event) (uint, error) {
+	var raw string
+	switch v := event.Data["report_id"].(type) {
+	case string:
+		raw = v
+	case float64:
+		raw = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return 0, fmt.Errorf("в событии отсутствует report_id")
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("некорректный report_id: %s", raw)
+	}
+	return uint(id), nil
+}
+
+// notificationEventTimestamp возвращает время события или текущее время
+func notificationEventTimestamp(event *events.Event) time.Time {
+	if event.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return event.Timestamp
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"report-service/internal/events"
+	"report-service/internal/models"
+)
+
+// notificationEvent событие доставки уведомления об отчете reportID
+func notificationEvent(id string, eventType events.EventType, reportID uint, timestamp time.Time) *events.Event {
+	return &events.Event{
+		ID:        id,
+		Type:      eventType,
+		Timestamp: timestamp,
+		Data: map[string]interface{}{
+			"report_id": strconv.FormatUint(uint64(reportID), 10),
+			"saga_id":   "saga-1",
+		},
+	}
+}
+
+// reloadReport читает отчет из базы
+func (e *testEnv) reloadReport(t *testing.T, id uint) *models.Report {
+	t.Helper()
+	var report models.Report
+	if err := e.db.First(&report, id).Error; err != nil {
+		t.Fatalf("получение отчета: %v", err)
+	}
+	return &report
+}
+
+func TestNotificationDeliveredEventUpdatesReport(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusCompleted)
+	handler := NewNotificationEventHandler(events.NotificationDelivered, env.reportService, env.stateStore)
+	deliveredAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := handler.Handle(context.Background(), notificationEvent("event-1", events.NotificationDelivered, report.ID, deliveredAt)); err != nil {
+		t.Fatalf("обработка события: %v", err)
+	}
+
+	updated := env.reloadReport(t, report.ID)
+	if updated.NotificationStatus != models.NotificationStatusDelivered || updated.NotifiedAt == nil || !updated.NotifiedAt.Equal(deliveredAt) {
+		t.Errorf("статус уведомления %q, notified_at %v", updated.NotificationStatus, updated.NotifiedAt)
+	}
+	processed, err := env.stateStore.IsEventProcessed(context.Background(), "event-1")
+	if err != nil || !processed {
+		t.Errorf("событие не записано в журнал: %v", err)
+	}
+
+	// Повторная доставка того же события не меняет отчет
+	env.db.Model(&models.Report{}).Where("id = ?", report.ID).Update("notification_status", "")
+	if err := handler.Handle(context.Background(), notificationEvent("event-1", events.NotificationDelivered, report.ID, deliveredAt)); err != nil {
+		t.Fatalf("повторная обработка: %v", err)
+	}
+	if status := env.reloadReport(t, report.ID).NotificationStatus; status != "" {
+		t.Errorf("повторное событие изменило статус на %q", status)
+	}
+}
+
+func TestNotificationFailedEventMarksReport(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusCompleted)
+	handler := NewNotificationEventHandler(events.NotificationFailed, env.reportService, env.stateStore)
+
+	if err := handler.Handle(context.Background(), notificationEvent("event-2", events.NotificationFailed, report.ID, time.Now())); err != nil {
+		t.Fatalf("обработка события: %v", err)
+	}
+	if status := env.reloadReport(t, report.ID).NotificationStatus; status != models.NotificationStatusFailed {
+		t.Errorf("статус уведомления %q, ожидался %q", status, models.NotificationStatusFailed)
+	}
+
+	// Событие о несуществующем отчете не возвращается в очередь
+	if err := handler.Handle(context.Background(), notificationEvent("event-3", events.NotificationFailed, 999, time.Now())); err != nil {
+		t.Errorf("событие о несуществующем отчете: %v", err)
+	}
+}
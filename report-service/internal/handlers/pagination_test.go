@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"report-service/internal/apperrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paginate разбирает page и limit из строки запроса так же, как обработчики списков
+func paginate(query string) (int, int, bool, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/reports?"+query, nil)
+	page, limit, ok := parsePagination(c)
+	return page, limit, ok, rec
+}
+
+func TestParsePaginationRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr string
+	}{
+		{"page=abc", "page"},
+		{"page=0", "page"},
+		{"page=-1", "page"},
+		{"page=1.5", "page"},
+		{"limit=abc", "limit"},
+		{"limit=0", "limit"},
+		{"limit=-10", "limit"},
+		{"page=2&limit=", "limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, _, ok, rec := paginate(tt.query)
+			if ok {
+				t.Fatal("некорректные параметры приняты")
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("статус %d, ожидался 400", rec.Code)
+			}
+			var body struct {
+				Error apperrors.AppError `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+			if body.Error.Code != apperrors.CodeValidation || !strings.Contains(body.Error.Message, tt.wantErr) {
+				t.Errorf("ошибка %+v, ожидалась ошибка параметра %s", body.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParsePaginationClampsLimitToMax(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantPage  int
+		wantLimit int
+	}{
+		{"page=3&limit=25", 3, 25},
+		{"limit=100", 1, 100},
+		{"limit=101", 1, 100},
+		{"page=2&limit=100000", 2, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			page, limit, ok, rec := paginate(tt.query)
+			if !ok {
+				t.Fatalf("параметры отклонены: %s", rec.Body.String())
+			}
+			if page != tt.wantPage || limit != tt.wantLimit {
+				t.Errorf("page %d, limit %d, ожидалось %d и %d", page, limit, tt.wantPage, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestParsePaginationUsesConfiguredDefaults(t *testing.T) {
+	defaultLimit, maxLimit := defaultPageLimit, maxPageLimit
+	t.Cleanup(func() {
+		defaultPageLimit, maxPageLimit = defaultLimit, maxLimit
+	})
+
+	if page, limit, ok, _ := paginate(""); !ok || page != 1 || limit != 10 {
+		t.Errorf("без параметров: page %d, limit %d, ожидалось 1 и 10", page, limit)
+	}
+
+	SetDefaultPageLimit(25)
+	SetMaxPageLimit(50)
+	// Неположительные значения из конфигурации игнорируются
+	SetDefaultPageLimit(0)
+	SetMaxPageLimit(-1)
+
+	tests := []struct {
+		query     string
+		wantLimit int
+	}{
+		{"", 25},
+		{"limit=40", 40},
+		{"limit=51", 50},
+	}
+	for _, tt := range tests {
+		if _, limit, ok, rec := paginate(tt.query); !ok || limit != tt.wantLimit {
+			t.Errorf("%q: limit %d, ожидалось %d (%s)", tt.query, limit, tt.wantLimit, rec.Body.String())
+		}
+	}
+}
@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"report-service/internal/apperrors"
+	"report-service/internal/audit"
 	"report-service/internal/events"
 	"report-service/internal/metrics"
 	"report-service/internal/models"
@@ -15,19 +19,29 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// auditEntityReport тип сущности отчета в журнале аудита
+const auditEntityReport = "report"
+
+// queueRetryAfter пауза, которую клиенту предлагается выждать при переполненной очереди Saga
+const queueRetryAfter = 5 * time.Second
+
 // ReportHandler обработчик для отчетов
 type ReportHandler struct {
 	reportService   *services.ReportService
 	sagaCoordinator *events.IdempotentSagaCoordinator
+	sagaPool        *events.SagaWorkerPool
 	metrics         *metrics.Metrics
+	auditLog        *audit.Logger
 }
 
 // NewReportHandler создает новый обработчик отчетов
-func NewReportHandler(reportService *services.ReportService, sagaCoordinator *events.IdempotentSagaCoordinator, metrics *metrics.Metrics) *ReportHandler {
+func NewReportHandler(reportService *services.ReportService, sagaCoordinator *events.IdempotentSagaCoordinator, sagaPool *events.SagaWorkerPool, metrics *metrics.Metrics, auditLog *audit.Logger) *ReportHandler {
 	return &ReportHandler{
 		reportService:   reportService,
 		sagaCoordinator: sagaCoordinator,
+		sagaPool:        sagaPool,
 		metrics:         metrics,
+		auditLog:        auditLog,
 	}
 }
 
@@ -37,14 +51,14 @@ func (h *ReportHandler) CreateReport(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		h.metrics.RecordBusinessOperation("report-service", "create_report", time.Since(start), false)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	var req models.ReportCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.metrics.RecordBusinessOperation("report-service", "create_report", time.Since(start), false)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
 		return
 	}
 
@@ -52,31 +66,30 @@ func (h *ReportHandler) CreateReport(c *gin.Context) {
 	report, err := h.reportService.CreateReport(userID.(uint), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка создания отчета")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
-	// Создаем идемпотентную Saga для генерации отчета
-	saga := events.NewIdempotentReportCreationSaga(
-		strconv.FormatUint(uint64(report.ID), 10),
-		strconv.FormatUint(uint64(userID.(uint)), 10),
-		strconv.FormatUint(uint64(req.TemplateID), 10),
-		map[string]interface{}{
-			"parameters":  req.Parameters,
-			"name":        req.Name,
-			"description": req.Description,
-		},
-	)
+	h.auditLog.Record(userID.(uint), audit.ActionCreate, auditEntityReport, report.ID, nil, report)
 
-	// Запускаем Saga асинхронно
-	go func() {
-		ctx := context.Background()
-		if err := saga.Execute(ctx, h.sagaCoordinator); err != nil {
-			logrus.WithError(err).Errorf("Ошибка выполнения Saga создания отчета %s", saga.ID)
-			// Обновляем статус отчета на failed
-			h.reportService.UpdateReportStatus(report.ID, string(models.StatusFailed))
-		}
-	}()
+	if err := h.reportService.AcquireGenerationLock(report.ID); err != nil {
+		h.metrics.RecordBusinessOperation("report-service", "create_report", time.Since(start), false)
+		apperrors.Respond(c, err)
+		return
+	}
+
+	// Запускаем идемпотентную Saga для генерации отчета
+	if err := h.startGenerationSaga(report.ID, userID.(uint), req.TemplateID, map[string]interface{}{
+		"parameters":            req.Parameters,
+		"name":                  req.Name,
+		"description":           req.Description,
+		"format":                report.Format,
+		events.CorrelationIDKey: report.CorrelationID,
+	}); err != nil {
+		h.metrics.RecordBusinessOperation("report-service", "create_report", time.Since(start), false)
+		apperrors.Respond(c, err)
+		return
+	}
 
 	h.metrics.RecordBusinessOperation("report-service", "create_report", time.Since(start), true)
 	c.JSON(http.StatusAccepted, models.ReportCreateResponse{
@@ -90,31 +103,39 @@ func (h *ReportHandler) CreateReport(c *gin.Context) {
 func (h *ReportHandler) GetReports(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	// Получаем параметры запроса
 	status := c.Query("status")
-	pageStr := c.DefaultQuery("page", "1")
-	limitStr := c.DefaultQuery("limit", "10")
-
-	page, err := strconv.Atoi(pageStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр page"})
+	page, limit, ok := parsePagination(c)
+	if !ok {
 		return
 	}
 
-	limit, err := strconv.Atoi(limitStr)
+	reports, err := h.reportService.GetReports(userID.(uint), status, page, limit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр limit"})
+		logrus.WithError(err).Error("Ошибка получения списка отчетов")
+		apperrors.Respond(c, err)
 		return
 	}
 
-	reports, err := h.reportService.GetReports(userID.(uint), status, page, limit)
-	if err != nil {
-		logrus.WithError(err).Error("Ошибка получения списка отчетов")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Проекция полей: ?fields=id,name,status
+	if fields := parseFields(c); fields != nil {
+		projected, err := projectFields(reports.Reports, fields)
+		if err != nil {
+			logrus.WithError(err).Error("Ошибка проекции полей отчетов")
+			apperrors.Respond(c, apperrors.Internal(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"reports": projected,
+			"total":   reports.Total,
+			"page":    reports.Page,
+			"limit":   reports.Limit,
+		})
 		return
 	}
 
@@ -125,49 +146,113 @@ func (h *ReportHandler) GetReports(c *gin.Context) {
 func (h *ReportHandler) GetReport(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID отчета"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
 		return
 	}
 
 	report, err := h.reportService.GetReport(uint(id), userID.(uint))
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка получения отчета")
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, report)
 }
 
+// GetTemplateUsage возвращает число отчетов, построенных по шаблону, и время последнего использования
+func (h *ReportHandler) GetTemplateUsage(c *gin.Context) {
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID шаблона"))
+		return
+	}
+
+	usage, err := h.reportService.GetTemplateUsage(uint(templateID))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка подсчета отчетов шаблона")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
 // GetReportStatus получение статуса отчета
 func (h *ReportHandler) GetReportStatus(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID отчета"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
 		return
 	}
 
 	report, err := h.reportService.GetReport(uint(id), userID.(uint))
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка получения отчета")
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.reportStatus(c.Request.Context(), report))
+}
+
+// maxStatusBatchSize максимальное число ID в пакетном запросе статусов
+const maxStatusBatchSize = 100
+
+// GetReportStatuses возвращает статусы и прогресс нескольких отчетов пользователя.
+// Отсутствующие и чужие ID не прерывают запрос, а перечисляются отдельно
+func (h *ReportHandler) GetReportStatuses(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
+		return
+	}
+
+	var req models.ReportStatusBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
+		return
+	}
+	if len(req.IDs) > maxStatusBatchSize {
+		apperrors.Respond(c, apperrors.Validation(fmt.Sprintf("Можно запросить не более %d отчетов", maxStatusBatchSize)))
 		return
 	}
 
+	reports, notFound, forbidden, err := h.reportService.GetReportsForStatus(req.IDs, userID.(uint))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения статусов отчетов")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	response := models.ReportStatusBatchResponse{
+		Statuses:  make([]models.ReportStatusResponse, len(reports)),
+		NotFound:  notFound,
+		Forbidden: forbidden,
+	}
+	for i := range reports {
+		response.Statuses[i] = h.reportStatus(c.Request.Context(), &reports[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// reportStatus формирует статус отчета с прогрессом, путем к файлу и причиной ошибки
+func (h *ReportHandler) reportStatus(ctx context.Context, report *models.ReportResponse) models.ReportStatusResponse {
 	response := models.ReportStatusResponse{
 		ID:     report.ID,
 		Status: report.Status,
@@ -183,37 +268,86 @@ func (h *ReportHandler) GetReportStatus(c *gin.Context) {
 		response.Progress = 50 // Примерное значение, можно сделать более точным
 	}
 
-	c.JSON(http.StatusOK, response)
+	// Для упавшего отчета показываем шаг Saga, на котором произошла ошибка
+	if report.Status == string(models.StatusFailed) {
+		failure, err := h.sagaCoordinator.GetReportSagaFailure(ctx, report.ID)
+		if err != nil {
+			logrus.WithError(err).Warnf("Не удалось получить причину ошибки отчета %d", report.ID)
+		} else {
+			response.FailedStep = failure.StepName
+			response.Error = failure.Error
+			response.RetryCount = failure.RetryCount
+		}
+	}
+
+	return response
 }
 
 // UpdateReport обновление отчета
 func (h *ReportHandler) UpdateReport(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID отчета"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
 		return
 	}
 
 	var req models.ReportUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
 		return
 	}
 
+	before, _ := h.reportService.GetReport(uint(id), userID.(uint))
 	report, err := h.reportService.UpdateReport(uint(id), userID.(uint), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка обновления отчета")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
+		return
+	}
+
+	h.auditLog.Record(userID.(uint), audit.ActionUpdate, auditEntityReport, report.ID, before, report)
+
+	c.JSON(http.StatusOK, report)
+}
+
+// UpdateReportParameters замена параметров отчета до начала генерации
+func (h *ReportHandler) UpdateReportParameters(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
+		return
+	}
+
+	var req models.ReportParametersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
 		return
 	}
 
+	before, _ := h.reportService.GetReport(uint(id), userID.(uint))
+	report, err := h.reportService.ReplaceReportParameters(uint(id), userID.(uint), req.Parameters)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка обновления параметров отчета")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	h.auditLog.Record(userID.(uint), audit.ActionUpdate, auditEntityReport, report.ID, before, report)
+
 	c.JSON(http.StatusOK, report)
 }
 
@@ -221,24 +355,27 @@ func (h *ReportHandler) UpdateReport(c *gin.Context) {
 func (h *ReportHandler) DeleteReport(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID отчета"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
 		return
 	}
 
+	before, _ := h.reportService.GetReport(uint(id), userID.(uint))
 	err = h.reportService.DeleteReport(uint(id), userID.(uint))
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка удаления отчета")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
+	h.auditLog.Record(userID.(uint), audit.ActionDelete, auditEntityReport, uint(id), before, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Отчет успешно удален"})
 }
 
@@ -246,52 +383,164 @@ func (h *ReportHandler) DeleteReport(c *gin.Context) {
 func (h *ReportHandler) GenerateReport(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID отчета"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
 		return
 	}
 
 	var req models.ReportGenerateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
 		return
 	}
 
 	report, err := h.reportService.GenerateReport(uint(id), userID.(uint), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка генерации отчета")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
+		return
+	}
+
+	if err := h.startGenerationSaga(report.ID, userID.(uint), report.TemplateID, map[string]interface{}{
+		"parameters":            req.Parameters,
+		"name":                  report.Name,
+		"description":           report.Description,
+		"version":               report.Version,
+		"format":                report.Format,
+		events.CorrelationIDKey: report.CorrelationID,
+	}); err != nil {
+		apperrors.Respond(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Генерация отчета запущена", "report": report})
 }
 
+// RetryReport повторно запускает Saga неудачного отчета, сохраняя ID отчета
+func (h *ReportHandler) RetryReport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
+		return
+	}
+
+	report, err := h.reportService.GetReport(uint(id), userID.(uint))
+	if err != nil {
+		apperrors.Respond(c, err)
+		return
+	}
+	if report.Status != string(models.StatusFailed) {
+		apperrors.Respond(c, apperrors.Conflict("повторить можно только отчет в статусе failed").WithDetails(gin.H{
+			"status": report.Status,
+		}))
+		return
+	}
+
+	saga, err := h.sagaCoordinator.FindReportSaga(c.Request.Context(), uint(id))
+	if err != nil {
+		logrus.WithError(err).Warnf("Saga отчета %d не найдена", id)
+		apperrors.Respond(c, apperrors.NotFound("Saga отчета не найдена"))
+		return
+	}
+	if !saga.Status.IsRetryable() {
+		apperrors.Respond(c, apperrors.Conflict("Saga отчета не завершилась ошибкой").WithDetails(gin.H{
+			"saga_id":        saga.ID,
+			"current_status": saga.Status,
+		}))
+		return
+	}
+
+	before := report
+	report, err = h.reportService.RetryReport(uint(id), userID.(uint))
+	if err != nil {
+		apperrors.Respond(c, err)
+		return
+	}
+
+	retrySaga := &events.IdempotentReportCreationSaga{ID: saga.ID}
+	if err := h.sagaPool.Submit(func(ctx context.Context) {
+		defer h.reportService.ReleaseGenerationLock(uint(id))
+		if err := retrySaga.RetryFailedSaga(ctx, h.sagaCoordinator); err != nil {
+			logrus.WithError(err).Errorf("Ошибка повторного выполнения Saga %s отчета %d", saga.ID, id)
+			h.reportService.UpdateReportStatus(uint(id), string(models.StatusFailed))
+		}
+	}); err != nil {
+		logrus.WithError(err).Warnf("Повтор Saga отчета %d отклонен", id)
+		h.reportService.ReleaseGenerationLock(uint(id))
+		h.reportService.UpdateReportStatus(uint(id), string(models.StatusFailed))
+		apperrors.Respond(c, apperrors.TooManyRequests("Слишком много отчетов в очереди, повторите запрос позже").WithRetryAfter(queueRetryAfter))
+		return
+	}
+
+	h.auditLog.Record(userID.(uint), audit.ActionUpdate, auditEntityReport, uint(id), before, report)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Повторная генерация отчета запущена",
+		"saga_id": saga.ID,
+		"report":  report,
+	})
+}
+
+// startGenerationSaga ставит Saga генерации отчета в очередь пула воркеров.
+// Блокировка генерации отчета должна быть захвачена заранее, она снимается по завершении Saga.
+func (h *ReportHandler) startGenerationSaga(reportID, userID, templateID uint, data map[string]interface{}) error {
+	saga := events.NewIdempotentReportCreationSaga(
+		strconv.FormatUint(uint64(reportID), 10),
+		strconv.FormatUint(uint64(userID), 10),
+		strconv.FormatUint(uint64(templateID), 10),
+		data,
+	)
+
+	err := h.sagaPool.Submit(func(ctx context.Context) {
+		defer h.reportService.ReleaseGenerationLock(reportID)
+		if err := saga.Execute(ctx, h.sagaCoordinator); err != nil {
+			logrus.WithError(err).Errorf("Ошибка выполнения Saga генерации отчета %s", saga.ID)
+			// Обновляем статус отчета на failed
+			h.reportService.UpdateReportStatus(reportID, string(models.StatusFailed))
+		}
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("Saga генерации отчета %d отклонена", reportID)
+		h.reportService.ReleaseGenerationLock(reportID)
+		h.reportService.UpdateReportStatus(reportID, string(models.StatusFailed))
+		return apperrors.TooManyRequests("Слишком много отчетов в очереди, повторите запрос позже").WithRetryAfter(queueRetryAfter)
+	}
+
+	return nil
+}
+
 // DownloadReport скачивание отчета
 func (h *ReportHandler) DownloadReport(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID отчета"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
 		return
 	}
 
 	report, err := h.reportService.DownloadReport(uint(id), userID.(uint))
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка скачивания отчета")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -308,26 +557,37 @@ func (h *ReportHandler) ExportReportCSV(c *gin.Context) {
 	// Получаем ID пользователя из контекста
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID отчета"})
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
 		return
 	}
 
-	csvData, err := h.reportService.ExportReportToCSV(uint(id), userID.(uint))
+	loc, ok := parseTimezone(c)
+	if !ok {
+		return
+	}
+
+	opts := models.ReportCSVOptions{Location: loc}
+	for _, column := range strings.Split(c.Query("columns"), ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			opts.Columns = append(opts.Columns, column)
+		}
+	}
+
+	csvData, name, err := h.reportService.ExportReportToCSV(uint(id), userID.(uint), opts)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка экспорта отчета в CSV")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, err)
 		return
 	}
 
 	// Устанавливаем заголовки для скачивания CSV файла
-	c.Header("Content-Type", "text/csv")
-	c.Header("Content-Disposition", "attachment; filename=report_"+idStr+".csv")
+	setAttachmentHeaders(c, reportFilename(name, uint(id), ".csv"))
 	c.String(http.StatusOK, csvData)
 }
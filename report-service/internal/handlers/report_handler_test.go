@@ -0,0 +1,974 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"report-service/internal/audit"
+	"report-service/internal/clients"
+	"report-service/internal/events"
+	"report-service/internal/models"
+	"report-service/internal/repository"
+	"report-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRetryReportRestartsFailedSaga(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusFailed)
+	saga := env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/reports/:id/retry", env.reports.RetryReport) })
+
+	rec := doJSON(router, http.MethodPost, fmt.Sprintf("/reports/%d/retry", report.ID), nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("статус %d, ожидался 202: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		SagaID string                `json:"saga_id"`
+		Report models.ReportResponse `json:"report"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if body.SagaID != saga.ID || body.Report.ID != report.ID {
+		t.Errorf("повтор вернул saga_id=%s report.id=%d, ожидались %s и %d", body.SagaID, body.Report.ID, saga.ID, report.ID)
+	}
+
+	env.waitForLockRelease(t, report.ID)
+
+	restarted, err := env.stateStore.GetSagaState(context.Background(), saga.ID)
+	if err != nil {
+		t.Fatalf("получение Saga: %v", err)
+	}
+	if restarted.Status != events.SagaStatusCompleted {
+		t.Errorf("Saga после повтора в статусе %s, ожидался completed", restarted.Status)
+	}
+
+	var count int64
+	env.db.Model(&models.Report{}).Count(&count)
+	if count != 1 {
+		t.Errorf("отчетов после повтора: %d, ожидался 1 — повтор не должен создавать новый отчет", count)
+	}
+}
+
+func TestRetryReportKeepsSagaIdentity(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusFailed)
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/reports/:id/retry", env.reports.RetryReport) })
+
+	// Компенсированная Saga рассылки: отчет создан, хранение файла откатано
+	batch := events.NewBatchRenderNotifySaga(strconv.FormatUint(uint64(report.ID), 10), "1", "1", map[string]interface{}{
+		events.CorrelationIDKey: "corr-2396",
+	}, []string{"a@example.com"}, 0)
+	compensatedAt := time.Now()
+	for _, step := range batch.Steps {
+		step.Status = events.SagaStepCompensated
+		step.Attempts = 3
+		step.Error = "storage недоступен"
+		step.CompensationError = "файл не удален"
+		step.CompensatedAt = &compensatedAt
+	}
+	state := &events.Saga{
+		ID:     batch.ID,
+		Name:   batch.Name,
+		Status: events.SagaStatusCompensated,
+		Steps:  batch.Steps,
+		Data: map[string]interface{}{
+			"user_id":               "1",
+			"template_id":           "1",
+			events.CorrelationIDKey: "corr-2396",
+		},
+	}
+	if err := env.stateStore.SaveSagaState(context.Background(), state); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+
+	rec := doJSON(router, http.MethodPost, fmt.Sprintf("/reports/%d/retry", report.ID), nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("статус %d, ожидался 202: %s", rec.Code, rec.Body.String())
+	}
+	env.waitForLockRelease(t, report.ID)
+
+	restarted, err := env.stateStore.GetSagaState(context.Background(), batch.ID)
+	if err != nil {
+		t.Fatalf("получение Saga: %v", err)
+	}
+	if restarted.Status != events.SagaStatusCompleted {
+		t.Errorf("Saga после повтора в статусе %s, ожидался completed", restarted.Status)
+	}
+	if restarted.Name != events.BatchRenderNotifySagaName {
+		t.Errorf("имя Saga после повтора %q, ожидалось %q", restarted.Name, events.BatchRenderNotifySagaName)
+	}
+	if got := restarted.CorrelationID(); got != "corr-2396" {
+		t.Errorf("correlation_id после повтора %q, ожидался corr-2396", got)
+	}
+	if got := restarted.Data["template_id"]; got != "1" {
+		t.Errorf("template_id после повтора %v, ожидался 1", got)
+	}
+	for _, step := range restarted.Steps {
+		if step.CompensationError != "" || step.CompensatedAt != nil || step.Error != "" || step.Attempts > 1 {
+			t.Errorf("шаг %s не сброшен: attempts=%d error=%q compensation_error=%q compensated_at=%v",
+				step.ID, step.Attempts, step.Error, step.CompensationError, step.CompensatedAt)
+		}
+	}
+}
+
+func TestRetryReportRejectsCompletedReport(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusCompleted)
+	env.saveReportSaga(t, report.ID, events.SagaStatusCompleted)
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/reports/:id/retry", env.reports.RetryReport) })
+
+	rec := doJSON(router, http.MethodPost, fmt.Sprintf("/reports/%d/retry", report.ID), nil)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("статус %d, ожидался 409: %s", rec.Code, rec.Body.String())
+	}
+	if len(env.steps.executed) != 0 {
+		t.Errorf("выполнены шаги %v, повтор не должен запускать Saga", env.steps.executed)
+	}
+}
+
+func TestReportHandlersRespondWithServiceErrors(t *testing.T) {
+	env := newTestEnv(t)
+	completed := env.createReport(t, 1, models.StatusCompleted)
+	processing := env.createReport(t, 1, models.StatusProcessing)
+	expired := env.createReport(t, 1, models.StatusExpired)
+	foreign := env.createReport(t, 2, models.StatusCompleted)
+	router := env.router(1, func(r gin.IRoutes) {
+		r.GET("/reports/:id", env.reports.GetReport)
+		r.PUT("/reports/:id", env.reports.UpdateReport)
+		r.DELETE("/reports/:id", env.reports.DeleteReport)
+		r.POST("/reports/:id/generate", env.reports.GenerateReport)
+		r.GET("/reports/:id/download", env.reports.DownloadReport)
+	})
+
+	// details — ожидаемое поле details в JSON, пустая строка означает его отсутствие
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    interface{}
+		status  int
+		code    string
+		details string
+	}{
+		{"некорректный ID", http.MethodGet, "/reports/abc", nil, http.StatusBadRequest, "validation_error", ""},
+		{"некорректный статус", http.MethodPut, fmt.Sprintf("/reports/%d", completed.ID), gin.H{"status": "archived"}, http.StatusBadRequest, "validation_error", `{"status":"archived"}`},
+		{"чужой отчет", http.MethodGet, fmt.Sprintf("/reports/%d", foreign.ID), nil, http.StatusForbidden, "forbidden", ""},
+		{"удаление чужого отчета", http.MethodDelete, fmt.Sprintf("/reports/%d", foreign.ID), nil, http.StatusForbidden, "forbidden", ""},
+		{"обновление несуществующего отчета", http.MethodPut, "/reports/999", gin.H{"name": "Новый"}, http.StatusNotFound, "not_found", ""},
+		{"недопустимая смена статуса", http.MethodPut, fmt.Sprintf("/reports/%d", completed.ID), gin.H{"status": "pending"}, http.StatusConflict, "conflict", `{"from":"completed","to":"pending"}`},
+		{"удаление несуществующего отчета", http.MethodDelete, "/reports/999", nil, http.StatusNotFound, "not_found", ""},
+		{"повторная генерация", http.MethodPost, fmt.Sprintf("/reports/%d/generate", processing.ID), gin.H{}, http.StatusConflict, "conflict", ""},
+		{"скачивание неготового отчета", http.MethodGet, fmt.Sprintf("/reports/%d/download", processing.ID), nil, http.StatusConflict, "conflict", ""},
+		{"скачивание просроченного отчета", http.MethodGet, fmt.Sprintf("/reports/%d/download", expired.ID), nil, http.StatusGone, "gone", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doJSON(router, tt.method, tt.path, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("статус %d, ожидался %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			var body struct {
+				Error struct {
+					Code    string          `json:"code"`
+					Message string          `json:"message"`
+					Details json.RawMessage `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+			if body.Error.Code != tt.code {
+				t.Errorf("код ошибки %q, ожидался %q: %s", body.Error.Code, tt.code, rec.Body.String())
+			}
+			if body.Error.Message == "" {
+				t.Errorf("пустое сообщение ошибки: %s", rec.Body.String())
+			}
+			if string(body.Error.Details) != tt.details {
+				t.Errorf("details %s, ожидалось %q", body.Error.Details, tt.details)
+			}
+		})
+	}
+}
+
+func TestExportReportCSVSelectsColumns(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusCompleted)
+	router := env.router(1, func(r gin.IRoutes) { r.GET("/reports/:id/export/csv", env.reports.ExportReportCSV) })
+	path := fmt.Sprintf("/reports/%d/export/csv", report.ID)
+
+	tests := []struct {
+		name  string
+		query string
+		want  [][]string
+	}{
+		{
+			name:  "selected columns in requested order",
+			query: "?columns=status,%20name",
+			want:  [][]string{{"Status", "Name"}, {"completed", "Отчет"}},
+		},
+		{
+			name:  "single column",
+			query: "?columns=id",
+			want:  [][]string{{"ID"}, {strconv.FormatUint(uint64(report.ID), 10)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doJSON(router, http.MethodGet, path+tt.query, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+			}
+			records, err := csv.NewReader(rec.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("разбор CSV: %v", err)
+			}
+			if !reflect.DeepEqual(records, tt.want) {
+				t.Errorf("CSV %q, ожидался %q", records, tt.want)
+			}
+		})
+	}
+
+	t.Run("all columns by default", func(t *testing.T) {
+		rec := doJSON(router, http.MethodGet, path, nil)
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil || len(records) != 2 || len(records[0]) != 12 {
+			t.Fatalf("ожидались заголовок и строка из 12 колонок, получено %q (%v)", records, err)
+		}
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		rec := doJSON(router, http.MethodGet, path+"?columns=name,password", nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("статус %d, ожидался 400: %s", rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Error struct {
+				Details map[string]string `json:"details"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		if !strings.Contains(body.Error.Details["allowed_columns"], "md5_hash") {
+			t.Errorf("ответ не содержит допустимые колонки: %s", rec.Body.String())
+		}
+	})
+}
+
+func TestForceRegenerateCompletedReport(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusCompleted)
+	if err := env.db.Model(report).Updates(map[string]interface{}{"file_path": "/tmp/report_1.csv", "file_size": 10, "md5_hash": "abc"}).Error; err != nil {
+		t.Fatalf("сохранение файла отчета: %v", err)
+	}
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/reports/:id/generate", env.reports.GenerateReport) })
+	path := fmt.Sprintf("/reports/%d/generate", report.ID)
+
+	// Без force завершенный отчет не генерируется повторно
+	if rec := doJSON(router, http.MethodPost, path, map[string]interface{}{}); rec.Code == http.StatusOK {
+		t.Fatalf("генерация завершенного отчета без force принята: %s", rec.Body.String())
+	}
+
+	rec := doJSON(router, http.MethodPost, path, map[string]interface{}{"force": true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Report models.ReportResponse `json:"report"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if body.Report.Status != string(models.StatusProcessing) || body.Report.Version != 2 {
+		t.Errorf("отчет после перезапуска: статус %s, версия %d, ожидались processing и 2", body.Report.Status, body.Report.Version)
+	}
+	if body.Report.FilePath != "" {
+		t.Errorf("файл прежней версии остался в отчете: %s", body.Report.FilePath)
+	}
+
+	env.waitForLockRelease(t, report.ID)
+	if len(env.steps.executed) == 0 {
+		t.Error("Saga генерации не запущена")
+	}
+}
+
+func TestForceRegenerateRejectsConcurrentRuns(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusCompleted)
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/reports/:id/generate", env.reports.GenerateReport) })
+	path := fmt.Sprintf("/reports/%d/generate", report.ID)
+
+	// Saga первого запроса ждет, пока все конкурирующие запросы получат ответ
+	release := make(chan struct{})
+	env.steps.fail = func(step *events.SagaStep) error {
+		<-release
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := doJSON(router, http.MethodPost, path, map[string]interface{}{"force": true})
+			mu.Lock()
+			defer mu.Unlock()
+			switch rec.Code {
+			case http.StatusOK:
+				accepted++
+			case http.StatusConflict:
+			default:
+				t.Errorf("статус %d, ожидался 200 или 409: %s", rec.Code, rec.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1 {
+		t.Errorf("принято перезапусков: %d, ожидался 1", accepted)
+	}
+
+	// Пока генерация выполняется, повторный перезапуск отклоняется
+	if rec := doJSON(router, http.MethodPost, path, map[string]interface{}{"force": true}); rec.Code != http.StatusConflict {
+		t.Errorf("перезапуск во время генерации: статус %d, ожидался 409", rec.Code)
+	}
+	close(release)
+	env.waitForLockRelease(t, report.ID)
+
+	var stored models.Report
+	env.db.First(&stored, report.ID)
+	if stored.Version != 2 {
+		t.Errorf("версия отчета %d, ожидалась 2 — перезапуск должен выполниться один раз", stored.Version)
+	}
+}
+
+func TestForceRegenerateFollowsStatusMachine(t *testing.T) {
+	tests := []struct {
+		status   models.ReportStatus
+		accepted bool
+	}{
+		{models.StatusFailed, true},
+		{models.StatusCancelled, false},
+		{models.StatusExpired, false},
+		{models.StatusPending, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			env := newTestEnv(t)
+			report := env.createReport(t, 1, tt.status)
+			router := env.router(1, func(r gin.IRoutes) { r.POST("/reports/:id/generate", env.reports.GenerateReport) })
+
+			rec := doJSON(router, http.MethodPost, fmt.Sprintf("/reports/%d/generate", report.ID), map[string]interface{}{"force": true})
+			if tt.accepted {
+				if rec.Code != http.StatusOK {
+					t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+				}
+				env.waitForLockRelease(t, report.ID)
+				return
+			}
+
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("статус %d, ожидался 409: %s", rec.Code, rec.Body.String())
+			}
+			var body struct {
+				Error struct {
+					Code    string            `json:"code"`
+					Details map[string]string `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+			if body.Error.Code != "conflict" || body.Error.Details["from"] != string(tt.status) || body.Error.Details["to"] != string(models.StatusProcessing) {
+				t.Errorf("ошибка %+v, ожидался conflict с переходом %s -> processing", body.Error, tt.status)
+			}
+
+			var stored models.Report
+			env.db.First(&stored, report.ID)
+			if stored.Status != string(tt.status) || stored.Version != report.Version {
+				t.Errorf("отчет изменен: статус %s, версия %d", stored.Status, stored.Version)
+			}
+			if len(env.steps.executed) != 0 {
+				t.Error("Saga генерации запущена для отчета в конечном статусе")
+			}
+		})
+	}
+}
+
+// assertRetryAfter проверяет паузу перед повтором в заголовке Retry-After и в теле ошибки
+func assertRetryAfter(t *testing.T, rec *httptest.ResponseRecorder, seconds int) {
+	t.Helper()
+	if got := rec.Header().Get("Retry-After"); got != strconv.Itoa(seconds) {
+		t.Errorf("Retry-After %q, ожидалось %d", got, seconds)
+	}
+	var body struct {
+		Error struct {
+			Code       string `json:"code"`
+			RetryAfter int    `json:"retry_after_seconds"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if body.Error.Code != "too_many_requests" || body.Error.RetryAfter != seconds {
+		t.Errorf("ошибка %+v, ожидались too_many_requests и retry_after_seconds %d", body.Error, seconds)
+	}
+}
+
+func TestGenerateReportRejectedWhenQueueIsFull(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusPending)
+
+	// Пул без воркеров и очереди не принимает ни одной Saga
+	fullPool := events.NewSagaWorkerPool(1, 0)
+	reports := NewReportHandler(env.reportService, env.coordinator, fullPool, testMetrics(), audit.NewLogger(env.db))
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/reports/:id/generate", reports.GenerateReport) })
+
+	rec := doJSON(router, http.MethodPost, fmt.Sprintf("/reports/%d/generate", report.ID), map[string]interface{}{})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("статус %d, ожидался 429: %s", rec.Code, rec.Body.String())
+	}
+	assertRetryAfter(t, rec, 5)
+
+	// Отклоненная генерация не оставляет блокировку и не выполняет шаги
+	var locks int64
+	env.db.Model(&models.ReportGenerationLock{}).Where("report_id = ?", report.ID).Count(&locks)
+	if locks != 0 {
+		t.Error("блокировка генерации не снята после отказа")
+	}
+	if len(env.steps.executed) != 0 {
+		t.Errorf("выполнены шаги %v", env.steps.executed)
+	}
+}
+
+func TestForeignReportAccessPolicy(t *testing.T) {
+	for _, hide := range []bool{true, false} {
+		t.Run(fmt.Sprintf("hide=%v", hide), func(t *testing.T) {
+			env := newTestEnv(t)
+			reportService := services.NewReportService(repository.NewReportRepository(env.db), repository.NewReportGenerationLockRepository(env.db), events.NewOutboxManager(env.db), hide, time.Hour, 0)
+			reports := NewReportHandler(reportService, env.coordinator, env.pool, testMetrics(), audit.NewLogger(env.db))
+			router := env.router(1, func(r gin.IRoutes) {
+				r.GET("/reports/:id", reports.GetReport)
+				r.PUT("/reports/:id", reports.UpdateReport)
+				r.DELETE("/reports/:id", reports.DeleteReport)
+				r.GET("/reports/:id/download", reports.DownloadReport)
+			})
+
+			owned := env.createReport(t, 1, models.StatusCompleted)
+			foreign := env.createReport(t, 2, models.StatusCompleted)
+			if rec := doJSON(router, http.MethodGet, fmt.Sprintf("/reports/%d", owned.ID), nil); rec.Code != http.StatusOK {
+				t.Fatalf("свой отчет: статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+			}
+
+			foreignStatus := http.StatusForbidden
+			if hide {
+				foreignStatus = http.StatusNotFound
+			}
+			requests := []struct {
+				method string
+				path   string
+				body   interface{}
+			}{
+				{http.MethodGet, "/reports/%d", nil},
+				{http.MethodPut, "/reports/%d", gin.H{"name": "Чужой"}},
+				{http.MethodGet, "/reports/%d/download", nil},
+				{http.MethodDelete, "/reports/%d", nil},
+			}
+			for _, req := range requests {
+				foreignRec := doJSON(router, req.method, fmt.Sprintf(req.path, foreign.ID), req.body)
+				if foreignRec.Code != foreignStatus {
+					t.Errorf("%s чужого отчета: статус %d, ожидался %d", req.method+" "+req.path, foreignRec.Code, foreignStatus)
+				}
+				missingRec := doJSON(router, req.method, fmt.Sprintf(req.path, 999), req.body)
+				if missingRec.Code != http.StatusNotFound {
+					t.Errorf("%s несуществующего отчета: статус %d, ожидался 404", req.method+" "+req.path, missingRec.Code)
+				}
+				// Скрытый чужой отчет неотличим от несуществующего
+				if hide && foreignRec.Body.String() != missingRec.Body.String() {
+					t.Errorf("%s: ответ на чужой отчет %s отличается от ответа на несуществующий %s", req.method+" "+req.path, foreignRec.Body.String(), missingRec.Body.String())
+				}
+			}
+
+			var stored models.Report
+			if err := env.db.First(&stored, foreign.ID).Error; err != nil || stored.Name != foreign.Name {
+				t.Errorf("чужой отчет изменен или удален: %+v (%v)", stored, err)
+			}
+		})
+	}
+}
+
+func TestGetReportsProjectsRequestedFields(t *testing.T) {
+	env := newTestEnv(t)
+	first := env.createReport(t, 1, models.StatusCompleted)
+	second := env.createReport(t, 1, models.StatusPending)
+	router := env.router(1, func(r gin.IRoutes) {
+		r.GET("/reports", env.reports.GetReports)
+	})
+
+	get := func(t *testing.T, query string) map[string]json.RawMessage {
+		t.Helper()
+		rec := doJSON(router, http.MethodGet, "/reports?"+query, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return body
+	}
+	items := func(t *testing.T, body map[string]json.RawMessage) []map[string]interface{} {
+		t.Helper()
+		var reports []map[string]interface{}
+		if err := json.Unmarshal(body["reports"], &reports); err != nil {
+			t.Fatalf("разбор отчетов: %v", err)
+		}
+		return reports
+	}
+	keys := func(item map[string]interface{}) []string {
+		names := make([]string, 0, len(item))
+		for name := range item {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	t.Run("только запрошенные поля", func(t *testing.T) {
+		// Пробелы вокруг имен допустимы, неизвестные поля игнорируются
+		body := get(t, "fields=id,%20status%20,unknown")
+		reports := items(t, body)
+		if len(reports) != 2 {
+			t.Fatalf("отчетов %d, ожидалось 2", len(reports))
+		}
+		for _, report := range reports {
+			if got := keys(report); !reflect.DeepEqual(got, []string{"id", "status"}) {
+				t.Errorf("поля отчета %v, ожидались [id status]", got)
+			}
+		}
+		ids := map[float64]string{}
+		for _, report := range reports {
+			ids[report["id"].(float64)] = report["status"].(string)
+		}
+		if ids[float64(first.ID)] != string(models.StatusCompleted) || ids[float64(second.ID)] != string(models.StatusPending) {
+			t.Errorf("отчеты %v", reports)
+		}
+		// Поля страницы не проецируются
+		if string(body["total"]) != "2" || string(body["page"]) != "1" || body["limit"] == nil {
+			t.Errorf("страница: total %s, page %s, limit %s", body["total"], body["page"], body["limit"])
+		}
+	})
+
+	t.Run("только неизвестные поля", func(t *testing.T) {
+		reports := items(t, get(t, "fields=unknown"))
+		if len(reports) != 2 {
+			t.Fatalf("отчетов %d, ожидалось 2", len(reports))
+		}
+		for _, report := range reports {
+			if len(report) != 0 {
+				t.Errorf("отчет %v, ожидался пустой объект", report)
+			}
+		}
+	})
+
+	t.Run("пустой список полей", func(t *testing.T) {
+		for _, report := range items(t, get(t, "fields=%20,%20")) {
+			if report["name"] == nil || report["user_id"] == nil || report["created_at"] == nil {
+				t.Errorf("без проекции ожидались все поля: %v", report)
+			}
+		}
+	})
+}
+
+func TestUpdateReportParametersOnlyWhilePending(t *testing.T) {
+	env := newTestEnv(t)
+	pending := env.createReport(t, 1, models.StatusPending)
+	completed := env.createReport(t, 1, models.StatusCompleted)
+	env.db.Model(completed).Update("parameters", `{"month":"2024-04"}`)
+	router := env.router(1, func(r gin.IRoutes) {
+		r.PUT("/reports/:id/parameters", env.reports.UpdateReportParameters)
+	})
+	stored := func(id uint) string {
+		var report models.Report
+		env.db.First(&report, id)
+		return report.Parameters
+	}
+	path := func(id uint) string { return fmt.Sprintf("/reports/%d/parameters", id) }
+
+	t.Run("отчет в очереди", func(t *testing.T) {
+		rec := doJSON(router, http.MethodPut, path(pending.ID), gin.H{"parameters": gin.H{"month": "2024-05", "region": "north"}})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+		var report models.ReportResponse
+		json.Unmarshal(rec.Body.Bytes(), &report)
+		if report.Parameters != `{"month":"2024-05","region":"north"}` || stored(pending.ID) != report.Parameters {
+			t.Errorf("параметры в ответе %s, в базе %s", report.Parameters, stored(pending.ID))
+		}
+
+		var audits int64
+		env.db.Model(&audit.AuditLog{}).Where("entity_id = ? AND action = ?", pending.ID, audit.ActionUpdate).Count(&audits)
+		if audits != 1 {
+			t.Errorf("записей аудита %d, ожидалась 1", audits)
+		}
+
+		// Пустой объект очищает параметры
+		if rec := doJSON(router, http.MethodPut, path(pending.ID), gin.H{"parameters": gin.H{}}); rec.Code != http.StatusOK || stored(pending.ID) != "" {
+			t.Errorf("очистка: статус %d, параметры %q", rec.Code, stored(pending.ID))
+		}
+	})
+
+	t.Run("готовый отчет", func(t *testing.T) {
+		rec := doJSON(router, http.MethodPut, path(completed.ID), gin.H{"parameters": gin.H{"month": "2024-05"}})
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("статус %d, ожидался 409: %s", rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Error struct {
+				Code    string            `json:"code"`
+				Details map[string]string `json:"details"`
+			} `json:"error"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if body.Error.Code != "conflict" || body.Error.Details["status"] != string(models.StatusCompleted) {
+			t.Errorf("ошибка %+v", body.Error)
+		}
+		if stored(completed.ID) != `{"month":"2024-04"}` {
+			t.Errorf("параметры готового отчета изменены: %s", stored(completed.ID))
+		}
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"без параметров", path(pending.ID), gin.H{}, http.StatusBadRequest},
+		{"параметры не объект", path(pending.ID), gin.H{"parameters": "month=2024-05"}, http.StatusBadRequest},
+		{"несуществующий отчет", path(999), gin.H{"parameters": gin.H{}}, http.StatusNotFound},
+		{"чужой отчет", path(env.createReport(t, 2, models.StatusPending).ID), gin.H{"parameters": gin.H{}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := doJSON(router, http.MethodPut, tt.path, tt.body); rec.Code != tt.status {
+				t.Errorf("статус %d, ожидался %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetReportStatusShowsFailedSagaStep(t *testing.T) {
+	env := newTestEnv(t)
+	router := env.router(1, func(r gin.IRoutes) { r.GET("/reports/:id/status", env.reports.GetReportStatus) })
+	ctx := context.Background()
+
+	status := func(t *testing.T, reportID uint) models.ReportStatusResponse {
+		t.Helper()
+		rec := doJSON(router, http.MethodGet, fmt.Sprintf("/reports/%d/status", reportID), nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+		}
+		var body models.ReportStatusResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return body
+	}
+
+	t.Run("ошибка шага", func(t *testing.T) {
+		report := env.createReport(t, 1, models.StatusFailed)
+		saga := env.saveReportSaga(t, report.ID, events.SagaStatusExecuting)
+		saga.Status = events.SagaStatusFailed
+		saga.Error = "Saga прервана"
+		saga.Steps[0].Status = events.SagaStepCompleted
+		saga.Steps[1].Status = events.SagaStepFailed
+		saga.Steps[1].Error = "шаблон 1 не найден"
+		if err := env.stateStore.SaveSagaState(ctx, saga); err != nil {
+			t.Fatalf("сохранение Saga: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := env.stateStore.IncrementRetryCount(ctx, saga.ID); err != nil {
+				t.Fatalf("увеличение счетчика повторов: %v", err)
+			}
+		}
+
+		body := status(t, report.ID)
+		if body.Status != string(models.StatusFailed) || body.FailedStep != "Validate Template" ||
+			body.Error != "шаблон 1 не найден" || body.RetryCount != 2 {
+			t.Errorf("ответ %+v", body)
+		}
+	})
+
+	t.Run("ошибка Saga без ошибки шага", func(t *testing.T) {
+		report := env.createReport(t, 1, models.StatusFailed)
+		saga := env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+		saga.Error = "таймаут Saga"
+		if err := env.stateStore.SaveSagaState(ctx, saga); err != nil {
+			t.Fatalf("сохранение Saga: %v", err)
+		}
+
+		body := status(t, report.ID)
+		if body.FailedStep != saga.Steps[0].Name || body.Error != "таймаут Saga" || body.RetryCount != 0 {
+			t.Errorf("ответ %+v", body)
+		}
+	})
+
+	t.Run("без Saga", func(t *testing.T) {
+		report := env.createReport(t, 1, models.StatusFailed)
+		if body := status(t, report.ID); body.FailedStep != "" || body.RetryCount != 0 {
+			t.Errorf("ответ %+v", body)
+		}
+	})
+
+	t.Run("завершенный отчет", func(t *testing.T) {
+		report := env.createReport(t, 1, models.StatusCompleted)
+		env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+		if body := status(t, report.ID); body.FailedStep != "" || body.Error != "" {
+			t.Errorf("ответ %+v", body)
+		}
+	})
+}
+
+func TestGetReportStatusesSplitsOwnedForeignAndMissing(t *testing.T) {
+	for _, hide := range []bool{false, true} {
+		t.Run(fmt.Sprintf("hide=%v", hide), func(t *testing.T) {
+			env := newTestEnv(t)
+			reportService := services.NewReportService(repository.NewReportRepository(env.db), repository.NewReportGenerationLockRepository(env.db), events.NewOutboxManager(env.db), hide, time.Hour, 0)
+			reports := NewReportHandler(reportService, env.coordinator, env.pool, testMetrics(), audit.NewLogger(env.db))
+			router := env.router(1, func(r gin.IRoutes) { r.POST("/reports/status/batch", reports.GetReportStatuses) })
+
+			completed := env.createReport(t, 1, models.StatusCompleted)
+			env.db.Model(completed).Update("file_path", "/files/report.csv")
+			processing := env.createReport(t, 1, models.StatusProcessing)
+			failed := env.createReport(t, 1, models.StatusFailed)
+			env.saveReportSaga(t, failed.ID, events.SagaStatusFailed)
+			foreign := env.createReport(t, 2, models.StatusCompleted)
+
+			// Повторный ID учитывается один раз, порядок статусов совпадает с запросом
+			ids := []uint{processing.ID, foreign.ID, 999, completed.ID, failed.ID, processing.ID}
+			rec := doJSON(router, http.MethodPost, "/reports/status/batch", gin.H{"ids": ids})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+			}
+			var body models.ReportStatusBatchResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+
+			wantStatuses := []models.ReportStatusResponse{
+				{ID: processing.ID, Status: string(models.StatusProcessing), Progress: 50},
+				{ID: completed.ID, Status: string(models.StatusCompleted), FilePath: "/files/report.csv"},
+				{ID: failed.ID, Status: string(models.StatusFailed), FailedStep: "Validate User"},
+			}
+			if !reflect.DeepEqual(body.Statuses, wantStatuses) {
+				t.Errorf("статусы %+v, ожидалось %+v", body.Statuses, wantStatuses)
+			}
+
+			// Скрытый чужой отчет неотличим от несуществующего
+			wantNotFound, wantForbidden := []uint{999}, []uint{foreign.ID}
+			if hide {
+				wantNotFound, wantForbidden = []uint{foreign.ID, 999}, nil
+			}
+			if !reflect.DeepEqual(body.NotFound, wantNotFound) || !reflect.DeepEqual(body.Forbidden, wantForbidden) {
+				t.Errorf("not_found %v, forbidden %v, ожидалось %v и %v", body.NotFound, body.Forbidden, wantNotFound, wantForbidden)
+			}
+		})
+	}
+}
+
+func TestGetReportStatusesValidatesRequest(t *testing.T) {
+	env := newTestEnv(t)
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/reports/status/batch", env.reports.GetReportStatuses) })
+
+	tooMany := make([]uint, maxStatusBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = uint(i + 1)
+	}
+	invalid := []struct {
+		name string
+		body gin.H
+	}{
+		{"без ids", gin.H{}},
+		{"пустой список", gin.H{"ids": []uint{}}},
+		{"больше максимума", gin.H{"ids": tooMany}},
+		{"не числа", gin.H{"ids": []string{"1"}}},
+	}
+	for _, tt := range invalid {
+		rec := doJSON(router, http.MethodPost, "/reports/status/batch", tt.body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"validation_error"`) {
+			t.Errorf("%s: статус %d: %s", tt.name, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Все ID отсутствуют — пустой список статусов, а не ошибка
+	rec := doJSON(router, http.MethodPost, "/reports/status/batch", gin.H{"ids": []uint{998, 999}})
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"statuses":[],"not_found":[998,999]}` {
+		t.Errorf("статус %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// expirationStorage storage-service для удаления просроченных отчетов: файлы ищутся по
+// correlation_id, удаление файла failFileID завершается ошибкой
+type expirationStorage struct {
+	mu         sync.Mutex
+	files      map[string][]uint
+	deleted    []string
+	failFileID string
+}
+
+func (s *expirationStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/files/":
+		files := []clients.FileInfo{}
+		for _, id := range s.files[r.URL.Query().Get("correlation_id")] {
+			files = append(files, clients.FileInfo{ID: id})
+		}
+		json.NewEncoder(w).Encode(gin.H{"files": files})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/files/hash/"):
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodDelete:
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/files/")
+		if id == s.failFileID {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.deleted = append(s.deleted, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestReportExpirationLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	reportService := services.NewReportService(repository.NewReportRepository(env.db), repository.NewReportGenerationLockRepository(env.db), events.NewOutboxManager(env.db), false, time.Hour, 2*time.Hour)
+	reports := NewReportHandler(reportService, env.coordinator, env.pool, testMetrics(), audit.NewLogger(env.db))
+	router := env.router(1, func(r gin.IRoutes) { r.GET("/reports/:id/download", reports.DownloadReport) })
+
+	// create создает отчет и доводит его генерацию до completed
+	create := func(t *testing.T, name string, ttlSeconds int64) *models.Report {
+		t.Helper()
+		created, err := reportService.CreateReport(1, &models.ReportCreateRequest{Name: name, TemplateID: 1, Format: "csv", TTLSeconds: ttlSeconds})
+		if err != nil {
+			t.Fatalf("создание отчета %s: %v", name, err)
+		}
+		for _, status := range []models.ReportStatus{models.StatusProcessing, models.StatusCompleted} {
+			if err := reportService.UpdateReportStatus(created.ID, string(status)); err != nil {
+				t.Fatalf("статус %s: %v", status, err)
+			}
+		}
+		env.db.Model(&models.Report{}).Where("id = ?", created.ID).Update("file_path", "/files/"+name+".csv")
+		var report models.Report
+		env.db.First(&report, created.ID)
+		return &report
+	}
+
+	start := time.Now()
+	short := create(t, "short", 60)
+	byDefault := create(t, "default", 0)
+	failing := create(t, "failing", 60)
+
+	// Срок отсчитывается от завершения генерации; без ttl_seconds берется REPORT_TTL
+	if short.TTLSeconds != 60 || short.ExpiresAt == nil || short.ExpiresAt.Sub(start) < time.Minute || short.ExpiresAt.Sub(start) > time.Minute+5*time.Second {
+		t.Errorf("ttl_seconds %d, expires_at %v, ожидался срок через минуту", short.TTLSeconds, short.ExpiresAt)
+	}
+	if byDefault.TTLSeconds != 7200 || byDefault.ExpiresAt == nil || byDefault.ExpiresAt.Sub(start) < 2*time.Hour {
+		t.Errorf("ttl_seconds %d, expires_at %v, ожидался срок REPORT_TTL", byDefault.TTLSeconds, byDefault.ExpiresAt)
+	}
+
+	storage := &expirationStorage{
+		files: map[string][]uint{
+			short.CorrelationID:   {7, 8},
+			failing.CorrelationID: {9},
+		},
+		failFileID: "9",
+	}
+	server := httptest.NewServer(storage)
+	t.Cleanup(server.Close)
+	job := services.NewReportExpirationJob(reportService, clients.NewStorageClient(server.URL, clients.RetryPolicy{Attempts: 1}))
+	ctx := context.Background()
+
+	if expired := job.ExpireReports(ctx, start.Add(30*time.Second)); expired != 0 || len(storage.deleted) != 0 {
+		t.Fatalf("до истечения срока: просрочено %d, удалены файлы %v", expired, storage.deleted)
+	}
+	if rec := doJSON(router, http.MethodGet, fmt.Sprintf("/reports/%d/download", short.ID), nil); rec.Code != http.StatusOK {
+		t.Fatalf("скачивание до истечения срока: статус %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Отчет, файл которого не удалось удалить, остается completed до следующего запуска
+	if expired := job.ExpireReports(ctx, start.Add(2*time.Minute)); expired != 1 {
+		t.Errorf("просрочено отчетов %d, ожидался 1", expired)
+	}
+	if !reflect.DeepEqual(storage.deleted, []string{"7", "8"}) {
+		t.Errorf("удалены файлы %v, ожидались 7 и 8", storage.deleted)
+	}
+
+	statuses := map[uint]models.ReportStatus{short.ID: models.StatusExpired, byDefault.ID: models.StatusCompleted, failing.ID: models.StatusCompleted}
+	for id, want := range statuses {
+		var stored models.Report
+		env.db.First(&stored, id)
+		if stored.Status != string(want) {
+			t.Errorf("отчет %d в статусе %s, ожидался %s", id, stored.Status, want)
+		}
+		if want == models.StatusExpired && (stored.FilePath != "" || stored.FileSize != 0) {
+			t.Errorf("у просроченного отчета остались сведения о файле: %q, %d", stored.FilePath, stored.FileSize)
+		}
+	}
+
+	rec := doJSON(router, http.MethodGet, fmt.Sprintf("/reports/%d/download", short.ID), nil)
+	if rec.Code != http.StatusGone || !strings.Contains(rec.Body.String(), `"gone"`) {
+		t.Errorf("скачивание просроченного отчета: статус %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doJSON(router, http.MethodGet, fmt.Sprintf("/reports/%d/download", failing.ID), nil); rec.Code != http.StatusOK {
+		t.Errorf("скачивание отчета с неудаленным файлом: статус %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestReportWithoutTTLNeverExpires(t *testing.T) {
+	env := newTestEnv(t)
+
+	created, err := env.reportService.CreateReport(1, &models.ReportCreateRequest{Name: "Бессрочный", TemplateID: 1, Format: "csv"})
+	if err != nil {
+		t.Fatalf("создание отчета: %v", err)
+	}
+	for _, status := range []models.ReportStatus{models.StatusProcessing, models.StatusCompleted} {
+		if err := env.reportService.UpdateReportStatus(created.ID, string(status)); err != nil {
+			t.Fatalf("статус %s: %v", status, err)
+		}
+	}
+
+	job := services.NewReportExpirationJob(env.reportService, clients.NewStorageClient("http://127.0.0.1:0", clients.RetryPolicy{Attempts: 1}))
+	if expired := job.ExpireReports(context.Background(), time.Now().Add(24*365*time.Hour)); expired != 0 {
+		t.Errorf("просрочено отчетов %d, ожидалось 0", expired)
+	}
+
+	var stored models.Report
+	env.db.First(&stored, created.ID)
+	if stored.Status != string(models.StatusCompleted) || stored.TTLSeconds != 0 || stored.ExpiresAt != nil {
+		t.Errorf("отчет %s, ttl_seconds %d, expires_at %v", stored.Status, stored.TTLSeconds, stored.ExpiresAt)
+	}
+}
@@ -1,28 +1,52 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"report-service/internal/apperrors"
 	"report-service/internal/events"
+	"report-service/internal/models"
+	"report-service/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // SagaHandler обработчик для Saga операций
 type SagaHandler struct {
 	sagaCoordinator *events.IdempotentSagaCoordinator
 	stateStore      *events.SagaStateStore
+	sagaPool        *events.SagaWorkerPool
+	reportService   *services.ReportService
+	stepHandler     *SagaStepHandler
+
+	retention        time.Duration
+	cleanupBatchSize int
+	streamInterval   time.Duration
 }
 
 // NewSagaHandler создает новый обработчик Saga
-func NewSagaHandler(sagaCoordinator *events.IdempotentSagaCoordinator, stateStore *events.SagaStateStore) *SagaHandler {
+func NewSagaHandler(sagaCoordinator *events.IdempotentSagaCoordinator, stateStore *events.SagaStateStore, sagaPool *events.SagaWorkerPool, reportService *services.ReportService, stepHandler *SagaStepHandler, retention time.Duration, cleanupBatchSize int, streamInterval time.Duration) *SagaHandler {
+	if streamInterval <= 0 {
+		streamInterval = 500 * time.Millisecond
+	}
 	return &SagaHandler{
-		sagaCoordinator: sagaCoordinator,
-		stateStore:      stateStore,
+		sagaCoordinator:  sagaCoordinator,
+		stateStore:       stateStore,
+		sagaPool:         sagaPool,
+		reportService:    reportService,
+		stepHandler:      stepHandler,
+		retention:        retention,
+		cleanupBatchSize: cleanupBatchSize,
+		streamInterval:   streamInterval,
 	}
 }
 
@@ -32,17 +56,39 @@ type CreateReportSagaRequest struct {
 	Parameters map[string]interface{} `json:"parameters"`
 }
 
+// validateSagaReportFormat проверяет формат файла из параметров Saga до ее запуска,
+// чтобы неподдерживаемый формат давал 400, а не проваленную Saga
+func validateSagaReportFormat(parameters map[string]interface{}) error {
+	value, ok := parameters["format"]
+	if !ok {
+		return nil
+	}
+	format, ok := value.(string)
+	if !ok {
+		return apperrors.Validation("format должен быть строкой")
+	}
+	if normalized := models.ReportFormat(strings.ToLower(strings.TrimSpace(format))); normalized != "" && !normalized.IsValid() {
+		return apperrors.Validation("Неподдерживаемый формат отчета: " + format)
+	}
+	return nil
+}
+
 // CreateReportSaga создает новую Saga для создания отчета
 func (h *SagaHandler) CreateReportSaga(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	var req CreateReportSagaRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
+		return
+	}
+
+	if err := validateSagaReportFormat(req.Parameters); err != nil {
+		apperrors.Respond(c, err)
 		return
 	}
 
@@ -57,7 +103,7 @@ func (h *SagaHandler) CreateReportSaga(c *gin.Context) {
 	// Создаем обычную Saga для сохранения в базе данных
 	saga := &events.Saga{
 		ID:        idempotentSaga.ID,
-		Name:      "Idempotent Report Creation Saga",
+		Name:      events.ReportCreationSagaName,
 		Status:    events.SagaStatusPending,
 		Steps:     idempotentSaga.Steps,
 		Data:      map[string]interface{}{"template_id": req.TemplateID, "parameters": req.Parameters},
@@ -65,13 +111,15 @@ func (h *SagaHandler) CreateReportSaga(c *gin.Context) {
 		UpdatedAt: time.Now(),
 	}
 
-	// Запускаем выполнение Saga асинхронно
-	go func() {
-		backgroundCtx := context.Background()
-		if err := idempotentSaga.Execute(backgroundCtx, h.sagaCoordinator); err != nil {
+	// Ставим выполнение Saga в очередь пула воркеров; блокировку генерации захватит
+	// шаг generate-report, создав отчет
+	if !h.submitSaga(c, saga.ID, func(ctx context.Context) {
+		if err := idempotentSaga.Execute(ctx, h.sagaCoordinator); err != nil {
 			logrus.WithError(err).Errorf("Ошибка выполнения Saga %s", saga.ID)
 		}
-	}()
+	}) {
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
 		"message": "Saga создания отчета запущена",
@@ -80,17 +128,77 @@ func (h *SagaHandler) CreateReportSaga(c *gin.Context) {
 	})
 }
 
+// BatchRenderNotifySagaRequest запрос на генерацию отчета с рассылкой нескольким получателям
+type BatchRenderNotifySagaRequest struct {
+	TemplateID string                 `json:"template_id" binding:"required"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Recipients []string               `json:"recipients" binding:"required,min=1,max=100,dive,required"`
+	// ChannelID email канал notification-service, через который файл отчета отправляется вложением
+	ChannelID uint `json:"channel_id"`
+}
+
+// CreateBatchRenderNotifySaga запускает Saga, которая генерирует отчет и отправляет
+// его каждому получателю отдельным шагом
+func (h *SagaHandler) CreateBatchRenderNotifySaga(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
+		return
+	}
+
+	var req BatchRenderNotifySagaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
+		return
+	}
+
+	if err := validateSagaReportFormat(req.Parameters); err != nil {
+		apperrors.Respond(c, err)
+		return
+	}
+
+	saga := events.NewBatchRenderNotifySaga(
+		"0", // reportID будет создан позже
+		strconv.FormatUint(uint64(userID.(uint)), 10),
+		req.TemplateID,
+		req.Parameters,
+		req.Recipients,
+		req.ChannelID,
+	)
+
+	// Число шагов растет с числом получателей: Saga сверх SAGA_MAX_STEPS отклоняется до ответа 202
+	if err := h.sagaCoordinator.ValidateSteps(saga.Steps); err != nil {
+		apperrors.Respond(c, apperrors.Validation(err.Error()))
+		return
+	}
+
+	if !h.submitSaga(c, saga.ID, func(ctx context.Context) {
+		if err := saga.Execute(ctx, h.sagaCoordinator); err != nil {
+			logrus.WithError(err).Errorf("Ошибка выполнения Saga %s", saga.ID)
+		}
+	}) {
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Saga генерации и рассылки отчета запущена",
+		"saga_id":    saga.ID,
+		"status":     "started",
+		"recipients": len(req.Recipients),
+	})
+}
+
 // GetSagaStatus получает статус Saga
 func (h *SagaHandler) GetSagaStatus(c *gin.Context) {
 	_, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	sagaID := c.Param("id")
 	if sagaID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID Saga не указан"})
+		apperrors.Respond(c, apperrors.Validation("ID Saga не указан"))
 		return
 	}
 
@@ -98,18 +206,27 @@ func (h *SagaHandler) GetSagaStatus(c *gin.Context) {
 	saga, err := h.sagaCoordinator.GetSaga(c.Request.Context(), sagaID)
 	if err != nil {
 		logrus.WithError(err).Errorf("Ошибка получения Saga %s", sagaID)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Saga не найдена"})
+		apperrors.Respond(c, apperrors.NotFound("Saga не найдена"))
 		return
 	}
 
+	// Ошибки компенсации по шагам, чтобы частично компенсированная Saga не выглядела чистой
+	compensationErrors := make(map[string]string)
+	for _, step := range saga.Steps {
+		if step.CompensationError != "" {
+			compensationErrors[step.ID] = step.CompensationError
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"saga_id":      saga.ID,
-		"status":       saga.Status,
-		"steps":        saga.Steps,
-		"created_at":   saga.CreatedAt,
-		"updated_at":   saga.UpdatedAt,
-		"completed_at": saga.CompletedAt,
-		"error":        saga.Error,
+		"saga_id":             saga.ID,
+		"status":              saga.Status,
+		"steps":               saga.Steps,
+		"created_at":          saga.CreatedAt,
+		"updated_at":          saga.UpdatedAt,
+		"completed_at":        saga.CompletedAt,
+		"error":               saga.Error,
+		"compensation_errors": compensationErrors,
 	})
 }
 
@@ -117,13 +234,13 @@ func (h *SagaHandler) GetSagaStatus(c *gin.Context) {
 func (h *SagaHandler) GetSagaProgress(c *gin.Context) {
 	_, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	sagaID := c.Param("id")
 	if sagaID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID Saga не указан"})
+		apperrors.Respond(c, apperrors.Validation("ID Saga не указан"))
 		return
 	}
 
@@ -133,24 +250,202 @@ func (h *SagaHandler) GetSagaProgress(c *gin.Context) {
 	progress, err := tempSaga.GetSagaProgress(c.Request.Context(), h.sagaCoordinator)
 	if err != nil {
 		logrus.WithError(err).Errorf("Ошибка получения прогресса Saga %s", sagaID)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Saga не найдена"})
+		apperrors.Respond(c, apperrors.NotFound("Saga не найдена"))
 		return
 	}
 
 	c.JSON(http.StatusOK, progress)
 }
 
+// StreamSagaProgress отправляет прогресс Saga как Server-Sent Events. Состояние опрашивается
+// с интервалом streamInterval; событие progress отправляется при каждом изменении прогресса,
+// а по достижении Saga конечного статуса отправляется событие complete и поток закрывается.
+func (h *SagaHandler) StreamSagaProgress(c *gin.Context) {
+	saga, ok := h.loadOwnedSaga(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Отключает буферизацию ответа в nginx ingress
+	c.Header("X-Accel-Buffering", "no")
+
+	ctx := c.Request.Context()
+	tracked := &events.IdempotentReportCreationSaga{ID: saga.ID}
+	ticker := time.NewTicker(h.streamInterval)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		progress, err := tracked.GetSagaProgress(ctx, h.sagaCoordinator)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logrus.WithError(err).Errorf("Ошибка получения прогресса Saga %s", saga.ID)
+			c.SSEvent("error", gin.H{"error": "Не удалось получить прогресс Saga"})
+			c.Writer.Flush()
+			return
+		}
+
+		payload, err := json.Marshal(progress)
+		if err != nil {
+			logrus.WithError(err).Errorf("Ошибка сериализации прогресса Saga %s", saga.ID)
+			return
+		}
+		if progress.Status.IsTerminal() {
+			c.SSEvent("complete", string(payload))
+			c.Writer.Flush()
+			return
+		}
+		if !bytes.Equal(payload, last) {
+			c.SSEvent("progress", string(payload))
+			c.Writer.Flush()
+			last = payload
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetSagaStep получает состояние отдельного шага Saga
+func (h *SagaHandler) GetSagaStep(c *gin.Context) {
+	saga, ok := h.loadOwnedSaga(c)
+	if !ok {
+		return
+	}
+
+	step := saga.FindStep(c.Param("stepId"))
+	if step == nil {
+		apperrors.Respond(c, apperrors.NotFound("Шаг Saga не найден"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"saga_id": saga.ID,
+		"step":    step,
+	})
+}
+
+// RetrySagaStep повторяет неудачный шаг Saga и продолжает выполнение с него
+func (h *SagaHandler) RetrySagaStep(c *gin.Context) {
+	saga, ok := h.loadOwnedSaga(c)
+	if !ok {
+		return
+	}
+
+	stepID := c.Param("stepId")
+	step := saga.FindStep(stepID)
+	if step == nil {
+		apperrors.Respond(c, apperrors.NotFound("Шаг Saga не найден"))
+		return
+	}
+
+	if !h.lockSaga(c, saga) {
+		return
+	}
+
+	if err := h.sagaCoordinator.ResetFailedStep(c.Request.Context(), saga.ID, stepID); err != nil {
+		events.ReleaseSagaReport(c.Request.Context(), h.reportService, h.stateStore, saga.ID)
+		if errors.Is(err, events.ErrStepNotFailed) {
+			apperrors.Respond(c, apperrors.Conflict("Шаг Saga не в статусе Failed").WithDetails(gin.H{
+				"current_status": step.Status,
+			}))
+			return
+		}
+		logrus.WithError(err).Errorf("Ошибка сброса шага %s Saga %s", stepID, saga.ID)
+		apperrors.Respond(c, apperrors.Internal(err))
+		return
+	}
+
+	if !h.submitSaga(c, saga.ID, func(ctx context.Context) {
+		if err := h.sagaCoordinator.ResumeSaga(ctx, saga.ID, stepID); err != nil {
+			logrus.WithError(err).Errorf("Ошибка повторного выполнения шага %s Saga %s", stepID, saga.ID)
+		}
+	}) {
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Повторное выполнение шага запущено",
+		"saga_id": saga.ID,
+		"step_id": stepID,
+		"status":  "retrying",
+	})
+}
+
+// lockSaga захватывает блокировку генерации отчета Saga; если генерация отчета уже
+// выполняется, отвечает 409
+func (h *SagaHandler) lockSaga(c *gin.Context, saga *events.Saga) bool {
+	if err := events.LockSagaReport(h.reportService, saga); err != nil {
+		apperrors.Respond(c, err)
+		return false
+	}
+	return true
+}
+
+// submitSaga ставит выполнение Saga в очередь пула воркеров. После выполнения run
+// блокировка генерации отчета Saga снимается; если очередь заполнена, блокировка
+// снимается сразу и клиент получает 429
+func (h *SagaHandler) submitSaga(c *gin.Context, sagaID string, run events.SagaTask) bool {
+	err := h.sagaPool.Submit(func(ctx context.Context) {
+		defer events.ReleaseSagaReport(ctx, h.reportService, h.stateStore, sagaID)
+		run(ctx)
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("Saga %s отклонена", sagaID)
+		events.ReleaseSagaReport(c.Request.Context(), h.reportService, h.stateStore, sagaID)
+		apperrors.Respond(c, apperrors.TooManyRequests("Слишком много Saga в очереди, повторите запрос позже").WithRetryAfter(queueRetryAfter))
+		return false
+	}
+	return true
+}
+
+// loadOwnedSaga загружает Saga и проверяет, что она принадлежит текущему пользователю
+func (h *SagaHandler) loadOwnedSaga(c *gin.Context) (*events.Saga, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
+		return nil, false
+	}
+
+	sagaID := c.Param("id")
+	if sagaID == "" {
+		apperrors.Respond(c, apperrors.Validation("ID Saga не указан"))
+		return nil, false
+	}
+
+	saga, err := h.sagaCoordinator.GetSaga(c.Request.Context(), sagaID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Ошибка получения Saga %s", sagaID)
+		apperrors.Respond(c, apperrors.NotFound("Saga не найдена"))
+		return nil, false
+	}
+
+	if saga.OwnerID() != strconv.FormatUint(uint64(userID.(uint)), 10) {
+		apperrors.Respond(c, apperrors.Forbidden("Доступ к Saga запрещен"))
+		return nil, false
+	}
+
+	return saga, true
+}
+
 // RetrySaga повторяет выполнение неудачной Saga
 func (h *SagaHandler) RetrySaga(c *gin.Context) {
 	_, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	sagaID := c.Param("id")
 	if sagaID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID Saga не указан"})
+		apperrors.Respond(c, apperrors.Validation("ID Saga не указан"))
 		return
 	}
 
@@ -158,29 +453,33 @@ func (h *SagaHandler) RetrySaga(c *gin.Context) {
 	saga, err := h.sagaCoordinator.GetSaga(c.Request.Context(), sagaID)
 	if err != nil {
 		logrus.WithError(err).Errorf("Ошибка получения Saga %s", sagaID)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Saga не найдена"})
+		apperrors.Respond(c, apperrors.NotFound("Saga не найдена"))
 		return
 	}
 
-	// Проверяем, что Saga действительно неудачная
-	if saga.Status != events.SagaStatusFailed {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":          "Saga не в статусе Failed",
+	// Повторить можно только неудачную или компенсированную Saga, как и при повторе отчета
+	if !saga.Status.IsRetryable() {
+		apperrors.Respond(c, apperrors.Conflict("Saga нельзя повторить в текущем статусе").WithDetails(gin.H{
 			"current_status": saga.Status,
-		})
+		}))
+		return
+	}
+
+	if !h.lockSaga(c, saga) {
 		return
 	}
 
 	// Создаем временную Saga для повторного выполнения
 	tempSaga := &events.IdempotentReportCreationSaga{ID: sagaID}
 
-	// Запускаем повторное выполнение асинхронно
-	go func() {
-		ctx := c.Request.Context()
+	// Ставим повторное выполнение в очередь пула воркеров
+	if !h.submitSaga(c, sagaID, func(ctx context.Context) {
 		if err := tempSaga.RetryFailedSaga(ctx, h.sagaCoordinator); err != nil {
 			logrus.WithError(err).Errorf("Ошибка повторного выполнения Saga %s", sagaID)
 		}
-	}()
+	}) {
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
 		"message": "Повторное выполнение Saga запущено",
@@ -193,13 +492,13 @@ func (h *SagaHandler) RetrySaga(c *gin.Context) {
 func (h *SagaHandler) CancelSaga(c *gin.Context) {
 	_, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	sagaID := c.Param("id")
 	if sagaID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID Saga не указан"})
+		apperrors.Respond(c, apperrors.Validation("ID Saga не указан"))
 		return
 	}
 
@@ -207,23 +506,22 @@ func (h *SagaHandler) CancelSaga(c *gin.Context) {
 	saga, err := h.sagaCoordinator.GetSaga(c.Request.Context(), sagaID)
 	if err != nil {
 		logrus.WithError(err).Errorf("Ошибка получения Saga %s", sagaID)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Saga не найдена"})
+		apperrors.Respond(c, apperrors.NotFound("Saga не найдена"))
 		return
 	}
 
 	// Проверяем, что Saga можно отменить
 	if saga.Status == events.SagaStatusCompleted {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":          "Нельзя отменить завершенную Saga",
+		apperrors.Respond(c, apperrors.Conflict("Нельзя отменить завершенную Saga").WithDetails(gin.H{
 			"current_status": saga.Status,
-		})
+		}))
 		return
 	}
 
 	// Обновляем статус Saga на Failed для запуска компенсации
 	if err := h.sagaCoordinator.UpdateSagaStatus(c.Request.Context(), sagaID, events.SagaStatusFailed); err != nil {
 		logrus.WithError(err).Errorf("Ошибка отмены Saga %s", sagaID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка отмены Saga"})
+		apperrors.Respond(c, apperrors.Internal(err))
 		return
 	}
 
@@ -238,24 +536,14 @@ func (h *SagaHandler) CancelSaga(c *gin.Context) {
 func (h *SagaHandler) ListSagas(c *gin.Context) {
 	_, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
 		return
 	}
 
 	// Получаем параметры запроса
 	_ = c.Query("status")
-	pageStr := c.DefaultQuery("page", "1")
-	limitStr := c.DefaultQuery("limit", "10")
-
-	page, err := strconv.Atoi(pageStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр page"})
-		return
-	}
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр limit"})
+	page, limit, ok := parsePagination(c)
+	if !ok {
 		return
 	}
 
@@ -271,18 +559,52 @@ func (h *SagaHandler) ListSagas(c *gin.Context) {
 	})
 }
 
+// GetCapabilities возвращает пары сервис/действие, поддерживаемые шагами Saga
+func (h *SagaHandler) GetCapabilities(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SagaCapabilitiesResponse{Capabilities: h.stepHandler.Capabilities()})
+}
+
+// CleanupSagas удаляет завершенные и компенсированные Saga старше срока хранения вместе с журналом событий.
+// Параметр older_than_days переопределяет срок хранения из конфигурации.
+func (h *SagaHandler) CleanupSagas(c *gin.Context) {
+	retention := h.retention
+	if daysStr := c.Query("older_than_days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 {
+			apperrors.Respond(c, apperrors.Validation("older_than_days должен быть положительным числом"))
+			return
+		}
+		retention = time.Duration(days) * 24 * time.Hour
+	}
+
+	olderThan := time.Now().Add(-retention)
+	sagas, eventLogs, err := h.stateStore.DeleteFinishedSagas(c.Request.Context(), olderThan, h.cleanupBatchSize)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка очистки старых Saga")
+		apperrors.Respond(c, apperrors.Internal(err))
+		return
+	}
+
+	logrus.Infof("Удалено Saga: %d, записей журнала событий: %d", sagas, eventLogs)
+	c.JSON(http.StatusOK, models.SagaCleanupResponse{
+		DeletedSagas:     sagas,
+		DeletedEventLogs: eventLogs,
+		OlderThan:        olderThan,
+	})
+}
+
 // ForceCompleteSaga принудительно завершает Saga
 func (h *SagaHandler) ForceCompleteSaga(c *gin.Context) {
 	sagaID := c.Param("id")
 	if sagaID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Saga ID required"})
+		apperrors.Respond(c, apperrors.Validation("Saga ID required"))
 		return
 	}
 
 	ctx := c.Request.Context()
 	if err := h.sagaCoordinator.ForceCompleteSaga(ctx, sagaID); err != nil {
 		logrus.WithError(err).Errorf("Ошибка принудительного завершения Saga %s", sagaID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка завершения Saga"})
+		apperrors.Respond(c, apperrors.Internal(err))
 		return
 	}
 
@@ -292,3 +614,68 @@ func (h *SagaHandler) ForceCompleteSaga(c *gin.Context) {
 		"status":  "completed",
 	})
 }
+
+// SagaExportResponse полная выгрузка Saga для отладки
+type SagaExportResponse struct {
+	SagaState  *events.SagaState      `json:"saga_state"`
+	Steps      []*events.SagaStep     `json:"steps"`
+	Data       map[string]interface{} `json:"data"`
+	EventLog   []events.EventLog      `json:"event_log"`
+	Report     *models.ReportResponse `json:"report,omitempty"`
+	ExportedAt time.Time              `json:"exported_at"`
+}
+
+// ExportSaga выгружает состояние Saga, журнал событий и связанный отчет одним JSON-документом
+func (h *SagaHandler) ExportSaga(c *gin.Context) {
+	sagaID := c.Param("id")
+	if sagaID == "" {
+		apperrors.Respond(c, apperrors.Validation("ID Saga не указан"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	state, err := h.stateStore.GetSagaStateRecord(ctx, sagaID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apperrors.Respond(c, apperrors.NotFound("Saga не найдена"))
+			return
+		}
+		logrus.WithError(err).Errorf("Ошибка получения Saga %s", sagaID)
+		apperrors.Respond(c, apperrors.Internal(err))
+		return
+	}
+
+	saga, err := h.stateStore.GetSagaState(ctx, sagaID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Ошибка разбора состояния Saga %s", sagaID)
+		apperrors.Respond(c, apperrors.Internal(err))
+		return
+	}
+
+	eventLog, err := h.stateStore.GetEventLogs(ctx, sagaID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Ошибка получения журнала событий Saga %s", sagaID)
+		apperrors.Respond(c, apperrors.Internal(err))
+		return
+	}
+
+	export := SagaExportResponse{
+		SagaState:  state,
+		Steps:      saga.Steps,
+		Data:       saga.Data,
+		EventLog:   eventLog,
+		ExportedAt: time.Now(),
+	}
+
+	// Связанный отчет добавляется, если его удалось найти; отсутствие не ошибка
+	if reportID := saga.ReportID(); reportID != 0 {
+		report, err := h.reportService.GetReportByID(reportID)
+		if err != nil {
+			logrus.WithError(err).Warnf("Отчет %d для выгрузки Saga %s не получен", reportID, sagaID)
+		} else {
+			export.Report = report
+		}
+	}
+
+	c.JSON(http.StatusOK, export)
+}
@@ -0,0 +1,633 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"report-service/internal/audit"
+	"report-service/internal/events"
+	"report-service/internal/middleware"
+	"report-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConcurrentRetriesStartSingleGeneration(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusFailed)
+	saga := env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+	router := env.router(1, func(r gin.IRoutes) {
+		r.POST("/sagas/:id/retry", env.sagas.RetrySaga)
+		r.POST("/sagas/:id/steps/:stepId/retry", env.sagas.RetrySagaStep)
+		r.POST("/reports/:id/retry", env.reports.RetryReport)
+	})
+
+	// Первый шаг запущенной Saga ждет, пока все конкурирующие запросы получат ответ
+	release := make(chan struct{})
+	env.steps.fail = func(step *events.SagaStep) error {
+		<-release
+		return nil
+	}
+
+	paths := []string{
+		"/sagas/" + saga.ID + "/retry",
+		"/sagas/" + saga.ID + "/steps/" + saga.Steps[0].ID + "/retry",
+		fmt.Sprintf("/reports/%d/retry", report.ID),
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	for i := 0; i < 9; i++ {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			rec := doJSON(router, http.MethodPost, path, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			switch rec.Code {
+			case http.StatusAccepted:
+				accepted++
+			case http.StatusConflict:
+			default:
+				t.Errorf("%s: статус %d, ожидался 202 или 409: %s", path, rec.Code, rec.Body.String())
+			}
+		}(paths[i%len(paths)])
+	}
+	wg.Wait()
+	close(release)
+
+	if accepted != 1 {
+		t.Errorf("принято повторов: %d, ожидался 1 — генерация отчета должна запускаться один раз", accepted)
+	}
+
+	env.waitForLockRelease(t, report.ID)
+}
+
+func TestRetrySagaRejectedWhileReportIsGenerating(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusFailed)
+	saga := env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/sagas/:id/retry", env.sagas.RetrySaga) })
+
+	if err := env.reportService.AcquireGenerationLock(report.ID); err != nil {
+		t.Fatalf("захват блокировки: %v", err)
+	}
+
+	rec := doJSON(router, http.MethodPost, "/sagas/"+saga.ID+"/retry", nil)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("статус %d, ожидался 409: %s", rec.Code, rec.Body.String())
+	}
+	if len(env.steps.executed) != 0 {
+		t.Errorf("выполнены шаги %v, Saga не должна запускаться под чужой блокировкой", env.steps.executed)
+	}
+
+	env.reportService.ReleaseGenerationLock(report.ID)
+}
+
+func TestRetrySagaAcceptsRetryableStatuses(t *testing.T) {
+	tests := []struct {
+		status events.SagaStatus
+		want   int
+	}{
+		{events.SagaStatusFailed, http.StatusAccepted},
+		{events.SagaStatusCompensated, http.StatusAccepted},
+		{events.SagaStatusCompensationFailed, http.StatusConflict},
+		{events.SagaStatusCompleted, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			env := newTestEnv(t)
+			report := env.createReport(t, 1, models.StatusFailed)
+			saga := env.saveReportSaga(t, report.ID, tt.status)
+			router := env.router(1, func(r gin.IRoutes) { r.POST("/sagas/:id/retry", env.sagas.RetrySaga) })
+
+			rec := doJSON(router, http.MethodPost, "/sagas/"+saga.ID+"/retry", nil)
+			if rec.Code != tt.want {
+				t.Fatalf("статус %d, ожидался %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			// Повторы через /sagas и /reports принимают одни и те же статусы
+			if retryable := rec.Code == http.StatusAccepted; retryable != tt.status.IsRetryable() {
+				t.Errorf("RetrySaga принимает %s: %v, IsRetryable: %v", tt.status, retryable, tt.status.IsRetryable())
+			}
+			if rec.Code == http.StatusAccepted {
+				env.waitForLockRelease(t, report.ID)
+			}
+		})
+	}
+}
+
+func TestGetSagaStatusShowsCompensationErrors(t *testing.T) {
+	env := newTestEnv(t)
+	env.steps.failCompensation = func(step *events.SagaStep) error {
+		if step.ID == "store-file" {
+			return errors.New("storage-service недоступен")
+		}
+		return nil
+	}
+
+	report := env.createReport(t, 1, models.StatusFailed)
+	saga := env.saveReportSaga(t, report.ID, events.SagaStatusExecuting)
+	for _, step := range saga.Steps {
+		step.Status = events.SagaStepCompleted
+	}
+	if err := env.stateStore.SaveSagaState(context.Background(), saga); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+	if err := env.coordinator.CompensateSaga(context.Background(), saga.ID, "ошибка уведомления"); err == nil {
+		t.Fatal("ожидалась ошибка компенсации")
+	}
+
+	router := env.router(1, func(r gin.IRoutes) { r.GET("/sagas/:id", env.sagas.GetSagaStatus) })
+	rec := doJSON(router, http.MethodGet, "/sagas/"+saga.ID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Status             events.SagaStatus  `json:"status"`
+		Steps              []*events.SagaStep `json:"steps"`
+		CompensationErrors map[string]string  `json:"compensation_errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if body.Status != events.SagaStatusCompensationFailed {
+		t.Errorf("статус Saga %s, ожидался %s", body.Status, events.SagaStatusCompensationFailed)
+	}
+	if len(body.CompensationErrors) != 1 || !strings.Contains(body.CompensationErrors["store-file"], "storage-service недоступен") {
+		t.Errorf("compensation_errors %v", body.CompensationErrors)
+	}
+	for _, step := range body.Steps {
+		if step.ID != "store-file" && (step.Status != events.SagaStepCompensated || step.CompensatedAt == nil) {
+			t.Errorf("шаг %s: статус %s, compensated_at %v", step.ID, step.Status, step.CompensatedAt)
+		}
+	}
+}
+
+func TestBatchRenderNotifyRejectsTooManySteps(t *testing.T) {
+	env := newTestEnv(t)
+	// Saga из 8 шагов и трех получателей превышает максимум в 10 шагов
+	coordinator := events.NewIdempotentSagaCoordinator(events.NewLocalEventPublisher(), env.stateStore, env.steps, testMetrics(), 10)
+	sagas := NewSagaHandler(coordinator, env.stateStore, env.pool, env.reportService, nil, time.Hour, 100, 0)
+	router := env.router(1, func(r gin.IRoutes) {
+		r.POST("/sagas/reports/batch-notify", sagas.CreateBatchRenderNotifySaga)
+	})
+
+	rec := doJSON(router, http.MethodPost, "/sagas/reports/batch-notify", map[string]interface{}{
+		"template_id": "1",
+		"recipients":  []string{"a@example.com", "b@example.com", "c@example.com"},
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("статус %d, ожидался 400: %s", rec.Code, rec.Body.String())
+	}
+
+	var count int64
+	env.db.Model(&events.SagaState{}).Count(&count)
+	if count != 0 {
+		t.Errorf("сохранено Saga: %d, ожидалось 0", count)
+	}
+	if executed := len(env.steps.executed); executed != 0 {
+		t.Errorf("выполнено шагов: %d", executed)
+	}
+}
+
+func TestCreateReportSagaRejectsUnsupportedFormat(t *testing.T) {
+	env := newTestEnv(t)
+	router := env.router(1, func(r gin.IRoutes) {
+		r.POST("/sagas/reports", env.sagas.CreateReportSaga)
+		r.POST("/sagas/reports/batch-notify", env.sagas.CreateBatchRenderNotifySaga)
+	})
+
+	requests := map[string]map[string]interface{}{
+		"/sagas/reports": {"template_id": "1", "parameters": map[string]interface{}{"format": "pdf"}},
+		"/sagas/reports/batch-notify": {
+			"template_id": "1",
+			"parameters":  map[string]interface{}{"format": "docx"},
+			"recipients":  []string{"a@example.com"},
+		},
+	}
+	for path, body := range requests {
+		if rec := doJSON(router, http.MethodPost, path, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: статус %d, ожидался 400: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	var count int64
+	env.db.Model(&events.SagaState{}).Count(&count)
+	if count != 0 {
+		t.Errorf("сохранено Saga: %d, ожидалось 0", count)
+	}
+}
+
+// readSSE читает из потока одно событие Server-Sent Events и возвращает его имя и данные
+func readSSE(t *testing.T, reader *bufio.Reader) (name, data string) {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("чтение потока: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			return name, data
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+}
+
+func TestStreamSagaProgressUntilCompletion(t *testing.T) {
+	env := newTestEnv(t)
+	sagas := NewSagaHandler(env.coordinator, env.stateStore, env.pool, env.reportService, nil, time.Hour, 100, 10*time.Millisecond)
+	server := httptest.NewServer(env.router(1, func(r gin.IRoutes) {
+		r.GET("/sagas/:id/stream", sagas.StreamSagaProgress)
+	}))
+	defer server.Close()
+
+	report := env.createReport(t, 1, models.StatusProcessing)
+	saga := env.saveReportSaga(t, report.ID, events.SagaStatusExecuting)
+
+	resp, err := http.Get(server.URL + "/sagas/" + saga.ID + "/stream")
+	if err != nil {
+		t.Fatalf("подключение к потоку: %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/event-stream") {
+		t.Fatalf("Content-Type %q, ожидался text/event-stream", got)
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	progress := func(data string) events.SagaProgress {
+		t.Helper()
+		var p events.SagaProgress
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			t.Fatalf("разбор события %q: %v", data, err)
+		}
+		return p
+	}
+
+	name, data := readSSE(t, reader)
+	if name != "progress" || progress(data).CompletedSteps != 0 {
+		t.Fatalf("первое событие %s %s, ожидался progress без выполненных шагов", name, data)
+	}
+
+	// Выполненный шаг порождает новое событие progress
+	saga.Steps[0].Status = events.SagaStepCompleted
+	if err := env.stateStore.SaveSagaState(context.Background(), saga); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+	name, data = readSSE(t, reader)
+	if name != "progress" || progress(data).CompletedSteps != 1 {
+		t.Fatalf("событие %s %s, ожидался progress с одним выполненным шагом", name, data)
+	}
+
+	// Конечный статус завершает поток событием complete
+	if err := env.stateStore.UpdateSagaStatus(context.Background(), saga.ID, events.SagaStatusCompleted); err != nil {
+		t.Fatalf("обновление статуса Saga: %v", err)
+	}
+	name, data = readSSE(t, reader)
+	if name != "complete" || progress(data).Status != events.SagaStatusCompleted {
+		t.Fatalf("событие %s %s, ожидался complete", name, data)
+	}
+	if _, err := reader.ReadString('\n'); err != io.EOF {
+		t.Errorf("поток не закрыт после complete: %v", err)
+	}
+}
+
+func TestGetSagaStep(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusFailed)
+	saga := env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+	step := saga.Steps[0]
+
+	tests := []struct {
+		name   string
+		userID uint
+		path   string
+		want   int
+	}{
+		{"существующий шаг", 1, "/sagas/" + saga.ID + "/steps/" + step.ID, http.StatusOK},
+		{"несуществующий шаг", 1, "/sagas/" + saga.ID + "/steps/unknown", http.StatusNotFound},
+		{"несуществующая Saga", 1, "/sagas/unknown/steps/" + step.ID, http.StatusNotFound},
+		{"чужая Saga", 2, "/sagas/" + saga.ID + "/steps/" + step.ID, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := env.router(tt.userID, func(r gin.IRoutes) { r.GET("/sagas/:id/steps/:stepId", env.sagas.GetSagaStep) })
+			rec := doJSON(router, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.want {
+				t.Fatalf("статус %d, ожидался %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				SagaID string          `json:"saga_id"`
+				Step   events.SagaStep `json:"step"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+			if body.SagaID != saga.ID || body.Step.ID != step.ID || body.Step.Status != events.SagaStepFailed {
+				t.Errorf("получен шаг %s Saga %s в статусе %s", body.Step.ID, body.SagaID, body.Step.Status)
+			}
+		})
+	}
+}
+
+func TestRetrySagaStepResumesFailedStep(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusFailed)
+	saga := env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+	saga.Steps[0].Error = "user-service недоступен"
+	if err := env.stateStore.SaveSagaState(context.Background(), saga); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/sagas/:id/steps/:stepId/retry", env.sagas.RetrySagaStep) })
+
+	stepID := saga.Steps[0].ID
+	rec := doJSON(router, http.MethodPost, "/sagas/"+saga.ID+"/steps/"+stepID+"/retry", nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("статус %d, ожидался 202: %s", rec.Code, rec.Body.String())
+	}
+	env.waitForLockRelease(t, report.ID)
+
+	retried, err := env.stateStore.GetSagaState(context.Background(), saga.ID)
+	if err != nil {
+		t.Fatalf("получение Saga: %v", err)
+	}
+	if retried.Status != events.SagaStatusCompleted {
+		t.Errorf("Saga после повтора шага в статусе %s, ожидался completed", retried.Status)
+	}
+	step := retried.FindStep(stepID)
+	if step.Status != events.SagaStepCompleted || step.Error != "" {
+		t.Errorf("шаг после повтора: статус %s, ошибка %q", step.Status, step.Error)
+	}
+	// Выполнение продолжается с повторенного шага до конца Saga
+	if len(env.steps.executed) != len(saga.Steps) || env.steps.executed[0] != stepID {
+		t.Errorf("выполнены шаги %v", env.steps.executed)
+	}
+}
+
+func TestRetrySagaStepRejectsCompletedStep(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusFailed)
+	saga := env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+	saga.Steps[0].Status = events.SagaStepCompleted
+	saga.Steps[1].Status = events.SagaStepFailed
+	if err := env.stateStore.SaveSagaState(context.Background(), saga); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/sagas/:id/steps/:stepId/retry", env.sagas.RetrySagaStep) })
+
+	rec := doJSON(router, http.MethodPost, "/sagas/"+saga.ID+"/steps/"+saga.Steps[0].ID+"/retry", nil)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("статус %d, ожидался 409: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), string(events.SagaStepCompleted)) {
+		t.Errorf("в ответе нет текущего статуса шага: %s", rec.Body.String())
+	}
+	if len(env.steps.executed) != 0 {
+		t.Errorf("выполнены шаги %v, повтор должен быть отклонен", env.steps.executed)
+	}
+
+	// Отклоненный повтор снимает блокировку: повтор неудачного шага принимается
+	rec = doJSON(router, http.MethodPost, "/sagas/"+saga.ID+"/steps/"+saga.Steps[1].ID+"/retry", nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("повтор неудачного шага: статус %d, ожидался 202: %s", rec.Code, rec.Body.String())
+	}
+	env.waitForLockRelease(t, report.ID)
+}
+
+func TestCleanupSagasPurgesOldFinishedSagas(t *testing.T) {
+	env := newTestEnv(t)
+	// Маленькая пачка проверяет удаление в несколько проходов
+	sagas := NewSagaHandler(env.coordinator, env.stateStore, env.pool, env.reportService, nil, 24*time.Hour, 2, 0)
+	router := env.router(1, func(r gin.IRoutes) { r.POST("/admin/sagas/cleanup", sagas.CleanupSagas) })
+	ctx := context.Background()
+
+	seed := func(id string, status events.SagaStatus, age time.Duration) {
+		t.Helper()
+		if err := env.stateStore.SaveSagaState(ctx, &events.Saga{ID: id, Name: "test", Status: status, Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("сохранение Saga: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := env.stateStore.LogEvent(ctx, id, fmt.Sprintf("%s-event-%d", id, i), events.SagaStarted); err != nil {
+				t.Fatalf("запись журнала: %v", err)
+			}
+		}
+		if err := env.db.Model(&events.SagaState{}).Where("id = ?", id).UpdateColumn("updated_at", time.Now().Add(-age)).Error; err != nil {
+			t.Fatalf("сдвиг времени Saga: %v", err)
+		}
+	}
+	old := 48 * time.Hour
+	seed("old-completed-1", events.SagaStatusCompleted, old)
+	seed("old-completed-2", events.SagaStatusCompleted, old)
+	seed("old-completed-3", events.SagaStatusCompleted, old)
+	seed("old-compensated", events.SagaStatusCompensated, old)
+	seed("recent-completed", events.SagaStatusCompleted, time.Hour)
+	seed("old-executing", events.SagaStatusExecuting, old)
+	seed("old-failed", events.SagaStatusFailed, old)
+
+	rec := doJSON(router, http.MethodPost, "/admin/sagas/cleanup", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+	var body models.SagaCleanupResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if body.DeletedSagas != 4 || body.DeletedEventLogs != 8 {
+		t.Errorf("удалено Saga %d и записей журнала %d, ожидалось 4 и 8", body.DeletedSagas, body.DeletedEventLogs)
+	}
+
+	var remaining []string
+	env.db.Model(&events.SagaState{}).Order("id").Pluck("id", &remaining)
+	if strings.Join(remaining, ",") != "old-executing,old-failed,recent-completed" {
+		t.Errorf("остались Saga %v", remaining)
+	}
+	var logs int64
+	env.db.Model(&events.EventLog{}).Count(&logs)
+	if logs != 6 {
+		t.Errorf("осталось записей журнала %d, ожидалось 6", logs)
+	}
+
+	// Срок хранения из запроса заменяет настроенный
+	rec = doJSON(router, http.MethodPost, "/admin/sagas/cleanup?older_than_days=1", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.DeletedSagas != 0 {
+		t.Errorf("повторная очистка удалила %d Saga (%v)", body.DeletedSagas, err)
+	}
+	for _, days := range []string{"0", "-1", "abc"} {
+		if rec := doJSON(router, http.MethodPost, "/admin/sagas/cleanup?older_than_days="+days, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("older_than_days=%s: статус %d, ожидался 400", days, rec.Code)
+		}
+	}
+}
+
+func TestExportSagaIncludesStepsEventLogAndReport(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusFailed)
+	saga := env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+	ctx := context.Background()
+	for _, eventType := range []events.EventType{events.SagaStarted, events.SagaFailed} {
+		if err := env.stateStore.LogEvent(ctx, saga.ID, saga.ID+"-"+string(eventType), eventType); err != nil {
+			t.Fatalf("запись журнала: %v", err)
+		}
+	}
+
+	routerFor := func(role string) *gin.Engine {
+		router := gin.New()
+		router.GET("/sagas/:id/export", func(c *gin.Context) {
+			c.Set("user_id", uint(1))
+			c.Set("role", role)
+		}, middleware.Role("admin"), env.sagas.ExportSaga)
+		return router
+	}
+
+	rec := doJSON(routerFor("admin"), http.MethodGet, "/sagas/"+saga.ID+"/export", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+	var export SagaExportResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &export); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if export.SagaState == nil || export.SagaState.ID != saga.ID {
+		t.Fatalf("в выгрузке нет состояния Saga: %s", rec.Body.String())
+	}
+	if len(export.Steps) != len(saga.Steps) || export.Steps[0].ID != saga.Steps[0].ID || export.Steps[0].Status != events.SagaStepFailed {
+		t.Errorf("в выгрузке шаги %+v", export.Steps)
+	}
+	if len(export.EventLog) != 2 {
+		t.Errorf("в журнале событий выгрузки %d записей, ожидалось 2", len(export.EventLog))
+	}
+	if export.Report == nil || export.Report.ID != report.ID {
+		t.Errorf("в выгрузке нет связанного отчета %d", report.ID)
+	}
+
+	if rec := doJSON(routerFor("admin"), http.MethodGet, "/sagas/unknown/export", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("несуществующая Saga: статус %d, ожидался 404", rec.Code)
+	}
+	if rec := doJSON(routerFor("user"), http.MethodGet, "/sagas/"+saga.ID+"/export", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("выгрузка не администратором: статус %d, ожидался 403", rec.Code)
+	}
+}
+
+func TestGetSagaProgressReportsStepDurations(t *testing.T) {
+	env := newTestEnv(t)
+	report := env.createReport(t, 1, models.StatusFailed)
+	saga := env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+
+	started := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	completed := started.Add(1500 * time.Millisecond)
+	failedAt := completed.Add(time.Second)
+	saga.Steps[0].Status = events.SagaStepCompleted
+	saga.Steps[0].ExecutedAt = &started
+	saga.Steps[0].CompletedAt = &completed
+	saga.Steps[1].Status = events.SagaStepFailed
+	saga.Steps[1].ExecutedAt = &failedAt
+	saga.Steps[1].Error = "template-service недоступен"
+	if err := env.stateStore.SaveSagaState(context.Background(), saga); err != nil {
+		t.Fatalf("сохранение Saga: %v", err)
+	}
+
+	router := env.router(1, func(r gin.IRoutes) { r.GET("/sagas/:id/progress", env.sagas.GetSagaProgress) })
+	rec := doJSON(router, http.MethodGet, "/sagas/"+saga.ID+"/progress", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+	var progress events.SagaProgress
+	if err := json.Unmarshal(rec.Body.Bytes(), &progress); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+
+	if progress.TotalSteps != len(saga.Steps) || progress.CompletedSteps != 1 || progress.FailedSteps != 1 {
+		t.Errorf("шагов всего %d, выполнено %d, с ошибкой %d", progress.TotalSteps, progress.CompletedSteps, progress.FailedSteps)
+	}
+	if len(progress.Steps) != len(saga.Steps) {
+		t.Fatalf("в хронологии %d шагов, ожидалось %d", len(progress.Steps), len(saga.Steps))
+	}
+
+	done := progress.Steps[0]
+	if done.ID != saga.Steps[0].ID || done.DurationMs == nil || *done.DurationMs != 1500 {
+		t.Errorf("выполненный шаг %s: duration_ms %v, ожидалось 1500", done.ID, done.DurationMs)
+	}
+	failed := progress.Steps[1]
+	if failed.DurationMs != nil || failed.Error != "template-service недоступен" || failed.ExecutedAt == nil {
+		t.Errorf("шаг с ошибкой: duration_ms %v, error %q, executed_at %v", failed.DurationMs, failed.Error, failed.ExecutedAt)
+	}
+	if pending := progress.Steps[2]; pending.DurationMs != nil || pending.ExecutedAt != nil {
+		t.Errorf("невыполненный шаг: duration_ms %v, executed_at %v", pending.DurationMs, pending.ExecutedAt)
+	}
+	if !strings.Contains(rec.Body.String(), `"duration_ms":1500`) {
+		t.Errorf("в ответе нет duration_ms: %s", rec.Body.String())
+	}
+}
+
+func TestSagaEndpointsRejectedWhenQueueIsFull(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Пул без воркеров и очереди не принимает ни одной Saga
+	fullPool := events.NewSagaWorkerPool(1, 0)
+	sagas := NewSagaHandler(env.coordinator, env.stateStore, fullPool, env.reportService, nil, time.Hour, 100, 0)
+	reports := NewReportHandler(env.reportService, env.coordinator, fullPool, testMetrics(), audit.NewLogger(env.db))
+	router := env.router(1, func(r gin.IRoutes) {
+		r.POST("/sagas/reports", sagas.CreateReportSaga)
+		r.POST("/sagas/:id/retry", sagas.RetrySaga)
+		r.POST("/sagas/:id/steps/:stepId/retry", sagas.RetrySagaStep)
+		r.POST("/reports/:id/retry", reports.RetryReport)
+	})
+
+	// failedSaga создает упавший отчет с Saga для каждого запроса отдельно
+	failedSaga := func(t *testing.T) (*models.Report, *events.Saga) {
+		t.Helper()
+		report := env.createReport(t, 1, models.StatusFailed)
+		return report, env.saveReportSaga(t, report.ID, events.SagaStatusFailed)
+	}
+	requests := []struct {
+		name string
+		path func(t *testing.T) string
+		body interface{}
+	}{
+		{"создание Saga", func(t *testing.T) string { return "/sagas/reports" }, gin.H{"template_id": "1", "parameters": gin.H{}}},
+		{"повтор Saga", func(t *testing.T) string {
+			_, saga := failedSaga(t)
+			return "/sagas/" + saga.ID + "/retry"
+		}, nil},
+		{"повтор шага", func(t *testing.T) string {
+			_, saga := failedSaga(t)
+			return "/sagas/" + saga.ID + "/steps/" + saga.Steps[0].ID + "/retry"
+		}, nil},
+		{"повтор отчета", func(t *testing.T) string {
+			report, _ := failedSaga(t)
+			return fmt.Sprintf("/reports/%d/retry", report.ID)
+		}, nil},
+	}
+	for _, req := range requests {
+		t.Run(req.name, func(t *testing.T) {
+			rec := doJSON(router, http.MethodPost, req.path(t), req.body)
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("статус %d, ожидался 429: %s", rec.Code, rec.Body.String())
+			}
+			assertRetryAfter(t, rec, 5)
+		})
+	}
+}
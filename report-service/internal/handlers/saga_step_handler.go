@@ -2,62 +2,197 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"report-service/internal/apperrors"
+	"report-service/internal/clients"
 	"report-service/internal/events"
+	"report-service/internal/jwt"
 	"report-service/internal/models"
 	"report-service/internal/services"
 
 	"github.com/sirupsen/logrus"
 )
 
+// stepFunc выполняет или компенсирует шаг Saga
+type stepFunc func(ctx context.Context, step *events.SagaStep) error
+
+// stepKey пара сервис/действие, по которой находится обработчик шага
+type stepKey struct {
+	service string
+	action  string
+}
+
 // SagaStepHandler обработчик для выполнения шагов Saga
 type SagaStepHandler struct {
 	reportService  *services.ReportService
 	eventPublisher events.EventPublisher
+	templateClient *clients.TemplateClient
+	storageClient  *clients.StorageClient
+	jwtManager     *jwt.Manager
+
+	actions       map[stepKey]stepFunc
+	compensations map[stepKey]stepFunc
 }
 
 // NewSagaStepHandler создает новый обработчик шагов Saga
-func NewSagaStepHandler(reportService *services.ReportService, eventPublisher events.EventPublisher) *SagaStepHandler {
-	return &SagaStepHandler{
+func NewSagaStepHandler(reportService *services.ReportService, eventPublisher events.EventPublisher, templateClient *clients.TemplateClient, storageClient *clients.StorageClient, jwtManager *jwt.Manager) *SagaStepHandler {
+	h := &SagaStepHandler{
 		reportService:  reportService,
 		eventPublisher: eventPublisher,
+		templateClient: templateClient,
+		storageClient:  storageClient,
+		jwtManager:     jwtManager,
+		actions:        make(map[stepKey]stepFunc),
+		compensations:  make(map[stepKey]stepFunc),
+	}
+
+	h.register("report-service", "validate_parameters", h.validateParameters, nil)
+	h.register("report-service", "generate_report", h.generateReport, h.compensateGenerateReport)
+	h.register("report-service", "update_status", h.updateReportStatus, nil)
+	h.register("report-service", events.RecordMetadataAction, h.recordMetadata, nil)
+	h.register("user-service", "validate_user", h.validateUser, nil)
+	h.register("template-service", "validate_template", h.validateTemplate, nil)
+	h.register("data-service", "collect_data", h.collectData, nil)
+	h.register("storage-service", "store_file", h.storeFile, h.compensateStoreFile)
+	h.register("notification-service", "send_notification", h.sendNotification, nil)
+	h.register("notification-service", events.SendReportToRecipientAction, h.sendReportToRecipient, nil)
+
+	return h
+}
+
+// register добавляет действие сервиса и, если есть, его компенсацию
+func (h *SagaStepHandler) register(service, action string, execute, compensate stepFunc) {
+	key := stepKey{service: service, action: action}
+	h.actions[key] = execute
+	if compensate != nil {
+		h.compensations[key] = compensate
+	}
+}
+
+// hasService проверяет, что для сервиса зарегистрировано хотя бы одно действие
+func hasService(registry map[stepKey]stepFunc, service string) bool {
+	for key := range registry {
+		if key.service == service {
+			return true
+		}
 	}
+	return false
+}
+
+// Capabilities возвращает поддерживаемые пары сервис/действие, отсортированные по сервису и действию
+func (h *SagaStepHandler) Capabilities() []models.SagaStepCapability {
+	capabilities := make([]models.SagaStepCapability, 0, len(h.actions))
+	for key := range h.actions {
+		_, compensable := h.compensations[key]
+		capabilities = append(capabilities, models.SagaStepCapability{
+			Service:     key.service,
+			Action:      key.action,
+			Compensable: compensable,
+		})
+	}
+
+	sort.Slice(capabilities, func(i, j int) bool {
+		if capabilities[i].Service != capabilities[j].Service {
+			return capabilities[i].Service < capabilities[j].Service
+		}
+		return capabilities[i].Action < capabilities[j].Action
+	})
+	return capabilities
 }
 
 // ExecuteStep выполняет шаг Saga
 func (h *SagaStepHandler) ExecuteStep(ctx context.Context, step *events.SagaStep) error {
 	logrus.Infof("Выполняем шаг Saga: %s", step.Name)
 
-	switch step.Service {
-	case "report-service":
-		return h.executeReportServiceStep(ctx, step)
-	case "user-service":
-		return h.executeUserServiceStep(ctx, step)
-	case "template-service":
-		return h.executeTemplateServiceStep(ctx, step)
-	case "data-service":
-		return h.executeDataServiceStep(ctx, step)
-	case "storage-service":
-		return h.executeStorageServiceStep(ctx, step)
-	case "notification-service":
-		return h.executeNotificationServiceStep(ctx, step)
-	default:
-		return fmt.Errorf("неизвестный сервис: %s", step.Service)
+	execute, ok := h.actions[stepKey{service: step.Service, action: step.Action}]
+	if !ok {
+		if !hasService(h.actions, step.Service) {
+			return fmt.Errorf("неизвестный сервис: %s", step.Service)
+		}
+		return fmt.Errorf("неизвестное действие для %s: %s", step.Service, step.Action)
+	}
+	return execute(ctx, step)
+}
+
+// validateParameters проверяет, что обязательные переменные шаблона заданы в parameters,
+// есть среди полей собранных данных или имеют значение по умолчанию, чтобы ошибка
+// всплыла до генерации отчета
+func (h *SagaStepHandler) validateParameters(ctx context.Context, step *events.SagaStep) error {
+	templateIDStr, ok := step.Data["template_id"].(string)
+	if !ok {
+		return fmt.Errorf("отсутствует template_id в данных шага")
+	}
+	templateID, err := strconv.ParseUint(templateIDStr, 10, 32)
+	if err != nil {
+		return fmt.Errorf("некорректный template_id: %w", err)
+	}
+
+	userIDStr, _ := step.Data["user_id"].(string)
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		return fmt.Errorf("некорректный user_id: %w", err)
+	}
+
+	// Saga выполняется в фоне без исходного запроса, поэтому к template-service
+	// обращаемся с токеном от имени пользователя, запустившего Saga
+	token, err := h.jwtManager.GenerateToken(uint(userID), "report-service", "", "user")
+	if err != nil {
+		return fmt.Errorf("ошибка создания токена для template-service: %w", err)
+	}
+
+	variables, err := h.templateClient.GetTemplateVariables(ctx, uint(templateID), "Bearer "+token)
+	if err != nil {
+		if errors.Is(err, clients.ErrNotFound) {
+			return fmt.Errorf("шаблон %d не найден", templateID)
+		}
+		// Без переменных шаблона параметры не проверить: Saga не продолжается непроверенной
+		return fmt.Errorf("ошибка получения переменных шаблона %d: %w", templateID, err)
+	}
+
+	available := make(map[string]bool)
+	if parameters, ok := step.Data["parameters"].(map[string]interface{}); ok {
+		for name, value := range parameters {
+			if value != nil && value != "" {
+				available[name] = true
+			}
+		}
+	}
+	if fields, ok := step.Data["collected_fields"].([]interface{}); ok {
+		for _, field := range fields {
+			if name, ok := field.(string); ok {
+				available[name] = true
+			}
+		}
+	}
+
+	if missing := missingRequiredVariables(variables, available); len(missing) > 0 {
+		return fmt.Errorf("не заданы обязательные параметры шаблона: %s", strings.Join(missing, ", "))
 	}
+
+	logrus.Infof("Параметры шаблона %d проверены", templateID)
+	return nil
 }
 
-// executeReportServiceStep выполняет шаги report-service
-func (h *SagaStepHandler) executeReportServiceStep(ctx context.Context, step *events.SagaStep) error {
-	switch step.Action {
-	case "generate_report":
-		return h.generateReport(ctx, step)
-	case "update_status":
-		return h.updateReportStatus(ctx, step)
-	default:
-		return fmt.Errorf("неизвестное действие для report-service: %s", step.Action)
+// missingRequiredVariables возвращает обязательные переменные без значения и без значения по умолчанию
+func missingRequiredVariables(variables []clients.TemplateVariable, available map[string]bool) []string {
+	var missing []string
+	for _, variable := range variables {
+		if !variable.Required || variable.Default != "" {
+			continue
+		}
+		if !available[variable.Name] {
+			missing = append(missing, variable.Name)
+		}
 	}
+	return missing
 }
 
 // generateReport генерирует отчет
@@ -89,27 +224,98 @@ func (h *SagaStepHandler) generateReport(ctx context.Context, step *events.SagaS
 		return fmt.Errorf("некорректный user_id: %w", err)
 	}
 
-	// Создаем запрос на создание отчета
-	createReq := &models.ReportCreateRequest{
-		Name:        parameters["title"].(string),
-		Description: "Отчет создан через сагу",
-		TemplateID:  uint(templateID),
-		Parameters:  fmt.Sprintf(`{"title":"%s"}`, parameters["title"].(string)),
+	// Saga генерации существующего отчета (генерация, принудительная перегенерация, повтор)
+	// обновляет этот же отчет, а не создает новый
+	reportID, err := existingReportID(step)
+	if err != nil {
+		return err
+	}
+	if reportID != 0 {
+		if _, err := h.reportService.GetReportByID(reportID); err != nil {
+			// Отчет, созданный этим шагом при прошлом запуске, мог быть удален: создаем заново
+			created, _ := step.Data["report_created"].(bool)
+			if !created || !apperrors.HasStatus(err, http.StatusNotFound) {
+				return fmt.Errorf("ошибка получения отчета %d: %w", reportID, err)
+			}
+			reportID = 0
+		}
 	}
 
-	// Сохраняем отчет в БД
-	createdReport, err := h.reportService.CreateReport(uint(userID), createReq)
-	if err != nil {
-		return fmt.Errorf("ошибка создания отчета: %w", err)
+	if reportID == 0 {
+		title, _ := parameters["title"].(string)
+		if title == "" {
+			title = fmt.Sprintf("Отчет по шаблону %d", templateID)
+		}
+		encodedParameters, err := json.Marshal(map[string]interface{}{"title": title})
+		if err != nil {
+			return fmt.Errorf("ошибка сериализации параметров отчета: %w", err)
+		}
+
+		// Создаем запрос на создание отчета
+		createReq := &models.ReportCreateRequest{
+			Name:        title,
+			Description: "Отчет создан через сагу",
+			TemplateID:  uint(templateID),
+			Parameters:  string(encodedParameters),
+		}
+		if format, ok := step.Data["format"].(string); ok {
+			createReq.Format = format
+		}
+		if correlationID, ok := step.Data[events.CorrelationIDKey].(string); ok {
+			createReq.CorrelationID = correlationID
+		}
+
+		// Сохраняем отчет в БД
+		createdReport, err := h.reportService.CreateReport(uint(userID), createReq)
+		if err != nil {
+			return fmt.Errorf("ошибка создания отчета: %w", err)
+		}
+		reportID = createdReport.ID
+		step.Data["report_created"] = true
+
+		// Созданный отчет блокируется до завершения Saga, которая снимет блокировку
+		// по report_id из своего состояния
+		if err := h.reportService.AcquireGenerationLock(reportID); err != nil {
+			return fmt.Errorf("ошибка блокировки генерации отчета %d: %w", reportID, err)
+		}
+	}
+
+	if err := h.reportService.UpdateReportStatus(reportID, string(models.StatusProcessing)); err != nil {
+		return fmt.Errorf("ошибка обновления статуса отчета: %w", err)
 	}
 
 	// Обновляем report_id в данных шага для последующих шагов
-	step.Data["report_id"] = strconv.FormatUint(uint64(createdReport.ID), 10)
+	step.Data["report_id"] = strconv.FormatUint(uint64(reportID), 10)
 
-	logrus.Infof("Отчет %d создан и статус установлен на processing", createdReport.ID)
+	logrus.Infof("Отчет %d в статусе processing", reportID)
 	return nil
 }
 
+// existingReportID возвращает ID отчета, для которого запущена Saga; 0 — отчет еще не создан
+func existingReportID(step *events.SagaStep) (uint, error) {
+	reportIDStr, _ := step.Data["report_id"].(string)
+	if reportIDStr == "" {
+		return 0, nil
+	}
+	reportID, err := strconv.ParseUint(reportIDStr, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("некорректный report_id: %w", err)
+	}
+	return uint(reportID), nil
+}
+
+// stepReportID возвращает ID отчета, который Saga передала шагу из generate-report
+func stepReportID(step *events.SagaStep) (uint, error) {
+	reportID, err := existingReportID(step)
+	if err != nil {
+		return 0, err
+	}
+	if reportID == 0 {
+		return 0, fmt.Errorf("отсутствует report_id в данных шага")
+	}
+	return reportID, nil
+}
+
 // updateReportStatus обновляет статус отчета
 func (h *SagaStepHandler) updateReportStatus(ctx context.Context, step *events.SagaStep) error {
 	status, ok := step.Data["status"].(string)
@@ -117,201 +323,263 @@ func (h *SagaStepHandler) updateReportStatus(ctx context.Context, step *events.S
 		return fmt.Errorf("отсутствует status в данных шага")
 	}
 
-	// Получаем user_id из данных шага
-	userID, _ := step.Data["user_id"].(string)
-	if userID == "" {
-		return fmt.Errorf("отсутствует user_id в данных шага")
+	reportID, err := stepReportID(step)
+	if err != nil {
+		return err
 	}
 
-	// Получаем report_id из БД - ищем последний отчет для данного пользователя
-	userIDUint, err := strconv.ParseUint(userID, 10, 32)
-	if err != nil {
-		return fmt.Errorf("некорректный user_id: %w", err)
+	if err := h.reportService.UpdateReportStatus(reportID, status); err != nil {
+		return fmt.Errorf("ошибка обновления статуса отчета: %w", err)
 	}
 
-	// Получаем последний отчет пользователя
-	reportsResponse, err := h.reportService.GetReports(uint(userIDUint), "", 1, 1)
+	logrus.Infof("Статус отчета %d обновлен на %s", reportID, status)
+	return nil
+}
+
+// recordMetadata сохраняет в отчет длительность генерации и число использованных записей
+func (h *SagaStepHandler) recordMetadata(ctx context.Context, step *events.SagaStep) error {
+	reportID, err := stepReportID(step)
 	if err != nil {
-		return fmt.Errorf("ошибка получения отчетов пользователя: %w", err)
+		return err
 	}
 
-	if len(reportsResponse.Reports) == 0 {
-		return fmt.Errorf("отчеты пользователя %s не найдены", userID)
+	startedAtStr, _ := step.Data["saga_started_at"].(string)
+	startedAt, err := time.Parse(time.RFC3339Nano, startedAtStr)
+	if err != nil {
+		return fmt.Errorf("некорректное время старта саги: %w", err)
+	}
+	duration := time.Since(startedAt)
+
+	// После сохранения состояния саги в JSON числа приходят как float64
+	recordCount := 0
+	switch v := step.Data["record_count"].(type) {
+	case int:
+		recordCount = v
+	case float64:
+		recordCount = int(v)
 	}
 
-	// Используем ID последнего отчета
-	reportID := reportsResponse.Reports[0].ID
-
-	if err := h.reportService.UpdateReportStatus(reportID, status); err != nil {
-		return fmt.Errorf("ошибка обновления статуса отчета: %w", err)
+	if err := h.reportService.UpdateReportMetadata(reportID, recordCount, duration); err != nil {
+		return err
 	}
 
-	logrus.Infof("Статус отчета %d обновлен на %s", reportID, status)
+	logrus.Infof("Метаданные отчета %d сохранены: записей %d, длительность %s", reportID, recordCount, duration)
 	return nil
 }
 
-// executeUserServiceStep выполняет шаги user-service
-func (h *SagaStepHandler) executeUserServiceStep(ctx context.Context, step *events.SagaStep) error {
-	switch step.Action {
-	case "validate_user":
-		// Здесь должна быть логика валидации пользователя
-		// Пока просто логируем
-		logrus.Info("Валидация пользователя выполнена")
-		return nil
-	default:
-		return fmt.Errorf("неизвестное действие для user-service: %s", step.Action)
-	}
+// validateUser выполняет шаг validate_user user-service
+func (h *SagaStepHandler) validateUser(ctx context.Context, step *events.SagaStep) error {
+	// Здесь должна быть логика валидации пользователя
+	// Пока просто логируем
+	logrus.Info("Валидация пользователя выполнена")
+	return nil
 }
 
-// executeTemplateServiceStep выполняет шаги template-service
-func (h *SagaStepHandler) executeTemplateServiceStep(ctx context.Context, step *events.SagaStep) error {
-	switch step.Action {
-	case "validate_template":
-		// Здесь должна быть логика валидации шаблона
-		// Пока просто логируем
-		logrus.Info("Валидация шаблона выполнена")
-		return nil
-	default:
-		return fmt.Errorf("неизвестное действие для template-service: %s", step.Action)
-	}
+// validateTemplate выполняет шаг validate_template template-service
+func (h *SagaStepHandler) validateTemplate(ctx context.Context, step *events.SagaStep) error {
+	// Здесь должна быть логика валидации шаблона
+	// Пока просто логируем
+	logrus.Info("Валидация шаблона выполнена")
+	return nil
 }
 
-// executeDataServiceStep выполняет шаги data-service
-func (h *SagaStepHandler) executeDataServiceStep(ctx context.Context, step *events.SagaStep) error {
-	switch step.Action {
-	case "collect_data":
-		// Здесь должна быть логика сбора данных
-		// Пока просто логируем
-		logrus.Info("Сбор данных выполнен")
-		return nil
-	default:
-		return fmt.Errorf("неизвестное действие для data-service: %s", step.Action)
+// collectData выполняет шаг collect_data data-service
+func (h *SagaStepHandler) collectData(ctx context.Context, step *events.SagaStep) error {
+	// Здесь должна быть логика сбора данных.
+	// Пока учитываем записи, переданные в параметрах отчета
+	var records []interface{}
+	if parameters, ok := step.Data["parameters"].(map[string]interface{}); ok {
+		records, _ = parameters["data"].([]interface{})
 	}
-}
 
-// executeStorageServiceStep выполняет шаги storage-service
-func (h *SagaStepHandler) executeStorageServiceStep(ctx context.Context, step *events.SagaStep) error {
-	switch step.Action {
-	case "store_file":
-		reportIDStr, ok := step.Data["report_id"].(string)
-		if !ok {
-			return fmt.Errorf("отсутствует report_id в данных шага")
+	// Поля записей доступны шаблону наравне с parameters
+	seen := make(map[string]bool)
+	fields := []interface{}{}
+	for _, record := range records {
+		if fieldsOf, ok := record.(map[string]interface{}); ok {
+			for name := range fieldsOf {
+				if !seen[name] {
+					seen[name] = true
+					fields = append(fields, name)
+				}
+			}
 		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].(string) < fields[j].(string) })
 
-		reportID, err := strconv.ParseUint(reportIDStr, 10, 32)
-		if err != nil {
-			return fmt.Errorf("некорректный report_id: %w", err)
-		}
+	step.Data["record_count"] = len(records)
+	step.Data["collected_fields"] = fields
 
-		// Симулируем сохранение файла
-		filePath := fmt.Sprintf("/reports/report_%d.pdf", reportID)
-		fileSize := int64(1024 * 1024) // 1MB
-		md5Hash := fmt.Sprintf("hash_%d", reportID)
+	logrus.Infof("Сбор данных выполнен, записей: %d", len(records))
+	return nil
+}
 
-		// Обновляем отчет с путем к файлу
-		if err := h.reportService.UpdateReportFilePath(uint(reportID), filePath, fileSize, md5Hash); err != nil {
-			return fmt.Errorf("ошибка обновления пути к файлу: %w", err)
-		}
+// storeFile выполняет шаг store_file storage-service
+func (h *SagaStepHandler) storeFile(ctx context.Context, step *events.SagaStep) error {
+	reportID, err := stepReportID(step)
+	if err != nil {
+		return err
+	}
 
-		logrus.Infof("Файл отчета %d сохранен по пути %s", reportID, filePath)
-		return nil
-	default:
-		return fmt.Errorf("неизвестное действие для storage-service: %s", step.Action)
+	// Содержимое и расширение файла определяются форматом, выбранным при создании отчета
+	name, content, err := h.reportService.RenderReportFile(reportID)
+	if err != nil {
+		return fmt.Errorf("ошибка формирования файла отчета: %w", err)
+	}
+	correlationID, _ := step.Data[events.CorrelationIDKey].(string)
+	file, err := h.storageClient.UploadFile(ctx, name, content, correlationID, "")
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения файла отчета: %w", err)
 	}
+
+	step.Data["file_id"] = strconv.FormatUint(uint64(file.ID), 10)
+	step.Data["file_name"] = name
+	// Хранилище возвращает уже существующий файл с тем же содержимым: при компенсации
+	// удаляется только файл, загруженный с ID этого отчета
+	step.Data["file_uploaded"] = correlationID != "" && file.CorrelationID == correlationID
+
+	if err := h.reportService.UpdateReportFilePath(reportID, file.Path, file.Size, file.Hash); err != nil {
+		return fmt.Errorf("ошибка обновления пути к файлу: %w", err)
+	}
+
+	logrus.Infof("Файл отчета %d сохранен в storage-service (файл %d)", reportID, file.ID)
+	return nil
 }
 
-// executeNotificationServiceStep выполняет шаги notification-service
-func (h *SagaStepHandler) executeNotificationServiceStep(ctx context.Context, step *events.SagaStep) error {
-	switch step.Action {
-	case "send_notification":
-		// Получаем user_id из данных шага
-		userID, _ := step.Data["user_id"].(string)
+// sendNotification выполняет шаг send_notification notification-service
+func (h *SagaStepHandler) sendNotification(ctx context.Context, step *events.SagaStep) error {
+	userID, _ := step.Data["user_id"].(string)
+	id, err := stepReportID(step)
+	if err != nil {
+		return err
+	}
+	reportID := strconv.FormatUint(uint64(id), 10)
+
+	// Публикуем событие, которое прочитает notification-service
+	correlationID, _ := step.Data[events.CorrelationIDKey].(string)
+	event, err := events.NewEvent(events.ReportCompleted, "report-service", map[string]interface{}{
+		"report_id":             reportID,
+		"user_id":               userID,
+		"type":                  "report_ready",
+		events.CorrelationIDKey: correlationID,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
+	event.WithCorrelationID(correlationID)
+	if err := h.eventPublisher.Publish(ctx, event); err != nil {
+		return fmt.Errorf("ошибка публикации события уведомления: %w", err)
+	}
+	logrus.Infof("Событие ReportCompleted опубликовано для notification-service с report_id: %s", reportID)
+	return nil
+}
 
-		// Получаем report_id из БД - ищем последний отчет для данного пользователя
-		userIDUint, err := strconv.ParseUint(userID, 10, 32)
-		if err != nil {
-			return fmt.Errorf("некорректный user_id: %w", err)
-		}
+// sendReportToRecipient выполняет шаг send_report_to_recipient notification-service:
+// публикует событие готовности отчета для одного получателя с ID файла отчета в
+// storage-service, который notification-service прикладывает к письму
+func (h *SagaStepHandler) sendReportToRecipient(ctx context.Context, step *events.SagaStep) error {
+	recipient, _ := step.Data["recipient"].(string)
+	if recipient == "" {
+		return fmt.Errorf("отсутствует recipient в данных шага")
+	}
 
-		// Получаем последний отчет пользователя
-		reportsResponse, err := h.reportService.GetReports(uint(userIDUint), "", 1, 1)
-		if err != nil {
-			return fmt.Errorf("ошибка получения отчетов пользователя: %w", err)
-		}
+	reportID, err := stepReportID(step)
+	if err != nil {
+		return err
+	}
+	reportIDStr := strconv.FormatUint(uint64(reportID), 10)
 
-		if len(reportsResponse.Reports) == 0 {
-			return fmt.Errorf("отчеты пользователя %s не найдены", userID)
-		}
+	report, err := h.reportService.GetReportByID(reportID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения отчета: %w", err)
+	}
 
-		// Используем ID последнего отчета
-		reportID := strconv.FormatUint(uint64(reportsResponse.Reports[0].ID), 10)
+	fileID, _ := step.Data["file_id"].(string)
+	if fileID == "" {
+		return fmt.Errorf("отсутствует file_id в данных шага")
+	}
 
-		// Публикуем событие, которое прочитает notification-service
-		event := events.NewEvent(events.ReportCompleted, "report-service", map[string]interface{}{
-			"report_id": reportID,
-			"user_id":   userID,
-			"type":      "report_ready",
-		})
-		if err := h.eventPublisher.Publish(ctx, event); err != nil {
-			return fmt.Errorf("ошибка публикации события уведомления: %w", err)
+	userID, _ := step.Data["user_id"].(string)
+	notificationType, _ := step.Data["type"].(string)
+	correlationID, _ := step.Data[events.CorrelationIDKey].(string)
+	data := map[string]interface{}{
+		"report_id":             reportIDStr,
+		"user_id":               userID,
+		"recipient":             recipient,
+		"type":                  notificationType,
+		"file_path":             report.FilePath,
+		"file_id":               fileID,
+		events.CorrelationIDKey: correlationID,
+	}
+	for _, key := range []string{"file_name", "channel_id"} {
+		if v, _ := step.Data[key].(string); v != "" {
+			data[key] = v
 		}
-		logrus.Infof("Событие ReportCompleted опубликовано для notification-service с report_id: %s", reportID)
-		return nil
-	default:
-		return fmt.Errorf("неизвестное действие для notification-service: %s", step.Action)
 	}
+	event, err := events.NewEvent(events.ReportCompleted, "report-service", data)
+	if err != nil {
+		return fmt.Errorf("ошибка создания события: %w", err)
+	}
+	event.WithCorrelationID(correlationID)
+	if err := h.eventPublisher.Publish(ctx, event); err != nil {
+		return fmt.Errorf("ошибка публикации события уведомления: %w", err)
+	}
+	logrus.Infof("Отчет %s отправлен получателю %s", reportIDStr, recipient)
+	return nil
 }
 
 // CompensateStep выполняет компенсацию шага Saga
 func (h *SagaStepHandler) CompensateStep(ctx context.Context, step *events.SagaStep) error {
 	logrus.Infof("Компенсируем шаг Saga: %s", step.Name)
 
-	switch step.Service {
-	case "report-service":
-		return h.compensateReportServiceStep(ctx, step)
-	case "storage-service":
-		return h.compensateStorageServiceStep(ctx, step)
-	default:
-		logrus.Infof("Компенсация для сервиса %s не требуется", step.Service)
-		return nil
+	compensate, ok := h.compensations[stepKey{service: step.Service, action: step.Action}]
+	if !ok {
+		if !hasService(h.compensations, step.Service) {
+			logrus.Infof("Компенсация для сервиса %s не требуется", step.Service)
+			return nil
+		}
+		return fmt.Errorf("неизвестное действие для компенсации %s: %s", step.Service, step.Action)
 	}
+	return compensate(ctx, step)
 }
 
-// compensateReportServiceStep компенсирует шаги report-service
-func (h *SagaStepHandler) compensateReportServiceStep(ctx context.Context, step *events.SagaStep) error {
-	switch step.Action {
-	case "generate_report":
-		reportIDStr, ok := step.Data["report_id"].(string)
-		if !ok {
-			return fmt.Errorf("отсутствует report_id в данных шага")
-		}
-
-		reportID, err := strconv.ParseUint(reportIDStr, 10, 32)
-		if err != nil {
-			return fmt.Errorf("некорректный report_id: %w", err)
-		}
+// compensateGenerateReport компенсирует шаг generate_report: переводит отчет в failed
+func (h *SagaStepHandler) compensateGenerateReport(ctx context.Context, step *events.SagaStep) error {
+	reportIDStr, ok := step.Data["report_id"].(string)
+	if !ok {
+		return fmt.Errorf("отсутствует report_id в данных шага")
+	}
 
-		// Обновляем статус на failed
-		if err := h.reportService.UpdateReportStatus(uint(reportID), string(models.StatusFailed)); err != nil {
-			return fmt.Errorf("ошибка обновления статуса на failed: %w", err)
-		}
+	reportID, err := strconv.ParseUint(reportIDStr, 10, 32)
+	if err != nil {
+		return fmt.Errorf("некорректный report_id: %w", err)
+	}
 
-		logrus.Infof("Статус отчета %d обновлен на failed (компенсация)", reportID)
-		return nil
-	default:
-		return fmt.Errorf("неизвестное действие для компенсации report-service: %s", step.Action)
+	// Обновляем статус на failed
+	if err := h.reportService.UpdateReportStatus(uint(reportID), string(models.StatusFailed)); err != nil {
+		return fmt.Errorf("ошибка обновления статуса на failed: %w", err)
 	}
+
+	logrus.Infof("Статус отчета %d обновлен на failed (компенсация)", reportID)
+	return nil
 }
 
-// compensateStorageServiceStep компенсирует шаги storage-service
-func (h *SagaStepHandler) compensateStorageServiceStep(ctx context.Context, step *events.SagaStep) error {
-	switch step.Action {
-	case "store_file":
-		// Здесь должна быть логика удаления файла
-		// Пока просто логируем
-		logrus.Info("Файл удален (компенсация)")
+// compensateStoreFile компенсирует шаг store_file
+func (h *SagaStepHandler) compensateStoreFile(ctx context.Context, step *events.SagaStep) error {
+	fileIDStr, _ := step.Data["file_id"].(string)
+	if uploaded, _ := step.Data["file_uploaded"].(bool); !uploaded || fileIDStr == "" {
+		logrus.Info("Файл не загружался этой Saga, удаление не требуется (компенсация)")
 		return nil
-	default:
-		return fmt.Errorf("неизвестное действие для компенсации storage-service: %s", step.Action)
 	}
+	fileID, err := strconv.ParseUint(fileIDStr, 10, 32)
+	if err != nil {
+		return fmt.Errorf("некорректный file_id: %w", err)
+	}
+
+	if err := h.storageClient.DeleteFile(ctx, uint(fileID), ""); err != nil {
+		return fmt.Errorf("ошибка удаления файла %d: %w", fileID, err)
+	}
+
+	logrus.Infof("Файл %d удален (компенсация)", fileID)
+	return nil
 }
@@ -0,0 +1,648 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"report-service/internal/clients"
+	"report-service/internal/events"
+	"report-service/internal/jwt"
+	"report-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeStorage storage-service, запоминающий загруженные и удаленные файлы
+type fakeStorage struct {
+	mu      sync.Mutex
+	uploads []fakeUpload
+	deleted []string
+	// failUploads отвечает 500 на загрузку файла
+	failUploads bool
+}
+
+// fakeUpload файл, загруженный в fakeStorage
+type fakeUpload struct {
+	name          string
+	correlationID string
+	hash          string
+	content       []byte
+}
+
+func (s *fakeStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/files/upload":
+		if s.failUploads {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		upload := fakeUpload{
+			name:          r.FormValue("name"),
+			correlationID: r.FormValue("correlation_id"),
+			hash:          fmt.Sprintf("%x", md5.Sum(content)),
+			content:       content,
+		}
+		s.uploads = append(s.uploads, upload)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"file": map[string]interface{}{
+			"id":             len(s.uploads),
+			"name":           header.Filename,
+			"path":           "/storage/" + upload.hash,
+			"size":           len(content),
+			"hash":           upload.hash,
+			"correlation_id": upload.correlationID,
+		}})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v1/files/"):
+		s.deleted = append(s.deleted, strings.TrimPrefix(r.URL.Path, "/api/v1/files/"))
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// recordingPublisher запоминает опубликованные события
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []*events.Event
+	// fail возвращает ошибку публикации события, если не nil
+	fail func(event *events.Event) error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event *events.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail != nil {
+		if err := p.fail(event); err != nil {
+			return err
+		}
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) PublishAsync(ctx context.Context, event *events.Event) error {
+	return p.Publish(ctx, event)
+}
+
+// ofType возвращает опубликованные события типа eventType
+func (p *recordingPublisher) ofType(eventType events.EventType) []*events.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var found []*events.Event
+	for _, event := range p.events {
+		if event.Type == eventType {
+			found = append(found, event)
+		}
+	}
+	return found
+}
+
+// stepFakes соседние сервисы, с которыми работают настоящие шаги Saga
+type stepFakes struct {
+	storage   *fakeStorage
+	published *recordingPublisher
+}
+
+// newStepCoordinator создает координатор, выполняющий шаги настоящим SagaStepHandler;
+// template-service подменяется сервером без обязательных переменных шаблона
+func newStepCoordinator(t *testing.T, env *testEnv) (*events.IdempotentSagaCoordinator, *stepFakes) {
+	t.Helper()
+	return newStepCoordinatorWithTemplates(t, env, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"variables":[]}`))
+	}))
+}
+
+// newStepCoordinatorWithTemplates создает координатор, в котором template-service
+// подменяется обработчиком templates
+func newStepCoordinatorWithTemplates(t *testing.T, env *testEnv, templatesHandler http.Handler) (*events.IdempotentSagaCoordinator, *stepFakes) {
+	t.Helper()
+
+	templates := httptest.NewServer(templatesHandler)
+	t.Cleanup(templates.Close)
+
+	fakes := &stepFakes{storage: &fakeStorage{}, published: &recordingPublisher{}}
+	storage := httptest.NewServer(fakes.storage)
+	t.Cleanup(storage.Close)
+
+	retry := clients.RetryPolicy{Attempts: 1}
+	steps := NewSagaStepHandler(env.reportService, fakes.published, clients.NewTemplateClient(templates.URL, retry, 0), clients.NewStorageClient(storage.URL, retry), jwt.NewManager("test-secret"))
+	return events.NewIdempotentSagaCoordinator(fakes.published, env.stateStore, steps, testMetrics(), 0), fakes
+}
+
+func TestReportSagaRecordsMetadata(t *testing.T) {
+	env := newTestEnv(t)
+	coordinator, _ := newStepCoordinator(t, env)
+
+	parameters := map[string]interface{}{
+		"title":  "Продажи",
+		"format": string(models.FormatCSV),
+		"data":   []interface{}{1, 2, 3},
+	}
+	saga := events.NewIdempotentReportCreationSaga("0", "7", "3", parameters)
+	if err := saga.Execute(context.Background(), coordinator); err != nil {
+		t.Fatalf("выполнение Saga: %v", err)
+	}
+
+	state, err := env.stateStore.GetSagaState(context.Background(), saga.ID)
+	if err != nil {
+		t.Fatalf("состояние Saga: %v", err)
+	}
+	reportID := state.ReportID()
+	if reportID == 0 {
+		t.Fatal("Saga не создала отчет")
+	}
+
+	var report models.Report
+	if err := env.db.First(&report, reportID).Error; err != nil {
+		t.Fatalf("получение отчета: %v", err)
+	}
+	if report.Status != string(models.StatusCompleted) {
+		t.Errorf("статус отчета %q, ожидался completed", report.Status)
+	}
+	if report.RecordCount != 3 {
+		t.Errorf("record_count = %d, ожидалось 3", report.RecordCount)
+	}
+	if report.GenerationDurationMs < 0 {
+		t.Errorf("generation_duration_ms = %d", report.GenerationDurationMs)
+	}
+
+	// ID созданного отчета передан шагам через объявленные входы
+	want := strconv.FormatUint(uint64(reportID), 10)
+	for _, stepID := range []string{"store-file", "record-metadata", "update-status"} {
+		if got, _ := state.FindStep(stepID).Data["report_id"].(string); got != want {
+			t.Errorf("шаг %s получил report_id %q, ожидался %q", stepID, got, want)
+		}
+	}
+}
+
+func TestReportSagaRecordsMetadataBeforeCompletion(t *testing.T) {
+	saga := events.NewIdempotentReportCreationSaga("0", "7", "3", map[string]interface{}{})
+
+	index := make(map[string]int, len(saga.Steps))
+	for i, step := range saga.Steps {
+		index[step.ID] = i
+	}
+	// Ошибка метаданных откатывает отчет, поэтому шаг должен идти до перевода в completed
+	if index["record-metadata"] > index["update-status"] {
+		t.Errorf("record-metadata (%d) выполняется после update-status (%d)", index["record-metadata"], index["update-status"])
+	}
+}
+
+func TestReportSagaPropagatesCorrelationID(t *testing.T) {
+	env := newTestEnv(t)
+	coordinator, fakes := newStepCoordinator(t, env)
+
+	const correlationID = "corr-2429"
+	parameters := map[string]interface{}{
+		"title":                 "Продажи",
+		"format":                string(models.FormatCSV),
+		events.CorrelationIDKey: correlationID,
+	}
+	saga := events.NewIdempotentReportCreationSaga("0", "7", "3", parameters)
+	if err := saga.Execute(context.Background(), coordinator); err != nil {
+		t.Fatalf("выполнение Saga: %v", err)
+	}
+
+	state, err := env.stateStore.GetSagaState(context.Background(), saga.ID)
+	if err != nil {
+		t.Fatalf("состояние Saga: %v", err)
+	}
+	if got := state.CorrelationID(); got != correlationID {
+		t.Errorf("correlation_id Saga %q, ожидался %q", got, correlationID)
+	}
+
+	var report models.Report
+	if err := env.db.First(&report, state.ReportID()).Error; err != nil {
+		t.Fatalf("получение отчета: %v", err)
+	}
+	if report.CorrelationID != correlationID {
+		t.Errorf("correlation_id отчета %q, ожидался %q", report.CorrelationID, correlationID)
+	}
+
+	// Файл отчета действительно загружен в storage-service с ID отчета
+	if len(fakes.storage.uploads) != 1 {
+		t.Fatalf("загружено файлов: %d, ожидался 1", len(fakes.storage.uploads))
+	}
+	upload := fakes.storage.uploads[0]
+	if upload.correlationID != correlationID {
+		t.Errorf("файл загружен с correlation_id %q, ожидался %q", upload.correlationID, correlationID)
+	}
+	if want := fmt.Sprintf("report_%d.csv", report.ID); upload.name != want {
+		t.Errorf("имя файла %q, ожидалось %q", upload.name, want)
+	}
+	if report.MD5Hash != upload.hash {
+		t.Errorf("хеш файла отчета %q, загружен %q", report.MD5Hash, upload.hash)
+	}
+
+	// Событие для notification-service несет тот же ID в данных и метаданных
+	completed := fakes.published.ofType(events.ReportCompleted)
+	if len(completed) != 1 {
+		t.Fatalf("событий report.completed: %d, ожидалось 1", len(completed))
+	}
+	if got := completed[0].Data[events.CorrelationIDKey]; got != correlationID {
+		t.Errorf("correlation_id в данных события %v, ожидался %q", got, correlationID)
+	}
+	if got := completed[0].Metadata[events.CorrelationIDKey]; got != correlationID {
+		t.Errorf("correlation_id в метаданных события %v, ожидался %q", got, correlationID)
+	}
+}
+
+func TestReportSagaFormatDrivesStoredFile(t *testing.T) {
+	tests := []struct {
+		format models.ReportFormat
+		// check проверяет, что содержимое файла сформировано в формате отчета
+		check func(t *testing.T, content []byte)
+	}{
+		{models.FormatCSV, func(t *testing.T, content []byte) {
+			records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+			if err != nil || len(records) != 2 || records[0][0] != "ID" || records[1][1] != "Продажи" {
+				t.Errorf("файл не является CSV отчета (%v): %q", err, content)
+			}
+		}},
+		{models.FormatHTML, func(t *testing.T, content []byte) {
+			if !bytes.HasPrefix(content, []byte("<!DOCTYPE html>")) || !bytes.Contains(content, []byte("<td>Продажи</td>")) {
+				t.Errorf("файл не является HTML отчета: %q", content)
+			}
+		}},
+		{models.FormatXLSX, func(t *testing.T, content []byte) {
+			archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+			if err != nil {
+				t.Fatalf("файл не является XLSX: %v", err)
+			}
+			sheet, err := archive.Open("xl/worksheets/sheet1.xml")
+			if err != nil {
+				t.Fatalf("в XLSX нет листа: %v", err)
+			}
+			defer sheet.Close()
+			data, _ := io.ReadAll(sheet)
+			if !bytes.Contains(data, []byte("<t>Продажи</t>")) {
+				t.Errorf("лист XLSX не содержит название отчета: %s", data)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			env := newTestEnv(t)
+			coordinator, fakes := newStepCoordinator(t, env)
+
+			saga := events.NewIdempotentReportCreationSaga("0", "7", "3", map[string]interface{}{
+				"title":  "Продажи",
+				"format": strings.ToUpper(string(tt.format)),
+			})
+			if err := saga.Execute(context.Background(), coordinator); err != nil {
+				t.Fatalf("выполнение Saga: %v", err)
+			}
+			_, report := reportOfSaga(t, env, saga.ID)
+
+			if len(fakes.storage.uploads) != 1 {
+				t.Fatalf("загружено файлов: %d, ожидался 1", len(fakes.storage.uploads))
+			}
+			upload := fakes.storage.uploads[0]
+			if want := fmt.Sprintf("report_%d.%s", report.ID, tt.format); upload.name != want {
+				t.Errorf("имя файла %q, ожидалось %q", upload.name, want)
+			}
+			tt.check(t, upload.content)
+		})
+	}
+}
+
+func TestReportSagaValidatesRequiredParameters(t *testing.T) {
+	// region и period обязательны, currency имеет значение по умолчанию
+	variables := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"variables":[
+			{"name":"region","required":true},
+			{"name":"period","required":true},
+			{"name":"currency","required":true,"default":"RUB"},
+			{"name":"comment"}
+		]}`))
+	})
+
+	tests := []struct {
+		name       string
+		templates  http.Handler
+		parameters map[string]interface{}
+		wantFailed bool
+	}{
+		{
+			name:       "missing parameter",
+			templates:  variables,
+			parameters: map[string]interface{}{"title": "Продажи", "region": "north"},
+			wantFailed: true,
+		},
+		{
+			name:       "empty parameter",
+			templates:  variables,
+			parameters: map[string]interface{}{"title": "Продажи", "region": "", "period": "2024-01"},
+			wantFailed: true,
+		},
+		{
+			name:      "parameter from collected data",
+			templates: variables,
+			parameters: map[string]interface{}{
+				"title":  "Продажи",
+				"region": "north",
+				"data":   []interface{}{map[string]interface{}{"period": "2024-01", "amount": 10}},
+			},
+		},
+		{
+			name: "template service unavailable",
+			templates: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}),
+			parameters: map[string]interface{}{"title": "Продажи", "region": "north", "period": "2024-01"},
+			wantFailed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			coordinator, fakes := newStepCoordinatorWithTemplates(t, env, tt.templates)
+
+			saga := events.NewIdempotentReportCreationSaga("0", "7", "3", tt.parameters)
+			err := saga.Execute(context.Background(), coordinator)
+			if (err != nil) != tt.wantFailed {
+				t.Fatalf("выполнение Saga: %v, ожидалась ошибка: %v", err, tt.wantFailed)
+			}
+
+			state, err := env.stateStore.GetSagaState(context.Background(), saga.ID)
+			if err != nil {
+				t.Fatalf("состояние Saga: %v", err)
+			}
+			if !tt.wantFailed {
+				if state.Status != events.SagaStatusCompleted {
+					t.Errorf("Saga в статусе %s, ожидался completed", state.Status)
+				}
+				return
+			}
+
+			if step := state.FindStep("validate-parameters"); step.Status != events.SagaStepFailed {
+				t.Errorf("шаг validate-parameters в статусе %s, ожидался failed", step.Status)
+			}
+			// Saga остановлена до генерации: отчет не создан, файл не загружен
+			if step := state.FindStep("generate-report"); step.Status != events.SagaStepPending {
+				t.Errorf("шаг generate-report в статусе %s, ожидался pending", step.Status)
+			}
+			var count int64
+			env.db.Unscoped().Model(&models.Report{}).Count(&count)
+			if count != 0 {
+				t.Errorf("создано отчетов: %d, ожидалось 0", count)
+			}
+			if len(fakes.storage.uploads) != 0 {
+				t.Errorf("загружено файлов: %d, ожидалось 0", len(fakes.storage.uploads))
+			}
+		})
+	}
+}
+
+// reportOfSaga возвращает отчет, созданный Saga
+func reportOfSaga(t *testing.T, env *testEnv, sagaID string) (*events.Saga, *models.Report) {
+	t.Helper()
+	state, err := env.stateStore.GetSagaState(context.Background(), sagaID)
+	if err != nil {
+		t.Fatalf("состояние Saga: %v", err)
+	}
+	var report models.Report
+	if err := env.db.Unscoped().First(&report, state.ReportID()).Error; err != nil {
+		t.Fatalf("получение отчета: %v", err)
+	}
+	return state, &report
+}
+
+func TestBatchRenderNotifySagaNotifiesAllRecipients(t *testing.T) {
+	env := newTestEnv(t)
+	coordinator, fakes := newStepCoordinator(t, env)
+
+	recipients := []string{"a@example.com", "b@example.com", "c@example.com"}
+	saga := events.NewBatchRenderNotifySaga("0", "7", "3", map[string]interface{}{"format": "csv"}, recipients, 5)
+	if err := saga.Execute(context.Background(), coordinator); err != nil {
+		t.Fatalf("выполнение Saga: %v", err)
+	}
+
+	state, report := reportOfSaga(t, env, saga.ID)
+	if state.Status != events.SagaStatusCompleted {
+		t.Errorf("статус Saga %q, ожидался completed", state.Status)
+	}
+	if report.Status != string(models.StatusCompleted) {
+		t.Errorf("статус отчета %q, ожидался completed", report.Status)
+	}
+
+	// Каждый получатель получает событие с файлом отчета из storage-service
+	notified := make(map[string]bool)
+	for _, event := range fakes.published.ofType(events.ReportCompleted) {
+		recipient, _ := event.Data["recipient"].(string)
+		notified[recipient] = true
+		if event.Data["file_id"] != "1" {
+			t.Errorf("%s: file_id %v, ожидался загруженный файл 1", recipient, event.Data["file_id"])
+		}
+		if event.Data["file_name"] != fmt.Sprintf("report_%d.csv", report.ID) {
+			t.Errorf("%s: file_name %v", recipient, event.Data["file_name"])
+		}
+		if event.Data["channel_id"] != "5" {
+			t.Errorf("%s: channel_id %v, ожидался 5", recipient, event.Data["channel_id"])
+		}
+	}
+	for _, recipient := range recipients {
+		if !notified[recipient] {
+			t.Errorf("получатель %s не уведомлен", recipient)
+		}
+	}
+	if len(notified) != len(recipients) {
+		t.Errorf("уведомлены %v, ожидались %v", notified, recipients)
+	}
+}
+
+func TestBatchRenderNotifySagaStorageFailureCompensatesReport(t *testing.T) {
+	env := newTestEnv(t)
+	coordinator, fakes := newStepCoordinator(t, env)
+	fakes.storage.failUploads = true
+
+	saga := events.NewBatchRenderNotifySaga("0", "7", "3", map[string]interface{}{}, []string{"a@example.com"}, 0)
+	if err := saga.Execute(context.Background(), coordinator); err == nil {
+		t.Fatal("Saga выполнена, хотя storage-service недоступен")
+	}
+
+	state, report := reportOfSaga(t, env, saga.ID)
+	if state.Status != events.SagaStatusCompensated {
+		t.Errorf("статус Saga %q, ожидался compensated", state.Status)
+	}
+	if step := state.FindStep("generate-report"); step.Status != events.SagaStepCompensated {
+		t.Errorf("шаг generate-report в статусе %q, ожидался compensated", step.Status)
+	}
+	if report.Status != string(models.StatusFailed) {
+		t.Errorf("статус отчета %q, ожидался failed", report.Status)
+	}
+	if got := len(fakes.published.ofType(events.ReportCompleted)); got != 0 {
+		t.Errorf("отправлено уведомлений о готовности: %d", got)
+	}
+}
+
+func TestBatchRenderNotifySagaRecipientFailureKeepsReport(t *testing.T) {
+	env := newTestEnv(t)
+	coordinator, fakes := newStepCoordinator(t, env)
+	fakes.published.fail = func(event *events.Event) error {
+		if event.Data["recipient"] == "b@example.com" {
+			return fmt.Errorf("брокер недоступен")
+		}
+		return nil
+	}
+
+	recipients := []string{"a@example.com", "b@example.com", "c@example.com"}
+	saga := events.NewBatchRenderNotifySaga("0", "7", "3", map[string]interface{}{}, recipients, 0)
+	if err := saga.Execute(context.Background(), coordinator); err != nil {
+		t.Fatalf("выполнение Saga: %v", err)
+	}
+
+	// Ошибка отправки одному получателю не откатывает готовый отчет и не мешает остальным
+	state, report := reportOfSaga(t, env, saga.ID)
+	if state.Status != events.SagaStatusCompleted {
+		t.Errorf("статус Saga %q, ожидался completed", state.Status)
+	}
+	if report.Status != string(models.StatusCompleted) || report.DeletedAt.Valid {
+		t.Errorf("отчет в статусе %q (удален: %v), ожидался completed", report.Status, report.DeletedAt.Valid)
+	}
+	if step := state.FindStep("notify-recipient-2"); step.Status != events.SagaStepFailed {
+		t.Errorf("шаг notify-recipient-2 в статусе %q, ожидался failed", step.Status)
+	}
+	if got := len(fakes.published.ofType(events.ReportCompleted)); got != 2 {
+		t.Errorf("уведомлено получателей: %d, ожидалось 2", got)
+	}
+	if len(fakes.storage.deleted) != 0 {
+		t.Errorf("файл отчета удален: %v", fakes.storage.deleted)
+	}
+}
+
+func TestReportSagaOutcomeSelectsNotificationTemplate(t *testing.T) {
+	parameters := map[string]interface{}{"format": string(models.FormatCSV), "data": []interface{}{1}}
+
+	t.Run("успешная генерация", func(t *testing.T) {
+		env := newTestEnv(t)
+		coordinator, fakes := newStepCoordinator(t, env)
+
+		saga := events.NewIdempotentReportCreationSaga("0", "7", "3", parameters)
+		if err := saga.Execute(context.Background(), coordinator); err != nil {
+			t.Fatalf("выполнение Saga: %v", err)
+		}
+
+		completed := fakes.published.ofType(events.ReportCompleted)
+		if len(completed) != 1 || completed[0].Data["type"] != "report_ready" {
+			t.Fatalf("события report.completed %+v", completed)
+		}
+		if failed := fakes.published.ofType(events.ReportFailed); len(failed) != 0 {
+			t.Errorf("опубликовано report.failed: %+v", failed)
+		}
+	})
+
+	t.Run("ошибка генерации", func(t *testing.T) {
+		env := newTestEnv(t)
+		coordinator, fakes := newStepCoordinator(t, env)
+		fakes.storage.failUploads = true
+
+		saga := events.NewIdempotentReportCreationSaga("0", "7", "3", parameters)
+		if err := saga.Execute(context.Background(), coordinator); err == nil {
+			t.Fatal("Saga выполнена, хотя storage-service недоступен")
+		}
+
+		failed := fakes.published.ofType(events.ReportFailed)
+		if len(failed) != 1 {
+			t.Fatalf("событий report.failed: %d, ожидалось 1", len(failed))
+		}
+		data := failed[0].Data
+		if data["type"] != "report_failed" || data["user_id"] != "7" || data["saga_id"] != saga.ID {
+			t.Errorf("данные report.failed %+v", data)
+		}
+		if reportID, _ := data["report_id"].(string); reportID == "" || reportID == "0" {
+			t.Errorf("report_id %v", data["report_id"])
+		}
+		if errText, _ := data["error"].(string); errText == "" {
+			t.Error("в событии нет текста ошибки")
+		}
+		if completed := fakes.published.ofType(events.ReportCompleted); len(completed) != 0 {
+			t.Errorf("опубликовано report.completed: %+v", completed)
+		}
+	})
+}
+
+func TestSagaCapabilitiesListRegisteredActions(t *testing.T) {
+	env := newTestEnv(t)
+	steps := NewSagaStepHandler(env.reportService, events.NewLocalEventPublisher(), nil, nil, jwt.NewManager("test-secret"))
+	sagas := NewSagaHandler(env.coordinator, env.stateStore, env.pool, env.reportService, steps, time.Hour, 100, 0)
+	router := env.router(1, func(r gin.IRoutes) { r.GET("/sagas/capabilities", sagas.GetCapabilities) })
+
+	rec := doJSON(router, http.MethodGet, "/sagas/capabilities", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+	var body models.SagaCapabilitiesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+
+	want := []models.SagaStepCapability{
+		{Service: "data-service", Action: "collect_data"},
+		{Service: "notification-service", Action: "send_notification"},
+		{Service: "notification-service", Action: events.SendReportToRecipientAction},
+		{Service: "report-service", Action: "generate_report", Compensable: true},
+		{Service: "report-service", Action: events.RecordMetadataAction},
+		{Service: "report-service", Action: "update_status"},
+		{Service: "report-service", Action: "validate_parameters"},
+		{Service: "storage-service", Action: "store_file", Compensable: true},
+		{Service: "template-service", Action: "validate_template"},
+		{Service: "user-service", Action: "validate_user"},
+	}
+	if !reflect.DeepEqual(body.Capabilities, want) {
+		t.Errorf("действия %+v, ожидалось %+v", body.Capabilities, want)
+	}
+}
+
+func TestExecuteStepRejectsUnknownAction(t *testing.T) {
+	env := newTestEnv(t)
+	steps := NewSagaStepHandler(env.reportService, events.NewLocalEventPublisher(), nil, nil, jwt.NewManager("test-secret"))
+
+	tests := []struct {
+		name    string
+		service string
+		action  string
+		wantErr string
+	}{
+		{"неизвестное действие", "report-service", "archive_report", "неизвестное действие для report-service: archive_report"},
+		{"неизвестный сервис", "billing-service", "charge", "неизвестный сервис: billing-service"},
+		{"действие другого сервиса", "user-service", "collect_data", "неизвестное действие для user-service: collect_data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &events.SagaStep{Name: "step", Service: tt.service, Action: tt.action}
+			if err := steps.ExecuteStep(context.Background(), step); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ошибка %v, ожидалась %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"report-service/internal/apperrors"
+	"report-service/internal/settings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SettingsHandler обработчик настроек сервиса
+type SettingsHandler struct {
+	store *settings.Store
+}
+
+// NewSettingsHandler создает обработчик настроек сервиса
+func NewSettingsHandler(store *settings.Store) *SettingsHandler {
+	return &SettingsHandler{
+		store: store,
+	}
+}
+
+// GetSetting возвращает текущее значение настройки или значение по умолчанию
+func (h *SettingsHandler) GetSetting(c *gin.Context) {
+	setting, err := h.store.Get(c.Param("key"))
+	if err != nil {
+		respondSettingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setting)
+}
+
+// UpdateSetting изменяет значение настройки без перезапуска сервиса
+func (h *SettingsHandler) UpdateSetting(c *gin.Context) {
+	var req settings.SettingUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
+		return
+	}
+
+	setting, err := h.store.Set(c.Request.Context(), c.Param("key"), req.Value)
+	if err != nil {
+		respondSettingError(c, err)
+		return
+	}
+
+	logrus.WithField("key", setting.Key).Infof("Настройка изменена: %s", setting.Value)
+	c.JSON(http.StatusOK, setting)
+}
+
+// respondSettingError переводит ошибки хранилища настроек в ответ API
+func respondSettingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, settings.ErrUnknownSetting):
+		apperrors.Respond(c, apperrors.NotFound("Настройка не найдена"))
+	case errors.Is(err, settings.ErrInvalidValue):
+		apperrors.Respond(c, apperrors.Validation(err.Error()))
+	default:
+		logrus.WithError(err).Error("Ошибка работы с настройками")
+		apperrors.Respond(c, apperrors.Internal(err))
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"report-service/internal/apperrors"
+	"report-service/internal/clients"
+	"report-service/internal/models"
+	"report-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ShareHandler обработчик публичных ссылок на отчеты
+type ShareHandler struct {
+	shareService  *services.ShareService
+	storageClient *clients.StorageClient
+	publicBaseURL string
+}
+
+// NewShareHandler создает новый обработчик ссылок; файлы отчетов скачиваются из storage-service
+func NewShareHandler(shareService *services.ShareService, storageClient *clients.StorageClient, publicBaseURL string) *ShareHandler {
+	return &ShareHandler{
+		shareService:  shareService,
+		storageClient: storageClient,
+		publicBaseURL: strings.TrimRight(publicBaseURL, "/"),
+	}
+}
+
+// CreateShare создает подписанную ссылку на скачивание отчета
+func (h *ShareHandler) CreateShare(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
+		return
+	}
+
+	var req models.ReportShareRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apperrors.Respond(c, apperrors.Validation("Некорректные данные запроса").WithDetails(err.Error()))
+			return
+		}
+	}
+
+	ttl := time.Duration(req.ExpiresInSeconds) * time.Second
+	share, token, err := h.shareService.CreateShare(uint(id), userID.(uint), ttl)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка создания ссылки на отчет")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.ReportShareResponse{
+		ID:        share.ID,
+		ReportID:  share.ReportID,
+		Token:     token,
+		URL:       fmt.Sprintf("%s/api/v1/reports/shared?token=%s", h.publicBaseURL, token),
+		ExpiresAt: share.ExpiresAt,
+	})
+}
+
+// RevokeShare отзывает ссылку на отчет
+func (h *ShareHandler) RevokeShare(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Respond(c, apperrors.Unauthorized("Пользователь не авторизован"))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID отчета"))
+		return
+	}
+	shareID, err := strconv.ParseUint(c.Param("shareId"), 10, 32)
+	if err != nil {
+		apperrors.Respond(c, apperrors.Validation("Некорректный ID ссылки"))
+		return
+	}
+
+	if err := h.shareService.RevokeShare(uint(id), uint(shareID), userID.(uint)); err != nil {
+		logrus.WithError(err).Error("Ошибка отзыва ссылки на отчет")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetSharedReport отдает файл отчета по подписанной ссылке без аутентификации.
+// Файл хранится в storage-service и передается клиенту потоком; сжатые файлы
+// storage-service распаковывает при скачивании.
+func (h *ShareHandler) GetSharedReport(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		apperrors.Respond(c, apperrors.Validation("Не указан token"))
+		return
+	}
+
+	report, err := h.shareService.ResolveShare(token)
+	if err != nil {
+		logrus.WithError(err).Warn("Отклонен доступ по ссылке на отчет")
+		apperrors.Respond(c, err)
+		return
+	}
+
+	if report.MD5Hash == "" {
+		apperrors.Respond(c, apperrors.NotFound("файл отчета недоступен"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	file, err := h.storageClient.GetFileByHash(ctx, report.MD5Hash, "")
+	if err != nil {
+		logrus.WithError(err).Errorf("Файл отчета %d не получен из хранилища", report.ID)
+		if errors.Is(err, clients.ErrNotFound) {
+			apperrors.Respond(c, apperrors.NotFound("файл отчета недоступен"))
+			return
+		}
+		apperrors.Respond(c, apperrors.Internal(err))
+		return
+	}
+
+	fileName := file.Name
+	if fileName == "" {
+		fileName = report.FilePath
+	}
+	setAttachmentHeaders(c, path.Base(fileName))
+	c.Status(http.StatusOK)
+
+	// После начала передачи статус изменить нельзя: при ошибке клиент получит обрезанный файл
+	if _, err := h.storageClient.DownloadFile(ctx, file.ID, "", c.Writer); err != nil {
+		logrus.WithError(err).Errorf("Скачивание файла отчета %d по ссылке прервано", report.ID)
+		if c.Writer.Written() {
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Del("Content-Disposition")
+		if errors.Is(err, clients.ErrNotFound) {
+			apperrors.Respond(c, apperrors.NotFound("файл отчета недоступен"))
+			return
+		}
+		apperrors.Respond(c, apperrors.Internal(err))
+	}
+}
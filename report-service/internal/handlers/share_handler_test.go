@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"report-service/internal/clients"
+	"report-service/internal/models"
+	"report-service/internal/repository"
+	"report-service/internal/services"
+	"report-service/internal/sharing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testShareSecret = "share-secret"
+
+// shareStorage заглушка storage-service: файл с хешем share-hash хранится под собственным
+// путем хранилища, которого нет на диске report-service, и отдается уже распакованным
+func shareStorage(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/files/hash/share-hash", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 11, "name": "report_1.csv", "size": 21, "mime_type": "text/csv", "path": "/var/lib/storage/ab/cd/abcd.gz", "hash": "share-hash"}`))
+	})
+	mux.HandleFunc("/api/v1/files/11/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id,name\n1,Отчет\n"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestShareRouter возвращает маршрутизатор с публичным скачиванием по ссылке и сервис ссылок
+func newTestShareRouter(t *testing.T, env *testEnv) (*gin.Engine, *services.ShareService) {
+	shareService := services.NewShareService(env.reportService, repository.NewReportShareRepository(env.db), sharing.NewSigner(testShareSecret), time.Hour)
+	storageClient := clients.NewStorageClient(shareStorage(t).URL, clients.RetryPolicy{Attempts: 1})
+	router := gin.New()
+	router.GET("/api/v1/reports/shared", NewShareHandler(shareService, storageClient, "http://gateway").GetSharedReport)
+	return router, shareService
+}
+
+// createStoredReport создает готовый отчет, файл которого загружен в storage-service с хешем hash
+func createStoredReport(t *testing.T, env *testEnv, userID uint, hash string) *models.Report {
+	t.Helper()
+	report := env.createReport(t, userID, models.StatusCompleted)
+	report.FilePath = "/var/lib/storage/ab/cd/abcd.gz"
+	report.MD5Hash = hash
+	if err := env.db.Save(report).Error; err != nil {
+		t.Fatalf("сохранение отчета: %v", err)
+	}
+	return report
+}
+
+// getShared скачивает отчет по токену без аутентификации
+func getShared(router http.Handler, token string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/reports/shared?token="+url.QueryEscape(token), nil))
+	return rec
+}
+
+func TestGetSharedReportVerifiesToken(t *testing.T) {
+	env := newTestEnv(t)
+	router, shareService := newTestShareRouter(t, env)
+	report := createStoredReport(t, env, 1, "share-hash")
+
+	share, token, err := shareService.CreateShare(report.ID, 1, 0)
+	if err != nil {
+		t.Fatalf("создание ссылки: %v", err)
+	}
+
+	rec := getShared(router, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("действующая ссылка: статус %d, тело %s", rec.Code, rec.Body.String())
+	}
+	if rec.Body.String() != "id,name\n1,Отчет\n" {
+		t.Errorf("скачан файл %q", rec.Body.String())
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); !strings.Contains(disposition, `filename="report_1.csv"`) {
+		t.Errorf("Content-Disposition = %q", disposition)
+	}
+
+	expired := sharing.NewSigner(testShareSecret).Sign(share.ID, time.Now().Add(-time.Minute))
+	if rec := getShared(router, expired); rec.Code != http.StatusForbidden {
+		t.Errorf("истекшая ссылка: статус %d, ожидался 403", rec.Code)
+	}
+
+	// Подпись другим ключом и измененная полезная нагрузка не принимаются
+	foreign := sharing.NewSigner("other-secret").Sign(share.ID, time.Now().Add(time.Hour))
+	payload, signature, _ := strings.Cut(token, ".")
+	tampered := strings.ToUpper(payload[:1]) + payload[1:] + "." + signature
+	if payload[:1] == strings.ToUpper(payload[:1]) {
+		tampered = strings.ToLower(payload[:1]) + payload[1:] + "." + signature
+	}
+	for name, token := range map[string]string{"чужой ключ": foreign, "измененный токен": tampered, "без подписи": payload} {
+		if rec := getShared(router, token); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: статус %d, ожидался 401", name, rec.Code)
+		}
+	}
+
+	if rec := getShared(router, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("без токена: статус %d, ожидался 400", rec.Code)
+	}
+}
+
+func TestGetSharedReportFileMissingInStorage(t *testing.T) {
+	env := newTestEnv(t)
+	router, shareService := newTestShareRouter(t, env)
+	report := createStoredReport(t, env, 1, "lost-hash")
+
+	_, token, err := shareService.CreateShare(report.ID, 1, 0)
+	if err != nil {
+		t.Fatalf("создание ссылки: %v", err)
+	}
+
+	rec := getShared(router, token)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("файл не найден в хранилище: статус %d, ожидался 404", rec.Code)
+	}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("ответ не JSON: %v", err)
+	}
+	if body.Error.Code != "not_found" {
+		t.Errorf("код ошибки %q, ожидался not_found", body.Error.Code)
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != "" {
+		t.Errorf("ответ с ошибкой отдан как вложение: %q", disposition)
+	}
+}
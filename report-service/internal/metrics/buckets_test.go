@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// testMetrics метрики регистрируются в глобальном реестре, поэтому создаются один раз
+var testMetrics = sync.OnceValue(func() *Metrics {
+	return NewMetrics("report-service-test")
+})
+
+// durationBuckets возвращает границы гистограммы http_request_duration_seconds маршрута
+// и число наблюдений; ok = false, если у маршрута нет серии
+func durationBuckets(t *testing.T, endpoint string) (bounds []float64, count uint64, ok bool) {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("сбор метрик: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "http_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if !hasLabel(metric, "endpoint", endpoint) {
+				continue
+			}
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				bounds = append(bounds, bucket.GetUpperBound())
+			}
+			return bounds, metric.GetHistogram().GetSampleCount(), true
+		}
+	}
+	return nil, 0, false
+}
+
+// hasLabel проверяет значение метки серии
+func hasLabel(metric *dto.Metric, name, value string) bool {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue() == value
+		}
+	}
+	return false
+}
+
+func TestRouteUsesOverriddenBuckets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(testMetrics().HTTPMiddleware("report-service"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/reports/export/all", Buckets(ExportBuckets), ok)
+	router.GET("/reports/:id", ok)
+	router.GET("/reports/:id/export/csv", Buckets(nil), ok)
+
+	for _, path := range []string{"/reports/export/all", "/reports/export/all", "/reports/1", "/reports/1/export/csv"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: статус %d", path, rec.Code)
+		}
+	}
+
+	tests := []struct {
+		endpoint string
+		want     []float64
+		count    uint64
+	}{
+		{"/reports/export/all", ExportBuckets, 2},
+		// Маршруты без переопределения и с пустым списком используют общие границы
+		{"/reports/:id", prometheus.DefBuckets, 1},
+		{"/reports/:id/export/csv", prometheus.DefBuckets, 1},
+	}
+	for _, tt := range tests {
+		bounds, count, found := durationBuckets(t, tt.endpoint)
+		if !found {
+			t.Errorf("%s: нет серии http_request_duration_seconds", tt.endpoint)
+			continue
+		}
+		if !reflect.DeepEqual(bounds, tt.want) || count != tt.count {
+			t.Errorf("%s: границы %v, наблюдений %d, ожидались %v и %d", tt.endpoint, bounds, count, tt.want, tt.count)
+		}
+	}
+}
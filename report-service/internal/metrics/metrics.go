@@ -16,6 +16,8 @@ type Metrics struct {
 	HTTPRequestsTotal    *prometheus.CounterVec
 	HTTPRequestDuration  *prometheus.HistogramVec
 	HTTPRequestsInFlight *prometheus.GaugeVec
+	// Гистограммы длительности маршрутов с собственными границами (см. Buckets)
+	routeDurations *routeDurationCollector
 
 	// Бизнес метрики
 	BusinessOperationsTotal   *prometheus.CounterVec
@@ -26,6 +28,16 @@ type Metrics struct {
 	DatabaseQueryDuration *prometheus.HistogramVec
 	DatabaseErrorsTotal   *prometheus.CounterVec
 
+	// Метрики брокера сообщений
+	EventsPublishedTotal *prometheus.CounterVec
+	EventPublishDuration *prometheus.HistogramVec
+	EventsConsumedTotal  *prometheus.CounterVec
+	EventConsumeDuration *prometheus.HistogramVec
+
+	// Метрики Outbox
+	OutboxPending *prometheus.GaugeVec
+	OutboxFailed  *prometheus.GaugeVec
+
 	// Системные метрики
 	MemoryUsage       *prometheus.GaugeVec
 	CPUUsage          *prometheus.GaugeVec
@@ -53,6 +65,8 @@ func NewMetrics(serviceName string) *Metrics {
 			[]string{"service", "method", "endpoint"},
 		),
 
+		routeDurations: newRouteDurationCollector(),
+
 		HTTPRequestsInFlight: promauto.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "http_requests_in_flight",
@@ -105,6 +119,58 @@ func NewMetrics(serviceName string) *Metrics {
 			[]string{"service", "operation", "error_type"},
 		),
 
+		// Метрики брокера сообщений
+		EventsPublishedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "events_published_total",
+				Help: "Total number of events published to the message broker",
+			},
+			[]string{"service", "event_type", "status"},
+		),
+
+		EventPublishDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "event_publish_duration_seconds",
+				Help:    "Event publish duration in seconds",
+				Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+			},
+			[]string{"service", "event_type"},
+		),
+
+		EventsConsumedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "events_consumed_total",
+				Help: "Total number of events consumed from the message broker",
+			},
+			[]string{"service", "event_type", "result"},
+		),
+
+		EventConsumeDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "event_consume_duration_seconds",
+				Help:    "Event handling duration in seconds",
+				Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			},
+			[]string{"service", "event_type"},
+		),
+
+		// Метрики Outbox
+		OutboxPending: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "outbox_pending",
+				Help: "Number of outbox events waiting to be published",
+			},
+			[]string{"service"},
+		),
+
+		OutboxFailed: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "outbox_failed",
+				Help: "Number of outbox events that failed to publish",
+			},
+			[]string{"service"},
+		),
+
 		// Системные метрики
 		MemoryUsage: promauto.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -156,11 +222,7 @@ func (m *Metrics) HTTPMiddleware(serviceName string) gin.HandlerFunc {
 			statusStr,
 		).Inc()
 
-		m.HTTPRequestDuration.WithLabelValues(
-			serviceName,
-			c.Request.Method,
-			c.FullPath(),
-		).Observe(duration)
+		m.durationObserver(c, serviceName).Observe(duration)
 	})
 }
 
@@ -186,6 +248,29 @@ func (m *Metrics) RecordDatabaseOperation(serviceName, operation string, duratio
 	}
 }
 
+// RecordEventPublished записывает метрики публикации события
+func (m *Metrics) RecordEventPublished(serviceName, eventType string, duration time.Duration, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+
+	m.EventsPublishedTotal.WithLabelValues(serviceName, eventType, status).Inc()
+	m.EventPublishDuration.WithLabelValues(serviceName, eventType).Observe(duration.Seconds())
+}
+
+// RecordEventConsumed записывает метрики обработки события (result: ack, nack, reject)
+func (m *Metrics) RecordEventConsumed(serviceName, eventType, result string, duration time.Duration) {
+	m.EventsConsumedTotal.WithLabelValues(serviceName, eventType, result).Inc()
+	m.EventConsumeDuration.WithLabelValues(serviceName, eventType).Observe(duration.Seconds())
+}
+
+// SetOutboxBacklog записывает число ожидающих и неудачных событий Outbox
+func (m *Metrics) SetOutboxBacklog(serviceName string, pending, failed int64) {
+	m.OutboxPending.WithLabelValues(serviceName).Set(float64(pending))
+	m.OutboxFailed.WithLabelValues(serviceName).Set(float64(failed))
+}
+
 // SetupMetricsEndpoint настраивает endpoint для метрик
 func (m *Metrics) SetupMetricsEndpoint(router *gin.Engine, serviceName string) {
 	// Добавляем middleware для HTTP метрик
@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"errors"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
 	"report-service/internal/jwt"
@@ -50,6 +53,36 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// RequireJSON отвечает 415 на POST/PUT/PATCH запросы с телом, у которых Content-Type
+// не application/json. Маршруты из exempt (в виде c.FullPath()) не проверяются
+func RequireJSON(exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			c.Next()
+			return
+		}
+		if skip[c.FullPath()] || c.Request.ContentLength == 0 {
+			c.Next()
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type должен быть application/json"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
@@ -62,7 +95,8 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
-func Auth(jwtManager *jwt.Manager) gin.HandlerFunc {
+// Auth middleware для аутентификации по JWT или, если apiTokens задан, по API токену пользователя
+func Auth(jwtManager *jwt.Manager, apiTokens *APITokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -78,6 +112,24 @@ func Auth(jwtManager *jwt.Manager) gin.HandlerFunc {
 		}
 
 		tokenString := authHeader[7:]
+		if apiTokens != nil && strings.HasPrefix(tokenString, APITokenPrefix) {
+			identity, err := apiTokens.Verify(c.Request.Context(), tokenString)
+			if err != nil {
+				if errors.Is(err, ErrInvalidAPIToken) {
+					c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				} else {
+					logrus.WithError(err).Error("Ошибка проверки API токена")
+					c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Token verification unavailable"})
+				}
+				c.Abort()
+				return
+			}
+
+			setAPITokenIdentity(c, identity)
+			c.Next()
+			return
+		}
+
 		claims, err := jwtManager.ValidateToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -95,6 +147,35 @@ func Auth(jwtManager *jwt.Manager) gin.HandlerFunc {
 	}
 }
 
+// Role middleware для проверки роли пользователя
+func Role(allowedRoles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := c.Get("role")
+		if !exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Role not found in context"})
+			c.Abort()
+			return
+		}
+
+		userRole, ok := role.(string)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid role type"})
+			c.Abort()
+			return
+		}
+
+		for _, allowedRole := range allowedRoles {
+			if userRole == allowedRole {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
+}
+
 func generateRequestID() string {
 	return time.Now().Format("20060102150405") + "-" + randomString(8)
 }
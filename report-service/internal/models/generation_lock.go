@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ReportGenerationLock блокировка генерации отчета. Пока запись не истекла,
+// повторный запуск генерации того же отчета отклоняется.
+type ReportGenerationLock struct {
+	ReportID  uint      `gorm:"primaryKey;autoIncrement:false"`
+	LockedAt  time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+// TableName возвращает имя таблицы
+func (ReportGenerationLock) TableName() string {
+	return "report_generation_locks"
+}
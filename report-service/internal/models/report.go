@@ -8,19 +8,33 @@ import (
 
 // Report модель отчета
 type Report struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"not null"`
-	Description string         `json:"description"`
-	TemplateID  uint           `json:"template_id" gorm:"not null"`
-	UserID      uint           `json:"user_id" gorm:"not null"`
-	Status      string         `json:"status" gorm:"default:'pending'"`
-	Parameters  string         `json:"parameters" gorm:"type:text"`
-	FilePath    string         `json:"file_path"`
-	FileSize    int64          `json:"file_size"`
-	MD5Hash     string         `json:"md5_hash"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"not null"`
+	Description string `json:"description"`
+	TemplateID  uint   `json:"template_id" gorm:"not null"`
+	UserID      uint   `json:"user_id" gorm:"not null"`
+	Status      string `json:"status" gorm:"default:'pending'"`
+	Parameters  string `json:"parameters" gorm:"type:text"`
+	Format      string `json:"format" gorm:"not null;default:'csv'"`
+	FilePath    string `json:"file_path"`
+	FileSize    int64  `json:"file_size"`
+	MD5Hash     string `json:"md5_hash"`
+	Version     int    `json:"version" gorm:"not null;default:1"`
+	// CorrelationID сквозной ID отчета: передается в Saga, события, файл и уведомление
+	CorrelationID string `json:"correlation_id" gorm:"size:36;index"`
+	// NotificationStatus статус доставки уведомления о готовности отчета
+	NotificationStatus string     `json:"notification_status"`
+	NotifiedAt         *time.Time `json:"notified_at,omitempty"`
+	// TTLSeconds срок хранения файла после завершения генерации; 0 — бессрочно
+	TTLSeconds int64 `json:"ttl_seconds,omitempty"`
+	// ExpiresAt момент, после которого файл отчета удаляется, а отчет переходит в expired
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	// RecordCount и GenerationDurationMs заполняются финальным шагом саги
+	RecordCount          int            `json:"record_count"`
+	GenerationDurationMs int64          `json:"generation_duration_ms"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // TableName возвращает имя таблицы
@@ -37,24 +51,103 @@ const (
 	StatusCompleted  ReportStatus = "completed"
 	StatusFailed     ReportStatus = "failed"
 	StatusCancelled  ReportStatus = "cancelled"
+	StatusExpired    ReportStatus = "expired"
 )
 
 // IsValid проверяет валидность статуса отчета
 func (s ReportStatus) IsValid() bool {
 	switch s {
-	case StatusPending, StatusProcessing, StatusCompleted, StatusFailed, StatusCancelled:
+	case StatusPending, StatusProcessing, StatusCompleted, StatusFailed, StatusCancelled, StatusExpired:
 		return true
 	default:
 		return false
 	}
 }
 
+// reportStatusTransitions допустимые переходы между статусами отчета
+var reportStatusTransitions = map[ReportStatus][]ReportStatus{
+	StatusPending:    {StatusProcessing, StatusFailed, StatusCancelled},
+	StatusProcessing: {StatusCompleted, StatusFailed, StatusCancelled},
+	StatusFailed:     {StatusPending},
+	StatusCompleted:  {StatusExpired},
+	StatusCancelled:  {},
+	StatusExpired:    {},
+}
+
+// CanTransitionTo проверяет, допустим ли переход в указанный статус
+func (s ReportStatus) CanTransitionTo(next ReportStatus) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range reportStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// reportRegenerationSources статусы, из которых отчет можно принудительно сгенерировать заново.
+// Отмененные и истекшие отчеты остаются конечными и новую версию не получают
+var reportRegenerationSources = []ReportStatus{StatusCompleted, StatusFailed}
+
+// CanRegenerate проверяет, допустима ли принудительная перегенерация отчета в этом статусе
+func (s ReportStatus) CanRegenerate() bool {
+	for _, allowed := range reportRegenerationSources {
+		if allowed == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportFormat формат итогового файла отчета
+type ReportFormat string
+
+const (
+	FormatXLSX ReportFormat = "xlsx"
+	FormatCSV  ReportFormat = "csv"
+	FormatHTML ReportFormat = "html"
+)
+
+// DefaultReportFormat формат отчета, если он не указан при создании
+const DefaultReportFormat = FormatCSV
+
+// IsValid проверяет, умеет ли сервис формировать файл отчета в этом формате
+func (f ReportFormat) IsValid() bool {
+	switch f {
+	case FormatXLSX, FormatCSV, FormatHTML:
+		return true
+	default:
+		return false
+	}
+}
+
+// Extension возвращает расширение файла для формата отчета
+func (f ReportFormat) Extension() string {
+	if !f.IsValid() {
+		return "." + string(DefaultReportFormat)
+	}
+	return "." + string(f)
+}
+
+// Статусы доставки уведомления о готовности отчета
+const (
+	NotificationStatusDelivered = "delivered"
+	NotificationStatusFailed    = "failed"
+)
+
 // ReportCreateRequest запрос на создание отчета
 type ReportCreateRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
 	TemplateID  uint   `json:"template_id" binding:"required"`
 	Parameters  string `json:"parameters"`
+	Format      string `json:"format"` // csv, xlsx или html; по умолчанию csv
+	// TTLSeconds срок хранения файла после генерации; без него используется REPORT_TTL
+	TTLSeconds int64 `json:"ttl_seconds" binding:"omitempty,min=1"`
+	// CorrelationID задается Saga, чтобы отчет получил сквозной ID исходного запроса
+	CorrelationID string `json:"-"`
 }
 
 // ReportUpdateRequest запрос на обновление отчета
@@ -65,42 +158,73 @@ type ReportUpdateRequest struct {
 	Parameters  string `json:"parameters"`
 }
 
+// ReportParametersRequest запрос на замену параметров отчета; пустой объект очищает параметры
+type ReportParametersRequest struct {
+	Parameters map[string]interface{} `json:"parameters" binding:"required"`
+}
+
+// ReportCSVOptions параметры CSV-выгрузки отчета: набор колонок и часовой пояс дат.
+// Пустой Columns означает все колонки, nil Location — UTC.
+type ReportCSVOptions struct {
+	Columns  []string
+	Location *time.Location
+}
+
 // ReportGenerateRequest запрос на генерацию отчета
 type ReportGenerateRequest struct {
 	Parameters map[string]interface{} `json:"parameters"`
+	Force      bool                   `json:"force"` // перезапустить генерацию даже для завершенного отчета
 }
 
 // ReportResponse ответ с данными отчета
 type ReportResponse struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	TemplateID  uint      `json:"template_id"`
-	UserID      uint      `json:"user_id"`
-	Status      string    `json:"status"`
-	Parameters  string    `json:"parameters"`
-	FilePath    string    `json:"file_path"`
-	FileSize    int64     `json:"file_size"`
-	MD5Hash     string    `json:"md5_hash"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                   uint       `json:"id"`
+	Name                 string     `json:"name"`
+	Description          string     `json:"description"`
+	TemplateID           uint       `json:"template_id"`
+	UserID               uint       `json:"user_id"`
+	Status               string     `json:"status"`
+	Parameters           string     `json:"parameters"`
+	Format               string     `json:"format"`
+	FilePath             string     `json:"file_path"`
+	FileSize             int64      `json:"file_size"`
+	MD5Hash              string     `json:"md5_hash"`
+	Version              int        `json:"version"`
+	CorrelationID        string     `json:"correlation_id,omitempty"`
+	NotificationStatus   string     `json:"notification_status,omitempty"`
+	NotifiedAt           *time.Time `json:"notified_at,omitempty"`
+	TTLSeconds           int64      `json:"ttl_seconds,omitempty"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
+	RecordCount          int        `json:"record_count"`
+	GenerationDurationMs int64      `json:"generation_duration_ms"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
 }
 
 // ToResponse преобразует Report в ReportResponse
 func (r *Report) ToResponse() ReportResponse {
 	return ReportResponse{
-		ID:          r.ID,
-		Name:        r.Name,
-		Description: r.Description,
-		TemplateID:  r.TemplateID,
-		UserID:      r.UserID,
-		Status:      r.Status,
-		Parameters:  r.Parameters,
-		FilePath:    r.FilePath,
-		FileSize:    r.FileSize,
-		MD5Hash:     r.MD5Hash,
-		CreatedAt:   r.CreatedAt,
-		UpdatedAt:   r.UpdatedAt,
+		ID:                   r.ID,
+		Name:                 r.Name,
+		Description:          r.Description,
+		TemplateID:           r.TemplateID,
+		UserID:               r.UserID,
+		Status:               r.Status,
+		Parameters:           r.Parameters,
+		Format:               r.Format,
+		FilePath:             r.FilePath,
+		FileSize:             r.FileSize,
+		MD5Hash:              r.MD5Hash,
+		Version:              r.Version,
+		CorrelationID:        r.CorrelationID,
+		NotificationStatus:   r.NotificationStatus,
+		NotifiedAt:           r.NotifiedAt,
+		TTLSeconds:           r.TTLSeconds,
+		ExpiresAt:            r.ExpiresAt,
+		RecordCount:          r.RecordCount,
+		GenerationDurationMs: r.GenerationDurationMs,
+		CreatedAt:            r.CreatedAt,
+		UpdatedAt:            r.UpdatedAt,
 	}
 }
 
@@ -119,6 +243,14 @@ type ReportCreateResponse struct {
 	Message string `json:"message"`
 }
 
+// TemplateUsageResponse использование шаблона отчетами
+type TemplateUsageResponse struct {
+	TemplateID    uint       `json:"template_id"`
+	ActiveReports int64      `json:"active_reports"`
+	TotalReports  int64      `json:"total_reports"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+}
+
 // ReportStatusResponse ответ со статусом отчета
 type ReportStatusResponse struct {
 	ID       uint   `json:"id"`
@@ -126,4 +258,111 @@ type ReportStatusResponse struct {
 	FilePath string `json:"file_path,omitempty"`
 	Progress int    `json:"progress,omitempty"`
 	Error    string `json:"error,omitempty"`
+	// FailedStep и RetryCount заполняются для отчетов в статусе failed по данным Saga
+	FailedStep string `json:"failed_step,omitempty"`
+	RetryCount int    `json:"retry_count,omitempty"`
+}
+
+// ReportStatusBatchRequest запрос статусов нескольких отчетов
+type ReportStatusBatchRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1"`
+}
+
+// ReportStatusBatchResponse статусы отчетов пользователя. ID чужих отчетов попадают
+// в Forbidden, а при скрытии чужих отчетов — в NotFound вместе с отсутствующими
+type ReportStatusBatchResponse struct {
+	Statuses  []ReportStatusResponse `json:"statuses"`
+	NotFound  []uint                 `json:"not_found"`
+	Forbidden []uint                 `json:"forbidden,omitempty"`
+}
+
+// ReportTemplateInfo сведения о шаблоне отчета
+type ReportTemplateInfo struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ReportFileInfo метаданные файла отчета
+type ReportFileInfo struct {
+	ID          uint   `json:"id"`
+	Size        int64  `json:"size"`
+	MimeType    string `json:"mime_type"`
+	DownloadURL string `json:"download_url"`
+}
+
+// ReportDetailResponse отчет вместе с шаблоном и файлом.
+// При недоступности соседних сервисов возвращаются частичные данные с флагами.
+type ReportDetailResponse struct {
+	Report            ReportResponse      `json:"report"`
+	Template          *ReportTemplateInfo `json:"template,omitempty"`
+	File              *ReportFileInfo     `json:"file,omitempty"`
+	TemplateAvailable bool                `json:"template_available"`
+	FileAvailable     bool                `json:"file_available"`
+	Partial           bool                `json:"partial"`
+	Warnings          []string            `json:"warnings,omitempty"`
+}
+
+// ReportTraceStep шаг Saga в трассировке отчета
+type ReportTraceStep struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ReportTraceSaga Saga генерации отчета в трассировке
+type ReportTraceSaga struct {
+	ID        string            `json:"id"`
+	Status    string            `json:"status"`
+	Error     string            `json:"error,omitempty"`
+	Steps     []ReportTraceStep `json:"steps"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// ReportTraceNotification уведомление, отправленное по событиям отчета
+type ReportTraceNotification struct {
+	ID           uint       `json:"id"`
+	Recipient    string     `json:"recipient"`
+	Type         string     `json:"type"`
+	Status       string     `json:"status"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+	SentAt       *time.Time `json:"sent_at,omitempty"`
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ReportTraceResponse путь отчета через сервисы, связанный сквозным correlation_id:
+// отчет, Saga генерации, файлы storage-service и уведомления notification-service.
+// При недоступности соседних сервисов возвращаются частичные данные с предупреждениями.
+type ReportTraceResponse struct {
+	CorrelationID string                    `json:"correlation_id"`
+	Report        ReportResponse            `json:"report"`
+	Saga          *ReportTraceSaga          `json:"saga,omitempty"`
+	Files         []ReportFileInfo          `json:"files"`
+	Notifications []ReportTraceNotification `json:"notifications"`
+	Partial       bool                      `json:"partial"`
+	Warnings      []string                  `json:"warnings,omitempty"`
+}
+
+// ReportExportManifestEntry запись manifest.json архива со всеми отчетами пользователя
+type ReportExportManifestEntry struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	TemplateID uint      `json:"template_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	File       string    `json:"file,omitempty"`
+	FileSize   int64     `json:"file_size,omitempty"`
+	MD5Hash    string    `json:"md5_hash,omitempty"`
+	Included   bool      `json:"included"`
+	Note       string    `json:"note,omitempty"`
+}
+
+// ReportExportManifest содержимое manifest.json архива
+type ReportExportManifest struct {
+	UserID      uint                        `json:"user_id"`
+	GeneratedAt time.Time                   `json:"generated_at"`
+	Reports     []ReportExportManifestEntry `json:"reports"`
 }
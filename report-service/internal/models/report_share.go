@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// ReportShare публичная ссылка на скачивание отчета
+type ReportShare struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	ReportID  uint       `json:"report_id" gorm:"not null;index"`
+	UserID    uint       `json:"user_id" gorm:"not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName возвращает имя таблицы
+func (ReportShare) TableName() string {
+	return "report_shares"
+}
+
+// IsActive проверяет, что ссылка не отозвана и не истекла
+func (s *ReportShare) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// ReportShareRequest запрос на создание ссылки
+type ReportShareRequest struct {
+	ExpiresInSeconds int `json:"expires_in_seconds" binding:"omitempty,min=60"`
+}
+
+// ReportShareResponse ответ с публичной ссылкой
+type ReportShareResponse struct {
+	ID        uint      `json:"id"`
+	ReportID  uint      `json:"report_id"`
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SagaStepCapability поддерживаемое действие шага Saga
+type SagaStepCapability struct {
+	Service     string `json:"service"`
+	Action      string `json:"action"`
+	Compensable bool   `json:"compensable"`
+}
+
+// SagaCapabilitiesResponse список действий, которые можно использовать в шагах Saga
+type SagaCapabilitiesResponse struct {
+	Capabilities []SagaStepCapability `json:"capabilities"`
+}
+
+// SagaCleanupResponse результат удаления старых Saga
+type SagaCleanupResponse struct {
+	DeletedSagas     int64     `json:"deleted_sagas"`
+	DeletedEventLogs int64     `json:"deleted_event_logs"`
+	OlderThan        time.Time `json:"older_than"`
+}
@@ -1,9 +1,13 @@
 package repository
 
 import (
+	"time"
+
+	"report-service/internal/database"
 	"report-service/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ReportRepository репозиторий для работы с отчетами
@@ -21,6 +25,17 @@ func (r *ReportRepository) Create(report *models.Report) error {
 	return r.db.Create(report).Error
 }
 
+// CreateWithTx создает отчет и выполняет afterCreate в той же транзакции.
+// Ошибка afterCreate откатывает создание отчета.
+func (r *ReportRepository) CreateWithTx(report *models.Report, afterCreate func(tx *gorm.DB) error) error {
+	return database.WithTransaction(r.db, func(tx *gorm.DB) error {
+		if err := tx.Create(report).Error; err != nil {
+			return err
+		}
+		return afterCreate(tx)
+	})
+}
+
 // GetByID получает отчет по ID
 func (r *ReportRepository) GetByID(id uint) (*models.Report, error) {
 	var report models.Report
@@ -28,6 +43,13 @@ func (r *ReportRepository) GetByID(id uint) (*models.Report, error) {
 	return &report, err
 }
 
+// GetByIDs получает отчеты по списку ID; отсутствующие ID пропускаются
+func (r *ReportRepository) GetByIDs(ids []uint) ([]models.Report, error) {
+	var reports []models.Report
+	err := r.db.Where("id IN ?", ids).Find(&reports).Error
+	return reports, err
+}
+
 // GetAll получает все отчеты с пагинацией
 func (r *ReportRepository) GetAll(page, limit int, status string) ([]models.Report, int64, error) {
 	var reports []models.Report
@@ -73,6 +95,35 @@ func (r *ReportRepository) GetReportsWithPagination(page, limit int, userID uint
 	return reports, total, err
 }
 
+// GetAllByUserID получает все отчеты пользователя в порядке создания
+func (r *ReportRepository) GetAllByUserID(userID uint) ([]models.Report, error) {
+	var reports []models.Report
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&reports).Error
+	return reports, err
+}
+
+// CountActiveByTemplateID считает отчеты шаблона, кроме неудачных и отмененных
+func (r *ReportRepository) CountActiveByTemplateID(templateID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Report{}).
+		Where("template_id = ? AND status NOT IN ?", templateID, []models.ReportStatus{models.StatusFailed, models.StatusCancelled}).
+		Count(&count).Error
+	return count, err
+}
+
+// GetTemplateUsageStats возвращает число отчетов шаблона и время создания последнего из них
+func (r *ReportRepository) GetTemplateUsageStats(templateID uint) (int64, *time.Time, error) {
+	var stats struct {
+		Total    int64
+		LastUsed *time.Time
+	}
+	err := r.db.Model(&models.Report{}).
+		Select("COUNT(*) AS total, MAX(created_at) AS last_used").
+		Where("template_id = ?", templateID).
+		Scan(&stats).Error
+	return stats.Total, stats.LastUsed, err
+}
+
 // UpdateFilePath обновляет путь к файлу отчета
 func (r *ReportRepository) UpdateFilePath(id uint, filePath string, fileSize int64, md5Hash string) error {
 	return r.db.Model(&models.Report{}).Where("id = ?", id).Updates(map[string]interface{}{
@@ -87,12 +138,152 @@ func (r *ReportRepository) UpdateStatus(id uint, status string) error {
 	return r.db.Model(&models.Report{}).Where("id = ?", id).Update("status", status).Error
 }
 
+// CompleteWithExpiry переводит отчет в completed и назначает момент удаления его файла
+func (r *ReportRepository) CompleteWithExpiry(id uint, expiresAt time.Time) error {
+	return r.db.Model(&models.Report{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.StatusCompleted,
+		"expires_at": expiresAt,
+	}).Error
+}
+
+// FindExpired получает готовые отчеты, срок хранения которых истек к моменту now
+func (r *ReportRepository) FindExpired(now time.Time, limit int) ([]models.Report, error) {
+	var reports []models.Report
+	err := r.db.Where("status = ? AND expires_at <= ?", models.StatusCompleted, now).
+		Order("expires_at").
+		Limit(limit).
+		Find(&reports).Error
+	return reports, err
+}
+
+// MarkExpired переводит готовый отчет в expired и очищает сведения о файле.
+// Возвращает false, если отчет уже не в статусе completed (например, запущена перегенерация).
+func (r *ReportRepository) MarkExpired(id uint) (bool, error) {
+	result := r.db.Model(&models.Report{}).
+		Where("id = ? AND status = ?", id, models.StatusCompleted).
+		Updates(map[string]interface{}{
+			"status":    models.StatusExpired,
+			"file_path": "",
+			"file_size": 0,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateNotificationStatus сохраняет статус доставки уведомления об отчете
+func (r *ReportRepository) UpdateNotificationStatus(id uint, status string, notifiedAt time.Time) error {
+	return r.db.Model(&models.Report{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"notification_status": status,
+		"notified_at":         notifiedAt,
+	}).Error
+}
+
+// UpdateGenerationMetadata сохраняет число использованных записей и длительность генерации
+func (r *ReportRepository) UpdateGenerationMetadata(id uint, recordCount int, durationMs int64) error {
+	return r.db.Model(&models.Report{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"record_count":           recordCount,
+		"generation_duration_ms": durationMs,
+	}).Error
+}
+
+// StartRegeneration атомарно переводит отчет из статуса from в processing с новой версией.
+// Возвращает false, если статус отчета уже изменился, например генерацию запустил параллельный запрос.
+func (r *ReportRepository) StartRegeneration(id uint, from string) (bool, error) {
+	result := r.db.Model(&models.Report{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]interface{}{
+			"status":     models.StatusProcessing,
+			"version":    gorm.Expr("version + 1"),
+			"file_path":  "",
+			"file_size":  0,
+			"md5_hash":   "",
+			"expires_at": nil,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
 // Update обновляет отчет
 func (r *ReportRepository) Update(report *models.Report) error {
 	return r.db.Save(report).Error
 }
 
+// UpdateParametersIfStatus заменяет параметры отчета, только если он в указанном статусе.
+// Возвращает false, если статус отчета уже изменился.
+func (r *ReportRepository) UpdateParametersIfStatus(id uint, parameters, status string) (bool, error) {
+	result := r.db.Model(&models.Report{}).
+		Where("id = ? AND status = ?", id, status).
+		Update("parameters", parameters)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // Delete удаляет отчет (мягкое удаление)
 func (r *ReportRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Report{}, id).Error
 }
+
+// ReportShareRepository репозиторий публичных ссылок на отчеты
+type ReportShareRepository struct {
+	db *gorm.DB
+}
+
+// NewReportShareRepository создает новый репозиторий ссылок
+func NewReportShareRepository(db *gorm.DB) *ReportShareRepository {
+	return &ReportShareRepository{db: db}
+}
+
+// Create создает ссылку
+func (r *ReportShareRepository) Create(share *models.ReportShare) error {
+	return r.db.Create(share).Error
+}
+
+// GetByID получает ссылку по ID
+func (r *ReportShareRepository) GetByID(id uint) (*models.ReportShare, error) {
+	var share models.ReportShare
+	err := r.db.First(&share, id).Error
+	return &share, err
+}
+
+// Revoke отзывает ссылку отчета. Возвращает false, если активная ссылка не найдена.
+func (r *ReportShareRepository) Revoke(id, reportID uint, revokedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.ReportShare{}).
+		Where("id = ? AND report_id = ? AND revoked_at IS NULL", id, reportID).
+		Update("revoked_at", revokedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReportGenerationLockRepository репозиторий блокировок генерации отчетов
+type ReportGenerationLockRepository struct {
+	db *gorm.DB
+}
+
+// NewReportGenerationLockRepository создает новый репозиторий блокировок
+func NewReportGenerationLockRepository(db *gorm.DB) *ReportGenerationLockRepository {
+	return &ReportGenerationLockRepository{db: db}
+}
+
+// Acquire захватывает блокировку отчета на ttl одним запросом: запись создается,
+// если ее нет, или перезаписывается, если прежняя блокировка истекла.
+// Возвращает false, если блокировку держит другая генерация.
+func (r *ReportGenerationLockRepository) Acquire(reportID uint, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	lock := models.ReportGenerationLock{
+		ReportID:  reportID,
+		LockedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "report_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"locked_at", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "report_generation_locks.expires_at < ?", Vars: []interface{}{now}},
+		}},
+	}).Create(&lock)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Release снимает блокировку отчета
+func (r *ReportGenerationLockRepository) Release(reportID uint) error {
+	return r.db.Delete(&models.ReportGenerationLock{}, reportID).Error
+}
@@ -6,9 +6,12 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"report-service/internal/audit"
+	"report-service/internal/clients"
 	"report-service/internal/config"
 	"report-service/internal/database"
 	"report-service/internal/events"
@@ -18,6 +21,9 @@ import (
 	"report-service/internal/middleware"
 	"report-service/internal/repository"
 	"report-service/internal/services"
+	"report-service/internal/settings"
+	"report-service/internal/sharing"
+	"report-service/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -39,6 +45,14 @@ func NewServer(cfg *config.Config) *Server {
 func (s *Server) Start() error {
 	// Настройка логгера
 	s.cfg.SetupLogger()
+	handlers.SetDefaultPageLimit(s.cfg.DefaultPageLimit)
+	handlers.SetMaxPageLimit(s.cfg.MaxPageLimit)
+
+	sagaIDGenerator, err := events.NewSagaIDGenerator(s.cfg.SagaIDGenerator)
+	if err != nil {
+		return fmt.Errorf("некорректный SAGA_ID_GENERATOR: %w", err)
+	}
+	events.SetSagaIDGenerator(sagaIDGenerator)
 
 	// Автомиграция если включена
 	if s.cfg.AutoMigrate {
@@ -48,25 +62,25 @@ func (s *Server) Start() error {
 	}
 
 	// Подключение к базе данных
-	db, err := database.Connect(s.cfg)
+	db, err := database.ConnectWithRetry(s.cfg)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
 
 	// Инициализация зависимостей
 	reportRepo := repository.NewReportRepository(db)
-	reportService := services.NewReportService(reportRepo)
+	outboxManager := events.NewOutboxManager(db)
+	reportService := services.NewReportService(reportRepo, repository.NewReportGenerationLockRepository(db), outboxManager, s.cfg.HideForeignReports, s.cfg.GenerationLockTTL, s.cfg.ReportTTL)
 	jwtManager := jwt.NewManager(s.cfg.JWTSecret)
 	metricsManager := metrics.NewMetrics("report-service")
 
 	// Инициализация Saga компонентов
 	sagaStateStore := events.NewSagaStateStore(db)
-	outboxManager := events.NewOutboxManager(db)
 
 	// Создание RabbitMQ publisher (если URL указан)
 	var eventPublisher events.EventPublisher
 	if s.cfg.RabbitMQURL != "" {
-		rabbitPublisher, err := events.NewRabbitMQPublisher(s.cfg.RabbitMQURL)
+		rabbitPublisher, err := events.NewRabbitMQPublisher(s.cfg.RabbitMQURL, metricsManager)
 		if err != nil {
 			logrus.WithError(err).Warn("Не удалось подключиться к RabbitMQ, используем локальную публикацию")
 			eventPublisher = &events.LocalEventPublisher{}
@@ -78,14 +92,32 @@ func (s *Server) Start() error {
 		eventPublisher = &events.LocalEventPublisher{}
 	}
 
+	retryPolicy := clients.RetryPolicy{
+		Attempts:   s.cfg.HTTPRetryAttempts,
+		Backoff:    s.cfg.HTTPRetryBackoff,
+		MaxBackoff: s.cfg.HTTPRetryMaxBackoff,
+	}
+
+	storageClient := clients.NewStorageClient(s.cfg.StorageServiceURL, retryPolicy)
+
 	// Создание идемпотентного Saga Coordinator
-	sagaStepHandler := handlers.NewSagaStepHandler(reportService, eventPublisher)
-	sagaCoordinator := events.NewIdempotentSagaCoordinator(eventPublisher, sagaStateStore, sagaStepHandler, metricsManager)
+	sagaStepHandler := handlers.NewSagaStepHandler(reportService, eventPublisher, clients.NewTemplateClient(s.cfg.TemplateServiceURL, retryPolicy, s.cfg.TemplateCacheTTL), storageClient, jwtManager)
+	sagaCoordinator := events.NewIdempotentSagaCoordinator(eventPublisher, sagaStateStore, sagaStepHandler, metricsManager, s.cfg.SagaMaxSteps)
+
+	// Настройки, изменяемые без перезапуска через /admin/settings
+	settingsStore := settings.NewStore(db, map[string]string{
+		settings.OutboxBatchSize: strconv.Itoa(s.cfg.OutboxBatchSize),
+	})
+	settingsCtx, stopSettings := context.WithCancel(context.Background())
+	defer stopSettings()
+	go settingsStore.Start(settingsCtx, s.cfg.SettingsRefreshInterval)
 
 	// Запуск Outbox Publisher для надежной публикации событий
 	if outboxManager != nil {
 		outboxPublisher := events.NewOutboxPublisher(outboxManager, eventPublisher)
-		go outboxPublisher.StartPublishing(context.Background(), 1*time.Second, 10)
+		go outboxPublisher.StartPublishing(context.Background(), 1*time.Second, func() int {
+			return settingsStore.Int(settings.OutboxBatchSize, s.cfg.OutboxBatchSize)
+		})
 	}
 
 	// Миграция Saga таблиц
@@ -98,8 +130,61 @@ func (s *Server) Start() error {
 		}
 	}
 
+	// Подписка на события доставки уведомлений от notification-service
+	if s.cfg.RabbitMQURL != "" {
+		subscriber, err := events.NewRabbitMQSubscriber(s.cfg.RabbitMQURL, metricsManager)
+		if err != nil {
+			logrus.WithError(err).Warn("Не удалось подписаться на события уведомлений")
+		} else {
+			defer subscriber.Close()
+			for _, eventType := range []events.EventType{events.NotificationDelivered, events.NotificationFailed} {
+				handler := handlers.NewNotificationEventHandler(eventType, reportService, sagaStateStore)
+				if err := subscriber.Subscribe(context.Background(), eventType, handler); err != nil {
+					logrus.WithError(err).Warnf("Ошибка подписки на событие %s", eventType)
+				}
+			}
+		}
+	}
+
+	// Пул воркеров ограничивает число одновременно выполняемых Saga
+	sagaPool := events.NewSagaWorkerPool(s.cfg.SagaWorkers, s.cfg.SagaQueueSize)
+	sagaPool.Start()
+
+	// Повтор или компенсация Saga, зависших в статусе executing
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	defer stopMonitor()
+	if s.cfg.SagaStaleThreshold > 0 {
+		staleMonitor := events.NewStaleSagaMonitor(sagaCoordinator, sagaStateStore, sagaPool, reportService, s.cfg.SagaStaleThreshold, s.cfg.SagaStaleMaxRetries)
+		go staleMonitor.Start(monitorCtx, s.cfg.SagaStaleCheckInterval)
+	}
+
+	// Размер очереди Outbox в метриках
+	if s.cfg.OutboxMetricsInterval > 0 {
+		outboxMonitor := events.NewOutboxBacklogMonitor(outboxManager, metricsManager)
+		go outboxMonitor.Start(monitorCtx, s.cfg.OutboxMetricsInterval)
+	}
+
+	// Публичные ссылки на отчеты
+	shareSecret := s.cfg.ShareSecret
+	if shareSecret == "" {
+		shareSecret = s.cfg.JWTSecret
+	}
+	shareService := services.NewShareService(reportService, repository.NewReportShareRepository(db), sharing.NewSigner(shareSecret), s.cfg.ShareLinkTTL)
+
+	detailService := services.NewDetailService(reportService, clients.NewTemplateClient(s.cfg.TemplateServiceURL, retryPolicy, 0), storageClient, clients.NewNotificationClient(s.cfg.NotificationServiceURL, retryPolicy), sagaCoordinator)
+	exportService := services.NewExportService(reportService, storageClient)
+
+	// Удаление файлов отчетов с истекшим сроком хранения
+	if s.cfg.ReportExpirationInterval > 0 {
+		expirationJob := services.NewReportExpirationJob(reportService, storageClient)
+		go expirationJob.Start(monitorCtx, s.cfg.ReportExpirationInterval)
+	}
+
+	// Журнал аудита изменений отчетов
+	auditLog := audit.NewLogger(db)
+
 	// Создание роутера
-	router := s.setupRouter(reportService, jwtManager, sagaCoordinator, sagaStateStore, metricsManager)
+	router := s.setupRouter(reportService, shareService, storageClient, detailService, exportService, auditLog, settingsStore, jwtManager, sagaCoordinator, sagaStateStore, sagaStepHandler, sagaPool, metricsManager)
 
 	// Создание HTTP сервера
 	srv := &http.Server{
@@ -122,19 +207,25 @@ func (s *Server) Start() error {
 	logrus.Info("Остановка Report Service...")
 
 	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
 		return fmt.Errorf("принудительная остановка сервера: %w", err)
 	}
 
+	// Дожидаемся выполняющихся Saga в пределах оставшегося SHUTDOWN_TIMEOUT
+	stopMonitor()
+	if err := sagaPool.Stop(ctx); err != nil {
+		logrus.WithError(err).Warn("Не все Saga завершились до истечения SHUTDOWN_TIMEOUT, выполнение прервано")
+	}
+
 	logrus.Info("Report Service остановлен")
 	return nil
 }
 
 // setupRouter настраивает маршруты и middleware
-func (s *Server) setupRouter(reportService *services.ReportService, jwtManager *jwt.Manager, sagaCoordinator *events.IdempotentSagaCoordinator, sagaStateStore *events.SagaStateStore, metricsManager *metrics.Metrics) *gin.Engine {
+func (s *Server) setupRouter(reportService *services.ReportService, shareService *services.ShareService, storageClient *clients.StorageClient, detailService *services.DetailService, exportService *services.ExportService, auditLog *audit.Logger, settingsStore *settings.Store, jwtManager *jwt.Manager, sagaCoordinator *events.IdempotentSagaCoordinator, sagaStateStore *events.SagaStateStore, sagaStepHandler *handlers.SagaStepHandler, sagaPool *events.SagaWorkerPool, metricsManager *metrics.Metrics) *gin.Engine {
 	router := gin.Default()
 
 	// Инициализация метрик
@@ -145,63 +236,103 @@ func (s *Server) setupRouter(reportService *services.ReportService, jwtManager *
 	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS())
 	router.Use(middleware.RequestID())
+	router.Use(middleware.RequireJSON())
 
 	// Инициализация обработчиков
-	reportHandler := handlers.NewReportHandler(reportService, sagaCoordinator, metricsManager)
-	sagaHandler := handlers.NewSagaHandler(sagaCoordinator, sagaStateStore)
+	reportHandler := handlers.NewReportHandler(reportService, sagaCoordinator, sagaPool, metricsManager, auditLog)
+	sagaHandler := handlers.NewSagaHandler(sagaCoordinator, sagaStateStore, sagaPool, reportService, sagaStepHandler, s.cfg.SagaRetention, s.cfg.SagaCleanupBatchSize, s.cfg.SagaStreamPollInterval)
+	shareHandler := handlers.NewShareHandler(shareService, storageClient, s.cfg.PublicBaseURL)
+	detailHandler := handlers.NewDetailHandler(detailService)
+	exportHandler := handlers.NewExportHandler(exportService)
 
 	// Настройка маршрутов
-	s.setupRoutes(router, reportHandler, sagaHandler, jwtManager)
+	auditHandler := handlers.NewAuditHandler(auditLog)
+	settingsHandler := handlers.NewSettingsHandler(settingsStore)
+
+	s.setupRoutes(router, reportHandler, sagaHandler, shareHandler, detailHandler, exportHandler, auditHandler, settingsHandler, jwtManager, middleware.NewAPITokenVerifier(s.cfg.UserServiceURL, s.cfg.APITokenCacheTTL))
 
 	return router
 }
 
 // setupRoutes настраивает маршруты API
-func (s *Server) setupRoutes(router *gin.Engine, reportHandler *handlers.ReportHandler, sagaHandler *handlers.SagaHandler, jwtManager *jwt.Manager) {
+func (s *Server) setupRoutes(router *gin.Engine, reportHandler *handlers.ReportHandler, sagaHandler *handlers.SagaHandler, shareHandler *handlers.ShareHandler, detailHandler *handlers.DetailHandler, exportHandler *handlers.ExportHandler, auditHandler *handlers.AuditHandler, settingsHandler *handlers.SettingsHandler, jwtManager *jwt.Manager, apiTokens *middleware.APITokenVerifier) {
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
-			"service":   "report-service",
-			"timestamp": time.Now().Unix(),
-		})
+		c.JSON(http.StatusOK, version.Health("report-service"))
 	})
 
 	api := router.Group("/api/v1")
 	{
+		// Публичное скачивание по подписанной ссылке
+		api.GET("/reports/shared", shareHandler.GetSharedReport)
+
 		// Защищенные маршруты (требуют аутентификации)
 		protected := api.Group("/reports")
-		protected.Use(middleware.Auth(jwtManager))
+		protected.Use(middleware.Auth(jwtManager, apiTokens))
 		{
-			protected.POST("/", reportHandler.CreateReport)
-			protected.GET("/", reportHandler.GetReports)
-			protected.GET("/:id", reportHandler.GetReport)
-			protected.GET("/:id/status", reportHandler.GetReportStatus)
-			protected.PUT("/:id", reportHandler.UpdateReport)
-			protected.DELETE("/:id", reportHandler.DeleteReport)
-			protected.POST("/generate", reportHandler.GenerateReport)
-			protected.GET("/:id/download", reportHandler.DownloadReport)
-			protected.GET("/:id/export/csv", reportHandler.ExportReportCSV)
+			// Пакетный запрос статусов только читает отчеты
+			protected.POST("/status/batch", middleware.RequireReadScope("reports"), reportHandler.GetReportStatuses)
+
+			reports := protected.Group("", middleware.RequireScope("reports"))
+			reports.POST("/", reportHandler.CreateReport)
+			reports.GET("/", reportHandler.GetReports)
+			reports.GET("/export/all", metrics.Buckets(metrics.ExportBuckets), exportHandler.ExportAllReports)
+			reports.GET("/:id", reportHandler.GetReport)
+			reports.GET("/:id/status", reportHandler.GetReportStatus)
+			reports.GET("/:id/detail", detailHandler.GetReportDetail)
+			reports.GET("/:id/trace", detailHandler.GetReportTrace)
+			reports.PUT("/:id", reportHandler.UpdateReport)
+			reports.PUT("/:id/parameters", reportHandler.UpdateReportParameters)
+			reports.DELETE("/:id", reportHandler.DeleteReport)
+			reports.POST("/:id/generate", reportHandler.GenerateReport)
+			reports.POST("/:id/retry", reportHandler.RetryReport)
+			reports.GET("/:id/download", reportHandler.DownloadReport)
+			reports.GET("/:id/export/csv", metrics.Buckets(metrics.ExportBuckets), reportHandler.ExportReportCSV)
+			reports.POST("/:id/share", shareHandler.CreateShare)
+			reports.DELETE("/:id/share/:shareId", shareHandler.RevokeShare)
+		}
+
+		// Использование шаблонов отчетами (для template-service)
+		templates := api.Group("/templates")
+		templates.Use(middleware.Auth(jwtManager, apiTokens), middleware.RequireScope("reports"))
+		{
+			templates.GET("/:id/usage", reportHandler.GetTemplateUsage)
 		}
 
 		// Saga маршруты
 		saga := api.Group("/sagas")
-		saga.Use(middleware.Auth(jwtManager))
+		saga.Use(middleware.Auth(jwtManager, apiTokens), middleware.RequireScope("reports"))
 		{
 			saga.POST("/reports", sagaHandler.CreateReportSaga)
+			saga.POST("/reports/batch-notify", sagaHandler.CreateBatchRenderNotifySaga)
+			saga.GET("/capabilities", sagaHandler.GetCapabilities)
 			saga.GET("/:id", sagaHandler.GetSagaStatus)
 			saga.GET("/:id/progress", sagaHandler.GetSagaProgress)
+			saga.GET("/:id/stream", sagaHandler.StreamSagaProgress)
+			saga.GET("/:id/export", middleware.Role("admin"), metrics.Buckets(metrics.ExportBuckets), sagaHandler.ExportSaga)
+			saga.GET("/:id/steps/:stepId", sagaHandler.GetSagaStep)
+			saga.POST("/:id/steps/:stepId/retry", sagaHandler.RetrySagaStep)
 			saga.POST("/:id/retry", sagaHandler.RetrySaga)
 			saga.DELETE("/:id", sagaHandler.CancelSaga)
 			saga.POST("/:id/force-complete", sagaHandler.ForceCompleteSaga)
 			saga.GET("/", sagaHandler.ListSagas)
 		}
+
+		// Журнал аудита и обслуживание Saga (только для администраторов)
+		admin := api.Group("/admin")
+		admin.Use(middleware.Auth(jwtManager, apiTokens), middleware.Role("admin"), middleware.RequireScope("reports"))
+		{
+			admin.GET("/audit", auditHandler.GetAuditLogs)
+			admin.POST("/sagas/cleanup", sagaHandler.CleanupSagas)
+			admin.GET("/settings/:key", settingsHandler.GetSetting)
+			admin.PUT("/settings/:key", settingsHandler.UpdateSetting)
+		}
 	}
 }
 
 // migrate выполняет миграции базы данных
 func (s *Server) migrate() error {
-	_, err := database.Connect(s.cfg)
+	_, err := database.ConnectWithRetry(s.cfg)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
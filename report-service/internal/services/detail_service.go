@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"report-service/internal/clients"
+	"report-service/internal/events"
+	"report-service/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DetailService собирает сводную информацию об отчете из нескольких сервисов
+type DetailService struct {
+	reportService      *ReportService
+	templateClient     *clients.TemplateClient
+	storageClient      *clients.StorageClient
+	notificationClient *clients.NotificationClient
+	sagaCoordinator    *events.IdempotentSagaCoordinator
+}
+
+// NewDetailService создает новый сервис сводной информации
+func NewDetailService(reportService *ReportService, templateClient *clients.TemplateClient, storageClient *clients.StorageClient, notificationClient *clients.NotificationClient, sagaCoordinator *events.IdempotentSagaCoordinator) *DetailService {
+	return &DetailService{
+		reportService:      reportService,
+		templateClient:     templateClient,
+		storageClient:      storageClient,
+		notificationClient: notificationClient,
+		sagaCoordinator:    sagaCoordinator,
+	}
+}
+
+// GetReportDetail возвращает отчет, его шаблон и файл. Ошибки соседних сервисов
+// не прерывают запрос: соответствующая часть отсутствует, а ответ помечается как частичный.
+func (s *DetailService) GetReportDetail(ctx context.Context, id, userID uint, authHeader string) (*models.ReportDetailResponse, error) {
+	report, err := s.reportService.getOwnedReport(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &models.ReportDetailResponse{Report: report.ToResponse()}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	warn := func(message string, err error) {
+		logrus.WithError(err).Warnf("Отчет %d: %s", id, message)
+		mu.Lock()
+		detail.Warnings = append(detail.Warnings, message)
+		mu.Unlock()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		template, err := s.templateClient.GetTemplate(ctx, report.TemplateID, authHeader)
+		if err != nil {
+			if errors.Is(err, clients.ErrNotFound) {
+				warn("шаблон отчета не найден", err)
+			} else {
+				warn("template-service недоступен", err)
+			}
+			return
+		}
+		mu.Lock()
+		detail.Template = &models.ReportTemplateInfo{ID: template.ID, Name: template.Name, Type: template.Type}
+		detail.TemplateAvailable = true
+		mu.Unlock()
+	}()
+
+	// Файл есть только у сгенерированного отчета
+	if report.MD5Hash != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			file, err := s.storageClient.GetFileByHash(ctx, report.MD5Hash, authHeader)
+			if err != nil {
+				if errors.Is(err, clients.ErrNotFound) {
+					warn("файл отчета не найден в хранилище", err)
+				} else {
+					warn("storage-service недоступен", err)
+				}
+				return
+			}
+			mu.Lock()
+			detail.File = &models.ReportFileInfo{
+				ID:          file.ID,
+				Size:        file.Size,
+				MimeType:    file.MimeType,
+				DownloadURL: s.storageClient.DownloadURL(file.ID),
+			}
+			detail.FileAvailable = true
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	detail.Partial = len(detail.Warnings) > 0
+	return detail, nil
+}
+
+// GetReportTrace собирает записи, связанные с отчетом сквозным correlation_id: Saga
+// генерации, файлы storage-service и уведомления notification-service. Как и в
+// GetReportDetail, ошибки соседних сервисов только помечают ответ как частичный.
+func (s *DetailService) GetReportTrace(ctx context.Context, id, userID uint, authHeader string) (*models.ReportTraceResponse, error) {
+	report, err := s.reportService.getOwnedReport(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := &models.ReportTraceResponse{
+		CorrelationID: report.CorrelationID,
+		Report:        report.ToResponse(),
+		Files:         []models.ReportFileInfo{},
+		Notifications: []models.ReportTraceNotification{},
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	warn := func(message string, err error) {
+		logrus.WithError(err).Warnf("Трассировка отчета %d: %s", id, message)
+		mu.Lock()
+		trace.Warnings = append(trace.Warnings, message)
+		mu.Unlock()
+	}
+	addFile := func(file *clients.FileInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, existing := range trace.Files {
+			if existing.ID == file.ID {
+				return
+			}
+		}
+		trace.Files = append(trace.Files, models.ReportFileInfo{
+			ID:          file.ID,
+			Size:        file.Size,
+			MimeType:    file.MimeType,
+			DownloadURL: s.storageClient.DownloadURL(file.ID),
+		})
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		saga, err := s.sagaCoordinator.FindReportSaga(ctx, report.ID)
+		if err != nil {
+			warn("Saga отчета не найдена", err)
+			return
+		}
+		traceSaga := &models.ReportTraceSaga{
+			ID:        saga.ID,
+			Status:    string(saga.Status),
+			Error:     saga.Error,
+			Steps:     make([]models.ReportTraceStep, len(saga.Steps)),
+			CreatedAt: saga.CreatedAt,
+		}
+		for i, step := range saga.Steps {
+			traceSaga.Steps[i] = models.ReportTraceStep{
+				ID:          step.ID,
+				Name:        step.Name,
+				Status:      string(step.Status),
+				Error:       step.Error,
+				CompletedAt: step.CompletedAt,
+			}
+		}
+		mu.Lock()
+		trace.Saga = traceSaga
+		mu.Unlock()
+	}()
+
+	// Отчеты, созданные до появления correlation_id, связаны только через хеш файла
+	if report.CorrelationID != "" {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			files, err := s.storageClient.FindFilesByCorrelationID(ctx, report.CorrelationID, authHeader)
+			if err != nil {
+				warn("storage-service недоступен", err)
+				return
+			}
+			for i := range files {
+				addFile(&files[i])
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			notifications, err := s.notificationClient.FindByCorrelationID(ctx, report.CorrelationID, authHeader)
+			if err != nil {
+				warn("notification-service недоступен", err)
+				return
+			}
+			mu.Lock()
+			for _, n := range notifications {
+				trace.Notifications = append(trace.Notifications, models.ReportTraceNotification{
+					ID:           n.ID,
+					Recipient:    n.Recipient,
+					Type:         n.Type,
+					Status:       n.Status,
+					ErrorMessage: n.ErrorMessage,
+					SentAt:       n.SentAt,
+					DeliveredAt:  n.DeliveredAt,
+					CreatedAt:    n.CreatedAt,
+				})
+			}
+			mu.Unlock()
+		}()
+	}
+
+	if report.MD5Hash != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			file, err := s.storageClient.GetFileByHash(ctx, report.MD5Hash, authHeader)
+			if err != nil {
+				if !errors.Is(err, clients.ErrNotFound) {
+					warn("storage-service недоступен", err)
+				}
+				return
+			}
+			addFile(file)
+		}()
+	}
+
+	wg.Wait()
+	trace.Partial = len(trace.Warnings) > 0
+	return trace, nil
+}
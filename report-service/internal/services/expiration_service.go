@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"report-service/internal/clients"
+	"report-service/internal/models"
+	"report-service/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReportExpirationJob удаляет из хранилища файлы отчетов с истекшим сроком хранения
+// и переводит такие отчеты в статус expired
+type ReportExpirationJob struct {
+	reportRepo    *repository.ReportRepository
+	storageClient *clients.StorageClient
+	batchSize     int
+}
+
+// NewReportExpirationJob создает фоновую задачу удаления просроченных отчетов
+func NewReportExpirationJob(reportService *ReportService, storageClient *clients.StorageClient) *ReportExpirationJob {
+	return &ReportExpirationJob{
+		reportRepo:    reportService.reportRepo,
+		storageClient: storageClient,
+		batchSize:     100,
+	}
+}
+
+// Start периодически удаляет просроченные отчеты до отмены контекста
+func (j *ReportExpirationJob) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("Остановка удаления просроченных отчетов")
+			return
+		case <-ticker.C:
+			if expired := j.ExpireReports(ctx, time.Now()); expired > 0 {
+				logrus.Infof("Удалены файлы просроченных отчетов: %d", expired)
+			}
+		}
+	}
+}
+
+// ExpireReports обрабатывает отчеты, срок хранения которых истек к моменту now, и возвращает
+// число переведенных в expired. Отчет, файл которого не удалось удалить, остается completed
+// и обрабатывается при следующем запуске.
+func (j *ReportExpirationJob) ExpireReports(ctx context.Context, now time.Time) int {
+	reports, err := j.reportRepo.FindExpired(now, j.batchSize)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка поиска просроченных отчетов")
+		return 0
+	}
+
+	expired := 0
+	for i := range reports {
+		report := &reports[i]
+		if err := j.deleteFiles(ctx, report); err != nil {
+			logrus.WithError(err).Warnf("Отчет %d: не удалось удалить файл из хранилища", report.ID)
+			continue
+		}
+
+		marked, err := j.reportRepo.MarkExpired(report.ID)
+		if err != nil {
+			logrus.WithError(err).Errorf("Отчет %d: ошибка перевода в статус expired", report.ID)
+			continue
+		}
+		if marked {
+			expired++
+		}
+	}
+	return expired
+}
+
+// deleteFiles удаляет файлы отчета: все файлы со сквозным ID отчета или, если его нет, файл по MD5
+func (j *ReportExpirationJob) deleteFiles(ctx context.Context, report *models.Report) error {
+	var files []clients.FileInfo
+	switch {
+	case report.CorrelationID != "":
+		found, err := j.storageClient.FindFilesByCorrelationID(ctx, report.CorrelationID, "")
+		if err != nil && !errors.Is(err, clients.ErrNotFound) {
+			return err
+		}
+		files = found
+	case report.MD5Hash != "":
+		file, err := j.storageClient.GetFileByHash(ctx, report.MD5Hash, "")
+		if err != nil && !errors.Is(err, clients.ErrNotFound) {
+			return err
+		}
+		if file != nil {
+			files = append(files, *file)
+		}
+	}
+
+	for _, file := range files {
+		if err := j.storageClient.DeleteFile(ctx, file.ID, ""); err != nil {
+			return fmt.Errorf("файл %d: %w", file.ID, err)
+		}
+	}
+	return nil
+}
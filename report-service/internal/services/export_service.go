@@ -0,0 +1,149 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"report-service/internal/apperrors"
+	"report-service/internal/clients"
+	"report-service/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// manifestFileName имя файла с метаданными внутри архива
+const manifestFileName = "manifest.json"
+
+// ExportService выгружает отчеты пользователя одним ZIP архивом
+type ExportService struct {
+	reportService *ReportService
+	storageClient *clients.StorageClient
+}
+
+// NewExportService создает новый сервис выгрузки отчетов
+func NewExportService(reportService *ReportService, storageClient *clients.StorageClient) *ExportService {
+	return &ExportService{
+		reportService: reportService,
+		storageClient: storageClient,
+	}
+}
+
+// ListUserReports возвращает отчеты пользователя для выгрузки. Вызывается до начала
+// записи ответа, чтобы ошибки базы данных можно было вернуть обычным статусом.
+func (s *ExportService) ListUserReports(userID uint) ([]models.Report, error) {
+	reports, err := s.reportService.reportRepo.GetAllByUserID(userID)
+	if err != nil {
+		return nil, apperrors.Internal(fmt.Errorf("ошибка получения отчетов пользователя: %w", err))
+	}
+	return reports, nil
+}
+
+// WriteArchive потоково пишет в w ZIP архив с файлами готовых отчетов и manifest.json.
+// Неготовые отчеты и файлы, которые не удалось скачать, попадают только в манифест с пояснением.
+// Даты манифеста записываются в часовом поясе loc (UTC при nil).
+func (s *ExportService) WriteArchive(ctx context.Context, userID uint, reports []models.Report, authHeader string, loc *time.Location, w io.Writer) error {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	archive := zip.NewWriter(w)
+	manifest := models.ReportExportManifest{
+		UserID:      userID,
+		GeneratedAt: time.Now().In(loc),
+		Reports:     make([]models.ReportExportManifestEntry, 0, len(reports)),
+	}
+
+	for _, report := range reports {
+		// Сервис отдает архив только владельцу, но проверяем на случай ошибки выборки
+		if report.UserID != userID {
+			continue
+		}
+
+		entry := models.ReportExportManifestEntry{
+			ID:         report.ID,
+			Name:       report.Name,
+			Status:     report.Status,
+			TemplateID: report.TemplateID,
+			CreatedAt:  report.CreatedAt.In(loc),
+		}
+
+		switch {
+		case report.Status != string(models.StatusCompleted):
+			entry.Note = fmt.Sprintf("отчет не готов (статус %s)", report.Status)
+		case report.MD5Hash == "":
+			entry.Note = "у отчета нет файла"
+		default:
+			if err := s.addReportFile(ctx, archive, &report, authHeader, &entry); err != nil {
+				// Запись в архив прервана, продолжать бессмысленно
+				return err
+			}
+		}
+
+		manifest.Reports = append(manifest.Reports, entry)
+	}
+
+	manifestWriter, err := archive.Create(manifestFileName)
+	if err != nil {
+		return fmt.Errorf("ошибка записи манифеста: %w", err)
+	}
+	encoder := json.NewEncoder(manifestWriter)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("ошибка записи манифеста: %w", err)
+	}
+
+	return archive.Close()
+}
+
+// addReportFile скачивает файл отчета из storage-service прямо в архив.
+// Ошибки storage-service отражаются в манифесте, ошибка записи в архив возвращается.
+func (s *ExportService) addReportFile(ctx context.Context, archive *zip.Writer, report *models.Report, authHeader string, entry *models.ReportExportManifestEntry) error {
+	file, err := s.storageClient.GetFileByHash(ctx, report.MD5Hash, authHeader)
+	if err != nil {
+		logrus.WithError(err).Warnf("Отчет %d: файл не получен из хранилища", report.ID)
+		if errors.Is(err, clients.ErrNotFound) {
+			entry.Note = "файл отчета не найден в хранилище"
+		} else {
+			entry.Note = "storage-service недоступен"
+		}
+		return nil
+	}
+
+	fileName := file.Name
+	if fileName == "" {
+		fileName = report.FilePath
+	}
+	name := fmt.Sprintf("reports/%d_%s", report.ID, path.Base(fileName))
+	fileWriter, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: report.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка записи в архив: %w", err)
+	}
+
+	// Ошибку при частично записанном файле исправить нельзя: архив уже отправляется клиенту
+	size, err := s.storageClient.DownloadFile(ctx, file.ID, authHeader, fileWriter)
+	if err != nil {
+		if size > 0 {
+			return fmt.Errorf("отчет %d: %w", report.ID, err)
+		}
+		logrus.WithError(err).Warnf("Отчет %d: файл не скачан", report.ID)
+		entry.File = name
+		entry.Note = "файл не удалось скачать, в архиве пустая запись"
+		return nil
+	}
+
+	entry.File = name
+	entry.FileSize = size
+	entry.MD5Hash = report.MD5Hash
+	entry.Included = true
+	return nil
+}
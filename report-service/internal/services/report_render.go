@@ -0,0 +1,150 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"time"
+
+	"report-service/internal/apperrors"
+	"report-service/internal/models"
+)
+
+// renderReport записывает отчет в файл выбранного формата с колонками выгрузки
+func renderReport(w io.Writer, format models.ReportFormat, report *models.Report, columns []csvColumn, loc *time.Location) error {
+	switch format {
+	case models.FormatCSV:
+		return writeReportCSV(w, report, columns, loc)
+	case models.FormatHTML:
+		return writeReportHTML(w, report, columns, loc)
+	case models.FormatXLSX:
+		return writeReportXLSX(w, report, columns, loc)
+	default:
+		return apperrors.Validation("Неподдерживаемый формат отчета: " + string(format))
+	}
+}
+
+// reportHTMLTemplate страница отчета: таблица «поле — значение»
+var reportHTMLTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+{{- range .Rows}}
+<tr><th>{{.Header}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// writeReportHTML записывает отчет HTML-страницей, значения экранируются шаблоном
+func writeReportHTML(w io.Writer, report *models.Report, columns []csvColumn, loc *time.Location) error {
+	type row struct{ Header, Value string }
+	data := struct {
+		Title string
+		Rows  []row
+	}{Title: report.Name}
+	for _, column := range columns {
+		data.Rows = append(data.Rows, row{Header: column.header, Value: column.value(report, loc)})
+	}
+
+	if err := reportHTMLTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("ошибка формирования HTML отчета: %w", err)
+	}
+	return nil
+}
+
+// xlsxStaticParts части книги XLSX, не зависящие от содержимого отчета
+var xlsxStaticParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Report" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxSheet лист книги XLSX со строками из встроенных строк (inlineStr)
+type xlsxSheet struct {
+	XMLName xml.Name  `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main worksheet"`
+	Rows    []xlsxRow `xml:"sheetData>row"`
+}
+
+type xlsxRow struct {
+	Index int        `xml:"r,attr"`
+	Cells []xlsxCell `xml:"c"`
+}
+
+type xlsxCell struct {
+	Ref  string `xml:"r,attr"`
+	Type string `xml:"t,attr"`
+	Text string `xml:"is>t"`
+}
+
+// writeReportXLSX записывает отчет книгой XLSX: строка заголовков и строка значений
+func writeReportXLSX(w io.Writer, report *models.Report, columns []csvColumn, loc *time.Location) error {
+	sheet := xlsxSheet{Rows: []xlsxRow{{Index: 1}, {Index: 2}}}
+	for i, column := range columns {
+		col := xlsxColumnName(i)
+		sheet.Rows[0].Cells = append(sheet.Rows[0].Cells, xlsxCell{Ref: col + "1", Type: "inlineStr", Text: column.header})
+		sheet.Rows[1].Cells = append(sheet.Rows[1].Cells, xlsxCell{Ref: col + "2", Type: "inlineStr", Text: column.value(report, loc)})
+	}
+
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxStaticParts {
+		entry, err := archive.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("ошибка формирования XLSX отчета: %w", err)
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return fmt.Errorf("ошибка формирования XLSX отчета: %w", err)
+		}
+	}
+
+	entry, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return fmt.Errorf("ошибка формирования XLSX отчета: %w", err)
+	}
+	if _, err := io.WriteString(entry, xml.Header); err != nil {
+		return fmt.Errorf("ошибка формирования XLSX отчета: %w", err)
+	}
+	if err := xml.NewEncoder(entry).Encode(sheet); err != nil {
+		return fmt.Errorf("ошибка формирования XLSX отчета: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("ошибка формирования XLSX отчета: %w", err)
+	}
+	return nil
+}
+
+// xlsxColumnName буквенное обозначение колонки по номеру с 0: A, B, ..., Z, AA
+func xlsxColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// reportFileName имя файла отчета: расширение определяется форматом
+func reportFileName(report *models.Report) string {
+	return "report_" + strconv.FormatUint(uint64(report.ID), 10) + models.ReportFormat(report.Format).Extension()
+}
@@ -1,28 +1,69 @@
 package services
 
 import (
-	"encoding/csv"
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
 
+	"report-service/internal/apperrors"
+	"report-service/internal/csvutil"
+	"report-service/internal/events"
 	"report-service/internal/models"
 	"report-service/internal/repository"
 
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 // ReportService сервис для работы с отчетами
 type ReportService struct {
 	reportRepo *repository.ReportRepository
+	lockRepo   *repository.ReportGenerationLockRepository
+	outbox     *events.OutboxManager
+	// hideForeignReports отвечает 404 на чужие отчеты, чтобы не раскрывать их существование
+	hideForeignReports bool
+	// generationLockTTL время жизни блокировки генерации, если Saga не сняла ее сама
+	generationLockTTL time.Duration
+	// reportTTL срок хранения файла отчета, если он не задан в запросе; 0 — бессрочно
+	reportTTL time.Duration
 }
 
 // NewReportService создает новый сервис отчетов
-func NewReportService(reportRepo *repository.ReportRepository) *ReportService {
+func NewReportService(reportRepo *repository.ReportRepository, lockRepo *repository.ReportGenerationLockRepository, outbox *events.OutboxManager, hideForeignReports bool, generationLockTTL, reportTTL time.Duration) *ReportService {
 	return &ReportService{
-		reportRepo: reportRepo,
+		reportRepo:         reportRepo,
+		lockRepo:           lockRepo,
+		outbox:             outbox,
+		hideForeignReports: hideForeignReports,
+		generationLockTTL:  generationLockTTL,
+		reportTTL:          reportTTL,
+	}
+}
+
+// AcquireGenerationLock захватывает блокировку генерации отчета.
+// Если генерация отчета уже выполняется, возвращается Conflict.
+func (s *ReportService) AcquireGenerationLock(id uint) error {
+	acquired, err := s.lockRepo.Acquire(id, s.generationLockTTL)
+	if err != nil {
+		return fmt.Errorf("ошибка блокировки генерации отчета: %w", err)
+	}
+	if !acquired {
+		return apperrors.Conflict("генерация отчета уже выполняется")
+	}
+	return nil
+}
+
+// ReleaseGenerationLock снимает блокировку генерации отчета
+func (s *ReportService) ReleaseGenerationLock(id uint) {
+	if err := s.lockRepo.Release(id); err != nil {
+		logrus.WithError(err).Warnf("Не удалось снять блокировку генерации отчета %d", id)
 	}
 }
 
@@ -30,20 +71,55 @@ func NewReportService(reportRepo *repository.ReportRepository) *ReportService {
 func (s *ReportService) CreateReport(userID uint, req *models.ReportCreateRequest) (*models.ReportResponse, error) {
 	// Проверяем валидность статуса
 	if req.TemplateID == 0 {
-		return nil, errors.New("ID шаблона обязателен")
+		return nil, apperrors.Validation("ID шаблона обязателен")
 	}
 
-	// Создаем новый отчет
-	report := &models.Report{
-		Name:        req.Name,
-		Description: req.Description,
-		TemplateID:  req.TemplateID,
-		UserID:      userID,
-		Status:      string(models.StatusPending),
-		Parameters:  req.Parameters,
+	format := models.ReportFormat(strings.ToLower(strings.TrimSpace(req.Format)))
+	if format == "" {
+		format = models.DefaultReportFormat
+	}
+	if !format.IsValid() {
+		return nil, apperrors.Validation("Неподдерживаемый формат отчета: " + req.Format)
+	}
+
+	correlationID := req.CorrelationID
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+	}
+
+	ttlSeconds := req.TTLSeconds
+	if ttlSeconds == 0 {
+		ttlSeconds = int64(s.reportTTL / time.Second)
 	}
 
-	if err := s.reportRepo.Create(report); err != nil {
+	// Создаем новый отчет
+	report := &models.Report{
+		Name:          req.Name,
+		Description:   req.Description,
+		TemplateID:    req.TemplateID,
+		UserID:        userID,
+		Status:        string(models.StatusPending),
+		Parameters:    req.Parameters,
+		Format:        string(format),
+		CorrelationID: correlationID,
+		TTLSeconds:    ttlSeconds,
+	}
+
+	// Отчет и событие report.created сохраняются атомарно, событие публикует Outbox Publisher
+	err := s.reportRepo.CreateWithTx(report, func(tx *gorm.DB) error {
+		event, err := events.NewEvent(events.ReportCreated, "report-service", map[string]interface{}{
+			"report_id":   strconv.FormatUint(uint64(report.ID), 10),
+			"user_id":     strconv.FormatUint(uint64(report.UserID), 10),
+			"template_id": strconv.FormatUint(uint64(report.TemplateID), 10),
+			"format":      report.Format,
+		})
+		if err != nil {
+			return err
+		}
+		event.WithCorrelationID(report.CorrelationID)
+		return s.outbox.WithTx(tx).SaveEvent(context.Background(), event)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("ошибка создания отчета: %w", err)
 	}
 
@@ -73,17 +149,9 @@ func (s *ReportService) GetReports(userID uint, status string, page, limit int)
 
 // GetReport получает отчет по ID
 func (s *ReportService) GetReport(id uint, userID uint) (*models.ReportResponse, error) {
-	report, err := s.reportRepo.GetByID(id)
+	report, err := s.getOwnedReport(id, userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("отчет не найден")
-		}
-		return nil, fmt.Errorf("ошибка получения отчета: %w", err)
-	}
-
-	// Проверяем, что отчет принадлежит пользователю
-	if report.UserID != userID {
-		return nil, errors.New("доступ запрещен")
+		return nil, err
 	}
 
 	response := report.ToResponse()
@@ -92,17 +160,9 @@ func (s *ReportService) GetReport(id uint, userID uint) (*models.ReportResponse,
 
 // UpdateReport обновляет отчет
 func (s *ReportService) UpdateReport(id uint, userID uint, req *models.ReportUpdateRequest) (*models.ReportResponse, error) {
-	report, err := s.reportRepo.GetByID(id)
+	report, err := s.getOwnedReport(id, userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("отчет не найден")
-		}
-		return nil, fmt.Errorf("ошибка получения отчета: %w", err)
-	}
-
-	// Проверяем, что отчет принадлежит пользователю
-	if report.UserID != userID {
-		return nil, errors.New("доступ запрещен")
+		return nil, err
 	}
 
 	// Обновляем поля
@@ -113,6 +173,17 @@ func (s *ReportService) UpdateReport(id uint, userID uint, req *models.ReportUpd
 		report.Description = req.Description
 	}
 	if req.Status != "" {
+		// expired назначается только задачей удаления просроченных отчетов вместе с удалением файла
+		if req.Status == string(models.StatusExpired) {
+			return nil, apperrors.Validation("статус expired назначается автоматически")
+		}
+		if err := validateStatusTransition(report.Status, req.Status); err != nil {
+			return nil, err
+		}
+		if req.Status == string(models.StatusCompleted) && report.Status != req.Status && report.TTLSeconds > 0 {
+			expiresAt := time.Now().Add(time.Duration(report.TTLSeconds) * time.Second)
+			report.ExpiresAt = &expiresAt
+		}
 		report.Status = req.Status
 	}
 	if req.Parameters != "" {
@@ -127,19 +198,45 @@ func (s *ReportService) UpdateReport(id uint, userID uint, req *models.ReportUpd
 	return &response, nil
 }
 
-// DeleteReport удаляет отчет
-func (s *ReportService) DeleteReport(id uint, userID uint) error {
-	report, err := s.reportRepo.GetByID(id)
+// ReplaceReportParameters заменяет параметры отчета, пока генерация не начата
+func (s *ReportService) ReplaceReportParameters(id uint, userID uint, parameters map[string]interface{}) (*models.ReportResponse, error) {
+	report, err := s.getOwnedReport(id, userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("отчет не найден")
+		return nil, err
+	}
+
+	if report.Status != string(models.StatusPending) {
+		return nil, apperrors.Conflict("параметры можно менять только у отчета в статусе pending").WithDetails(map[string]string{
+			"status": report.Status,
+		})
+	}
+
+	encoded := ""
+	if len(parameters) > 0 {
+		data, err := json.Marshal(parameters)
+		if err != nil {
+			return nil, apperrors.Validation("Некорректные параметры отчета").WithDetails(err.Error())
 		}
-		return fmt.Errorf("ошибка получения отчета: %w", err)
+		encoded = string(data)
 	}
 
-	// Проверяем, что отчет принадлежит пользователю
-	if report.UserID != userID {
-		return errors.New("доступ запрещен")
+	updated, err := s.reportRepo.UpdateParametersIfStatus(id, encoded, string(models.StatusPending))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка обновления параметров отчета: %w", err)
+	}
+	if !updated {
+		return nil, apperrors.Conflict("статус отчета изменился, параметры не обновлены")
+	}
+
+	report.Parameters = encoded
+	response := report.ToResponse()
+	return &response, nil
+}
+
+// DeleteReport удаляет отчет
+func (s *ReportService) DeleteReport(id uint, userID uint) error {
+	if _, err := s.getOwnedReport(id, userID); err != nil {
+		return err
 	}
 
 	if err := s.reportRepo.Delete(id); err != nil {
@@ -151,12 +248,72 @@ func (s *ReportService) DeleteReport(id uint, userID uint) error {
 
 // UpdateReportStatus обновляет статус отчета
 func (s *ReportService) UpdateReportStatus(id uint, status string) error {
+	report, err := s.reportRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.NotFound("отчет не найден")
+		}
+		return fmt.Errorf("ошибка получения отчета: %w", err)
+	}
+
+	if err := validateStatusTransition(report.Status, status); err != nil {
+		return err
+	}
+
+	// Срок хранения файла отсчитывается от завершения генерации
+	if status == string(models.StatusCompleted) && report.TTLSeconds > 0 {
+		expiresAt := time.Now().Add(time.Duration(report.TTLSeconds) * time.Second)
+		if err := s.reportRepo.CompleteWithExpiry(id, expiresAt); err != nil {
+			return fmt.Errorf("ошибка обновления статуса: %w", err)
+		}
+		return nil
+	}
+
 	if err := s.reportRepo.UpdateStatus(id, status); err != nil {
 		return fmt.Errorf("ошибка обновления статуса: %w", err)
 	}
 	return nil
 }
 
+// GetTemplateUsage возвращает число активных отчетов по шаблону
+func (s *ReportService) GetTemplateUsage(templateID uint) (*models.TemplateUsageResponse, error) {
+	count, err := s.reportRepo.CountActiveByTemplateID(templateID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подсчета отчетов шаблона: %w", err)
+	}
+
+	total, lastUsed, err := s.reportRepo.GetTemplateUsageStats(templateID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подсчета отчетов шаблона: %w", err)
+	}
+
+	return &models.TemplateUsageResponse{
+		TemplateID:    templateID,
+		ActiveReports: count,
+		TotalReports:  total,
+		LastUsedAt:    lastUsed,
+	}, nil
+}
+
+// RecordNotificationDelivery фиксирует результат доставки уведомления об отчете
+func (s *ReportService) RecordNotificationDelivery(id uint, status string, notifiedAt time.Time) error {
+	if status != models.NotificationStatusDelivered && status != models.NotificationStatusFailed {
+		return apperrors.Validation("некорректный статус доставки уведомления")
+	}
+
+	if _, err := s.reportRepo.GetByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.NotFound("отчет не найден")
+		}
+		return fmt.Errorf("ошибка получения отчета: %w", err)
+	}
+
+	if err := s.reportRepo.UpdateNotificationStatus(id, status, notifiedAt); err != nil {
+		return fmt.Errorf("ошибка обновления статуса уведомления: %w", err)
+	}
+	return nil
+}
+
 // UpdateReportFilePath обновляет путь к файлу отчета
 func (s *ReportService) UpdateReportFilePath(id uint, filePath string, fileSize int64, md5Hash string) error {
 	if err := s.reportRepo.UpdateFilePath(id, filePath, fileSize, md5Hash); err != nil {
@@ -165,19 +322,60 @@ func (s *ReportService) UpdateReportFilePath(id uint, filePath string, fileSize
 	return nil
 }
 
+// UpdateReportMetadata сохраняет метаданные генерации отчета
+func (s *ReportService) UpdateReportMetadata(id uint, recordCount int, duration time.Duration) error {
+	if err := s.reportRepo.UpdateGenerationMetadata(id, recordCount, duration.Milliseconds()); err != nil {
+		return fmt.Errorf("ошибка сохранения метаданных отчета: %w", err)
+	}
+	return nil
+}
+
 // GenerateReport генерирует отчет
-func (s *ReportService) GenerateReport(id uint, userID uint, req *models.ReportGenerateRequest) (*models.ReportResponse, error) {
-	report, err := s.reportRepo.GetByID(id)
+// Блокировка генерации остается захваченной при успехе и снимается вызывающим после завершения Saga.
+func (s *ReportService) GenerateReport(id uint, userID uint, req *models.ReportGenerateRequest) (_ *models.ReportResponse, err error) {
+	report, err := s.getOwnedReport(id, userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("отчет не найден")
+		return nil, err
+	}
+
+	if err := s.AcquireGenerationLock(id); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			s.ReleaseGenerationLock(id)
 		}
-		return nil, fmt.Errorf("ошибка получения отчета: %w", err)
+	}()
+
+	if report.Status == string(models.StatusProcessing) {
+		return nil, apperrors.Conflict("генерация отчета уже выполняется")
 	}
 
-	// Проверяем, что отчет принадлежит пользователю
-	if report.UserID != userID {
-		return nil, errors.New("доступ запрещен")
+	// Принудительная перегенерация создает новую версию завершенного или неудачного отчета
+	if req.Force {
+		if !models.ReportStatus(report.Status).CanRegenerate() {
+			return nil, statusTransitionConflict(report.Status, string(models.StatusProcessing))
+		}
+
+		started, err := s.reportRepo.StartRegeneration(id, report.Status)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка перезапуска генерации: %w", err)
+		}
+		if !started {
+			return nil, apperrors.Conflict("генерация отчета уже выполняется")
+		}
+
+		report, err = s.reportRepo.GetByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения отчета: %w", err)
+		}
+
+		response := report.ToResponse()
+		return &response, nil
+	}
+
+	if err := validateStatusTransition(report.Status, string(models.StatusProcessing)); err != nil {
+		return nil, err
 	}
 
 	// Обновляем статус на processing
@@ -192,98 +390,256 @@ func (s *ReportService) GenerateReport(id uint, userID uint, req *models.ReportG
 	return &response, nil
 }
 
+// RetryReport возвращает неудачный отчет в статус pending для повторного запуска Saga.
+// Как и в GenerateReport, захваченная блокировка генерации снимается вызывающим.
+func (s *ReportService) RetryReport(id uint, userID uint) (*models.ReportResponse, error) {
+	report, err := s.getOwnedReport(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Status != string(models.StatusFailed) {
+		return nil, apperrors.Conflict("повторить можно только отчет в статусе failed").WithDetails(map[string]string{
+			"status": report.Status,
+		})
+	}
+
+	if err := s.AcquireGenerationLock(id); err != nil {
+		return nil, err
+	}
+	if err := s.reportRepo.UpdateStatus(id, string(models.StatusPending)); err != nil {
+		s.ReleaseGenerationLock(id)
+		return nil, fmt.Errorf("ошибка обновления статуса: %w", err)
+	}
+
+	report.Status = string(models.StatusPending)
+	response := report.ToResponse()
+	return &response, nil
+}
+
 // DownloadReport возвращает информацию для скачивания отчета
 func (s *ReportService) DownloadReport(id uint, userID uint) (*models.ReportResponse, error) {
-	report, err := s.reportRepo.GetByID(id)
+	report, err := s.getOwnedReport(id, userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("отчет не найден")
+		return nil, err
+	}
+
+	if report.Status == string(models.StatusExpired) {
+		return nil, apperrors.Gone("срок хранения отчета истек, файл удален")
+	}
+
+	// Проверяем, что отчет готов
+	if report.Status != string(models.StatusCompleted) {
+		return nil, apperrors.Conflict("отчет еще не готов")
+	}
+
+	response := report.ToResponse()
+	return &response, nil
+}
+
+// csvColumn колонка CSV-выгрузки отчета
+type csvColumn struct {
+	name   string // имя для параметра columns
+	header string
+	value  func(r *models.Report, loc *time.Location) string // время форматируется в зоне loc
+}
+
+// reportCSVColumns допустимые колонки CSV-выгрузки в порядке по умолчанию
+var reportCSVColumns = []csvColumn{
+	{"id", "ID", func(r *models.Report, loc *time.Location) string { return strconv.FormatUint(uint64(r.ID), 10) }},
+	{"name", "Name", func(r *models.Report, loc *time.Location) string { return r.Name }},
+	{"description", "Description", func(r *models.Report, loc *time.Location) string { return r.Description }},
+	{"template_id", "Template ID", func(r *models.Report, loc *time.Location) string { return strconv.FormatUint(uint64(r.TemplateID), 10) }},
+	{"user_id", "User ID", func(r *models.Report, loc *time.Location) string { return strconv.FormatUint(uint64(r.UserID), 10) }},
+	{"status", "Status", func(r *models.Report, loc *time.Location) string { return r.Status }},
+	{"parameters", "Parameters", func(r *models.Report, loc *time.Location) string { return r.Parameters }},
+	{"file_path", "File Path", func(r *models.Report, loc *time.Location) string { return r.FilePath }},
+	{"file_size", "File Size", func(r *models.Report, loc *time.Location) string { return strconv.FormatInt(r.FileSize, 10) }},
+	{"md5_hash", "MD5 Hash", func(r *models.Report, loc *time.Location) string { return r.MD5Hash }},
+	{"created_at", "Created At", func(r *models.Report, loc *time.Location) string { return r.CreatedAt.In(loc).Format(time.RFC3339) }},
+	{"updated_at", "Updated At", func(r *models.Report, loc *time.Location) string { return r.UpdatedAt.In(loc).Format(time.RFC3339) }},
+}
+
+// selectCSVColumns возвращает запрошенные колонки в указанном порядке или все при пустом списке
+func selectCSVColumns(names []string) ([]csvColumn, error) {
+	if len(names) == 0 {
+		return reportCSVColumns, nil
+	}
+
+	allowed := make([]string, len(reportCSVColumns))
+	for i, column := range reportCSVColumns {
+		allowed[i] = column.name
+	}
+
+	selected := make([]csvColumn, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, column := range reportCSVColumns {
+			if column.name == name {
+				selected = append(selected, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, apperrors.Validation(fmt.Sprintf("неизвестная колонка %q", name)).WithDetails(map[string]string{
+				"allowed_columns": strings.Join(allowed, ","),
+			})
 		}
-		return nil, fmt.Errorf("ошибка получения отчета: %w", err)
 	}
+	return selected, nil
+}
 
-	// Проверяем, что отчет принадлежит пользователю
-	if report.UserID != userID {
-		return nil, errors.New("доступ запрещен")
+// ExportReportToCSV экспортирует отчет в формат CSV и возвращает его вместе с названием отчета.
+// opts ограничивает набор колонок для предпросмотра.
+func (s *ReportService) ExportReportToCSV(id uint, userID uint, opts models.ReportCSVOptions) (string, string, error) {
+	columns, err := selectCSVColumns(opts.Columns)
+	if err != nil {
+		return "", "", err
+	}
+
+	report, err := s.getOwnedReport(id, userID)
+	if err != nil {
+		return "", "", err
 	}
 
 	// Проверяем, что отчет готов
 	if report.Status != string(models.StatusCompleted) {
-		return nil, errors.New("отчет еще не готов")
+		return "", "", apperrors.Conflict("отчет еще не готов")
+	}
+
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var csvData strings.Builder
+	if err := writeReportCSV(&csvData, report, columns, loc); err != nil {
+		return "", "", err
+	}
+
+	return csvData.String(), report.Name, nil
+}
+
+// RenderReportFile формирует файл отчета, который Saga сохраняет в storage-service:
+// поля отчета с колонками выгрузки по умолчанию и временем в UTC в формате, выбранном
+// при создании отчета. Возвращает имя файла с расширением формата и содержимое.
+func (s *ReportService) RenderReportFile(id uint) (string, []byte, error) {
+	report, err := s.reportRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, apperrors.NotFound("отчет не найден")
+		}
+		return "", nil, fmt.Errorf("ошибка получения отчета: %w", err)
+	}
+
+	var content bytes.Buffer
+	if err := renderReport(&content, models.ReportFormat(report.Format), report, reportCSVColumns, time.UTC); err != nil {
+		return "", nil, err
+	}
+	return reportFileName(report), content.Bytes(), nil
+}
+
+// writeReportCSV записывает отчет в CSV с выбранными колонками: строка заголовков и строка значений
+func writeReportCSV(w io.Writer, report *models.Report, columns []csvColumn, loc *time.Location) error {
+	headers := make([]string, len(columns))
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.header
+		record[i] = column.value(report, loc)
+	}
+
+	return csvutil.WriteRecords(w, headers, [][]string{record})
+}
+
+// GetReportByID получает отчет без проверки владельца (для служебных выгрузок)
+func (s *ReportService) GetReportByID(id uint) (*models.ReportResponse, error) {
+	report, err := s.reportRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("отчет не найден")
+		}
+		return nil, fmt.Errorf("ошибка получения отчета: %w", err)
 	}
 
 	response := report.ToResponse()
 	return &response, nil
 }
 
-// ExportReportToCSV экспортирует отчет в формат CSV
-func (s *ReportService) ExportReportToCSV(id uint, userID uint) (string, error) {
+// GetReportsForStatus получает отчеты пользователя по списку ID для пакетного запроса статусов.
+// Повторные ID учитываются один раз, порядок найденных отчетов совпадает с порядком в запросе.
+func (s *ReportService) GetReportsForStatus(ids []uint, userID uint) (reports []models.ReportResponse, notFound, forbidden []uint, err error) {
+	found, err := s.reportRepo.GetByIDs(ids)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("ошибка получения отчетов: %w", err)
+	}
+
+	byID := make(map[uint]*models.Report, len(found))
+	for i := range found {
+		byID[found[i].ID] = &found[i]
+	}
+
+	notFound = []uint{}
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		report, ok := byID[id]
+		switch {
+		case !ok, report.UserID != userID && s.hideForeignReports:
+			notFound = append(notFound, id)
+		case report.UserID != userID:
+			forbidden = append(forbidden, id)
+		default:
+			reports = append(reports, report.ToResponse())
+		}
+	}
+
+	return reports, notFound, forbidden, nil
+}
+
+// getOwnedReport получает отчет, доступный пользователю
+func (s *ReportService) getOwnedReport(id uint, userID uint) (*models.Report, error) {
 	report, err := s.reportRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", errors.New("отчет не найден")
+			return nil, apperrors.NotFound("отчет не найден")
 		}
-		return "", fmt.Errorf("ошибка получения отчета: %w", err)
+		return nil, fmt.Errorf("ошибка получения отчета: %w", err)
 	}
 
 	// Проверяем, что отчет принадлежит пользователю
 	if report.UserID != userID {
-		return "", errors.New("доступ запрещен")
+		if s.hideForeignReports {
+			return nil, apperrors.NotFound("отчет не найден")
+		}
+		return nil, apperrors.Forbidden("доступ запрещен")
 	}
 
-	// Проверяем, что отчет готов
-	if report.Status != string(models.StatusCompleted) {
-		return "", errors.New("отчет еще не готов")
+	return report, nil
+}
+
+// validateStatusTransition проверяет допустимость смены статуса отчета
+func validateStatusTransition(current, next string) error {
+	nextStatus := models.ReportStatus(next)
+	if !nextStatus.IsValid() {
+		return apperrors.Validation("некорректный статус отчета").WithDetails(map[string]string{"status": next})
 	}
 
-	// Создаем CSV данные
-	var csvData strings.Builder
-	writer := csv.NewWriter(&csvData)
-
-	// Заголовки CSV
-	headers := []string{
-		"ID",
-		"Name",
-		"Description",
-		"Template ID",
-		"User ID",
-		"Status",
-		"Parameters",
-		"File Path",
-		"File Size",
-		"MD5 Hash",
-		"Created At",
-		"Updated At",
-	}
-
-	if err := writer.Write(headers); err != nil {
-		return "", fmt.Errorf("ошибка записи заголовков CSV: %w", err)
-	}
-
-	// Данные отчета
-	record := []string{
-		strconv.FormatUint(uint64(report.ID), 10),
-		report.Name,
-		report.Description,
-		strconv.FormatUint(uint64(report.TemplateID), 10),
-		strconv.FormatUint(uint64(report.UserID), 10),
-		report.Status,
-		report.Parameters,
-		report.FilePath,
-		strconv.FormatInt(report.FileSize, 10),
-		report.MD5Hash,
-		report.CreatedAt.Format(time.RFC3339),
-		report.UpdatedAt.Format(time.RFC3339),
-	}
-
-	if err := writer.Write(record); err != nil {
-		return "", fmt.Errorf("ошибка записи данных CSV: %w", err)
-	}
-
-	writer.Flush()
-	if err := writer.Error(); err != nil {
-		return "", fmt.Errorf("ошибка записи CSV: %w", err)
-	}
-
-	return csvData.String(), nil
+	if !models.ReportStatus(current).CanTransitionTo(nextStatus) {
+		return statusTransitionConflict(current, next)
+	}
+
+	return nil
+}
+
+// statusTransitionConflict ошибка недопустимой смены статуса отчета
+func statusTransitionConflict(current, next string) *apperrors.AppError {
+	return apperrors.Conflict("недопустимая смена статуса отчета").WithDetails(map[string]string{
+		"from": current,
+		"to":   next,
+	})
 }
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+
+	"report-service/internal/apperrors"
+	"report-service/internal/models"
+)
+
+func TestValidateStatusTransition(t *testing.T) {
+	tests := []struct {
+		from, to models.ReportStatus
+		want     int // 0 — переход допустим, иначе HTTP статус ошибки
+	}{
+		{models.StatusPending, models.StatusProcessing, 0},
+		{models.StatusPending, models.StatusFailed, 0},
+		{models.StatusPending, models.StatusCancelled, 0},
+		{models.StatusProcessing, models.StatusCompleted, 0},
+		{models.StatusProcessing, models.StatusFailed, 0},
+		{models.StatusProcessing, models.StatusCancelled, 0},
+		{models.StatusFailed, models.StatusPending, 0},
+		{models.StatusCompleted, models.StatusExpired, 0},
+		{models.StatusCompleted, models.StatusCompleted, 0},
+
+		{models.StatusCompleted, models.StatusPending, http.StatusConflict},
+		{models.StatusCompleted, models.StatusProcessing, http.StatusConflict},
+		{models.StatusPending, models.StatusCompleted, http.StatusConflict},
+		{models.StatusFailed, models.StatusCompleted, http.StatusConflict},
+		{models.StatusCancelled, models.StatusPending, http.StatusConflict},
+		{models.StatusExpired, models.StatusCompleted, http.StatusConflict},
+
+		{models.StatusPending, "archived", http.StatusBadRequest},
+		{models.StatusProcessing, "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			err := validateStatusTransition(string(tt.from), string(tt.to))
+			if tt.want == 0 {
+				if err != nil {
+					t.Fatalf("переход отклонен: %v", err)
+				}
+				return
+			}
+			if !apperrors.HasStatus(err, tt.want) {
+				t.Fatalf("ожидалась ошибка со статусом %d, получено %v", tt.want, err)
+			}
+		})
+	}
+}
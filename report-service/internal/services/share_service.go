@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"report-service/internal/apperrors"
+	"report-service/internal/models"
+	"report-service/internal/repository"
+	"report-service/internal/sharing"
+
+	"gorm.io/gorm"
+)
+
+// ShareService сервис публичных ссылок на скачивание отчетов
+type ShareService struct {
+	reportService *ReportService
+	shareRepo     *repository.ReportShareRepository
+	signer        *sharing.Signer
+	defaultTTL    time.Duration
+}
+
+// NewShareService создает новый сервис ссылок
+func NewShareService(reportService *ReportService, shareRepo *repository.ReportShareRepository, signer *sharing.Signer, defaultTTL time.Duration) *ShareService {
+	return &ShareService{
+		reportService: reportService,
+		shareRepo:     shareRepo,
+		signer:        signer,
+		defaultTTL:    defaultTTL,
+	}
+}
+
+// CreateShare создает подписанную ссылку на готовый отчет пользователя
+func (s *ShareService) CreateShare(reportID, userID uint, ttl time.Duration) (*models.ReportShare, string, error) {
+	report, err := s.reportService.getOwnedReport(reportID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if report.Status != string(models.StatusCompleted) {
+		return nil, "", apperrors.Conflict("отчет еще не готов")
+	}
+
+	if ttl <= 0 {
+		ttl = s.defaultTTL
+	}
+
+	share := &models.ReportShare{
+		ReportID:  report.ID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ttl).Truncate(time.Second),
+	}
+	if err := s.shareRepo.Create(share); err != nil {
+		return nil, "", fmt.Errorf("ошибка создания ссылки: %w", err)
+	}
+
+	return share, s.signer.Sign(share.ID, share.ExpiresAt), nil
+}
+
+// RevokeShare отзывает ссылку на отчет пользователя
+func (s *ShareService) RevokeShare(reportID, shareID, userID uint) error {
+	if _, err := s.reportService.getOwnedReport(reportID, userID); err != nil {
+		return err
+	}
+
+	revoked, err := s.shareRepo.Revoke(shareID, reportID, time.Now())
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва ссылки: %w", err)
+	}
+	if !revoked {
+		return apperrors.NotFound("ссылка не найдена")
+	}
+	return nil
+}
+
+// ResolveShare проверяет токен и возвращает отчет, на который он ссылается
+func (s *ShareService) ResolveShare(token string) (*models.Report, error) {
+	now := time.Now()
+	shareID, err := s.signer.Verify(token, now)
+	if err != nil {
+		if errors.Is(err, sharing.ErrTokenExpired) {
+			return nil, apperrors.Forbidden(err.Error())
+		}
+		return nil, apperrors.Unauthorized(err.Error())
+	}
+
+	share, err := s.shareRepo.GetByID(shareID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("ссылка не найдена")
+		}
+		return nil, fmt.Errorf("ошибка получения ссылки: %w", err)
+	}
+	if !share.IsActive(now) {
+		return nil, apperrors.Forbidden("ссылка отозвана или истекла")
+	}
+
+	report, err := s.reportService.reportRepo.GetByID(share.ReportID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NotFound("отчет не найден")
+		}
+		return nil, fmt.Errorf("ошибка получения отчета: %w", err)
+	}
+	if report.Status != string(models.StatusCompleted) {
+		return nil, apperrors.Conflict("отчет еще не готов")
+	}
+
+	return report, nil
+}
@@ -0,0 +1,177 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Ключи настроек, которые читает сервис
+const (
+	// OutboxBatchSize число событий Outbox, публикуемых за один проход
+	OutboxBatchSize = "outbox.batch_size"
+)
+
+// ErrUnknownSetting возвращается для ключа, которого нет в списке настроек сервиса
+var ErrUnknownSetting = errors.New("неизвестная настройка")
+
+// ErrInvalidValue возвращается, если значение не подходит для настройки
+var ErrInvalidValue = errors.New("некорректное значение настройки")
+
+// positiveIntSettings настройки, значение которых должно быть положительным целым
+var positiveIntSettings = map[string]bool{
+	OutboxBatchSize: true,
+}
+
+// Setting настройка сервиса, изменяемая без перезапуска
+type Setting struct {
+	Key       string    `gorm:"primaryKey"`
+	Value     string    `gorm:"type:text;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// TableName возвращает имя таблицы
+func (Setting) TableName() string {
+	return "settings"
+}
+
+// SettingResponse настройка в ответе API; для незаданной настройки
+// возвращается значение по умолчанию с default: true
+type SettingResponse struct {
+	Key       string     `json:"key"`
+	Value     string     `json:"value"`
+	Default   bool       `json:"default"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// SettingUpdateRequest тело запроса изменения настройки
+type SettingUpdateRequest struct {
+	Value string `json:"value" binding:"required"`
+}
+
+// Store хранит настройки в базе и кеширует их в памяти. Кеш обновляется
+// периодически, поэтому изменения с других реплик применяются с задержкой
+// не больше интервала обновления.
+type Store struct {
+	db       *gorm.DB
+	defaults map[string]string
+
+	mu     sync.RWMutex
+	values map[string]Setting
+}
+
+// NewStore создает хранилище настроек. defaults задает допустимые ключи
+// и их значения, пока настройка не изменена через API
+func NewStore(db *gorm.DB, defaults map[string]string) *Store {
+	return &Store{
+		db:       db,
+		defaults: defaults,
+		values:   make(map[string]Setting),
+	}
+}
+
+// Start периодически перечитывает настройки из базы до отмены ctx
+func (s *Store) Start(ctx context.Context, interval time.Duration) {
+	if err := s.Refresh(ctx); err != nil {
+		logrus.WithError(err).Warn("Не удалось загрузить настройки")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				logrus.WithError(err).Warn("Не удалось обновить настройки")
+			}
+		}
+	}
+}
+
+// Refresh загружает все настройки из базы в кеш
+func (s *Store) Refresh(ctx context.Context) error {
+	var rows []Setting
+	if err := s.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return fmt.Errorf("ошибка получения настроек: %w", err)
+	}
+
+	values := make(map[string]Setting, len(rows))
+	for _, row := range rows {
+		values[row.Key] = row
+	}
+
+	s.mu.Lock()
+	s.values = values
+	s.mu.Unlock()
+	return nil
+}
+
+// Get возвращает настройку из кеша или ее значение по умолчанию
+func (s *Store) Get(key string) (*SettingResponse, error) {
+	defaultValue, ok := s.defaults[key]
+	if !ok {
+		return nil, ErrUnknownSetting
+	}
+
+	s.mu.RLock()
+	setting, found := s.values[key]
+	s.mu.RUnlock()
+
+	if !found {
+		return &SettingResponse{Key: key, Value: defaultValue, Default: true}, nil
+	}
+	updatedAt := setting.UpdatedAt
+	return &SettingResponse{Key: key, Value: setting.Value, UpdatedAt: &updatedAt}, nil
+}
+
+// Int возвращает целочисленное значение настройки; при отсутствии ключа
+// или нечисловом значении возвращается fallback
+func (s *Store) Int(key string, fallback int) int {
+	setting, err := s.Get(key)
+	if err != nil {
+		return fallback
+	}
+	value, err := strconv.Atoi(setting.Value)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Set сохраняет значение настройки и сразу обновляет кеш
+func (s *Store) Set(ctx context.Context, key, value string) (*SettingResponse, error) {
+	if _, ok := s.defaults[key]; !ok {
+		return nil, ErrUnknownSetting
+	}
+	if positiveIntSettings[key] {
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+			return nil, fmt.Errorf("%w: ожидается положительное целое число", ErrInvalidValue)
+		}
+	}
+
+	setting := Setting{Key: key, Value: value, UpdatedAt: time.Now()}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&setting).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения настройки: %w", err)
+	}
+
+	s.mu.Lock()
+	s.values[key] = setting
+	s.mu.Unlock()
+
+	updatedAt := setting.UpdatedAt
+	return &SettingResponse{Key: key, Value: value, UpdatedAt: &updatedAt}, nil
+}
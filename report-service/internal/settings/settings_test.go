@@ -0,0 +1,151 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB создает отдельную SQLite базу с таблицей настроек
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "settings.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&Setting{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+	return db
+}
+
+// newTestStore создает хранилище с единственной настройкой outbox.batch_size = 100
+func newTestStore(db *gorm.DB) *Store {
+	return NewStore(db, map[string]string{OutboxBatchSize: "100"})
+}
+
+func TestStoreReturnsDefaults(t *testing.T) {
+	store := newTestStore(newTestDB(t))
+
+	setting, err := store.Get(OutboxBatchSize)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if setting.Value != "100" || !setting.Default || setting.UpdatedAt != nil {
+		t.Errorf("настройка %+v, ожидалось значение по умолчанию 100", setting)
+	}
+	if got := store.Int(OutboxBatchSize, 10); got != 100 {
+		t.Errorf("Int = %d, ожидалось 100", got)
+	}
+
+	if _, err := store.Get("outbox.interval"); !errors.Is(err, ErrUnknownSetting) {
+		t.Errorf("неизвестный ключ: ошибка %v, ожидалась ErrUnknownSetting", err)
+	}
+	if got := store.Int("outbox.interval", 10); got != 10 {
+		t.Errorf("Int для неизвестного ключа = %d, ожидался fallback 10", got)
+	}
+}
+
+func TestStoreSetOverridesDefault(t *testing.T) {
+	db := newTestDB(t)
+	store := newTestStore(db)
+	ctx := context.Background()
+
+	for _, value := range []string{"250", "50"} {
+		setting, err := store.Set(ctx, OutboxBatchSize, value)
+		if err != nil {
+			t.Fatalf("Set(%s): %v", value, err)
+		}
+		if setting.Value != value || setting.Default || setting.UpdatedAt == nil {
+			t.Errorf("ответ %+v", setting)
+		}
+	}
+
+	// Новое значение видно сразу, без обновления кеша
+	setting, err := store.Get(OutboxBatchSize)
+	if err != nil || setting.Value != "50" || setting.Default {
+		t.Fatalf("настройка %+v, ошибка %v", setting, err)
+	}
+	if got := store.Int(OutboxBatchSize, 10); got != 50 {
+		t.Errorf("Int = %d, ожидалось 50", got)
+	}
+
+	// Повторная запись обновляет строку, а не добавляет новую
+	var rows []Setting
+	db.Find(&rows)
+	if len(rows) != 1 || rows[0].Value != "50" {
+		t.Errorf("в базе %+v, ожидалась одна строка со значением 50", rows)
+	}
+}
+
+func TestStoreSetRejectsInvalidValues(t *testing.T) {
+	store := newTestStore(newTestDB(t))
+	ctx := context.Background()
+
+	for _, value := range []string{"0", "-5", "много", "1.5"} {
+		if _, err := store.Set(ctx, OutboxBatchSize, value); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("значение %q: ошибка %v, ожидалась ErrInvalidValue", value, err)
+		}
+	}
+	if _, err := store.Set(ctx, "outbox.interval", "5s"); !errors.Is(err, ErrUnknownSetting) {
+		t.Errorf("неизвестный ключ: ошибка %v, ожидалась ErrUnknownSetting", err)
+	}
+
+	if setting, _ := store.Get(OutboxBatchSize); setting.Value != "100" || !setting.Default {
+		t.Errorf("после отклоненных изменений %+v", setting)
+	}
+}
+
+func TestStoreRefreshPicksUpOtherReplicas(t *testing.T) {
+	db := newTestDB(t)
+	local := newTestStore(db)
+	remote := newTestStore(db)
+
+	if _, err := remote.Set(context.Background(), OutboxBatchSize, "300"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := local.Int(OutboxBatchSize, 10); got != 100 {
+		t.Errorf("до обновления кеша Int = %d, ожидалось 100", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		local.Start(ctx, 10*time.Millisecond)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for local.Int(OutboxBatchSize, 10) != 300 {
+		if time.Now().After(deadline) {
+			t.Fatalf("изменение другой реплики не применилось: %d", local.Int(OutboxBatchSize, 10))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Удаленная из базы настройка возвращается к значению по умолчанию
+	db.Where("key = ?", OutboxBatchSize).Delete(&Setting{})
+	if err := local.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if setting, _ := local.Get(OutboxBatchSize); setting.Value != "100" || !setting.Default {
+		t.Errorf("после удаления %+v", setting)
+	}
+}
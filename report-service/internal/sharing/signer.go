@@ -0,0 +1,73 @@
+package sharing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибки проверки токена
+var (
+	ErrInvalidToken = errors.New("некорректная ссылка")
+	ErrTokenExpired = errors.New("срок действия ссылки истек")
+)
+
+// Signer подписывает токены публичных ссылок HMAC-SHA256
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner создает подписчика с указанным секретом
+func NewSigner(secret string) *Signer {
+	return &Signer{secret: []byte(secret)}
+}
+
+// Sign формирует токен вида <payload>.<signature>, где payload содержит ID ссылки и срок действия
+func (s *Signer) Sign(shareID uint, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%d.%d", shareID, expiresAt.Unix())
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + s.signature(encoded)
+}
+
+// Verify проверяет подпись и срок действия токена и возвращает ID ссылки
+func (s *Signer) Verify(token string, now time.Time) (uint, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.signature(encoded))) {
+		return 0, ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	idStr, expStr, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+
+	shareID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	expUnix, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	if !now.Before(time.Unix(expUnix, 0)) {
+		return 0, ErrTokenExpired
+	}
+	return uint(shareID), nil
+}
+
+// signature вычисляет подпись полезной нагрузки
+func (s *Signer) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/sirupsen/logrus"
@@ -11,10 +14,31 @@ type Config struct {
 	Port        int    `envconfig:"PORT" default:"8087"`
 	Environment string `envconfig:"ENVIRONMENT" default:"development"`
 	LogLevel    string `envconfig:"LOG_LEVEL" default:"info"`
+	LogFormat   string `envconfig:"LOG_FORMAT" default:""` // json или text
+	// ShutdownTimeout время на завершение запросов при остановке
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"25s"`
 
 	DatabaseURL string `envconfig:"DATABASE_URL" required:"true"`
-	JWTSecret   string `envconfig:"JWT_SECRET" required:"true"`
-	StoragePath string `envconfig:"STORAGE_PATH" default:"/tmp/reports"`
+	// DBConnectAttempts и DBConnectInterval задают ожидание БД при старте: число попыток и начальный интервал
+	DBConnectAttempts int           `envconfig:"DB_CONNECT_ATTEMPTS" default:"10"`
+	DBConnectInterval time.Duration `envconfig:"DB_CONNECT_INTERVAL" default:"1s"`
+	JWTSecret         string        `envconfig:"JWT_SECRET" required:"true"`
+	// StoragePath каталог хранения файлов, задается для каждого окружения и приводится к абсолютному пути
+	StoragePath string `envconfig:"STORAGE_PATH" required:"true"`
+
+	// Ограничения загрузки: размер в байтах и списки MIME-типов через запятую
+	MaxUploadSize    int64    `envconfig:"MAX_UPLOAD_SIZE" default:"52428800"`
+	AllowedMimeTypes []string `envconfig:"ALLOWED_MIME_TYPES" default:""`
+	DeniedMimeTypes  []string `envconfig:"DENIED_MIME_TYPES" default:""`
+	// CompressMimeTypes типы файлов, которые хранятся сжатыми gzip; пустой список отключает сжатие
+	CompressMimeTypes []string `envconfig:"COMPRESS_MIME_TYPES" default:""`
+	// UploadSessionTTL срок незавершенной загрузки по частям; 0 — сессии не истекают
+	UploadSessionTTL time.Duration `envconfig:"UPLOAD_SESSION_TTL" default:"24h"`
+	// UploadCleanupInterval период удаления просроченных загрузок и их частей; 0 отключает удаление
+	UploadCleanupInterval time.Duration `envconfig:"UPLOAD_CLEANUP_INTERVAL" default:"1h"`
+
+	DefaultPageLimit int `envconfig:"DEFAULT_PAGE_LIMIT" default:"10"`
+	MaxPageLimit     int `envconfig:"MAX_PAGE_LIMIT" default:"100"`
 
 	AutoMigrate bool `envconfig:"AUTO_MIGRATE" default:"true"`
 	SeedData    bool `envconfig:"SEED_DATA" default:"true"`
@@ -27,21 +51,40 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("ошибка обработки конфигурации: %w", err)
 	}
 
+	storagePath, err := filepath.Abs(cfg.StoragePath)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный STORAGE_PATH: %w", err)
+	}
+	cfg.StoragePath = storagePath
+
 	return &cfg, nil
 }
 
+// SetupLogger применяет LOG_FORMAT и LOG_LEVEL. Без LOG_FORMAT в production
+// используется JSON, иначе текст; некорректный уровень заменяется на info.
 func (c *Config) SetupLogger() {
+	switch strings.ToLower(c.LogFormat) {
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	default:
+		if c.Environment == "production" {
+			logrus.SetFormatter(&logrus.JSONFormatter{})
+		} else {
+			logrus.SetFormatter(&logrus.TextFormatter{})
+		}
+		if c.LogFormat != "" {
+			logrus.Warnf("Неизвестный LOG_FORMAT %q, формат выбран по окружению", c.LogFormat)
+		}
+	}
+
 	level, err := logrus.ParseLevel(c.LogLevel)
 	if err != nil {
 		level = logrus.InfoLevel
+		logrus.Warnf("Некорректный LOG_LEVEL %q, используется info", c.LogLevel)
 	}
 	logrus.SetLevel(level)
-
-	if c.Environment == "production" {
-		logrus.SetFormatter(&logrus.JSONFormatter{})
-	} else {
-		logrus.SetFormatter(&logrus.TextFormatter{})
-	}
 }
 
 func (c *Config) IsDevelopment() bool {
@@ -44,6 +44,8 @@ func Migrate() error {
 	// Миграция моделей
 	if err := db.AutoMigrate(
 		&models.File{},
+		&models.UploadSession{},
+		&models.UploadChunk{},
 	); err != nil {
 		return fmt.Errorf("ошибка миграции моделей: %w", err)
 	}
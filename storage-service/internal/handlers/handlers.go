@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -28,17 +29,38 @@ func NewFileHandler(fileService *services.FileService, metrics *metrics.Metrics)
 	}
 }
 
+// multipartOverhead запас на заголовки и поля multipart-формы сверх размера файла
+const multipartOverhead = 1 << 20
+
 // UploadFile загрузка файла
 func (h *FileHandler) UploadFile(c *gin.Context) {
 	start := time.Now()
+
+	// Ограничиваем тело запроса, чтобы не читать в память заведомо большой файл
+	maxSize := h.fileService.MaxUploadSize()
+	if maxSize > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+	}
+
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
 		h.metrics.RecordBusinessOperation("storage-service", "upload_file", time.Since(start), false)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrFileTooLarge.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не найден"})
 		return
 	}
 	defer file.Close()
 
+	if maxSize > 0 && header.Size > maxSize {
+		h.metrics.RecordBusinessOperation("storage-service", "upload_file", time.Since(start), false)
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrFileTooLarge.Error()})
+		return
+	}
+
 	name := c.PostForm("name")
 	if name == "" {
 		name = header.Filename
@@ -56,16 +78,26 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 	hash := fmt.Sprintf("%x", md5.Sum(content))
 
 	req := &models.FileUploadRequest{
-		Name:        name,
-		Description: description,
-		IsPublic:    isPublic,
+		Name:          name,
+		Description:   description,
+		IsPublic:      isPublic,
+		CorrelationID: c.PostForm("correlation_id"),
 	}
 
 	result, err := h.fileService.UploadFile(req, header.Filename, content, hash)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка загрузки файла")
 		h.metrics.RecordBusinessOperation("storage-service", "upload_file", time.Since(start), false)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrFileTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrMimeTypeNotAllowed):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidPath):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -75,11 +107,38 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 
 // GetFiles получение списка файлов
 func (h *FileHandler) GetFiles(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	public := c.Query("public")
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 
-	files, total, err := h.fileService.GetFiles(page, limit, public)
+	filter := models.FileFilter{
+		MimeType:      c.Query("mime_type"),
+		Query:         c.Query("q"),
+		CorrelationID: c.Query("correlation_id"),
+	}
+	if public := c.Query("public"); public != "" {
+		isPublic := public == "true"
+		filter.IsPublic = &isPublic
+	}
+	if minSize := c.Query("min_size"); minSize != "" {
+		size, err := strconv.ParseInt(minSize, 10, 64)
+		if err != nil || size < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр min_size"})
+			return
+		}
+		filter.MinSize = &size
+	}
+	if maxSize := c.Query("max_size"); maxSize != "" {
+		size, err := strconv.ParseInt(maxSize, 10, 64)
+		if err != nil || size < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр max_size"})
+			return
+		}
+		filter.MaxSize = &size
+	}
+
+	files, total, err := h.fileService.GetFiles(page, limit, filter)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка получения файлов")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -147,7 +206,7 @@ func (h *FileHandler) UpdateFile(c *gin.Context) {
 
 	var req models.FileUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
@@ -217,8 +276,10 @@ func (h *FileHandler) SearchFiles(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 
 	files, total, err := h.fileService.SearchFiles(query, page, limit)
 	if err != nil {
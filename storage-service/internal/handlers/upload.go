@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"storage-service/internal/models"
+	"storage-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// uploadTokenHeader заголовок с токеном, выданным при создании сессии загрузки
+const uploadTokenHeader = "X-Upload-Token"
+
+// respondUploadError переводит ошибки загрузки по частям в HTTP статусы
+func respondUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUploadExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUploadCompleted), errors.Is(err, services.ErrUploadIncomplete):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrHashMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFileTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMimeTypeNotAllowed):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChunkOutOfRange), errors.Is(err, services.ErrInvalidUpload), errors.Is(err, services.ErrInvalidPath):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// InitUpload создание сессии загрузки по частям
+func (h *FileHandler) InitUpload(c *gin.Context) {
+	var req models.UploadInitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	session, err := h.fileService.InitUpload(&req)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка создания сессии загрузки")
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetUpload состояние загрузки по частям
+func (h *FileHandler) GetUpload(c *gin.Context) {
+	session, err := h.fileService.GetUpload(c.Param("uploadId"), c.GetHeader(uploadTokenHeader))
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// UploadChunk прием части файла, тело запроса — содержимое части
+func (h *FileHandler) UploadChunk(c *gin.Context) {
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный номер части"})
+		return
+	}
+
+	if maxSize := h.fileService.MaxUploadSize(); maxSize > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	}
+
+	session, err := h.fileService.UploadChunk(c.Param("uploadId"), c.GetHeader(uploadTokenHeader), n, c.Request.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrFileTooLarge.Error()})
+			return
+		}
+		logrus.WithError(err).Error("Ошибка приема части файла")
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// CompleteUpload сборка файла из частей с проверкой хеша
+func (h *FileHandler) CompleteUpload(c *gin.Context) {
+	start := time.Now()
+
+	result, err := h.fileService.CompleteUpload(c.Param("uploadId"), c.GetHeader(uploadTokenHeader))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка завершения загрузки")
+		h.metrics.RecordBusinessOperation("storage-service", "complete_upload", time.Since(start), false)
+		respondUploadError(c, err)
+		return
+	}
+
+	h.metrics.RecordBusinessOperation("storage-service", "complete_upload", time.Since(start), true)
+	c.JSON(http.StatusCreated, result)
+}
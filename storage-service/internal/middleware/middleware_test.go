@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequireJSON("/api/v1/files/upload", "/api/v1/files/upload/:uploadId/chunk/:n"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/api/v1/files/upload", ok)
+	router.PUT("/api/v1/files/upload/:uploadId/chunk/:n", ok)
+	router.PUT("/api/v1/files/:id", ok)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		status      int
+	}{
+		{"JSON", http.MethodPut, "/api/v1/files/1", "application/json", http.StatusOK},
+		{"неверный Content-Type", http.MethodPut, "/api/v1/files/1", "text/plain", http.StatusUnsupportedMediaType},
+		{"multipart загрузка", http.MethodPost, "/api/v1/files/upload", "multipart/form-data; boundary=x", http.StatusOK},
+		{"часть загрузки", http.MethodPut, "/api/v1/files/upload/abc/chunk/1", "application/octet-stream", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("data"))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("статус %d, ожидался %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
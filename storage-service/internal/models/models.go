@@ -7,17 +7,20 @@ import (
 )
 
 type File struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"not null"`
-	Path        string         `json:"path" gorm:"not null"`
-	Size        int64          `json:"size"`
-	MimeType    string         `json:"mime_type"`
-	Hash        string         `json:"hash"` // MD5 хеш файла
-	Description string         `json:"description"`
-	IsPublic    bool           `json:"is_public" gorm:"default:false"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Name          string         `json:"name" gorm:"not null"`
+	Path          string         `json:"path" gorm:"not null"`
+	Size          int64          `json:"size"`        // исходный размер файла
+	StoredSize    int64          `json:"stored_size"` // размер на диске с учетом сжатия
+	Compressed    bool           `json:"compressed" gorm:"not null;default:false"`
+	MimeType      string         `json:"mime_type"`
+	Hash          string         `json:"hash"` // MD5 хеш файла
+	Description   string         `json:"description"`
+	IsPublic      bool           `json:"is_public" gorm:"default:false"`
+	CorrelationID string         `json:"correlation_id" gorm:"index"` // связь с отчетом, Saga и уведомлением report-service
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 func (File) TableName() string {
@@ -25,9 +28,10 @@ func (File) TableName() string {
 }
 
 type FileUploadRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	IsPublic    bool   `json:"is_public"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	IsPublic      bool   `json:"is_public"`
+	CorrelationID string `json:"correlation_id"`
 }
 
 type FileUpdateRequest struct {
@@ -37,30 +41,36 @@ type FileUpdateRequest struct {
 }
 
 type FileResponse struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Path        string    `json:"path"`
-	Size        int64     `json:"size"`
-	MimeType    string    `json:"mime_type"`
-	Hash        string    `json:"hash"`
-	Description string    `json:"description"`
-	IsPublic    bool      `json:"is_public"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            uint      `json:"id"`
+	Name          string    `json:"name"`
+	Path          string    `json:"path"`
+	Size          int64     `json:"size"`
+	StoredSize    int64     `json:"stored_size"`
+	Compressed    bool      `json:"compressed"`
+	MimeType      string    `json:"mime_type"`
+	Hash          string    `json:"hash"`
+	Description   string    `json:"description"`
+	IsPublic      bool      `json:"is_public"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 func (f *File) ToResponse() FileResponse {
 	return FileResponse{
-		ID:          f.ID,
-		Name:        f.Name,
-		Path:        f.Path,
-		Size:        f.Size,
-		MimeType:    f.MimeType,
-		Hash:        f.Hash,
-		Description: f.Description,
-		IsPublic:    f.IsPublic,
-		CreatedAt:   f.CreatedAt,
-		UpdatedAt:   f.UpdatedAt,
+		ID:            f.ID,
+		Name:          f.Name,
+		Path:          f.Path,
+		Size:          f.Size,
+		StoredSize:    f.StoredSize,
+		Compressed:    f.Compressed,
+		MimeType:      f.MimeType,
+		Hash:          f.Hash,
+		Description:   f.Description,
+		IsPublic:      f.IsPublic,
+		CorrelationID: f.CorrelationID,
+		CreatedAt:     f.CreatedAt,
+		UpdatedAt:     f.UpdatedAt,
 	}
 }
 
@@ -81,10 +91,93 @@ type FileDownloadResponse struct {
 	Content []byte       `json:"content"`
 }
 
+// FileFilter параметры фильтрации списка файлов
+type FileFilter struct {
+	IsPublic      *bool
+	MimeType      string // префикс, например image/
+	MinSize       *int64
+	MaxSize       *int64
+	Query         string // поиск по имени файла
+	CorrelationID string
+}
+
 type StorageStatsResponse struct {
 	TotalFiles   int64 `json:"total_files"`
 	TotalSize    int64 `json:"total_size"`
 	PublicFiles  int64 `json:"public_files"`
 	PrivateFiles int64 `json:"private_files"`
 	AverageSize  int64 `json:"average_size"`
+
+	// Статистика дедупликации по хешу содержимого
+	UniqueHashes   int64 `json:"unique_hashes"`
+	DuplicateCount int64 `json:"duplicate_count"`
+	BytesSaved     int64 `json:"bytes_saved"`
+}
+
+// Статусы сессии загрузки по частям
+const (
+	UploadStatusPending   = "pending"
+	UploadStatusCompleted = "completed"
+)
+
+// UploadSession сессия загрузки файла по частям
+type UploadSession struct {
+	ID           string `json:"id" gorm:"primaryKey"`
+	Name         string `json:"name" gorm:"not null"`
+	Filename     string `json:"filename"`
+	Description  string `json:"description"`
+	IsPublic     bool   `json:"is_public" gorm:"default:false"`
+	TotalChunks  int    `json:"total_chunks" gorm:"not null"`
+	TotalSize    int64  `json:"total_size" gorm:"not null"`
+	ExpectedHash string `json:"expected_hash" gorm:"not null"` // MD5 хеш итогового файла
+	Status       string `json:"status" gorm:"not null;default:'pending'"`
+	FileID       uint   `json:"file_id"` // ID файла после завершения загрузки
+	// TokenHash SHA-256 токена загрузки: части и завершение принимаются только от создателя сессии
+	TokenHash string `json:"-" gorm:"not null;default:''"`
+	// ExpiresAt срок незавершенной загрузки, после него сессия и ее части удаляются
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (UploadSession) TableName() string {
+	return "upload_sessions"
+}
+
+// UploadChunk принятая часть файла
+type UploadChunk struct {
+	UploadID  string `gorm:"primaryKey"`
+	Index     int    `gorm:"primaryKey;column:chunk_index"`
+	Size      int64  `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (UploadChunk) TableName() string {
+	return "upload_chunks"
+}
+
+type UploadInitRequest struct {
+	Name        string `json:"name"`
+	Filename    string `json:"filename" binding:"required"`
+	Description string `json:"description"`
+	IsPublic    bool   `json:"is_public"`
+	TotalChunks int    `json:"total_chunks" binding:"required,min=1"`
+	TotalSize   int64  `json:"total_size" binding:"required,min=1"`
+	Hash        string `json:"hash" binding:"required"` // MD5 хеш итогового файла в hex
+}
+
+// UploadSessionResponse состояние загрузки: принятые и недостающие части (нумерация с 0)
+type UploadSessionResponse struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	TotalChunks    int    `json:"total_chunks"`
+	TotalSize      int64  `json:"total_size"`
+	ReceivedBytes  int64  `json:"received_bytes"`
+	ReceivedChunks []int  `json:"received_chunks"`
+	MissingChunks  []int  `json:"missing_chunks"`
+	FileID         uint   `json:"file_id,omitempty"`
+	// UploadToken возвращается только при создании сессии и передается в заголовке X-Upload-Token
+	UploadToken string     `json:"upload_token,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
 }
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"strings"
+
 	"storage-service/internal/models"
 
 	"gorm.io/gorm"
@@ -34,13 +36,28 @@ func (r *FileRepository) GetByHash(hash string) (*models.File, error) {
 }
 
 // GetAll получает все файлы с пагинацией
-func (r *FileRepository) GetAll(page, limit int, isPublic *bool) ([]models.File, int64, error) {
+func (r *FileRepository) GetAll(page, limit int, filter models.FileFilter) ([]models.File, int64, error) {
 	var files []models.File
 	var total int64
 
 	query := r.db.Model(&models.File{})
-	if isPublic != nil {
-		query = query.Where("is_public = ?", *isPublic)
+	if filter.IsPublic != nil {
+		query = query.Where("is_public = ?", *filter.IsPublic)
+	}
+	if filter.MimeType != "" {
+		query = query.Where("mime_type LIKE ?", escapeLike(filter.MimeType)+"%")
+	}
+	if filter.MinSize != nil {
+		query = query.Where("size >= ?", *filter.MinSize)
+	}
+	if filter.MaxSize != nil {
+		query = query.Where("size <= ?", *filter.MaxSize)
+	}
+	if filter.Query != "" {
+		query = query.Where("name ILIKE ?", "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.CorrelationID != "" {
+		query = query.Where("correlation_id = ?", filter.CorrelationID)
 	}
 
 	if err := query.Count(&total).Error; err != nil {
@@ -106,5 +123,32 @@ func (r *FileRepository) GetStorageStats() (*models.StorageStatsResponse, error)
 		stats.AverageSize = stats.TotalSize / stats.TotalFiles
 	}
 
+	if err := r.db.Model(&models.File{}).Where("hash <> ''").Distinct("hash").Count(&stats.UniqueHashes).Error; err != nil {
+		return nil, err
+	}
+
+	var hashedFiles int64
+	if err := r.db.Model(&models.File{}).Where("hash <> ''").Count(&hashedFiles).Error; err != nil {
+		return nil, err
+	}
+	stats.DuplicateCount = hashedFiles - stats.UniqueHashes
+
+	// Каждая лишняя запись с тем же хешем ссылается на уже сохраненное содержимое
+	duplicates := r.db.Model(&models.File{}).
+		Select("hash, COUNT(*) AS copies, MAX(size) AS size").
+		Where("hash <> ''").
+		Group("hash").
+		Having("COUNT(*) > 1")
+	if err := r.db.Table("(?) AS duplicates", duplicates).
+		Select("COALESCE(SUM((copies - 1) * size), 0)").
+		Scan(&stats.BytesSaved).Error; err != nil {
+		return nil, err
+	}
+
 	return &stats, nil
 }
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"storage-service/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB создает отдельную SQLite базу с мигрированными файлами
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "storage.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.AutoMigrate(&models.File{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+	return db
+}
+
+// seedFiles сохраняет записи файлов в обход сервиса
+func seedFiles(t *testing.T, db *gorm.DB, files ...*models.File) {
+	t.Helper()
+	for _, file := range files {
+		if file.Path == "" {
+			file.Path = file.Hash
+		}
+		if err := db.Create(file).Error; err != nil {
+			t.Fatalf("создание записи %q: %v", file.Name, err)
+		}
+	}
+}
+
+func TestGetStorageStatsCountsDuplicates(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewFileRepository(db)
+
+	// Три копии одного содержимого, две копии другого, один уникальный файл и файл без хеша
+	seedFiles(t, db,
+		&models.File{Name: "a1.csv", Size: 100, Hash: "aaa"},
+		&models.File{Name: "a2.csv", Size: 100, Hash: "aaa"},
+		&models.File{Name: "a3.csv", Size: 100, Hash: "aaa", IsPublic: true},
+		&models.File{Name: "b1.pdf", Size: 40, Hash: "bbb"},
+		&models.File{Name: "b2.pdf", Size: 40, Hash: "bbb"},
+		&models.File{Name: "c.txt", Size: 10, Hash: "ccc"},
+		&models.File{Name: "legacy.txt", Size: 10, Path: "legacy"},
+	)
+
+	// Удаленная копия не учитывается
+	deleted := &models.File{Name: "a4.csv", Size: 100, Hash: "aaa"}
+	seedFiles(t, db, deleted)
+	if err := repo.Delete(deleted.ID); err != nil {
+		t.Fatalf("удаление записи: %v", err)
+	}
+
+	stats, err := repo.GetStorageStats()
+	if err != nil {
+		t.Fatalf("получение статистики: %v", err)
+	}
+
+	if stats.TotalFiles != 7 || stats.TotalSize != 400 || stats.PublicFiles != 1 || stats.PrivateFiles != 6 {
+		t.Errorf("общая статистика %+v", stats)
+	}
+	if stats.UniqueHashes != 3 {
+		t.Errorf("unique_hashes %d, ожидалось 3", stats.UniqueHashes)
+	}
+	if stats.DuplicateCount != 3 {
+		t.Errorf("duplicate_count %d, ожидалось 3", stats.DuplicateCount)
+	}
+	// Две лишние копии по 100 байт и одна по 40
+	if stats.BytesSaved != 240 {
+		t.Errorf("bytes_saved %d, ожидалось 240", stats.BytesSaved)
+	}
+}
+
+func TestGetStorageStatsWithoutDuplicates(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewFileRepository(db)
+
+	stats, err := repo.GetStorageStats()
+	if err != nil {
+		t.Fatalf("статистика пустого хранилища: %v", err)
+	}
+	if stats.TotalFiles != 0 || stats.UniqueHashes != 0 || stats.DuplicateCount != 0 || stats.BytesSaved != 0 {
+		t.Errorf("статистика пустого хранилища %+v", stats)
+	}
+
+	seedFiles(t, db,
+		&models.File{Name: "a.csv", Size: 100, Hash: "aaa"},
+		&models.File{Name: "b.csv", Size: 50, Hash: "bbb"},
+	)
+	stats, err = repo.GetStorageStats()
+	if err != nil {
+		t.Fatalf("получение статистики: %v", err)
+	}
+	if stats.UniqueHashes != 2 || stats.DuplicateCount != 0 || stats.BytesSaved != 0 {
+		t.Errorf("статистика без дубликатов %+v", stats)
+	}
+}
+
+func TestGetAllFiltersByMimePrefixAndSize(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewFileRepository(db)
+
+	seedFiles(t, db,
+		&models.File{Name: "small.png", Size: 10, MimeType: "image/png", Hash: "1"},
+		&models.File{Name: "large.jpg", Size: 5000, MimeType: "image/jpeg", Hash: "2"},
+		&models.File{Name: "report.csv", Size: 300, MimeType: "text/csv", Hash: "3"},
+		&models.File{Name: "report.pdf", Size: 800, MimeType: "application/pdf", Hash: "4"},
+	)
+
+	size := func(v int64) *int64 { return &v }
+	tests := []struct {
+		name   string
+		filter models.FileFilter
+		want   []string
+	}{
+		{"без фильтров", models.FileFilter{}, []string{"large.jpg", "report.csv", "report.pdf", "small.png"}},
+		{"префикс MIME", models.FileFilter{MimeType: "image/"}, []string{"large.jpg", "small.png"}},
+		{"точный MIME", models.FileFilter{MimeType: "text/csv"}, []string{"report.csv"}},
+		{"минимальный размер", models.FileFilter{MinSize: size(300)}, []string{"large.jpg", "report.csv", "report.pdf"}},
+		{"максимальный размер", models.FileFilter{MaxSize: size(300)}, []string{"report.csv", "small.png"}},
+		{"диапазон размеров", models.FileFilter{MinSize: size(100), MaxSize: size(1000)}, []string{"report.csv", "report.pdf"}},
+		{"префикс и диапазон", models.FileFilter{MimeType: "image/", MinSize: size(100)}, []string{"large.jpg"}},
+		{"ничего не найдено", models.FileFilter{MimeType: "video/"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, total, err := repo.GetAll(1, 10, tt.filter)
+			if err != nil {
+				t.Fatalf("получение файлов: %v", err)
+			}
+			var names []string
+			for _, file := range files {
+				names = append(names, file.Name)
+			}
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") || total != int64(len(tt.want)) {
+				t.Errorf("найдены %v (всего %d), ожидались %v", names, total, tt.want)
+			}
+		})
+	}
+}
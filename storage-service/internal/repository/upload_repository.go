@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"time"
+
+	"storage-service/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UploadRepository struct {
+	db *gorm.DB
+}
+
+func NewUploadRepository(db *gorm.DB) *UploadRepository {
+	return &UploadRepository{db: db}
+}
+
+// CreateSession создает сессию загрузки
+func (r *UploadRepository) CreateSession(session *models.UploadSession) error {
+	return r.db.Create(session).Error
+}
+
+// GetSession получает сессию загрузки по ID
+func (r *UploadRepository) GetSession(id string) (*models.UploadSession, error) {
+	var session models.UploadSession
+	err := r.db.Where("id = ?", id).First(&session).Error
+	return &session, err
+}
+
+// UpdateSession обновляет сессию загрузки
+func (r *UploadRepository) UpdateSession(session *models.UploadSession) error {
+	return r.db.Save(session).Error
+}
+
+// SaveChunk сохраняет сведения о части; повторная загрузка той же части перезаписывает размер
+func (r *UploadRepository) SaveChunk(chunk *models.UploadChunk) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "upload_id"}, {Name: "chunk_index"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size", "created_at"}),
+	}).Create(chunk).Error
+}
+
+// GetChunks возвращает принятые части загрузки по возрастанию номера
+func (r *UploadRepository) GetChunks(uploadID string) ([]models.UploadChunk, error) {
+	var chunks []models.UploadChunk
+	err := r.db.Where("upload_id = ?", uploadID).Order("chunk_index ASC").Find(&chunks).Error
+	return chunks, err
+}
+
+// DeleteChunks удаляет сведения о частях загрузки
+func (r *UploadRepository) DeleteChunks(uploadID string) error {
+	return r.db.Where("upload_id = ?", uploadID).Delete(&models.UploadChunk{}).Error
+}
+
+// FindExpiredSessions возвращает незавершенные сессии, срок которых истек к моменту now
+func (r *UploadRepository) FindExpiredSessions(now time.Time, limit int) ([]models.UploadSession, error) {
+	var sessions []models.UploadSession
+	err := r.db.Where("status = ? AND expires_at IS NOT NULL AND expires_at < ?", models.UploadStatusPending, now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// DeleteSession удаляет сессию загрузки вместе со сведениями о частях
+func (r *UploadRepository) DeleteSession(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("upload_id = ?", id).Delete(&models.UploadChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&models.UploadSession{}).Error
+	})
+}
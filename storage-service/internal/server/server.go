@@ -7,7 +7,6 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"storage-service/internal/config"
 	"storage-service/internal/database"
@@ -17,10 +16,10 @@ import (
 	"storage-service/internal/middleware"
 	"storage-service/internal/repository"
 	"storage-service/internal/services"
+	"storage-service/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
-	"gorm.io/gorm"
 )
 
 type Server struct {
@@ -35,6 +34,8 @@ func NewServer(cfg *config.Config) *Server {
 
 func (s *Server) Start() error {
 	s.cfg.SetupLogger()
+	handlers.SetDefaultPageLimit(s.cfg.DefaultPageLimit)
+	handlers.SetMaxPageLimit(s.cfg.MaxPageLimit)
 
 	if s.cfg.AutoMigrate {
 		if err := s.migrate(); err != nil {
@@ -42,7 +43,7 @@ func (s *Server) Start() error {
 		}
 	}
 
-	db, err := database.Connect(s.cfg.DatabaseURL)
+	db, err := database.ConnectWithRetry(s.cfg.DatabaseURL, s.cfg.DBConnectAttempts, s.cfg.DBConnectInterval)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
@@ -50,7 +51,21 @@ func (s *Server) Start() error {
 	jwtManager := jwt.NewManager(s.cfg.JWTSecret)
 	metricsManager := metrics.NewMetrics("storage-service")
 
-	router := s.setupRouter(db, jwtManager, metricsManager)
+	fileService := services.NewFileService(repository.NewFileRepository(db), repository.NewUploadRepository(db), s.cfg.StoragePath, services.UploadPolicy{
+		MaxSize:           s.cfg.MaxUploadSize,
+		AllowedMimeTypes:  s.cfg.AllowedMimeTypes,
+		DeniedMimeTypes:   s.cfg.DeniedMimeTypes,
+		CompressMimeTypes: s.cfg.CompressMimeTypes,
+		SessionTTL:        s.cfg.UploadSessionTTL,
+	})
+
+	cleanupCtx, stopCleanup := context.WithCancel(context.Background())
+	defer stopCleanup()
+	if s.cfg.UploadCleanupInterval > 0 {
+		go fileService.StartUploadCleanup(cleanupCtx, s.cfg.UploadCleanupInterval)
+	}
+
+	router := s.setupRouter(fileService, jwtManager, metricsManager)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.cfg.Port),
@@ -69,7 +84,7 @@ func (s *Server) Start() error {
 	<-quit
 	logrus.Info("Остановка Storage Service...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
@@ -80,7 +95,7 @@ func (s *Server) Start() error {
 	return nil
 }
 
-func (s *Server) setupRouter(db *gorm.DB, jwtManager *jwt.Manager, metricsManager *metrics.Metrics) *gin.Engine {
+func (s *Server) setupRouter(fileService *services.FileService, jwtManager *jwt.Manager, metricsManager *metrics.Metrics) *gin.Engine {
 	router := gin.Default()
 
 	// Инициализация метрик
@@ -90,10 +105,7 @@ func (s *Server) setupRouter(db *gorm.DB, jwtManager *jwt.Manager, metricsManage
 	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS())
 	router.Use(middleware.RequestID())
-
-	fileRepo := repository.NewFileRepository(db)
-
-	fileService := services.NewFileService(fileRepo, s.cfg.StoragePath)
+	router.Use(middleware.RequireJSON("/api/v1/files/upload", "/api/v1/files/upload/:uploadId/chunk/:n"))
 
 	fileHandler := handlers.NewFileHandler(fileService, metricsManager)
 
@@ -104,11 +116,7 @@ func (s *Server) setupRouter(db *gorm.DB, jwtManager *jwt.Manager, metricsManage
 
 func (s *Server) setupRoutes(router *gin.Engine, fileHandler *handlers.FileHandler, jwtManager *jwt.Manager) {
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  "healthy",
-			"service": "storage-service",
-			"version": "1.0.0",
-		})
+		c.JSON(http.StatusOK, version.Health("storage-service"))
 	})
 
 	api := router.Group("/api/v1")
@@ -116,6 +124,10 @@ func (s *Server) setupRoutes(router *gin.Engine, fileHandler *handlers.FileHandl
 		files := api.Group("/files")
 		{
 			files.POST("/upload", fileHandler.UploadFile)
+			files.POST("/upload/init", fileHandler.InitUpload)
+			files.GET("/upload/:uploadId", fileHandler.GetUpload)
+			files.PUT("/upload/:uploadId/chunk/:n", fileHandler.UploadChunk)
+			files.POST("/upload/:uploadId/complete", fileHandler.CompleteUpload)
 			files.GET("/", fileHandler.GetFiles)
 			files.GET("/:id", fileHandler.GetFile)
 			files.GET("/:id/download", fileHandler.DownloadFile)
@@ -134,7 +146,7 @@ func (s *Server) setupRoutes(router *gin.Engine, fileHandler *handlers.FileHandl
 }
 
 func (s *Server) migrate() error {
-	_, err := database.Connect(s.cfg.DatabaseURL)
+	_, err := database.ConnectWithRetry(s.cfg.DatabaseURL, s.cfg.DBConnectAttempts, s.cfg.DBConnectInterval)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
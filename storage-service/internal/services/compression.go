@@ -0,0 +1,85 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// shouldCompress проверяет, нужно ли сжимать файл данного типа при сохранении
+func (s *FileService) shouldCompress(mimeType string) bool {
+	return matchMimeType(mimeType, s.uploadPolicy.CompressMimeTypes)
+}
+
+// compressContent сжимает содержимое gzip. Возвращает false, если сжатие не уменьшило размер
+// и файл выгоднее хранить как есть.
+func compressContent(content []byte) ([]byte, bool, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(content); err != nil {
+		return nil, false, fmt.Errorf("ошибка сжатия файла: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, false, fmt.Errorf("ошибка сжатия файла: %w", err)
+	}
+	if buf.Len() >= len(content) {
+		return content, false, nil
+	}
+	return buf.Bytes(), true, nil
+}
+
+// decompressContent распаковывает содержимое, сохраненное compressContent
+func decompressContent(content []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка распаковки файла: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка распаковки файла: %w", err)
+	}
+	return data, nil
+}
+
+// compressFile потоком сжимает файл src размером size в dst и возвращает размер dst.
+// Если сжатие не уменьшило размер, dst удаляется и возвращается false.
+func compressFile(dst, src string, size int64) (int64, bool, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, false, fmt.Errorf("ошибка сжатия файла: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, false, fmt.Errorf("ошибка сжатия файла: %w", err)
+	}
+
+	writer := gzip.NewWriter(out)
+	_, err = io.Copy(writer, in)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return 0, false, fmt.Errorf("ошибка сжатия файла: %w", err)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		os.Remove(dst)
+		return 0, false, fmt.Errorf("ошибка сжатия файла: %w", err)
+	}
+	if info.Size() >= size {
+		os.Remove(dst)
+		return size, false, nil
+	}
+	return info.Size(), true, nil
+}
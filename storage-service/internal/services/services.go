@@ -3,9 +3,11 @@ package services
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"storage-service/internal/models"
 	"storage-service/internal/repository"
@@ -13,20 +15,121 @@ import (
 	"gorm.io/gorm"
 )
 
+// Ошибки проверки загружаемого файла
+var (
+	ErrFileTooLarge       = errors.New("размер файла превышает допустимый")
+	ErrMimeTypeNotAllowed = errors.New("тип файла не разрешен")
+	ErrInvalidPath        = errors.New("путь к файлу выходит за пределы хранилища")
+)
+
+// UploadPolicy ограничения на загружаемые файлы
+type UploadPolicy struct {
+	MaxSize           int64         // максимальный размер в байтах, 0 — без ограничения
+	AllowedMimeTypes  []string      // если задан, разрешены только эти типы (поддерживается "image/*")
+	DeniedMimeTypes   []string      // запрещенные типы, проверяются после разрешенных
+	CompressMimeTypes []string      // типы, которые хранятся сжатыми gzip (поддерживается "text/*")
+	SessionTTL        time.Duration // срок незавершенной загрузки по частям, 0 — без ограничения
+}
+
 type FileService struct {
-	fileRepo    *repository.FileRepository
-	storagePath string
+	fileRepo     *repository.FileRepository
+	uploadRepo   *repository.UploadRepository
+	storagePath  string
+	uploadPolicy UploadPolicy
 }
 
-func NewFileService(fileRepo *repository.FileRepository, storagePath string) *FileService {
+func NewFileService(fileRepo *repository.FileRepository, uploadRepo *repository.UploadRepository, storagePath string, uploadPolicy UploadPolicy) *FileService {
 	return &FileService{
-		fileRepo:    fileRepo,
-		storagePath: storagePath,
+		fileRepo:     fileRepo,
+		uploadRepo:   uploadRepo,
+		storagePath:  filepath.Clean(storagePath),
+		uploadPolicy: uploadPolicy,
+	}
+}
+
+// resolvePath строит путь к файлу внутри хранилища и отклоняет имена, выходящие за его пределы
+func (s *FileService) resolvePath(name string) (string, error) {
+	filePath := filepath.Join(s.storagePath, name)
+	if err := s.checkPath(filePath); err != nil {
+		return "", err
 	}
+	return filePath, nil
+}
+
+// checkPath проверяет, что путь после очистки находится внутри хранилища
+func (s *FileService) checkPath(filePath string) error {
+	rel, err := filepath.Rel(s.storagePath, filepath.Clean(filePath))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %s", ErrInvalidPath, filePath)
+	}
+	return nil
+}
+
+// MaxUploadSize возвращает максимальный размер загружаемого файла
+func (s *FileService) MaxUploadSize() int64 {
+	return s.uploadPolicy.MaxSize
+}
+
+// ValidateUpload проверяет размер и определенный по содержимому тип файла
+func (s *FileService) ValidateUpload(content []byte) error {
+	if err := s.validateSize(int64(len(content))); err != nil {
+		return err
+	}
+	return s.validateMimeType(content)
+}
+
+// validateSize проверяет размер файла по политике загрузки
+func (s *FileService) validateSize(size int64) error {
+	if s.uploadPolicy.MaxSize > 0 && size > s.uploadPolicy.MaxSize {
+		return fmt.Errorf("%w: %d байт при максимуме %d", ErrFileTooLarge, size, s.uploadPolicy.MaxSize)
+	}
+	return nil
+}
+
+// validateMimeType проверяет тип файла, определенный по первым байтам содержимого
+func (s *FileService) validateMimeType(content []byte) error {
+	detected := detectMimeType(content)
+	if len(s.uploadPolicy.AllowedMimeTypes) > 0 && !matchMimeType(detected, s.uploadPolicy.AllowedMimeTypes) {
+		return fmt.Errorf("%w: %s", ErrMimeTypeNotAllowed, detected)
+	}
+	if matchMimeType(detected, s.uploadPolicy.DeniedMimeTypes) {
+		return fmt.Errorf("%w: %s", ErrMimeTypeNotAllowed, detected)
+	}
+	return nil
+}
+
+// detectMimeType определяет тип файла по содержимому без параметров
+func detectMimeType(content []byte) string {
+	mimeType := http.DetectContentType(content)
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return strings.TrimSpace(mimeType)
+}
+
+// matchMimeType проверяет тип по списку с поддержкой шаблонов вида "image/*"
+func matchMimeType(mimeType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if pattern == mimeType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
 }
 
 // UploadFile загружает файл
 func (s *FileService) UploadFile(req *models.FileUploadRequest, filename string, content []byte, hash string) (*models.FileUploadResponse, error) {
+	if err := s.ValidateUpload(content); err != nil {
+		return nil, err
+	}
+
 	existingFile, err := s.fileRepo.GetByHash(hash)
 	if err == nil && existingFile != nil {
 		return &models.FileUploadResponse{
@@ -37,23 +140,36 @@ func (s *FileService) UploadFile(req *models.FileUploadRequest, filename string,
 
 	mimeType := s.getMimeType(filename)
 
-	filePath := filepath.Join(s.storagePath, hash)
+	filePath, err := s.resolvePath(hash)
+	if err != nil {
+		return nil, err
+	}
 	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
 		return nil, fmt.Errorf("ошибка создания директории: %w", err)
 	}
 
-	if err := os.WriteFile(filePath, content, 0644); err != nil {
+	stored, compressed := content, false
+	if s.shouldCompress(mimeType) {
+		if stored, compressed, err = compressContent(content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.WriteFile(filePath, stored, 0644); err != nil {
 		return nil, fmt.Errorf("ошибка сохранения файла: %w", err)
 	}
 
 	file := &models.File{
-		Name:        req.Name,
-		Path:        filePath,
-		Size:        int64(len(content)),
-		MimeType:    mimeType,
-		Hash:        hash,
-		Description: req.Description,
-		IsPublic:    req.IsPublic,
+		Name:          req.Name,
+		Path:          filePath,
+		Size:          int64(len(content)),
+		StoredSize:    int64(len(stored)),
+		Compressed:    compressed,
+		MimeType:      mimeType,
+		Hash:          hash,
+		Description:   req.Description,
+		IsPublic:      req.IsPublic,
+		CorrelationID: req.CorrelationID,
 	}
 
 	if err := s.fileRepo.Create(file); err != nil {
@@ -68,14 +184,8 @@ func (s *FileService) UploadFile(req *models.FileUploadRequest, filename string,
 }
 
 // GetFiles получает список файлов
-func (s *FileService) GetFiles(page, limit int, public string) ([]models.FileResponse, int64, error) {
-	var isPublic *bool
-	if public != "" {
-		publicBool := public == "true"
-		isPublic = &publicBool
-	}
-
-	files, total, err := s.fileRepo.GetAll(page, limit, isPublic)
+func (s *FileService) GetFiles(page, limit int, filter models.FileFilter) ([]models.FileResponse, int64, error) {
+	files, total, err := s.fileRepo.GetAll(page, limit, filter)
 	if err != nil {
 		return nil, 0, fmt.Errorf("ошибка получения файлов: %w", err)
 	}
@@ -112,10 +222,19 @@ func (s *FileService) DownloadFile(id uint) (*models.FileDownloadResponse, error
 		return nil, fmt.Errorf("ошибка получения файла: %w", err)
 	}
 
+	if err := s.checkPath(file.Path); err != nil {
+		return nil, err
+	}
+
 	content, err := os.ReadFile(file.Path)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка чтения файла: %w", err)
 	}
+	if file.Compressed {
+		if content, err = decompressContent(content); err != nil {
+			return nil, err
+		}
+	}
 
 	return &models.FileDownloadResponse{
 		File:    file.ToResponse(),
@@ -159,6 +278,10 @@ func (s *FileService) DeleteFile(id uint) error {
 		return fmt.Errorf("ошибка получения файла: %w", err)
 	}
 
+	if err := s.checkPath(file.Path); err != nil {
+		return err
+	}
+
 	if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("ошибка удаления файла с диска: %w", err)
 	}
@@ -215,6 +338,8 @@ func (s *FileService) getMimeType(filename string) string {
 	switch ext {
 	case ".txt":
 		return "text/plain"
+	case ".csv":
+		return "text/csv"
 	case ".html", ".htm":
 		return "text/html"
 	case ".css":
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"storage-service/internal/models"
+)
+
+func TestResolvePathRejectsTraversal(t *testing.T) {
+	service, _ := newTestFileService(t, UploadPolicy{})
+
+	for _, name := range []string{"../escape", "../../etc/passwd", "uploads/../../escape", "..", ".", ""} {
+		if path, err := service.resolvePath(name); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("имя %q: получен путь %q (%v), ожидалась ErrInvalidPath", name, path, err)
+		}
+	}
+
+	for _, name := range []string{"d41d8cd98f00b204e9800998ecf8427e", "uploads/abc/0", "nested/../inside"} {
+		path, err := service.resolvePath(name)
+		if err != nil {
+			t.Errorf("имя %q отклонено: %v", name, err)
+			continue
+		}
+		if !strings.HasPrefix(path, service.storagePath+string(filepath.Separator)) {
+			t.Errorf("имя %q: путь %q вне хранилища", name, path)
+		}
+	}
+}
+
+func TestUploadFileRejectsTraversalHash(t *testing.T) {
+	service, db := newTestFileService(t, UploadPolicy{})
+	outside := filepath.Join(filepath.Dir(service.storagePath), "escape")
+
+	_, err := service.UploadFile(&models.FileUploadRequest{Name: "report.csv"}, "report.csv", []byte("id,name\n"), "../escape")
+	if !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("ожидалась ErrInvalidPath, получено %v", err)
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("файл записан за пределами хранилища: %v", err)
+	}
+	var count int64
+	db.Model(&models.File{}).Count(&count)
+	if count != 0 {
+		t.Errorf("создано записей файлов: %d", count)
+	}
+}
+
+func TestStoredPathOutsideStorageIsRejected(t *testing.T) {
+	service, db := newTestFileService(t, UploadPolicy{})
+
+	// Запись, путь которой указывает за пределы хранилища
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatalf("запись файла: %v", err)
+	}
+	file := &models.File{Name: "secret", Path: outside, Size: 6, Hash: "hash"}
+	if err := db.Create(file).Error; err != nil {
+		t.Fatalf("создание записи: %v", err)
+	}
+
+	if _, err := service.DownloadFile(file.ID); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("скачивание: ожидалась ErrInvalidPath, получено %v", err)
+	}
+	if err := service.DeleteFile(file.ID); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("удаление: ожидалась ErrInvalidPath, получено %v", err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("файл за пределами хранилища удален: %v", err)
+	}
+}
+
+func TestCompressedFileRoundTrip(t *testing.T) {
+	service, _ := newTestFileService(t, UploadPolicy{CompressMimeTypes: []string{"text/*"}})
+	content := []byte(strings.Repeat("id,name,amount\n1,Отчет,100\n", 200))
+
+	result, err := service.UploadFile(&models.FileUploadRequest{Name: "report.csv"}, "report.csv", content, "csvhash")
+	if err != nil {
+		t.Fatalf("загрузка: %v", err)
+	}
+	file := result.File
+	if !file.Compressed || file.Size != int64(len(content)) || file.StoredSize >= file.Size {
+		t.Fatalf("compressed=%v, size=%d, stored_size=%d: ожидался сжатый файл меньше исходного", file.Compressed, file.Size, file.StoredSize)
+	}
+	info, err := os.Stat(filepath.Join(service.storagePath, "csvhash"))
+	if err != nil || info.Size() != file.StoredSize {
+		t.Fatalf("на диске %v байт (%v), ожидалось %d", info, err, file.StoredSize)
+	}
+
+	downloaded, err := service.DownloadFile(file.ID)
+	if err != nil {
+		t.Fatalf("скачивание: %v", err)
+	}
+	if !bytes.Equal(downloaded.Content, content) {
+		t.Error("скачанный файл отличается от загруженного")
+	}
+
+	// Типы вне COMPRESS_MIME_TYPES хранятся как есть
+	raw, err := service.UploadFile(&models.FileUploadRequest{Name: "data.json"}, "data.json", content, "jsonhash")
+	if err != nil {
+		t.Fatalf("загрузка: %v", err)
+	}
+	if raw.File.Compressed || raw.File.StoredSize != raw.File.Size {
+		t.Errorf("файл без сжатия: compressed=%v, stored_size=%d", raw.File.Compressed, raw.File.StoredSize)
+	}
+}
+
+func TestAssembledUploadIsCompressed(t *testing.T) {
+	service, _ := newTestFileService(t, UploadPolicy{CompressMimeTypes: []string{"text/*"}})
+	content := []byte(strings.Repeat("повторяющаяся строка отчета\n", 100))
+	session, chunks := initTestUpload(t, service, content, 512)
+
+	for n, chunk := range chunks {
+		if _, err := service.UploadChunk(session.ID, session.UploadToken, n, bytes.NewReader(chunk)); err != nil {
+			t.Fatalf("часть %d: %v", n, err)
+		}
+	}
+	result, err := service.CompleteUpload(session.ID, session.UploadToken)
+	if err != nil {
+		t.Fatalf("завершение загрузки: %v", err)
+	}
+	if !result.File.Compressed || result.File.StoredSize >= result.File.Size {
+		t.Fatalf("compressed=%v, size=%d, stored_size=%d", result.File.Compressed, result.File.Size, result.File.StoredSize)
+	}
+
+	downloaded, err := service.DownloadFile(result.File.ID)
+	if err != nil {
+		t.Fatalf("скачивание: %v", err)
+	}
+	if !bytes.Equal(downloaded.Content, content) {
+		t.Error("скачанный файл отличается от загруженного")
+	}
+}
+
+func TestValidateUpload(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdf := []byte("%PDF-1.4\n%тест\n")
+	text := []byte("id,name\n1,Отчет\n")
+
+	tests := []struct {
+		name    string
+		policy  UploadPolicy
+		content []byte
+		wantErr error
+	}{
+		{"без ограничений", UploadPolicy{}, pdf, nil},
+		{"размер на границе", UploadPolicy{MaxSize: int64(len(text))}, text, nil},
+		{"превышен размер", UploadPolicy{MaxSize: int64(len(text)) - 1}, text, ErrFileTooLarge},
+		{"разрешенный тип по шаблону", UploadPolicy{AllowedMimeTypes: []string{"image/*"}}, png, nil},
+		{"тип не из разрешенных", UploadPolicy{AllowedMimeTypes: []string{"image/*", "text/plain"}}, pdf, ErrMimeTypeNotAllowed},
+		{"запрещенный тип", UploadPolicy{DeniedMimeTypes: []string{"application/pdf"}}, pdf, ErrMimeTypeNotAllowed},
+		{"запрет важнее разрешения", UploadPolicy{AllowedMimeTypes: []string{"image/*"}, DeniedMimeTypes: []string{"image/png"}}, png, ErrMimeTypeNotAllowed},
+		{"размер проверяется раньше типа", UploadPolicy{MaxSize: 4, DeniedMimeTypes: []string{"application/pdf"}}, pdf, ErrFileTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestFileService(t, tt.policy)
+			err := service.ValidateUpload(tt.content)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("файл отклонен: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ожидалась %v, получено %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUploadFileRejectedByPolicyCreatesNothing(t *testing.T) {
+	service, db := newTestFileService(t, UploadPolicy{MaxSize: 1024, DeniedMimeTypes: []string{"application/pdf"}})
+
+	if _, err := service.UploadFile(&models.FileUploadRequest{Name: "big.csv"}, "big.csv", bytes.Repeat([]byte("a"), 1025), "big"); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("большой файл: ожидалась ErrFileTooLarge, получено %v", err)
+	}
+	if _, err := service.UploadFile(&models.FileUploadRequest{Name: "report.pdf"}, "report.pdf", []byte("%PDF-1.4\n"), "pdf"); !errors.Is(err, ErrMimeTypeNotAllowed) {
+		t.Errorf("запрещенный тип: ожидалась ErrMimeTypeNotAllowed, получено %v", err)
+	}
+
+	var count int64
+	db.Model(&models.File{}).Count(&count)
+	if count != 0 {
+		t.Errorf("создано записей файлов: %d", count)
+	}
+	entries, _ := os.ReadDir(service.storagePath)
+	if len(entries) != 0 {
+		t.Errorf("в хранилище записаны файлы: %v", entries)
+	}
+}
@@ -0,0 +1,484 @@
+package services
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"storage-service/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Ошибки загрузки по частям
+var (
+	ErrUploadNotFound   = errors.New("сессия загрузки не найдена")
+	ErrUploadCompleted  = errors.New("загрузка уже завершена")
+	ErrChunkOutOfRange  = errors.New("некорректный номер части")
+	ErrUploadIncomplete = errors.New("получены не все части файла")
+	ErrHashMismatch     = errors.New("хеш собранного файла не совпадает с ожидаемым")
+	ErrInvalidUpload    = errors.New("некорректные параметры загрузки")
+	ErrUploadExpired    = errors.New("срок сессии загрузки истек")
+)
+
+// maxUploadChunks ограничивает число частей одной загрузки
+const maxUploadChunks = 10000
+
+// uploadsDir каталог внутри хранилища для частей незавершенных загрузок
+const uploadsDir = "uploads"
+
+// uploadCleanupBatchSize число просроченных сессий, удаляемых за один проход
+const uploadCleanupBatchSize = 100
+
+// InitUpload создает сессию загрузки по частям
+func (s *FileService) InitUpload(req *models.UploadInitRequest) (*models.UploadSessionResponse, error) {
+	if req.TotalChunks > maxUploadChunks {
+		return nil, fmt.Errorf("%w: не более %d частей", ErrInvalidUpload, maxUploadChunks)
+	}
+	hash := strings.ToLower(strings.TrimSpace(req.Hash))
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != md5.Size {
+		return nil, fmt.Errorf("%w: hash должен быть MD5 в hex", ErrInvalidUpload)
+	}
+	if err := s.validateSize(req.TotalSize); err != nil {
+		return nil, err
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка генерации ID загрузки: %w", err)
+	}
+	token, err := newUploadID()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка генерации токена загрузки: %w", err)
+	}
+
+	name := req.Name
+	if name == "" {
+		name = req.Filename
+	}
+
+	session := &models.UploadSession{
+		ID:           id,
+		Name:         name,
+		Filename:     req.Filename,
+		Description:  req.Description,
+		IsPublic:     req.IsPublic,
+		TotalChunks:  req.TotalChunks,
+		TotalSize:    req.TotalSize,
+		ExpectedHash: hash,
+		Status:       models.UploadStatusPending,
+		TokenHash:    hashUploadToken(token),
+	}
+	if s.uploadPolicy.SessionTTL > 0 {
+		expiresAt := time.Now().Add(s.uploadPolicy.SessionTTL)
+		session.ExpiresAt = &expiresAt
+	}
+	if err := s.uploadRepo.CreateSession(session); err != nil {
+		return nil, fmt.Errorf("ошибка создания сессии загрузки: %w", err)
+	}
+
+	response := s.sessionResponse(session, nil)
+	response.UploadToken = token
+	return response, nil
+}
+
+// GetUpload возвращает состояние загрузки, по которому клиент докачивает недостающие части
+func (s *FileService) GetUpload(uploadID, token string) (*models.UploadSessionResponse, error) {
+	session, err := s.getSession(uploadID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := s.uploadRepo.GetChunks(session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения частей загрузки: %w", err)
+	}
+	return s.sessionResponse(session, chunks), nil
+}
+
+// UploadChunk сохраняет часть n (нумерация с 0). Части принимаются в любом порядке,
+// повторная отправка части заменяет ранее полученную. Сумма частей не может превысить
+// объявленный размер файла.
+func (s *FileService) UploadChunk(uploadID, token string, n int, body io.Reader) (*models.UploadSessionResponse, error) {
+	session, err := s.getSession(uploadID, token)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == models.UploadStatusCompleted {
+		return nil, ErrUploadCompleted
+	}
+	if n < 0 || n >= session.TotalChunks {
+		return nil, fmt.Errorf("%w: %d из %d", ErrChunkOutOfRange, n, session.TotalChunks)
+	}
+
+	chunks, err := s.uploadRepo.GetChunks(session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения частей загрузки: %w", err)
+	}
+	remaining := session.TotalSize
+	for _, chunk := range chunks {
+		if chunk.Index != n {
+			remaining -= chunk.Size
+		}
+	}
+
+	chunkPath, err := s.chunkPath(session.ID, n)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(chunkPath), 0755); err != nil {
+		return nil, fmt.Errorf("ошибка создания директории: %w", err)
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы оборванная передача не оставила неполную часть
+	tmpPath := chunkPath + ".tmp"
+	size, err := writeFile(tmpPath, io.LimitReader(body, remaining+1))
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("ошибка сохранения части: %w", err)
+	}
+	if size > remaining {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("%w: части больше объявленного размера файла %d байт", ErrInvalidUpload, session.TotalSize)
+	}
+	if err := os.Rename(tmpPath, chunkPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("ошибка сохранения части: %w", err)
+	}
+
+	if err := s.uploadRepo.SaveChunk(&models.UploadChunk{UploadID: session.ID, Index: n, Size: size}); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения сведений о части: %w", err)
+	}
+
+	chunks, err = s.uploadRepo.GetChunks(session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения частей загрузки: %w", err)
+	}
+	return s.sessionResponse(session, chunks), nil
+}
+
+// CompleteUpload собирает части в порядке номеров, сверяет размер и MD5 хеш и создает файл
+func (s *FileService) CompleteUpload(uploadID, token string) (*models.FileUploadResponse, error) {
+	session, err := s.getSession(uploadID, token)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == models.UploadStatusCompleted {
+		return nil, ErrUploadCompleted
+	}
+
+	chunks, err := s.uploadRepo.GetChunks(session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения частей загрузки: %w", err)
+	}
+	if missing := missingChunks(session.TotalChunks, chunks); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: недостает частей %v", ErrUploadIncomplete, missing)
+	}
+
+	assembledPath, err := s.chunkPath(session.ID, -1)
+	if err != nil {
+		return nil, err
+	}
+	size, hash, head, err := s.assemble(session, assembledPath)
+	if err != nil {
+		os.Remove(assembledPath)
+		return nil, err
+	}
+
+	if size != session.TotalSize {
+		os.Remove(assembledPath)
+		return nil, fmt.Errorf("%w: собрано %d байт, ожидалось %d", ErrInvalidUpload, size, session.TotalSize)
+	}
+	if hash != session.ExpectedHash {
+		os.Remove(assembledPath)
+		return nil, fmt.Errorf("%w: получен %s, ожидался %s", ErrHashMismatch, hash, session.ExpectedHash)
+	}
+	if err := s.validateMimeType(head); err != nil {
+		os.Remove(assembledPath)
+		return nil, err
+	}
+
+	result, err := s.storeAssembled(session, assembledPath, size, hash)
+	if err != nil {
+		os.Remove(assembledPath)
+		return nil, err
+	}
+
+	session.Status = models.UploadStatusCompleted
+	session.FileID = result.File.ID
+	if err := s.uploadRepo.UpdateSession(session); err != nil {
+		return nil, fmt.Errorf("ошибка обновления сессии загрузки: %w", err)
+	}
+	if err := s.uploadRepo.DeleteChunks(session.ID); err != nil {
+		return nil, fmt.Errorf("ошибка удаления сведений о частях: %w", err)
+	}
+	s.removeUploadDir(session.ID)
+
+	return result, nil
+}
+
+// StartUploadCleanup периодически удаляет просроченные загрузки до отмены контекста
+func (s *FileService) StartUploadCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("Остановка удаления просроченных загрузок")
+			return
+		case <-ticker.C:
+			if removed := s.CleanupExpiredUploads(time.Now()); removed > 0 {
+				logrus.Infof("Удалены просроченные загрузки по частям: %d", removed)
+			}
+		}
+	}
+}
+
+// CleanupExpiredUploads удаляет незавершенные сессии, срок которых истек к моменту now,
+// вместе с принятыми частями и возвращает число удаленных сессий
+func (s *FileService) CleanupExpiredUploads(now time.Time) int {
+	sessions, err := s.uploadRepo.FindExpiredSessions(now, uploadCleanupBatchSize)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка поиска просроченных загрузок")
+		return 0
+	}
+
+	removed := 0
+	for _, session := range sessions {
+		s.removeUploadDir(session.ID)
+		if err := s.uploadRepo.DeleteSession(session.ID); err != nil {
+			logrus.WithError(err).Warnf("Не удалось удалить сессию загрузки %s", session.ID)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// removeUploadDir удаляет каталог с частями загрузки
+func (s *FileService) removeUploadDir(uploadID string) {
+	dir, err := s.resolvePath(filepath.Join(uploadsDir, uploadID))
+	if err != nil {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		logrus.WithError(err).Warnf("Не удалось удалить части загрузки %s", uploadID)
+	}
+}
+
+// assemble склеивает части в файл, одновременно считая размер и MD5 хеш
+func (s *FileService) assemble(session *models.UploadSession, assembledPath string) (int64, string, []byte, error) {
+	out, err := os.Create(assembledPath)
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("ошибка создания файла: %w", err)
+	}
+	defer out.Close()
+
+	hasher := md5.New()
+	sniff := &headBuffer{limit: 512}
+	writer := io.MultiWriter(out, hasher, sniff)
+
+	var total int64
+	for n := 0; n < session.TotalChunks; n++ {
+		chunkPath, err := s.chunkPath(session.ID, n)
+		if err != nil {
+			return 0, "", nil, err
+		}
+		written, err := copyFile(writer, chunkPath)
+		if err != nil {
+			return 0, "", nil, fmt.Errorf("ошибка сборки части %d: %w", n, err)
+		}
+		total += written
+	}
+
+	if err := out.Sync(); err != nil {
+		return 0, "", nil, fmt.Errorf("ошибка сохранения файла: %w", err)
+	}
+	return total, hex.EncodeToString(hasher.Sum(nil)), sniff.data, nil
+}
+
+// storeAssembled переносит собранный файл в хранилище и создает запись; дубликат по хешу не сохраняется повторно
+func (s *FileService) storeAssembled(session *models.UploadSession, assembledPath string, size int64, hash string) (*models.FileUploadResponse, error) {
+	existingFile, err := s.fileRepo.GetByHash(hash)
+	if err == nil && existingFile != nil {
+		os.Remove(assembledPath)
+		return &models.FileUploadResponse{
+			File:    existingFile.ToResponse(),
+			Message: "Файл уже существует",
+		}, nil
+	}
+
+	filePath, err := s.resolvePath(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Сжатие по типу файла, как при обычной загрузке; несжатый файл переносится как есть
+	mimeType := s.getMimeType(session.Filename)
+	storedSize, compressed := size, false
+	if s.shouldCompress(mimeType) {
+		if storedSize, compressed, err = compressFile(filePath, assembledPath, size); err != nil {
+			return nil, err
+		}
+	}
+	if compressed {
+		os.Remove(assembledPath)
+	} else if err := os.Rename(assembledPath, filePath); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения файла: %w", err)
+	}
+
+	file := &models.File{
+		Name:        session.Name,
+		Path:        filePath,
+		Size:        size,
+		StoredSize:  storedSize,
+		Compressed:  compressed,
+		MimeType:    mimeType,
+		Hash:        hash,
+		Description: session.Description,
+		IsPublic:    session.IsPublic,
+	}
+	if err := s.fileRepo.Create(file); err != nil {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("ошибка создания записи файла: %w", err)
+	}
+
+	return &models.FileUploadResponse{
+		File:    file.ToResponse(),
+		Message: "Файл успешно загружен",
+	}, nil
+}
+
+// getSession получает сессию загрузки по ID и токену ее создателя. Отсутствующая сессия
+// и неверный токен дают ErrUploadNotFound, истекшая незавершенная — ErrUploadExpired
+func (s *FileService) getSession(uploadID, token string) (*models.UploadSession, error) {
+	session, err := s.uploadRepo.GetSession(uploadID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("ошибка получения сессии загрузки: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(session.TokenHash), []byte(hashUploadToken(token))) != 1 {
+		return nil, ErrUploadNotFound
+	}
+	if session.Status == models.UploadStatusPending && session.ExpiresAt != nil && time.Now().After(*session.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	return session, nil
+}
+
+// hashUploadToken хеш токена загрузки для хранения в сессии
+func hashUploadToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// chunkPath путь к части n загрузки; n < 0 означает собранный файл
+func (s *FileService) chunkPath(uploadID string, n int) (string, error) {
+	name := "assembled"
+	if n >= 0 {
+		name = strconv.Itoa(n)
+	}
+	return s.resolvePath(filepath.Join(uploadsDir, uploadID, name))
+}
+
+// sessionResponse формирует ответ с принятыми и недостающими частями
+func (s *FileService) sessionResponse(session *models.UploadSession, chunks []models.UploadChunk) *models.UploadSessionResponse {
+	response := &models.UploadSessionResponse{
+		ID:             session.ID,
+		Name:           session.Name,
+		Status:         session.Status,
+		TotalChunks:    session.TotalChunks,
+		TotalSize:      session.TotalSize,
+		ReceivedChunks: make([]int, 0, len(chunks)),
+		MissingChunks:  []int{},
+		FileID:         session.FileID,
+		ExpiresAt:      session.ExpiresAt,
+	}
+	for _, chunk := range chunks {
+		response.ReceivedChunks = append(response.ReceivedChunks, chunk.Index)
+		response.ReceivedBytes += chunk.Size
+	}
+	if session.Status != models.UploadStatusCompleted {
+		response.MissingChunks = missingChunks(session.TotalChunks, chunks)
+	}
+	return response
+}
+
+// missingChunks возвращает номера частей, которые еще не получены
+func missingChunks(total int, chunks []models.UploadChunk) []int {
+	received := make(map[int]bool, len(chunks))
+	for _, chunk := range chunks {
+		received[chunk.Index] = true
+	}
+
+	missing := []int{}
+	for n := 0; n < total; n++ {
+		if !received[n] {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
+
+// newUploadID генерирует случайный ID сессии загрузки
+func newUploadID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// writeFile записывает содержимое reader в файл и возвращает число байт
+func writeFile(path string, r io.Reader) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return written, err
+}
+
+// copyFile дописывает содержимое файла в w
+func copyFile(w io.Writer, path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
+
+// headBuffer запоминает первые limit байт потока для определения типа файла
+type headBuffer struct {
+	data  []byte
+	limit int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - len(b.data); remaining > 0 {
+		if len(p) < remaining {
+			remaining = len(p)
+		}
+		b.data = append(b.data, p[:remaining]...)
+	}
+	return len(p), nil
+}
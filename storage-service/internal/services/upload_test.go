@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"storage-service/internal/models"
+	"storage-service/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestFileService создает сервис файлов поверх отдельной SQLite базы и временного хранилища
+func newTestFileService(t *testing.T, policy UploadPolicy) (*FileService, *gorm.DB) {
+	t.Helper()
+
+	storagePath := t.TempDir()
+	dsn := filepath.Join(t.TempDir(), "storage.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.AutoMigrate(&models.File{}, &models.UploadSession{}, &models.UploadChunk{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+
+	service := NewFileService(repository.NewFileRepository(db), repository.NewUploadRepository(db), storagePath, policy)
+	return service, db
+}
+
+// initTestUpload создает сессию для content, разбитого на части по chunkSize байт
+func initTestUpload(t *testing.T, service *FileService, content []byte, chunkSize int) (*models.UploadSessionResponse, [][]byte) {
+	t.Helper()
+
+	var chunks [][]byte
+	for start := 0; start < len(content); start += chunkSize {
+		end := min(start+chunkSize, len(content))
+		chunks = append(chunks, content[start:end])
+	}
+
+	sum := md5.Sum(content)
+	session, err := service.InitUpload(&models.UploadInitRequest{
+		Filename:    "report.txt",
+		TotalChunks: len(chunks),
+		TotalSize:   int64(len(content)),
+		Hash:        hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		t.Fatalf("создание сессии: %v", err)
+	}
+	if session.UploadToken == "" {
+		t.Fatal("сессия создана без токена загрузки")
+	}
+	return session, chunks
+}
+
+func TestUploadChunksOutOfOrder(t *testing.T) {
+	service, _ := newTestFileService(t, UploadPolicy{})
+	content := []byte("первая часть|вторая часть|третья часть")
+	session, chunks := initTestUpload(t, service, content, 16)
+
+	for n := len(chunks) - 1; n >= 0; n-- {
+		if _, err := service.UploadChunk(session.ID, session.UploadToken, n, bytes.NewReader(chunks[n])); err != nil {
+			t.Fatalf("часть %d: %v", n, err)
+		}
+	}
+
+	state, err := service.GetUpload(session.ID, session.UploadToken)
+	if err != nil {
+		t.Fatalf("состояние загрузки: %v", err)
+	}
+	if len(state.MissingChunks) != 0 || state.ReceivedBytes != int64(len(content)) {
+		t.Fatalf("недостает частей %v, получено %d байт", state.MissingChunks, state.ReceivedBytes)
+	}
+
+	result, err := service.CompleteUpload(session.ID, session.UploadToken)
+	if err != nil {
+		t.Fatalf("завершение загрузки: %v", err)
+	}
+	stored, err := os.ReadFile(result.File.Path)
+	if err != nil {
+		t.Fatalf("чтение собранного файла: %v", err)
+	}
+	if !bytes.Equal(stored, content) {
+		t.Fatalf("собранный файл %q, ожидался %q", stored, content)
+	}
+
+	if _, err := service.CompleteUpload(session.ID, session.UploadToken); !errors.Is(err, ErrUploadCompleted) {
+		t.Fatalf("повторное завершение: ожидалась ErrUploadCompleted, получено %v", err)
+	}
+}
+
+func TestCompleteUploadHashMismatch(t *testing.T) {
+	service, _ := newTestFileService(t, UploadPolicy{})
+	session, chunks := initTestUpload(t, service, []byte("report contents"), 8)
+
+	for n, chunk := range chunks {
+		if n == 0 {
+			chunk = append([]byte{chunk[0] ^ 0xFF}, chunk[1:]...)
+		}
+		if _, err := service.UploadChunk(session.ID, session.UploadToken, n, bytes.NewReader(chunk)); err != nil {
+			t.Fatalf("часть %d: %v", n, err)
+		}
+	}
+
+	if _, err := service.CompleteUpload(session.ID, session.UploadToken); !errors.Is(err, ErrHashMismatch) {
+		t.Fatalf("ожидалась ErrHashMismatch, получено %v", err)
+	}
+	state, err := service.GetUpload(session.ID, session.UploadToken)
+	if err != nil {
+		t.Fatalf("состояние загрузки: %v", err)
+	}
+	if state.Status != models.UploadStatusPending {
+		t.Fatalf("статус после несовпадения хеша %s, ожидался %s", state.Status, models.UploadStatusPending)
+	}
+}
+
+func TestCompleteUploadMissingChunks(t *testing.T) {
+	service, _ := newTestFileService(t, UploadPolicy{})
+	session, chunks := initTestUpload(t, service, []byte("abcdefghij"), 4)
+
+	if _, err := service.UploadChunk(session.ID, session.UploadToken, 1, bytes.NewReader(chunks[1])); err != nil {
+		t.Fatalf("часть 1: %v", err)
+	}
+	if _, err := service.CompleteUpload(session.ID, session.UploadToken); !errors.Is(err, ErrUploadIncomplete) {
+		t.Fatalf("ожидалась ErrUploadIncomplete, получено %v", err)
+	}
+}
+
+func TestUploadChunksExceedingTotalSize(t *testing.T) {
+	service, _ := newTestFileService(t, UploadPolicy{})
+	session, chunks := initTestUpload(t, service, []byte("abcdefghij"), 5)
+
+	if _, err := service.UploadChunk(session.ID, session.UploadToken, 0, bytes.NewReader(chunks[0])); err != nil {
+		t.Fatalf("часть 0: %v", err)
+	}
+	if _, err := service.UploadChunk(session.ID, session.UploadToken, 1, bytes.NewReader([]byte("123456"))); !errors.Is(err, ErrInvalidUpload) {
+		t.Fatalf("ожидалась ErrInvalidUpload, получено %v", err)
+	}
+	// Повторная отправка части заменяет прежнюю и не учитывается дважды
+	if _, err := service.UploadChunk(session.ID, session.UploadToken, 0, bytes.NewReader(chunks[0])); err != nil {
+		t.Fatalf("повторная часть 0: %v", err)
+	}
+}
+
+func TestUploadRequiresSessionToken(t *testing.T) {
+	service, _ := newTestFileService(t, UploadPolicy{})
+	session, chunks := initTestUpload(t, service, []byte("abcdefghij"), 5)
+	other, _ := initTestUpload(t, service, []byte("0123456789"), 5)
+
+	for _, token := range []string{"", other.UploadToken} {
+		if _, err := service.GetUpload(session.ID, token); !errors.Is(err, ErrUploadNotFound) {
+			t.Fatalf("состояние с токеном %q: ожидалась ErrUploadNotFound, получено %v", token, err)
+		}
+		if _, err := service.UploadChunk(session.ID, token, 0, bytes.NewReader(chunks[0])); !errors.Is(err, ErrUploadNotFound) {
+			t.Fatalf("часть с токеном %q: ожидалась ErrUploadNotFound, получено %v", token, err)
+		}
+		if _, err := service.CompleteUpload(session.ID, token); !errors.Is(err, ErrUploadNotFound) {
+			t.Fatalf("завершение с токеном %q: ожидалась ErrUploadNotFound, получено %v", token, err)
+		}
+	}
+}
+
+func TestExpiredUploadsAreRejectedAndCleanedUp(t *testing.T) {
+	service, db := newTestFileService(t, UploadPolicy{SessionTTL: time.Hour})
+	expired, chunks := initTestUpload(t, service, []byte("abcdefghij"), 5)
+	active, _ := initTestUpload(t, service, []byte("0123456789"), 5)
+
+	if _, err := service.UploadChunk(expired.ID, expired.UploadToken, 0, bytes.NewReader(chunks[0])); err != nil {
+		t.Fatalf("часть 0: %v", err)
+	}
+	past := time.Now().Add(-time.Minute)
+	if err := db.Model(&models.UploadSession{}).Where("id = ?", expired.ID).Update("expires_at", past).Error; err != nil {
+		t.Fatalf("перевод сессии в просроченные: %v", err)
+	}
+
+	if _, err := service.UploadChunk(expired.ID, expired.UploadToken, 1, bytes.NewReader(chunks[1])); !errors.Is(err, ErrUploadExpired) {
+		t.Fatalf("ожидалась ErrUploadExpired, получено %v", err)
+	}
+
+	if removed := service.CleanupExpiredUploads(time.Now()); removed != 1 {
+		t.Fatalf("удалено сессий %d, ожидалась 1", removed)
+	}
+	if _, err := service.GetUpload(expired.ID, expired.UploadToken); !errors.Is(err, ErrUploadNotFound) {
+		t.Fatalf("после удаления ожидалась ErrUploadNotFound, получено %v", err)
+	}
+	var chunkCount int64
+	db.Model(&models.UploadChunk{}).Where("upload_id = ?", expired.ID).Count(&chunkCount)
+	if chunkCount != 0 {
+		t.Fatalf("осталось сведений о частях: %d", chunkCount)
+	}
+	if _, err := os.Stat(filepath.Join(service.storagePath, uploadsDir, expired.ID)); !os.IsNotExist(err) {
+		t.Fatalf("каталог частей просроченной загрузки не удален: %v", err)
+	}
+
+	if _, err := service.GetUpload(active.ID, active.UploadToken); err != nil {
+		t.Fatalf("действующая сессия: %v", err)
+	}
+}
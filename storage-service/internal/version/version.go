@@ -0,0 +1,35 @@
+package version
+
+import "time"
+
+// Информация о сборке, задается при компиляции через -ldflags:
+//
+//	go build -ldflags "-X storage-service/internal/version.Version=1.2.0 -X storage-service/internal/version.Commit=abc123 -X storage-service/internal/version.BuildTime=2024-01-01T00:00:00Z"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// startedAt время запуска процесса для расчета uptime
+var startedAt = time.Now()
+
+// Uptime возвращает время работы сервиса
+func Uptime() time.Duration {
+	return time.Since(startedAt)
+}
+
+// Health формирует единый ответ /health с информацией о сборке и uptime
+func Health(service string) map[string]interface{} {
+	uptime := Uptime()
+	return map[string]interface{}{
+		"status":         "healthy",
+		"service":        service,
+		"version":        Version,
+		"commit":         Commit,
+		"build_time":     BuildTime,
+		"uptime":         uptime.Truncate(time.Second).String(),
+		"uptime_seconds": int64(uptime.Seconds()),
+		"timestamp":      time.Now().Unix(),
+	}
+}
@@ -0,0 +1,73 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ReportClient клиент report-service
+type ReportClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewReportClient создает клиент report-service. Пустой URL отключает клиент.
+func NewReportClient(baseURL string) *ReportClient {
+	if baseURL == "" {
+		return nil
+	}
+	return &ReportClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// TemplateUsage использование шаблона по данным report-service
+type TemplateUsage struct {
+	ActiveReports int64      `json:"active_reports"`
+	TotalReports  int64      `json:"total_reports"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+}
+
+// GetTemplateUsage возвращает статистику отчетов, построенных по шаблону.
+// authHeader пробрасывается из исходного запроса пользователя.
+func (c *ReportClient) GetTemplateUsage(ctx context.Context, templateID uint, authHeader string) (*TemplateUsage, error) {
+	url := fmt.Sprintf("%s/api/v1/templates/%d/usage", c.baseURL, templateID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса к report-service: %w", err)
+	}
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("report-service недоступен: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("report-service вернул статус %d", resp.StatusCode)
+	}
+
+	var usage TemplateUsage
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		return nil, fmt.Errorf("ошибка разбора ответа report-service: %w", err)
+	}
+
+	return &usage, nil
+}
+
+// CountActiveReports возвращает число активных отчетов, построенных по шаблону
+func (c *ReportClient) CountActiveReports(ctx context.Context, templateID uint, authHeader string) (int64, error) {
+	usage, err := c.GetTemplateUsage(ctx, templateID, authHeader)
+	if err != nil {
+		return 0, err
+	}
+	return usage.ActiveReports, nil
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/sirupsen/logrus"
@@ -11,9 +13,32 @@ type Config struct {
 	Port        int    `envconfig:"PORT" default:"8082"`
 	Environment string `envconfig:"ENVIRONMENT" default:"development"`
 	LogLevel    string `envconfig:"LOG_LEVEL" default:"info"`
+	LogFormat   string `envconfig:"LOG_FORMAT" default:""` // json или text
+	// ShutdownTimeout время на завершение запросов при остановке
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"25s"`
 
 	DatabaseURL string `envconfig:"DATABASE_URL" required:"true"`
-	JWTSecret   string `envconfig:"JWT_SECRET" required:"true"`
+	// DBConnectAttempts и DBConnectInterval задают ожидание БД при старте: число попыток и начальный интервал
+	DBConnectAttempts int           `envconfig:"DB_CONNECT_ATTEMPTS" default:"10"`
+	DBConnectInterval time.Duration `envconfig:"DB_CONNECT_INTERVAL" default:"1s"`
+	JWTSecret         string        `envconfig:"JWT_SECRET" required:"true"`
+
+	// UserServiceURL адрес User Service для проверки API токенов; APITokenCacheTTL время
+	// кеширования проверки, отзыв токена вступает в силу не позже чем через этот интервал
+	UserServiceURL   string        `envconfig:"USER_SERVICE_URL" default:"http://localhost:8081"`
+	APITokenCacheTTL time.Duration `envconfig:"API_TOKEN_CACHE_TTL" default:"30s"`
+
+	ReportServiceURL string `envconfig:"REPORT_SERVICE_URL" default:"http://localhost:8083"`
+
+	DefaultPageLimit int `envconfig:"DEFAULT_PAGE_LIMIT" default:"10"`
+	MaxPageLimit     int `envconfig:"MAX_PAGE_LIMIT" default:"100"`
+
+	// VariablesAllLimit максимальное число переменных в ответе GET /variables?all=true
+	VariablesAllLimit int `envconfig:"VARIABLES_ALL_LIMIT" default:"1000"`
+
+	// Ограничения рендеринга шаблонов
+	RenderMaxOutputBytes int           `envconfig:"RENDER_MAX_OUTPUT_BYTES" default:"5242880"`
+	RenderTimeout        time.Duration `envconfig:"RENDER_TIMEOUT" default:"2s"`
 
 	AutoMigrate bool `envconfig:"AUTO_MIGRATE" default:"true"`
 	SeedData    bool `envconfig:"SEED_DATA" default:"true"`
@@ -29,18 +54,31 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// SetupLogger применяет LOG_FORMAT и LOG_LEVEL. Без LOG_FORMAT в production
+// используется JSON, иначе текст; некорректный уровень заменяется на info.
 func (c *Config) SetupLogger() {
+	switch strings.ToLower(c.LogFormat) {
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	default:
+		if c.Environment == "production" {
+			logrus.SetFormatter(&logrus.JSONFormatter{})
+		} else {
+			logrus.SetFormatter(&logrus.TextFormatter{})
+		}
+		if c.LogFormat != "" {
+			logrus.Warnf("Неизвестный LOG_FORMAT %q, формат выбран по окружению", c.LogFormat)
+		}
+	}
+
 	level, err := logrus.ParseLevel(c.LogLevel)
 	if err != nil {
 		level = logrus.InfoLevel
+		logrus.Warnf("Некорректный LOG_LEVEL %q, используется info", c.LogLevel)
 	}
 	logrus.SetLevel(level)
-
-	if c.Environment == "production" {
-		logrus.SetFormatter(&logrus.JSONFormatter{})
-	} else {
-		logrus.SetFormatter(&logrus.TextFormatter{})
-	}
 }
 
 func (c *Config) IsDevelopment() bool {
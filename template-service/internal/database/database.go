@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 
+	"template-service/internal/audit"
 	"template-service/internal/config"
 	"template-service/internal/models"
 
@@ -19,6 +20,8 @@ func Connect(databaseURL string) (*gorm.DB, error) {
 
 	db, err = gorm.Open(postgres.Open(databaseURL), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// Нарушения уникальности приходят как gorm.ErrDuplicatedKey
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("ошибка подключения к базе данных: %w", err)
@@ -41,10 +44,16 @@ func Migrate() error {
 		return fmt.Errorf("база данных не подключена")
 	}
 
+	if err := dedupTemplateNames(); err != nil {
+		return fmt.Errorf("ошибка удаления дубликатов шаблонов: %w", err)
+	}
+
 	if err := db.AutoMigrate(
 		&models.Template{},
 		&models.TemplateCategory{},
 		&models.TemplateVariable{},
+		&models.TemplateRevision{},
+		&audit.AuditLog{},
 	); err != nil {
 		return fmt.Errorf("ошибка миграции моделей: %w", err)
 	}
@@ -53,6 +62,29 @@ func Migrate() error {
 	return nil
 }
 
+// dedupTemplateNames мягко удаляет повторы имени шаблона в категории,
+// оставляя самый ранний, чтобы можно было создать уникальный индекс
+func dedupTemplateNames() error {
+	if !db.Migrator().HasTable(&models.Template{}) {
+		return nil
+	}
+
+	result := db.Exec(`
+		UPDATE templates SET deleted_at = NOW()
+		WHERE deleted_at IS NULL AND id NOT IN (
+			SELECT MIN(id) FROM templates
+			WHERE deleted_at IS NULL
+			GROUP BY name, category
+		)`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Удалено дубликатов шаблонов: %d", result.RowsAffected)
+	}
+	return nil
+}
+
 func SeedData() error {
 	if db == nil {
 		return fmt.Errorf("база данных не подключена")
@@ -113,7 +145,7 @@ func SeedData() error {
 	}
 
 	for _, t := range templates {
-		if err := db.Create(&t).Error; err != nil {
+		if err := db.Where(models.Template{Name: t.Name, Category: t.Category}).FirstOrCreate(&t).Error; err != nil {
 			log.Printf("Ошибка создания шаблона %s: %v", t.Name, err)
 		}
 	}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Ошибки валидации называют поля так же, как они записаны в JSON
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+// jsonFieldName возвращает имя поля из тега json или имя поля структуры
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
+
+// fieldErrorMessage формирует сообщение для ошибки проверки одного поля
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "обязательное поле"
+	case "email":
+		return "некорректный email"
+	case "url":
+		return "некорректный URL"
+	case "min":
+		if fe.Kind() == reflect.String || fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map {
+			return fmt.Sprintf("длина должна быть не меньше %s", fe.Param())
+		}
+		return fmt.Sprintf("значение должно быть не меньше %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String || fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map {
+			return fmt.Sprintf("длина должна быть не больше %s", fe.Param())
+		}
+		return fmt.Sprintf("значение должно быть не больше %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("допустимые значения: %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	default:
+		return fmt.Sprintf("не прошло проверку %s", fe.Tag())
+	}
+}
+
+// bindingErrors переводит ошибку ShouldBindJSON в карту поле → сообщение.
+// Возвращает nil, если ошибка не относится к конкретным полям (например, некорректный JSON).
+func bindingErrors(err error) map[string]string {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make(map[string]string, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields[fe.Field()] = fieldErrorMessage(fe)
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return map[string]string{typeErr.Field: fmt.Sprintf("ожидается значение типа %s", typeErr.Type)}
+	}
+	return nil
+}
+
+// respondBindingError отвечает 400: {"errors": {поле: сообщение}} для ошибок полей
+// и {"error": ...} для тела запроса, которое не удалось разобрать
+func respondBindingError(c *gin.Context, err error) {
+	if fields := bindingErrors(err); fields != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": fields})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное тело запроса: " + err.Error()})
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 
+	"template-service/internal/audit"
 	"template-service/internal/metrics"
 	"template-service/internal/models"
 	"template-service/internal/services"
@@ -13,15 +16,20 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// auditEntityTemplate тип сущности шаблона в журнале аудита
+const auditEntityTemplate = "template"
+
 type TemplateHandler struct {
 	templateService *services.TemplateService
 	metrics         *metrics.Metrics
+	auditLog        *audit.Logger
 }
 
-func NewTemplateHandler(templateService *services.TemplateService, metrics *metrics.Metrics) *TemplateHandler {
+func NewTemplateHandler(templateService *services.TemplateService, metrics *metrics.Metrics, auditLog *audit.Logger) *TemplateHandler {
 	return &TemplateHandler{
 		templateService: templateService,
 		metrics:         metrics,
+		auditLog:        auditLog,
 	}
 }
 
@@ -31,31 +39,40 @@ func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
 	var req models.TemplateCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.metrics.RecordBusinessOperation("template-service", "create_template", time.Since(start), false)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
-	template, err := h.templateService.CreateTemplate(&req)
+	actorID, _ := currentAuthor(c)
+	template, err := h.templateService.CreateTemplate(actorID, &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка создания шаблона")
 		h.metrics.RecordBusinessOperation("template-service", "create_template", time.Since(start), false)
+		if errors.Is(err, services.ErrTemplateNameConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	h.metrics.RecordBusinessOperation("template-service", "create_template", time.Since(start), true)
+	h.auditLog.Record(actorID, audit.ActionCreate, auditEntityTemplate, template.ID, nil, template)
 	c.JSON(http.StatusCreated, template)
 }
 
 // GetTemplates получение списка шаблонов
 func (h *TemplateHandler) GetTemplates(c *gin.Context) {
 	start := time.Now()
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 	category := c.Query("category")
+	templateType := c.Query("type")
 	active := c.Query("active")
 
-	templates, total, err := h.templateService.GetTemplates(page, limit, category, active)
+	templates, total, err := h.templateService.GetTemplates(page, limit, category, templateType, active)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка получения шаблонов")
 		h.metrics.RecordBusinessOperation("template-service", "get_templates", time.Since(start), false)
@@ -102,20 +119,88 @@ func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
 
 	var req models.TemplateUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
-	template, err := h.templateService.UpdateTemplate(uint(id), &req)
+	authorID, authorName := currentAuthor(c)
+	before, _ := h.templateService.GetTemplate(uint(id))
+	template, err := h.templateService.UpdateTemplate(uint(id), authorID, authorName, &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка обновления шаблона")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrTemplateNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTemplateNameConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.auditLog.Record(authorID, audit.ActionUpdate, auditEntityTemplate, template.ID, before, template)
+	c.JSON(http.StatusOK, template)
+}
+
+// GetTemplateRevisions получение истории версий шаблона
+func (h *TemplateHandler) GetTemplateRevisions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		return
+	}
+
+	revisions, err := h.templateService.GetTemplateRevisions(uint(id))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения истории шаблона")
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.JSON(http.StatusOK, revisions)
+}
+
+// RevertTemplate откат шаблона к указанной версии
+func (h *TemplateHandler) RevertTemplate(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		return
+	}
+
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || rev <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный номер версии"})
+		return
+	}
+
+	authorID, authorName := currentAuthor(c)
+	before, _ := h.templateService.GetTemplate(uint(id))
+	template, err := h.templateService.RevertTemplate(uint(id), rev, authorID, authorName)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка отката шаблона")
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditLog.Record(authorID, audit.ActionUpdate, auditEntityTemplate, template.ID, before, template)
+
 	c.JSON(http.StatusOK, template)
 }
 
+// currentAuthor возвращает автора изменения из JWT
+func currentAuthor(c *gin.Context) (uint, string) {
+	var authorID uint
+	if v, ok := c.Get("user_id"); ok {
+		authorID, _ = v.(uint)
+	}
+	authorName := c.GetString("name")
+	return authorID, authorName
+}
+
 // DeleteTemplate удаление шаблона
 func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
 	idStr := c.Param("id")
@@ -125,15 +210,52 @@ func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
 		return
 	}
 
-	if err := h.templateService.DeleteTemplate(uint(id)); err != nil {
+	force := c.Query("force") == "true"
+	before, _ := h.templateService.GetTemplate(uint(id))
+	if err := h.templateService.DeleteTemplate(c.Request.Context(), uint(id), force, c.GetHeader("Authorization")); err != nil {
 		logrus.WithError(err).Error("Ошибка удаления шаблона")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrTemplateNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTemplateInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
+	actorID, _ := currentAuthor(c)
+	h.auditLog.Record(actorID, audit.ActionDelete, auditEntityTemplate, uint(id), before, nil)
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// GetTemplateUsage число отчетов, использующих шаблон, и время последнего использования
+func (h *TemplateHandler) GetTemplateUsage(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		return
+	}
+
+	usage, err := h.templateService.GetTemplateUsage(c.Request.Context(), uint(id), c.GetHeader("Authorization"))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения использования шаблона")
+		switch {
+		case errors.Is(err, services.ErrTemplateNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUsageUnavailable):
+			respondRetryLater(c, http.StatusServiceUnavailable, services.ErrUsageUnavailable.Error(), usageRetryAfter)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
 // SearchTemplates поиск шаблонов
 func (h *TemplateHandler) SearchTemplates(c *gin.Context) {
 	query := c.Query("q")
@@ -142,8 +264,10 @@ func (h *TemplateHandler) SearchTemplates(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 
 	templates, total, err := h.templateService.SearchTemplates(query, page, limit)
 	if err != nil {
@@ -160,24 +284,165 @@ func (h *TemplateHandler) SearchTemplates(c *gin.Context) {
 	})
 }
 
+// BatchGetTemplates получение нескольких шаблонов по списку ID
+func (h *TemplateHandler) BatchGetTemplates(c *gin.Context) {
+	var req models.TemplateBatchGetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	result, err := h.templateService.GetTemplatesByIDs(req.IDs)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка пакетного получения шаблонов")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// BulkSetTemplatesActive массовое включение или отключение шаблонов
+func (h *TemplateHandler) BulkSetTemplatesActive(c *gin.Context) {
+	var req models.TemplateBulkActiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	authorID, _ := currentAuthor(c)
+	before, err := h.templateService.GetTemplatesByIDs(req.IDs)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения шаблонов")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.templateService.SetTemplatesActive(req.IDs, *req.IsActive, authorID)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка массового изменения активности шаблонов")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, template := range result.Updated {
+		previous := before.Templates[template.ID]
+		h.auditLog.Record(authorID, audit.ActionUpdate, auditEntityTemplate, template.ID, previous, template)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 // RenderTemplate рендеринг шаблона
 func (h *TemplateHandler) RenderTemplate(c *gin.Context) {
 	var req models.RenderTemplateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
-	result, err := h.templateService.RenderTemplate(&req)
+	result, err := h.templateService.RenderTemplate(c.Request.Context(), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка рендеринга шаблона")
+		switch {
+		case errors.Is(err, services.ErrTemplateNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrRenderOutputTooLarge):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrRenderTimeout):
+			respondRetryLater(c, http.StatusServiceUnavailable, err.Error(), renderRetryAfter)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ValidateTemplate проверка рендеринга шаблона без возврата результата.
+// Невалидный шаблон возвращает 422 с перечнем проблем.
+func (h *TemplateHandler) ValidateTemplate(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		return
+	}
+
+	var req models.ValidateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondBindingError(c, err)
+		return
+	}
+
+	result, err := h.templateService.ValidateTemplate(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка проверки шаблона")
+		if errors.Is(err, services.ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if !result.Valid {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
 	c.JSON(http.StatusOK, result)
 }
 
+// ExportTemplateBundle выгрузка шаблона с переменными и категорией
+func (h *TemplateHandler) ExportTemplateBundle(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		return
+	}
+
+	bundle, err := h.templateService.ExportBundle(uint(id))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка выгрузки пакета шаблона")
+		if errors.Is(err, services.ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ImportTemplateBundle импорт шаблона из пакета с созданием категории и переменных
+func (h *TemplateHandler) ImportTemplateBundle(c *gin.Context) {
+	var bundle models.TemplateBundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	actorID, _ := currentAuthor(c)
+	result, err := h.templateService.ImportBundle(actorID, &bundle)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка импорта пакета шаблона")
+		switch {
+		case errors.Is(err, services.ErrInvalidBundle):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTemplateNameConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.auditLog.Record(actorID, audit.ActionCreate, auditEntityTemplate, result.Template.ID, nil, result.Template)
+	c.JSON(http.StatusCreated, result)
+}
+
 type TemplateCategoryHandler struct {
 	categoryService *services.TemplateCategoryService
 }
@@ -191,14 +456,14 @@ func NewTemplateCategoryHandler(categoryService *services.TemplateCategoryServic
 func (h *TemplateCategoryHandler) CreateCategory(c *gin.Context) {
 	var req models.TemplateCategoryCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
 	category, err := h.categoryService.CreateCategory(&req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка создания категории")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCategoryError(c, err)
 		return
 	}
 
@@ -207,8 +472,10 @@ func (h *TemplateCategoryHandler) CreateCategory(c *gin.Context) {
 
 // GetCategories получение списка категорий
 func (h *TemplateCategoryHandler) GetCategories(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 	active := c.Query("active")
 
 	categories, total, err := h.categoryService.GetCategories(page, limit, active)
@@ -226,6 +493,18 @@ func (h *TemplateCategoryHandler) GetCategories(c *gin.Context) {
 	})
 }
 
+// GetCategoryTree получение категорий в виде дерева
+func (h *TemplateCategoryHandler) GetCategoryTree(c *gin.Context) {
+	tree, err := h.categoryService.GetCategoryTree(c.Query("active"))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения дерева категорий")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": tree})
+}
+
 // GetCategory получение категории по ID
 func (h *TemplateCategoryHandler) GetCategory(c *gin.Context) {
 	idStr := c.Param("id")
@@ -256,14 +535,14 @@ func (h *TemplateCategoryHandler) UpdateCategory(c *gin.Context) {
 
 	var req models.TemplateCategoryUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
 	category, err := h.categoryService.UpdateCategory(uint(id), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка обновления категории")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCategoryError(c, err)
 		return
 	}
 
@@ -288,13 +567,27 @@ func (h *TemplateCategoryHandler) DeleteCategory(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// respondCategoryError переводит ошибки изменения категории в ответ API
+func respondCategoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrParentCategoryNotFound), errors.Is(err, services.ErrCategoryCycle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 type TemplateVariableHandler struct {
 	variableService *services.TemplateVariableService
+	allLimit        int
 }
 
-func NewTemplateVariableHandler(variableService *services.TemplateVariableService) *TemplateVariableHandler {
+// NewTemplateVariableHandler создает обработчик переменных; allLimit ограничивает
+// число переменных, возвращаемых без пагинации при all=true
+func NewTemplateVariableHandler(variableService *services.TemplateVariableService, allLimit int) *TemplateVariableHandler {
 	return &TemplateVariableHandler{
 		variableService: variableService,
+		allLimit:        allLimit,
 	}
 }
 
@@ -302,7 +595,7 @@ func NewTemplateVariableHandler(variableService *services.TemplateVariableServic
 func (h *TemplateVariableHandler) CreateVariable(c *gin.Context) {
 	var req models.TemplateVariableCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
@@ -316,11 +609,23 @@ func (h *TemplateVariableHandler) CreateVariable(c *gin.Context) {
 	c.JSON(http.StatusCreated, variable)
 }
 
-// GetVariables получение списка переменных
+// GetVariables получение списка переменных. С all=true возвращает все переменные
+// шаблона template_id одной страницей, но не больше allLimit
 func (h *TemplateVariableHandler) GetVariables(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	all := c.Query("all") == "true"
+	page, limit := 1, h.allLimit
+	if !all {
+		var ok bool
+		page, limit, ok = parsePagination(c)
+		if !ok {
+			return
+		}
+	}
 	templateIDStr := c.Query("template_id")
+	if all && templateIDStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Для all=true необходимо указать template_id"})
+		return
+	}
 
 	var templateID uint
 	if templateIDStr != "" {
@@ -377,7 +682,7 @@ func (h *TemplateVariableHandler) UpdateVariable(c *gin.Context) {
 
 	var req models.TemplateVariableUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Рекомендуемые паузы перед повтором для ответов 429/503
+const (
+	usageRetryAfter  = 30 * time.Second
+	renderRetryAfter = 5 * time.Second
+)
+
+// retryAfterSeconds округляет паузу вверх до целых секунд, но не меньше одной
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
+
+// respondRetryLater отвечает ошибкой временной недоступности: выставляет заголовок
+// Retry-After и дублирует паузу в теле в поле retry_after_seconds
+func respondRetryLater(c *gin.Context, status int, message string, retryAfter time.Duration) {
+	seconds := retryAfterSeconds(retryAfter)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(status, gin.H{"error": message, "retry_after_seconds": seconds})
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APITokenPrefix префикс API токена пользователя; остальные токены проверяются как JWT
+const APITokenPrefix = "usk_"
+
+// maxCachedAPITokens после этого размера из кеша удаляются истекшие записи
+const maxCachedAPITokens = 1000
+
+// ErrInvalidAPIToken User Service отклонил API токен
+var ErrInvalidAPIToken = errors.New("недействительный API токен")
+
+// APITokenIdentity владелец и права API токена
+type APITokenIdentity struct {
+	UserID uint     `json:"user_id"`
+	Name   string   `json:"name"`
+	Email  string   `json:"email"`
+	Role   string   `json:"role"`
+	Scopes []string `json:"scopes"`
+}
+
+type cachedAPIToken struct {
+	identity  *APITokenIdentity
+	expiresAt time.Time
+}
+
+// APITokenVerifier проверяет API токены через User Service. Результат проверки
+// кешируется на cacheTTL, поэтому отзыв токена вступает в силу не позже чем через cacheTTL.
+type APITokenVerifier struct {
+	introspectURL string
+	client        *http.Client
+	cacheTTL      time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedAPIToken
+}
+
+// NewAPITokenVerifier создает проверку API токенов через User Service
+func NewAPITokenVerifier(userServiceURL string, cacheTTL time.Duration) *APITokenVerifier {
+	return &APITokenVerifier{
+		introspectURL: strings.TrimRight(userServiceURL, "/") + "/api/v1/auth/tokens/introspect",
+		client:        &http.Client{Timeout: 5 * time.Second},
+		cacheTTL:      cacheTTL,
+		cache:         make(map[string]cachedAPIToken),
+	}
+}
+
+// Verify возвращает владельца и права API токена
+func (v *APITokenVerifier) Verify(ctx context.Context, token string) (*APITokenIdentity, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	v.mu.Lock()
+	cached, ok := v.cache[key]
+	v.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.identity, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"token": token})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.introspectURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса проверки токена: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса к User Service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusBadRequest:
+		return nil, ErrInvalidAPIToken
+	default:
+		return nil, fmt.Errorf("User Service вернул статус %d при проверке токена", resp.StatusCode)
+	}
+
+	var identity APITokenIdentity
+	if err := json.NewDecoder(resp.Body).Decode(&identity); err != nil {
+		return nil, fmt.Errorf("ошибка разбора ответа User Service: %w", err)
+	}
+
+	if v.cacheTTL > 0 {
+		now := time.Now()
+		v.mu.Lock()
+		if len(v.cache) >= maxCachedAPITokens {
+			for k, entry := range v.cache {
+				if now.After(entry.expiresAt) {
+					delete(v.cache, k)
+				}
+			}
+		}
+		v.cache[key] = cachedAPIToken{identity: &identity, expiresAt: now.Add(v.cacheTTL)}
+		v.mu.Unlock()
+	}
+
+	return &identity, nil
+}
+
+// RequireScope ограничивает запросы по API токену правами на группу маршрутов:
+// GET и HEAD требуют права resource:read, остальные методы — resource:write.
+// Запросы по JWT ограничиваются только ролью пользователя.
+func RequireScope(resource string) gin.HandlerFunc {
+	return requireScope(resource, false)
+}
+
+// RequireReadScope требует права resource:read для POST маршрутов, которые только
+// читают данные (рендеринг, пакетное получение)
+func RequireReadScope(resource string) gin.HandlerFunc {
+	return requireScope(resource, true)
+}
+
+func requireScope(resource string, readOnly bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("token_scopes")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		scope := resource + ":write"
+		if readOnly || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = resource + ":read"
+		}
+
+		scopes, _ := value.([]string)
+		for _, granted := range scopes {
+			if granted == scope {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":          "Insufficient token scope",
+			"required_scope": scope,
+		})
+		c.Abort()
+	}
+}
+
+// setAPITokenIdentity сохраняет владельца и права API токена в контексте запроса
+func setAPITokenIdentity(c *gin.Context, identity *APITokenIdentity) {
+	c.Set("user_id", identity.UserID)
+	c.Set("name", identity.Name)
+	c.Set("email", identity.Email)
+	c.Set("role", identity.Role)
+	c.Set("auth_method", "api_token")
+	c.Set("token_scopes", identity.Scopes)
+}
@@ -8,13 +8,15 @@ import (
 
 type Template struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"not null"`
+	Name        string         `json:"name" gorm:"not null;uniqueIndex:idx_templates_name_category,where:deleted_at IS NULL"`
 	Description string         `json:"description"`
 	Content     string         `json:"content" gorm:"type:text"`
 	Type        string         `json:"type" gorm:"not null"` // html, pdf, excel, csv
-	Category    string         `json:"category"`
+	Category    string         `json:"category" gorm:"uniqueIndex:idx_templates_name_category,where:deleted_at IS NULL"`
 	Variables   string         `json:"variables" gorm:"type:text"` // JSON переменные
 	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	CreatedBy   uint           `json:"created_by"` // ID пользователя, создавшего шаблон
+	UpdatedBy   uint           `json:"updated_by"` // ID пользователя, последним изменившего шаблон
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
@@ -24,10 +26,27 @@ func (Template) TableName() string {
 	return "templates"
 }
 
+// TemplateRevision предыдущая версия содержимого шаблона
+type TemplateRevision struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TemplateID uint      `json:"template_id" gorm:"not null;uniqueIndex:idx_template_revision"`
+	Revision   int       `json:"revision" gorm:"not null;uniqueIndex:idx_template_revision"`
+	Content    string    `json:"content" gorm:"type:text"`
+	Variables  string    `json:"variables" gorm:"type:text"`
+	AuthorID   uint      `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (TemplateRevision) TableName() string {
+	return "template_revisions"
+}
+
 type TemplateCategory struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
 	Name        string         `json:"name" gorm:"not null"`
 	Description string         `json:"description"`
+	ParentID    *uint          `json:"parent_id" gorm:"index"` // родительская категория; nil — корневая
 	IsActive    bool           `json:"is_active" gorm:"default:true"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
@@ -78,13 +97,16 @@ type TemplateUpdateRequest struct {
 type TemplateCategoryCreateRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"`
 	IsActive    bool   `json:"is_active"`
 }
 
 type TemplateCategoryUpdateRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	IsActive    bool   `json:"is_active"`
+	// ParentID не задан — родитель не меняется, 0 — категория становится корневой
+	ParentID *uint `json:"parent_id"`
+	IsActive bool  `json:"is_active"`
 }
 
 type TemplateVariableCreateRequest struct {
@@ -113,6 +135,8 @@ type TemplateResponse struct {
 	Category    string    `json:"category"`
 	Variables   string    `json:"variables"`
 	IsActive    bool      `json:"is_active"`
+	CreatedBy   uint      `json:"created_by"`
+	UpdatedBy   uint      `json:"updated_by"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -127,6 +151,8 @@ func (t *Template) ToResponse() TemplateResponse {
 		Category:    t.Category,
 		Variables:   t.Variables,
 		IsActive:    t.IsActive,
+		CreatedBy:   t.CreatedBy,
+		UpdatedBy:   t.UpdatedBy,
 		CreatedAt:   t.CreatedAt,
 		UpdatedAt:   t.UpdatedAt,
 	}
@@ -136,16 +162,24 @@ type TemplateCategoryResponse struct {
 	ID          uint      `json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
+	ParentID    *uint     `json:"parent_id,omitempty"`
 	IsActive    bool      `json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// TemplateCategoryTreeNode категория с вложенными подкатегориями
+type TemplateCategoryTreeNode struct {
+	TemplateCategoryResponse
+	Children []TemplateCategoryTreeNode `json:"children"`
+}
+
 func (tc *TemplateCategory) ToResponse() TemplateCategoryResponse {
 	return TemplateCategoryResponse{
 		ID:          tc.ID,
 		Name:        tc.Name,
 		Description: tc.Description,
+		ParentID:    tc.ParentID,
 		IsActive:    tc.IsActive,
 		CreatedAt:   tc.CreatedAt,
 		UpdatedAt:   tc.UpdatedAt,
@@ -178,6 +212,12 @@ func (tv *TemplateVariable) ToResponse() TemplateVariableResponse {
 	}
 }
 
+type TemplateRevisionsResponse struct {
+	TemplateID uint               `json:"template_id"`
+	Revisions  []TemplateRevision `json:"revisions"`
+	Total      int                `json:"total"`
+}
+
 type TemplatesResponse struct {
 	Templates []TemplateResponse `json:"templates"`
 	Total     int64              `json:"total"`
@@ -199,14 +239,105 @@ type TemplateVariablesResponse struct {
 	Limit     int                        `json:"limit"`
 }
 
+type TemplateBatchGetRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1,max=100"`
+}
+
+type TemplateBatchGetResponse struct {
+	Templates  map[uint]TemplateResponse `json:"templates"`
+	MissingIDs []uint                    `json:"missing_ids"`
+}
+
+// TemplateBulkActiveRequest массовое включение или отключение шаблонов
+type TemplateBulkActiveRequest struct {
+	IDs      []uint `json:"ids" binding:"required,min=1,max=100"`
+	IsActive *bool  `json:"is_active" binding:"required"`
+}
+
+// TemplateBulkActiveResponse результат массового изменения активности шаблонов
+type TemplateBulkActiveResponse struct {
+	Updated    []TemplateResponse `json:"updated"`
+	MissingIDs []uint             `json:"missing_ids"`
+}
+
+// TemplateUsageResponse использование шаблона отчетами
+type TemplateUsageResponse struct {
+	TemplateID    uint       `json:"template_id"`
+	ActiveReports int64      `json:"active_reports"`
+	TotalReports  int64      `json:"total_reports"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+}
+
 type RenderTemplateRequest struct {
 	TemplateID uint                   `json:"template_id" binding:"required"`
 	Variables  map[string]interface{} `json:"variables"`
 	Format     string                 `json:"format"` // html, pdf, excel, csv
 }
 
+// ValidateTemplateRequest переменные для проверки шаблона; отсутствующие берутся из значений по умолчанию
+type ValidateTemplateRequest struct {
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// ValidateTemplateResponse результат проверки шаблона
+type ValidateTemplateResponse struct {
+	TemplateID         uint     `json:"template_id"`
+	Valid              bool     `json:"valid"`
+	UndefinedVariables []string `json:"undefined_variables"`
+	Errors             []string `json:"errors"`
+}
+
 type RenderTemplateResponse struct {
 	Content string `json:"content"`
 	Format  string `json:"format"`
 	Size    int    `json:"size"`
 }
+
+// TemplateBundleVersion текущая версия формата пакета шаблона
+const TemplateBundleVersion = 1
+
+// TemplateBundle шаблон вместе с переменными и категорией для переноса между окружениями.
+// ID в пакете — исходные идентификаторы, при импорте назначаются новые.
+type TemplateBundle struct {
+	Version   int                      `json:"version"`
+	Template  TemplateBundleTemplate   `json:"template"`
+	Category  *TemplateBundleCategory  `json:"category,omitempty"`
+	Variables []TemplateBundleVariable `json:"variables"`
+}
+
+type TemplateBundleTemplate struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	Category    string `json:"category"`
+	Variables   string `json:"variables"`
+	IsActive    bool   `json:"is_active"`
+}
+
+type TemplateBundleCategory struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsActive    bool   `json:"is_active"`
+}
+
+type TemplateBundleVariable struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Default     string `json:"default"`
+	Description string `json:"description"`
+}
+
+// TemplateBundleImportResponse результат импорта и соответствие исходных ID новым
+type TemplateBundleImportResponse struct {
+	Template         TemplateResponse           `json:"template"`
+	Category         *TemplateCategoryResponse  `json:"category,omitempty"`
+	CategoryCreated  bool                       `json:"category_created"`
+	Variables        []TemplateVariableResponse `json:"variables"`
+	SourceTemplateID uint                       `json:"source_template_id"`
+	VariableIDs      map[uint]uint              `json:"variable_ids"` // исходный ID -> новый ID
+}
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"errors"
+
+	"template-service/internal/database"
 	"template-service/internal/models"
 
 	"gorm.io/gorm"
@@ -26,14 +29,27 @@ func (r *TemplateRepository) GetByID(id uint) (*models.Template, error) {
 	return &template, err
 }
 
-// GetAll получает все шаблоны с пагинацией
-func (r *TemplateRepository) GetAll(page, limit int, category string, isActive *bool) ([]models.Template, int64, error) {
+// GetByIDs получает шаблоны по списку ID одним запросом
+func (r *TemplateRepository) GetByIDs(ids []uint) ([]models.Template, error) {
+	var templates []models.Template
+	if len(ids) == 0 {
+		return templates, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&templates).Error
+	return templates, err
+}
+
+// GetAll получает все шаблоны с пагинацией; categories — допустимые категории (пустой список — любые)
+func (r *TemplateRepository) GetAll(page, limit int, categories []string, templateType string, isActive *bool) ([]models.Template, int64, error) {
 	var templates []models.Template
 	var total int64
 
 	query := r.db.Model(&models.Template{})
-	if category != "" {
-		query = query.Where("category = ?", category)
+	if len(categories) > 0 {
+		query = query.Where("category IN ?", categories)
+	}
+	if templateType != "" {
+		query = query.Where("type = ?", templateType)
 	}
 	if isActive != nil {
 		query = query.Where("is_active = ?", *isActive)
@@ -53,11 +69,110 @@ func (r *TemplateRepository) Update(template *models.Template) error {
 	return r.db.Save(template).Error
 }
 
+// UpdateWithRevision сохраняет предыдущую версию шаблона и обновляет его в одной транзакции
+func (r *TemplateRepository) UpdateWithRevision(template *models.Template, revision *models.TemplateRevision) error {
+	return database.WithTransaction(r.db, func(tx *gorm.DB) error {
+		var last int
+		if err := tx.Model(&models.TemplateRevision{}).
+			Where("template_id = ?", revision.TemplateID).
+			Select("COALESCE(MAX(revision), 0)").
+			Scan(&last).Error; err != nil {
+			return err
+		}
+
+		revision.Revision = last + 1
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+
+		return tx.Save(template).Error
+	})
+}
+
+// SetActive изменяет активность шаблонов из списка в одной транзакции
+// и возвращает найденные шаблоны после изменения
+func (r *TemplateRepository) SetActive(ids []uint, active bool, updatedBy uint) ([]models.Template, error) {
+	var templates []models.Template
+	err := database.WithTransaction(r.db, func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Template{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{"is_active": active, "updated_by": updatedBy}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Find(&templates).Error
+	})
+	return templates, err
+}
+
+// GetRevisions получает историю версий шаблона
+func (r *TemplateRepository) GetRevisions(templateID uint) ([]models.TemplateRevision, error) {
+	var revisions []models.TemplateRevision
+	err := r.db.Where("template_id = ?", templateID).Order("revision DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+// GetRevision получает конкретную версию шаблона
+func (r *TemplateRepository) GetRevision(templateID uint, revision int) (*models.TemplateRevision, error) {
+	var rev models.TemplateRevision
+	err := r.db.Where("template_id = ? AND revision = ?", templateID, revision).First(&rev).Error
+	return &rev, err
+}
+
 // Delete удаляет шаблон
 func (r *TemplateRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Template{}, id).Error
 }
 
+// DeleteWithVariables мягко удаляет шаблон вместе с его переменными
+func (r *TemplateRepository) DeleteWithVariables(id uint) error {
+	return database.WithTransaction(r.db, func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", id).Delete(&models.TemplateVariable{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.Template{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// ImportBundle в одной транзакции находит или создает категорию по имени, создает шаблон
+// и его переменные. Возвращает true, если категория была создана.
+func (r *TemplateRepository) ImportBundle(category *models.TemplateCategory, template *models.Template, variables []models.TemplateVariable) (bool, error) {
+	categoryCreated := false
+	err := database.WithTransaction(r.db, func(tx *gorm.DB) error {
+		if category != nil {
+			err := tx.Where("name = ?", category.Name).First(category).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := tx.Create(category).Error; err != nil {
+					return err
+				}
+				categoryCreated = true
+			} else if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(template).Error; err != nil {
+			return err
+		}
+
+		for i := range variables {
+			variables[i].TemplateID = template.ID
+			if err := tx.Create(&variables[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return categoryCreated, err
+}
+
 // Search ищет шаблоны по имени и описанию
 func (r *TemplateRepository) Search(query string, page, limit int) ([]models.Template, int64, error) {
 	var templates []models.Template
@@ -98,6 +213,13 @@ func (r *TemplateCategoryRepository) GetByID(id uint) (*models.TemplateCategory,
 	return &category, err
 }
 
+// GetByName получает категорию по имени
+func (r *TemplateCategoryRepository) GetByName(name string) (*models.TemplateCategory, error) {
+	var category models.TemplateCategory
+	err := r.db.Where("name = ?", name).First(&category).Error
+	return &category, err
+}
+
 // GetAll получает все категории с пагинацией
 func (r *TemplateCategoryRepository) GetAll(page, limit int, isActive *bool) ([]models.TemplateCategory, int64, error) {
 	var categories []models.TemplateCategory
@@ -117,6 +239,17 @@ func (r *TemplateCategoryRepository) GetAll(page, limit int, isActive *bool) ([]
 	return categories, total, err
 }
 
+// List получает все категории без пагинации для построения дерева
+func (r *TemplateCategoryRepository) List(isActive *bool) ([]models.TemplateCategory, error) {
+	var categories []models.TemplateCategory
+	query := r.db.Model(&models.TemplateCategory{})
+	if isActive != nil {
+		query = query.Where("is_active = ?", *isActive)
+	}
+	err := query.Order("name").Find(&categories).Error
+	return categories, err
+}
+
 // Update обновляет категорию
 func (r *TemplateCategoryRepository) Update(category *models.TemplateCategory) error {
 	return r.db.Save(category).Error
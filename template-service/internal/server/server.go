@@ -7,8 +7,9 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"template-service/internal/audit"
+	"template-service/internal/clients"
 	"template-service/internal/config"
 	"template-service/internal/database"
 	"template-service/internal/handlers"
@@ -17,6 +18,7 @@ import (
 	"template-service/internal/middleware"
 	"template-service/internal/repository"
 	"template-service/internal/services"
+	"template-service/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -35,6 +37,8 @@ func NewServer(cfg *config.Config) *Server {
 
 func (s *Server) Start() error {
 	s.cfg.SetupLogger()
+	handlers.SetDefaultPageLimit(s.cfg.DefaultPageLimit)
+	handlers.SetMaxPageLimit(s.cfg.MaxPageLimit)
 
 	if s.cfg.AutoMigrate {
 		if err := s.migrate(); err != nil {
@@ -42,7 +46,7 @@ func (s *Server) Start() error {
 		}
 	}
 
-	db, err := database.Connect(s.cfg.DatabaseURL)
+	db, err := database.ConnectWithRetry(s.cfg.DatabaseURL, s.cfg.DBConnectAttempts, s.cfg.DBConnectInterval)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
@@ -69,7 +73,7 @@ func (s *Server) Start() error {
 	<-quit
 	logrus.Info("Остановка Template Service...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
@@ -90,60 +94,78 @@ func (s *Server) setupRouter(db *gorm.DB, jwtManager *jwt.Manager, metricsManage
 	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS())
 	router.Use(middleware.RequestID())
+	router.Use(middleware.RequireJSON())
 
 	templateRepo := repository.NewTemplateRepository(db)
 	categoryRepo := repository.NewTemplateCategoryRepository(db)
 	variableRepo := repository.NewTemplateVariableRepository(db)
 
-	templateService := services.NewTemplateService(templateRepo, metricsManager)
+	templateService := services.NewTemplateService(templateRepo, variableRepo, categoryRepo, clients.NewReportClient(s.cfg.ReportServiceURL), services.RenderLimits{
+		MaxOutputBytes: s.cfg.RenderMaxOutputBytes,
+		Timeout:        s.cfg.RenderTimeout,
+	}, metricsManager)
 	categoryService := services.NewTemplateCategoryService(categoryRepo)
 	variableService := services.NewTemplateVariableService(variableRepo)
 
-	templateHandler := handlers.NewTemplateHandler(templateService, metricsManager)
+	auditLog := audit.NewLogger(db)
+
+	templateHandler := handlers.NewTemplateHandler(templateService, metricsManager, auditLog)
+	auditHandler := handlers.NewAuditHandler(auditLog)
 	categoryHandler := handlers.NewTemplateCategoryHandler(categoryService)
-	variableHandler := handlers.NewTemplateVariableHandler(variableService)
+	variableHandler := handlers.NewTemplateVariableHandler(variableService, s.cfg.VariablesAllLimit)
+
+	apiTokens := middleware.NewAPITokenVerifier(s.cfg.UserServiceURL, s.cfg.APITokenCacheTTL)
 
-	s.setupRoutes(router, templateHandler, categoryHandler, variableHandler, jwtManager)
+	s.setupRoutes(router, templateHandler, categoryHandler, variableHandler, auditHandler, jwtManager, apiTokens)
 
 	return router
 }
 
-func (s *Server) setupRoutes(router *gin.Engine, templateHandler *handlers.TemplateHandler, categoryHandler *handlers.TemplateCategoryHandler, variableHandler *handlers.TemplateVariableHandler, jwtManager *jwt.Manager) {
+func (s *Server) setupRoutes(router *gin.Engine, templateHandler *handlers.TemplateHandler, categoryHandler *handlers.TemplateCategoryHandler, variableHandler *handlers.TemplateVariableHandler, auditHandler *handlers.AuditHandler, jwtManager *jwt.Manager, apiTokens *middleware.APITokenVerifier) {
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  "healthy",
-			"service": "template-service",
-			"version": "1.0.0",
-		})
+		c.JSON(http.StatusOK, version.Health("template-service"))
 	})
 
 	api := router.Group("/api/v1")
 	{
 		templates := api.Group("/templates")
-		templates.Use(middleware.Auth(jwtManager))
+		templates.Use(middleware.Auth(jwtManager, apiTokens))
 		{
-			templates.POST("/", templateHandler.CreateTemplate)
-			templates.GET("/", templateHandler.GetTemplates)
-			templates.GET("/:id", templateHandler.GetTemplate)
-			templates.PUT("/:id", templateHandler.UpdateTemplate)
-			templates.DELETE("/:id", templateHandler.DeleteTemplate)
-			templates.GET("/search", templateHandler.SearchTemplates)
-			templates.POST("/render", templateHandler.RenderTemplate)
+			// Проверка, рендеринг и пакетное получение только читают шаблоны
+			reads := templates.Group("", middleware.RequireReadScope("templates"))
+			reads.POST("/:id/validate", templateHandler.ValidateTemplate)
+			reads.POST("/render", templateHandler.RenderTemplate)
+			reads.POST("/batch-get", templateHandler.BatchGetTemplates)
+
+			scoped := templates.Group("", middleware.RequireScope("templates"))
+			scoped.POST("/", templateHandler.CreateTemplate)
+			scoped.GET("/", templateHandler.GetTemplates)
+			scoped.GET("/:id", templateHandler.GetTemplate)
+			scoped.PUT("/:id", templateHandler.UpdateTemplate)
+			scoped.DELETE("/:id", templateHandler.DeleteTemplate)
+			scoped.GET("/:id/revisions", templateHandler.GetTemplateRevisions)
+			scoped.GET("/:id/usage", templateHandler.GetTemplateUsage)
+			scoped.POST("/:id/revert/:rev", templateHandler.RevertTemplate)
+			scoped.GET("/search", templateHandler.SearchTemplates)
+			scoped.POST("/bulk-active", templateHandler.BulkSetTemplatesActive)
+			scoped.GET("/:id/bundle", templateHandler.ExportTemplateBundle)
+			scoped.POST("/bundle", templateHandler.ImportTemplateBundle)
 		}
 
 		categories := api.Group("/categories")
-		categories.Use(middleware.Auth(jwtManager))
+		categories.Use(middleware.Auth(jwtManager, apiTokens), middleware.RequireScope("templates"))
 		{
 			categories.POST("/", categoryHandler.CreateCategory)
 			categories.GET("/", categoryHandler.GetCategories)
+			categories.GET("/tree", categoryHandler.GetCategoryTree)
 			categories.GET("/:id", categoryHandler.GetCategory)
 			categories.PUT("/:id", categoryHandler.UpdateCategory)
 			categories.DELETE("/:id", categoryHandler.DeleteCategory)
 		}
 
 		variables := api.Group("/variables")
-		variables.Use(middleware.Auth(jwtManager))
+		variables.Use(middleware.Auth(jwtManager, apiTokens), middleware.RequireScope("templates"))
 		{
 			variables.POST("/", variableHandler.CreateVariable)
 			variables.GET("/", variableHandler.GetVariables)
@@ -151,12 +173,18 @@ func (s *Server) setupRoutes(router *gin.Engine, templateHandler *handlers.Templ
 			variables.PUT("/:id", variableHandler.UpdateVariable)
 			variables.DELETE("/:id", variableHandler.DeleteVariable)
 		}
+
+		admin := api.Group("/admin")
+		admin.Use(middleware.Auth(jwtManager, apiTokens), middleware.Role("admin"), middleware.RequireScope("templates"))
+		{
+			admin.GET("/audit", auditHandler.GetAuditLogs)
+		}
 	}
 }
 
 // migrate выполняет миграции базы данных
 func (s *Server) migrate() error {
-	_, err := database.Connect(s.cfg.DatabaseURL)
+	_, err := database.ConnectWithRetry(s.cfg.DatabaseURL, s.cfg.DBConnectAttempts, s.cfg.DBConnectInterval)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
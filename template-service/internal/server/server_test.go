@@ -0,0 +1,529 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"template-service/internal/audit"
+	"template-service/internal/config"
+	"template-service/internal/jwt"
+	"template-service/internal/metrics"
+	"template-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testMetrics метрики регистрируются в глобальном реестре, поэтому создаются один раз
+var testMetrics = sync.OnceValue(func() *metrics.Metrics {
+	return metrics.NewMetrics("template-service-test")
+})
+
+// testRouter маршрутизатор Template Service поверх отдельной SQLite базы и JWT пользователя
+func testRouter(t *testing.T, cfg *config.Config) (*gin.Engine, *gorm.DB, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dsn := filepath.Join(t.TempDir(), "templates.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&models.Template{}, &models.TemplateCategory{}, &models.TemplateVariable{}, &models.TemplateRevision{}, &audit.AuditLog{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+
+	jwtManager := jwt.NewManager("test-secret")
+	router := NewServer(cfg).setupRouter(db, jwtManager, testMetrics())
+
+	token, err := jwtManager.GenerateToken(1, "Пользователь", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("выпуск JWT: %v", err)
+	}
+	return router, db, token
+}
+
+// do выполняет запрос с токеном в заголовке Authorization
+func do(router http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// seedTemplate сохраняет шаблон в обход API
+func seedTemplate(t *testing.T, db *gorm.DB, template *models.Template) *models.Template {
+	t.Helper()
+	if template.Content == "" {
+		template.Content = "{{title}}"
+	}
+	if template.Type == "" {
+		template.Type = "html"
+	}
+	if err := db.Create(template).Error; err != nil {
+		t.Fatalf("создание шаблона %q: %v", template.Name, err)
+	}
+	return template
+}
+
+func TestBatchGetTemplatesReportsMissingIDs(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{})
+	sales := seedTemplate(t, db, &models.Template{Name: "Продажи"})
+	staff := seedTemplate(t, db, &models.Template{Name: "Кадры"})
+
+	rec := do(router, http.MethodPost, "/api/v1/templates/batch-get", token, map[string][]uint{"ids": {sales.ID, 404, staff.ID, 404, 405}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var result models.TemplateBatchGetResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if len(result.Templates) != 2 || result.Templates[sales.ID].Name != "Продажи" || result.Templates[staff.ID].Name != "Кадры" {
+		t.Errorf("найдены шаблоны %+v", result.Templates)
+	}
+	// Повторяющийся ненайденный ID возвращается один раз, в порядке запроса
+	if len(result.MissingIDs) != 2 || result.MissingIDs[0] != 404 || result.MissingIDs[1] != 405 {
+		t.Errorf("missing_ids %v, ожидалось [404 405]", result.MissingIDs)
+	}
+
+	rec = do(router, http.MethodPost, "/api/v1/templates/batch-get", token, map[string][]uint{"ids": {}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("пустой список: статус %d, ожидался 400", rec.Code)
+	}
+}
+
+func TestGetTemplateUsageFromReportService(t *testing.T) {
+	lastUsed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var requested, authorization string
+	failing := false
+	reportService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		requested, authorization = r.URL.Path, r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(map[string]interface{}{"active_reports": 2, "total_reports": 9, "last_used_at": lastUsed})
+	}))
+	defer reportService.Close()
+
+	router, db, token := testRouter(t, &config.Config{ReportServiceURL: reportService.URL})
+	template := seedTemplate(t, db, &models.Template{Name: "Продажи"})
+
+	rec := do(router, http.MethodGet, fmt.Sprintf("/api/v1/templates/%d/usage", template.ID), token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+	}
+	var usage models.TemplateUsageResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &usage); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if usage.TemplateID != template.ID || usage.ActiveReports != 2 || usage.TotalReports != 9 || usage.LastUsedAt == nil || !usage.LastUsedAt.Equal(lastUsed) {
+		t.Errorf("использование %+v", usage)
+	}
+	// Запрос уходит в report-service от имени пользователя
+	if requested != fmt.Sprintf("/api/v1/templates/%d/usage", template.ID) || authorization != "Bearer "+token {
+		t.Errorf("report-service получил %s с Authorization %q", requested, authorization)
+	}
+
+	if rec := do(router, http.MethodGet, "/api/v1/templates/999/usage", token, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("несуществующий шаблон: статус %d, ожидался 404", rec.Code)
+	}
+
+	failing = true
+	rec = do(router, http.MethodGet, fmt.Sprintf("/api/v1/templates/%d/usage", template.ID), token, nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("report-service недоступен: статус %d, ожидался 503", rec.Code)
+	}
+	assertRetryAfter(t, rec, 30)
+}
+
+// assertRetryAfter проверяет паузу перед повтором в заголовке Retry-After и в теле ответа
+func assertRetryAfter(t *testing.T, rec *httptest.ResponseRecorder, seconds int) {
+	t.Helper()
+	if got := rec.Header().Get("Retry-After"); got != strconv.Itoa(seconds) {
+		t.Errorf("Retry-After %q, ожидалось %d", got, seconds)
+	}
+	var body struct {
+		Error      string `json:"error"`
+		RetryAfter int    `json:"retry_after_seconds"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if body.Error == "" || body.RetryAfter != seconds {
+		t.Errorf("ответ %s, ожидалось retry_after_seconds %d", rec.Body.String(), seconds)
+	}
+}
+
+func TestRenderTimeoutSuggestsRetry(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{RenderTimeout: time.Minute})
+	template := seedTemplate(t, db, &models.Template{Name: "Продажи", Content: "Итого: {{total}}"})
+
+	// Запрос, отмененный до окончания рендеринга, завершается как превышение времени
+	body, _ := json.Marshal(map[string]interface{}{"template_id": template.ID, "variables": map[string]interface{}{"total": 42}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/templates/render", bytes.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("статус %d, ожидался 503: %s", rec.Code, rec.Body.String())
+	}
+	assertRetryAfter(t, rec, 5)
+
+	// Без отмены тот же запрос выполняется без подсказки о повторе
+	rec = do(router, http.MethodPost, "/api/v1/templates/render", token, map[string]interface{}{"template_id": template.ID, "variables": map[string]interface{}{"total": 42}})
+	if rec.Code != http.StatusOK || rec.Header().Get("Retry-After") != "" {
+		t.Errorf("статус %d, Retry-After %q: %s", rec.Code, rec.Header().Get("Retry-After"), rec.Body.String())
+	}
+}
+
+func TestValidateTemplateEndpoint(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{})
+	template := seedTemplate(t, db, &models.Template{Name: "Продажи", Content: "<h1>{{title}}</h1> за {{period}}"})
+	if err := db.Create(&models.TemplateVariable{TemplateID: template.ID, Name: "period", Type: "date", Default: "2024-01"}).Error; err != nil {
+		t.Fatalf("создание переменной: %v", err)
+	}
+	path := fmt.Sprintf("/api/v1/templates/%d/validate", template.ID)
+
+	decode := func(rec *httptest.ResponseRecorder) models.ValidateTemplateResponse {
+		t.Helper()
+		var result models.ValidateTemplateResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return result
+	}
+
+	// period берется из значения по умолчанию, title передан в запросе
+	rec := do(router, http.MethodPost, path, token, map[string]interface{}{"variables": map[string]interface{}{"title": "Отчет"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("корректный шаблон: статус %d: %s", rec.Code, rec.Body.String())
+	}
+	if result := decode(rec); !result.Valid || len(result.UndefinedVariables) != 0 || len(result.Errors) != 0 {
+		t.Errorf("корректный шаблон: %+v", result)
+	}
+
+	// Без title переменная не определена
+	rec = do(router, http.MethodPost, path, token, nil)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("неопределенная переменная: статус %d, ожидался 422: %s", rec.Code, rec.Body.String())
+	}
+	if result := decode(rec); result.Valid || len(result.UndefinedVariables) != 1 || result.UndefinedVariables[0] != "title" {
+		t.Errorf("неопределенная переменная: %+v", result)
+	}
+
+	broken := seedTemplate(t, db, &models.Template{Name: "Сломанный", Content: "{{title}} и {{"})
+	rec = do(router, http.MethodPost, fmt.Sprintf("/api/v1/templates/%d/validate", broken.ID), token, map[string]interface{}{"variables": map[string]interface{}{"title": "Отчет"}})
+	if result := decode(rec); rec.Code != http.StatusUnprocessableEntity || len(result.Errors) != 1 {
+		t.Errorf("незакрытая подстановка: статус %d, %+v", rec.Code, result)
+	}
+
+	if rec := do(router, http.MethodPost, "/api/v1/templates/999/validate", token, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("несуществующий шаблон: статус %d, ожидался 404", rec.Code)
+	}
+}
+
+func TestGetTemplatesFiltersByTypeAndCategory(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{})
+	finance := models.TemplateCategory{Name: "Финансы", IsActive: true}
+	if err := db.Create(&finance).Error; err != nil {
+		t.Fatalf("создание категории: %v", err)
+	}
+	if err := db.Create(&models.TemplateCategory{Name: "Продажи", ParentID: &finance.ID, IsActive: true}).Error; err != nil {
+		t.Fatalf("создание категории: %v", err)
+	}
+
+	seedTemplate(t, db, &models.Template{Name: "Баланс", Category: "Финансы", Type: "pdf"})
+	seedTemplate(t, db, &models.Template{Name: "Выручка", Category: "Продажи", Type: "pdf"})
+	seedTemplate(t, db, &models.Template{Name: "Сводка", Category: "Финансы", Type: "html"})
+	seedTemplate(t, db, &models.Template{Name: "Штат", Category: "Кадры", Type: "pdf"})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"type=pdf", []string{"Баланс", "Выручка", "Штат"}},
+		// Фильтр по категории включает подкатегории, тип сужает выборку
+		{"category=Финансы&type=pdf", []string{"Баланс", "Выручка"}},
+		{"category=Продажи&type=pdf", []string{"Выручка"}},
+		{"category=Финансы&type=html", []string{"Сводка"}},
+		{"category=Кадры&type=excel", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := do(router, http.MethodGet, "/api/v1/templates/?"+tt.query, token, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+			}
+			var result models.TemplatesResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+
+			var names []string
+			for _, template := range result.Templates {
+				names = append(names, template.Name)
+			}
+			sort.Strings(names)
+			if result.Total != int64(len(tt.want)) || !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("найдено %v (total %d), ожидалось %v", names, result.Total, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetVariablesAllIsCappedByLimit(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{VariablesAllLimit: 15})
+	small := seedTemplate(t, db, &models.Template{Name: "Продажи"})
+	large := seedTemplate(t, db, &models.Template{Name: "Кадры"})
+	seedVariables := func(template *models.Template, count int) {
+		t.Helper()
+		for i := 0; i < count; i++ {
+			variable := models.TemplateVariable{TemplateID: template.ID, Name: fmt.Sprintf("var%d", i), Type: "string"}
+			if err := db.Create(&variable).Error; err != nil {
+				t.Fatalf("создание переменной: %v", err)
+			}
+		}
+	}
+	seedVariables(small, 12)
+	seedVariables(large, 20)
+
+	get := func(query string) (int, models.TemplateVariablesResponse) {
+		t.Helper()
+		rec := do(router, http.MethodGet, "/api/v1/variables/?"+query, token, nil)
+		var result models.TemplateVariablesResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+		}
+		return rec.Code, result
+	}
+
+	// Без all действует страница по умолчанию
+	if code, result := get(fmt.Sprintf("template_id=%d", small.ID)); code != http.StatusOK || len(result.Variables) != 10 || result.Total != 12 {
+		t.Errorf("постранично: статус %d, переменных %d из %d", code, len(result.Variables), result.Total)
+	}
+
+	// all=true возвращает все переменные шаблона одним ответом
+	code, result := get(fmt.Sprintf("template_id=%d&all=true", small.ID))
+	if code != http.StatusOK || len(result.Variables) != 12 || result.Total != 12 {
+		t.Errorf("all=true: статус %d, переменных %d из %d", code, len(result.Variables), result.Total)
+	}
+	for _, variable := range result.Variables {
+		if variable.TemplateID != small.ID {
+			t.Errorf("переменная чужого шаблона %+v", variable)
+		}
+	}
+
+	// Ответ ограничен VARIABLES_ALL_LIMIT, total показывает полное число
+	if code, result := get(fmt.Sprintf("template_id=%d&all=true", large.ID)); code != http.StatusOK || len(result.Variables) != 15 || result.Total != 20 || result.Limit != 15 {
+		t.Errorf("all=true сверх лимита: статус %d, переменных %d из %d, limit %d", code, len(result.Variables), result.Total, result.Limit)
+	}
+
+	if code, _ := get("all=true"); code != http.StatusBadRequest {
+		t.Errorf("all=true без template_id: статус %d, ожидался 400", code)
+	}
+}
+
+func TestBulkSetTemplatesActive(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{})
+	sales := seedTemplate(t, db, &models.Template{Name: "Продажи", IsActive: true})
+	staff := seedTemplate(t, db, &models.Template{Name: "Кадры", IsActive: true})
+	other := seedTemplate(t, db, &models.Template{Name: "Склад", IsActive: true})
+
+	bulk := func(t *testing.T, active bool, ids ...uint) models.TemplateBulkActiveResponse {
+		t.Helper()
+		rec := do(router, http.MethodPost, "/api/v1/templates/bulk-active", token, map[string]interface{}{"ids": ids, "is_active": active})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+		var result models.TemplateBulkActiveResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return result
+	}
+	activeByName := func(t *testing.T) map[string]bool {
+		t.Helper()
+		var templates []models.Template
+		if err := db.Find(&templates).Error; err != nil {
+			t.Fatalf("чтение шаблонов: %v", err)
+		}
+		active := make(map[string]bool, len(templates))
+		for _, template := range templates {
+			active[template.Name] = template.IsActive
+		}
+		return active
+	}
+
+	t.Run("отключение", func(t *testing.T) {
+		result := bulk(t, false, sales.ID, 404, staff.ID, sales.ID)
+		if len(result.Updated) != 2 || result.Updated[0].ID != sales.ID || result.Updated[1].ID != staff.ID || result.Updated[0].IsActive {
+			t.Fatalf("изменены %+v", result.Updated)
+		}
+		if result.Updated[0].UpdatedBy != 1 {
+			t.Errorf("updated_by %d, ожидался автор запроса", result.Updated[0].UpdatedBy)
+		}
+		if !reflect.DeepEqual(result.MissingIDs, []uint{404}) {
+			t.Errorf("missing_ids %v, ожидалось [404]", result.MissingIDs)
+		}
+		if got := activeByName(t); !reflect.DeepEqual(got, map[string]bool{"Продажи": false, "Кадры": false, "Склад": true}) {
+			t.Errorf("активность шаблонов %v", got)
+		}
+
+		// Каждое изменение попадает в журнал аудита
+		var entries int64
+		db.Model(&audit.AuditLog{}).Where("entity_id IN ?", []uint{sales.ID, staff.ID}).Count(&entries)
+		if entries != 2 {
+			t.Errorf("записей аудита: %d, ожидалось 2", entries)
+		}
+	})
+
+	t.Run("включение", func(t *testing.T) {
+		result := bulk(t, true, staff.ID, other.ID)
+		if len(result.Updated) != 2 || !result.Updated[0].IsActive || len(result.MissingIDs) != 0 {
+			t.Errorf("результат %+v", result)
+		}
+		if got := activeByName(t); !reflect.DeepEqual(got, map[string]bool{"Продажи": false, "Кадры": true, "Склад": true}) {
+			t.Errorf("активность шаблонов %v", got)
+		}
+	})
+
+	for name, body := range map[string]interface{}{
+		"без is_active": map[string]interface{}{"ids": []uint{sales.ID}},
+		"пустой список": map[string]interface{}{"ids": []uint{}, "is_active": true},
+	} {
+		if rec := do(router, http.MethodPost, "/api/v1/templates/bulk-active", token, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: статус %d, ожидался 400", name, rec.Code)
+		}
+	}
+}
+
+func TestTemplateAuthorsComeFromToken(t *testing.T) {
+	router, _, token := testRouter(t, &config.Config{})
+	editorToken, err := jwt.NewManager("test-secret").GenerateToken(2, "Редактор", "editor@example.com", "user")
+	if err != nil {
+		t.Fatalf("выпуск JWT: %v", err)
+	}
+
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder, status int) models.TemplateResponse {
+		t.Helper()
+		if rec.Code != status {
+			t.Fatalf("статус %d, ожидался %d: %s", rec.Code, status, rec.Body.String())
+		}
+		var template models.TemplateResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &template); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		return template
+	}
+
+	// Значения из тела запроса не подменяют пользователя из токена
+	created := decode(t, do(router, http.MethodPost, "/api/v1/templates/", token, map[string]interface{}{
+		"name": "Продажи", "content": "Итого: {{total}}", "type": "sales", "created_by": 99, "updated_by": 99,
+	}), http.StatusCreated)
+	if created.CreatedBy != 1 || created.UpdatedBy != 1 {
+		t.Errorf("после создания created_by %d, updated_by %d, ожидалось 1 и 1", created.CreatedBy, created.UpdatedBy)
+	}
+
+	path := fmt.Sprintf("/api/v1/templates/%d", created.ID)
+	updated := decode(t, do(router, http.MethodPut, path, editorToken, map[string]interface{}{"name": "Продажи 2024", "updated_by": 99}), http.StatusOK)
+	if updated.CreatedBy != 1 || updated.UpdatedBy != 2 {
+		t.Errorf("после изменения created_by %d, updated_by %d, ожидалось 1 и 2", updated.CreatedBy, updated.UpdatedBy)
+	}
+
+	stored := decode(t, do(router, http.MethodGet, path, token, nil), http.StatusOK)
+	if stored.CreatedBy != 1 || stored.UpdatedBy != 2 {
+		t.Errorf("сохранено created_by %d, updated_by %d", stored.CreatedBy, stored.UpdatedBy)
+	}
+}
+
+func TestCreateTemplateReturnsFieldErrors(t *testing.T) {
+	router, db, token := testRouter(t, &config.Config{})
+
+	tests := []struct {
+		name string
+		path string
+		body interface{}
+		want map[string]string
+	}{
+		{
+			name: "шаблон без обязательных полей",
+			path: "/api/v1/templates/",
+			body: map[string]string{"name": "Продажи"},
+			want: map[string]string{"content": "обязательное поле", "type": "обязательное поле"},
+		},
+		{
+			name: "переменная без шаблона",
+			path: "/api/v1/variables/",
+			body: map[string]string{"name": "month", "type": "string"},
+			want: map[string]string{"template_id": "обязательное поле"},
+		},
+		{
+			name: "неверный тип ID шаблона",
+			path: "/api/v1/variables/",
+			body: map[string]interface{}{"template_id": "первый", "name": "month", "type": "string"},
+			want: map[string]string{"template_id": "ожидается значение типа uint"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(router, http.MethodPost, tt.path, token, tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("статус %d, ожидался 400: %s", rec.Code, rec.Body.String())
+			}
+			var body struct {
+				Errors map[string]string `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+			if !reflect.DeepEqual(body.Errors, tt.want) {
+				t.Errorf("ошибки %v, ожидалось %v", body.Errors, tt.want)
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&models.Template{}).Count(&count)
+	if count != 0 {
+		t.Errorf("создано шаблонов: %d, ожидалось 0", count)
+	}
+}
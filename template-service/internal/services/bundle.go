@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"template-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ExportBundle собирает шаблон, его переменные и категорию в пакет для переноса
+func (s *TemplateService) ExportBundle(id uint) (*models.TemplateBundle, error) {
+	template, err := s.templateRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("ошибка получения шаблона: %w", err)
+	}
+
+	variables, err := s.variableRepo.GetByTemplateID(id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения переменных шаблона: %w", err)
+	}
+
+	bundle := &models.TemplateBundle{
+		Version: models.TemplateBundleVersion,
+		Template: models.TemplateBundleTemplate{
+			ID:          template.ID,
+			Name:        template.Name,
+			Description: template.Description,
+			Content:     template.Content,
+			Type:        template.Type,
+			Category:    template.Category,
+			Variables:   template.Variables,
+			IsActive:    template.IsActive,
+		},
+		Variables: make([]models.TemplateBundleVariable, len(variables)),
+	}
+	for i, v := range variables {
+		bundle.Variables[i] = models.TemplateBundleVariable{
+			ID:          v.ID,
+			Name:        v.Name,
+			Type:        v.Type,
+			Required:    v.Required,
+			Default:     v.Default,
+			Description: v.Description,
+		}
+	}
+
+	if template.Category != "" {
+		category, err := s.categoryRepo.GetByName(template.Category)
+		switch {
+		case err == nil:
+			bundle.Category = &models.TemplateBundleCategory{
+				ID:          category.ID,
+				Name:        category.Name,
+				Description: category.Description,
+				IsActive:    category.IsActive,
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, fmt.Errorf("ошибка получения категории шаблона: %w", err)
+		}
+	}
+
+	return bundle, nil
+}
+
+// validateBundle проверяет версию и обязательные поля пакета
+func validateBundle(bundle *models.TemplateBundle) error {
+	if bundle.Version != models.TemplateBundleVersion {
+		return fmt.Errorf("%w: неподдерживаемая версия %d", ErrInvalidBundle, bundle.Version)
+	}
+
+	t := bundle.Template
+	if strings.TrimSpace(t.Name) == "" || t.Content == "" || strings.TrimSpace(t.Type) == "" {
+		return fmt.Errorf("%w: у шаблона обязательны name, content и type", ErrInvalidBundle)
+	}
+
+	if bundle.Category != nil {
+		if strings.TrimSpace(bundle.Category.Name) == "" {
+			return fmt.Errorf("%w: у категории обязательно name", ErrInvalidBundle)
+		}
+		if t.Category != "" && t.Category != bundle.Category.Name {
+			return fmt.Errorf("%w: категория шаблона %q не совпадает с категорией пакета %q", ErrInvalidBundle, t.Category, bundle.Category.Name)
+		}
+	}
+
+	seen := make(map[string]bool, len(bundle.Variables))
+	for i, v := range bundle.Variables {
+		if strings.TrimSpace(v.Name) == "" || strings.TrimSpace(v.Type) == "" {
+			return fmt.Errorf("%w: у переменной %d обязательны name и type", ErrInvalidBundle, i)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("%w: переменная %q указана дважды", ErrInvalidBundle, v.Name)
+		}
+		seen[v.Name] = true
+	}
+	return nil
+}
+
+// ImportBundle создает шаблон из пакета, при необходимости создавая категорию.
+// Исходные ID из пакета не используются: записи получают новые ID.
+func (s *TemplateService) ImportBundle(actorID uint, bundle *models.TemplateBundle) (*models.TemplateBundleImportResponse, error) {
+	if err := validateBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	t := bundle.Template
+	template := &models.Template{
+		Name:        t.Name,
+		Description: t.Description,
+		Content:     t.Content,
+		Type:        t.Type,
+		Category:    t.Category,
+		Variables:   t.Variables,
+		IsActive:    t.IsActive,
+		CreatedBy:   actorID,
+		UpdatedBy:   actorID,
+	}
+
+	var category *models.TemplateCategory
+	switch {
+	case bundle.Category != nil:
+		category = &models.TemplateCategory{
+			Name:        bundle.Category.Name,
+			Description: bundle.Category.Description,
+			IsActive:    bundle.Category.IsActive,
+		}
+		template.Category = bundle.Category.Name
+	case t.Category != "":
+		category = &models.TemplateCategory{Name: t.Category, IsActive: true}
+	}
+
+	variables := make([]models.TemplateVariable, len(bundle.Variables))
+	for i, v := range bundle.Variables {
+		variables[i] = models.TemplateVariable{
+			Name:        v.Name,
+			Type:        v.Type,
+			Required:    v.Required,
+			Default:     v.Default,
+			Description: v.Description,
+		}
+	}
+
+	categoryCreated, err := s.templateRepo.ImportBundle(category, template, variables)
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrTemplateNameConflict
+		}
+		return nil, fmt.Errorf("ошибка импорта шаблона: %w", err)
+	}
+
+	response := &models.TemplateBundleImportResponse{
+		Template:         template.ToResponse(),
+		CategoryCreated:  categoryCreated,
+		Variables:        make([]models.TemplateVariableResponse, len(variables)),
+		SourceTemplateID: t.ID,
+		VariableIDs:      make(map[uint]uint),
+	}
+	if category != nil {
+		categoryResponse := category.ToResponse()
+		response.Category = &categoryResponse
+	}
+	for i := range variables {
+		response.Variables[i] = variables[i].ToResponse()
+		if source := bundle.Variables[i].ID; source != 0 {
+			response.VariableIDs[source] = variables[i].ID
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"template-service/internal/models"
+)
+
+func TestTemplateBundleRoundTripBetweenDatabases(t *testing.T) {
+	source, sourceDB := newTestTemplateService(t, nil)
+	if err := sourceDB.Create(&models.TemplateCategory{Name: "Финансы", Description: "Финансовые отчеты", IsActive: true}).Error; err != nil {
+		t.Fatalf("создание категории: %v", err)
+	}
+	template := createTemplate(t, source, "Продажи", "Финансы", "<h1>{{title}}</h1>{{period}}")
+	for _, variable := range []*models.TemplateVariable{
+		{TemplateID: template.ID, Name: "title", Type: "string", Required: true},
+		{TemplateID: template.ID, Name: "period", Type: "date", Default: "2024-01", Description: "Период"},
+	} {
+		if err := sourceDB.Create(variable).Error; err != nil {
+			t.Fatalf("создание переменной: %v", err)
+		}
+	}
+
+	exported, err := source.ExportBundle(template.ID)
+	if err != nil {
+		t.Fatalf("экспорт: %v", err)
+	}
+	// Пакет передается между окружениями как JSON
+	payload, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatalf("сериализация пакета: %v", err)
+	}
+	var bundle models.TemplateBundle
+	if err := json.Unmarshal(payload, &bundle); err != nil {
+		t.Fatalf("разбор пакета: %v", err)
+	}
+
+	target, targetDB := newTestTemplateService(t, nil)
+	// В целевой базе уже есть записи, поэтому ID из пакета не совпадут с новыми
+	createTemplate(t, target, "Кадры", "", "{{name}}")
+
+	imported, err := target.ImportBundle(7, &bundle)
+	if err != nil {
+		t.Fatalf("импорт: %v", err)
+	}
+	if !imported.CategoryCreated || imported.Category == nil || imported.Category.Name != "Финансы" || imported.Category.Description != "Финансовые отчеты" {
+		t.Errorf("категория после импорта %+v (создана %v)", imported.Category, imported.CategoryCreated)
+	}
+	got := imported.Template
+	if got.ID == template.ID || imported.SourceTemplateID != template.ID {
+		t.Errorf("ID шаблона %d, исходный %d в ответе %d", got.ID, template.ID, imported.SourceTemplateID)
+	}
+	if got.Name != "Продажи" || got.Category != "Финансы" || got.Content != template.Content || got.Type != "html" || got.CreatedBy != 7 {
+		t.Errorf("импортирован шаблон %+v", got)
+	}
+
+	variables, err := target.variableRepo.GetByTemplateID(got.ID)
+	if err != nil {
+		t.Fatalf("переменные шаблона: %v", err)
+	}
+	byName := make(map[string]models.TemplateVariable, len(variables))
+	for _, v := range variables {
+		byName[v.Name] = v
+	}
+	if len(byName) != 2 || !byName["title"].Required || byName["period"].Default != "2024-01" || byName["period"].Description != "Период" {
+		t.Errorf("импортированы переменные %+v", variables)
+	}
+	for _, v := range exported.Variables {
+		if imported.VariableIDs[v.ID] != byName[v.Name].ID {
+			t.Errorf("переменная %s: исходный ID %d сопоставлен с %d, создана с %d", v.Name, v.ID, imported.VariableIDs[v.ID], byName[v.Name].ID)
+		}
+	}
+
+	// Повторный экспорт из целевой базы дает тот же пакет с точностью до ID
+	reexported, err := target.ExportBundle(got.ID)
+	if err != nil {
+		t.Fatalf("повторный экспорт: %v", err)
+	}
+	if reexported.Template.Content != exported.Template.Content || len(reexported.Variables) != len(exported.Variables) || reexported.Category.Name != exported.Category.Name {
+		t.Errorf("повторный экспорт %+v отличается от исходного %+v", reexported, exported)
+	}
+
+	// Повторный импорт того же шаблона конфликтует по имени в категории и ничего не создает
+	if _, err := target.ImportBundle(7, &bundle); !errors.Is(err, ErrTemplateNameConflict) {
+		t.Errorf("повторный импорт: ожидалась ErrTemplateNameConflict, получено %v", err)
+	}
+	var categories int64
+	targetDB.Model(&models.TemplateCategory{}).Count(&categories)
+	if categories != 1 {
+		t.Errorf("категорий в целевой базе: %d, ожидалась 1", categories)
+	}
+}
+
+func TestImportBundleRejectsInvalidBundle(t *testing.T) {
+	service, db := newTestTemplateService(t, nil)
+
+	valid := models.TemplateBundle{
+		Version:  models.TemplateBundleVersion,
+		Template: models.TemplateBundleTemplate{Name: "Продажи", Content: "{{title}}", Type: "html"},
+	}
+	tests := map[string]func(b *models.TemplateBundle){
+		"другая версия":   func(b *models.TemplateBundle) { b.Version = models.TemplateBundleVersion + 1 },
+		"без содержимого": func(b *models.TemplateBundle) { b.Template.Content = "" },
+		"категория не совпадает": func(b *models.TemplateBundle) {
+			b.Template.Category = "А"
+			b.Category = &models.TemplateBundleCategory{Name: "Б"}
+		},
+		"переменная указана дважды": func(b *models.TemplateBundle) {
+			b.Variables = []models.TemplateBundleVariable{{Name: "x", Type: "string"}, {Name: "x", Type: "string"}}
+		},
+	}
+	for name, mutate := range tests {
+		bundle := valid
+		mutate(&bundle)
+		if _, err := service.ImportBundle(1, &bundle); !errors.Is(err, ErrInvalidBundle) {
+			t.Errorf("%s: ожидалась ErrInvalidBundle, получено %v", name, err)
+		}
+	}
+
+	var templates int64
+	db.Model(&models.Template{}).Count(&templates)
+	if templates != 0 {
+		t.Errorf("создано шаблонов из некорректных пакетов: %d", templates)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+
+	"template-service/internal/models"
+)
+
+var (
+	ErrParentCategoryNotFound = errors.New("родительская категория не найдена")
+	ErrCategoryCycle          = errors.New("родительская категория не может быть самой категорией или ее потомком")
+)
+
+// buildCategoryTree собирает дерево категорий. Категории, родитель которых
+// отсутствует в списке (удален или отфильтрован), становятся корневыми.
+func buildCategoryTree(categories []models.TemplateCategory) []models.TemplateCategoryTreeNode {
+	known := make(map[uint]bool, len(categories))
+	for _, category := range categories {
+		known[category.ID] = true
+	}
+
+	children := make(map[uint][]models.TemplateCategory)
+	var roots []models.TemplateCategory
+	for _, category := range categories {
+		if category.ParentID != nil && known[*category.ParentID] && *category.ParentID != category.ID {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	visited := make(map[uint]bool, len(categories))
+	var build func(nodes []models.TemplateCategory) []models.TemplateCategoryTreeNode
+	build = func(nodes []models.TemplateCategory) []models.TemplateCategoryTreeNode {
+		result := make([]models.TemplateCategoryTreeNode, 0, len(nodes))
+		for _, category := range nodes {
+			// Защита от цикла, записанного в базу в обход проверки
+			if visited[category.ID] {
+				continue
+			}
+			visited[category.ID] = true
+			result = append(result, models.TemplateCategoryTreeNode{
+				TemplateCategoryResponse: category.ToResponse(),
+				Children:                 build(children[category.ID]),
+			})
+		}
+		return result
+	}
+	return build(roots)
+}
+
+// descendantNames возвращает имя категории и имена всех ее потомков
+func descendantNames(categories []models.TemplateCategory, name string) []string {
+	children := make(map[uint][]models.TemplateCategory)
+	var roots []models.TemplateCategory
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+		if category.Name == name {
+			roots = append(roots, category)
+		}
+	}
+
+	names := []string{name}
+	seen := map[string]bool{name: true}
+	visited := make(map[uint]bool)
+	queue := roots
+	for len(queue) > 0 {
+		category := queue[0]
+		queue = queue[1:]
+		if visited[category.ID] {
+			continue
+		}
+		visited[category.ID] = true
+		if !seen[category.Name] {
+			seen[category.Name] = true
+			names = append(names, category.Name)
+		}
+		queue = append(queue, children[category.ID]...)
+	}
+	return names
+}
+
+// validateCategoryParent проверяет, что parentID существует и не является
+// категорией id или ее потомком. id равен 0 для новой категории.
+func validateCategoryParent(categories []models.TemplateCategory, id, parentID uint) error {
+	parents := make(map[uint]*uint, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	if _, ok := parents[parentID]; !ok {
+		return ErrParentCategoryNotFound
+	}
+
+	visited := make(map[uint]bool)
+	for current := &parentID; current != nil; current = parents[*current] {
+		if *current == id || visited[*current] {
+			return ErrCategoryCycle
+		}
+		visited[*current] = true
+	}
+	return nil
+}
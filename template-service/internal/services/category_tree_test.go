@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"template-service/internal/models"
+	"template-service/internal/repository"
+)
+
+// createCategory создает категорию с родителем parentID (0 — корневая)
+func createCategory(t *testing.T, service *TemplateCategoryService, name string, parentID uint) *models.TemplateCategoryResponse {
+	t.Helper()
+	req := &models.TemplateCategoryCreateRequest{Name: name, IsActive: true}
+	if parentID != 0 {
+		req.ParentID = &parentID
+	}
+	category, err := service.CreateCategory(req)
+	if err != nil {
+		t.Fatalf("создание категории %q: %v", name, err)
+	}
+	return category
+}
+
+func TestGetCategoryTreeNestsChildren(t *testing.T) {
+	service := NewTemplateCategoryService(repository.NewTemplateCategoryRepository(newTestDB(t)))
+
+	finance := createCategory(t, service, "Финансы", 0)
+	sales := createCategory(t, service, "Продажи", finance.ID)
+	createCategory(t, service, "Квартальные", sales.ID)
+	createCategory(t, service, "Налоги", finance.ID)
+	createCategory(t, service, "Кадры", 0)
+
+	tree, err := service.GetCategoryTree("")
+	if err != nil {
+		t.Fatalf("получение дерева: %v", err)
+	}
+
+	// Категории на каждом уровне упорядочены по имени
+	if len(tree) != 2 || tree[0].Name != "Кадры" || tree[1].Name != "Финансы" {
+		t.Fatalf("корневые категории %+v", tree)
+	}
+	children := tree[1].Children
+	if len(children) != 2 || children[0].Name != "Налоги" || children[1].Name != "Продажи" {
+		t.Fatalf("подкатегории Финансов %+v", children)
+	}
+	if len(children[1].Children) != 1 || children[1].Children[0].Name != "Квартальные" {
+		t.Errorf("подкатегории Продаж %+v", children[1].Children)
+	}
+	if len(tree[0].Children) != 0 {
+		t.Errorf("у Кадров подкатегории %+v", tree[0].Children)
+	}
+}
+
+func TestCategoryParentRejectsCycles(t *testing.T) {
+	service := NewTemplateCategoryService(repository.NewTemplateCategoryRepository(newTestDB(t)))
+
+	root := createCategory(t, service, "Финансы", 0)
+	child := createCategory(t, service, "Продажи", root.ID)
+	grandchild := createCategory(t, service, "Квартальные", child.ID)
+
+	for name, parentID := range map[string]uint{"сама категория": root.ID, "дочерняя": child.ID, "внук": grandchild.ID} {
+		_, err := service.UpdateCategory(root.ID, &models.TemplateCategoryUpdateRequest{ParentID: &parentID, IsActive: true})
+		if !errors.Is(err, ErrCategoryCycle) {
+			t.Errorf("родитель — %s: ожидалась ErrCategoryCycle, получено %v", name, err)
+		}
+	}
+
+	missing := uint(999)
+	if _, err := service.CreateCategory(&models.TemplateCategoryCreateRequest{Name: "Сирота", ParentID: &missing}); !errors.Is(err, ErrParentCategoryNotFound) {
+		t.Errorf("несуществующий родитель: ожидалась ErrParentCategoryNotFound, получено %v", err)
+	}
+
+	// Перенос в другую ветку и открепление к корню разрешены
+	other := createCategory(t, service, "Кадры", 0)
+	if _, err := service.UpdateCategory(grandchild.ID, &models.TemplateCategoryUpdateRequest{ParentID: &other.ID, IsActive: true}); err != nil {
+		t.Errorf("перенос в другую ветку: %v", err)
+	}
+	detach := uint(0)
+	updated, err := service.UpdateCategory(child.ID, &models.TemplateCategoryUpdateRequest{ParentID: &detach, IsActive: true})
+	if err != nil || updated.ParentID != nil {
+		t.Errorf("открепление к корню: parent_id %v (%v)", updated, err)
+	}
+}
+
+func TestBuildCategoryTreeSurvivesStoredCycle(t *testing.T) {
+	one, two := uint(1), uint(2)
+	// Цикл, записанный в базу в обход проверки, не зацикливает построение и в дерево не попадает;
+	// категория с удаленным родителем становится корневой
+	tree := buildCategoryTree([]models.TemplateCategory{
+		{ID: 1, Name: "А", ParentID: &two},
+		{ID: 2, Name: "Б", ParentID: &one},
+		{ID: 3, Name: "В", ParentID: func() *uint { id := uint(42); return &id }()},
+	})
+	if len(tree) != 1 || tree[0].Name != "В" {
+		t.Errorf("дерево %+v", tree)
+	}
+}
@@ -1,11 +1,14 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"template-service/internal/clients"
 	"template-service/internal/metrics"
 	"template-service/internal/models"
 	"template-service/internal/repository"
@@ -13,20 +16,45 @@ import (
 	"gorm.io/gorm"
 )
 
+// Ошибки шаблонов
+var (
+	ErrTemplateNotFound     = errors.New("шаблон не найден")
+	ErrTemplateInUse        = errors.New("шаблон используется активными отчетами")
+	ErrUsageUnavailable     = errors.New("данные об использовании шаблона недоступны")
+	ErrTemplateNameConflict = errors.New("шаблон с таким именем уже существует в категории")
+	ErrRenderOutputTooLarge = errors.New("результат рендеринга превышает допустимый размер")
+	ErrRenderTimeout        = errors.New("превышено время рендеринга шаблона")
+	ErrInvalidBundle        = errors.New("некорректный пакет шаблона")
+)
+
+// RenderLimits ограничения рендеринга шаблона
+type RenderLimits struct {
+	MaxOutputBytes int           // максимальный размер результата, 0 — без ограничения
+	Timeout        time.Duration // максимальное время рендеринга, 0 — без ограничения
+}
+
 type TemplateService struct {
 	templateRepo *repository.TemplateRepository
+	variableRepo *repository.TemplateVariableRepository
+	categoryRepo *repository.TemplateCategoryRepository
+	reportClient *clients.ReportClient
+	renderLimits RenderLimits
 	metrics      *metrics.Metrics
 }
 
-func NewTemplateService(templateRepo *repository.TemplateRepository, metrics *metrics.Metrics) *TemplateService {
+func NewTemplateService(templateRepo *repository.TemplateRepository, variableRepo *repository.TemplateVariableRepository, categoryRepo *repository.TemplateCategoryRepository, reportClient *clients.ReportClient, renderLimits RenderLimits, metrics *metrics.Metrics) *TemplateService {
 	return &TemplateService{
 		templateRepo: templateRepo,
+		variableRepo: variableRepo,
+		categoryRepo: categoryRepo,
+		reportClient: reportClient,
+		renderLimits: renderLimits,
 		metrics:      metrics,
 	}
 }
 
 // CreateTemplate создает новый шаблон
-func (s *TemplateService) CreateTemplate(req *models.TemplateCreateRequest) (*models.TemplateResponse, error) {
+func (s *TemplateService) CreateTemplate(actorID uint, req *models.TemplateCreateRequest) (*models.TemplateResponse, error) {
 	start := time.Now()
 	template := &models.Template{
 		Name:        req.Name,
@@ -36,10 +64,15 @@ func (s *TemplateService) CreateTemplate(req *models.TemplateCreateRequest) (*mo
 		Category:    req.Category,
 		Variables:   req.Variables,
 		IsActive:    req.IsActive,
+		CreatedBy:   actorID,
+		UpdatedBy:   actorID,
 	}
 
 	if err := s.templateRepo.Create(template); err != nil {
 		s.metrics.RecordDatabaseOperation("template-service", "create_template", time.Since(start), err)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrTemplateNameConflict
+		}
 		return nil, fmt.Errorf("ошибка создания шаблона: %w", err)
 	}
 	s.metrics.RecordDatabaseOperation("template-service", "create_template", time.Since(start), nil)
@@ -49,14 +82,23 @@ func (s *TemplateService) CreateTemplate(req *models.TemplateCreateRequest) (*mo
 }
 
 // GetTemplates получает список шаблонов
-func (s *TemplateService) GetTemplates(page, limit int, category, active string) ([]models.TemplateResponse, int64, error) {
+func (s *TemplateService) GetTemplates(page, limit int, category, templateType, active string) ([]models.TemplateResponse, int64, error) {
 	var isActive *bool
 	if active != "" {
 		activeBool := active == "true"
 		isActive = &activeBool
 	}
 
-	templates, total, err := s.templateRepo.GetAll(page, limit, category, isActive)
+	var categories []string
+	if category != "" {
+		all, err := s.categoryRepo.List(nil)
+		if err != nil {
+			return nil, 0, fmt.Errorf("ошибка получения категорий: %w", err)
+		}
+		categories = descendantNames(all, category)
+	}
+
+	templates, total, err := s.templateRepo.GetAll(page, limit, categories, templateType, isActive)
 	if err != nil {
 		return nil, 0, fmt.Errorf("ошибка получения шаблонов: %w", err)
 	}
@@ -83,16 +125,77 @@ func (s *TemplateService) GetTemplate(id uint) (*models.TemplateResponse, error)
 	return &response, nil
 }
 
+// GetTemplatesByIDs получает шаблоны по списку ID и возвращает ненайденные ID
+func (s *TemplateService) GetTemplatesByIDs(ids []uint) (*models.TemplateBatchGetResponse, error) {
+	templates, err := s.templateRepo.GetByIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения шаблонов: %w", err)
+	}
+
+	response := &models.TemplateBatchGetResponse{
+		Templates:  make(map[uint]models.TemplateResponse, len(templates)),
+		MissingIDs: []uint{},
+	}
+	for _, template := range templates {
+		response.Templates[template.ID] = template.ToResponse()
+	}
+
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := response.Templates[id]; !ok && !seen[id] {
+			response.MissingIDs = append(response.MissingIDs, id)
+		}
+		seen[id] = true
+	}
+
+	return response, nil
+}
+
+// SetTemplatesActive включает или отключает шаблоны из списка одной транзакцией.
+// Ненайденные ID возвращаются в missing_ids, остальные шаблоны изменяются.
+func (s *TemplateService) SetTemplatesActive(ids []uint, active bool, authorID uint) (*models.TemplateBulkActiveResponse, error) {
+	templates, err := s.templateRepo.SetActive(ids, active, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка изменения активности шаблонов: %w", err)
+	}
+
+	byID := make(map[uint]models.Template, len(templates))
+	for _, template := range templates {
+		byID[template.ID] = template
+	}
+
+	response := &models.TemplateBulkActiveResponse{
+		Updated:    make([]models.TemplateResponse, 0, len(templates)),
+		MissingIDs: []uint{},
+	}
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if template, ok := byID[id]; ok {
+			response.Updated = append(response.Updated, template.ToResponse())
+		} else {
+			response.MissingIDs = append(response.MissingIDs, id)
+		}
+	}
+
+	return response, nil
+}
+
 // UpdateTemplate обновляет шаблон
-func (s *TemplateService) UpdateTemplate(id uint, req *models.TemplateUpdateRequest) (*models.TemplateResponse, error) {
+func (s *TemplateService) UpdateTemplate(id uint, authorID uint, authorName string, req *models.TemplateUpdateRequest) (*models.TemplateResponse, error) {
 	template, err := s.templateRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("шаблон не найден")
+			return nil, ErrTemplateNotFound
 		}
 		return nil, fmt.Errorf("ошибка получения шаблона: %w", err)
 	}
 
+	revision := newRevision(template, authorID, authorName)
+
 	if req.Name != "" {
 		template.Name = req.Name
 	}
@@ -112,8 +215,12 @@ func (s *TemplateService) UpdateTemplate(id uint, req *models.TemplateUpdateRequ
 		template.Variables = req.Variables
 	}
 	template.IsActive = req.IsActive
+	template.UpdatedBy = authorID
 
-	if err := s.templateRepo.Update(template); err != nil {
+	if err := s.templateRepo.UpdateWithRevision(template, revision); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrTemplateNameConflict
+		}
 		return nil, fmt.Errorf("ошибка обновления шаблона: %w", err)
 	}
 
@@ -121,14 +228,125 @@ func (s *TemplateService) UpdateTemplate(id uint, req *models.TemplateUpdateRequ
 	return &response, nil
 }
 
-// DeleteTemplate удаляет шаблон
-func (s *TemplateService) DeleteTemplate(id uint) error {
-	if err := s.templateRepo.Delete(id); err != nil {
+// GetTemplateRevisions возвращает историю версий шаблона
+func (s *TemplateService) GetTemplateRevisions(id uint) (*models.TemplateRevisionsResponse, error) {
+	if _, err := s.templateRepo.GetByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("шаблон не найден")
+		}
+		return nil, fmt.Errorf("ошибка получения шаблона: %w", err)
+	}
+
+	revisions, err := s.templateRepo.GetRevisions(id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории шаблона: %w", err)
+	}
+
+	return &models.TemplateRevisionsResponse{
+		TemplateID: id,
+		Revisions:  revisions,
+		Total:      len(revisions),
+	}, nil
+}
+
+// RevertTemplate восстанавливает содержимое шаблона из указанной версии
+func (s *TemplateService) RevertTemplate(id uint, rev int, authorID uint, authorName string) (*models.TemplateResponse, error) {
+	template, err := s.templateRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("шаблон не найден")
+		}
+		return nil, fmt.Errorf("ошибка получения шаблона: %w", err)
+	}
+
+	target, err := s.templateRepo.GetRevision(id, rev)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("версия шаблона не найдена")
+		}
+		return nil, fmt.Errorf("ошибка получения версии шаблона: %w", err)
+	}
+
+	// Текущее содержимое тоже попадает в историю, чтобы откат можно было отменить
+	revision := newRevision(template, authorID, authorName)
+	template.Content = target.Content
+	template.Variables = target.Variables
+	template.UpdatedBy = authorID
+
+	if err := s.templateRepo.UpdateWithRevision(template, revision); err != nil {
+		return nil, fmt.Errorf("ошибка восстановления шаблона: %w", err)
+	}
+
+	response := template.ToResponse()
+	return &response, nil
+}
+
+// newRevision фиксирует текущее содержимое шаблона перед изменением
+func newRevision(template *models.Template, authorID uint, authorName string) *models.TemplateRevision {
+	return &models.TemplateRevision{
+		TemplateID: template.ID,
+		Content:    template.Content,
+		Variables:  template.Variables,
+		AuthorID:   authorID,
+		AuthorName: authorName,
+	}
+}
+
+// DeleteTemplate удаляет шаблон вместе с его переменными.
+// Без force удаление отклоняется, если по шаблону есть активные отчеты.
+func (s *TemplateService) DeleteTemplate(ctx context.Context, id uint, force bool, authHeader string) error {
+	if _, err := s.templateRepo.GetByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTemplateNotFound
+		}
+		return fmt.Errorf("ошибка получения шаблона: %w", err)
+	}
+
+	if !force && s.reportClient != nil {
+		count, err := s.reportClient.CountActiveReports(ctx, id, authHeader)
+		if err != nil {
+			return fmt.Errorf("не удалось проверить использование шаблона: %w", err)
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: %d", ErrTemplateInUse, count)
+		}
+	}
+
+	if err := s.templateRepo.DeleteWithVariables(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTemplateNotFound
+		}
 		return fmt.Errorf("ошибка удаления шаблона: %w", err)
 	}
 	return nil
 }
 
+// GetTemplateUsage возвращает число отчетов, построенных по шаблону, по данным report-service
+func (s *TemplateService) GetTemplateUsage(ctx context.Context, id uint, authHeader string) (*models.TemplateUsageResponse, error) {
+	if _, err := s.templateRepo.GetByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("ошибка получения шаблона: %w", err)
+	}
+
+	if s.reportClient == nil {
+		return nil, ErrUsageUnavailable
+	}
+
+	usage, err := s.reportClient.GetTemplateUsage(ctx, id, authHeader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUsageUnavailable, err)
+	}
+
+	return &models.TemplateUsageResponse{
+		TemplateID:    id,
+		ActiveReports: usage.ActiveReports,
+		TotalReports:  usage.TotalReports,
+		LastUsedAt:    usage.LastUsedAt,
+	}, nil
+}
+
 // SearchTemplates ищет шаблоны
 func (s *TemplateService) SearchTemplates(query string, page, limit int) ([]models.TemplateResponse, int64, error) {
 	templates, total, err := s.templateRepo.Search(query, page, limit)
@@ -145,20 +363,24 @@ func (s *TemplateService) SearchTemplates(query string, page, limit int) ([]mode
 }
 
 // RenderTemplate рендерит шаблон с переменными
-func (s *TemplateService) RenderTemplate(req *models.RenderTemplateRequest) (*models.RenderTemplateResponse, error) {
+func (s *TemplateService) RenderTemplate(ctx context.Context, req *models.RenderTemplateRequest) (*models.RenderTemplateResponse, error) {
 	template, err := s.templateRepo.GetByID(req.TemplateID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("шаблон не найден")
+			return nil, ErrTemplateNotFound
 		}
 		return nil, fmt.Errorf("ошибка получения шаблона: %w", err)
 	}
 
-	// Простой рендеринг - замена переменных в шаблоне
-	content := template.Content
-	for key, value := range req.Variables {
-		placeholder := "{{" + key + "}}"
-		content = strings.ReplaceAll(content, placeholder, fmt.Sprintf("%v", value))
+	if s.renderLimits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.renderLimits.Timeout)
+		defer cancel()
+	}
+
+	content, err := s.renderContent(ctx, template.Content, req.Variables)
+	if err != nil {
+		return nil, err
 	}
 
 	format := req.Format
@@ -173,6 +395,134 @@ func (s *TemplateService) RenderTemplate(req *models.RenderTemplateRequest) (*mo
 	}, nil
 }
 
+// ValidateTemplate проверяет, что шаблон разбирается и рендерится с переданными
+// переменными (или значениями по умолчанию), не возвращая результат рендеринга
+func (s *TemplateService) ValidateTemplate(ctx context.Context, id uint, req *models.ValidateTemplateRequest) (*models.ValidateTemplateResponse, error) {
+	template, err := s.templateRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("ошибка получения шаблона: %w", err)
+	}
+
+	defined, err := s.variableRepo.GetByTemplateID(id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения переменных шаблона: %w", err)
+	}
+
+	variables := make(map[string]interface{}, len(defined)+len(req.Variables))
+	for _, v := range defined {
+		if v.Default != "" {
+			variables[v.Name] = v.Default
+		}
+	}
+	for key, value := range req.Variables {
+		variables[key] = value
+	}
+
+	response := &models.ValidateTemplateResponse{
+		TemplateID:         template.ID,
+		UndefinedVariables: []string{},
+		Errors:             []string{},
+	}
+
+	placeholders, parseErrors := parsePlaceholders(template.Content)
+	response.Errors = append(response.Errors, parseErrors...)
+	for _, name := range placeholders {
+		if _, ok := variables[name]; !ok {
+			response.UndefinedVariables = append(response.UndefinedVariables, name)
+		}
+	}
+
+	if s.renderLimits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.renderLimits.Timeout)
+		defer cancel()
+	}
+	if _, err := s.renderContent(ctx, template.Content, variables); err != nil {
+		if !errors.Is(err, ErrRenderOutputTooLarge) && !errors.Is(err, ErrRenderTimeout) {
+			return nil, err
+		}
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	response.Valid = len(response.Errors) == 0 && len(response.UndefinedVariables) == 0
+	return response, nil
+}
+
+// parsePlaceholders находит имена переменных вида {{name}} в порядке сортировки
+// и ошибки разбора: незакрытые и пустые подстановки
+func parsePlaceholders(content string) ([]string, []string) {
+	seen := make(map[string]bool)
+	var names, parseErrors []string
+
+	for offset := 0; ; {
+		open := strings.Index(content[offset:], "{{")
+		if open < 0 {
+			break
+		}
+		open += offset
+
+		end := strings.Index(content[open+2:], "}}")
+		if end < 0 {
+			parseErrors = append(parseErrors, fmt.Sprintf("незакрытая подстановка в позиции %d", open))
+			break
+		}
+		end += open + 2
+
+		name := content[open+2 : end]
+		switch {
+		case strings.TrimSpace(name) == "":
+			parseErrors = append(parseErrors, fmt.Sprintf("пустая подстановка в позиции %d", open))
+		case strings.Contains(name, "{{"):
+			parseErrors = append(parseErrors, fmt.Sprintf("вложенная подстановка в позиции %d", open))
+		case !seen[name]:
+			seen[name] = true
+			names = append(names, name)
+		}
+		offset = end + 2
+	}
+
+	sort.Strings(names)
+	return names, parseErrors
+}
+
+// renderContent подставляет переменные, соблюдая ограничения размера и времени.
+// Размер результата проверяется до подстановки, чтобы не выделять лишнюю память.
+func (s *TemplateService) renderContent(ctx context.Context, content string, variables map[string]interface{}) (string, error) {
+	maxBytes := s.renderLimits.MaxOutputBytes
+	if maxBytes > 0 && len(content) > maxBytes {
+		return "", ErrRenderOutputTooLarge
+	}
+
+	for key, value := range variables {
+		if ctx.Err() != nil {
+			return "", ErrRenderTimeout
+		}
+
+		placeholder := "{{" + key + "}}"
+		count := strings.Count(content, placeholder)
+		if count == 0 {
+			continue
+		}
+
+		replacement := fmt.Sprintf("%v", value)
+		if maxBytes > 0 {
+			projected := int64(len(content)) + int64(count)*int64(len(replacement)-len(placeholder))
+			if projected > int64(maxBytes) {
+				return "", ErrRenderOutputTooLarge
+			}
+		}
+		content = strings.ReplaceAll(content, placeholder, replacement)
+	}
+
+	if ctx.Err() != nil {
+		return "", ErrRenderTimeout
+	}
+	return content, nil
+}
+
 type TemplateCategoryService struct {
 	categoryRepo *repository.TemplateCategoryRepository
 }
@@ -191,6 +541,13 @@ func (s *TemplateCategoryService) CreateCategory(req *models.TemplateCategoryCre
 		IsActive:    req.IsActive,
 	}
 
+	if req.ParentID != nil && *req.ParentID != 0 {
+		if err := s.checkParent(0, *req.ParentID); err != nil {
+			return nil, err
+		}
+		category.ParentID = req.ParentID
+	}
+
 	if err := s.categoryRepo.Create(category); err != nil {
 		return nil, fmt.Errorf("ошибка создания категории: %w", err)
 	}
@@ -250,6 +607,16 @@ func (s *TemplateCategoryService) UpdateCategory(id uint, req *models.TemplateCa
 	if req.Description != "" {
 		category.Description = req.Description
 	}
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			category.ParentID = nil
+		} else {
+			if err := s.checkParent(id, *req.ParentID); err != nil {
+				return nil, err
+			}
+			category.ParentID = req.ParentID
+		}
+	}
 	category.IsActive = req.IsActive
 
 	if err := s.categoryRepo.Update(category); err != nil {
@@ -260,6 +627,31 @@ func (s *TemplateCategoryService) UpdateCategory(id uint, req *models.TemplateCa
 	return &response, nil
 }
 
+// GetCategoryTree возвращает категории в виде дерева
+func (s *TemplateCategoryService) GetCategoryTree(active string) ([]models.TemplateCategoryTreeNode, error) {
+	var isActive *bool
+	if active != "" {
+		activeBool := active == "true"
+		isActive = &activeBool
+	}
+
+	categories, err := s.categoryRepo.List(isActive)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения категорий: %w", err)
+	}
+
+	return buildCategoryTree(categories), nil
+}
+
+// checkParent проверяет родителя категории id на существование и отсутствие цикла
+func (s *TemplateCategoryService) checkParent(id, parentID uint) error {
+	categories, err := s.categoryRepo.List(nil)
+	if err != nil {
+		return fmt.Errorf("ошибка получения категорий: %w", err)
+	}
+	return validateCategoryParent(categories, id, parentID)
+}
+
 // DeleteCategory удаляет категорию
 func (s *TemplateCategoryService) DeleteCategory(id uint) error {
 	if err := s.categoryRepo.Delete(id); err != nil {
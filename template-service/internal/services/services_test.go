@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"template-service/internal/clients"
+	"template-service/internal/metrics"
+	"template-service/internal/models"
+	"template-service/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testMetrics метрики регистрируются в глобальном реестре, поэтому создаются один раз
+var testMetrics = sync.OnceValue(func() *metrics.Metrics {
+	return metrics.NewMetrics("template-service-test")
+})
+
+// newTestDB создает отдельную SQLite базу с мигрированными моделями Template Service
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "templates.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&models.Template{}, &models.TemplateCategory{}, &models.TemplateVariable{}, &models.TemplateRevision{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+	return db
+}
+
+// newTestTemplateService создает сервис шаблонов; nil reportClient отключает запросы к report-service
+func newTestTemplateService(t *testing.T, reportClient *clients.ReportClient) (*TemplateService, *gorm.DB) {
+	t.Helper()
+
+	db := newTestDB(t)
+	service := NewTemplateService(repository.NewTemplateRepository(db), repository.NewTemplateVariableRepository(db), repository.NewTemplateCategoryRepository(db), reportClient, RenderLimits{}, testMetrics())
+	return service, db
+}
+
+// createTemplate создает шаблон через сервис
+func createTemplate(t *testing.T, service *TemplateService, name, category, content string) *models.TemplateResponse {
+	t.Helper()
+	template, err := service.CreateTemplate(1, &models.TemplateCreateRequest{Name: name, Content: content, Type: "html", Category: category, IsActive: true})
+	if err != nil {
+		t.Fatalf("создание шаблона %q: %v", name, err)
+	}
+	return template
+}
+
+func TestRenderContentSubstitutesVariables(t *testing.T) {
+	service := &TemplateService{}
+
+	content, err := service.renderContent(context.Background(), "Отчет {{title}} за {{period}}: {{title}}", map[string]interface{}{
+		"title":  "Продажи",
+		"period": 2024,
+		"unused": "значение",
+	})
+	if err != nil {
+		t.Fatalf("рендеринг: %v", err)
+	}
+	if content != "Отчет Продажи за 2024: Продажи" {
+		t.Errorf("получено %q", content)
+	}
+}
+
+func TestRenderContentOutputLimit(t *testing.T) {
+	service := &TemplateService{renderLimits: RenderLimits{MaxOutputBytes: 32}}
+	ctx := context.Background()
+
+	// Шаблон больше лимита отклоняется до подстановки
+	if _, err := service.renderContent(ctx, strings.Repeat("x", 33), nil); !errors.Is(err, ErrRenderOutputTooLarge) {
+		t.Errorf("большой шаблон: ожидалась ErrRenderOutputTooLarge, получено %v", err)
+	}
+
+	// Подстановка, раздувающая результат сверх лимита
+	if _, err := service.renderContent(ctx, "{{v}}{{v}}{{v}}", map[string]interface{}{"v": strings.Repeat("y", 11)}); !errors.Is(err, ErrRenderOutputTooLarge) {
+		t.Errorf("раздувание подстановкой: ожидалась ErrRenderOutputTooLarge, получено %v", err)
+	}
+
+	// Результат ровно в лимит допустим
+	content, err := service.renderContent(ctx, "{{v}}{{v}}", map[string]interface{}{"v": strings.Repeat("y", 16)})
+	if err != nil || len(content) != 32 {
+		t.Errorf("результат на границе лимита: %d байт (%v)", len(content), err)
+	}
+
+	unlimited := &TemplateService{}
+	if _, err := unlimited.renderContent(ctx, "{{v}}", map[string]interface{}{"v": strings.Repeat("y", 1<<16)}); err != nil {
+		t.Errorf("без ограничения размера: %v", err)
+	}
+}
+
+func TestRenderContentTimeBudget(t *testing.T) {
+	service := &TemplateService{}
+
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	if _, err := service.renderContent(expired, "{{a}}", map[string]interface{}{"a": 1}); !errors.Is(err, ErrRenderTimeout) {
+		t.Errorf("с переменными: ожидалась ErrRenderTimeout, получено %v", err)
+	}
+	// Время проверяется и после подстановки, даже если подставлять нечего
+	if _, err := service.renderContent(expired, "без переменных", nil); !errors.Is(err, ErrRenderTimeout) {
+		t.Errorf("без переменных: ожидалась ErrRenderTimeout, получено %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := service.renderContent(ctx, "{{a}}", map[string]interface{}{"a": 1}); err != nil {
+		t.Errorf("в пределах бюджета: %v", err)
+	}
+}
+
+func TestUpdateTemplateRecordsRevisionAndRevertRestoresIt(t *testing.T) {
+	service, _ := newTestTemplateService(t, nil)
+	template := createTemplate(t, service, "Продажи", "", "<h1>{{title}}</h1>")
+
+	if _, err := service.UpdateTemplate(template.ID, 2, "Редактор", &models.TemplateUpdateRequest{Content: "<h2>{{title}}</h2>", IsActive: true}); err != nil {
+		t.Fatalf("обновление шаблона: %v", err)
+	}
+
+	history, err := service.GetTemplateRevisions(template.ID)
+	if err != nil {
+		t.Fatalf("история шаблона: %v", err)
+	}
+	if history.Total != 1 {
+		t.Fatalf("версий в истории: %d, ожидалась 1", history.Total)
+	}
+	first := history.Revisions[0]
+	if first.Revision != 1 || first.Content != "<h1>{{title}}</h1>" || first.AuthorID != 2 || first.AuthorName != "Редактор" {
+		t.Errorf("сохранена версия %+v", first)
+	}
+
+	reverted, err := service.RevertTemplate(template.ID, first.Revision, 3, "Администратор")
+	if err != nil {
+		t.Fatalf("откат шаблона: %v", err)
+	}
+	if reverted.Content != "<h1>{{title}}</h1>" || reverted.UpdatedBy != 3 {
+		t.Errorf("после отката содержимое %q, updated_by %d", reverted.Content, reverted.UpdatedBy)
+	}
+
+	// Откат тоже попадает в историю, поэтому его можно отменить
+	history, err = service.GetTemplateRevisions(template.ID)
+	if err != nil {
+		t.Fatalf("история шаблона: %v", err)
+	}
+	if history.Total != 2 || history.Revisions[0].Revision != 2 || history.Revisions[0].Content != "<h2>{{title}}</h2>" {
+		t.Errorf("история после отката %+v", history.Revisions)
+	}
+
+	if _, err := service.RevertTemplate(template.ID, 99, 3, "Администратор"); err == nil {
+		t.Error("откат к несуществующей версии выполнен")
+	}
+}
+
+// stubReportService отвечает на запрос использования шаблона статистикой usage
+// и запоминает полученный заголовок Authorization
+func stubReportService(t *testing.T, usage clients.TemplateUsage) (*clients.ReportClient, *string) {
+	t.Helper()
+
+	var authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(usage)
+	}))
+	t.Cleanup(server.Close)
+	return clients.NewReportClient(server.URL), &authHeader
+}
+
+func TestDeleteTemplateCascadesToVariables(t *testing.T) {
+	reportClient, authHeader := stubReportService(t, clients.TemplateUsage{ActiveReports: 0, TotalReports: 5})
+	service, db := newTestTemplateService(t, reportClient)
+	template := createTemplate(t, service, "Продажи", "", "{{title}}")
+	other := createTemplate(t, service, "Кадры", "", "{{name}}")
+
+	for _, variable := range []*models.TemplateVariable{
+		{TemplateID: template.ID, Name: "title", Type: "string"},
+		{TemplateID: template.ID, Name: "period", Type: "date"},
+		{TemplateID: other.ID, Name: "name", Type: "string"},
+	} {
+		if err := db.Create(variable).Error; err != nil {
+			t.Fatalf("создание переменной: %v", err)
+		}
+	}
+
+	if err := service.DeleteTemplate(context.Background(), template.ID, false, "Bearer token"); err != nil {
+		t.Fatalf("удаление шаблона: %v", err)
+	}
+	if *authHeader != "Bearer token" {
+		t.Errorf("в report-service передан Authorization %q", *authHeader)
+	}
+
+	var templates, variables int64
+	db.Model(&models.Template{}).Where("id = ?", template.ID).Count(&templates)
+	db.Model(&models.TemplateVariable{}).Where("template_id = ?", template.ID).Count(&variables)
+	if templates != 0 || variables != 0 {
+		t.Errorf("после удаления осталось шаблонов %d, переменных %d", templates, variables)
+	}
+	// Переменные других шаблонов не затронуты
+	db.Model(&models.TemplateVariable{}).Where("template_id = ?", other.ID).Count(&variables)
+	if variables != 1 {
+		t.Errorf("переменных другого шаблона: %d, ожидалась 1", variables)
+	}
+
+	if err := service.DeleteTemplate(context.Background(), template.ID, false, ""); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("повторное удаление: ожидалась ErrTemplateNotFound, получено %v", err)
+	}
+}
+
+func TestDeleteTemplateInUseRequiresForce(t *testing.T) {
+	reportClient, _ := stubReportService(t, clients.TemplateUsage{ActiveReports: 2, TotalReports: 3})
+	service, db := newTestTemplateService(t, reportClient)
+	template := createTemplate(t, service, "Продажи", "", "{{title}}")
+	if err := db.Create(&models.TemplateVariable{TemplateID: template.ID, Name: "title", Type: "string"}).Error; err != nil {
+		t.Fatalf("создание переменной: %v", err)
+	}
+
+	err := service.DeleteTemplate(context.Background(), template.ID, false, "")
+	if !errors.Is(err, ErrTemplateInUse) {
+		t.Fatalf("ожидалась ErrTemplateInUse, получено %v", err)
+	}
+	if _, err := service.GetTemplate(template.ID); err != nil {
+		t.Fatalf("используемый шаблон удален: %v", err)
+	}
+
+	if err := service.DeleteTemplate(context.Background(), template.ID, true, ""); err != nil {
+		t.Fatalf("принудительное удаление: %v", err)
+	}
+	var variables int64
+	db.Model(&models.TemplateVariable{}).Where("template_id = ?", template.ID).Count(&variables)
+	if variables != 0 {
+		t.Errorf("после принудительного удаления осталось переменных: %d", variables)
+	}
+}
+
+func TestDeleteTemplateFailsWhenUsageUnknown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	service, _ := newTestTemplateService(t, clients.NewReportClient(server.URL))
+	template := createTemplate(t, service, "Продажи", "", "{{title}}")
+
+	// Без данных об использовании шаблон не удаляется
+	if err := service.DeleteTemplate(context.Background(), template.ID, false, ""); err == nil {
+		t.Fatal("шаблон удален без проверки использования")
+	}
+	if _, err := service.GetTemplate(template.ID); err != nil {
+		t.Errorf("шаблон удален: %v", err)
+	}
+}
+
+func TestTemplateNameIsUniqueWithinCategory(t *testing.T) {
+	service, _ := newTestTemplateService(t, nil)
+	createTemplate(t, service, "Продажи", "Финансы", "{{title}}")
+
+	if _, err := service.CreateTemplate(1, &models.TemplateCreateRequest{Name: "Продажи", Content: "{{other}}", Type: "html", Category: "Финансы"}); !errors.Is(err, ErrTemplateNameConflict) {
+		t.Fatalf("дубликат в категории: ожидалась ErrTemplateNameConflict, получено %v", err)
+	}
+
+	// То же имя в другой категории и без категории допустимо
+	createTemplate(t, service, "Продажи", "Кадры", "{{title}}")
+	uncategorized := createTemplate(t, service, "Продажи", "", "{{title}}")
+
+	// Переименование в занятое имя тоже отклоняется
+	if _, err := service.UpdateTemplate(uncategorized.ID, 1, "Редактор", &models.TemplateUpdateRequest{Category: "Финансы", IsActive: true}); !errors.Is(err, ErrTemplateNameConflict) {
+		t.Errorf("перенос в категорию с тем же именем: ожидалась ErrTemplateNameConflict, получено %v", err)
+	}
+}
+
+func TestDeletedTemplateNameCanBeReused(t *testing.T) {
+	service, db := newTestTemplateService(t, nil)
+	deleted := createTemplate(t, service, "Продажи", "Финансы", "{{title}}")
+
+	if err := service.DeleteTemplate(context.Background(), deleted.ID, false, ""); err != nil {
+		t.Fatalf("удаление шаблона: %v", err)
+	}
+
+	reused := createTemplate(t, service, "Продажи", "Финансы", "{{title}} v2")
+	if reused.ID == deleted.ID {
+		t.Fatal("новый шаблон получил ID удаленного")
+	}
+
+	// Удаленная запись сохраняется мягко и не мешает новой
+	var total int64
+	db.Unscoped().Model(&models.Template{}).Where("name = ? AND category = ?", "Продажи", "Финансы").Count(&total)
+	if total != 2 {
+		t.Errorf("записей с именем в категории с учетом удаленных: %d, ожидалось 2", total)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 
+	"user-service/internal/audit"
 	"user-service/internal/config"
 	"user-service/internal/models"
 
@@ -50,11 +51,18 @@ func Migrate() error {
 
 	err := db.AutoMigrate(
 		&models.User{},
+		&models.APIToken{},
+		&audit.AuditLog{},
 	)
 	if err != nil {
 		return fmt.Errorf("ошибка миграции: %w", err)
 	}
 
+	// Уникальность email без учета регистра: A@x.com и a@x.com — один пользователь
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))").Error; err != nil {
+		return fmt.Errorf("ошибка создания индекса email без учета регистра (проверьте дубликаты email в разном регистре): %w", err)
+	}
+
 	log.Println("Миграции выполнены успешно")
 	return nil
 }
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"user-service/internal/config"
+
+	"gorm.io/gorm"
+)
+
+// maxConnectBackoff ограничивает задержку между попытками подключения
+const maxConnectBackoff = 30 * time.Second
+
+// ConnectWithRetry подключается к БД, повторяя попытки с экспоненциальной задержкой,
+// пока Postgres не станет доступен. Число попыток и начальный интервал берутся из конфигурации.
+func ConnectWithRetry(cfg *config.Config) (*gorm.DB, error) {
+	return retryConnect(func() (*gorm.DB, error) {
+		return Connect(cfg)
+	}, cfg.DBConnectAttempts, cfg.DBConnectInterval)
+}
+
+// retryConnect вызывает connect до attempts раз, удваивая интервал после каждой неудачи
+func retryConnect(connect func() (*gorm.DB, error), attempts int, interval time.Duration) (*gorm.DB, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	delay := interval
+	for attempt := 1; attempt <= attempts; attempt++ {
+		conn, err := connect()
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+
+		if attempt == attempts {
+			break
+		}
+		log.Printf("База данных недоступна (попытка %d из %d), повтор через %s: %v", attempt, attempts, delay, err)
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > maxConnectBackoff {
+			delay = maxConnectBackoff
+		}
+	}
+
+	return nil, fmt.Errorf("база данных недоступна после %d попыток: %w", attempts, lastErr)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"user-service/internal/models"
+	"user-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type APITokenHandler struct {
+	tokenService *services.APITokenService
+}
+
+func NewAPITokenHandler(tokenService *services.APITokenService) *APITokenHandler {
+	return &APITokenHandler{tokenService: tokenService}
+}
+
+// CreateToken выпускает API токен текущему пользователю.
+// Выпуск доступен только по JWT, чтобы API токен не мог порождать новые токены.
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	if method, _ := c.Get("auth_method"); method == "api_token" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Создание API токенов доступно только по JWT"})
+		return
+	}
+
+	var req models.APITokenCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	token, err := h.tokenService.CreateToken(actorID(c), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidScope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.WithError(err).Error("Ошибка создания API токена")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// GetTokens список API токенов текущего пользователя
+func (h *APITokenHandler) GetTokens(c *gin.Context) {
+	tokens, err := h.tokenService.ListTokens(actorID(c))
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения API токенов")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APITokensResponse{Tokens: tokens})
+}
+
+// RevokeToken отзывает API токен текущего пользователя
+func (h *APITokenHandler) RevokeToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный ID"})
+		return
+	}
+
+	if err := h.tokenService.RevokeToken(actorID(c), uint(id)); err != nil {
+		if errors.Is(err, services.ErrAPITokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.WithError(err).Error("Ошибка отзыва API токена")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// IntrospectToken проверяет API токен по запросу API Gateway и других сервисов,
+// которые не имеют доступа к таблице api_tokens
+func (h *APITokenHandler) IntrospectToken(c *gin.Context) {
+	var req models.APITokenIntrospectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindingError(c, err)
+		return
+	}
+
+	user, scopes, err := h.tokenService.AuthenticateAPIToken(req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		logrus.WithError(err).Error("Ошибка проверки API токена")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APITokenIntrospectResponse{
+		UserID: user.ID,
+		Name:   user.Name,
+		Email:  user.Email,
+		Role:   user.Role,
+		Scopes: scopes,
+	})
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"user-service/internal/audit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditHandler обработчик журнала аудита
+type AuditHandler struct {
+	auditLog *audit.Logger
+}
+
+// NewAuditHandler создает обработчик журнала аудита
+func NewAuditHandler(auditLog *audit.Logger) *AuditHandler {
+	return &AuditHandler{
+		auditLog: auditLog,
+	}
+}
+
+// GetAuditLogs возвращает журнал аудита с фильтрами actor_id, entity_type,
+// entity_id, action и интервалом from/to в формате RFC3339
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	entries, total, err := h.auditLog.List(filter, page, limit)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения журнала аудита")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения журнала аудита"})
+		return
+	}
+
+	c.JSON(http.StatusOK, audit.AuditLogsResponse{
+		AuditLogs: entries,
+		Total:     total,
+		Page:      page,
+		Limit:     limit,
+	})
+}
+
+// parseAuditFilter разбирает фильтры журнала аудита; при ошибке отвечает 400
+func parseAuditFilter(c *gin.Context) (audit.Filter, bool) {
+	filter := audit.Filter{
+		EntityType: c.Query("entity_type"),
+		Action:     c.Query("action"),
+	}
+
+	for param, target := range map[string]*uint{"actor_id": &filter.ActorID, "entity_id": &filter.EntityID} {
+		if value := c.Query(param); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр " + param})
+				return filter, false
+			}
+			*target = uint(id)
+		}
+	}
+
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр " + param + ", ожидается RFC3339"})
+				return filter, false
+			}
+			*target = &t
+		}
+	}
+
+	return filter, true
+}
+
+// actorID возвращает ID пользователя, выполняющего запрос, или 0
+func actorID(c *gin.Context) uint {
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uint); ok {
+			return id
+		}
+	}
+	return 0
+}
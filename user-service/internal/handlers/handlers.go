@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"user-service/internal/audit"
 	"user-service/internal/metrics"
 	"user-service/internal/models"
 	"user-service/internal/services"
@@ -13,15 +14,20 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// auditEntityUser тип сущности пользователя в журнале аудита
+const auditEntityUser = "user"
+
 type UserHandler struct {
 	userService *services.UserService
 	metrics     *metrics.Metrics
+	auditLog    *audit.Logger
 }
 
-func NewUserHandler(userService *services.UserService, metrics *metrics.Metrics) *UserHandler {
+func NewUserHandler(userService *services.UserService, metrics *metrics.Metrics, auditLog *audit.Logger) *UserHandler {
 	return &UserHandler{
 		userService: userService,
 		metrics:     metrics,
+		auditLog:    auditLog,
 	}
 }
 
@@ -30,7 +36,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 	var req models.UserCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.metrics.RecordBusinessOperation("user-service", "register", time.Since(start), false)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
@@ -43,6 +49,8 @@ func (h *UserHandler) Register(c *gin.Context) {
 	}
 
 	h.metrics.RecordBusinessOperation("user-service", "register", time.Since(start), true)
+	// При регистрации пользователь создает сам себя
+	h.auditLog.Record(user.ID, audit.ActionCreate, auditEntityUser, user.ID, nil, user)
 	c.JSON(http.StatusCreated, user)
 }
 
@@ -51,7 +59,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 	var req models.UserLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.metrics.RecordBusinessOperation("user-service", "login", time.Since(start), false)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
@@ -68,8 +76,10 @@ func (h *UserHandler) Login(c *gin.Context) {
 }
 
 func (h *UserHandler) GetUsers(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 	role := c.Query("role")
 	active := c.Query("active")
 
@@ -116,10 +126,11 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 	var req models.UserUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
+	before, _ := h.userService.GetUser(uint(id))
 	user, err := h.userService.UpdateUser(uint(id), &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка обновления пользователя")
@@ -127,6 +138,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	h.auditLog.Record(actorID(c), audit.ActionUpdate, auditEntityUser, user.ID, before, user)
 	c.JSON(http.StatusOK, user)
 }
 
@@ -138,12 +150,15 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	before, _ := h.userService.GetUser(uint(id))
 	if err := h.userService.DeleteUser(uint(id)); err != nil {
 		logrus.WithError(err).Error("Ошибка удаления пользователя")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.auditLog.Record(actorID(c), audit.ActionDelete, auditEntityUser, uint(id), before, nil)
+
 	c.JSON(http.StatusNoContent, nil)
 }
 
@@ -170,6 +185,40 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+func (h *UserHandler) GetMe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+		return
+	}
+
+	id, ok := userID.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Неверный тип ID пользователя"})
+		return
+	}
+
+	user, err := h.userService.GetUser(id)
+	if err != nil {
+		logrus.WithError(err).Error("Ошибка получения текущего пользователя")
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := models.MeResponse{
+		User:        *user,
+		Role:        user.Role,
+		Permissions: models.UserRole(user.Role).Permissions(),
+	}
+	if expiresAt, ok := c.Get("token_expires_at"); ok {
+		if t, ok := expiresAt.(time.Time); ok {
+			response.TokenExpiresAt = &t
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -185,10 +234,11 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 
 	var req models.UserUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
+	before, _ := h.userService.GetUser(id)
 	user, err := h.userService.UpdateUser(id, &req)
 	if err != nil {
 		logrus.WithError(err).Error("Ошибка обновления профиля")
@@ -196,6 +246,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	h.auditLog.Record(id, audit.ActionUpdate, auditEntityUser, user.ID, before, user)
 	c.JSON(http.StatusOK, user)
 }
 
@@ -218,7 +269,7 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindingError(c, err)
 		return
 	}
 
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPageLimit размер страницы, если limit не указан
+var defaultPageLimit = 10
+
+// maxPageLimit максимальный размер страницы в списках
+var maxPageLimit = 100
+
+// SetDefaultPageLimit задает размер страницы по умолчанию
+func SetDefaultPageLimit(limit int) {
+	if limit > 0 {
+		defaultPageLimit = limit
+	}
+}
+
+// SetMaxPageLimit задает максимальный размер страницы
+func SetMaxPageLimit(limit int) {
+	if limit > 0 {
+		maxPageLimit = limit
+	}
+}
+
+// parsePagination разбирает параметры page и limit, ограничивая limit сверху.
+// При некорректных значениях отвечает 400 и возвращает false.
+func parsePagination(c *gin.Context) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр page"})
+		return 0, 0, false
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный параметр limit"})
+		return 0, 0, false
+	}
+
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	return page, limit, true
+}
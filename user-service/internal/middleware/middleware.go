@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
 	"user-service/internal/jwt"
+	"user-service/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -50,6 +53,36 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// RequireJSON отвечает 415 на POST/PUT/PATCH запросы с телом, у которых Content-Type
+// не application/json. Маршруты из exempt (в виде c.FullPath()) не проверяются
+func RequireJSON(exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			c.Next()
+			return
+		}
+		if skip[c.FullPath()] || c.Request.ContentLength == 0 {
+			c.Next()
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type должен быть application/json"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
@@ -66,7 +99,45 @@ func generateRequestID() string {
 	return time.Now().Format("20060102150405") + "-" + randomString(8)
 }
 
-func Auth(jwtManager *jwt.Manager) gin.HandlerFunc {
+// APITokenAuthenticator проверяет долгоживущие API токены пользователей
+type APITokenAuthenticator interface {
+	AuthenticateAPIToken(token string) (*models.User, []string, error)
+}
+
+// RequireScope ограничивает запросы по API токену правами на группу маршрутов:
+// GET и HEAD требуют права resource:read, остальные методы — resource:write.
+// Запросы по JWT ограничиваются только ролью пользователя.
+func RequireScope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("token_scopes")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		scope := resource + ":write"
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = resource + ":read"
+		}
+
+		scopes, _ := value.([]string)
+		for _, granted := range scopes {
+			if granted == scope {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":          "Insufficient token scope",
+			"required_scope": scope,
+		})
+		c.Abort()
+	}
+}
+
+// Auth принимает JWT или, если apiTokens задан, API токен с префиксом models.APITokenPrefix
+func Auth(jwtManager *jwt.Manager, apiTokens APITokenAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -86,6 +157,27 @@ func Auth(jwtManager *jwt.Manager) gin.HandlerFunc {
 		}
 
 		tokenString := authHeader[7:]
+		if apiTokens != nil && strings.HasPrefix(tokenString, models.APITokenPrefix) {
+			user, scopes, err := apiTokens.AuthenticateAPIToken(tokenString)
+			if err != nil {
+				logrus.WithError(err).Debug("Отклонен API токен")
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "Invalid token",
+				})
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", user.ID)
+			c.Set("name", user.Name)
+			c.Set("email", user.Email)
+			c.Set("role", user.Role)
+			c.Set("auth_method", "api_token")
+			c.Set("token_scopes", scopes)
+			c.Next()
+			return
+		}
+
 		claims, err := jwtManager.ValidateToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -99,11 +191,43 @@ func Auth(jwtManager *jwt.Manager) gin.HandlerFunc {
 		c.Set("name", claims.Name)
 		c.Set("email", claims.Email)
 		c.Set("role", claims.Role)
+		if claims.ExpiresAt != nil {
+			c.Set("token_expires_at", claims.ExpiresAt.Time)
+		}
 
 		c.Next()
 	}
 }
 
+// Role middleware для проверки роли пользователя
+func Role(allowedRoles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := c.Get("role")
+		if !exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Role not found in context"})
+			c.Abort()
+			return
+		}
+
+		userRole, ok := role.(string)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid role type"})
+			c.Abort()
+			return
+		}
+
+		for _, allowedRole := range allowedRoles {
+			if userRole == allowedRole {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
+}
+
 func randomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	b := make([]byte, length)
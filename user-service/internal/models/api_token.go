@@ -0,0 +1,93 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APITokenPrefix префикс API токена, по которому middleware отличает его от JWT
+const APITokenPrefix = "usk_"
+
+// APIToken долгоживущий токен пользователя для скриптов; хранится только SHA-256 хеш
+type APIToken struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	UserID     uint           `json:"user_id" gorm:"not null;index"`
+	Name       string         `json:"name" gorm:"not null"`
+	TokenHash  string         `json:"-" gorm:"not null;uniqueIndex"`
+	Prefix     string         `json:"prefix" gorm:"not null"`  // начало токена для отображения
+	Scopes     string         `json:"scopes" gorm:"type:text"` // права через запятую
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// ScopeList возвращает права токена списком
+func (t *APIToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+// APITokenCreateRequest запрос на создание API токена.
+// Пустой Scopes означает все права роли пользователя, нулевой ExpiresInDays — бессрочный токен.
+type APITokenCreateRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1"`
+}
+
+// APITokenResponse сведения об API токене без секрета
+type APITokenResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *APIToken) ToResponse() APITokenResponse {
+	return APITokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     t.ScopeList(),
+		LastUsedAt: t.LastUsedAt,
+		ExpiresAt:  t.ExpiresAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// APITokenCreateResponse ответ на создание токена; Token возвращается только один раз
+type APITokenCreateResponse struct {
+	APITokenResponse
+	Token string `json:"token"`
+}
+
+// APITokensResponse список API токенов пользователя
+type APITokensResponse struct {
+	Tokens []APITokenResponse `json:"tokens"`
+}
+
+// APITokenIntrospectRequest запрос проверки API токена от API Gateway и других сервисов
+type APITokenIntrospectRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// APITokenIntrospectResponse владелец и права проверенного API токена
+type APITokenIntrospectResponse struct {
+	UserID uint     `json:"user_id"`
+	Name   string   `json:"name"`
+	Email  string   `json:"email"`
+	Role   string   `json:"role"`
+	Scopes []string `json:"scopes"`
+}
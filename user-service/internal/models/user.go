@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -22,6 +23,12 @@ func (User) TableName() string {
 	return "users"
 }
 
+// NormalizeEmail приводит email к виду, в котором он хранится и сравнивается:
+// без пробелов по краям и в нижнем регистре
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 type UserRole string
 
 const (
@@ -39,6 +46,40 @@ func (r UserRole) IsValid() bool {
 	}
 }
 
+var rolePermissions = map[UserRole][]string{
+	RoleAdmin: {
+		"users:read", "users:write",
+		"templates:read", "templates:write",
+		"reports:read", "reports:write",
+		"data:read", "data:write",
+		"notifications:read", "notifications:write",
+		"storage:read", "storage:write",
+	},
+	RoleManager: {
+		"users:read",
+		"templates:read", "templates:write",
+		"reports:read", "reports:write",
+		"data:read", "data:write",
+		"notifications:read",
+		"storage:read", "storage:write",
+	},
+	RoleUser: {
+		"templates:read",
+		"reports:read", "reports:write",
+		"data:read",
+		"notifications:read",
+		"storage:read",
+	},
+}
+
+// Permissions возвращает эффективные права роли
+func (r UserRole) Permissions() []string {
+	permissions := rolePermissions[r]
+	result := make([]string, len(permissions))
+	copy(result, permissions)
+	return result
+}
+
 type UserCreateRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
@@ -86,6 +127,13 @@ type LoginResponse struct {
 	Token string       `json:"token"`
 }
 
+type MeResponse struct {
+	User           UserResponse `json:"user"`
+	Role           string       `json:"role"`
+	Permissions    []string     `json:"permissions"`
+	TokenExpiresAt *time.Time   `json:"token_expires_at,omitempty"`
+}
+
 type UsersResponse struct {
 	Users []UserResponse `json:"users"`
 	Total int64          `json:"total"`
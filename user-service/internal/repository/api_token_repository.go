@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"time"
+
+	"user-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type APITokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+// Create сохраняет API токен
+func (r *APITokenRepository) Create(token *models.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetByHash получает неотозванный токен по хешу
+func (r *APITokenRepository) GetByHash(hash string) (*models.APIToken, error) {
+	var token models.APIToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListByUserID возвращает токены пользователя, новые первыми
+func (r *APITokenRepository) ListByUserID(userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// DeleteForUser отзывает токен пользователя; возвращает число удаленных записей
+func (r *APITokenRepository) DeleteForUser(id, userID uint) (int64, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIToken{})
+	return result.RowsAffected, result.Error
+}
+
+// TouchLastUsed обновляет время последнего использования, если оно старше olderThan
+func (r *APITokenRepository) TouchLastUsed(id uint, usedAt, olderThan time.Time) error {
+	return r.db.Model(&models.APIToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, olderThan).
+		UpdateColumn("last_used_at", usedAt).Error
+}
@@ -14,10 +14,10 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-// GetByEmail получает пользователя по email
+// GetByEmail получает пользователя по email без учета регистра
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.Where("LOWER(email) = ?", models.NormalizeEmail(email)).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -27,7 +27,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 // GetByEmailAndPassword получает пользователя по email и паролю
 func (r *UserRepository) GetByEmailAndPassword(email, password string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("email = ? AND password = ?", email, password).First(&user).Error
+	err := r.db.Where("LOWER(email) = ? AND password = ?", models.NormalizeEmail(email), password).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -142,9 +142,9 @@ func (r *UserRepository) GetUsersWithPagination(page, limit int, role, active st
 	return users, total, err
 }
 
-// IsEmailExists проверяет существование email
+// IsEmailExists проверяет существование email без учета регистра
 func (r *UserRepository) IsEmailExists(email string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	err := r.db.Model(&models.User{}).Where("LOWER(email) = ?", models.NormalizeEmail(email)).Count(&count).Error
 	return count > 0, err
 }
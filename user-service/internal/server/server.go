@@ -7,16 +7,18 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"user-service/internal/audit"
 	"user-service/internal/config"
 	"user-service/internal/database"
 	"user-service/internal/handlers"
 	"user-service/internal/jwt"
 	"user-service/internal/metrics"
 	"user-service/internal/middleware"
+	"user-service/internal/models"
 	"user-service/internal/repository"
 	"user-service/internal/services"
+	"user-service/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -34,6 +36,8 @@ func NewServer(cfg *config.Config) *Server {
 
 func (s *Server) Start() error {
 	s.cfg.SetupLogger()
+	handlers.SetDefaultPageLimit(s.cfg.DefaultPageLimit)
+	handlers.SetMaxPageLimit(s.cfg.MaxPageLimit)
 
 	if s.cfg.AutoMigrate {
 		if err := s.migrate(); err != nil {
@@ -41,7 +45,7 @@ func (s *Server) Start() error {
 		}
 	}
 
-	db, err := database.Connect(s.cfg)
+	db, err := database.ConnectWithRetry(s.cfg)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
@@ -50,8 +54,11 @@ func (s *Server) Start() error {
 	jwtManager := jwt.NewManager(s.cfg.JWTSecret)
 	metricsManager := metrics.NewMetrics("user-service")
 	userService := services.NewUserService(userRepo, jwtManager, metricsManager)
+	apiTokenService := services.NewAPITokenService(repository.NewAPITokenRepository(db), userRepo)
 
-	router := s.setupRouter(userService, jwtManager, metricsManager)
+	auditLog := audit.NewLogger(db)
+
+	router := s.setupRouter(userService, apiTokenService, auditLog, jwtManager, metricsManager)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.cfg.Port),
@@ -70,7 +77,7 @@ func (s *Server) Start() error {
 	<-quit
 	logrus.Info("Остановка User Service...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
@@ -81,7 +88,7 @@ func (s *Server) Start() error {
 	return nil
 }
 
-func (s *Server) setupRouter(userService *services.UserService, jwtManager *jwt.Manager, metricsManager *metrics.Metrics) *gin.Engine {
+func (s *Server) setupRouter(userService *services.UserService, apiTokenService *services.APITokenService, auditLog *audit.Logger, jwtManager *jwt.Manager, metricsManager *metrics.Metrics) *gin.Engine {
 	router := gin.Default()
 
 	// Инициализация метрик
@@ -91,22 +98,21 @@ func (s *Server) setupRouter(userService *services.UserService, jwtManager *jwt.
 	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS())
 	router.Use(middleware.RequestID())
+	router.Use(middleware.RequireJSON())
 
-	userHandler := handlers.NewUserHandler(userService, metricsManager)
+	userHandler := handlers.NewUserHandler(userService, metricsManager, auditLog)
+	auditHandler := handlers.NewAuditHandler(auditLog)
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenService)
 
-	s.setupRoutes(router, userHandler, jwtManager)
+	s.setupRoutes(router, userHandler, auditHandler, apiTokenHandler, jwtManager, apiTokenService)
 
 	return router
 }
 
-func (s *Server) setupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, jwtManager *jwt.Manager) {
+func (s *Server) setupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, auditHandler *handlers.AuditHandler, apiTokenHandler *handlers.APITokenHandler, jwtManager *jwt.Manager, apiTokens middleware.APITokenAuthenticator) {
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
-			"service":   "user-service",
-			"timestamp": time.Now().Unix(),
-		})
+		c.JSON(http.StatusOK, version.Health("user-service"))
 	})
 
 	api := router.Group("/api/v1")
@@ -118,20 +124,42 @@ func (s *Server) setupRoutes(router *gin.Engine, userHandler *handlers.UserHandl
 		}
 
 		protected := api.Group("/users")
-		protected.Use(middleware.Auth(jwtManager))
+		protected.Use(middleware.Auth(jwtManager, apiTokens))
 		{
+			// Собственный профиль читается любым токеном, изменение требует users:write
+			protected.GET("/me", userHandler.GetMe)
 			protected.GET("/profile", userHandler.GetProfile)
-			protected.PUT("/profile", userHandler.UpdateProfile)
-			protected.GET("/", userHandler.GetUsers)
-			protected.GET("/:id", userHandler.GetUser)
-			protected.PUT("/:id", userHandler.UpdateUser)
-			protected.DELETE("/:id", userHandler.DeleteUser)
+			protected.PUT("/profile", middleware.RequireScope("users"), userHandler.UpdateProfile)
+
+			users := protected.Group("", middleware.RequireScope("users"))
+			users.GET("/", userHandler.GetUsers)
+			users.GET("/:id", userHandler.GetUser)
+			users.PUT("/:id", userHandler.UpdateUser)
+			users.DELETE("/:id", userHandler.DeleteUser)
+		}
+
+		// Проверка API токенов для API Gateway и других сервисов
+		api.POST("/auth/tokens/introspect", apiTokenHandler.IntrospectToken)
+
+		// API токены текущего пользователя
+		tokens := api.Group("/auth/tokens")
+		tokens.Use(middleware.Auth(jwtManager, apiTokens))
+		{
+			tokens.POST("", apiTokenHandler.CreateToken)
+			tokens.GET("", apiTokenHandler.GetTokens)
+			tokens.DELETE("/:id", apiTokenHandler.RevokeToken)
+		}
+
+		admin := api.Group("/admin")
+		admin.Use(middleware.Auth(jwtManager, apiTokens), middleware.Role(string(models.RoleAdmin)), middleware.RequireScope("users"))
+		{
+			admin.GET("/audit", auditHandler.GetAuditLogs)
 		}
 	}
 }
 
 func (s *Server) migrate() error {
-	_, err := database.Connect(s.cfg)
+	_, err := database.ConnectWithRetry(s.cfg)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"user-service/internal/audit"
+	"user-service/internal/config"
+	"user-service/internal/jwt"
+	"user-service/internal/metrics"
+	"user-service/internal/models"
+	"user-service/internal/repository"
+	"user-service/internal/services"
+	"user-service/internal/version"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testMetrics метрики регистрируются в глобальном реестре, поэтому создаются один раз
+var testMetrics = sync.OnceValue(func() *metrics.Metrics {
+	return metrics.NewMetrics("user-service-test")
+})
+
+// testRouter маршрутизатор User Service поверх отдельной SQLite базы и JWT пользователя
+func testRouter(t *testing.T) (*gin.Engine, *models.User, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dsn := filepath.Join(t.TempDir(), "users.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&models.User{}, &models.APIToken{}, &audit.AuditLog{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+
+	user := &models.User{Name: "Пользователь", Email: "user@example.com", Password: "hash", Role: string(models.RoleUser), IsActive: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("создание пользователя: %v", err)
+	}
+
+	jwtManager := jwt.NewManager("test-secret")
+	userRepo := repository.NewUserRepository(db)
+	userService := services.NewUserService(userRepo, jwtManager, testMetrics())
+	apiTokenService := services.NewAPITokenService(repository.NewAPITokenRepository(db), userRepo)
+
+	s := NewServer(&config.Config{})
+	router := s.setupRouter(userService, apiTokenService, audit.NewLogger(db), jwtManager, testMetrics())
+
+	token, err := jwtManager.GenerateToken(user.ID, user.Name, user.Email, user.Role)
+	if err != nil {
+		t.Fatalf("выпуск JWT: %v", err)
+	}
+	return router, user, token
+}
+
+// do выполняет запрос с токеном в заголовке Authorization
+func do(router http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPITokenLifecycle(t *testing.T) {
+	router, user, jwtToken := testRouter(t)
+
+	rec := do(router, http.MethodPost, "/api/v1/auth/tokens", jwtToken, map[string]interface{}{"name": "ci", "scopes": []string{"reports:read"}})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("создание токена: статус %d: %s", rec.Code, rec.Body.String())
+	}
+	var created models.APITokenCreateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+
+	// Аутентификация API токеном
+	rec = do(router, http.MethodGet, "/api/v1/users/me", created.Token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("запрос с API токеном: статус %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(router, http.MethodPost, "/api/v1/auth/tokens/introspect", "", map[string]string{"token": created.Token})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("проверка токена: статус %d: %s", rec.Code, rec.Body.String())
+	}
+	var identity models.APITokenIntrospectResponse
+	json.Unmarshal(rec.Body.Bytes(), &identity)
+	if identity.UserID != user.ID || len(identity.Scopes) != 1 || identity.Scopes[0] != "reports:read" {
+		t.Errorf("проверка вернула %+v, ожидался пользователь %d с правами [reports:read]", identity, user.ID)
+	}
+
+	// Права токена ограничивают маршруты, а новые токены выпускаются только по JWT
+	if rec := do(router, http.MethodPut, "/api/v1/users/profile", created.Token, map[string]string{"name": "Другое"}); rec.Code != http.StatusForbidden {
+		t.Errorf("изменение профиля без users:write: статус %d, ожидался 403", rec.Code)
+	}
+	if rec := do(router, http.MethodPost, "/api/v1/auth/tokens", created.Token, map[string]string{"name": "nested"}); rec.Code != http.StatusForbidden {
+		t.Errorf("выпуск токена по API токену: статус %d, ожидался 403", rec.Code)
+	}
+
+	// Отзыв
+	rec = do(router, http.MethodDelete, fmt.Sprintf("/api/v1/auth/tokens/%d", created.ID), jwtToken, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("отзыв токена: статус %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(router, http.MethodGet, "/api/v1/users/me", created.Token, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("запрос с отозванным токеном: статус %d, ожидался 401", rec.Code)
+	}
+	if rec := do(router, http.MethodPost, "/api/v1/auth/tokens/introspect", "", map[string]string{"token": created.Token}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("проверка отозванного токена: статус %d, ожидался 401", rec.Code)
+	}
+}
+
+func TestGetMeReturnsAuthenticatedUser(t *testing.T) {
+	router, user, token := testRouter(t)
+
+	rec := do(router, http.MethodGet, "/api/v1/users/me", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+	var me models.MeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &me); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if me.User.ID != user.ID || me.User.Email != user.Email || me.Role != string(models.RoleUser) {
+		t.Errorf("получен пользователь %d (%s) с ролью %s, ожидался %d (%s) с ролью user", me.User.ID, me.User.Email, me.Role, user.ID, user.Email)
+	}
+	if len(me.Permissions) == 0 {
+		t.Error("в ответе нет прав роли")
+	}
+	if me.TokenExpiresAt == nil || !me.TokenExpiresAt.After(time.Now()) {
+		t.Errorf("срок действия JWT %v, ожидался в будущем", me.TokenExpiresAt)
+	}
+	if strings.Contains(rec.Body.String(), "password") {
+		t.Error("в ответе есть пароль")
+	}
+
+	for name, token := range map[string]string{"без токена": "", "невалидный токен": "not-a-jwt"} {
+		if rec := do(router, http.MethodGet, "/api/v1/users/me", token, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: статус %d, ожидался 401", name, rec.Code)
+		}
+	}
+}
+
+func TestMetricsEndpointCountsRequests(t *testing.T) {
+	router, _, token := testRouter(t)
+	m := testMetrics()
+
+	// Метрики общие для всех тестов пакета, поэтому сравниваются приращения
+	requests := m.HTTPRequestsTotal.WithLabelValues("user-service", http.MethodGet, "/api/v1/users/me", "OK")
+	registrations := m.BusinessOperationsTotal.WithLabelValues("user-service", "register", "success")
+	failedRegistrations := m.BusinessOperationsTotal.WithLabelValues("user-service", "register", "error")
+	requestsBefore := testutil.ToFloat64(requests)
+	registrationsBefore, failedBefore := testutil.ToFloat64(registrations), testutil.ToFloat64(failedRegistrations)
+
+	for i := 0; i < 2; i++ {
+		if rec := do(router, http.MethodGet, "/api/v1/users/me", token, nil); rec.Code != http.StatusOK {
+			t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	register := map[string]string{"name": "Новый", "email": "new@example.com", "password": "secret1"}
+	if rec := do(router, http.MethodPost, "/api/v1/users/register", "", register); rec.Code != http.StatusCreated {
+		t.Fatalf("регистрация: статус %d: %s", rec.Code, rec.Body.String())
+	}
+	do(router, http.MethodPost, "/api/v1/users/register", "", map[string]string{"email": "broken"})
+
+	if got := testutil.ToFloat64(requests) - requestsBefore; got != 2 {
+		t.Errorf("http_requests_total /api/v1/users/me вырос на %v, ожидалось 2", got)
+	}
+	if got := testutil.ToFloat64(registrations) - registrationsBefore; got != 1 {
+		t.Errorf("успешных регистраций %v, ожидалась 1", got)
+	}
+	if got := testutil.ToFloat64(failedRegistrations) - failedBefore; got != 1 {
+		t.Errorf("неудачных регистраций %v, ожидалась 1", got)
+	}
+
+	rec := do(router, http.MethodGet, "/metrics", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics: статус %d", rec.Code)
+	}
+	for _, series := range []string{
+		`http_requests_total{endpoint="/api/v1/users/me",method="GET",service="user-service",status_code="OK"}`,
+		`business_operations_total{operation="register",service="user-service",status="success"}`,
+		`http_request_duration_seconds_count{endpoint="/api/v1/users/me",method="GET",service="user-service"}`,
+	} {
+		if !strings.Contains(rec.Body.String(), series) {
+			t.Errorf("в /metrics нет %s", series)
+		}
+	}
+}
+
+func TestUpdateProfileWritesAuditDiff(t *testing.T) {
+	router, user, token := testRouter(t)
+
+	rec := do(router, http.MethodPut, "/api/v1/users/profile", token, map[string]string{"name": "Новое имя"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("обновление профиля: статус %d: %s", rec.Code, rec.Body.String())
+	}
+	// Повторное обновление без изменений тоже попадает в журнал, но без списка изменений
+	do(router, http.MethodPut, "/api/v1/users/profile", token, map[string]string{"name": "Новое имя"})
+
+	adminToken, err := jwt.NewManager("test-secret").GenerateToken(99, "Администратор", "admin@example.com", string(models.RoleAdmin))
+	if err != nil {
+		t.Fatalf("выпуск JWT: %v", err)
+	}
+	rec = do(router, http.MethodGet, fmt.Sprintf("/api/v1/admin/audit?entity_type=user&entity_id=%d&action=update", user.ID), adminToken, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("журнал аудита: статус %d: %s", rec.Code, rec.Body.String())
+	}
+	var result audit.AuditLogsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if result.Total != 2 {
+		t.Fatalf("записей %d, ожидалось 2", result.Total)
+	}
+
+	// Записи идут от последней к первой
+	unchanged, updated := result.AuditLogs[0], result.AuditLogs[1]
+	if updated.ActorID != user.ID || updated.EntityID != user.ID {
+		t.Errorf("запись %+v", updated)
+	}
+	var changes map[string]audit.FieldChange
+	if err := json.Unmarshal(updated.Changes, &changes); err != nil {
+		t.Fatalf("разбор изменений %s: %v", updated.Changes, err)
+	}
+	// updated_at меняется при каждом сохранении и в изменения не входит
+	want := map[string]audit.FieldChange{"name": {Before: "Пользователь", After: "Новое имя"}}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Errorf("изменения %v, ожидалось %v", changes, want)
+	}
+
+	var before, after models.UserResponse
+	json.Unmarshal(updated.Before, &before)
+	json.Unmarshal(updated.After, &after)
+	if before.Name != "Пользователь" || after.Name != "Новое имя" || after.Email != user.Email {
+		t.Errorf("снимки до %+v и после %+v", before, after)
+	}
+	if len(unchanged.Changes) != 0 {
+		t.Errorf("изменения без правок: %s", unchanged.Changes)
+	}
+}
+
+func TestHealthReportsBuildVersion(t *testing.T) {
+	router, _, _ := testRouter(t)
+
+	// Так же значения подставляет -ldflags "-X user-service/internal/version.Version=..." при сборке
+	saved := [3]string{version.Version, version.Commit, version.BuildTime}
+	t.Cleanup(func() {
+		version.Version, version.Commit, version.BuildTime = saved[0], saved[1], saved[2]
+	})
+	version.Version, version.Commit, version.BuildTime = "1.4.2", "abc123", "2024-05-01T12:00:00Z"
+
+	rec := do(router, http.MethodGet, "/health", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", rec.Code, rec.Body.String())
+	}
+	var health struct {
+		Status        string `json:"status"`
+		Service       string `json:"service"`
+		Version       string `json:"version"`
+		Commit        string `json:"commit"`
+		BuildTime     string `json:"build_time"`
+		Uptime        string `json:"uptime"`
+		UptimeSeconds *int64 `json:"uptime_seconds"`
+		Timestamp     int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("разбор ответа: %v", err)
+	}
+	if health.Status != "healthy" || health.Service != "user-service" {
+		t.Errorf("статус %q, сервис %q", health.Status, health.Service)
+	}
+	if health.Version != "1.4.2" || health.Commit != "abc123" || health.BuildTime != "2024-05-01T12:00:00Z" {
+		t.Errorf("сборка %s %s %s", health.Version, health.Commit, health.BuildTime)
+	}
+	if health.Uptime == "" || health.UptimeSeconds == nil || *health.UptimeSeconds < 0 || time.Since(time.Unix(health.Timestamp, 0)) > time.Minute {
+		t.Errorf("uptime %q (%v), timestamp %d", health.Uptime, health.UptimeSeconds, health.Timestamp)
+	}
+}
+
+func TestRegisterReturnsFieldErrors(t *testing.T) {
+	router, _, _ := testRouter(t)
+
+	tests := []struct {
+		name string
+		body interface{}
+		want map[string]string
+	}{
+		{
+			name: "пустое тело",
+			body: map[string]string{},
+			want: map[string]string{"name": "обязательное поле", "email": "обязательное поле", "password": "обязательное поле"},
+		},
+		{
+			name: "некорректные значения",
+			body: map[string]string{"name": "Новый", "email": "broken", "password": "123"},
+			want: map[string]string{"email": "некорректный email", "password": "длина должна быть не меньше 6"},
+		},
+		{
+			name: "неверный тип",
+			body: json.RawMessage(`{"name": 42, "email": "new@example.com", "password": "secret1"}`),
+			want: map[string]string{"name": "ожидается значение типа string"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(router, http.MethodPost, "/api/v1/users/register", "", tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("статус %d, ожидался 400: %s", rec.Code, rec.Body.String())
+			}
+			var body struct {
+				Errors map[string]string `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("разбор ответа: %v", err)
+			}
+			if !reflect.DeepEqual(body.Errors, tt.want) {
+				t.Errorf("ошибки %v, ожидалось %v", body.Errors, tt.want)
+			}
+		})
+	}
+
+	t.Run("некорректный JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/register", strings.NewReader(`{"name": `))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("разбор ответа: %v", err)
+		}
+		if rec.Code != http.StatusBadRequest || body["errors"] != nil {
+			t.Fatalf("статус %d, ответ %s", rec.Code, rec.Body.String())
+		}
+		if msg, _ := body["error"].(string); !strings.HasPrefix(msg, "Некорректное тело запроса") {
+			t.Errorf("ошибка %q", msg)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"user-service/internal/models"
+	"user-service/internal/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAPITokenNotFound = errors.New("API токен не найден")
+	ErrInvalidAPIToken  = errors.New("недействительный API токен")
+	ErrInvalidScope     = errors.New("недопустимое право токена")
+)
+
+// lastUsedPrecision не чаще этого интервала обновляется last_used_at токена
+const lastUsedPrecision = time.Minute
+
+type APITokenService struct {
+	tokenRepo *repository.APITokenRepository
+	userRepo  *repository.UserRepository
+}
+
+func NewAPITokenService(tokenRepo *repository.APITokenRepository, userRepo *repository.UserRepository) *APITokenService {
+	return &APITokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// hashAPIToken возвращает SHA-256 хеш токена в hex
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken выпускает API токен пользователю. Права токена не могут превышать права роли.
+func (s *APITokenService) CreateToken(userID uint, req *models.APITokenCreateRequest) (*models.APITokenCreateResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пользователя: %w", err)
+	}
+
+	allowed := models.UserRole(user.Role).Permissions()
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, scope := range scopes {
+		if !containsString(allowed, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("ошибка генерации токена: %w", err)
+	}
+	plain := models.APITokenPrefix + hex.EncodeToString(secret)
+
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: hashAPIToken(plain),
+		Prefix:    plain[:len(models.APITokenPrefix)+8],
+		Scopes:    strings.Join(scopes, ","),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.tokenRepo.Create(token); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения токена: %w", err)
+	}
+
+	return &models.APITokenCreateResponse{
+		APITokenResponse: token.ToResponse(),
+		Token:            plain,
+	}, nil
+}
+
+// ListTokens возвращает API токены пользователя без секретов
+func (s *APITokenService) ListTokens(userID uint) ([]models.APITokenResponse, error) {
+	tokens, err := s.tokenRepo.ListByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения токенов: %w", err)
+	}
+
+	responses := make([]models.APITokenResponse, len(tokens))
+	for i := range tokens {
+		responses[i] = tokens[i].ToResponse()
+	}
+	return responses, nil
+}
+
+// RevokeToken отзывает API токен пользователя
+func (s *APITokenService) RevokeToken(userID, tokenID uint) error {
+	deleted, err := s.tokenRepo.DeleteForUser(tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва токена: %w", err)
+	}
+	if deleted == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIToken проверяет API токен и возвращает его владельца и права
+func (s *APITokenService) AuthenticateAPIToken(plain string) (*models.User, []string, error) {
+	token, err := s.tokenRepo.GetByHash(hashAPIToken(plain))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIToken
+		}
+		return nil, nil, fmt.Errorf("ошибка проверки токена: %w", err)
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	user, err := s.userRepo.GetByID(token.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIToken
+		}
+		return nil, nil, fmt.Errorf("ошибка получения владельца токена: %w", err)
+	}
+	if !user.IsActive {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	if err := s.tokenRepo.TouchLastUsed(token.ID, now, now.Add(-lastUsedPrecision)); err != nil {
+		logrus.WithError(err).Warnf("Не удалось обновить время использования API токена %d", token.ID)
+	}
+
+	return user, token.ScopeList(), nil
+}
+
+// containsString проверяет наличие строки в списке
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
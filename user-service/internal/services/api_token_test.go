@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"user-service/internal/models"
+	"user-service/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB создает отдельную SQLite базу с мигрированными моделями User Service
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "users.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("открытие базы: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&models.User{}, &models.APIToken{}); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+	return db
+}
+
+// newTestAPITokenService создает сервис API токенов поверх отдельной SQLite базы
+func newTestAPITokenService(t *testing.T) (*APITokenService, *gorm.DB) {
+	t.Helper()
+
+	db := newTestDB(t)
+	return NewAPITokenService(repository.NewAPITokenRepository(db), repository.NewUserRepository(db)), db
+}
+
+// createUser сохраняет активного пользователя с ролью role
+func createUser(t *testing.T, db *gorm.DB, email string, role models.UserRole) *models.User {
+	t.Helper()
+	user := &models.User{Name: "Пользователь", Email: email, Password: "hash", Role: string(role), IsActive: true}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("создание пользователя: %v", err)
+	}
+	return user
+}
+
+func TestCreateTokenStoresOnlyHash(t *testing.T) {
+	service, db := newTestAPITokenService(t)
+	user := createUser(t, db, "user@example.com", models.RoleUser)
+
+	created, err := service.CreateToken(user.ID, &models.APITokenCreateRequest{Name: "ci", Scopes: []string{"reports:read"}, ExpiresInDays: 7})
+	if err != nil {
+		t.Fatalf("создание токена: %v", err)
+	}
+	if !strings.HasPrefix(created.Token, models.APITokenPrefix) || !strings.HasPrefix(created.Token, created.Prefix) {
+		t.Errorf("токен %q с префиксом %q, ожидался префикс %s", created.Token, created.Prefix, models.APITokenPrefix)
+	}
+	if created.ExpiresAt == nil {
+		t.Error("не задан срок действия токена")
+	}
+
+	var stored models.APIToken
+	if err := db.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("получение токена: %v", err)
+	}
+	if stored.TokenHash == created.Token || stored.TokenHash != hashAPIToken(created.Token) {
+		t.Error("в базе должен храниться только SHA-256 хеш токена")
+	}
+
+	// Без явных прав токен получает права роли
+	all, err := service.CreateToken(user.ID, &models.APITokenCreateRequest{Name: "all"})
+	if err != nil {
+		t.Fatalf("создание токена: %v", err)
+	}
+	if strings.Join(all.Scopes, ",") != strings.Join(models.RoleUser.Permissions(), ",") {
+		t.Errorf("права токена %v, ожидались права роли %v", all.Scopes, models.RoleUser.Permissions())
+	}
+}
+
+func TestCreateTokenRejectsScopeAboveRole(t *testing.T) {
+	service, db := newTestAPITokenService(t)
+	user := createUser(t, db, "user@example.com", models.RoleUser)
+
+	_, err := service.CreateToken(user.ID, &models.APITokenCreateRequest{Name: "ci", Scopes: []string{"reports:read", "users:write"}})
+	if !errors.Is(err, ErrInvalidScope) {
+		t.Fatalf("ошибка %v, ожидалась ErrInvalidScope", err)
+	}
+
+	var count int64
+	db.Model(&models.APIToken{}).Count(&count)
+	if count != 0 {
+		t.Errorf("сохранено токенов: %d, ожидалось 0", count)
+	}
+}
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	service, db := newTestAPITokenService(t)
+	user := createUser(t, db, "user@example.com", models.RoleUser)
+
+	created, err := service.CreateToken(user.ID, &models.APITokenCreateRequest{Name: "ci", Scopes: []string{"reports:read"}})
+	if err != nil {
+		t.Fatalf("создание токена: %v", err)
+	}
+
+	owner, scopes, err := service.AuthenticateAPIToken(created.Token)
+	if err != nil {
+		t.Fatalf("проверка токена: %v", err)
+	}
+	if owner.ID != user.ID || len(scopes) != 1 || scopes[0] != "reports:read" {
+		t.Errorf("токен принадлежит %d с правами %v, ожидались %d и [reports:read]", owner.ID, scopes, user.ID)
+	}
+
+	var stored models.APIToken
+	db.First(&stored, created.ID)
+	if stored.LastUsedAt == nil {
+		t.Error("не обновлено время последнего использования")
+	}
+
+	if _, _, err := service.AuthenticateAPIToken(created.Token + "x"); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("ошибка %v для неизвестного токена, ожидалась ErrInvalidAPIToken", err)
+	}
+
+	// Токен деактивированного пользователя не принимается
+	db.Model(user).UpdateColumn("is_active", false)
+	if _, _, err := service.AuthenticateAPIToken(created.Token); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("ошибка %v для неактивного пользователя, ожидалась ErrInvalidAPIToken", err)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	service, db := newTestAPITokenService(t)
+	user := createUser(t, db, "user@example.com", models.RoleUser)
+	other := createUser(t, db, "other@example.com", models.RoleUser)
+
+	created, err := service.CreateToken(user.ID, &models.APITokenCreateRequest{Name: "ci"})
+	if err != nil {
+		t.Fatalf("создание токена: %v", err)
+	}
+
+	if err := service.RevokeToken(other.ID, created.ID); !errors.Is(err, ErrAPITokenNotFound) {
+		t.Fatalf("отзыв чужого токена: %v, ожидалась ErrAPITokenNotFound", err)
+	}
+	if _, _, err := service.AuthenticateAPIToken(created.Token); err != nil {
+		t.Fatalf("токен перестал работать после отзыва другим пользователем: %v", err)
+	}
+
+	if err := service.RevokeToken(user.ID, created.ID); err != nil {
+		t.Fatalf("отзыв токена: %v", err)
+	}
+	if _, _, err := service.AuthenticateAPIToken(created.Token); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("ошибка %v для отозванного токена, ожидалась ErrInvalidAPIToken", err)
+	}
+
+	tokens, err := service.ListTokens(user.ID)
+	if err != nil || len(tokens) != 0 {
+		t.Errorf("после отзыва осталось токенов: %d (%v)", len(tokens), err)
+	}
+}
@@ -30,8 +30,10 @@ func NewUserService(userRepo *repository.UserRepository, jwtManager *jwt.Manager
 
 // CreateUser создает нового пользователя
 func (s *UserService) CreateUser(req *models.UserCreateRequest) (*models.UserResponse, error) {
+	email := models.NormalizeEmail(req.Email)
+
 	start := time.Now()
-	exists, err := s.userRepo.IsEmailExists(req.Email)
+	exists, err := s.userRepo.IsEmailExists(email)
 	if err != nil {
 		s.metrics.RecordDatabaseOperation("user-service", "check_email_exists", time.Since(start), err)
 		return nil, fmt.Errorf("ошибка проверки email: %w", err)
@@ -57,7 +59,7 @@ func (s *UserService) CreateUser(req *models.UserCreateRequest) (*models.UserRes
 
 	user := &models.User{
 		Name:     req.Name,
-		Email:    req.Email,
+		Email:    email,
 		Password: string(hashedPassword),
 		Role:     role,
 		IsActive: true,
@@ -76,7 +78,7 @@ func (s *UserService) CreateUser(req *models.UserCreateRequest) (*models.UserRes
 // Login авторизует пользователя
 func (s *UserService) Login(req *models.UserLoginRequest) (*models.LoginResponse, error) {
 	start := time.Now()
-	user, err := s.userRepo.GetByEmail(req.Email)
+	user, err := s.userRepo.GetByEmail(models.NormalizeEmail(req.Email))
 	if err != nil {
 		s.metrics.RecordDatabaseOperation("user-service", "get_user_by_email", time.Since(start), err)
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -150,16 +152,18 @@ func (s *UserService) UpdateUser(id uint, req *models.UserUpdateRequest) (*model
 		user.Name = req.Name
 	}
 	if req.Email != "" {
-		if req.Email != user.Email {
-			exists, err := s.userRepo.IsEmailExists(req.Email)
+		email := models.NormalizeEmail(req.Email)
+		// Смена только регистра не меняет владельца адреса и не требует проверки
+		if email != models.NormalizeEmail(user.Email) {
+			exists, err := s.userRepo.IsEmailExists(email)
 			if err != nil {
 				return nil, fmt.Errorf("ошибка проверки email: %w", err)
 			}
 			if exists {
 				return nil, errors.New("пользователь с таким email уже существует")
 			}
-			user.Email = req.Email
 		}
+		user.Email = email
 	}
 	if req.Password != "" {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"sync"
+	"testing"
+
+	"user-service/internal/jwt"
+	"user-service/internal/metrics"
+	"user-service/internal/models"
+	"user-service/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testMetrics метрики регистрируются в глобальном реестре, поэтому создаются один раз
+var testMetrics = sync.OnceValue(func() *metrics.Metrics {
+	return metrics.NewMetrics("user-service-test")
+})
+
+func TestEmailIsCaseInsensitive(t *testing.T) {
+	db := newTestDB(t)
+	service := NewUserService(repository.NewUserRepository(db), jwt.NewManager("test-secret"), testMetrics())
+
+	created, err := service.CreateUser(&models.UserCreateRequest{Name: "Новый", Email: "  New.User@Example.com ", Password: "password"})
+	if err != nil {
+		t.Fatalf("регистрация: %v", err)
+	}
+	if created.Email != "new.user@example.com" {
+		t.Errorf("сохранен email %q, ожидался нормализованный", created.Email)
+	}
+
+	// Запись, сохраненная до нормализации, в смешанном регистре
+	hash, _ := bcrypt.GenerateFromPassword([]byte("legacy-password"), bcrypt.MinCost)
+	legacy := &models.User{Name: "Старый", Email: "Legacy@Example.COM", Password: string(hash), Role: string(models.RoleUser), IsActive: true}
+	if err := db.Create(legacy).Error; err != nil {
+		t.Fatalf("создание пользователя: %v", err)
+	}
+
+	for _, email := range []string{"NEW.USER@example.COM", "legacy@example.com"} {
+		if _, err := service.CreateUser(&models.UserCreateRequest{Name: "Дубликат", Email: email, Password: "password"}); err == nil {
+			t.Errorf("повторная регистрация %q в другом регистре принята", email)
+		}
+	}
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	if count != 2 {
+		t.Errorf("пользователей в базе: %d, ожидалось 2", count)
+	}
+
+	for email, password := range map[string]string{
+		"new.USER@EXAMPLE.com": "password",
+		" LEGACY@example.com ": "legacy-password",
+	} {
+		response, err := service.Login(&models.UserLoginRequest{Email: email, Password: password})
+		if err != nil {
+			t.Errorf("вход с email %q: %v", email, err)
+			continue
+		}
+		if response.Token == "" {
+			t.Errorf("вход с email %q: не выдан токен", email)
+		}
+	}
+	if response, err := service.Login(&models.UserLoginRequest{Email: "legacy@example.com", Password: "legacy-password"}); err != nil || response.User.ID != legacy.ID {
+		t.Errorf("вход найден пользователь %+v (%v), ожидался %d", response, err, legacy.ID)
+	}
+}